@@ -0,0 +1,22 @@
+// Command openapi prints the OpenAPI document of the backend, for generating
+// typed clients without a running server:
+//
+//	go run ./cmd/openapi > openapi.json
+package main
+
+import (
+	"log"
+	"os"
+
+	"tm-platform-backend/internal/httpapi"
+)
+
+func main() {
+	document, err := httpapi.OpenAPIDocument()
+	if err != nil {
+		log.Fatalf("build openapi document: %v", err)
+	}
+	if _, err := os.Stdout.Write(append(document, '\n')); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,70 +2,245 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"tm-platform-backend/internal/account"
 	"tm-platform-backend/internal/aichat"
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/cache"
 	"tm-platform-backend/internal/chats"
 	"tm-platform-backend/internal/config"
 	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/files"
+	"tm-platform-backend/internal/graphapi"
 	"tm-platform-backend/internal/handlers"
+	"tm-platform-backend/internal/health"
 	"tm-platform-backend/internal/hierarchy"
 	"tm-platform-backend/internal/httpapi"
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/jobs"
+	"tm-platform-backend/internal/llm"
 	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/previews"
 	"tm-platform-backend/internal/projectfiles"
 	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/quotas"
+	"tm-platform-backend/internal/retention"
+	"tm-platform-backend/internal/schema"
+	"tm-platform-backend/internal/search"
+	"tm-platform-backend/internal/storage"
+	"tm-platform-backend/internal/webhooks"
+	"tm-platform-backend/internal/workspaces"
 	"tm-platform-backend/internal/zhcp"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
 	cfg := config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("migrate failed: %v", err)
+		}
+		return
+	}
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("invalid configuration: %v", err)
 	}
 
+	if cfg.DBAutoMigrate {
+		if err := schema.Up(cfg.DatabaseDSN()); err != nil {
+			log.Fatalf("db migration failed: %v", err)
+		}
+	}
+	if err := schema.Check(cfg.DatabaseDSN()); err != nil {
+		log.Fatalf("db schema check failed: %v (run `server migrate up` or set DB_AUTO_MIGRATE=true)", err)
+	}
+
 	dbConn, err := db.Open(cfg.DatabaseDSN())
 	if err != nil {
 		log.Fatalf("db connection failed: %v", err)
 	}
 	defer dbConn.Close()
 
-	authRepo := auth.NewRepository(dbConn)
+	var replicaConn *sql.DB
+	if strings.TrimSpace(cfg.DBReadDSN) != "" {
+		replicaConn, err = db.Open(cfg.DBReadDSN)
+		if err != nil {
+			log.Fatalf("read replica connection failed: %v", err)
+		}
+		defer replicaConn.Close()
+	}
+
+	var redisClient *redis.Client
+	if strings.TrimSpace(cfg.RedisURL) != "" {
+		redisOptions, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("invalid REDIS_URL: %v", err)
+		}
+		redisClient = redis.NewClient(redisOptions)
+		defer redisClient.Close()
+	}
+	var appCache cache.Store = cache.NewMemory()
+	if redisClient != nil {
+		appCache = cache.NewRedis(redisClient)
+	}
+
+	authRepo := auth.NewRepository(dbConn).WithCache(appCache)
 	authSvc := auth.NewService(cfg.JWTSecret)
 	authHandler := auth.NewHandler(authRepo, authSvc, cfg.AppEnv)
 	hierarchyRepo := hierarchy.NewRepository(dbConn)
 	hierarchyHandler := hierarchy.NewHandler(hierarchyRepo, authRepo)
-	notificationsRepo := notifications.NewRepository(dbConn)
+	notificationsRepo := notifications.NewRepository(dbConn).WithCache(appCache)
+
+	jobQueue := jobs.NewQueue(dbConn)
+	notificationsRepo.RegisterJobs(jobQueue)
+	eventBus := events.NewBus()
+	notifications.NewSubscriber(notificationsRepo, jobQueue).Register(eventBus)
 
-	projectsRepo := projects.NewRepository(dbConn)
-	projectsHandler := projects.NewHTTPHandler(projectsRepo, notificationsRepo)
+	projectsRepo := projects.NewRepository(dbConn).WithCache(appCache).WithReplica(replicaConn).WithDefaultTimezone(cfg.DefaultTimezone)
+	projectsHandler := projects.NewHTTPHandler(projectsRepo, eventBus)
+	jobQueue.Register(projects.JobDeadlineReminders, projects.DeadlineReminders(projectsRepo, notificationsRepo))
+	jobQueue.Every(projects.JobDeadlineReminders, 15*time.Minute)
 
-	uploadHandler, err := handlers.NewUploadHandler("uploads")
+	fileStore, err := storage.New(storage.Config{
+		Driver:         cfg.StorageDriver,
+		LocalDir:       cfg.UploadsDir,
+		S3Endpoint:     cfg.S3Endpoint,
+		S3Region:       cfg.S3Region,
+		S3Bucket:       cfg.S3Bucket,
+		S3AccessKey:    cfg.S3AccessKey,
+		S3SecretKey:    cfg.S3SecretKey,
+		S3UsePathStyle: cfg.S3UsePathStyle,
+	})
+	if err != nil {
+		log.Fatalf("storage init failed: %v", err)
+	}
+
+	quotaRepo := quotas.NewRepository(dbConn, quotas.Limits{
+		UserBytes:    cfg.UserStorageQuotaMB << 20,
+		ProjectBytes: cfg.ProjectStorageQuotaMB << 20,
+	}).WithReplica(replicaConn)
+	workspacesRepo := workspaces.NewRepository(dbConn)
+	quotaHandler := quotas.NewHandler(quotaRepo, workspacesRepo.IsPlatformAdmin)
+	jobsHandler := jobs.NewHandler(jobQueue, workspacesRepo.IsPlatformAdmin)
+	webhooksRepo := webhooks.NewRepository(dbConn)
+	webhookDispatcher := webhooks.NewDispatcher(webhooksRepo, jobQueue)
+	webhookDispatcher.Register(eventBus)
+	webhooksHandler := webhooks.NewHandler(webhooksRepo, webhookDispatcher, workspacesRepo.IsWorkspaceAdmin)
+	searchRepo := search.NewRepository(dbConn).WithReplica(replicaConn)
+	search.NewIndexer(searchRepo, jobQueue).Register(eventBus)
+	jobQueue.Every(search.JobReconcile, 10*time.Minute)
+	searchHandler := search.NewHandler(searchRepo)
+	workspacesHandler := workspaces.NewHandler(workspacesRepo, workspacesRepo.IsPlatformAdmin)
+
+	uploadPolicy, err := handlers.NewUploadPolicy(map[string]handlers.UploadTypePolicy{
+		"image": {Extensions: cfg.UploadImageExtensions, MaxSize: cfg.UploadImageMaxMB << 20},
+		"video": {Extensions: cfg.UploadVideoExtensions, MaxSize: cfg.UploadVideoMaxMB << 20},
+		"file":  {Extensions: cfg.UploadFileExtensions, MaxSize: cfg.UploadFileMaxMB << 20},
+	})
+	if err != nil {
+		log.Fatalf("upload policy init failed: %v", err)
+	}
+
+	uploadHandler, err := handlers.NewUploadHandler(fileStore, handlers.NewUploadSessionRepository(dbConn), quotaRepo, uploadPolicy, projectsRepo.HasEditAccess)
 	if err != nil {
 		log.Fatalf("upload handler init failed: %v", err)
 	}
 
-	projectFilesRepo := projectfiles.NewRepository(dbConn)
-	projectFilesHandler := projectfiles.NewHandler(projectFilesRepo)
-	zhcpClient := zhcp.NewClient(cfg.ZHCPParserURL)
-	zhcpHandler := zhcp.NewHandler(zhcpClient, projectsRepo)
+	projectFilesRepo := projectfiles.NewRepository(dbConn).WithReplica(replicaConn)
+	zhcpClient := zhcp.NewClient(cfg.ZHCPParserURL, cfg.ZHCPParserKey)
+	registerLLMProviders()
+	llmCatalog, err := buildLLMCatalog(cfg)
+	if err != nil {
+		log.Fatalf("ai providers init failed: %v", err)
+	}
+	var embedder llm.Embedder
+	if (strings.TrimSpace(cfg.AIBaseURL) != "" || strings.TrimSpace(cfg.AIAPIKey) != "") && strings.TrimSpace(cfg.AIEmbeddingModel) != "" {
+		embedder = llm.NewOpenAIEmbedder(cfg.AIBaseURL, cfg.AIAPIKey, cfg.AIEmbeddingModel, cfg.AITimeout)
+	}
+	chatsRepo := chats.NewRepository(dbConn).WithCache(appCache).WithReplica(replicaConn)
+	retentionRepo := retention.NewRepository(dbConn)
+	retentionPurger := retention.NewPurger(retentionRepo, notificationsRepo, chatsRepo, projectFilesRepo, fileStore)
+	retentionPurger.Register(jobQueue)
+	jobQueue.Every(retention.JobPurge, time.Hour)
+	retentionHandler := retention.NewHandler(retentionRepo, retentionPurger, jobQueue, workspacesRepo.IsPlatformAdmin)
+	accountHandler := account.NewHandler(account.NewRepository(dbConn), fileStore)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	previewWorker := previews.NewWorker(previews.NewGenerator(fileStore), projectFilesRepo.SetPreview, 256)
+	previewWorker.Start(workerCtx, 2)
+	go enqueuePendingPreviews(workerCtx, projectFilesRepo, previewWorker)
+	jobQueue.Start(workerCtx, 2)
+	fileIndexer := projectfiles.NewIndexer(projectFilesRepo, fileStore, zhcpClient, embedder, 256).WithEvents(eventBus)
+	fileIndexer.Start(workerCtx, 1)
+	go fileIndexer.EnqueuePending(workerCtx, 200)
+	go fileIndexer.EmbedPending(workerCtx, 200)
+	projectFilesHandler := projectfiles.NewHandler(projectFilesRepo, fileStore, previewWorker, quotaRepo, fileIndexer, notificationsRepo, cfg.TrashRetention)
+	zhcpJobsRepo := zhcp.NewRepository(dbConn)
+	zhcpTracker := zhcp.NewTracker(zhcpJobsRepo, zhcpClient, projectsRepo, notificationsRepo, 3*time.Second)
+	zhcpTracker.Start(workerCtx)
+	zhcpHandler := zhcp.NewHandler(zhcpClient, projectsRepo, zhcpJobsRepo, zhcpTracker, projectFilesRepo, fileStore)
 	aiChatRepo := aichat.NewRepository(dbConn)
-	aiChatHandler := aichat.NewHandler(aiChatRepo)
+	aiPricing, err := aichat.ParsePricing(cfg.AIPricing)
+	if err != nil {
+		log.Fatalf("ai pricing init failed: %v", err)
+	}
+	aiUsage := aichat.NewUsageTracker(aiChatRepo, aiPricing, aichat.UsageLimits{
+		MonthlyTokens: cfg.AIMonthlyTokens,
+		MonthlyCost:   cfg.AIMonthlyCostUSD,
+	})
+	aiChatHandler := aichat.NewHandler(aiChatRepo, projectsRepo, projectFilesRepo, llmCatalog, embedder, aiUsage, workspacesRepo.IsPlatformAdmin)
 	notificationsHandler := notifications.NewHandler(notificationsRepo)
-	chatsRepo := chats.NewRepository(dbConn)
-	chatsHandler := chats.NewHandler(chatsRepo, notificationsRepo)
+	graphHandler := graphapi.NewHandler(projectsRepo, notificationsRepo)
+	chatsHandler := chats.NewHandler(chatsRepo, notificationsRepo, fileStore).WithEvents(eventBus)
+	urlSigner, err := storage.NewURLSigner(cfg.SignedURLSecret, cfg.SignedURLTTL)
+	if err != nil {
+		log.Fatalf("signed url init failed: %v", err)
+	}
+	filesHandler := files.NewHandler(files.NewRepository(dbConn), fileStore, urlSigner, projectFilesRepo.ObjectAccess, chatsRepo.AttachmentAccess)
 
-	readyCheck := func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		return dbConn.PingContext(ctx)
+	var rateLimitStore httpapi.RateLimitStore = httpapi.NewMemoryRateLimitStore()
+	if redisClient != nil {
+		rateLimitStore = httpapi.NewRedisRateLimitStore(redisClient)
+	}
+	rateLimits := httpapi.NewRateLimits(rateLimitStore, rateLimitGroups(cfg))
+	corsPolicies := httpapi.NewCORSPolicies(corsOrigins(cfg))
+	var recentWrites cache.Store
+	if replicaConn != nil {
+		recentWrites = appCache
 	}
+
+	// unconfigured dependencies keep a nil probe and report "disabled"
+	var replicaProbe, redisProbe, smtpProbe func(ctx context.Context) error
+	if replicaConn != nil {
+		replicaProbe = replicaConn.PingContext
+	}
+	if redisClient != nil {
+		redisProbe = func(ctx context.Context) error { return redisClient.Ping(ctx).Err() }
+	}
+	if addr := strings.TrimSpace(cfg.SMTPAddr); addr != "" {
+		smtpProbe = health.SMTP(addr)
+	}
+	readyChecker := health.NewChecker(cfg.ReadyTimeout, cfg.ReadyCacheTTL,
+		health.Dependency{Name: "postgres", Impact: health.ImpactCritical, Probe: dbConn.PingContext},
+		health.Dependency{Name: "postgres_replica", Impact: health.ImpactCritical, Probe: replicaProbe},
+		health.Dependency{Name: "object_storage", Impact: health.ImpactDegraded, Probe: func(ctx context.Context) error {
+			return storage.Ping(ctx, fileStore)
+		}},
+		health.Dependency{Name: "redis", Impact: health.ImpactDegraded, Probe: redisProbe},
+		health.Dependency{Name: "zhcp_parser", Impact: health.ImpactDegraded, Probe: zhcpClient.Ping},
+		health.Dependency{Name: "smtp", Impact: health.ImpactDegraded, Probe: smtpProbe},
+	)
 	router := httpapi.NewRouter(
 		authHandler,
 		hierarchyHandler,
@@ -76,22 +251,46 @@ func main() {
 		aiChatHandler,
 		notificationsHandler,
 		chatsHandler,
+		quotaHandler,
+		filesHandler,
+		jobsHandler,
+		webhooksHandler,
+		graphHandler,
+		searchHandler,
+		workspacesHandler,
+		retentionHandler,
+		accountHandler,
 		authSvc,
-		cfg.CORSOrigins,
-		readyCheck,
+		rateLimits,
+		httpapi.ReadYourWrites(recentWrites, cfg.DBReadSticky),
+		i18n.Middleware(auth.ProfileLocale(authRepo)),
+		workspaces.Middleware(workspacesRepo),
+		corsPolicies,
+		readyChecker,
 	)
 	mux := http.NewServeMux()
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+	uploadsGuard := func(r *http.Request, key string) (bool, error) {
+		if urlSigner.Verify(r, key) {
+			return true, nil
+		}
+		if !cfg.UploadsPublic {
+			return false, nil
+		}
+		return projectFilesRepo.PublicAccessAllowed(r.Context(), key)
+	}
+	mux.Handle(storage.PublicPrefix, corsPolicies.Uploads.Handler(http.StripPrefix(storage.PublicPrefix, storage.Handler(fileStore, uploadsGuard))))
 	mux.Handle("/", router)
 
 	server := &http.Server{
 		Addr:              cfg.ServerAddr,
-		Handler:           mux,
+		Handler:           httpapi.SecurityHeaders(cfg.HSTSMaxAge)(mux),
 		ReadTimeout:       cfg.ReadTimeout,
 		ReadHeaderTimeout: cfg.ReadHdrTO,
 		WriteTimeout:      cfg.WriteTimeout,
 		IdleTimeout:       cfg.IdleTimeout,
 	}
+	// Job event streams stay open until their job finishes
+	server.RegisterOnShutdown(zhcpTracker.StopStreams)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -101,6 +300,10 @@ func main() {
 		}
 	}()
 
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	go reloadConfig(reloadCh, cfg, corsPolicies, rateLimits)
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
@@ -111,10 +314,132 @@ func main() {
 		log.Fatalf("server failed: %v", err)
 	}
 
+	// Take the instance out of rotation and stop forwarding documents to the
+	// parser before connections start closing
+	readyChecker.Drain()
+	zhcpHandler.StopAccepting()
+	if cfg.ShutdownDelay > 0 {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGrace)
 	defer cancel()
+
+	// Running jobs finish alongside open requests; the ones still running at
+	// the deadline are queued again for another instance
+	drained := make(chan error, 1)
+	go func() { drained <- jobQueue.Drain(shutdownCtx) }()
+
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("graceful shutdown failed: %v", err)
+		log.Printf("graceful shutdown failed: %v", err)
+	}
+	if err := <-drained; err != nil {
+		log.Printf("background jobs interrupted by shutdown: %v", err)
 	}
 	log.Printf("server stopped")
 }
+
+// reloadConfig loads the configuration again on every signal and applies its
+// tunables, CORS origins and rate limits, to the running server. A
+// configuration that does not validate is ignored; changes to the other
+// settings are logged as needing a restart.
+func reloadConfig(signals <-chan os.Signal, started config.Config, cors *httpapi.CORSPolicies, limits *httpapi.RateLimits) {
+	for range signals {
+		next := config.Load()
+		if err := next.Validate(); err != nil {
+			log.Printf("config reload rejected: %v", err)
+			continue
+		}
+		cors.Set(corsOrigins(next))
+		limits.Set(rateLimitGroups(next))
+		for _, name := range started.RestartRequired(next) {
+			log.Printf("config reload: %s changed, restart to apply", name)
+		}
+		log.Printf("config reloaded")
+	}
+}
+
+func corsOrigins(cfg config.Config) httpapi.CORSOrigins {
+	return httpapi.CORSOrigins{
+		API:     cfg.CORSOrigins,
+		Uploads: cfg.CORSUploadOrigins,
+		Streams: cfg.CORSStreamOrigins,
+	}
+}
+
+func rateLimitGroups(cfg config.Config) httpapi.RateLimitGroups {
+	return httpapi.RateLimitGroups{
+		Auth:   httpapi.RateLimit(cfg.RateLimitAuth),
+		API:    httpapi.RateLimit(cfg.RateLimitAPI),
+		Upload: httpapi.RateLimit(cfg.RateLimitUpload),
+		Parse:  httpapi.RateLimit(cfg.RateLimitParse),
+	}
+}
+
+// enqueuePendingPreviews picks up previews that were still pending when the
+// server last stopped.
+func enqueuePendingPreviews(ctx context.Context, repo *projectfiles.Repository, worker *previews.Worker) {
+	jobs, err := repo.ListPendingPreviews(ctx, 200)
+	if err != nil {
+		log.Printf("list pending previews failed: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if !worker.Enqueue(job) {
+			return
+		}
+	}
+}
+
+// registerLLMProviders registers the providers AI_PROVIDERS may name. All of
+// them speak the OpenAI chat completions protocol.
+func registerLLMProviders() {
+	llm.RegisterProvider("openai", func(config llm.ProviderConfig) (llm.Client, error) {
+		return llm.NewOpenAIClient(config.BaseURL, config.APIKey, config.Models[0], config.Timeout), nil
+	})
+
+	llm.RegisterProvider("deepseek", func(config llm.ProviderConfig) (llm.Client, error) {
+		return llm.NewOpenAIClient(valueOrDefault(config.BaseURL, "https://api.deepseek.com/v1"), config.APIKey, config.Models[0], config.Timeout), nil
+	})
+
+	llm.RegisterProvider("ollama", func(config llm.ProviderConfig) (llm.Client, error) {
+		return llm.NewOpenAIClient(valueOrDefault(config.BaseURL, "http://localhost:11434/v1"), config.APIKey, config.Models[0], config.Timeout), nil
+	})
+}
+
+// buildLLMCatalog creates the configured providers. It returns nil when no
+// provider is configured, which disables the AI assistant.
+func buildLLMCatalog(cfg config.Config) (*llm.Catalog, error) {
+	if len(cfg.AIProviders) == 0 {
+		return nil, nil
+	}
+
+	catalog := llm.NewCatalog()
+	for _, provider := range cfg.AIProviders {
+		client, err := llm.CreateProvider(provider.Name, llm.ProviderConfig{
+			BaseURL: provider.BaseURL,
+			APIKey:  provider.APIKey,
+			Models:  provider.Models,
+			Timeout: cfg.AITimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := catalog.Add(provider.Name, client, provider.Models); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.AIDefaultProvider != "" {
+		if err := catalog.SetDefault(cfg.AIDefaultProvider, ""); err != nil {
+			return nil, err
+		}
+	}
+	return catalog, nil
+}
+
+func valueOrDefault(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"tm-platform-backend/internal/config"
+	"tm-platform-backend/internal/schema"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// runMigrate runs the migrate subcommand:
+//
+//	server migrate up          apply all pending migrations
+//	server migrate down [N]    revert the last N migrations, 1 by default
+//	server migrate status      print the applied and pending versions
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	dsn := cfg.DatabaseDSN()
+
+	switch args[0] {
+	case "up":
+		if err := schema.Up(dsn); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid steps %q", args[1])
+			}
+			steps = parsed
+		}
+		if err := schema.Down(dsn, steps); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	status, err := schema.Current(dsn)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("version: %d (latest %d)\n", status.Version, status.Latest)
+	if status.Dirty {
+		fmt.Println("dirty: the last migration failed; fix the schema, then force the version with the migrate CLI")
+	}
+	fmt.Printf("pending: %d\n", len(status.Pending))
+	return nil
+}
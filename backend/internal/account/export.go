@@ -0,0 +1,174 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+type profileExport struct {
+	Profile    Profile      `json:"profile"`
+	Workspaces []Membership `json:"workspaces"`
+	Projects   []Membership `json:"projects"`
+	ExportedAt time.Time    `json:"exportedAt"`
+}
+
+type messagesExport struct {
+	Chat      []Message          `json:"chat"`
+	Assistant []AssistantMessage `json:"assistant"`
+}
+
+// exportedFile is a File and where its content is in the archive; Path is
+// empty when the object is gone from storage.
+type exportedFile struct {
+	File
+	Path string `json:"path,omitempty"`
+}
+
+// Export answers a zip of everything the requester put into the platform:
+//
+//	profile.json   profile, workspace and project memberships
+//	comments.json  comments on tasks, files and reports
+//	messages.json  chat messages and messages to the assistant
+//	files.json     uploaded files, with their path in the archive
+//	files/...      the uploaded files themselves
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	profile, err := h.repo.Profile(ctx, userID)
+	if err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+	export := profileExport{Profile: profile, ExportedAt: time.Now().UTC()}
+	if export.Workspaces, err = h.repo.Workspaces(ctx, userID); err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+	if export.Projects, err = h.repo.Projects(ctx, userID); err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+	comments, err := h.repo.Comments(ctx, userID)
+	if err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+	var messages messagesExport
+	if messages.Chat, err = h.repo.Messages(ctx, userID); err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+	if messages.Assistant, err = h.repo.AssistantMessages(ctx, userID); err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+	files, err := h.repo.Files(ctx, userID)
+	if err != nil {
+		writeAccountError(w, "export account", err)
+		return
+	}
+
+	filename := "export-" + export.ExportedAt.Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// the status is sent; from here on a failure can only cut the archive
+	// short
+	archive := zip.NewWriter(w)
+	if err := writeArchive(ctx, archive, h.store, export, comments, messages, files); err != nil {
+		log.Printf("export account %s failed: %v", userID, err)
+		return
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("export account %s failed: %v", userID, err)
+	}
+}
+
+func writeArchive(ctx context.Context, archive *zip.Writer, store storage.Storage, export profileExport, comments []Comment, messages messagesExport, files []File) error {
+	if err := writeArchiveJSON(archive, "profile.json", export); err != nil {
+		return err
+	}
+	if err := writeArchiveJSON(archive, "comments.json", comments); err != nil {
+		return err
+	}
+	if err := writeArchiveJSON(archive, "messages.json", messages); err != nil {
+		return err
+	}
+
+	exported := make([]exportedFile, 0, len(files))
+	for _, file := range files {
+		item := exportedFile{File: file}
+		if key, ok := storage.KeyFromURL(file.URL); ok {
+			name := archiveFileName(file.ID, file.Name)
+			written, err := copyObject(ctx, archive, store, key, name)
+			if err != nil {
+				return err
+			}
+			if written {
+				item.Path = name
+			}
+		}
+		exported = append(exported, item)
+	}
+	return writeArchiveJSON(archive, "files.json", exported)
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, payload any) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(payload)
+}
+
+// copyObject adds a stored object to the archive. It reports false, without
+// an error, for objects no longer in storage.
+func copyObject(ctx context.Context, archive *zip.Writer, store storage.Storage, key, name string) (bool, error) {
+	body, info, err := store.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer body.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.ModTime})
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(entry, body); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// archiveFileName keeps uploads with the same name apart.
+func archiveFileName(id uuid.UUID, name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	return "files/" + id.String() + "-" + name
+}
@@ -0,0 +1,98 @@
+// Package account lets users take their data with them and delete their
+// account. The export is a zip of their profile, comments, messages and
+// uploaded files; deletion anonymizes what they wrote instead of removing
+// it, so the projects and chats of others stay whole.
+package account
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+	"tm-platform-backend/internal/storage"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Handler struct {
+	repo  *Repository
+	store storage.Storage
+}
+
+func NewHandler(repo *Repository, store storage.Storage) *Handler {
+	return &Handler{repo: repo, store: store}
+}
+
+// deleteAccountReq confirms the deletion with the current password.
+type deleteAccountReq struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// Delete deletes the account of the requester, see Repository.Delete.
+// Their access token keeps working until it expires; it can no longer be
+// refreshed.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+
+	var req deleteAccountReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	profile, err := h.repo.Profile(r.Context(), userID)
+	if err != nil {
+		writeAccountError(w, "load account", err)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(profile.PasswordHash), []byte(req.Password)); err != nil {
+		problem.Write(w, http.StatusForbidden, "invalid_credentials", "invalid credentials")
+		return
+	}
+
+	keys, err := h.repo.Delete(r.Context(), userID)
+	if err != nil {
+		writeAccountError(w, "delete account", err)
+		return
+	}
+	for _, key := range keys {
+		if err := h.store.Delete(r.Context(), key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("delete avatar object %s failed: %v", key, err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAccountError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "user not found")
+	case errors.Is(err, ErrOwnsSharedProjects):
+		problem.Write(w, http.StatusConflict, "owns_shared_projects", "delegate the projects you share with others before deleting your account")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func requesterID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,13 @@
+package account
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/me/export", Tag: "users", Summary: "Download a zip of your profile, comments, messages and uploaded files", ContentType: "application/zip"},
+	{Method: http.MethodDelete, Path: "/me", Tag: "users", Summary: "Delete your account: personal data is removed and what you wrote is kept anonymized", Body: deleteAccountReq{}, Status: http.StatusNoContent},
+}
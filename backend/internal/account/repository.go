@@ -0,0 +1,419 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"tm-platform-backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("user not found")
+	// ErrOwnsSharedProjects stops a deletion while the user owns projects
+	// other people work in; they delegate them first.
+	ErrOwnsSharedProjects = errors.New("user owns projects shared with other members")
+)
+
+// deletedUserName is shown instead of the name of a deleted user next to
+// everything they wrote.
+const deletedUserName = "Удалённый пользователь"
+
+type Profile struct {
+	ID           uuid.UUID  `json:"id"`
+	Email        string     `json:"email"`
+	FullName     *string    `json:"fullName,omitempty"`
+	AvatarURL    *string    `json:"avatarUrl,omitempty"`
+	Role         *string    `json:"role,omitempty"`
+	ManagerID    *uuid.UUID `json:"managerId,omitempty"`
+	Department   *string    `json:"department,omitempty"`
+	Locale       *string    `json:"locale,omitempty"`
+	Timezone     *string    `json:"timezone,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	PasswordHash string     `json:"-"`
+}
+
+type Membership struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Comment is anything the user wrote under a task, file or report.
+type Comment struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"` // task, file, delay_report or report_chat
+	ProjectID *uuid.UUID `json:"projectId,omitempty"`
+	SubjectID *uuid.UUID `json:"subjectId,omitempty"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ThreadID       uuid.UUID `json:"threadId"`
+	Text           *string   `json:"text,omitempty"`
+	AttachmentURL  *string   `json:"attachmentUrl,omitempty"`
+	AttachmentName *string   `json:"attachmentName,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// AssistantMessage is a message the user sent to the AI assistant.
+type AssistantMessage struct {
+	ID        uuid.UUID `json:"id"`
+	ThreadID  uuid.UUID `json:"threadId"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// File is an object the user uploaded: a version of a project file or a
+// chat attachment.
+type File struct {
+	Source    string     `json:"source"` // project_file or chat_attachment
+	ID        uuid.UUID  `json:"id"`
+	ProjectID *uuid.UUID `json:"projectId,omitempty"`
+	Name      string     `json:"name"`
+	Version   *int       `json:"version,omitempty"`
+	URL       string     `json:"url"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Profile(ctx context.Context, userID uuid.UUID) (Profile, error) {
+	var profile Profile
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT u.id, u.email, u.full_name, u.avatar_url, u.role, u.manager_id, d.name, u.locale, u.timezone, u.created_at, u.password_hash
+		 FROM users u
+		 LEFT JOIN departments d ON d.id = u.department_id
+		 WHERE u.id = $1 AND u.deleted_at IS NULL`,
+		userID,
+	).Scan(
+		&profile.ID,
+		&profile.Email,
+		&profile.FullName,
+		&profile.AvatarURL,
+		&profile.Role,
+		&profile.ManagerID,
+		&profile.Department,
+		&profile.Locale,
+		&profile.Timezone,
+		&profile.CreatedAt,
+		&profile.PasswordHash,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Profile{}, ErrNotFound
+	}
+	return profile, err
+}
+
+func (r *Repository) Workspaces(ctx context.Context, userID uuid.UUID) ([]Membership, error) {
+	return r.memberships(
+		ctx,
+		`SELECT w.id, w.name, wm.role, wm.joined_at
+		 FROM workspace_members wm
+		 JOIN workspaces w ON w.id = wm.workspace_id
+		 WHERE wm.user_id = $1
+		 ORDER BY wm.joined_at`,
+		userID,
+	)
+}
+
+func (r *Repository) Projects(ctx context.Context, userID uuid.UUID) ([]Membership, error) {
+	return r.memberships(
+		ctx,
+		`SELECT p.id, p.name, pm.role, pm.created_at
+		 FROM project_members pm
+		 JOIN projects p ON p.id = pm.project_id
+		 WHERE pm.user_id = $1
+		 ORDER BY pm.created_at`,
+		userID,
+	)
+}
+
+func (r *Repository) memberships(ctx context.Context, query string, userID uuid.UUID) ([]Membership, error) {
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]Membership, 0)
+	for rows.Next() {
+		var item Membership
+		if err := rows.Scan(&item.ID, &item.Name, &item.Role, &item.JoinedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *Repository) Comments(ctx context.Context, userID uuid.UUID) ([]Comment, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT c.id, 'task', st.project_id, c.task_id, c.message, c.created_at
+		 FROM task_comments c
+		 JOIN stage_tasks st ON st.id = c.task_id
+		 WHERE c.user_id = $1
+		 UNION ALL
+		 SELECT c.id, 'file', pf.project_id, c.file_id, c.body, c.created_at
+		 FROM project_file_comments c
+		 JOIN project_files pf ON pf.id = c.file_id
+		 WHERE c.author_id = $1
+		 UNION ALL
+		 SELECT c.id, 'delay_report', c.project_id, c.report_id, c.message, c.created_at
+		 FROM delay_report_comments c
+		 WHERE c.user_id = $1
+		 UNION ALL
+		 SELECT c.id, 'report_chat', c.project_id, c.task_id, c.message, c.created_at
+		 FROM report_chat_messages c
+		 WHERE c.user_id = $1
+		 ORDER BY 6`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]Comment, 0)
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(&comment.ID, &comment.Kind, &comment.ProjectID, &comment.SubjectID, &comment.Text, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *Repository) Messages(ctx context.Context, userID uuid.UUID) ([]Message, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, thread_id, text, attachment_url, attachment_name, created_at
+		 FROM chat_messages
+		 WHERE sender_id = $1
+		 ORDER BY created_at`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0)
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(&message.ID, &message.ThreadID, &message.Text, &message.AttachmentURL, &message.AttachmentName, &message.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (r *Repository) AssistantMessages(ctx context.Context, userID uuid.UUID) ([]AssistantMessage, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT m.id, m.thread_id, m.text, m.created_at
+		 FROM ai_chat_messages m
+		 JOIN ai_chat_threads t ON t.id = m.thread_id
+		 WHERE t.user_id = $1 AND m.sender = 'user'
+		 ORDER BY m.created_at`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]AssistantMessage, 0)
+	for rows.Next() {
+		var message AssistantMessage
+		if err := rows.Scan(&message.ID, &message.ThreadID, &message.Text, &message.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (r *Repository) Files(ctx context.Context, userID uuid.UUID) ([]File, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT 'project_file', v.id, pf.project_id, pf.name, v.version, v.url, v.created_at
+		 FROM project_file_versions v
+		 JOIN project_files pf ON pf.id = v.file_id
+		 WHERE v.uploaded_by = $1
+		 UNION ALL
+		 SELECT 'chat_attachment', m.id, NULL, COALESCE(NULLIF(BTRIM(m.attachment_name), ''), 'attachment'), NULL, m.attachment_url, m.created_at
+		 FROM chat_messages m
+		 WHERE m.sender_id = $1 AND m.attachment_url IS NOT NULL
+		 ORDER BY 7`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]File, 0)
+	for rows.Next() {
+		var file File
+		if err := rows.Scan(&file.Source, &file.ID, &file.ProjectID, &file.Name, &file.Version, &file.URL, &file.CreatedAt); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// Delete removes the personal data of a user and keeps what they wrote:
+// their row stays, anonymized, so comments, messages, tasks and files keep
+// their author, shown as deletedUserName. They leave their workspaces and
+// projects, their org chart seats become vacant, their notifications,
+// sessions and assistant chats go, and they can no longer log in. Sole
+// admins of a workspace hand it to its longest-standing member. It returns
+// the storage key of the avatar when nothing else refers to it.
+func (r *Repository) Delete(ctx context.Context, userID uuid.UUID) (keys []string, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var avatarURL sql.NullString
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT avatar_url FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		userID,
+	).Scan(&avatarURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+		return nil, err
+	}
+
+	var ownsShared bool
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1
+		 	FROM projects p
+		 	JOIN project_members pm ON pm.project_id = p.id
+		 	WHERE p.owner_id = $1 AND pm.user_id <> $1
+		 )`,
+		userID,
+	).Scan(&ownsShared); err != nil {
+		return nil, err
+	}
+	if ownsShared {
+		err = ErrOwnsSharedProjects
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`UPDATE workspace_members wm
+		 SET role = 'admin'
+		 FROM (
+		 	SELECT DISTINCT ON (other.workspace_id) other.workspace_id, other.user_id
+		 	FROM workspace_members own
+		 	JOIN workspace_members other ON other.workspace_id = own.workspace_id AND other.user_id <> own.user_id
+		 	WHERE own.user_id = $1
+		 	  AND own.role = 'admin'
+		 	  AND NOT EXISTS (
+		 	  	SELECT 1 FROM workspace_members a
+		 	  	WHERE a.workspace_id = own.workspace_id AND a.role = 'admin' AND a.user_id <> $1
+		 	  )
+		 	ORDER BY other.workspace_id, other.joined_at, other.user_id
+		 ) successor
+		 WHERE wm.workspace_id = successor.workspace_id AND wm.user_id = successor.user_id`,
+		userID,
+	); err != nil {
+		return nil, err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM workspace_members WHERE user_id = $1`,
+		`DELETE FROM project_members WHERE user_id = $1 AND role <> 'owner'`,
+		`DELETE FROM project_file_access WHERE user_id = $1`,
+		`DELETE FROM chat_user_presence WHERE user_id = $1`,
+		`DELETE FROM notifications WHERE user_id = $1`,
+		`UPDATE notifications SET actor_id = NULL WHERE actor_id = $1`,
+		`DELETE FROM auth_refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM upload_sessions WHERE user_id = $1`,
+		`DELETE FROM ai_chat_threads WHERE user_id = $1`,
+		`UPDATE hierarchy_nodes SET user_id = NULL WHERE user_id = $1`,
+		`UPDATE users SET manager_id = NULL WHERE manager_id = $1`,
+		`UPDATE storage_objects SET user_id = NULL WHERE user_id = $1`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`UPDATE users
+		 SET email = 'deleted-' || id || '@deleted.invalid',
+		     password_hash = '',
+		     full_name = $2,
+		     avatar_url = NULL,
+		     role = NULL,
+		     manager_id = NULL,
+		     department_id = NULL,
+		     locale = NULL,
+		     timezone = NULL,
+		     deleted_at = now()
+		 WHERE id = $1`,
+		userID,
+		deletedUserName,
+	); err != nil {
+		return nil, err
+	}
+
+	if avatarURL.Valid {
+		if key, ok := storage.KeyFromURL(avatarURL.String); ok {
+			var referenced bool
+			if err = tx.QueryRowContext(
+				ctx,
+				`SELECT EXISTS (SELECT 1 FROM users WHERE avatar_url = $1)
+				     OR EXISTS (SELECT 1 FROM project_files WHERE url = $1 OR preview_url = $1)
+				     OR EXISTS (SELECT 1 FROM project_file_versions WHERE url = $1)
+				     OR EXISTS (SELECT 1 FROM chat_messages WHERE attachment_url = $1)`,
+				avatarURL.String,
+			).Scan(&referenced); err != nil {
+				return nil, err
+			}
+			if !referenced {
+				if _, err = tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = $1`, key); err != nil {
+					return nil, err
+				}
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return nil, err
+	}
+	return keys, nil
+}
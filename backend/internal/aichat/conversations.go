@@ -0,0 +1,286 @@
+package aichat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// conversationModePrefix marks threads created through the conversations API
+// so they never collide with the per-mode threads of the legacy endpoints.
+const conversationModePrefix = "conversation:"
+
+const (
+	senderUser      = "user"
+	senderAssistant = "other"
+)
+
+const conversationColumns = `id, COALESCE(title, ''), project_id, COALESCE(provider, ''), COALESCE(model, ''), created_at, updated_at`
+
+// Conversation is an assistant chat. Provider and Model hold the user's model
+// choice; empty means the server default.
+type Conversation struct {
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	ProjectID *uuid.UUID `json:"projectId,omitempty"`
+	Provider  string     `json:"provider,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+func (r *Repository) CreateConversation(ctx context.Context, userID uuid.UUID, title string, projectID *uuid.UUID, provider, model string) (Conversation, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return Conversation{}, err
+	}
+
+	id := uuid.New()
+	return scanConversation(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO ai_chat_threads (id, user_id, mode, title, project_id, provider, model)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+		 RETURNING `+conversationColumns,
+		id,
+		userID,
+		conversationModePrefix+id.String(),
+		title,
+		projectID,
+		provider,
+		model,
+	))
+}
+
+func (r *Repository) ListConversations(ctx context.Context, userID uuid.UUID) ([]Conversation, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+conversationColumns+`
+		 FROM ai_chat_threads
+		 WHERE user_id = $1 AND mode LIKE $2 || '%'
+		 ORDER BY updated_at DESC`,
+		userID,
+		conversationModePrefix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := make([]Conversation, 0)
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, rows.Err()
+}
+
+// GetConversation returns a conversation owned by userID.
+func (r *Repository) GetConversation(ctx context.Context, userID, conversationID uuid.UUID) (Conversation, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return Conversation{}, err
+	}
+
+	return scanConversation(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+conversationColumns+`
+		 FROM ai_chat_threads
+		 WHERE id = $1 AND user_id = $2 AND mode LIKE $3 || '%'`,
+		conversationID,
+		userID,
+		conversationModePrefix,
+	))
+}
+
+// UpdateConversation renames a conversation and changes its model choice.
+func (r *Repository) UpdateConversation(ctx context.Context, userID, conversationID uuid.UUID, title, provider, model string) (Conversation, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return Conversation{}, err
+	}
+
+	return scanConversation(r.db.QueryRowContext(
+		ctx,
+		`UPDATE ai_chat_threads
+		 SET title = $4, provider = NULLIF($5, ''), model = NULLIF($6, ''), updated_at = now()
+		 WHERE id = $1 AND user_id = $2 AND mode LIKE $3 || '%'
+		 RETURNING `+conversationColumns,
+		conversationID,
+		userID,
+		conversationModePrefix,
+		title,
+		provider,
+		model,
+	))
+}
+
+func (r *Repository) DeleteConversation(ctx context.Context, userID, conversationID uuid.UUID) error {
+	if err := r.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM ai_chat_threads
+		 WHERE id = $1 AND user_id = $2 AND mode LIKE $3 || '%'`,
+		conversationID,
+		userID,
+		conversationModePrefix,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListConversationMessages returns the messages of a conversation, oldest
+// first. The caller must have checked ownership.
+func (r *Repository) ListConversationMessages(ctx context.Context, conversationID uuid.UUID) ([]Message, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT m.id, m.thread_id, m.sender, m.text, COALESCE(m.model, ''), m.sources,
+		        u.prompt_tokens, u.completion_tokens, u.cost, m.created_at
+		 FROM ai_chat_messages m
+		 LEFT JOIN ai_usage u ON u.message_id = m.id
+		 WHERE m.thread_id = $1
+		 ORDER BY m.created_at ASC, m.id ASC`,
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0)
+	for rows.Next() {
+		m, err := scanConversationMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	actions, err := r.listToolActions(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		messages[i].Actions = actions[messages[i].ID]
+	}
+
+	return messages, nil
+}
+
+// AddConversationMessage stores a message with the model that wrote it and
+// the document passages it cites, and bumps the conversation.
+func (r *Repository) AddConversationMessage(ctx context.Context, conversationID uuid.UUID, sender, text, model string, sources []Source) (Message, error) {
+	var rawSources []byte
+	if len(sources) > 0 {
+		encoded, err := json.Marshal(sources)
+		if err != nil {
+			return Message{}, err
+		}
+		rawSources = encoded
+	}
+
+	m, err := scanConversationMessage(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO ai_chat_messages (id, thread_id, sender, text, model, sources)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		 RETURNING id, thread_id, sender, text, COALESCE(model, ''), sources,
+		           NULL::int, NULL::int, NULL::double precision, created_at`,
+		uuid.New(),
+		conversationID,
+		sender,
+		text,
+		model,
+		rawSources,
+	))
+	if err != nil {
+		return Message{}, err
+	}
+
+	_, _ = r.db.ExecContext(ctx, `UPDATE ai_chat_threads SET updated_at = now() WHERE id = $1`, conversationID)
+	return m, nil
+}
+
+func scanConversationMessage(row conversationScanner) (Message, error) {
+	var (
+		m                Message
+		rawSources       []byte
+		promptTokens     sql.NullInt64
+		completionTokens sql.NullInt64
+		cost             sql.NullFloat64
+	)
+	if err := row.Scan(
+		&m.ID,
+		&m.ThreadID,
+		&m.Sender,
+		&m.Text,
+		&m.Model,
+		&rawSources,
+		&promptTokens,
+		&completionTokens,
+		&cost,
+		&m.CreatedAt,
+	); err != nil {
+		return Message{}, err
+	}
+	if promptTokens.Valid {
+		m.Usage = &MessageUsage{
+			PromptTokens:     int(promptTokens.Int64),
+			CompletionTokens: int(completionTokens.Int64),
+			Cost:             cost.Float64,
+		}
+	}
+	if len(rawSources) > 0 {
+		if err := json.Unmarshal(rawSources, &m.Sources); err != nil {
+			return Message{}, err
+		}
+	}
+	return m, nil
+}
+
+type conversationScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanConversation(row conversationScanner) (Conversation, error) {
+	var (
+		conversation Conversation
+		projectID    uuid.NullUUID
+	)
+	if err := row.Scan(
+		&conversation.ID,
+		&conversation.Title,
+		&projectID,
+		&conversation.Provider,
+		&conversation.Model,
+		&conversation.CreatedAt,
+		&conversation.UpdatedAt,
+	); err != nil {
+		return Conversation{}, err
+	}
+	if projectID.Valid {
+		conversation.ProjectID = &projectID.UUID
+	}
+	return conversation, nil
+}
@@ -0,0 +1,154 @@
+package aichat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	exportFormatMarkdown = "md"
+	exportFormatJSON     = "json"
+
+	exportRoleUser      = "user"
+	exportRoleAssistant = "assistant"
+)
+
+type conversationExport struct {
+	ID         uuid.UUID       `json:"id"`
+	Title      string          `json:"title"`
+	ProjectID  *uuid.UUID      `json:"projectId,omitempty"`
+	Provider   string          `json:"provider,omitempty"`
+	Model      string          `json:"model,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Messages   []exportMessage `json:"messages"`
+}
+
+type exportMessage struct {
+	Role      string    `json:"role"`
+	Text      string    `json:"text"`
+	Model     string    `json:"model,omitempty"`
+	Sources   []Source  `json:"sources,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExportConversation downloads a conversation as a Markdown (?format=md, the
+// default) or JSON (?format=json) transcript.
+func (h *Handler) ExportConversation(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		format = exportFormatMarkdown
+	}
+	if format != exportFormatMarkdown && format != exportFormatJSON {
+		problem.Write(w, http.StatusBadRequest, "invalid_format", "format must be md or json")
+		return
+	}
+
+	_, conversation, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	messages, err := h.repo.ListConversationMessages(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("list ai conversation messages failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch messages")
+		return
+	}
+
+	export := conversationExport{
+		ID:         conversation.ID,
+		Title:      conversation.Title,
+		ProjectID:  conversation.ProjectID,
+		Provider:   conversation.Provider,
+		Model:      conversation.Model,
+		CreatedAt:  conversation.CreatedAt,
+		UpdatedAt:  conversation.UpdatedAt,
+		ExportedAt: time.Now().UTC(),
+		Messages:   make([]exportMessage, 0, len(messages)),
+	}
+	for _, message := range messages {
+		role := exportRoleAssistant
+		if message.Sender == senderUser {
+			role = exportRoleUser
+		}
+		export.Messages = append(export.Messages, exportMessage{
+			Role:      role,
+			Text:      message.Text,
+			Model:     message.Model,
+			Sources:   message.Sources,
+			CreatedAt: message.CreatedAt,
+		})
+	}
+
+	filename := "conversation-" + conversation.ID.String() + "." + format
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if format == exportFormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(renderMarkdownExport(export)))
+}
+
+func renderMarkdownExport(export conversationExport) string {
+	title := export.Title
+	if title == "" {
+		title = "Разговор с ассистентом"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Создан: %s\n", formatExportTime(export.CreatedAt))
+	if export.Model != "" {
+		fmt.Fprintf(&b, "- Модель: %s/%s\n", export.Provider, export.Model)
+	} else {
+		b.WriteString("- Модель: по умолчанию\n")
+	}
+	if export.ProjectID != nil {
+		fmt.Fprintf(&b, "- Проект: %s\n", export.ProjectID)
+	}
+	fmt.Fprintf(&b, "- Экспортирован: %s\n", formatExportTime(export.ExportedAt))
+
+	for _, message := range export.Messages {
+		heading := "Пользователь"
+		if message.Role == exportRoleAssistant {
+			heading = "Ассистент"
+			if message.Model != "" {
+				heading += " (" + message.Model + ")"
+			}
+		}
+		fmt.Fprintf(&b, "\n## %s — %s\n\n%s\n", heading, formatExportTime(message.CreatedAt), strings.TrimSpace(message.Text))
+
+		if len(message.Sources) > 0 {
+			b.WriteString("\nИсточники:\n")
+			for _, source := range message.Sources {
+				fmt.Fprintf(&b, "%d. [%s](%s), фрагмент %d\n", source.Index, source.FileName, source.Link, source.ChunkIndex+1)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func formatExportTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
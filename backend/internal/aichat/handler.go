@@ -1,21 +1,52 @@
 package aichat
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/llm"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projectfiles"
+	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/request"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+const (
+	maxConversationTitleRunes   = 200
+	maxConversationMessageRunes = 8000
+	// maxHistoryMessages is how many earlier messages are sent to the model.
+	maxHistoryMessages = 20
+)
+
 type Handler struct {
-	repo *Repository
+	repo         *Repository
+	projectsRepo *projects.Repository
+	filesRepo    *projectfiles.Repository
+	catalog      *llm.Catalog
+	embedder     llm.Embedder
+	usage        *UsageTracker
+	isAdmin      AdminFunc
 }
 
-func NewHandler(repo *Repository) *Handler {
-	return &Handler{repo: repo}
+// AdminFunc reports whether a user may manage the system prompts.
+type AdminFunc func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+// NewHandler creates the AI chat handler. catalog may be nil, in which case
+// the assistant endpoints answer 503; without an embedder answers are not
+// grounded in project documents.
+func NewHandler(repo *Repository, projectsRepo *projects.Repository, filesRepo *projectfiles.Repository, catalog *llm.Catalog, embedder llm.Embedder, usage *UsageTracker, isAdmin AdminFunc) *Handler {
+	return &Handler{repo: repo, projectsRepo: projectsRepo, filesRepo: filesRepo, catalog: catalog, embedder: embedder, usage: usage, isAdmin: isAdmin}
 }
 
 type createMessageRequest struct {
@@ -28,14 +59,14 @@ type createMessageRequest struct {
 func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	mode := r.URL.Query().Get("mode")
 	messages, err := h.repo.ListMessages(r.Context(), userID, mode)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch messages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch messages")
 		return
 	}
 
@@ -45,19 +76,19 @@ func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AppendMessage(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req createMessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	message, err := h.repo.AppendMessage(r.Context(), userID, req.Mode, req.Sender, req.Text, req.ProjectInfo)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to save message"})
+		problem.Error(w, http.StatusInternalServerError, "failed to save message")
 		return
 	}
 
@@ -67,19 +98,460 @@ func (h *Handler) AppendMessage(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ResetMessages(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	mode := r.URL.Query().Get("mode")
 	if err := h.repo.ResetMessages(r.Context(), userID, mode); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset messages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to reset messages")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type createConversationRequest struct {
+	Title     string  `json:"title"`
+	ProjectID *string `json:"projectId"`
+	Provider  string  `json:"provider"`
+	Model     string  `json:"model"`
+}
+
+type updateConversationRequest struct {
+	Title    *string `json:"title"`
+	Provider *string `json:"provider"`
+	Model    *string `json:"model"`
+}
+
+type modelsResponse struct {
+	Enabled bool            `json:"enabled"`
+	Models  []llm.ModelInfo `json:"models"`
+}
+
+type sendConversationMessageRequest struct {
+	Text string `json:"text"`
+}
+
+type conversationResponse struct {
+	Conversation
+	Messages []Message `json:"messages"`
+}
+
+type sendConversationMessageResponse struct {
+	Message Message `json:"message"`
+	Reply   Message `json:"reply"`
+}
+
+// ListModels returns the models users may pick for a conversation.
+func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
+	if _, ok := userIDFromRequest(r); !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	response := modelsResponse{Models: []llm.ModelInfo{}}
+	if h.catalog != nil && !h.catalog.Empty() {
+		response.Enabled = true
+		response.Models = h.catalog.Models()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	conversations, err := h.repo.ListConversations(r.Context(), userID)
+	if err != nil {
+		log.Printf("list ai conversations failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch conversations")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, conversations)
+}
+
+// CreateConversation starts a conversation, optionally bound to a project the
+// requester can access.
+func (h *Handler) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	var req createConversationRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > maxConversationTitleRunes {
+		problem.Write(w, http.StatusBadRequest, "title_too_long", "title is too long")
+		return
+	}
+	provider, model := strings.TrimSpace(req.Provider), strings.TrimSpace(req.Model)
+	if err := h.validateModel(provider, model); err != nil {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var projectID *uuid.UUID
+	if req.ProjectID != nil && strings.TrimSpace(*req.ProjectID) != "" {
+		parsed, err := uuid.Parse(strings.TrimSpace(*req.ProjectID))
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid projectId")
+			return
+		}
+		project, err := h.projectsRepo.GetByID(r.Context(), userID, parsed)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+				return
+			}
+			log.Printf("load project for ai conversation failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to create conversation")
+			return
+		}
+		projectID = &parsed
+		if title == "" {
+			title = project.Title
+		}
+	}
+
+	conversation, err := h.repo.CreateConversation(r.Context(), userID, title, projectID, provider, model)
+	if err != nil {
+		log.Printf("create ai conversation failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create conversation")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, conversation)
+}
+
+func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	_, conversation, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	messages, err := h.repo.ListConversationMessages(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("list ai conversation messages failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch messages")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, conversationResponse{Conversation: conversation, Messages: messages})
+}
+
+// UpdateConversation renames a conversation or changes its model. An empty
+// provider and model switch back to the server default.
+func (h *Handler) UpdateConversation(w http.ResponseWriter, r *http.Request) {
+	userID, conversation, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateConversationRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	title, provider, model := conversation.Title, conversation.Provider, conversation.Model
+	if req.Title != nil {
+		title = strings.TrimSpace(*req.Title)
+		if utf8.RuneCountInString(title) > maxConversationTitleRunes {
+			problem.Write(w, http.StatusBadRequest, "title_too_long", "title is too long")
+			return
+		}
+	}
+	if req.Provider != nil || req.Model != nil {
+		provider, model = "", ""
+		if req.Provider != nil {
+			provider = strings.TrimSpace(*req.Provider)
+		}
+		if req.Model != nil {
+			model = strings.TrimSpace(*req.Model)
+		}
+		if err := h.validateModel(provider, model); err != nil {
+			problem.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	updated, err := h.repo.UpdateConversation(r.Context(), userID, conversation.ID, title, provider, model)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "conversation_not_found", "conversation not found")
+			return
+		}
+		log.Printf("update ai conversation failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to update conversation")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) DeleteConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	conversationID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_conversation_id", "invalid conversation id")
+		return
+	}
+
+	if err := h.repo.DeleteConversation(r.Context(), userID, conversationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "conversation_not_found", "conversation not found")
+			return
+		}
+		log.Printf("delete ai conversation failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to delete conversation")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// SendConversationMessage stores the user's message, asks the model for a
+// reply and stores it too. For project-bound conversations the current state
+// of the project is put into the prompt; access to the project is checked on
+// every message, so members who were removed lose the context immediately.
+func (h *Handler) SendConversationMessage(w http.ResponseWriter, r *http.Request) {
+	userID, conversation, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	if h.catalog == nil || h.catalog.Empty() {
+		problem.Error(w, http.StatusServiceUnavailable, "ai assistant is not configured")
+		return
+	}
+
+	var req sendConversationMessageRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		problem.Write(w, http.StatusBadRequest, "text_required", "text is required")
+		return
+	}
+	if utf8.RuneCountInString(text) > maxConversationMessageRunes {
+		problem.Write(w, http.StatusBadRequest, "text_too_long", "text is too long")
+		return
+	}
+
+	if !h.checkQuota(w, r, userID) {
+		return
+	}
+
+	prompt := []llm.Message{{Role: llm.RoleSystem, Content: h.systemPrompt(r, PersonaAssistant)}}
+	var sources []Source
+	if conversation.ProjectID != nil {
+		projectContext, err := buildProjectContext(r.Context(), h.projectsRepo, userID, *conversation.ProjectID, time.Now())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				problem.Write(w, http.StatusForbidden, "project_not_accessible", "no access to the conversation's project")
+				return
+			}
+			log.Printf("build ai project context failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to load project context")
+			return
+		}
+		prompt = append(prompt, llm.Message{Role: llm.RoleSystem, Content: "Данные проекта:\n" + projectContext})
+
+		reference, err := buildToolReference(r.Context(), h.projectsRepo, userID, *conversation.ProjectID)
+		if err != nil {
+			log.Printf("build ai tool reference failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to load project context")
+			return
+		}
+		prompt = append(prompt, llm.Message{Role: llm.RoleSystem, Content: reference})
+
+		// Grounding is best effort: without it the assistant still answers
+		// from the project data.
+		documents, found, err := h.retrieveSources(r.Context(), userID, *conversation.ProjectID, text)
+		if err != nil {
+			log.Printf("retrieve ai sources failed: %v", err)
+		}
+		if documents != "" {
+			prompt = append(prompt, llm.Message{Role: llm.RoleSystem, Content: documents})
+			sources = found
+		}
+	}
+
+	history, err := h.repo.ListConversationMessages(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("list ai conversation messages failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch messages")
+		return
+	}
+	if len(history) > maxHistoryMessages {
+		history = history[len(history)-maxHistoryMessages:]
+	}
+	for _, message := range history {
+		role := llm.RoleUser
+		if message.Sender != senderUser {
+			role = llm.RoleAssistant
+		}
+		prompt = append(prompt, llm.Message{Role: role, Content: message.Text})
+	}
+	prompt = append(prompt, llm.Message{Role: llm.RoleUser, Content: text})
+
+	userMessage, err := h.repo.AddConversationMessage(r.Context(), conversation.ID, senderUser, text, "", nil)
+	if err != nil {
+		log.Printf("save ai conversation message failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to save message")
+		return
+	}
+
+	// A choice that was removed from the allowlist falls back to the default.
+	// Project-bound conversations may act on the project through tools.
+	request := llm.Request{
+		Provider: conversation.Provider,
+		Model:    conversation.Model,
+		Messages: prompt,
+	}
+	if conversation.ProjectID != nil {
+		request.Tools = projectTools
+	}
+	completion, actions, err := h.runTools(r.Context(), userID, conversation, request)
+	if err != nil {
+		log.Printf("ai completion failed: %v", err)
+		problem.Error(w, http.StatusBadGateway, "ai assistant is unavailable")
+		return
+	}
+
+	reply, err := h.repo.AddConversationMessage(r.Context(), conversation.ID, senderAssistant, completion.Content, completion.Model, sources)
+	if err != nil {
+		log.Printf("save ai reply failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to save message")
+		return
+	}
+	if len(actions) > 0 {
+		if err := h.repo.attachToolActions(r.Context(), actions, reply.ID); err != nil {
+			log.Printf("attach ai tool actions failed: %v", err)
+		}
+		for i := range actions {
+			actions[i].MessageID = &reply.ID
+		}
+		reply.Actions = actions
+	}
+
+	usage, err := h.usage.Record(r.Context(), userID, usageFeatureChat, &conversation.ID, &reply.ID, completion)
+	if err != nil {
+		log.Printf("record ai usage failed: %v", err)
+	} else {
+		reply.Usage = &usage
+	}
+
+	writeJSON(w, http.StatusCreated, sendConversationMessageResponse{Message: userMessage, Reply: reply})
+}
+
+// Usage returns the requester's AI usage and quotas for the current month or
+// for ?month=YYYY-MM.
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	month := time.Now()
+	if raw := strings.TrimSpace(r.URL.Query().Get("month")); raw != "" {
+		parsed, err := time.Parse("2006-01", raw)
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, "invalid_month", "month must be YYYY-MM")
+			return
+		}
+		month = parsed
+	}
+
+	summary, err := h.usage.Summary(r.Context(), userID, month)
+	if err != nil {
+		log.Printf("get ai usage failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load usage")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// checkQuota answers 429 and returns false when userID has used up a monthly
+// AI quota.
+func (h *Handler) checkQuota(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	err := h.usage.CheckQuota(r.Context(), userID)
+	if err == nil {
+		return true
+	}
+
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		writeQuotaError(w, quotaErr)
+		return false
+	}
+	log.Printf("check ai quota failed: %v", err)
+	problem.Error(w, http.StatusInternalServerError, "failed to check usage quota")
+	return false
+}
+
+// validateModel accepts an empty choice (server default) or an allowlisted
+// provider/model pair.
+func (h *Handler) validateModel(provider, model string) error {
+	if provider == "" && model == "" {
+		return nil
+	}
+	if provider == "" || model == "" {
+		return errors.New("provider and model must be set together")
+	}
+	if h.catalog == nil || !h.catalog.Allowed(provider, model) {
+		return errors.New("model is not available")
+	}
+	return nil
+}
+
+func (h *Handler) loadConversation(w http.ResponseWriter, r *http.Request) (uuid.UUID, Conversation, bool) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, Conversation{}, false
+	}
+
+	conversationID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_conversation_id", "invalid conversation id")
+		return uuid.Nil, Conversation{}, false
+	}
+
+	conversation, err := h.repo.GetConversation(r.Context(), userID, conversationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "conversation_not_found", "conversation not found")
+			return uuid.Nil, Conversation{}, false
+		}
+		log.Printf("get ai conversation failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load conversation")
+		return uuid.Nil, Conversation{}, false
+	}
+
+	return userID, conversation, true
+}
+
 func userIDFromRequest(r *http.Request) (uuid.UUID, bool) {
 	userIDStr, ok := auth.UserIDFromContext(r.Context())
 	if !ok || strings.TrimSpace(userIDStr) == "" {
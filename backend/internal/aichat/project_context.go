@@ -0,0 +1,160 @@
+package aichat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tm-platform-backend/internal/projects"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxContextOverdueTasks = 30
+	maxContextDelayReports = 5
+	maxContextReportRunes  = 400
+)
+
+const assistantSystemPrompt = `Ты — ассистент платформы управления строительными проектами. ` +
+	`Отвечай по-русски, кратко и по делу. ` +
+	`Если к разговору привязан проект, опирайся только на переданные данные проекта; ` +
+	`если данных недостаточно, прямо скажи об этом и не выдумывай факты.`
+
+// buildProjectContext describes the current state of a project for the
+// assistant: stages with their progress, overdue tasks, the budget and the
+// latest delay reports. It fails with sql.ErrNoRows when userID has no access
+// to the project.
+func buildProjectContext(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID, now time.Time) (string, error) {
+	project, err := repo.GetByID(ctx, userID, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Проект: %s\n", project.Title)
+	fmt.Fprintf(&b, "Статус: %s\n", project.Status)
+	if project.StartDate != nil {
+		fmt.Fprintf(&b, "Начало: %s\n", formatContextDate(*project.StartDate))
+	}
+	if project.Deadline != nil {
+		fmt.Fprintf(&b, "Дедлайн: %s\n", formatContextDate(*project.Deadline))
+	}
+	fmt.Fprintf(&b, "Сегодня: %s\n", formatContextDate(now))
+
+	fmt.Fprintf(
+		&b,
+		"\nБюджет: всего %d, потрачено %d, остаток %d (использовано %.0f%%)\n",
+		project.TotalBudget,
+		project.SpentBudget,
+		project.RemainingBudget,
+		project.ProgressPercent,
+	)
+
+	stages, err := repo.ListStagesByProject(ctx, userID, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	type overdueTask struct {
+		stage string
+		task  projects.Task
+	}
+	overdue := make([]overdueTask, 0)
+
+	b.WriteString("\nЭтапы:\n")
+	if len(stages) == 0 {
+		b.WriteString("- этапов нет\n")
+	}
+	for _, stage := range stages {
+		tasks, err := repo.ListTasksByStage(ctx, userID, stage.ID)
+		if err != nil {
+			return "", err
+		}
+
+		done, delayed, late := 0, 0, 0
+		for _, task := range tasks {
+			switch task.Status {
+			case "done":
+				done++
+				continue
+			case "delayed":
+				delayed++
+			}
+			if task.Deadline != nil && task.Deadline.Before(now) {
+				late++
+				overdue = append(overdue, overdueTask{stage: stage.Title, task: task})
+			}
+		}
+		fmt.Fprintf(&b, "- %s: задач %d, выполнено %d, задержано %d, просрочено %d\n", stage.Title, len(tasks), done, delayed, late)
+	}
+
+	sort.Slice(overdue, func(i, j int) bool {
+		return overdue[i].task.Deadline.Before(*overdue[j].task.Deadline)
+	})
+	b.WriteString("\nПросроченные задачи:\n")
+	if len(overdue) == 0 {
+		b.WriteString("- нет\n")
+	}
+	for i, item := range overdue {
+		if i == maxContextOverdueTasks {
+			fmt.Fprintf(&b, "- и ещё %d\n", len(overdue)-i)
+			break
+		}
+		fmt.Fprintf(
+			&b,
+			"- %s (этап «%s», дедлайн %s, статус %s)\n",
+			item.task.Title,
+			item.stage,
+			formatContextDate(*item.task.Deadline),
+			item.task.Status,
+		)
+	}
+
+	// Delay reports are visible to project members only; users who reach the
+	// project through the hierarchy get the rest of the context without them.
+	reports, err := repo.ListDelayReports(ctx, userID, projectID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	if err == nil {
+		sort.Slice(reports, func(i, j int) bool {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		})
+		b.WriteString("\nПоследние отчёты о задержках:\n")
+		if len(reports) == 0 {
+			b.WriteString("- нет\n")
+		}
+		for i, report := range reports {
+			if i == maxContextDelayReports {
+				break
+			}
+			fmt.Fprintf(
+				&b,
+				"- %s, %s: %s\n",
+				formatContextDate(report.CreatedAt),
+				report.Author.Email,
+				truncateRunes(strings.Join(strings.Fields(report.Message), " "), maxContextReportRunes),
+			)
+		}
+	}
+
+	return b.String(), nil
+}
+
+func formatContextDate(t time.Time) string {
+	return t.Format("02.01.2006")
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit]) + "…"
+}
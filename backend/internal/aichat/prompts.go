@@ -0,0 +1,502 @@
+package aichat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Personas are the assistant roles whose system prompt can be replaced by
+// admins.
+const (
+	PersonaAssistant       = "assistant"
+	PersonaProjectSummary  = "project_summary"
+	PersonaTaskSuggestions = "task_suggestions"
+)
+
+const (
+	defaultPromptLocale = "ru"
+	maxPromptRunes      = 20000
+)
+
+// builtinPrompts are used when no version of a persona is active in the
+// database. They are written in the default locale.
+var builtinPrompts = map[string]string{
+	PersonaAssistant:       assistantSystemPrompt,
+	PersonaProjectSummary:  summarySystemPrompt,
+	PersonaTaskSuggestions: suggestionsSystemPrompt,
+}
+
+var promptLocalePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// SystemPrompt is one stored version of a persona's prompt for a locale.
+type SystemPrompt struct {
+	ID        uuid.UUID  `json:"id"`
+	Persona   string     `json:"persona"`
+	Locale    string     `json:"locale"`
+	Version   int        `json:"version"`
+	Content   string     `json:"content"`
+	Active    bool       `json:"active"`
+	CreatedBy *uuid.UUID `json:"createdBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// PersonaPrompts lists the prompts in effect for a persona: the built-in one
+// and the active stored version of each locale.
+type PersonaPrompts struct {
+	Persona string         `json:"persona"`
+	Builtin string         `json:"builtin"`
+	Active  []SystemPrompt `json:"active"`
+}
+
+type createPromptRequest struct {
+	Content  string `json:"content"`
+	Activate *bool  `json:"activate"`
+}
+
+// systemPrompt returns the prompt of persona for the request's locale. It
+// tries the requested locale, then the default locale, then the built-in
+// prompt; lookup failures are logged and fall back to the built-in prompt.
+func (h *Handler) systemPrompt(r *http.Request, persona string) string {
+	for _, locale := range requestLocales(r) {
+		prompt, err := h.repo.ActivePrompt(r.Context(), persona, locale)
+		if err == nil {
+			return prompt.Content
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("load ai system prompt failed: %v", err)
+			break
+		}
+	}
+	return builtinPrompts[persona]
+}
+
+// requestLocales returns the locales to try for r, most preferred first:
+// the "locale" query parameter or the first Accept-Language tag (with and
+// without its region), followed by the default locale.
+func requestLocales(r *http.Request) []string {
+	raw := strings.TrimSpace(r.URL.Query().Get("locale"))
+	if raw == "" {
+		raw, _, _ = strings.Cut(r.Header.Get("Accept-Language"), ",")
+		raw, _, _ = strings.Cut(raw, ";")
+	}
+
+	locales := make([]string, 0, 3)
+	if locale, ok := normalizeLocale(raw); ok {
+		locales = append(locales, locale)
+		if language, _, hasRegion := strings.Cut(locale, "-"); hasRegion {
+			locales = append(locales, language)
+		}
+	}
+	if len(locales) == 0 || locales[len(locales)-1] != defaultPromptLocale {
+		locales = append(locales, defaultPromptLocale)
+	}
+	return locales
+}
+
+func normalizeLocale(raw string) (string, bool) {
+	locale := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), "_", "-"))
+	return locale, promptLocalePattern.MatchString(locale)
+}
+
+// ListPrompts returns every persona with its built-in prompt and the active
+// stored versions. Admins only.
+func (h *Handler) ListPrompts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requirePromptAdmin(w, r); !ok {
+		return
+	}
+
+	active, err := h.repo.ListActivePrompts(r.Context())
+	if err != nil {
+		log.Printf("list ai system prompts failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch prompts")
+		return
+	}
+
+	personas := make([]string, 0, len(builtinPrompts))
+	for persona := range builtinPrompts {
+		personas = append(personas, persona)
+	}
+	sort.Strings(personas)
+
+	response := make([]PersonaPrompts, 0, len(personas))
+	for _, persona := range personas {
+		item := PersonaPrompts{Persona: persona, Builtin: builtinPrompts[persona], Active: make([]SystemPrompt, 0)}
+		for _, prompt := range active {
+			if prompt.Persona == persona {
+				item.Active = append(item.Active, prompt)
+			}
+		}
+		response = append(response, item)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ListPromptVersions returns every stored version of a persona's prompt for
+// a locale, newest first. Admins only.
+func (h *Handler) ListPromptVersions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requirePromptAdmin(w, r); !ok {
+		return
+	}
+	persona, locale, ok := promptTarget(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.repo.ListPromptVersions(r.Context(), persona, locale)
+	if err != nil {
+		log.Printf("list ai system prompt versions failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch prompts")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// CreatePromptVersion stores a new version of a persona's prompt for a
+// locale. It becomes active right away unless "activate" is false. Admins
+// only.
+func (h *Handler) CreatePromptVersion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requirePromptAdmin(w, r)
+	if !ok {
+		return
+	}
+	persona, locale, ok := promptTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req createPromptRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		problem.Write(w, http.StatusBadRequest, "content_required", "content is required")
+		return
+	}
+	if utf8.RuneCountInString(content) > maxPromptRunes {
+		problem.Write(w, http.StatusBadRequest, "content_too_long", "content is too long")
+		return
+	}
+	activate := req.Activate == nil || *req.Activate
+
+	prompt, err := h.repo.CreatePromptVersion(r.Context(), persona, locale, content, userID, activate)
+	if err != nil {
+		log.Printf("create ai system prompt failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to save prompt")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, prompt)
+}
+
+// ActivatePromptVersion makes an earlier version of a persona's prompt the
+// active one, e.g. to roll back a change. Admins only.
+func (h *Handler) ActivatePromptVersion(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requirePromptAdmin(w, r); !ok {
+		return
+	}
+	persona, locale, ok := promptTarget(w, r)
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(chi.URLParam(r, "version")))
+	if err != nil || version < 1 {
+		problem.Write(w, http.StatusBadRequest, "invalid_version", "invalid version")
+		return
+	}
+
+	prompt, err := h.repo.ActivatePromptVersion(r.Context(), persona, locale, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "prompt_version_not_found", "prompt version not found")
+			return
+		}
+		log.Printf("activate ai system prompt failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to activate prompt")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prompt)
+}
+
+// ResetPrompt deactivates the stored versions of a persona's prompt for a
+// locale, so the next locale in line or the built-in prompt is used again.
+// Admins only.
+func (h *Handler) ResetPrompt(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requirePromptAdmin(w, r); !ok {
+		return
+	}
+	persona, locale, ok := promptTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeactivatePrompt(r.Context(), persona, locale); err != nil {
+		log.Printf("reset ai system prompt failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to reset prompt")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) requirePromptAdmin(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+
+	allowed, err := h.isAdmin(r.Context(), userID)
+	if err != nil {
+		log.Printf("check ai prompt admin failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check permissions")
+		return uuid.Nil, false
+	}
+	if !allowed {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+func promptTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	persona := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "persona")))
+	if _, known := builtinPrompts[persona]; !known {
+		problem.Write(w, http.StatusNotFound, "unknown_persona", "unknown persona")
+		return "", "", false
+	}
+
+	locale, ok := normalizeLocale(chi.URLParam(r, "locale"))
+	if !ok {
+		problem.Write(w, http.StatusBadRequest, "invalid_locale", "invalid locale")
+		return "", "", false
+	}
+
+	return persona, locale, true
+}
+
+const systemPromptColumns = `id, persona, locale, version, content, active, created_by, created_at`
+
+// ActivePrompt returns the active version of persona's prompt for locale, or
+// sql.ErrNoRows when there is none.
+func (r *Repository) ActivePrompt(ctx context.Context, persona, locale string) (SystemPrompt, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return SystemPrompt{}, err
+	}
+
+	return scanSystemPrompt(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+systemPromptColumns+`
+		 FROM ai_system_prompts
+		 WHERE persona = $1 AND locale = $2 AND active`,
+		persona,
+		locale,
+	))
+}
+
+func (r *Repository) ListActivePrompts(ctx context.Context) ([]SystemPrompt, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.querySystemPrompts(
+		ctx,
+		`SELECT `+systemPromptColumns+`
+		 FROM ai_system_prompts
+		 WHERE active
+		 ORDER BY persona, locale`,
+	)
+}
+
+func (r *Repository) ListPromptVersions(ctx context.Context, persona, locale string) ([]SystemPrompt, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.querySystemPrompts(
+		ctx,
+		`SELECT `+systemPromptColumns+`
+		 FROM ai_system_prompts
+		 WHERE persona = $1 AND locale = $2
+		 ORDER BY version DESC`,
+		persona,
+		locale,
+	)
+}
+
+// CreatePromptVersion stores content as the next version of persona's prompt
+// for locale and, when activate is set, makes it the active one.
+func (r *Repository) CreatePromptVersion(ctx context.Context, persona, locale, content string, createdBy uuid.UUID, activate bool) (prompt SystemPrompt, err error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return SystemPrompt{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SystemPrompt{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if activate {
+		if _, err = tx.ExecContext(
+			ctx,
+			`UPDATE ai_system_prompts SET active = false WHERE persona = $1 AND locale = $2 AND active`,
+			persona,
+			locale,
+		); err != nil {
+			return SystemPrompt{}, err
+		}
+	}
+
+	prompt, err = scanSystemPrompt(tx.QueryRowContext(
+		ctx,
+		`INSERT INTO ai_system_prompts (id, persona, locale, version, content, active, created_by)
+		 SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5, $6
+		 FROM ai_system_prompts
+		 WHERE persona = $2 AND locale = $3
+		 RETURNING `+systemPromptColumns,
+		uuid.New(),
+		persona,
+		locale,
+		content,
+		activate,
+		createdBy,
+	))
+	if err != nil {
+		return SystemPrompt{}, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return SystemPrompt{}, err
+	}
+
+	return prompt, nil
+}
+
+// ActivatePromptVersion makes version the active prompt of persona for
+// locale. It returns sql.ErrNoRows when the version does not exist.
+func (r *Repository) ActivatePromptVersion(ctx context.Context, persona, locale string, version int) (prompt SystemPrompt, err error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return SystemPrompt{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SystemPrompt{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`UPDATE ai_system_prompts SET active = false WHERE persona = $1 AND locale = $2 AND active`,
+		persona,
+		locale,
+	); err != nil {
+		return SystemPrompt{}, err
+	}
+
+	prompt, err = scanSystemPrompt(tx.QueryRowContext(
+		ctx,
+		`UPDATE ai_system_prompts
+		 SET active = true
+		 WHERE persona = $1 AND locale = $2 AND version = $3
+		 RETURNING `+systemPromptColumns,
+		persona,
+		locale,
+		version,
+	))
+	if err != nil {
+		return SystemPrompt{}, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return SystemPrompt{}, err
+	}
+
+	return prompt, nil
+}
+
+func (r *Repository) DeactivatePrompt(ctx context.Context, persona, locale string) error {
+	if err := r.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE ai_system_prompts SET active = false WHERE persona = $1 AND locale = $2 AND active`,
+		persona,
+		locale,
+	)
+	return err
+}
+
+func (r *Repository) querySystemPrompts(ctx context.Context, query string, args ...any) ([]SystemPrompt, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prompts := make([]SystemPrompt, 0)
+	for rows.Next() {
+		prompt, err := scanSystemPrompt(rows)
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, rows.Err()
+}
+
+func scanSystemPrompt(row conversationScanner) (SystemPrompt, error) {
+	var (
+		prompt    SystemPrompt
+		createdBy uuid.NullUUID
+	)
+	if err := row.Scan(
+		&prompt.ID,
+		&prompt.Persona,
+		&prompt.Locale,
+		&prompt.Version,
+		&prompt.Content,
+		&prompt.Active,
+		&createdBy,
+		&prompt.CreatedAt,
+	); err != nil {
+		return SystemPrompt{}, err
+	}
+	if createdBy.Valid {
+		prompt.CreatedBy = &createdBy.UUID
+	}
+	return prompt, nil
+}
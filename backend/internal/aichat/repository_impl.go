@@ -47,6 +47,75 @@ CREATE INDEX IF NOT EXISTS idx_ai_chat_messages_thread_created
 
 CREATE INDEX IF NOT EXISTS idx_ai_chat_threads_user_updated
 	ON ai_chat_threads(user_id, updated_at DESC);
+
+ALTER TABLE ai_chat_threads
+	ADD COLUMN IF NOT EXISTS title TEXT,
+	ADD COLUMN IF NOT EXISTS project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS provider TEXT,
+	ADD COLUMN IF NOT EXISTS model TEXT;
+
+ALTER TABLE ai_chat_messages
+	ADD COLUMN IF NOT EXISTS model TEXT,
+	ADD COLUMN IF NOT EXISTS sources JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_ai_chat_threads_project
+	ON ai_chat_threads(project_id)
+	WHERE project_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	feature TEXT NOT NULL,
+	thread_id UUID REFERENCES ai_chat_threads(id) ON DELETE SET NULL,
+	message_id UUID REFERENCES ai_chat_messages(id) ON DELETE SET NULL,
+	provider TEXT NOT NULL DEFAULT '',
+	model TEXT NOT NULL DEFAULT '',
+	prompt_tokens INT NOT NULL DEFAULT 0,
+	completion_tokens INT NOT NULL DEFAULT 0,
+	cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_user_created
+	ON ai_usage(user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_message
+	ON ai_usage(message_id)
+	WHERE message_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS ai_system_prompts (
+	id UUID PRIMARY KEY,
+	persona TEXT NOT NULL,
+	locale TEXT NOT NULL,
+	version INT NOT NULL,
+	content TEXT NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT false,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (persona, locale, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ai_system_prompts_active
+	ON ai_system_prompts(persona, locale)
+	WHERE active;
+
+CREATE TABLE IF NOT EXISTS ai_tool_actions (
+	id UUID PRIMARY KEY,
+	thread_id UUID NOT NULL REFERENCES ai_chat_threads(id) ON DELETE CASCADE,
+	message_id UUID REFERENCES ai_chat_messages(id) ON DELETE SET NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	tool TEXT NOT NULL,
+	arguments JSONB NOT NULL,
+	status TEXT NOT NULL,
+	result JSONB,
+	error TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_tool_actions_thread
+	ON ai_tool_actions(thread_id, created_at);
 `)
 	})
 
@@ -59,6 +128,10 @@ type Message struct {
 	Sender      string          `json:"sender"`
 	Text        string          `json:"text"`
 	ProjectInfo json.RawMessage `json:"projectInfo,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Sources     []Source        `json:"sources,omitempty"`
+	Usage       *MessageUsage   `json:"usage,omitempty"`
+	Actions     []ToolAction    `json:"actions,omitempty"`
 	CreatedAt   time.Time       `json:"createdAt"`
 }
 
@@ -0,0 +1,73 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxRetrievedChunks = 5
+	// minChunkScore drops passages that are only loosely related to the
+	// question, so unrelated documents are not cited.
+	minChunkScore         = 0.3
+	maxSourceExcerptRunes = 300
+)
+
+// Source is a document passage the assistant was given to answer a message.
+type Source struct {
+	Index      int       `json:"index"`
+	FileID     uuid.UUID `json:"fileId"`
+	FileName   string    `json:"fileName"`
+	ChunkIndex int       `json:"chunkIndex"`
+	Excerpt    string    `json:"excerpt"`
+	Link       string    `json:"link"`
+}
+
+// retrieveSources finds the project document passages most relevant to
+// question among the files userID may read. It returns the prompt section
+// quoting them and the matching sources, numbered as in the prompt.
+func (h *Handler) retrieveSources(ctx context.Context, userID, projectID uuid.UUID, question string) (string, []Source, error) {
+	if h.embedder == nil || h.filesRepo == nil {
+		return "", nil, nil
+	}
+
+	vectors, err := h.embedder.Embed(ctx, []string{question})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(vectors) != 1 {
+		return "", nil, fmt.Errorf("embedder returned %d vectors", len(vectors))
+	}
+
+	matches, err := h.filesRepo.SearchChunks(ctx, userID, projectID, vectors[0], maxRetrievedChunks)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	sources := make([]Source, 0, len(matches))
+	for _, match := range matches {
+		if match.Score < minChunkScore {
+			break
+		}
+		source := Source{
+			Index:      len(sources) + 1,
+			FileID:     match.FileID,
+			FileName:   match.FileName,
+			ChunkIndex: match.ChunkIndex,
+			Excerpt:    truncateRunes(match.Content, maxSourceExcerptRunes),
+			Link:       fmt.Sprintf("/project/%s?fileId=%s", projectID, match.FileID),
+		}
+		sources = append(sources, source)
+		fmt.Fprintf(&b, "[%d] «%s», фрагмент %d:\n%s\n\n", source.Index, source.FileName, source.ChunkIndex+1, match.Content)
+	}
+	if len(sources) == 0 {
+		return "", nil, nil
+	}
+
+	return "Фрагменты документов проекта. Ссылайся на них в ответе номерами в квадратных скобках, например [1]:\n\n" +
+		strings.TrimSpace(b.String()), sources, nil
+}
@@ -0,0 +1,423 @@
+package aichat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/llm"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	usageFeatureTaskSuggestions = "task_suggestions"
+
+	maxSuggestionReports   = 20
+	maxSuggestionThreads   = 5
+	maxTaskSuggestions     = 10
+	maxAcceptedSuggestions = 20
+	maxSuggestionTitleLen  = 200
+)
+
+const suggestionsSystemPrompt = `Ты помогаешь руководителю строительного проекта разбирать отчёты о задержках. ` +
+	`По открытым отчётам и нерешённым обсуждениям предложи конкретные задачи, которые снимут задержку. ` +
+	`Используй только переданные данные. Ответь строго одним JSON-объектом без пояснений и без markdown ` +
+	`вида {"suggestions":[{"title":"...","description":"...","stage":"S1","assigneeEmail":"...","reason":"...","report":"R1"}]}. ` +
+	`"stage" и "report" — метки из данных, "assigneeEmail" — email участника проекта или пустая строка. ` +
+	`Не больше 10 задач, не повторяй уже существующие. Пиши по-русски.`
+
+// TaskSuggestion is a follow-up task proposed by the model. StageID and
+// ReportID always refer to the project; AssigneeEmail is empty or a member.
+type TaskSuggestion struct {
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	StageID       uuid.UUID  `json:"stageId"`
+	StageTitle    string     `json:"stageTitle"`
+	AssigneeEmail string     `json:"assigneeEmail,omitempty"`
+	Reason        string     `json:"reason"`
+	ReportID      *uuid.UUID `json:"reportId,omitempty"`
+}
+
+type taskSuggestionsResponse struct {
+	Suggestions []TaskSuggestion `json:"suggestions"`
+	Model       string           `json:"model"`
+	Usage       MessageUsage     `json:"usage"`
+}
+
+type acceptSuggestionsRequest struct {
+	Tasks []struct {
+		Title         string `json:"title"`
+		StageID       string `json:"stageId"`
+		AssigneeEmail string `json:"assigneeEmail"`
+		Deadline      string `json:"deadline"`
+	} `json:"tasks"`
+}
+
+// suggestionContext holds the labels the model refers to, so that its answer
+// can be mapped back to real stages, reports and members.
+type suggestionContext struct {
+	text    string
+	stages  map[string]projects.Stage
+	reports map[string]uuid.UUID
+	members map[string]struct{}
+}
+
+// modelTaskSuggestion is one item of the model's JSON answer.
+type modelTaskSuggestion struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	Stage         string `json:"stage"`
+	AssigneeEmail string `json:"assigneeEmail"`
+	Reason        string `json:"reason"`
+	Report        string `json:"report"`
+}
+
+// TaskSuggestions proposes follow-up tasks from the project's open delay
+// reports and their unanswered comments. Nothing is created: the manager
+// picks suggestions and sends them to AcceptTaskSuggestions.
+func (h *Handler) TaskSuggestions(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := h.loadManagedProject(w, r)
+	if !ok {
+		return
+	}
+
+	if h.catalog == nil || h.catalog.Empty() {
+		problem.Error(w, http.StatusServiceUnavailable, "ai assistant is not configured")
+		return
+	}
+
+	suggestionCtx, err := buildSuggestionContext(r.Context(), h.projectsRepo, userID, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+			return
+		}
+		log.Printf("build ai task suggestions context failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load project data")
+		return
+	}
+	if len(suggestionCtx.reports) == 0 || len(suggestionCtx.stages) == 0 {
+		writeJSON(w, http.StatusOK, taskSuggestionsResponse{Suggestions: []TaskSuggestion{}})
+		return
+	}
+
+	if !h.checkQuota(w, r, userID) {
+		return
+	}
+
+	completion, err := h.catalog.Complete(r.Context(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: h.systemPrompt(r, PersonaTaskSuggestions)},
+			{Role: llm.RoleUser, Content: suggestionCtx.text},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		log.Printf("ai task suggestions completion failed: %v", err)
+		problem.Error(w, http.StatusBadGateway, "ai assistant is unavailable")
+		return
+	}
+
+	usage, err := h.usage.Record(r.Context(), userID, usageFeatureTaskSuggestions, nil, nil, completion)
+	if err != nil {
+		log.Printf("record ai usage failed: %v", err)
+	}
+
+	suggestions, err := parseTaskSuggestions(completion.Content, suggestionCtx)
+	if err != nil {
+		log.Printf("parse ai task suggestions failed: %v", err)
+		problem.Error(w, http.StatusBadGateway, "ai assistant returned invalid suggestions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, taskSuggestionsResponse{
+		Suggestions: suggestions,
+		Model:       completion.Model,
+		Usage:       usage,
+	})
+}
+
+// AcceptTaskSuggestions creates the chosen suggestions as stage tasks. Either
+// every task is created or none is.
+func (h *Handler) AcceptTaskSuggestions(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := h.loadManagedProject(w, r)
+	if !ok {
+		return
+	}
+
+	var req acceptSuggestionsRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	if len(req.Tasks) == 0 {
+		problem.Write(w, http.StatusBadRequest, "tasks_required", "tasks are required")
+		return
+	}
+	if len(req.Tasks) > maxAcceptedSuggestions {
+		problem.Error(w, http.StatusBadRequest, fmt.Sprintf("cannot accept more than %d tasks at once", maxAcceptedSuggestions))
+		return
+	}
+
+	loc, err := h.projectsRepo.ProjectLocation(r.Context(), projectID)
+	if err != nil {
+		log.Printf("AcceptTaskSuggestions load time zone failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create tasks")
+		return
+	}
+
+	tasks := make([]projects.NewTask, 0, len(req.Tasks))
+	for _, item := range req.Tasks {
+		stageID, err := uuid.Parse(strings.TrimSpace(item.StageID))
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
+			return
+		}
+
+		task := projects.NewTask{StageID: stageID, Title: item.Title}
+		if email := strings.TrimSpace(item.AssigneeEmail); email != "" {
+			task.Assignees = []string{email}
+		}
+		if deadline := strings.TrimSpace(item.Deadline); deadline != "" {
+			parsed, err := time.Parse("2006-01-02", deadline)
+			if err != nil {
+				problem.Write(w, http.StatusBadRequest, "invalid_deadline", "invalid deadline")
+				return
+			}
+			parsed = projects.EndOfDay(parsed, loc)
+			task.Deadline = &parsed
+		}
+		tasks = append(tasks, task)
+	}
+
+	created, err := h.projectsRepo.CreateTasks(r.Context(), userID, projectID, tasks)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can create tasks")
+		case errors.Is(err, projects.ErrStageNotInProject),
+			errors.Is(err, projects.ErrAssigneeNotMember),
+			errors.Is(err, projects.ErrTaskTitleRequired):
+			problem.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("accept ai task suggestions failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to create tasks")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"tasks": created})
+}
+
+// loadManagedProject resolves the project of the request and checks that the
+// requester is one of its owners or managers.
+func (h *Handler) loadManagedProject(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	project, err := h.projectsRepo.GetByID(r.Context(), userID, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+			return uuid.Nil, uuid.Nil, false
+		}
+		log.Printf("load project for ai task suggestions failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load project data")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if project.CurrentUserRole != projects.ProjectMemberRoleOwner && project.CurrentUserRole != projects.ProjectMemberRoleManager {
+		problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can manage task suggestions")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, projectID, true
+}
+
+// buildSuggestionContext lists the project's stages, members and open delay
+// reports (those whose task is not done yet) with the comments nobody has
+// answered. Stages and reports are labelled S1.., R1.. for the model.
+func buildSuggestionContext(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (suggestionContext, error) {
+	result := suggestionContext{
+		stages:  make(map[string]projects.Stage),
+		reports: make(map[string]uuid.UUID),
+		members: make(map[string]struct{}),
+	}
+	var b strings.Builder
+
+	stages, err := repo.ListStagesByProject(ctx, userID, projectID)
+	if err != nil {
+		return suggestionContext{}, err
+	}
+	stageTitles := make(map[uuid.UUID]string, len(stages))
+	taskStatus := make(map[uuid.UUID]string)
+	taskTitles := make(map[uuid.UUID]string)
+
+	b.WriteString("Этапы:\n")
+	for i, stage := range stages {
+		label := "S" + strconv.Itoa(i+1)
+		result.stages[label] = stage
+		stageTitles[stage.ID] = stage.Title
+
+		tasks, err := repo.ListTasksByStage(ctx, userID, stage.ID)
+		if err != nil {
+			return suggestionContext{}, err
+		}
+		titles := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			taskStatus[task.ID] = task.Status
+			taskTitles[task.ID] = task.Title
+			titles = append(titles, task.Title)
+		}
+		fmt.Fprintf(&b, "- %s: %s", label, stage.Title)
+		if len(titles) > 0 {
+			fmt.Fprintf(&b, " (задачи: %s)", truncateRunes(strings.Join(titles, "; "), maxContextReportRunes))
+		}
+		b.WriteString("\n")
+	}
+
+	members, err := repo.ListMembersByProject(ctx, userID, projectID)
+	if err != nil {
+		return suggestionContext{}, err
+	}
+	b.WriteString("\nУчастники:\n")
+	for _, member := range members {
+		email := strings.ToLower(strings.TrimSpace(member.User.Email))
+		if email == "" {
+			continue
+		}
+		result.members[email] = struct{}{}
+		fmt.Fprintf(&b, "- %s (%s)\n", email, member.Role)
+	}
+
+	reports, err := repo.ListDelayReports(ctx, userID, projectID)
+	if err != nil {
+		return suggestionContext{}, err
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+
+	b.WriteString("\nОткрытые отчёты о задержках:\n")
+	for _, report := range reports {
+		if len(result.reports) == maxSuggestionReports {
+			break
+		}
+		if report.TaskID != nil && taskStatus[*report.TaskID] == "done" {
+			continue
+		}
+
+		label := "R" + strconv.Itoa(len(result.reports)+1)
+		result.reports[label] = report.ID
+
+		fmt.Fprintf(&b, "- %s, %s, %s", label, formatContextDate(report.CreatedAt), report.Author.Email)
+		if report.StageID != nil {
+			if title, ok := stageTitles[*report.StageID]; ok {
+				fmt.Fprintf(&b, ", этап «%s»", title)
+			}
+		}
+		if report.TaskID != nil {
+			if title, ok := taskTitles[*report.TaskID]; ok {
+				fmt.Fprintf(&b, ", задача «%s»", title)
+			}
+		}
+		fmt.Fprintf(&b, ": %s\n", truncateRunes(strings.Join(strings.Fields(report.Message), " "), maxContextReportRunes))
+
+		if report.CommentsCount == 0 {
+			continue
+		}
+		comments, err := repo.ListDelayReportComments(ctx, userID, projectID, report.ID)
+		if err != nil {
+			return suggestionContext{}, err
+		}
+		threads := 0
+		for _, comment := range comments {
+			if comment.ParentID != nil || comment.ReplyCount > 0 || threads == maxSuggestionThreads {
+				continue
+			}
+			threads++
+			fmt.Fprintf(
+				&b,
+				"  • без ответа, %s: %s\n",
+				comment.Author.Email,
+				truncateRunes(strings.Join(strings.Fields(comment.Message), " "), maxContextReportRunes),
+			)
+		}
+	}
+	if len(result.reports) == 0 {
+		b.WriteString("- нет\n")
+	}
+
+	result.text = b.String()
+	return result, nil
+}
+
+// parseTaskSuggestions decodes the model's JSON answer and keeps only the
+// suggestions that point at a known stage; unknown reports and assignees
+// outside the project are dropped from the suggestion.
+func parseTaskSuggestions(content string, suggestionCtx suggestionContext) ([]TaskSuggestion, error) {
+	content = strings.TrimSpace(content)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	var answer struct {
+		Suggestions []modelTaskSuggestion `json:"suggestions"`
+	}
+	if err := json.Unmarshal([]byte(content), &answer); err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]TaskSuggestion, 0, len(answer.Suggestions))
+	for _, item := range answer.Suggestions {
+		if len(suggestions) == maxTaskSuggestions {
+			break
+		}
+
+		title := truncateRunes(strings.TrimSpace(item.Title), maxSuggestionTitleLen)
+		stage, ok := suggestionCtx.stages[strings.ToUpper(strings.TrimSpace(item.Stage))]
+		if title == "" || !ok {
+			continue
+		}
+
+		suggestion := TaskSuggestion{
+			Title:       title,
+			Description: strings.TrimSpace(item.Description),
+			StageID:     stage.ID,
+			StageTitle:  stage.Title,
+			Reason:      strings.TrimSpace(item.Reason),
+		}
+		if email := strings.ToLower(strings.TrimSpace(item.AssigneeEmail)); email != "" {
+			if _, member := suggestionCtx.members[email]; member {
+				suggestion.AssigneeEmail = email
+			}
+		}
+		if reportID, ok := suggestionCtx.reports[strings.ToUpper(strings.TrimSpace(item.Report))]; ok {
+			suggestion.ReportID = &reportID
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, nil
+}
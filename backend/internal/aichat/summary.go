@@ -0,0 +1,317 @@
+package aichat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/llm"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	usageFeatureProjectSummary = "project_summary"
+
+	defaultSummaryDays = 7
+	maxSummaryDays     = 31
+	maxActivityItems   = 40
+)
+
+const summarySystemPrompt = `Ты готовишь еженедельную сводку по строительному проекту для руководителя. ` +
+	`Используй только переданные данные. Ответь строго одним JSON-объектом без пояснений и без markdown, ` +
+	`со следующими полями: "headline" (одно предложение о состоянии проекта), ` +
+	`"progress" (массив строк: что сделано за период), "risks" (массив строк: риски и задержки), ` +
+	`"blockers" (массив строк: что сейчас блокирует работу), "budget" (строка: состояние бюджета), ` +
+	`"nextSteps" (массив строк: рекомендуемые действия на следующую неделю). Пиши по-русски.`
+
+type projectSummaryRequest struct {
+	Days int  `json:"days"`
+	Save bool `json:"save"`
+}
+
+// ProjectSummary is the structured summary the model is asked to produce.
+type ProjectSummary struct {
+	Headline  string   `json:"headline"`
+	Progress  []string `json:"progress"`
+	Risks     []string `json:"risks"`
+	Blockers  []string `json:"blockers"`
+	Budget    string   `json:"budget"`
+	NextSteps []string `json:"nextSteps"`
+}
+
+type projectSummaryResponse struct {
+	ProjectID   uuid.UUID             `json:"projectId"`
+	PeriodStart time.Time             `json:"periodStart"`
+	PeriodEnd   time.Time             `json:"periodEnd"`
+	Summary     ProjectSummary        `json:"summary"`
+	Model       string                `json:"model"`
+	Usage       MessageUsage          `json:"usage"`
+	Page        *projects.ProjectPage `json:"page,omitempty"`
+}
+
+// ProjectSummary asks the model for a summary of the project's last days
+// (7 by default) from its activity, delay reports and budget. With
+// "save": true the summary is also stored as a project page, which requires
+// the owner or manager role.
+func (h *Handler) ProjectSummary(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return
+	}
+
+	var req projectSummaryRequest
+	if r.ContentLength != 0 {
+		if err := request.Decode(r, &req); err != nil {
+			request.WriteError(w, err)
+			return
+		}
+	}
+	if req.Days == 0 {
+		req.Days = defaultSummaryDays
+	}
+	if req.Days < 1 || req.Days > maxSummaryDays {
+		problem.Error(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", maxSummaryDays))
+		return
+	}
+
+	if h.catalog == nil || h.catalog.Empty() {
+		problem.Error(w, http.StatusServiceUnavailable, "ai assistant is not configured")
+		return
+	}
+
+	project, err := h.projectsRepo.GetByID(r.Context(), userID, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+			return
+		}
+		log.Printf("load project for ai summary failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load project data")
+		return
+	}
+	// Check before spending tokens on a summary that could not be saved.
+	if req.Save && project.CurrentUserRole != projects.ProjectMemberRoleOwner && project.CurrentUserRole != projects.ProjectMemberRoleManager {
+		problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can save pages")
+		return
+	}
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -req.Days)
+
+	projectContext, err := buildProjectContext(r.Context(), h.projectsRepo, userID, projectID, now)
+	if err == nil {
+		var activity string
+		activity, err = buildActivityContext(r.Context(), h.projectsRepo, userID, projectID, since)
+		projectContext += "\n" + activity
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+			return
+		}
+		log.Printf("build ai summary context failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load project data")
+		return
+	}
+
+	if !h.checkQuota(w, r, userID) {
+		return
+	}
+
+	completion, err := h.catalog.Complete(r.Context(), llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: h.systemPrompt(r, PersonaProjectSummary)},
+			{Role: llm.RoleUser, Content: fmt.Sprintf("Период: %s – %s\n\n%s", formatContextDate(since), formatContextDate(now), projectContext)},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		log.Printf("ai summary completion failed: %v", err)
+		problem.Error(w, http.StatusBadGateway, "ai assistant is unavailable")
+		return
+	}
+
+	usage, err := h.usage.Record(r.Context(), userID, usageFeatureProjectSummary, nil, nil, completion)
+	if err != nil {
+		log.Printf("record ai usage failed: %v", err)
+	}
+
+	summary, err := parseProjectSummary(completion.Content)
+	if err != nil {
+		log.Printf("parse ai summary failed: %v", err)
+		problem.Error(w, http.StatusBadGateway, "ai assistant returned an invalid summary")
+		return
+	}
+
+	response := projectSummaryResponse{
+		ProjectID:   projectID,
+		PeriodStart: since,
+		PeriodEnd:   now,
+		Summary:     summary,
+		Model:       completion.Model,
+		Usage:       usage,
+	}
+
+	if req.Save {
+		title := fmt.Sprintf("AI-сводка %s – %s", formatContextDate(since), formatContextDate(now))
+		page, err := h.projectsRepo.CreatePage(r.Context(), userID, projectID, title, summaryPageBlocks(summary))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can save pages")
+				return
+			}
+			log.Printf("save ai summary page failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to save summary page")
+			return
+		}
+		response.Page = &page
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// buildActivityContext lists what changed in the project since the given
+// time: updated tasks, new expenses and delay reports.
+func buildActivityContext(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID, since time.Time) (string, error) {
+	var b strings.Builder
+
+	stages, err := repo.ListStagesByProject(ctx, userID, projectID)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString("Задачи, изменённые за период:\n")
+	updated := 0
+	for _, stage := range stages {
+		tasks, err := repo.ListTasksByStage(ctx, userID, stage.ID)
+		if err != nil {
+			return "", err
+		}
+		for _, task := range tasks {
+			if task.UpdatedAt.Before(since) {
+				continue
+			}
+			updated++
+			if updated > maxActivityItems {
+				continue
+			}
+			fmt.Fprintf(&b, "- %s (этап «%s», статус %s, изменена %s)\n", task.Title, stage.Title, task.Status, formatContextDate(task.UpdatedAt))
+		}
+	}
+	switch {
+	case updated == 0:
+		b.WriteString("- нет\n")
+	case updated > maxActivityItems:
+		fmt.Fprintf(&b, "- и ещё %d\n", updated-maxActivityItems)
+	}
+
+	expenses, err := repo.ListExpenses(ctx, userID, projectID)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString("\nРасходы за период:\n")
+	var spent int64
+	added := 0
+	for _, expense := range expenses {
+		if expense.CreatedAt.Before(since) {
+			continue
+		}
+		spent += expense.Amount
+		added++
+		if added <= maxActivityItems {
+			fmt.Fprintf(&b, "- %s: %d (%s)\n", expense.Title, expense.Amount, formatContextDate(expense.CreatedAt))
+		}
+	}
+	if added == 0 {
+		b.WriteString("- нет\n")
+	} else {
+		fmt.Fprintf(&b, "Итого за период: %d\n", spent)
+	}
+
+	reports, err := repo.ListDelayReports(ctx, userID, projectID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	b.WriteString("\nОтчёты о задержках за период:\n")
+	filed := 0
+	for _, report := range reports {
+		if report.CreatedAt.Before(since) || filed == maxActivityItems {
+			continue
+		}
+		filed++
+		fmt.Fprintf(
+			&b,
+			"- %s, %s: %s\n",
+			formatContextDate(report.CreatedAt),
+			report.Author.Email,
+			truncateRunes(strings.Join(strings.Fields(report.Message), " "), maxContextReportRunes),
+		)
+	}
+	if filed == 0 {
+		b.WriteString("- нет\n")
+	}
+
+	return b.String(), nil
+}
+
+// parseProjectSummary decodes the model's JSON answer, tolerating a
+// surrounding markdown code fence.
+func parseProjectSummary(content string) (ProjectSummary, error) {
+	content = strings.TrimSpace(content)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	var summary ProjectSummary
+	if err := json.Unmarshal([]byte(content), &summary); err != nil {
+		return ProjectSummary{}, err
+	}
+	if strings.TrimSpace(summary.Headline) == "" {
+		return ProjectSummary{}, errors.New("summary has no headline")
+	}
+	return summary, nil
+}
+
+// summaryPageBlocks renders a summary as text blocks of the page editor.
+func summaryPageBlocks(summary ProjectSummary) []byte {
+	type block struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+
+	blocks := []block{{ID: uuid.NewString(), Type: "text", Content: summary.Headline}}
+	addList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		blocks = append(blocks, block{ID: uuid.NewString(), Type: "text", Content: title + ":\n• " + strings.Join(items, "\n• ")})
+	}
+	addList("Сделано", summary.Progress)
+	addList("Риски", summary.Risks)
+	addList("Блокеры", summary.Blockers)
+	if summary.Budget != "" {
+		blocks = append(blocks, block{ID: uuid.NewString(), Type: "text", Content: "Бюджет: " + summary.Budget})
+	}
+	addList("Следующие шаги", summary.NextSteps)
+
+	encoded, _ := json.Marshal(blocks)
+	return encoded
+}
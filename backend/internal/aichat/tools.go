@@ -0,0 +1,796 @@
+package aichat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tm-platform-backend/internal/llm"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projects"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	toolCreateTask      = "create_task"
+	toolMoveTask        = "move_task"
+	toolAddExpense      = "add_expense"
+	toolScheduleMeeting = "schedule_meeting"
+
+	ActionStatusPending  = "pending"
+	ActionStatusRunning  = "running"
+	ActionStatusExecuted = "executed"
+	ActionStatusFailed   = "failed"
+	ActionStatusRejected = "rejected"
+
+	// maxToolRounds is how many times in a row the model may call tools
+	// before it has to answer.
+	maxToolRounds = 4
+	// pendingActionTTL is how long a proposed action can be confirmed.
+	pendingActionTTL = 24 * time.Hour
+
+	maxToolReferenceTasks  = 100
+	maxToolTitleRunes      = 200
+	defaultMeetingDuration = 60
+	maxMeetingDuration     = 8 * 60
+)
+
+var taskStatuses = map[string]struct{}{"todo": {}, "in_progress": {}, "done": {}, "delayed": {}}
+
+// toolSpec describes a tool the assistant may call. Tools that change or
+// remove existing data, or spend money, need the user's confirmation: the
+// model only proposes them and the user confirms or rejects the action.
+type toolSpec struct {
+	tool    llm.Tool
+	confirm bool
+	parse   func(raw string) (toolInvocation, error)
+}
+
+var toolSpecs = map[string]toolSpec{
+	toolCreateTask: {
+		tool: llm.Tool{
+			Name:        toolCreateTask,
+			Description: "Создать задачу в этапе проекта.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"stage_id": {"type": "string", "description": "ID этапа из справочника"},
+					"title": {"type": "string", "description": "Название задачи"},
+					"deadline": {"type": "string", "description": "Дедлайн в формате YYYY-MM-DD"},
+					"assignee_email": {"type": "string", "description": "Email участника проекта"}
+				},
+				"required": ["stage_id", "title"]
+			}`),
+		},
+		parse: parseCreateTask,
+	},
+	toolMoveTask: {
+		tool: llm.Tool{
+			Name:        toolMoveTask,
+			Description: "Перенести задачу в другой этап и/или сменить её статус. Выполняется после подтверждения пользователем.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"task_id": {"type": "string", "description": "ID задачи из справочника"},
+					"stage_id": {"type": "string", "description": "ID нового этапа"},
+					"status": {"type": "string", "enum": ["todo", "in_progress", "done", "delayed"]}
+				},
+				"required": ["task_id"]
+			}`),
+		},
+		confirm: true,
+		parse:   parseMoveTask,
+	},
+	toolAddExpense: {
+		tool: llm.Tool{
+			Name:        toolAddExpense,
+			Description: "Добавить расход в бюджет проекта. Выполняется после подтверждения пользователем.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"title": {"type": "string", "description": "За что расход"},
+					"amount": {"type": "integer", "description": "Сумма, больше нуля"}
+				},
+				"required": ["title", "amount"]
+			}`),
+		},
+		confirm: true,
+		parse:   parseAddExpense,
+	},
+	toolScheduleMeeting: {
+		tool: llm.Tool{
+			Name:        toolScheduleMeeting,
+			Description: "Назначить встречу по проекту.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"title": {"type": "string"},
+					"starts_at": {"type": "string", "description": "Начало в формате RFC 3339 с часовым поясом, например 2025-03-01T10:00:00+05:00"},
+					"duration_minutes": {"type": "integer", "description": "Длительность в минутах, по умолчанию 60"},
+					"attendee_emails": {"type": "array", "items": {"type": "string"}},
+					"notes": {"type": "string"}
+				},
+				"required": ["title", "starts_at"]
+			}`),
+		},
+		parse: parseScheduleMeeting,
+	},
+}
+
+// projectTools lists the tools offered in project-bound conversations.
+var projectTools = []llm.Tool{
+	toolSpecs[toolCreateTask].tool,
+	toolSpecs[toolMoveTask].tool,
+	toolSpecs[toolAddExpense].tool,
+	toolSpecs[toolScheduleMeeting].tool,
+}
+
+// ToolAction is a tool call made by the assistant: executed right away or,
+// for tools that need confirmation, waiting for the user.
+type ToolAction struct {
+	ID             uuid.UUID       `json:"id"`
+	ConversationID uuid.UUID       `json:"conversationId"`
+	MessageID      *uuid.UUID      `json:"messageId,omitempty"`
+	ProjectID      uuid.UUID       `json:"projectId"`
+	Tool           string          `json:"tool"`
+	Arguments      json.RawMessage `json:"arguments"`
+	Status         string          `json:"status"`
+	Result         json.RawMessage `json:"result,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	ResolvedAt     *time.Time      `json:"resolvedAt,omitempty"`
+}
+
+// toolInvocation is a validated tool call, executed with the permissions of
+// the user the assistant acts for.
+type toolInvocation interface {
+	execute(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (any, error)
+}
+
+// toolUserError is a failure the model and the user may see as is.
+type toolUserError string
+
+func (e toolUserError) Error() string {
+	return string(e)
+}
+
+// runTools completes req and executes the tool calls the model asks for,
+// feeding their results back until it answers with text. The returned
+// response carries the usage of every round.
+func (h *Handler) runTools(ctx context.Context, userID uuid.UUID, conversation Conversation, req llm.Request) (llm.Response, []ToolAction, error) {
+	var (
+		total   llm.Response
+		actions []ToolAction
+	)
+	for round := 0; ; round++ {
+		if round == maxToolRounds {
+			req.Tools = nil
+		}
+
+		completion, err := h.catalog.Complete(ctx, req)
+		if err != nil {
+			return llm.Response{}, nil, err
+		}
+		total.Provider, total.Model = completion.Provider, completion.Model
+		total.Usage.PromptTokens += completion.Usage.PromptTokens
+		total.Usage.CompletionTokens += completion.Usage.CompletionTokens
+
+		if len(completion.ToolCalls) == 0 {
+			total.Content = completion.Content
+			return total, actions, nil
+		}
+
+		req.Messages = append(req.Messages, llm.Message{
+			Role:      llm.RoleAssistant,
+			Content:   completion.Content,
+			ToolCalls: completion.ToolCalls,
+		})
+		for _, call := range completion.ToolCalls {
+			action, result := h.runToolCall(ctx, userID, conversation, call)
+			if action != nil {
+				actions = append(actions, *action)
+			}
+			req.Messages = append(req.Messages, llm.Message{Role: llm.RoleTool, ToolCallID: call.ID, Content: result})
+		}
+	}
+}
+
+// runToolCall validates one call and either executes it or, for tools that
+// need confirmation, stores it as a pending action. It returns the recorded
+// action, if any, and the result to give back to the model.
+func (h *Handler) runToolCall(ctx context.Context, userID uuid.UUID, conversation Conversation, call llm.ToolCall) (*ToolAction, string) {
+	spec, ok := toolSpecs[call.Name]
+	if !ok || conversation.ProjectID == nil {
+		return nil, toolResultJSON(map[string]string{"error": "unknown tool " + call.Name})
+	}
+	invocation, err := spec.parse(call.Arguments)
+	if err != nil {
+		return nil, toolResultJSON(map[string]string{"error": err.Error()})
+	}
+
+	action := ToolAction{
+		ConversationID: conversation.ID,
+		ProjectID:      *conversation.ProjectID,
+		Tool:           call.Name,
+		Arguments:      json.RawMessage(call.Arguments),
+		Status:         ActionStatusPending,
+	}
+
+	if spec.confirm {
+		stored, err := h.repo.createToolAction(ctx, userID, action)
+		if err != nil {
+			log.Printf("save ai tool action failed: %v", err)
+			return nil, toolResultJSON(map[string]string{"error": "internal error"})
+		}
+		return &stored, toolResultJSON(map[string]string{
+			"status":   "pending_confirmation",
+			"actionId": stored.ID.String(),
+			"note":     "Действие будет выполнено, только когда пользователь подтвердит его.",
+		})
+	}
+
+	result, err := invocation.execute(ctx, h.projectsRepo, userID, *conversation.ProjectID)
+	action.Status, action.Result, action.Error = toolOutcome(call.Name, result, err)
+
+	var recorded *ToolAction
+	if stored, err := h.repo.createToolAction(ctx, userID, action); err != nil {
+		log.Printf("save ai tool action failed: %v", err)
+	} else {
+		recorded = &stored
+	}
+	if action.Status == ActionStatusFailed {
+		return recorded, toolResultJSON(map[string]string{"error": action.Error})
+	}
+	return recorded, string(action.Result)
+}
+
+// ConfirmToolAction executes an action the assistant proposed, with the
+// requester's current permissions.
+func (h *Handler) ConfirmToolAction(w http.ResponseWriter, r *http.Request) {
+	h.resolveToolAction(w, r, true)
+}
+
+// RejectToolAction discards an action the assistant proposed.
+func (h *Handler) RejectToolAction(w http.ResponseWriter, r *http.Request) {
+	h.resolveToolAction(w, r, false)
+}
+
+func (h *Handler) resolveToolAction(w http.ResponseWriter, r *http.Request, confirm bool) {
+	userID, conversation, ok := h.loadConversation(w, r)
+	if !ok {
+		return
+	}
+
+	actionID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "actionId")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_action_id", "invalid action id")
+		return
+	}
+
+	next := ActionStatusRejected
+	if confirm {
+		next = ActionStatusRunning
+	}
+	action, err := h.repo.claimToolAction(r.Context(), conversation.ID, actionID, next)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusConflict, "action_not_found", "action not found, expired or already resolved")
+			return
+		}
+		log.Printf("claim ai tool action failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to resolve action")
+		return
+	}
+	if !confirm {
+		writeJSON(w, http.StatusOK, action)
+		return
+	}
+
+	var (
+		result any
+		runErr error
+	)
+	spec, known := toolSpecs[action.Tool]
+	if !known {
+		runErr = toolUserError("unknown tool " + action.Tool)
+	} else {
+		var invocation toolInvocation
+		if invocation, runErr = spec.parse(string(action.Arguments)); runErr == nil {
+			result, runErr = invocation.execute(r.Context(), h.projectsRepo, userID, action.ProjectID)
+		}
+	}
+
+	status, rawResult, errText := toolOutcome(action.Tool, result, runErr)
+	action, err = h.repo.finishToolAction(r.Context(), action.ID, status, rawResult, errText)
+	if err != nil {
+		log.Printf("finish ai tool action failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to resolve action")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, action)
+}
+
+// toolOutcome turns the result of an execution into the stored status,
+// result and error. Unexpected errors are logged and hidden.
+func toolOutcome(tool string, result any, err error) (string, json.RawMessage, string) {
+	if err != nil {
+		var userErr toolUserError
+		if !errors.As(err, &userErr) {
+			log.Printf("ai tool %s failed: %v", tool, err)
+			return ActionStatusFailed, nil, "internal error"
+		}
+		return ActionStatusFailed, nil, userErr.Error()
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("encode ai tool %s result failed: %v", tool, err)
+		return ActionStatusFailed, nil, "internal error"
+	}
+	return ActionStatusExecuted, encoded, ""
+}
+
+func toolResultJSON(value any) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// toolFailure keeps the errors a user can act on and hides the rest.
+func toolFailure(err error, forbidden string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return toolUserError(forbidden)
+	case errors.Is(err, projects.ErrStageNotInProject),
+		errors.Is(err, projects.ErrAssigneeNotMember),
+		errors.Is(err, projects.ErrTaskTitleRequired),
+		errors.Is(err, projects.ErrMeetingTitleRequired),
+		errors.Is(err, projects.ErrMeetingInvalidTime),
+		errors.Is(err, projects.ErrAttendeeNotMember):
+		return toolUserError(err.Error())
+	default:
+		return err
+	}
+}
+
+func decodeToolArguments(raw string, out any) error {
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return toolUserError("invalid arguments: " + err.Error())
+	}
+	return nil
+}
+
+func parseToolUUID(value, field string) (uuid.UUID, error) {
+	id, err := uuid.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return uuid.Nil, toolUserError("invalid " + field)
+	}
+	return id, nil
+}
+
+func parseToolTitle(value string) (string, error) {
+	title := strings.TrimSpace(value)
+	if title == "" {
+		return "", toolUserError("title is required")
+	}
+	if utf8.RuneCountInString(title) > maxToolTitleRunes {
+		return "", toolUserError("title is too long")
+	}
+	return title, nil
+}
+
+type createTaskInvocation struct {
+	stageID  uuid.UUID
+	title    string
+	deadline *time.Time
+	assignee string
+}
+
+func parseCreateTask(raw string) (toolInvocation, error) {
+	var args struct {
+		StageID       string `json:"stage_id"`
+		Title         string `json:"title"`
+		Deadline      string `json:"deadline"`
+		AssigneeEmail string `json:"assignee_email"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+
+	var (
+		invocation createTaskInvocation
+		err        error
+	)
+	if invocation.stageID, err = parseToolUUID(args.StageID, "stage_id"); err != nil {
+		return nil, err
+	}
+	if invocation.title, err = parseToolTitle(args.Title); err != nil {
+		return nil, err
+	}
+	if deadline := strings.TrimSpace(args.Deadline); deadline != "" {
+		parsed, err := time.Parse("2006-01-02", deadline)
+		if err != nil {
+			return nil, toolUserError("deadline must be YYYY-MM-DD")
+		}
+		invocation.deadline = &parsed
+	}
+	invocation.assignee = strings.TrimSpace(args.AssigneeEmail)
+	return invocation, nil
+}
+
+func (c createTaskInvocation) execute(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (any, error) {
+	task := projects.NewTask{StageID: c.stageID, Title: c.title}
+	if c.deadline != nil {
+		loc, err := repo.ProjectLocation(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		deadline := projects.EndOfDay(*c.deadline, loc)
+		task.Deadline = &deadline
+	}
+	if c.assignee != "" {
+		task.Assignees = []string{c.assignee}
+	}
+
+	created, err := repo.CreateTasks(ctx, userID, projectID, []projects.NewTask{task})
+	if err != nil {
+		return nil, toolFailure(err, "only project owners and managers can create tasks")
+	}
+	return created[0], nil
+}
+
+type moveTaskInvocation struct {
+	taskID  uuid.UUID
+	stageID *uuid.UUID
+	status  string
+}
+
+func parseMoveTask(raw string) (toolInvocation, error) {
+	var args struct {
+		TaskID  string `json:"task_id"`
+		StageID string `json:"stage_id"`
+		Status  string `json:"status"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+
+	var (
+		invocation moveTaskInvocation
+		err        error
+	)
+	if invocation.taskID, err = parseToolUUID(args.TaskID, "task_id"); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.StageID) != "" {
+		stageID, err := parseToolUUID(args.StageID, "stage_id")
+		if err != nil {
+			return nil, err
+		}
+		invocation.stageID = &stageID
+	}
+	invocation.status = strings.TrimSpace(args.Status)
+	if invocation.status != "" {
+		if _, ok := taskStatuses[invocation.status]; !ok {
+			return nil, toolUserError("unknown status " + invocation.status)
+		}
+	}
+	if invocation.stageID == nil && invocation.status == "" {
+		return nil, toolUserError("stage_id or status is required")
+	}
+	return invocation, nil
+}
+
+func (m moveTaskInvocation) execute(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (any, error) {
+	task, err := repo.GetTaskByID(ctx, userID, m.taskID)
+	if err != nil {
+		return nil, toolFailure(err, "task not found")
+	}
+	if task.ProjectID != projectID {
+		return nil, toolUserError("task not found")
+	}
+
+	if m.stageID != nil {
+		stages, err := repo.ListStagesByProject(ctx, userID, projectID)
+		if err != nil {
+			return nil, toolFailure(err, "project not found")
+		}
+		found := false
+		for _, stage := range stages {
+			found = found || stage.ID == *m.stageID
+		}
+		if !found {
+			return nil, toolUserError(projects.ErrStageNotInProject.Error())
+		}
+	}
+
+	status := task.Status
+	if m.status != "" {
+		status = m.status
+	}
+
+	updated, err := repo.UpdateTask(ctx, userID, task.ID, task.Title, status, task.StartDate, task.Deadline, m.stageID, task.OrderIndex, task.Blocks)
+	if err != nil {
+		return nil, toolFailure(err, "no permission to change the task")
+	}
+	return updated, nil
+}
+
+type addExpenseInvocation struct {
+	title  string
+	amount int64
+}
+
+func parseAddExpense(raw string) (toolInvocation, error) {
+	var args struct {
+		Title  string `json:"title"`
+		Amount int64  `json:"amount"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Amount <= 0 {
+		return nil, toolUserError("amount must be > 0")
+	}
+
+	title, err := parseToolTitle(args.Title)
+	if err != nil {
+		return nil, err
+	}
+	return addExpenseInvocation{title: title, amount: args.Amount}, nil
+}
+
+func (a addExpenseInvocation) execute(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (any, error) {
+	expense, err := repo.CreateExpense(ctx, userID, projectID, userID, a.title, a.amount)
+	if err != nil {
+		return nil, toolFailure(err, "no permission to add expenses to the project")
+	}
+	return expense, nil
+}
+
+type scheduleMeetingInvocation struct {
+	meeting projects.NewMeeting
+}
+
+func parseScheduleMeeting(raw string) (toolInvocation, error) {
+	var args struct {
+		Title           string   `json:"title"`
+		StartsAt        string   `json:"starts_at"`
+		DurationMinutes int      `json:"duration_minutes"`
+		AttendeeEmails  []string `json:"attendee_emails"`
+		Notes           string   `json:"notes"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+
+	title, err := parseToolTitle(args.Title)
+	if err != nil {
+		return nil, err
+	}
+	startsAt, err := time.Parse(time.RFC3339, strings.TrimSpace(args.StartsAt))
+	if err != nil {
+		return nil, toolUserError("starts_at must be RFC 3339 with a time zone")
+	}
+	if args.DurationMinutes == 0 {
+		args.DurationMinutes = defaultMeetingDuration
+	}
+	if args.DurationMinutes < 0 || args.DurationMinutes > maxMeetingDuration {
+		return nil, toolUserError(fmt.Sprintf("duration_minutes must be between 1 and %d", maxMeetingDuration))
+	}
+
+	return scheduleMeetingInvocation{meeting: projects.NewMeeting{
+		Title:           title,
+		StartsAt:        startsAt,
+		DurationMinutes: args.DurationMinutes,
+		Notes:           args.Notes,
+		Attendees:       args.AttendeeEmails,
+	}}, nil
+}
+
+func (s scheduleMeetingInvocation) execute(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (any, error) {
+	meeting, err := repo.CreateMeeting(ctx, userID, projectID, s.meeting)
+	if err != nil {
+		return nil, toolFailure(err, "only project owners and managers can schedule meetings")
+	}
+	return meeting, nil
+}
+
+// buildToolReference lists the IDs the tools take: stages, open tasks and
+// member emails of the project.
+func buildToolReference(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID) (string, error) {
+	var b strings.Builder
+	b.WriteString("Справочник для инструментов. Чтобы изменить проект, вызывай инструменты с этими id; " +
+		"перенос задач и расходы выполняются только после подтверждения пользователем, не утверждай, что они уже сделаны.\n")
+	b.WriteString("Этапы (id — название):\n")
+
+	stages, err := repo.ListStagesByProject(ctx, userID, projectID)
+	if err != nil {
+		return "", err
+	}
+	listed := 0
+	var tasks strings.Builder
+	for _, stage := range stages {
+		fmt.Fprintf(&b, "- %s — %s\n", stage.ID, stage.Title)
+
+		stageTasks, err := repo.ListTasksByStage(ctx, userID, stage.ID)
+		if err != nil {
+			return "", err
+		}
+		for _, task := range stageTasks {
+			if task.Status == "done" || listed == maxToolReferenceTasks {
+				continue
+			}
+			listed++
+			fmt.Fprintf(&tasks, "- %s — %s (этап «%s», статус %s)\n", task.ID, task.Title, stage.Title, task.Status)
+		}
+	}
+	b.WriteString("\nНезавершённые задачи (id — название):\n")
+	b.WriteString(tasks.String())
+
+	members, err := repo.ListMembersByProject(ctx, userID, projectID)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString("\nУчастники:\n")
+	for _, member := range members {
+		fmt.Fprintf(&b, "- %s (%s)\n", member.User.Email, member.Role)
+	}
+
+	return b.String(), nil
+}
+
+const toolActionColumns = `id, thread_id, message_id, project_id, tool, arguments, status, result, COALESCE(error, ''), created_at, resolved_at`
+
+func (r *Repository) createToolAction(ctx context.Context, userID uuid.UUID, action ToolAction) (ToolAction, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return ToolAction{}, err
+	}
+
+	var resolvedAt *time.Time
+	if action.Status != ActionStatusPending {
+		now := time.Now()
+		resolvedAt = &now
+	}
+
+	return scanToolAction(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO ai_tool_actions (id, thread_id, user_id, project_id, tool, arguments, status, result, error, resolved_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+		 RETURNING `+toolActionColumns,
+		uuid.New(),
+		action.ConversationID,
+		userID,
+		action.ProjectID,
+		action.Tool,
+		[]byte(action.Arguments),
+		action.Status,
+		nullableJSON(action.Result),
+		action.Error,
+		resolvedAt,
+	))
+}
+
+// attachToolActions links actions to the assistant message they led to.
+func (r *Repository) attachToolActions(ctx context.Context, actions []ToolAction, messageID uuid.UUID) error {
+	for _, action := range actions {
+		if _, err := r.db.ExecContext(ctx, `UPDATE ai_tool_actions SET message_id = $2 WHERE id = $1`, action.ID, messageID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimToolAction moves a pending, unexpired action of a conversation to
+// status. It returns sql.ErrNoRows when there is no such action.
+func (r *Repository) claimToolAction(ctx context.Context, conversationID, actionID uuid.UUID, status string) (ToolAction, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return ToolAction{}, err
+	}
+
+	return scanToolAction(r.db.QueryRowContext(
+		ctx,
+		`UPDATE ai_tool_actions
+		 SET status = $3, resolved_at = CASE WHEN $3 = 'running' THEN NULL ELSE now() END
+		 WHERE id = $1 AND thread_id = $2 AND status = 'pending'
+		   AND created_at > now() - $4 * interval '1 second'
+		 RETURNING `+toolActionColumns,
+		actionID,
+		conversationID,
+		status,
+		int64(pendingActionTTL/time.Second),
+	))
+}
+
+func (r *Repository) finishToolAction(ctx context.Context, actionID uuid.UUID, status string, result json.RawMessage, errText string) (ToolAction, error) {
+	return scanToolAction(r.db.QueryRowContext(
+		ctx,
+		`UPDATE ai_tool_actions
+		 SET status = $2, result = $3, error = NULLIF($4, ''), resolved_at = now()
+		 WHERE id = $1
+		 RETURNING `+toolActionColumns,
+		actionID,
+		status,
+		nullableJSON(result),
+		errText,
+	))
+}
+
+// listToolActions returns the actions of a conversation grouped by the
+// message they belong to.
+func (r *Repository) listToolActions(ctx context.Context, conversationID uuid.UUID) (map[uuid.UUID][]ToolAction, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+toolActionColumns+`
+		 FROM ai_tool_actions
+		 WHERE thread_id = $1 AND message_id IS NOT NULL
+		 ORDER BY created_at ASC, id ASC`,
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := make(map[uuid.UUID][]ToolAction)
+	for rows.Next() {
+		action, err := scanToolAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		actions[*action.MessageID] = append(actions[*action.MessageID], action)
+	}
+	return actions, rows.Err()
+}
+
+func nullableJSON(value json.RawMessage) []byte {
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
+
+func scanToolAction(row conversationScanner) (ToolAction, error) {
+	var (
+		action     ToolAction
+		messageID  uuid.NullUUID
+		arguments  []byte
+		result     []byte
+		resolvedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&action.ID,
+		&action.ConversationID,
+		&messageID,
+		&action.ProjectID,
+		&action.Tool,
+		&arguments,
+		&action.Status,
+		&result,
+		&action.Error,
+		&action.CreatedAt,
+		&resolvedAt,
+	); err != nil {
+		return ToolAction{}, err
+	}
+	if messageID.Valid {
+		action.MessageID = &messageID.UUID
+	}
+	action.Arguments = arguments
+	if len(result) > 0 {
+		action.Result = result
+	}
+	if resolvedAt.Valid {
+		action.ResolvedAt = &resolvedAt.Time
+	}
+	return action, nil
+}
@@ -0,0 +1,330 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/llm"
+	"tm-platform-backend/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	usageFeatureChat = "chat"
+
+	usageQuotaExceededCode = "ai_quota_exceeded"
+)
+
+var ErrUsageQuotaExceeded = errors.New("monthly AI usage quota exceeded")
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Pricing maps model names to their prices. Models without a price are
+// recorded at zero cost.
+type Pricing map[string]ModelPrice
+
+// ParsePricing reads prices in the form "model=prompt:completion,...", e.g.
+// "gpt-4o-mini=0.15:0.6".
+func ParsePricing(raw string) (Pricing, error) {
+	pricing := make(Pricing)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, prices, ok := strings.Cut(entry, "=")
+		promptRaw, completionRaw, hasBoth := strings.Cut(prices, ":")
+		model = strings.TrimSpace(model)
+		if !ok || !hasBoth || model == "" {
+			return nil, fmt.Errorf("invalid price %q, expected model=prompt:completion", entry)
+		}
+
+		prompt, err := strconv.ParseFloat(strings.TrimSpace(promptRaw), 64)
+		if err != nil || prompt < 0 {
+			return nil, fmt.Errorf("invalid prompt price for %s", model)
+		}
+		completion, err := strconv.ParseFloat(strings.TrimSpace(completionRaw), 64)
+		if err != nil || completion < 0 {
+			return nil, fmt.Errorf("invalid completion price for %s", model)
+		}
+		pricing[model] = ModelPrice{Prompt: prompt, Completion: completion}
+	}
+	return pricing, nil
+}
+
+// Cost estimates the price of a completion in USD. Dated model versions
+// reported by providers ("gpt-4o-2024-08-06") use the price of the longest
+// configured prefix.
+func (p Pricing) Cost(model string, usage llm.Usage) float64 {
+	price, ok := p[model]
+	if !ok {
+		matched := ""
+		for name, candidate := range p {
+			if strings.HasPrefix(model, name+"-") && len(name) > len(matched) {
+				matched, price = name, candidate
+			}
+		}
+		if matched == "" {
+			return 0
+		}
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
+
+// UsageLimits are per-user monthly quotas; zero disables a limit.
+type UsageLimits struct {
+	MonthlyTokens int64
+	MonthlyCost   float64
+}
+
+// MessageUsage is what producing one message cost.
+type MessageUsage struct {
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+type ModelUsage struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// UsageSummary is a user's usage over one calendar month (UTC).
+type UsageSummary struct {
+	Month            string       `json:"month"`
+	Requests         int64        `json:"requests"`
+	PromptTokens     int64        `json:"promptTokens"`
+	CompletionTokens int64        `json:"completionTokens"`
+	TotalTokens      int64        `json:"totalTokens"`
+	Cost             float64      `json:"cost"`
+	TokenLimit       *int64       `json:"tokenLimit"`
+	CostLimit        *float64     `json:"costLimit"`
+	ResetsAt         time.Time    `json:"resetsAt"`
+	Models           []ModelUsage `json:"models"`
+}
+
+// writeQuotaError answers 429 with the quota details and when it resets.
+func writeQuotaError(w http.ResponseWriter, err *QuotaError) {
+	retryAfter := int64(time.Until(err.ResetsAt).Seconds())
+	w.Header().Set("Retry-After", strconv.FormatInt(max(retryAfter, 1), 10))
+	problem.WriteWith(w, http.StatusTooManyRequests, err.Code, err.Message, err)
+}
+
+// QuotaError is returned when a user has used up a monthly quota. It is
+// written to the client as a problem extended by its details.
+type QuotaError struct {
+	Message    string    `json:"error"`
+	Code       string    `json:"code"`
+	UsedTokens int64     `json:"usedTokens"`
+	TokenLimit *int64    `json:"tokenLimit,omitempty"`
+	UsedCost   float64   `json:"usedCost"`
+	CostLimit  *float64  `json:"costLimit,omitempty"`
+	ResetsAt   time.Time `json:"resetsAt"`
+}
+
+func (e *QuotaError) Error() string {
+	return e.Message
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrUsageQuotaExceeded
+}
+
+// UsageTracker records what AI requests cost and enforces monthly quotas.
+type UsageTracker struct {
+	repo    *Repository
+	pricing Pricing
+	limits  UsageLimits
+}
+
+func NewUsageTracker(repo *Repository, pricing Pricing, limits UsageLimits) *UsageTracker {
+	return &UsageTracker{repo: repo, pricing: pricing, limits: limits}
+}
+
+// CheckQuota fails with a *QuotaError when userID has used up a monthly
+// quota.
+func (t *UsageTracker) CheckQuota(ctx context.Context, userID uuid.UUID) error {
+	if t == nil || (t.limits.MonthlyTokens <= 0 && t.limits.MonthlyCost <= 0) {
+		return nil
+	}
+
+	from, to := usageMonth(time.Now())
+	tokens, cost, err := t.repo.sumUsage(ctx, userID, from, to)
+	if err != nil {
+		return err
+	}
+
+	var message string
+	switch {
+	case t.limits.MonthlyTokens > 0 && tokens >= t.limits.MonthlyTokens:
+		message = "monthly AI token quota exceeded"
+	case t.limits.MonthlyCost > 0 && cost >= t.limits.MonthlyCost:
+		message = "monthly AI cost quota exceeded"
+	default:
+		return nil
+	}
+
+	tokenLimit, costLimit := t.limitPointers()
+	return &QuotaError{
+		Message:    message,
+		Code:       usageQuotaExceededCode,
+		UsedTokens: tokens,
+		TokenLimit: tokenLimit,
+		UsedCost:   cost,
+		CostLimit:  costLimit,
+		ResetsAt:   to,
+	}
+}
+
+// Record stores the usage of a completion made for userID. messageID links it
+// to the stored reply, when there is one.
+func (t *UsageTracker) Record(ctx context.Context, userID uuid.UUID, feature string, threadID, messageID *uuid.UUID, response llm.Response) (MessageUsage, error) {
+	usage := MessageUsage{
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+	}
+	if t == nil {
+		return usage, nil
+	}
+	usage.Cost = t.pricing.Cost(response.Model, response.Usage)
+
+	if err := t.repo.insertUsage(ctx, userID, feature, threadID, messageID, response.Provider, response.Model, usage); err != nil {
+		return MessageUsage{}, err
+	}
+	return usage, nil
+}
+
+// Summary returns userID's usage in the calendar month containing month.
+func (t *UsageTracker) Summary(ctx context.Context, userID uuid.UUID, month time.Time) (UsageSummary, error) {
+	from, to := usageMonth(month)
+	models, err := t.repo.usageByModel(ctx, userID, from, to)
+	if err != nil {
+		return UsageSummary{}, err
+	}
+
+	summary := UsageSummary{Month: from.Format("2006-01"), ResetsAt: to, Models: models}
+	summary.TokenLimit, summary.CostLimit = t.limitPointers()
+	for _, model := range models {
+		summary.Requests += model.Requests
+		summary.PromptTokens += model.PromptTokens
+		summary.CompletionTokens += model.CompletionTokens
+		summary.Cost += model.Cost
+	}
+	summary.TotalTokens = summary.PromptTokens + summary.CompletionTokens
+	return summary, nil
+}
+
+func (t *UsageTracker) limitPointers() (*int64, *float64) {
+	var (
+		tokenLimit *int64
+		costLimit  *float64
+	)
+	if t.limits.MonthlyTokens > 0 {
+		limit := t.limits.MonthlyTokens
+		tokenLimit = &limit
+	}
+	if t.limits.MonthlyCost > 0 {
+		limit := t.limits.MonthlyCost
+		costLimit = &limit
+	}
+	return tokenLimit, costLimit
+}
+
+// usageMonth returns the bounds of the UTC calendar month containing t.
+func usageMonth(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0)
+}
+
+func (r *Repository) insertUsage(ctx context.Context, userID uuid.UUID, feature string, threadID, messageID *uuid.UUID, provider, model string, usage MessageUsage) error {
+	if err := r.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO ai_usage (id, user_id, feature, thread_id, message_id, provider, model, prompt_tokens, completion_tokens, cost)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		uuid.New(),
+		userID,
+		feature,
+		threadID,
+		messageID,
+		provider,
+		model,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.Cost,
+	)
+	return err
+}
+
+func (r *Repository) sumUsage(ctx context.Context, userID uuid.UUID, from, to time.Time) (int64, float64, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	var (
+		tokens int64
+		cost   float64
+	)
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(SUM(prompt_tokens + completion_tokens), 0), COALESCE(SUM(cost), 0)
+		 FROM ai_usage
+		 WHERE user_id = $1 AND created_at >= $2 AND created_at < $3`,
+		userID,
+		from,
+		to,
+	).Scan(&tokens, &cost)
+	return tokens, cost, err
+}
+
+func (r *Repository) usageByModel(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]ModelUsage, error) {
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT provider, model, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost), 0)
+		 FROM ai_usage
+		 WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		 GROUP BY provider, model
+		 ORDER BY SUM(prompt_tokens + completion_tokens) DESC`,
+		userID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	models := make([]ModelUsage, 0)
+	for rows.Next() {
+		var model ModelUsage
+		if err := rows.Scan(&model.Provider, &model.Model, &model.Requests, &model.PromptTokens, &model.CompletionTokens, &model.Cost); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+
+	return models, rows.Err()
+}
@@ -7,13 +7,16 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"io"
 	"log"
 	"net/http"
 	"net/mail"
 	"strings"
 	"time"
 
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -40,13 +43,12 @@ type authRequest struct {
 }
 
 type registerRequest struct {
-	Email       string `json:"email"`
-	Password    string `json:"password"`
-	Name        string `json:"name"`
-	FullName    string `json:"full_name"`
-	FullNameAlt string `json:"fullName"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
+	Email     string `json:"email" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	Name      string `json:"name"`
+	FullName  string `json:"full_name"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
 }
 
 type refreshRequest struct {
@@ -68,6 +70,8 @@ type userResponse struct {
 	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
 	DepartmentName *string    `json:"department_name,omitempty"`
 	CreatedAt      time.Time  `json:"created_at"`
+	Locale         *string    `json:"locale"`
+	Timezone       *string    `json:"timezone"`
 }
 
 type hierarchyNode struct {
@@ -83,32 +87,33 @@ type hierarchyNode struct {
 }
 
 type departmentResponse struct {
-	ID        uuid.UUID  `json:"id"`
-	Name      string     `json:"name"`
-	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	ParentID        *uuid.UUID `json:"parent_id,omitempty"`
+	IsSystem        bool       `json:"is_system"`
+	HierarchyNodeID *uuid.UUID `json:"hierarchy_node_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 type updateUserHierarchyRequest struct {
-	Role          *string `json:"role"`
-	ManagerID     *string `json:"manager_id"`
-	ManagerIDAlt  *string `json:"managerId"`
-	DepartmentID  *string `json:"department_id"`
-	DepartmentAlt *string `json:"departmentId"`
+	Role         *string `json:"role" validate:"max=120"`
+	ManagerID    *string `json:"manager_id"`
+	DepartmentID *string `json:"department_id"`
 }
 
 type createDepartmentRequest struct {
-	Name        string  `json:"name"`
-	ParentID    *string `json:"parent_id"`
-	ParentIDAlt *string `json:"parentId"`
+	Name     string  `json:"name" validate:"required,max=120"`
+	ParentID *string `json:"parent_id"`
 }
 
 type updateProfileRequest struct {
-	Email       *string `json:"email"`
-	FullName    *string `json:"full_name"`
-	FullNameAlt *string `json:"fullName"`
-	AvatarURL   *string `json:"avatar_url"`
-	AvatarAlt   *string `json:"avatarUrl"`
+	Email     *string `json:"email"`
+	FullName  *string `json:"full_name" validate:"max=120"`
+	AvatarURL *string `json:"avatar_url"`
+	// Locale is ru, kk or en; null follows Accept-Language
+	Locale *string `json:"locale"`
+	// Timezone is an IANA name; it seeds the projects the user creates
+	Timezone *string `json:"timezone" validate:"timezone"`
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
@@ -118,30 +123,23 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req registerRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	req.Email = strings.TrimSpace(req.Email)
-	if req.Email == "" || req.Password == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email and password are required"})
-		return
-	}
 	if _, err := mail.ParseAddress(req.Email); err != nil {
 		log.Printf("register: email parse error: %v", err)
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to hash password"})
+		problem.Error(w, http.StatusInternalServerError, "failed to hash password")
 		return
 	}
 
 	fullNameValue := strings.TrimSpace(req.FullName)
-	if fullNameValue == "" {
-		fullNameValue = strings.TrimSpace(req.FullNameAlt)
-	}
 	if fullNameValue == "" {
 		fullNameValue = strings.TrimSpace(req.Name)
 	}
@@ -160,11 +158,11 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": "email already registered"})
+			problem.Write(w, http.StatusConflict, "email_already_registered", "email already registered")
 			return
 		}
 		log.Printf("register: create user error: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to create user"})
+		problem.Write(w, http.StatusBadRequest, "failed_to_create_user", "failed to create user")
 		return
 	}
 
@@ -173,44 +171,40 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req authRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 	req.Email = strings.TrimSpace(req.Email)
-	if req.Email == "" || req.Password == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email and password are required"})
-		return
-	}
 	if _, err := mail.ParseAddress(req.Email); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid email"})
+		problem.Write(w, http.StatusBadRequest, "invalid_email", "invalid email")
 		return
 	}
 
 	user, err := h.repo.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_credentials", "invalid credentials")
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_credentials", "invalid credentials")
 		return
 	}
 
 	accessToken, _, err := h.svc.CreateToken(user.ID.String(), TokenTypeAccess, accessTokenTTL)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create token"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create token")
 		return
 	}
 	refreshToken, refreshJTI, err := h.svc.CreateToken(user.ID.String(), TokenTypeRefresh, refreshTokenTTL)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create token"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create token")
 		return
 	}
 	refreshHash := hashToken(refreshToken)
 	if err := h.repo.StoreRefreshToken(r.Context(), user.ID, refreshJTI, refreshHash, time.Now().UTC().Add(refreshTokenTTL)); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to persist refresh token"})
+		problem.Error(w, http.StatusInternalServerError, "failed to persist refresh token")
 		return
 	}
 
@@ -221,8 +215,8 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 	var req refreshRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, request.ErrEmptyBody) {
+		request.WriteError(w, err)
 		return
 	}
 	refreshToken := strings.TrimSpace(req.RefreshToken)
@@ -232,32 +226,32 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if refreshToken == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "refresh token is required"})
+		problem.Write(w, http.StatusBadRequest, "refresh_token_required", "refresh token is required")
 		return
 	}
 
 	claims, err := h.svc.ParseToken(refreshToken, TokenTypeRefresh)
 	if err != nil {
 		h.clearRefreshCookie(w, r)
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_token", "invalid token")
 		return
 	}
 
 	userID := strings.TrimSpace(claims.Subject)
 	if userID == "" {
 		h.clearRefreshCookie(w, r)
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token subject"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_token_subject", "invalid token subject")
 		return
 	}
 
 	accessToken, _, err := h.svc.CreateToken(userID, TokenTypeAccess, accessTokenTTL)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create token"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create token")
 		return
 	}
 	newRefreshToken, newRefreshJTI, err := h.svc.CreateToken(userID, TokenTypeRefresh, refreshTokenTTL)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create token"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create token")
 		return
 	}
 
@@ -274,10 +268,10 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.clearRefreshCookie(w, r)
 		if errors.Is(err, ErrRefreshTokenNotFound) || errors.Is(err, ErrRefreshTokenInvalid) {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid refresh token"})
+			problem.Write(w, http.StatusUnauthorized, "invalid_refresh_token", "invalid refresh token")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to refresh token"})
+		problem.Error(w, http.StatusInternalServerError, "failed to refresh token")
 		return
 	}
 
@@ -289,41 +283,46 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	userIDStr, ok := UserIDFromContext(r.Context())
 	if !ok || userIDStr == "" {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	requesterID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token subject"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_token_subject", "invalid token subject")
 		return
 	}
 
 	paramID := chi.URLParam(r, "id")
 	if strings.TrimSpace(paramID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user id is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "user id is required")
 		return
 	}
 
 	targetID, err := uuid.Parse(paramID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
-	user, err := h.repo.GetUserByID(r.Context(), targetID)
+	// Others are only visible from a workspace shared with them.
+	load := h.repo.GetWorkspaceUserByID
+	if requesterID == targetID {
+		load = h.repo.GetUserByID
+	}
+	user, err := load(r.Context(), targetID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load user"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load user")
 		return
 	}
 
 	if requesterID != user.ID {
 		if user.ManagerID == nil || *user.ManagerID != requesterID {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 	}
@@ -334,23 +333,23 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetUserManager(w http.ResponseWriter, r *http.Request) {
 	paramID := chi.URLParam(r, "id")
 	if strings.TrimSpace(paramID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user id is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "user id is required")
 		return
 	}
 
 	userID, err := uuid.Parse(paramID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
-	user, err := h.repo.GetUserByID(r.Context(), userID)
+	user, err := h.repo.GetWorkspaceUserByID(r.Context(), userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load user"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load user")
 		return
 	}
 
@@ -359,13 +358,13 @@ func (h *Handler) GetUserManager(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	manager, err := h.repo.GetUserByID(r.Context(), *user.ManagerID)
+	manager, err := h.repo.GetWorkspaceUserByID(r.Context(), *user.ManagerID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "manager not found"})
+			problem.Write(w, http.StatusNotFound, "manager_not_found", "manager not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load manager"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load manager")
 		return
 	}
 
@@ -375,29 +374,29 @@ func (h *Handler) GetUserManager(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetUserSubordinates(w http.ResponseWriter, r *http.Request) {
 	paramID := chi.URLParam(r, "id")
 	if strings.TrimSpace(paramID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user id is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "user id is required")
 		return
 	}
 
 	managerID, err := uuid.Parse(paramID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
-	_, err = h.repo.GetUserByID(r.Context(), managerID)
+	_, err = h.repo.GetWorkspaceUserByID(r.Context(), managerID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load user"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load user")
 		return
 	}
 
 	subordinates, err := h.repo.ListUsersByManagerID(r.Context(), managerID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load subordinates"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load subordinates")
 		return
 	}
 
@@ -412,7 +411,7 @@ func (h *Handler) GetUserSubordinates(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetHierarchy(w http.ResponseWriter, r *http.Request) {
 	users, err := h.repo.ListUsers(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load hierarchy"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load hierarchy")
 		return
 	}
 
@@ -454,7 +453,7 @@ func (h *Handler) GetHierarchy(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.repo.ListUsers(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load users"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load users")
 		return
 	}
 
@@ -468,33 +467,24 @@ func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
 	var req createDepartmentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	name := strings.TrimSpace(req.Name)
-	if name == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
-		return
-	}
-	if len(name) > 120 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is too long"})
-		return
-	}
-
-	parentID, err := parseOptionalUUID(req.ParentID, req.ParentIDAlt)
+	parentID, err := parseOptionalUUID(req.ParentID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid parent id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_parent_id", "invalid parent id")
 		return
 	}
 	if parentID != nil {
 		if _, err := h.repo.GetDepartmentByID(r.Context(), *parentID); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "parent department not found"})
+				problem.Write(w, http.StatusBadRequest, "parent_department_not_found", "parent department not found")
 				return
 			}
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate parent department"})
+			problem.Error(w, http.StatusInternalServerError, "failed to validate parent department")
 			return
 		}
 	}
@@ -503,10 +493,10 @@ func (h *Handler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": "department name already exists"})
+			problem.Write(w, http.StatusConflict, "department_name_already_exists", "department name already exists")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create department"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create department")
 		return
 	}
 
@@ -516,7 +506,7 @@ func (h *Handler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ListDepartments(w http.ResponseWriter, r *http.Request) {
 	departments, err := h.repo.ListDepartments(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load departments"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load departments")
 		return
 	}
 
@@ -531,123 +521,108 @@ func (h *Handler) ListDepartments(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateUserHierarchy(w http.ResponseWriter, r *http.Request) {
 	userIDStr, ok := UserIDFromContext(r.Context())
 	if !ok || userIDStr == "" {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	requesterID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token subject"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_token_subject", "invalid token subject")
 		return
 	}
 
 	paramID := chi.URLParam(r, "id")
 	if strings.TrimSpace(paramID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user id is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "user id is required")
 		return
 	}
 
 	targetID, err := uuid.Parse(paramID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
-	targetUser, err := h.repo.GetUserByID(r.Context(), targetID)
+	targetUser, err := h.repo.GetWorkspaceUserByID(r.Context(), targetID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load user"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load user")
 		return
 	}
 
 	allowed, err := h.canEditHierarchy(r.Context(), requesterID, targetUser)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate permissions"})
+		problem.Error(w, http.StatusInternalServerError, "failed to validate permissions")
 		return
 	}
 	if !allowed {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
-		return
-	}
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	var req updateUserHierarchyRequest
-	if err := json.Unmarshal(bodyBytes, &req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
-		return
-	}
-
-	rawFields := make(map[string]json.RawMessage)
-	if err := json.Unmarshal(bodyBytes, &rawFields); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	fields, err := request.DecodeFields(r, &req)
+	if err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	role := targetUser.Role
-	if hasAnyField(rawFields, "role") {
+	if fields.Has("role") {
 		role = normalizeRole(req.Role)
-		if role != nil && len(*role) > 120 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "role is too long"})
-			return
-		}
 	}
 
 	managerID := targetUser.ManagerID
-	if hasAnyField(rawFields, "manager_id", "managerId") {
-		managerID, err = parseOptionalUUID(req.ManagerID, req.ManagerIDAlt)
+	if fields.Has("manager_id") {
+		managerID, err = parseOptionalUUID(req.ManagerID)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid manager id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_manager_id", "invalid manager id")
 			return
 		}
 		if managerID != nil {
 			if *managerID == targetID {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user cannot manage self"})
+				problem.Write(w, http.StatusBadRequest, "user_cannot_manage_self", "user cannot manage self")
 				return
 			}
 
-			if _, err := h.repo.GetUserByID(r.Context(), *managerID); err != nil {
+			if _, err := h.repo.GetWorkspaceUserByID(r.Context(), *managerID); err != nil {
 				if errors.Is(err, sql.ErrNoRows) {
-					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "manager not found"})
+					problem.Write(w, http.StatusBadRequest, "manager_not_found", "manager not found")
 					return
 				}
-				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate manager"})
+				problem.Error(w, http.StatusInternalServerError, "failed to validate manager")
 				return
 			}
 
 			createsCycle, err := h.wouldCreateManagerCycle(r.Context(), targetID, *managerID)
 			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate hierarchy"})
+				problem.Error(w, http.StatusInternalServerError, "failed to validate hierarchy")
 				return
 			}
 			if createsCycle {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "manager hierarchy cycle detected"})
+				problem.Write(w, http.StatusBadRequest, "manager_hierarchy_cycle_detected", "manager hierarchy cycle detected")
 				return
 			}
 		}
 	}
 
 	departmentID := targetUser.DepartmentID
-	if hasAnyField(rawFields, "department_id", "departmentId") {
-		departmentID, err = parseOptionalUUID(req.DepartmentID, req.DepartmentAlt)
+	if fields.Has("department_id") {
+		departmentID, err = parseOptionalUUID(req.DepartmentID)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid department id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_department_id", "invalid department id")
 			return
 		}
 		if departmentID != nil {
 			if _, err := h.repo.GetDepartmentByID(r.Context(), *departmentID); err != nil {
 				if errors.Is(err, sql.ErrNoRows) {
-					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "department not found"})
+					problem.Write(w, http.StatusBadRequest, "department_not_found", "department not found")
 					return
 				}
-				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate department"})
+				problem.Error(w, http.StatusInternalServerError, "failed to validate department")
 				return
 			}
 		}
@@ -656,10 +631,10 @@ func (h *Handler) UpdateUserHierarchy(w http.ResponseWriter, r *http.Request) {
 	user, err := h.repo.UpdateUserHierarchy(r.Context(), targetID, role, managerID, departmentID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update user hierarchy"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update user hierarchy")
 		return
 	}
 
@@ -669,123 +644,118 @@ func (h *Handler) UpdateUserHierarchy(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 	userIDStr, ok := UserIDFromContext(r.Context())
 	if !ok || userIDStr == "" {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	requesterID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token subject"})
+		problem.Write(w, http.StatusUnauthorized, "invalid_token_subject", "invalid token subject")
 		return
 	}
 
 	paramID := chi.URLParam(r, "id")
 	if strings.TrimSpace(paramID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user id is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "user id is required")
 		return
 	}
 
 	targetID, err := uuid.Parse(paramID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
 	if requesterID != targetID {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	current, err := h.repo.GetUserByID(r.Context(), targetID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load user"})
-		return
-	}
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load user")
 		return
 	}
 
 	var req updateProfileRequest
-	if err := json.Unmarshal(bodyBytes, &req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
-		return
-	}
-
-	rawFields := make(map[string]json.RawMessage)
-	if err := json.Unmarshal(bodyBytes, &rawFields); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	fields, err := request.DecodeFields(r, &req)
+	if err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	email := current.Email
-	if hasAnyField(rawFields, "email") {
+	if fields.Has("email") {
 		if req.Email == nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email is required"})
+			problem.Write(w, http.StatusBadRequest, "email_required", "email is required")
 			return
 		}
 		email = strings.TrimSpace(*req.Email)
 		if email == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email is required"})
+			problem.Write(w, http.StatusBadRequest, "email_required", "email is required")
 			return
 		}
 		if _, err := mail.ParseAddress(email); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid email"})
+			problem.Write(w, http.StatusBadRequest, "invalid_email", "invalid email")
 			return
 		}
 	}
 
 	fullName := current.FullName
-	if hasAnyField(rawFields, "full_name", "fullName") {
-		value := req.FullName
-		if value == nil {
-			value = req.FullNameAlt
-		}
-		if value == nil {
-			fullName = nil
-		} else {
-			trimmed := strings.TrimSpace(*value)
-			if trimmed == "" {
-				fullName = nil
-			} else {
-				if len(trimmed) > 120 {
-					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "full name is too long"})
-					return
-				}
+	if fields.Has("full_name") {
+		fullName = nil
+		if req.FullName != nil {
+			if trimmed := strings.TrimSpace(*req.FullName); trimmed != "" {
 				fullName = &trimmed
 			}
 		}
 	}
 
 	avatarURL := current.AvatarURL
-	if hasAnyField(rawFields, "avatar_url", "avatarUrl") {
-		value := req.AvatarURL
-		if value == nil {
-			value = req.AvatarAlt
-		}
-
-		normalizedAvatarURL, err := normalizeAvatarURL(value)
+	if fields.Has("avatar_url") {
+		normalizedAvatarURL, err := normalizeAvatarURL(req.AvatarURL)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			problem.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
 		avatarURL = normalizedAvatarURL
 	}
 
-	updated, err := h.repo.UpdateUserProfile(r.Context(), targetID, email, fullName, avatarURL)
+	locale := current.Locale
+	if fields.Has("locale") {
+		locale = nil
+		if req.Locale != nil {
+			parsed, ok := i18n.Parse(*req.Locale)
+			if !ok {
+				problem.Write(w, http.StatusBadRequest, "unsupported_locale", "locale must be ru, kk or en")
+				return
+			}
+			value := string(parsed)
+			locale = &value
+		}
+	}
+
+	timezone := current.Timezone
+	if fields.Has("timezone") {
+		timezone = nil
+		if req.Timezone != nil && strings.TrimSpace(*req.Timezone) != "" {
+			value := strings.TrimSpace(*req.Timezone)
+			timezone = &value
+		}
+	}
+
+	updated, err := h.repo.UpdateUserProfile(r.Context(), targetID, email, fullName, avatarURL, locale, timezone)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": "email already registered"})
+			problem.Write(w, http.StatusConflict, "email_already_registered", "email already registered")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update profile"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update profile")
 		return
 	}
 
@@ -856,34 +826,21 @@ func normalizeAvatarURL(raw *string) (*string, error) {
 	return nil, errors.New("invalid avatar url")
 }
 
-func parseOptionalUUID(values ...*string) (*uuid.UUID, error) {
-	for _, value := range values {
-		if value == nil {
-			continue
-		}
-
-		trimmed := strings.TrimSpace(*value)
-		if trimmed == "" || strings.EqualFold(trimmed, "null") {
-			return nil, nil
-		}
-
-		parsed, err := uuid.Parse(trimmed)
-		if err != nil {
-			return nil, err
-		}
-		return &parsed, nil
+func parseOptionalUUID(value *string) (*uuid.UUID, error) {
+	if value == nil {
+		return nil, nil
 	}
 
-	return nil, nil
-}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" || strings.EqualFold(trimmed, "null") {
+		return nil, nil
+	}
 
-func hasAnyField(fields map[string]json.RawMessage, keys ...string) bool {
-	for _, key := range keys {
-		if _, ok := fields[key]; ok {
-			return true
-		}
+	parsed, err := uuid.Parse(trimmed)
+	if err != nil {
+		return nil, err
 	}
-	return false
+	return &parsed, nil
 }
 
 func (h *Handler) canEditHierarchy(ctx context.Context, requesterID uuid.UUID, targetUser User) (bool, error) {
@@ -942,6 +899,8 @@ func buildUserResponse(user User) userResponse {
 		DepartmentID:   user.DepartmentID,
 		DepartmentName: user.DepartmentName,
 		CreatedAt:      user.CreatedAt,
+		Locale:         user.Locale,
+		Timezone:       user.Timezone,
 	}
 }
 
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/i18n"
+
+	"github.com/google/uuid"
+)
+
+const localeCacheTTL = 10 * time.Minute
+
+func localeCacheKey(userID uuid.UUID) string {
+	return "users:locale:" + userID.String()
+}
+
+// UserLocale returns the locale the user chose, or "" when they follow
+// their browser.
+func (r *Repository) UserLocale(ctx context.Context, userID uuid.UUID) (string, error) {
+	return cache.Load(ctx, r.cache, localeCacheKey(userID), localeCacheTTL, func() (string, error) {
+		var locale sql.NullString
+		err := r.db.QueryRowContext(ctx, `SELECT locale FROM users WHERE id = $1`, userID).Scan(&locale)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return locale.String, err
+	})
+}
+
+// ProfileLocale returns the locale of the requester for i18n.Middleware. A
+// failed lookup falls back to Accept-Language rather than failing the
+// request.
+func ProfileLocale(repo *Repository) func(r *http.Request) (i18n.Locale, bool) {
+	return func(r *http.Request) (i18n.Locale, bool) {
+		userIDStr, ok := UserIDFromContext(r.Context())
+		if !ok {
+			return "", false
+		}
+		userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+		if err != nil {
+			return "", false
+		}
+
+		locale, err := repo.UserLocale(r.Context(), userID)
+		if err != nil {
+			log.Printf("load locale of user %s failed: %v", userID, err)
+			return "", false
+		}
+		return i18n.Parse(locale)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"strings"
+
+	"tm-platform-backend/internal/problem"
 )
 
 type contextKey string
@@ -16,18 +18,18 @@ func JwtMiddleware(svc *Service) func(http.Handler) http.Handler {
 			header := r.Header.Get("Authorization")
 			parts := strings.SplitN(header, " ", 2)
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing token"})
+				problem.Write(w, http.StatusUnauthorized, "missing_token", "missing token")
 				return
 			}
 
 			claims, err := svc.ParseToken(parts[1], TokenTypeAccess)
 			if err != nil {
-				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+				problem.Write(w, http.StatusUnauthorized, "invalid_token", "invalid token")
 				return
 			}
 
 			if claims.Subject == "" {
-				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token subject"})
+				problem.Write(w, http.StatusUnauthorized, "invalid_token_subject", "invalid token subject")
 				return
 			}
 
@@ -17,13 +17,17 @@ type User struct {
 	DepartmentID   *uuid.UUID `json:"department_id,omitempty" db:"department_id"`
 	DepartmentName *string    `json:"department_name,omitempty" db:"department_name"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	Locale         *string    `json:"locale,omitempty" db:"locale"`
+	Timezone       *string    `json:"timezone,omitempty" db:"timezone"`
 }
 
 type Department struct {
-	ID        uuid.UUID  `json:"id" db:"id"`
-	Name      string     `json:"name" db:"name"`
-	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Name            string     `json:"name" db:"name"`
+	ParentID        *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	IsSystem        bool       `json:"is_system" db:"is_system"`
+	HierarchyNodeID *uuid.UUID `json:"hierarchy_node_id,omitempty" db:"hierarchy_node_id"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 }
 
 type RefreshTokenRecord struct {
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/auth/register", Tag: "auth", Summary: "Register an account", Public: true, Body: registerRequest{}, Status: http.StatusCreated, Response: userResponse{}},
+	{Method: http.MethodPost, Path: "/auth/login", Tag: "auth", Summary: "Log in with email and password", Public: true, Body: authRequest{}, Response: authResponse{}},
+	{Method: http.MethodPost, Path: "/auth/refresh", Tag: "auth", Summary: "Exchange a refresh token, from the body or the cookie, for a new pair", Public: true, Body: refreshRequest{}, Response: authResponse{}},
+	{Method: http.MethodGet, Path: "/users", Tag: "users", Summary: "List users", Response: userResponse{}, List: true},
+	{Method: http.MethodGet, Path: "/users/{id}", Tag: "users", Summary: "Get a user profile", Response: userResponse{}},
+	{Method: http.MethodPatch, Path: "/users/{id}/profile", Tag: "users", Summary: "Update a user profile", Body: updateProfileRequest{}, Response: userResponse{}},
+	{Method: http.MethodPut, Path: "/users/{id}/hierarchy", Tag: "hierarchy", Summary: "Set the role, manager and department of a user", Body: updateUserHierarchyRequest{}, Response: userResponse{}},
+	{Method: http.MethodGet, Path: "/users/{id}/manager", Tag: "hierarchy", Summary: "Get the manager of a user, null when there is none", Response: userResponse{}},
+	{Method: http.MethodGet, Path: "/users/{id}/subordinates", Tag: "hierarchy", Summary: "List the direct subordinates of a user", Response: userResponse{}, List: true},
+	{Method: http.MethodGet, Path: "/hierarchy", Tag: "hierarchy", Summary: "Get the reporting tree of users", Response: hierarchyNode{}, List: true},
+	{Method: http.MethodPost, Path: "/departments", Tag: "hierarchy", Summary: "Create a department", Body: createDepartmentRequest{}, Status: http.StatusCreated, Response: departmentResponse{}},
+	{Method: http.MethodGet, Path: "/departments", Tag: "hierarchy", Summary: "List departments", Response: departmentResponse{}, List: true},
+}
@@ -6,11 +6,15 @@ import (
 	"errors"
 	"time"
 
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/db"
+
 	"github.com/google/uuid"
 )
 
 type Repository struct {
-	db *sql.DB
+	db    *sql.DB
+	cache cache.Store
 }
 
 type userScanner interface {
@@ -24,25 +28,61 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithCache caches the locales of users in store.
+func (r *Repository) WithCache(store cache.Store) *Repository {
+	r.cache = store
+	return r
+}
+
+// CreateUser registers a user and joins them to every workspace with open
+// signup, as its admin when it has none yet.
 func (r *Repository) CreateUser(ctx context.Context, email, passwordHash string, fullName *string) (User, error) {
-	row := r.db.QueryRowContext(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
 		ctx,
 		`INSERT INTO users (email, password_hash, full_name) VALUES ($1, $2, $3)
-		 RETURNING id, full_name, avatar_url, email, password_hash, role, manager_id, department_id, NULL::TEXT AS department_name, created_at`,
+		 RETURNING id, full_name, avatar_url, email, password_hash, role, manager_id, department_id, NULL::TEXT AS department_name, created_at, locale, timezone`,
 		email,
 		passwordHash,
 		fullName,
 	)
 
 	var user User
-	err := scanUser(row, &user)
-	return user, err
+	if err := scanUser(row, &user); err != nil {
+		return User{}, err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO workspace_members (workspace_id, user_id, role)
+		 SELECT w.id, $1,
+		 	CASE
+		 		WHEN EXISTS (SELECT 1 FROM workspace_members wm WHERE wm.workspace_id = w.id AND wm.role = 'admin') THEN 'member'
+		 		ELSE 'admin'
+		 	END
+		 FROM workspaces w
+		 WHERE w.open_signup
+		 ON CONFLICT (workspace_id, user_id) DO NOTHING`,
+		user.ID,
+	); err != nil {
+		return User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return User{}, err
+	}
+	return user, nil
 }
 
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at
+		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
 		 FROM users u
 		 LEFT JOIN departments d ON d.id = u.department_id
 		 WHERE u.email = $1`,
@@ -57,11 +97,30 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (User, er
 func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at
+		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
+		 FROM users u
+		 LEFT JOIN departments d ON d.id = u.department_id
+		 WHERE u.id = $1`,
+		id,
+	)
+
+	var user User
+	err := scanUser(row, &user)
+	return user, err
+}
+
+// GetWorkspaceUserByID is GetUserByID for the members of the current
+// workspace only.
+func (r *Repository) GetWorkspaceUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
 		 FROM users u
+		 JOIN workspace_members wm ON wm.user_id = u.id AND wm.workspace_id = $2
 		 LEFT JOIN departments d ON d.id = u.department_id
 		 WHERE u.id = $1`,
 		id,
+		db.Workspace(ctx),
 	)
 
 	var user User
@@ -72,11 +131,13 @@ func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (User, error
 func (r *Repository) ListUsersByManagerID(ctx context.Context, managerID uuid.UUID) ([]User, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at
+		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
 		 FROM users u
+		 JOIN workspace_members wm ON wm.user_id = u.id AND wm.workspace_id = $2
 		 LEFT JOIN departments d ON d.id = u.department_id
 		 WHERE u.manager_id = $1`,
 		managerID,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -102,9 +163,11 @@ func (r *Repository) ListUsersByManagerID(ctx context.Context, managerID uuid.UU
 func (r *Repository) ListUsers(ctx context.Context) ([]User, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at
+		`SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
 		 FROM users u
+		 JOIN workspace_members wm ON wm.user_id = u.id AND wm.workspace_id = $1
 		 LEFT JOIN departments d ON d.id = u.department_id`,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -130,21 +193,24 @@ func (r *Repository) ListUsers(ctx context.Context) ([]User, error) {
 func (r *Repository) GetDepartmentByID(ctx context.Context, id uuid.UUID) (Department, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, name, parent_id, created_at FROM departments WHERE id = $1`,
+		`SELECT id, name, parent_id, is_system, hierarchy_node_id, created_at FROM departments WHERE id = $1 AND workspace_id = $2`,
 		id,
+		db.Workspace(ctx),
 	)
 
 	var department Department
-	err := row.Scan(&department.ID, &department.Name, &department.ParentID, &department.CreatedAt)
+	err := row.Scan(&department.ID, &department.Name, &department.ParentID, &department.IsSystem, &department.HierarchyNodeID, &department.CreatedAt)
 	return department, err
 }
 
 func (r *Repository) ListDepartments(ctx context.Context) ([]Department, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT id, name, parent_id, created_at
+		`SELECT id, name, parent_id, is_system, hierarchy_node_id, created_at
 		 FROM departments
-		 ORDER BY name ASC`,
+		 WHERE workspace_id = $1
+		 ORDER BY is_system DESC, name ASC`,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -154,7 +220,7 @@ func (r *Repository) ListDepartments(ctx context.Context) ([]Department, error)
 	var departments []Department
 	for rows.Next() {
 		var department Department
-		if err := rows.Scan(&department.ID, &department.Name, &department.ParentID, &department.CreatedAt); err != nil {
+		if err := rows.Scan(&department.ID, &department.Name, &department.ParentID, &department.IsSystem, &department.HierarchyNodeID, &department.CreatedAt); err != nil {
 			return nil, err
 		}
 		departments = append(departments, department)
@@ -170,15 +236,16 @@ func (r *Repository) ListDepartments(ctx context.Context) ([]Department, error)
 func (r *Repository) CreateDepartment(ctx context.Context, name string, parentID *uuid.UUID) (Department, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO departments (name, parent_id)
-		 VALUES ($1, $2)
-		 RETURNING id, name, parent_id, created_at`,
+		`INSERT INTO departments (name, parent_id, workspace_id)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, name, parent_id, is_system, hierarchy_node_id, created_at`,
 		name,
 		parentID,
+		db.Workspace(ctx),
 	)
 
 	var department Department
-	err := row.Scan(&department.ID, &department.Name, &department.ParentID, &department.CreatedAt)
+	err := row.Scan(&department.ID, &department.Name, &department.ParentID, &department.IsSystem, &department.HierarchyNodeID, &department.CreatedAt)
 	return department, err
 }
 
@@ -191,9 +258,9 @@ func (r *Repository) UpdateUserHierarchy(ctx context.Context, userID uuid.UUID,
 			    manager_id = $3,
 			    department_id = $4
 			WHERE id = $1
-			RETURNING id, full_name, avatar_url, email, password_hash, role, manager_id, department_id, created_at
+			RETURNING id, full_name, avatar_url, email, password_hash, role, manager_id, department_id, created_at, locale, timezone
 		)
-		SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at
+		SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
 		FROM updated u
 		LEFT JOIN departments d ON d.id = u.department_id`,
 		userID,
@@ -207,29 +274,36 @@ func (r *Repository) UpdateUserHierarchy(ctx context.Context, userID uuid.UUID,
 	return user, err
 }
 
-func (r *Repository) UpdateUserProfile(ctx context.Context, userID uuid.UUID, email string, fullName, avatarURL *string) (User, error) {
+func (r *Repository) UpdateUserProfile(ctx context.Context, userID uuid.UUID, email string, fullName, avatarURL, locale, timezone *string) (User, error) {
 	row := r.db.QueryRowContext(
 		ctx,
 		`WITH updated AS (
 			UPDATE users
 			SET email = $2,
 			    full_name = $3,
-			    avatar_url = $4
+			    avatar_url = $4,
+			    locale = $5,
+			    timezone = $6
 			WHERE id = $1
-			RETURNING id, full_name, avatar_url, email, password_hash, role, manager_id, department_id, created_at
+			RETURNING id, full_name, avatar_url, email, password_hash, role, manager_id, department_id, created_at, locale, timezone
 		)
-		SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at
+		SELECT u.id, u.full_name, u.avatar_url, u.email, u.password_hash, u.role, u.manager_id, u.department_id, d.name, u.created_at, u.locale, u.timezone
 		FROM updated u
 		LEFT JOIN departments d ON d.id = u.department_id`,
 		userID,
 		email,
 		fullName,
 		avatarURL,
+		locale,
+		timezone,
 	)
 
 	var user User
-	err := scanUser(row, &user)
-	return user, err
+	if err := scanUser(row, &user); err != nil {
+		return User{}, err
+	}
+	cache.Invalidate(ctx, r.cache, localeCacheKey(userID))
+	return user, nil
 }
 
 func scanUser(scanner userScanner, user *User) error {
@@ -244,6 +318,8 @@ func scanUser(scanner userScanner, user *User) error {
 		&user.DepartmentID,
 		&user.DepartmentName,
 		&user.CreatedAt,
+		&user.Locale,
+		&user.Timezone,
 	)
 }
 
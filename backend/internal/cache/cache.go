@@ -0,0 +1,152 @@
+// Package cache keeps the results of hot aggregate queries, such as budget
+// summaries and unread counts, for a short time. Values are stored as JSON
+// in Redis when it is configured, so replicas share them and see each
+// other's invalidations, or in process otherwise.
+//
+// Repositories read through Load and call Invalidate after every write that
+// changes a cached value; the TTL only bounds staleness from writes made
+// outside the repository.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMiss is returned by Store.Get for keys without a value.
+var ErrMiss = errors.New("cache miss")
+
+// Store keeps encoded values by key.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Load returns the cached value of key, or the result of load, which it
+// caches for ttl. Failures of the store are logged and fall back to load, so
+// the cache never fails a request. A nil store always loads.
+func Load[T any](ctx context.Context, store Store, key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	if store == nil {
+		return load()
+	}
+
+	raw, err := store.Get(ctx, key)
+	if err == nil {
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			return value, nil
+		}
+	} else if !errors.Is(err, ErrMiss) {
+		log.Printf("cache get %s failed: %v", key, err)
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if raw, err := json.Marshal(value); err == nil {
+		if err := store.Set(ctx, key, raw, ttl); err != nil {
+			log.Printf("cache set %s failed: %v", key, err)
+		}
+	}
+	return value, nil
+}
+
+// Invalidate drops the values of keys. Failures are logged; the values then
+// expire with their TTL.
+func Invalidate(ctx context.Context, store Store, keys ...string) {
+	if store == nil || len(keys) == 0 {
+		return
+	}
+	if err := store.Delete(ctx, keys...); err != nil {
+		log.Printf("cache invalidate %v failed: %v", keys, err)
+	}
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory is an in-process Store. Every replica keeps its own values, and
+// invalidations made by one replica do not reach the others.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	sweepAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, ErrMiss
+	}
+	return entry.value, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key] = memoryEntry{value: value, expiresAt: now.Add(ttl)}
+	if now.After(m.sweepAt) {
+		for k, entry := range m.entries {
+			if now.After(entry.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+		m.sweepAt = now.Add(time.Minute)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Redis is a Store shared by all replicas.
+type Redis struct {
+	client *redis.Client
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
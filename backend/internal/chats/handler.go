@@ -11,7 +11,13 @@ import (
 	"time"
 
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/i18n"
 	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+	"tm-platform-backend/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -20,21 +26,27 @@ import (
 type Handler struct {
 	repo              *Repository
 	notificationsRepo *notifications.Repository
+	store             storage.Storage
+	events            *events.Bus
 }
 
-func NewHandler(repo *Repository, notificationsRepo *notifications.Repository) *Handler {
-	return &Handler{repo: repo, notificationsRepo: notificationsRepo}
+func NewHandler(repo *Repository, notificationsRepo *notifications.Repository, store storage.Storage) *Handler {
+	return &Handler{repo: repo, notificationsRepo: notificationsRepo, store: store}
+}
+
+// WithEvents publishes the messages sent through h on bus.
+func (h *Handler) WithEvents(bus *events.Bus) *Handler {
+	h.events = bus
+	return h
 }
 
 type ensureDirectThreadRequest struct {
-	UserID    *string `json:"user_id"`
-	UserIDAlt *string `json:"userId"`
+	UserID string `json:"user_id" validate:"required,uuid"`
 }
 
 type createGroupThreadRequest struct {
-	Name         *string  `json:"name"`
-	MemberIDs    []string `json:"member_ids"`
-	MemberIDsAlt []string `json:"memberIds"`
+	Name      *string  `json:"name" validate:"required"`
+	MemberIDs []string `json:"member_ids" validate:"uuid"`
 }
 
 type renameThreadRequest struct {
@@ -46,58 +58,68 @@ type callInviteRequest struct {
 }
 
 type appendMessageRequest struct {
-	Text            *string `json:"text"`
-	AttachmentURL   *string `json:"attachment_url"`
-	AttachmentURL2  *string `json:"attachmentUrl"`
-	AttachmentType  *string `json:"attachment_type"`
-	AttachmentType2 *string `json:"attachmentType"`
-	AttachmentName  *string `json:"attachment_name"`
-	AttachmentName2 *string `json:"attachmentName"`
+	Text           *string `json:"text"`
+	AttachmentURL  *string `json:"attachment_url"`
+	AttachmentType *string `json:"attachment_type"`
+	AttachmentName *string `json:"attachment_name"`
 }
 
 func (h *Handler) TouchPresence(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	if err := h.repo.UpsertPresence(r.Context(), userID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update presence"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update presence")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
+// usersPageLimits bound the pages of ListUsers
+var usersPageLimits = pagination.Limits{Default: 40, Max: 200}
+
 func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
-	limit := parseLimit(r.URL.Query().Get("limit"), 40)
-	items, err := h.repo.ListUsers(r.Context(), userID, limit)
+	page, err := pagination.Parse(r, usersPageLimits)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load users"})
+		pagination.WriteError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, items)
+	items, err := h.repo.ListUsers(r.Context(), userID, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to load users")
+		return
+	}
+
+	pagination.SetHeaders(w, r, items)
+	writeJSON(w, http.StatusOK, items.Items)
 }
 
 func (h *Handler) ListThreads(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	limit := parseLimit(r.URL.Query().Get("limit"), 60)
 	items, err := h.repo.ListThreads(r.Context(), userID, limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load chats"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load chats")
 		return
 	}
 
@@ -107,13 +129,13 @@ func (h *Handler) ListThreads(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UnreadCount(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	count, err := h.repo.UnreadCount(r.Context(), userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to count unread chats"})
+		problem.Error(w, http.StatusInternalServerError, "failed to count unread chats")
 		return
 	}
 
@@ -123,37 +145,26 @@ func (h *Handler) UnreadCount(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) EnsureDirectThread(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req ensureDirectThreadRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
-		return
-	}
-
-	targetRaw := firstNonNilString(req.UserID, req.UserIDAlt)
-	if targetRaw == nil || strings.TrimSpace(*targetRaw) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "userId is required"})
-		return
-	}
-
-	targetUserID, err := uuid.Parse(strings.TrimSpace(*targetRaw))
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
+	targetUserID := uuid.MustParse(strings.TrimSpace(req.UserID))
 	thread, err := h.repo.EnsureDirectThread(r.Context(), userID, targetUserID)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrInvalidInput):
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot create chat with self"})
+			problem.Write(w, http.StatusBadRequest, "cannot_create_chat_with_self", "cannot create chat with self")
 		case errors.Is(err, sql.ErrNoRows):
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create chat"})
+			problem.Error(w, http.StatusInternalServerError, "failed to create chat")
 		}
 		return
 	}
@@ -164,35 +175,21 @@ func (h *Handler) EnsureDirectThread(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateGroupThread(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req createGroupThreadRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	name := strings.TrimSpace(stringValue(req.Name))
-	if name == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
-		return
-	}
-
-	memberIDsRaw := req.MemberIDs
-	if len(memberIDsRaw) == 0 {
-		memberIDsRaw = req.MemberIDsAlt
-	}
-
 	unique := make(map[uuid.UUID]struct{})
-	memberIDs := make([]uuid.UUID, 0, len(memberIDsRaw))
-	for _, raw := range memberIDsRaw {
-		parsed, err := uuid.Parse(strings.TrimSpace(raw))
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid member id"})
-			return
-		}
+	memberIDs := make([]uuid.UUID, 0, len(req.MemberIDs))
+	for _, raw := range req.MemberIDs {
+		parsed := uuid.MustParse(strings.TrimSpace(raw))
 		if parsed == userID {
 			continue
 		}
@@ -207,11 +204,11 @@ func (h *Handler) CreateGroupThread(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrInvalidInput):
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "group requires at least 2 members"})
+			problem.Write(w, http.StatusBadRequest, "group_requires_at_least_2_members", "group requires at least 2 members")
 		case errors.Is(err, sql.ErrNoRows):
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "member not found"})
+			problem.Write(w, http.StatusNotFound, "member_not_found", "member not found")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create group"})
+			problem.Error(w, http.StatusInternalServerError, "failed to create group")
 		}
 		return
 	}
@@ -227,8 +224,8 @@ func (h *Handler) CreateGroupThread(w http.ResponseWriter, r *http.Request) {
 				memberID,
 				&actor,
 				notifications.KindProjectMember,
-				"Вас добавили в чат",
-				"Вы добавлены в групповой чат: "+thread.Name,
+				i18n.M("notification.chat_added.title"),
+				i18n.M("notification.chat_added.body", "chat", thread.Name),
 				"/chats?id="+thread.ID.String(),
 				"chat_thread",
 				&thread.ID,
@@ -242,19 +239,19 @@ func (h *Handler) CreateGroupThread(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) RenameThread(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	threadID, err := parseThreadID(chi.URLParam(r, "threadId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid thread id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_thread_id", "invalid thread id")
 		return
 	}
 
 	var req renameThreadRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
@@ -262,11 +259,11 @@ func (h *Handler) RenameThread(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrForbidden):
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		case errors.Is(err, ErrInvalidInput):
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "chat rename is available only for group chats with non-empty name"})
+			problem.Write(w, http.StatusBadRequest, "chat_rename_not_allowed", "chat rename is available only for group chats with non-empty name")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to rename chat"})
+			problem.Error(w, http.StatusInternalServerError, "failed to rename chat")
 		}
 		return
 	}
@@ -277,25 +274,25 @@ func (h *Handler) RenameThread(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) InviteToCall(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	threadID, err := parseThreadID(chi.URLParam(r, "threadId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid thread id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_thread_id", "invalid thread id")
 		return
 	}
 
 	var req callInviteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	roomID := strings.TrimSpace(stringValue(req.RoomID))
 	if roomID == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "roomId is required"})
+		problem.Write(w, http.StatusBadRequest, "room_id_required", "roomId is required")
 		return
 	}
 
@@ -303,9 +300,9 @@ func (h *Handler) InviteToCall(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrForbidden):
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load chat"})
+			problem.Error(w, http.StatusInternalServerError, "failed to load chat")
 		}
 		return
 	}
@@ -314,17 +311,17 @@ func (h *Handler) InviteToCall(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrForbidden):
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load chat members"})
+			problem.Error(w, http.StatusInternalServerError, "failed to load chat members")
 		}
 		return
 	}
 
 	if h.notificationsRepo != nil {
-		chatName := strings.TrimSpace(thread.Name)
-		if chatName == "" {
-			chatName = "Чат"
+		body := i18n.M("notification.call_invite.body_unnamed")
+		if chatName := strings.TrimSpace(thread.Name); chatName != "" {
+			body = i18n.M("notification.call_invite.body", "chat", chatName)
 		}
 
 		callLink := "/chats?id=" + threadID.String() + "&callRoom=" + url.QueryEscape(roomID)
@@ -339,8 +336,8 @@ func (h *Handler) InviteToCall(w http.ResponseWriter, r *http.Request) {
 				memberID,
 				&actor,
 				notifications.KindCallInvite,
-				"Вас зовут на видеозвонок",
-				"Подключиться к звонку в чате: "+chatName,
+				i18n.M("notification.call_invite.title"),
+				body,
 				callLink,
 				"chat_call",
 				&threadID,
@@ -354,20 +351,20 @@ func (h *Handler) InviteToCall(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	threadID, err := parseThreadID(chi.URLParam(r, "threadId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid thread id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_thread_id", "invalid thread id")
 		return
 	}
 
 	limit := parseLimit(r.URL.Query().Get("limit"), 80)
 	before, err := parseOptionalTime(r.URL.Query().Get("before"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid before"})
+		problem.Write(w, http.StatusBadRequest, "invalid_before", "invalid before")
 		return
 	}
 
@@ -375,9 +372,9 @@ func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrForbidden):
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load messages"})
+			problem.Error(w, http.StatusInternalServerError, "failed to load messages")
 		}
 		return
 	}
@@ -388,39 +385,53 @@ func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AppendMessage(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	threadID, err := parseThreadID(chi.URLParam(r, "threadId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid thread id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_thread_id", "invalid thread id")
 		return
 	}
 
 	var req appendMessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
+	attachmentURL := req.AttachmentURL
+	if attachmentURL != nil && h.store != nil {
+		if key, ok := storage.KeyFromURL(*attachmentURL); ok {
+			if _, err := h.store.Stat(r.Context(), key); err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					problem.Write(w, http.StatusBadRequest, "attachment_not_found", "attachment not found")
+					return
+				}
+				problem.Error(w, http.StatusInternalServerError, "failed to send message")
+				return
+			}
+		}
+	}
+
 	message, err := h.repo.AppendMessage(
 		r.Context(),
 		userID,
 		threadID,
 		req.Text,
-		firstNonNilString(req.AttachmentURL, req.AttachmentURL2),
-		firstNonNilString(req.AttachmentType, req.AttachmentType2),
-		firstNonNilString(req.AttachmentName, req.AttachmentName2),
+		attachmentURL,
+		req.AttachmentType,
+		req.AttachmentName,
 	)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrForbidden):
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		case errors.Is(err, ErrInvalidInput):
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message is empty"})
+			problem.Write(w, http.StatusBadRequest, "message_required", "message is empty")
 		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to send message"})
+			problem.Error(w, http.StatusInternalServerError, "failed to send message")
 		}
 		return
 	}
@@ -433,13 +444,13 @@ func (h *Handler) AppendMessage(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 
-				body := "Вам отправили сообщение"
+				body := i18n.M("notification.chat_message.body")
 				if message.Text != nil && strings.TrimSpace(*message.Text) != "" {
 					text := strings.TrimSpace(*message.Text)
 					if len(text) > 120 {
 						text = text[:120] + "..."
 					}
-					body = text
+					body = i18n.Text(text)
 				}
 
 				actor := userID
@@ -448,7 +459,7 @@ func (h *Handler) AppendMessage(w http.ResponseWriter, r *http.Request) {
 					memberID,
 					&actor,
 					notifications.KindTaskComment,
-					"Новое сообщение в чате",
+					i18n.M("notification.chat_message.title"),
 					body,
 					"/chats?id="+threadID.String(),
 					"chat_message",
@@ -458,6 +469,12 @@ func (h *Handler) AppendMessage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.events.Publish(r.Context(), events.ChatMessageSent{
+		MessageID: message.ID,
+		ThreadID:  message.ThreadID,
+		SenderID:  userID,
+	})
+
 	writeJSON(w, http.StatusCreated, message)
 }
 
@@ -496,15 +513,6 @@ func parseOptionalTime(raw string) (*time.Time, error) {
 	return &parsed, nil
 }
 
-func firstNonNilString(values ...*string) *string {
-	for _, value := range values {
-		if value != nil {
-			return value
-		}
-	}
-	return nil
-}
-
 func stringValue(value *string) string {
 	if value == nil {
 		return ""
@@ -0,0 +1,32 @@
+package chats
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+type okResponse struct {
+	OK bool `json:"ok"`
+}
+
+func limitParam(fallback string) openapi.Param {
+	return openapi.Param{Name: "limit", Kind: "integer", Description: "Page size, " + fallback + " by default"}
+}
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/chats/presence", Tag: "chats", Summary: "Mark the caller online", Response: okResponse{}},
+	{Method: http.MethodGet, Path: "/chats/unread-count", Tag: "chats", Summary: "Count unread chat messages", Response: openapi.Count{}},
+	{Method: http.MethodGet, Path: "/chats/users", Tag: "chats", Summary: "List users the caller can message", Response: UserItem{}, List: true, Page: &usersPageLimits},
+	{Method: http.MethodGet, Path: "/chats/threads", Tag: "chats", Summary: "List the threads of the caller", Query: []openapi.Param{limitParam("60")}, Response: ThreadItem{}, List: true},
+	{Method: http.MethodPost, Path: "/chats/threads/direct", Tag: "chats", Summary: "Open the direct thread with a user", Body: ensureDirectThreadRequest{}, Status: http.StatusCreated, Response: ThreadItem{}},
+	{Method: http.MethodPost, Path: "/chats/threads/group", Tag: "chats", Summary: "Create a group thread", Body: createGroupThreadRequest{}, Status: http.StatusCreated, Response: ThreadItem{}},
+	{Method: http.MethodPatch, Path: "/chats/threads/{threadId}", Tag: "chats", Summary: "Rename a group thread", Body: renameThreadRequest{}, Response: ThreadItem{}},
+	{Method: http.MethodPost, Path: "/chats/threads/{threadId}/call-invite", Tag: "chats", Summary: "Invite the members of a thread to a call", Body: callInviteRequest{}, Response: okResponse{}},
+	{Method: http.MethodGet, Path: "/chats/threads/{threadId}/messages", Tag: "chats", Summary: "List the messages of a thread, newest first", Query: []openapi.Param{
+		limitParam("80"),
+		{Name: "before", Kind: "string", Description: "RFC 3339 time; only messages sent before it"},
+	}, Response: Message{}, List: true},
+	{Method: http.MethodPost, Path: "/chats/threads/{threadId}/messages", Tag: "chats", Summary: "Send a message", Body: appendMessageRequest{}, Status: http.StatusCreated, Response: Message{}},
+}
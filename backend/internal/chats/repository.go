@@ -7,6 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/storage"
+
 	"github.com/google/uuid"
 )
 
@@ -16,13 +21,61 @@ var (
 )
 
 type Repository struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB
+	cache   cache.Store
 }
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithCache caches unread counts in store.
+func (r *Repository) WithCache(store cache.Store) *Repository {
+	r.cache = store
+	return r
+}
+
+// WithReplica sends the heavy reads of the repository to the read replica
+// conn, see db.Reader.
+func (r *Repository) WithReplica(conn *sql.DB) *Repository {
+	r.replica = conn
+	return r
+}
+
+func (r *Repository) reader(ctx context.Context) *sql.DB {
+	return db.Reader(ctx, r.db, r.replica)
+}
+
+const unreadCacheTTL = time.Minute
+
+func unreadCacheKey(userID uuid.UUID) string {
+	return "chats:unread:" + userID.String()
+}
+
+// invalidateUnread drops the cached unread counts of the members of a
+// thread after a message was posted to it.
+func (r *Repository) invalidateUnread(ctx context.Context, threadID uuid.UUID) {
+	if r.cache == nil {
+		return
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM chat_thread_members WHERE thread_id = $1`, threadID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var memberID uuid.UUID
+		if err := rows.Scan(&memberID); err != nil {
+			return
+		}
+		keys = append(keys, unreadCacheKey(memberID))
+	}
+	cache.Invalidate(ctx, r.cache, keys...)
+}
+
 func (r *Repository) UpsertPresence(ctx context.Context, userID uuid.UUID) error {
 	_, err := r.db.ExecContext(
 		ctx,
@@ -35,52 +88,80 @@ func (r *Repository) UpsertPresence(ctx context.Context, userID uuid.UUID) error
 	return err
 }
 
-func (r *Repository) ListUsers(ctx context.Context, requesterID uuid.UUID, limit int) ([]UserItem, error) {
-	if limit <= 0 || limit > 200 {
-		limit = 50
+// userKey is the sort key of ListUsers. Presence changes between requests
+// can move a user across pages; the email keeps the order total.
+type userKey struct {
+	Online     bool      `json:"online"`
+	ActivityAt time.Time `json:"activityAt"`
+	Email      string    `json:"email"`
+}
+
+func (r *Repository) ListUsers(ctx context.Context, requesterID uuid.UUID, page pagination.Page) (pagination.List[UserItem], error) {
+	var key userKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[UserItem]{}, err
 	}
 
-	rows, err := r.db.QueryContext(
-		ctx,
-		`SELECT
-			u.id::text,
-			u.email,
-			u.full_name,
-			u.avatar_url,
-			u.role,
-			d.name,
-			COALESCE(cp.last_seen > now() - INTERVAL '60 seconds', false) AS online,
-			cp.last_seen,
-			dt.thread_id::text,
-			lm.text,
-			lm.attachment_type,
-			lm.created_at,
-			lm.sender_id::text
-		FROM users u
-		LEFT JOIN departments d ON d.id = u.department_id
-		LEFT JOIN chat_user_presence cp ON cp.user_id = u.id
-		LEFT JOIN chat_direct_threads dt
-			ON (dt.user_a_id = $1 AND dt.user_b_id = u.id)
-			OR (dt.user_b_id = $1 AND dt.user_a_id = u.id)
-		LEFT JOIN LATERAL (
-			SELECT m.text, m.attachment_type, m.created_at, m.sender_id
-			FROM chat_messages m
-			WHERE m.thread_id = dt.thread_id
-			ORDER BY m.created_at DESC
-			LIMIT 1
-		) lm ON true
-		WHERE u.id <> $1
-		ORDER BY online DESC, COALESCE(lm.created_at, cp.last_seen, u.created_at) DESC, u.email ASC
-		LIMIT $2`,
-		requesterID,
-		limit,
-	)
+	query := `SELECT id, email, full_name, avatar_url, role, department_name, online, last_seen,
+			thread_id, last_message, last_message_type, last_message_at, last_message_sender_id, activity_at, COUNT(*) OVER ()
+		FROM (
+			SELECT
+				u.id::text AS id,
+				u.email,
+				u.full_name,
+				u.avatar_url,
+				u.role,
+				d.name AS department_name,
+				COALESCE(cp.last_seen > now() - INTERVAL '60 seconds', false) AS online,
+				cp.last_seen,
+				dt.thread_id::text AS thread_id,
+				lm.text AS last_message,
+				lm.attachment_type AS last_message_type,
+				lm.created_at AS last_message_at,
+				lm.sender_id::text AS last_message_sender_id,
+				COALESCE(lm.created_at, cp.last_seen, u.created_at) AS activity_at
+			FROM users u
+			JOIN workspace_members wm ON wm.user_id = u.id AND wm.workspace_id = $3
+			LEFT JOIN departments d ON d.id = u.department_id
+			LEFT JOIN chat_user_presence cp ON cp.user_id = u.id
+			LEFT JOIN chat_direct_threads dt
+				ON dt.workspace_id = $3
+				AND (
+					(dt.user_a_id = $1 AND dt.user_b_id = u.id)
+					OR (dt.user_b_id = $1 AND dt.user_a_id = u.id)
+				)
+			LEFT JOIN LATERAL (
+				SELECT m.text, m.attachment_type, m.created_at, m.sender_id
+				FROM chat_messages m
+				WHERE m.thread_id = dt.thread_id
+				ORDER BY m.created_at DESC
+				LIMIT 1
+			) lm ON true
+			WHERE u.id <> $1
+		) listed`
+	args := []any{requesterID, page.Fetch(), db.Workspace(ctx)}
+	if after {
+		query += `
+		WHERE online < $4
+			OR (online = $4 AND (activity_at < $5 OR (activity_at = $5 AND email > $6)))`
+		args = append(args, key.Online, key.ActivityAt, key.Email)
+	}
+	query += `
+		ORDER BY online DESC, activity_at DESC, email ASC
+		LIMIT $2`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return pagination.List[UserItem]{}, err
 	}
 	defer rows.Close()
 
-	items := make([]UserItem, 0)
+	var (
+		items []UserItem
+		keys  []userKey
+		total int
+	)
 	for rows.Next() {
 		var (
 			item                UserItem
@@ -93,6 +174,7 @@ func (r *Repository) ListUsers(ctx context.Context, requesterID uuid.UUID, limit
 			lastMessageType     sql.NullString
 			lastMessageAt       sql.NullTime
 			lastMessageSenderID sql.NullString
+			activityAt          time.Time
 		)
 
 		if err := rows.Scan(
@@ -109,13 +191,15 @@ func (r *Repository) ListUsers(ctx context.Context, requesterID uuid.UUID, limit
 			&lastMessageType,
 			&lastMessageAt,
 			&lastMessageSenderID,
+			&activityAt,
+			&total,
 		); err != nil {
-			return nil, err
+			return pagination.List[UserItem]{}, err
 		}
 
 		parsedID, err := uuid.Parse(idRaw)
 		if err != nil {
-			return nil, err
+			return pagination.List[UserItem]{}, err
 		}
 		item.ID = parsedID
 		item.FullName = nullableString(fullName)
@@ -137,9 +221,20 @@ func (r *Repository) ListUsers(ctx context.Context, requesterID uuid.UUID, limit
 		item.LastMessageSender = parseNullableUUID(lastMessageSenderID)
 
 		items = append(items, item)
+		keys = append(keys, userKey{Online: item.Online, ActivityAt: activityAt, Email: item.Email})
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.List[UserItem]{}, err
 	}
 
-	return items, rows.Err()
+	list, err := pagination.Cut(items, page, func(i int) any { return keys[i] })
+	if err != nil {
+		return pagination.List[UserItem]{}, err
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
 }
 
 func (r *Repository) EnsureDirectThread(ctx context.Context, requesterID, targetUserID uuid.UUID) (ThreadItem, error) {
@@ -147,8 +242,9 @@ func (r *Repository) EnsureDirectThread(ctx context.Context, requesterID, target
 		return ThreadItem{}, ErrInvalidInput
 	}
 
+	workspaceID := db.Workspace(ctx)
 	var targetExists bool
-	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, targetUserID).Scan(&targetExists); err != nil {
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM workspace_members WHERE user_id = $1 AND workspace_id = $2)`, targetUserID, workspaceID).Scan(&targetExists); err != nil {
 		return ThreadItem{}, err
 	}
 	if !targetExists {
@@ -170,7 +266,8 @@ func (r *Repository) EnsureDirectThread(ctx context.Context, requesterID, target
 		ctx,
 		`SELECT thread_id::text
 		 FROM chat_direct_threads
-		 WHERE user_a_id = $1 AND user_b_id = $2`,
+		 WHERE workspace_id = $1 AND user_a_id = $2 AND user_b_id = $3`,
+		workspaceID,
 		userA,
 		userB,
 	).Scan(&threadIDRaw)
@@ -194,10 +291,11 @@ func (r *Repository) EnsureDirectThread(ctx context.Context, requesterID, target
 	var newThreadIDRaw string
 	if err := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO chat_threads (is_group, created_by)
-		 VALUES (false, $1)
+		`INSERT INTO chat_threads (is_group, created_by, workspace_id)
+		 VALUES (false, $1, $2)
 		 RETURNING id::text`,
 		requesterID,
+		workspaceID,
 	).Scan(&newThreadIDRaw); err != nil {
 		return ThreadItem{}, err
 	}
@@ -205,11 +303,12 @@ func (r *Repository) EnsureDirectThread(ctx context.Context, requesterID, target
 	var mappedThreadIDRaw sql.NullString
 	if err := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO chat_direct_threads (user_a_id, user_b_id, thread_id)
-		 VALUES ($1, $2, $3)
-		 ON CONFLICT (user_a_id, user_b_id)
+		`INSERT INTO chat_direct_threads (workspace_id, user_a_id, user_b_id, thread_id)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (workspace_id, user_a_id, user_b_id)
 		 DO NOTHING
 		 RETURNING thread_id::text`,
+		workspaceID,
 		userA,
 		userB,
 		newThreadIDRaw,
@@ -223,7 +322,8 @@ func (r *Repository) EnsureDirectThread(ctx context.Context, requesterID, target
 			ctx,
 			`SELECT thread_id::text
 			 FROM chat_direct_threads
-			 WHERE user_a_id = $1 AND user_b_id = $2`,
+			 WHERE workspace_id = $1 AND user_a_id = $2 AND user_b_id = $3`,
+			workspaceID,
 			userA,
 			userB,
 		).Scan(&threadIDForMembers); err != nil {
@@ -267,7 +367,7 @@ func (r *Repository) CreateGroupThread(ctx context.Context, requesterID uuid.UUI
 
 	for _, memberID := range memberIDs {
 		var exists bool
-		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, memberID).Scan(&exists); err != nil {
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM workspace_members WHERE user_id = $1 AND workspace_id = $2)`, memberID, db.Workspace(ctx)).Scan(&exists); err != nil {
 			return ThreadItem{}, err
 		}
 		if !exists {
@@ -278,11 +378,12 @@ func (r *Repository) CreateGroupThread(ctx context.Context, requesterID uuid.UUI
 	var threadIDRaw string
 	if err := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO chat_threads (is_group, title, created_by)
-		 VALUES (true, $1, $2)
+		`INSERT INTO chat_threads (is_group, title, created_by, workspace_id)
+		 VALUES (true, $1, $2, $3)
 		 RETURNING id::text`,
 		title,
 		requesterID,
+		db.Workspace(ctx),
 	).Scan(&threadIDRaw); err != nil {
 		return ThreadItem{}, err
 	}
@@ -398,10 +499,12 @@ func (r *Repository) ListThreads(ctx context.Context, userID uuid.UUID, limit in
 			LIMIT 1
 		) m ON true
 		WHERE me.user_id = $1
+		  AND t.workspace_id = $3
 		ORDER BY COALESCE(m.created_at, t.updated_at) DESC
 		LIMIT $2`,
 		userID,
 		limit,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -421,21 +524,23 @@ func (r *Repository) ListThreads(ctx context.Context, userID uuid.UUID, limit in
 }
 
 func (r *Repository) UnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
-	var count int
-	err := r.db.QueryRowContext(
-		ctx,
-		`SELECT COUNT(*)::int
-		 FROM chat_messages m
-		 JOIN chat_thread_members me ON me.thread_id = m.thread_id
-		 WHERE me.user_id = $1
-		   AND m.sender_id <> $1
-		   AND m.created_at > COALESCE(me.last_read_at, 'epoch'::timestamptz)`,
-		userID,
-	).Scan(&count)
-	if err != nil {
-		return 0, err
-	}
-	return count, nil
+	return cache.Load(ctx, r.cache, unreadCacheKey(userID), unreadCacheTTL, func() (int, error) {
+		var count int
+		err := r.db.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*)::int
+			 FROM chat_messages m
+			 JOIN chat_thread_members me ON me.thread_id = m.thread_id
+			 WHERE me.user_id = $1
+			   AND m.sender_id <> $1
+			   AND m.created_at > COALESCE(me.last_read_at, 'epoch'::timestamptz)`,
+			userID,
+		).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		return count, nil
+	})
 }
 
 func (r *Repository) GetThread(ctx context.Context, userID, threadID uuid.UUID) (ThreadItem, error) {
@@ -636,6 +741,7 @@ func (r *Repository) ListMessages(ctx context.Context, userID, threadID uuid.UUI
 		threadID,
 		userID,
 	)
+	cache.Invalidate(ctx, r.cache, unreadCacheKey(userID))
 
 	return out, nil
 }
@@ -727,6 +833,7 @@ func (r *Repository) AppendMessage(ctx context.Context, userID, threadID uuid.UU
 		threadID,
 		userID,
 	)
+	r.invalidateUnread(ctx, threadID)
 
 	id, err := uuid.Parse(idRaw)
 	if err != nil {
@@ -821,6 +928,25 @@ func (r *Repository) ListThreadMemberIDs(ctx context.Context, requesterID, threa
 	return out, rows.Err()
 }
 
+// AttachmentAccess reports whether url is attached to any chat message and,
+// if so, whether userID is a member of one of those threads.
+func (r *Repository) AttachmentAccess(ctx context.Context, userID uuid.UUID, url string) (referenced bool, allowed bool, err error) {
+	err = r.db.QueryRowContext(
+		ctx,
+		`SELECT
+		 	EXISTS (SELECT 1 FROM chat_messages WHERE attachment_url = $1),
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM chat_messages m
+		 		JOIN chat_thread_members tm ON tm.thread_id = m.thread_id
+		 		WHERE m.attachment_url = $1 AND tm.user_id = $2
+		 	)`,
+		url,
+		userID,
+	).Scan(&referenced, &allowed)
+	return referenced, allowed, err
+}
+
 type threadScanner interface {
 	Scan(dest ...any) error
 }
@@ -987,3 +1113,118 @@ func normalizeNullableText(value *string) *string {
 	}
 	return &normalized
 }
+
+// removedAttachmentText replaces the text of a message whose only content was
+// an attachment removed by PurgeAttachmentsBefore.
+const removedAttachmentText = "Вложение удалено по истечении срока хранения"
+
+// CountAttachmentsBefore counts the attachments PurgeAttachmentsBefore would
+// remove.
+func (r *Repository) CountAttachmentsBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*)::int FROM chat_messages WHERE attachment_url IS NOT NULL AND created_at < $1`,
+		cutoff,
+	).Scan(&count)
+	return count, err
+}
+
+// PurgeAttachmentsBefore removes the attachments of up to limit messages sent
+// before cutoff; the messages themselves stay. It returns how many
+// attachments were removed and the storage keys that are no longer
+// referenced by any chat message or project file.
+func (r *Repository) PurgeAttachmentsBefore(ctx context.Context, cutoff time.Time, limit int) (purged int, keys []string, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id, attachment_url
+		 FROM chat_messages
+		 WHERE attachment_url IS NOT NULL AND created_at < $1
+		 ORDER BY created_at
+		 LIMIT $2
+		 FOR UPDATE SKIP LOCKED`,
+		cutoff,
+		limit,
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+	messageIDs := make([]uuid.UUID, 0)
+	urls := make(map[string]struct{})
+	for rows.Next() {
+		var (
+			messageID uuid.UUID
+			url       string
+		)
+		if err = rows.Scan(&messageID, &url); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		messageIDs = append(messageIDs, messageID)
+		urls[url] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return 0, nil, err
+	}
+	rows.Close()
+
+	for _, messageID := range messageIDs {
+		if _, err = tx.ExecContext(
+			ctx,
+			`UPDATE chat_messages
+			 SET text = COALESCE(NULLIF(BTRIM(text), ''), $2),
+			     attachment_url = NULL,
+			     attachment_type = NULL,
+			     attachment_name = NULL
+			 WHERE id = $1`,
+			messageID,
+			removedAttachmentText,
+		); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for url := range urls {
+		key, ok := storage.KeyFromURL(url)
+		if !ok {
+			continue
+		}
+
+		var referenced bool
+		if err = tx.QueryRowContext(
+			ctx,
+			`SELECT EXISTS (SELECT 1 FROM chat_messages WHERE attachment_url = $1)
+			     OR EXISTS (SELECT 1 FROM project_files WHERE url = $1 OR preview_url = $1)
+			     OR EXISTS (SELECT 1 FROM project_file_versions WHERE url = $1)`,
+			url,
+		).Scan(&referenced); err != nil {
+			return 0, nil, err
+		}
+		if referenced {
+			continue
+		}
+
+		if _, err = tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = $1`, key); err != nil {
+			return 0, nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return 0, nil, err
+	}
+
+	return len(messageIDs), keys, nil
+}
@@ -4,18 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	AppEnv        string
 	ServerAddr    string
 	ShutdownGrace time.Duration
+	ShutdownDelay time.Duration
 	ReadTimeout   time.Duration
 	WriteTimeout  time.Duration
 	IdleTimeout   time.Duration
@@ -27,50 +25,240 @@ type Config struct {
 	DBPassword    string
 	DBName        string
 	DBSSLMode     string
+	DBAutoMigrate bool // apply pending migrations on startup
+	// DBReadDSN points heavy reads at a read replica; empty reads from the
+	// primary. Users read from the primary for DBReadSticky after a write.
+	DBReadDSN     string
+	DBReadSticky  time.Duration
 	JWTSecret     string
 	ZHCPParserURL string
+	// ZHCPParserKey is sent to the parser, which requires it when its auth
+	// is enabled
+	ZHCPParserKey string
+
+	StorageDriver  string
+	UploadsDir     string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool
+
+	UserStorageQuotaMB    int64
+	ProjectStorageQuotaMB int64
+
+	SignedURLSecret string
+	SignedURLTTL    time.Duration
+	UploadsPublic   bool
+
+	TrashRetention time.Duration
+
+	// DefaultTimezone is the zone of projects whose owner set none in their
+	// profile
+	DefaultTimezone *time.Location
+
+	// CORSUploadOrigins may fetch /uploads/ and CORSStreamOrigins may read
+	// the event streams from a browser; both default to CORSOrigins.
+	CORSUploadOrigins []string
+	CORSStreamOrigins []string
+	// HSTSMaxAge is how long browsers keep to HTTPS after an HTTPS response;
+	// zero sends no Strict-Transport-Security.
+	HSTSMaxAge time.Duration
+
+	AIBaseURL         string
+	AIAPIKey          string
+	AIModel           string
+	AIEmbeddingModel  string
+	AITimeout         time.Duration
+	AIProviders       []AIProviderConfig
+	AIDefaultProvider string
+	AIPricing         string
+	AIMonthlyTokens   int64
+	AIMonthlyCostUSD  float64
+
+	UploadImageExtensions []string
+	UploadImageMaxMB      int64
+	UploadVideoExtensions []string
+	UploadVideoMaxMB      int64
+	UploadFileExtensions  []string
+	UploadFileMaxMB       int64
+
+	RedisURL string
+
+	RateLimitAuth   RateLimit
+	RateLimitAPI    RateLimit
+	RateLimitUpload RateLimit
+	RateLimitParse  RateLimit
+
+	// SMTPAddr is the mail server /ready probes, host:port; empty skips it.
+	SMTPAddr      string
+	ReadyTimeout  time.Duration
+	ReadyCacheTTL time.Duration
+
+	// problems found while loading, reported by Validate
+	problems []error
+	// settings holds the raw value of every setting read, by name
+	settings map[string]string
 }
 
+// RateLimit allows Requests per Window, set as e.g. "30/1m". Zero requests
+// disable the limit.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// AIProviderConfig configures one LLM provider users can pick models from.
+type AIProviderConfig struct {
+	Name    string
+	BaseURL string
+	APIKey  string
+	Models  []string
+}
+
+// Load reads the configuration. Each setting is taken from the first of the
+// environment, the .env file, the file named by CONFIG_FILE and the default
+// that has it. Problems with the values are reported by Validate.
 func Load() Config {
-	_ = godotenv.Load()
+	l := newLoader()
+	corsOrigins := l.get("CORS_ALLOWED_ORIGINS", "http://localhost:3000")
 
 	cfg := Config{
-		AppEnv:        strings.ToLower(getEnv("APP_ENV", "development")),
-		ServerAddr:    getEnv("SERVER_ADDR", ":8080"),
-		ShutdownGrace: envDurationSeconds("SHUTDOWN_TIMEOUT_SEC", 10),
-		ReadTimeout:   envDurationSeconds("HTTP_READ_TIMEOUT_SEC", 15),
-		WriteTimeout:  envDurationSeconds("HTTP_WRITE_TIMEOUT_SEC", 30),
-		IdleTimeout:   envDurationSeconds("HTTP_IDLE_TIMEOUT_SEC", 60),
-		ReadHdrTO:     envDurationSeconds("HTTP_READ_HEADER_TIMEOUT_SEC", 10),
-		CORSOrigins:   splitCSV(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000")),
-		DBHost:        getEnv("DB_HOST", "localhost"),
-		DBPort:        getEnv("DB_PORT", "5432"),
-		DBUser:        getEnv("DB_USER", "tm_user"),
-		DBPassword:    getEnv("DB_PASSWORD", "tm_password"),
-		DBName:        getEnv("DB_NAME", "tm_db"),
-		DBSSLMode:     getEnv("DB_SSLMODE", "disable"),
-		JWTSecret:     getEnv("JWT_SECRET", "change_me"),
-		ZHCPParserURL: getEnv("ZHCP_PARSER_URL", "http://localhost:8081"),
+		AppEnv:        strings.ToLower(l.get("APP_ENV", "development")),
+		ServerAddr:    l.get("SERVER_ADDR", ":8080"),
+		ShutdownGrace: l.seconds("SHUTDOWN_TIMEOUT_SEC", 10),
+		ShutdownDelay: l.seconds("SHUTDOWN_DRAIN_DELAY_SEC", 0),
+		ReadTimeout:   l.seconds("HTTP_READ_TIMEOUT_SEC", 15),
+		WriteTimeout:  l.seconds("HTTP_WRITE_TIMEOUT_SEC", 30),
+		IdleTimeout:   l.seconds("HTTP_IDLE_TIMEOUT_SEC", 60),
+		ReadHdrTO:     l.seconds("HTTP_READ_HEADER_TIMEOUT_SEC", 10),
+		CORSOrigins:   splitCSV(corsOrigins),
+		DBHost:        l.get("DB_HOST", "localhost"),
+		DBPort:        l.get("DB_PORT", "5432"),
+		DBUser:        l.get("DB_USER", "tm_user"),
+		DBPassword:    l.get("DB_PASSWORD", "tm_password"),
+		DBName:        l.get("DB_NAME", "tm_db"),
+		DBSSLMode:     l.get("DB_SSLMODE", "disable"),
+		DBAutoMigrate: l.bool("DB_AUTO_MIGRATE", false),
+		DBReadDSN:     l.get("DB_READ_DSN", ""),
+		DBReadSticky:  l.seconds("DB_READ_STICKY_SEC", 5),
+		JWTSecret:     l.get("JWT_SECRET", "change_me"),
+		ZHCPParserURL: l.get("ZHCP_PARSER_URL", "http://localhost:8081"),
+		ZHCPParserKey: l.get("ZHCP_PARSER_API_KEY", ""),
+
+		StorageDriver:  strings.ToLower(l.get("STORAGE_DRIVER", "local")),
+		UploadsDir:     l.get("UPLOADS_DIR", "uploads"),
+		S3Endpoint:     l.get("S3_ENDPOINT", ""),
+		S3Region:       l.get("S3_REGION", "us-east-1"),
+		S3Bucket:       l.get("S3_BUCKET", ""),
+		S3AccessKey:    l.get("S3_ACCESS_KEY_ID", ""),
+		S3SecretKey:    l.get("S3_SECRET_ACCESS_KEY", ""),
+		S3UsePathStyle: l.bool("S3_USE_PATH_STYLE", true),
+
+		UserStorageQuotaMB:    l.int64("STORAGE_USER_QUOTA_MB", 0),
+		ProjectStorageQuotaMB: l.int64("STORAGE_PROJECT_QUOTA_MB", 0),
+
+		SignedURLSecret: l.get("SIGNED_URL_SECRET", ""),
+		SignedURLTTL:    l.seconds("SIGNED_URL_TTL_SEC", 900),
+		UploadsPublic:   l.bool("UPLOADS_PUBLIC", false),
+
+		TrashRetention: time.Duration(l.int64("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+
+		DefaultTimezone: l.location("DEFAULT_TIMEZONE", time.UTC),
+
+		CORSUploadOrigins: splitCSV(l.get("CORS_UPLOAD_ORIGINS", corsOrigins)),
+		CORSStreamOrigins: splitCSV(l.get("CORS_STREAM_ORIGINS", corsOrigins)),
+		HSTSMaxAge:        time.Duration(l.int64("HSTS_MAX_AGE_DAYS", 365)) * 24 * time.Hour,
+
+		AIBaseURL:        l.get("AI_BASE_URL", ""),
+		AIAPIKey:         l.get("AI_API_KEY", ""),
+		AIModel:          l.get("AI_MODEL", "gpt-4o-mini"),
+		AIEmbeddingModel: l.get("AI_EMBEDDING_MODEL", "text-embedding-3-small"),
+		AITimeout:        l.seconds("AI_TIMEOUT_SEC", 60),
+		AIPricing:        l.get("AI_PRICING", "gpt-4o-mini=0.15:0.6,gpt-4o=2.5:10"),
+		AIMonthlyTokens:  l.int64("AI_MONTHLY_TOKEN_QUOTA", 0),
+		AIMonthlyCostUSD: l.float64("AI_MONTHLY_COST_QUOTA_USD", 0),
+
+		UploadImageExtensions: splitCSV(l.get("UPLOAD_IMAGE_EXTENSIONS", ".png,.jpg,.jpeg,.webp")),
+		UploadImageMaxMB:      l.int64("UPLOAD_IMAGE_MAX_MB", 25),
+		UploadVideoExtensions: splitCSV(l.get("UPLOAD_VIDEO_EXTENSIONS", ".mp4,.mov")),
+		UploadVideoMaxMB:      l.int64("UPLOAD_VIDEO_MAX_MB", 2048),
+		UploadFileExtensions:  splitCSV(l.get("UPLOAD_FILE_EXTENSIONS", ".pdf,.doc,.docx,.xls")),
+		UploadFileMaxMB:       l.int64("UPLOAD_FILE_MAX_MB", 500),
+
+		RedisURL: l.get("REDIS_URL", ""),
+
+		RateLimitAuth:   l.rateLimit("RATE_LIMIT_AUTH", RateLimit{Requests: 30, Window: time.Minute}),
+		RateLimitAPI:    l.rateLimit("RATE_LIMIT_API", RateLimit{Requests: 600, Window: time.Minute}),
+		RateLimitUpload: l.rateLimit("RATE_LIMIT_UPLOAD", RateLimit{Requests: 20, Window: time.Minute}),
+		RateLimitParse:  l.rateLimit("RATE_LIMIT_PARSE", RateLimit{Requests: 20, Window: time.Minute}),
+
+		SMTPAddr:      l.get("SMTP_ADDR", ""),
+		ReadyTimeout:  l.seconds("READY_TIMEOUT_SEC", 2),
+		ReadyCacheTTL: l.seconds("READY_CACHE_SEC", 5),
+	}
+
+	if strings.TrimSpace(cfg.SignedURLSecret) == "" {
+		cfg.SignedURLSecret = cfg.JWTSecret
 	}
 
+	cfg.AIProviders = l.aiProviders(cfg)
+	cfg.AIDefaultProvider = strings.TrimSpace(l.get("AI_DEFAULT_PROVIDER", ""))
+
 	if cfg.JWTSecret == "change_me" && cfg.AppEnv == "development" {
 		log.Println("warning: JWT_SECRET is using the default value")
 	}
 
+	l.checkUnknown()
+	cfg.problems = l.problems
+	cfg.settings = l.settings
 	return cfg
 }
 
+// Validate reports every problem with the configuration at once.
 func (c Config) Validate() error {
+	errs := append([]error(nil), c.problems...)
 	if strings.TrimSpace(c.JWTSecret) == "" {
-		return errors.New("JWT_SECRET is required")
+		errs = append(errs, errors.New("JWT_SECRET is required"))
 	}
 	if c.JWTSecret == "change_me" && c.AppEnv != "development" && c.AppEnv != "dev" && c.AppEnv != "test" {
-		return errors.New("JWT_SECRET must be changed outside development")
+		errs = append(errs, errors.New("JWT_SECRET must be changed outside development"))
 	}
 	if len(c.CORSOrigins) == 0 {
-		return errors.New("at least one CORS_ALLOWED_ORIGINS value is required")
+		errs = append(errs, errors.New("at least one CORS_ALLOWED_ORIGINS value is required"))
+	}
+	// the API and the streams are called with credentials, which browsers
+	// never send to a wildcard
+	if slices.Contains(c.CORSOrigins, "*") {
+		errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS cannot contain *, list the origins"))
+	}
+	if slices.Contains(c.CORSStreamOrigins, "*") {
+		errs = append(errs, errors.New("CORS_STREAM_ORIGINS cannot contain *, list the origins"))
+	}
+	switch c.StorageDriver {
+	case "local":
+	case "s3", "minio":
+		if strings.TrimSpace(c.S3Bucket) == "" {
+			errs = append(errs, errors.New("S3_BUCKET is required for s3 storage"))
+		}
+		if strings.TrimSpace(c.S3AccessKey) == "" || strings.TrimSpace(c.S3SecretKey) == "" {
+			errs = append(errs, errors.New("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for s3 storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported STORAGE_DRIVER %q, use local or s3", c.StorageDriver))
+	}
+	defaultFound := c.AIDefaultProvider == ""
+	for _, provider := range c.AIProviders {
+		if len(provider.Models) == 0 {
+			errs = append(errs, fmt.Errorf("no models configured for AI provider %q, set AI_%s_MODELS", provider.Name, envName(provider.Name)))
+		}
+		defaultFound = defaultFound || provider.Name == c.AIDefaultProvider
+	}
+	if !defaultFound {
+		errs = append(errs, fmt.Errorf("AI_DEFAULT_PROVIDER %q is not listed in AI_PROVIDERS", c.AIDefaultProvider))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (c Config) DatabaseDSN() string {
@@ -84,35 +272,3 @@ func (c Config) DatabaseDSN() string {
 		c.DBSSLMode,
 	)
 }
-
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return fallback
-}
-
-func envDurationSeconds(key string, fallbackSec int) time.Duration {
-	raw := strings.TrimSpace(os.Getenv(key))
-	if raw == "" {
-		return time.Duration(fallbackSec) * time.Second
-	}
-
-	sec, err := strconv.Atoi(raw)
-	if err != nil || sec <= 0 {
-		return time.Duration(fallbackSec) * time.Second
-	}
-	return time.Duration(sec) * time.Second
-}
-
-func splitCSV(value string) []string {
-	parts := strings.Split(value, ",")
-	origins := make([]string, 0, len(parts))
-	for _, part := range parts {
-		trimmed := strings.TrimSpace(part)
-		if trimmed != "" {
-			origins = append(origins, trimmed)
-		}
-	}
-	return origins
-}
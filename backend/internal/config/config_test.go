@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadDefaults loads the configuration with nothing set but what the test
+// sets itself.
+func loadDefaults(t *testing.T, env map[string]string) Config {
+	t.Helper()
+	for _, key := range []string{"APP_ENV", "CONFIG_FILE", "JWT_SECRET", "CORS_ALLOWED_ORIGINS", "STORAGE_DRIVER", "AI_PROVIDERS", "AI_BASE_URL", "AI_API_KEY", "AI_DEFAULT_PROVIDER"} {
+		t.Setenv(key, "")
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	return Load()
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := loadDefaults(t, nil).Validate(); err != nil {
+		t.Errorf("Validate() of the defaults = %v, want nil", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	openai := AIProviderConfig{Name: "openai", Models: []string{"gpt-4o-mini"}}
+
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr string // "" when the config is valid
+	}{
+		{
+			name:    "no JWT secret",
+			change:  func(c *Config) { c.JWTSecret = " " },
+			wantErr: "JWT_SECRET is required",
+		},
+		{
+			name:    "default JWT secret in production",
+			change:  func(c *Config) { c.AppEnv = "production" },
+			wantErr: "JWT_SECRET must be changed outside development",
+		},
+		{
+			name:   "default JWT secret in tests",
+			change: func(c *Config) { c.AppEnv = "test" },
+		},
+		{
+			name: "own JWT secret in production",
+			change: func(c *Config) {
+				c.AppEnv = "production"
+				c.JWTSecret = "s3cr3t-value"
+			},
+		},
+		{
+			name:    "no CORS origins",
+			change:  func(c *Config) { c.CORSOrigins = nil },
+			wantErr: "at least one CORS_ALLOWED_ORIGINS value is required",
+		},
+		{
+			name:    "wildcard CORS origin",
+			change:  func(c *Config) { c.CORSOrigins = []string{"https://app.example.com", "*"} },
+			wantErr: "CORS_ALLOWED_ORIGINS cannot contain *",
+		},
+		{
+			name:    "wildcard stream origin",
+			change:  func(c *Config) { c.CORSStreamOrigins = []string{"*"} },
+			wantErr: "CORS_STREAM_ORIGINS cannot contain *",
+		},
+		{
+			name: "s3 without a bucket",
+			change: func(c *Config) {
+				c.StorageDriver = "s3"
+				c.S3AccessKey, c.S3SecretKey = "key", "secret"
+			},
+			wantErr: "S3_BUCKET is required for s3 storage",
+		},
+		{
+			name: "s3 without credentials",
+			change: func(c *Config) {
+				c.StorageDriver = "minio"
+				c.S3Bucket, c.S3AccessKey = "uploads", "key"
+			},
+			wantErr: "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for s3 storage",
+		},
+		{
+			name: "complete s3",
+			change: func(c *Config) {
+				c.StorageDriver = "s3"
+				c.S3Bucket, c.S3AccessKey, c.S3SecretKey = "uploads", "key", "secret"
+			},
+		},
+		{
+			name:    "unknown storage driver",
+			change:  func(c *Config) { c.StorageDriver = "gcs" },
+			wantErr: `unsupported STORAGE_DRIVER "gcs", use local or s3`,
+		},
+		{
+			name:    "AI provider without models",
+			change:  func(c *Config) { c.AIProviders = []AIProviderConfig{{Name: "my-llm"}} },
+			wantErr: `no models configured for AI provider "my-llm", set AI_MY_LLM_MODELS`,
+		},
+		{
+			name: "default AI provider not listed",
+			change: func(c *Config) {
+				c.AIProviders = []AIProviderConfig{openai}
+				c.AIDefaultProvider = "ollama"
+			},
+			wantErr: `AI_DEFAULT_PROVIDER "ollama" is not listed in AI_PROVIDERS`,
+		},
+		{
+			name: "default AI provider listed",
+			change: func(c *Config) {
+				c.AIProviders = []AIProviderConfig{openai}
+				c.AIDefaultProvider = "openai"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadDefaults(t, nil)
+			tt.change(&cfg)
+
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsLoadProblems(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(yamlFile, []byte("rate_limit:\n  apii: 10/1m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "malformed seconds",
+			env:     map[string]string{"HTTP_READ_TIMEOUT_SEC": "15s"},
+			wantErr: `HTTP_READ_TIMEOUT_SEC="15s" from the environment: want a whole number of seconds`,
+		},
+		{
+			name:    "malformed rate limit",
+			env:     map[string]string{"RATE_LIMIT_API": "600"},
+			wantErr: `RATE_LIMIT_API="600" from the environment: want requests/window such as 30/1m, or 0 to disable`,
+		},
+		{
+			name:    "unknown time zone",
+			env:     map[string]string{"DEFAULT_TIMEZONE": "Almaty"},
+			wantErr: `DEFAULT_TIMEZONE="Almaty" from the environment`,
+		},
+		{
+			name:    "misspelt setting in the config file",
+			env:     map[string]string{"CONFIG_FILE": yamlFile},
+			wantErr: "unknown setting RATE_LIMIT_APII in " + yamlFile + ", did you mean RATE_LIMIT_API?",
+		},
+		{
+			name:    "config file of another format",
+			env:     map[string]string{"CONFIG_FILE": filepath.Join(dir, "config.toml")},
+			wantErr: `unsupported format ".toml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadDefaults(t, tt.env).Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads a YAML or JSON config file into settings named like the
+// environment variables: keys are upper-cased, nested keys joined with
+// underscores and lists with commas, so
+//
+//	rate_limit:
+//	  api: 600/1m
+//	cors_allowed_origins: [https://app.example.com]
+//
+// sets RATE_LIMIT_API and CORS_ALLOWED_ORIGINS.
+func readFile(name string) (map[string]string, error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, fmt.Errorf("CONFIG_FILE %s: unsupported format %q, use .yaml, .yml or .json", name, ext)
+	}
+
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+	var document map[string]any
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+
+	settings := make(map[string]string)
+	if err := flatten(settings, "", document); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return settings, nil
+}
+
+func flatten(settings map[string]string, prefix string, value any) error {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			name := envName(key)
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			if err := flatten(settings, name, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[any]any:
+		return fmt.Errorf("%s: keys must be names", prefix)
+	}
+
+	if _, ok := settings[prefix]; ok {
+		return fmt.Errorf("%s is set twice", prefix)
+	}
+	switch value := value.(type) {
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]any, map[any]any, []any:
+				return fmt.Errorf("%s: list items must be plain values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		settings[prefix] = strings.Join(items, ",")
+	case nil:
+		settings[prefix] = ""
+	default:
+		settings[prefix] = fmt.Sprint(value)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// loader reads settings by their environment variable names from the
+// layers Load describes, recording their raw values and what is wrong with
+// them.
+type loader struct {
+	dotenv   map[string]string
+	file     map[string]string
+	fileName string
+
+	settings map[string]string
+	problems []error
+}
+
+func newLoader() *loader {
+	l := &loader{settings: make(map[string]string)}
+
+	dotenv, err := godotenv.Read()
+	switch {
+	case err == nil:
+		l.dotenv = dotenv
+	case !errors.Is(err, fs.ErrNotExist):
+		l.problems = append(l.problems, fmt.Errorf("read .env: %w", err))
+	}
+
+	// CONFIG_FILE can only come from the environment or .env
+	if name := strings.TrimSpace(l.get("CONFIG_FILE", "")); name != "" {
+		file, err := readFile(name)
+		if err != nil {
+			l.problems = append(l.problems, err)
+		}
+		l.file, l.fileName = file, name
+	}
+	return l
+}
+
+// lookup returns the raw value of key and the layer it came from, or empty
+// strings when no layer sets it.
+func (l *loader) lookup(key string) (string, string) {
+	if value := os.Getenv(key); value != "" {
+		return value, "the environment"
+	}
+	if value := l.dotenv[key]; value != "" {
+		return value, ".env"
+	}
+	if value := l.file[key]; value != "" {
+		return value, l.fileName
+	}
+	return "", ""
+}
+
+func (l *loader) invalid(key, value, source, want string) {
+	l.problems = append(l.problems, fmt.Errorf("%s=%q from %s: want %s", key, value, source, want))
+}
+
+func (l *loader) get(key, fallback string) string {
+	value, _ := l.lookup(key)
+	l.settings[key] = value
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func (l *loader) seconds(key string, fallbackSec int) time.Duration {
+	raw := strings.TrimSpace(l.get(key, ""))
+	if raw == "" {
+		return time.Duration(fallbackSec) * time.Second
+	}
+
+	sec, err := strconv.Atoi(raw)
+	if err != nil || sec < 0 {
+		_, source := l.lookup(key)
+		l.invalid(key, raw, source, "a whole number of seconds")
+		return time.Duration(fallbackSec) * time.Second
+	}
+	if sec == 0 {
+		return time.Duration(fallbackSec) * time.Second
+	}
+	return time.Duration(sec) * time.Second
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	raw := strings.TrimSpace(l.get(key, ""))
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		_, source := l.lookup(key)
+		l.invalid(key, raw, source, "true or false")
+		return fallback
+	}
+	return value
+}
+
+func (l *loader) int64(key string, fallback int64) int64 {
+	raw := strings.TrimSpace(l.get(key, ""))
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		_, source := l.lookup(key)
+		l.invalid(key, raw, source, "a whole number, 0 or more")
+		return fallback
+	}
+	return value
+}
+
+func (l *loader) float64(key string, fallback float64) float64 {
+	raw := strings.TrimSpace(l.get(key, ""))
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		_, source := l.lookup(key)
+		l.invalid(key, raw, source, "a number, 0 or more")
+		return fallback
+	}
+	return value
+}
+
+// location reads an IANA time zone name such as Asia/Almaty.
+func (l *loader) location(key string, fallback *time.Location) *time.Location {
+	raw := strings.TrimSpace(l.get(key, ""))
+	if raw == "" {
+		return fallback
+	}
+
+	loc, err := time.LoadLocation(raw)
+	if err != nil || raw == "Local" {
+		_, source := l.lookup(key)
+		l.invalid(key, raw, source, "an IANA time zone such as Asia/Almaty")
+		return fallback
+	}
+	return loc
+}
+
+// rateLimit reads a limit like "30/1m" or "1000/1h"; "0" disables it.
+func (l *loader) rateLimit(key string, fallback RateLimit) RateLimit {
+	raw := strings.TrimSpace(l.get(key, ""))
+	if raw == "" {
+		return fallback
+	}
+	if raw == "0" {
+		return RateLimit{}
+	}
+
+	countRaw, windowRaw, ok := strings.Cut(raw, "/")
+	count, countErr := strconv.Atoi(strings.TrimSpace(countRaw))
+	window, windowErr := time.ParseDuration(strings.TrimSpace(windowRaw))
+	if !ok || countErr != nil || count < 0 || windowErr != nil || window <= 0 {
+		_, source := l.lookup(key)
+		l.invalid(key, raw, source, "requests/window such as 30/1m, or 0 to disable")
+		return fallback
+	}
+	return RateLimit{Requests: count, Window: window}
+}
+
+// aiProviders reads AI_PROVIDERS (e.g. "openai,ollama") and the
+// AI_<NAME>_BASE_URL, AI_<NAME>_API_KEY and AI_<NAME>_MODELS settings of each
+// provider. Without AI_PROVIDERS, AI_BASE_URL/AI_API_KEY configure a single
+// AI_PROVIDER (openai by default) offering AI_MODELS or AI_MODEL.
+func (l *loader) aiProviders(cfg Config) []AIProviderConfig {
+	names := splitCSV(l.get("AI_PROVIDERS", ""))
+	if len(names) == 0 {
+		if strings.TrimSpace(cfg.AIBaseURL) == "" && strings.TrimSpace(cfg.AIAPIKey) == "" {
+			return nil
+		}
+		models := splitCSV(l.get("AI_MODELS", ""))
+		if len(models) == 0 {
+			models = []string{cfg.AIModel}
+		}
+		return []AIProviderConfig{{
+			Name:    strings.ToLower(strings.TrimSpace(l.get("AI_PROVIDER", "openai"))),
+			BaseURL: cfg.AIBaseURL,
+			APIKey:  cfg.AIAPIKey,
+			Models:  models,
+		}}
+	}
+
+	providers := make([]AIProviderConfig, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		prefix := "AI_" + envName(name) + "_"
+		providers = append(providers, AIProviderConfig{
+			Name:    name,
+			BaseURL: l.get(prefix+"BASE_URL", ""),
+			APIKey:  l.get(prefix+"API_KEY", ""),
+			Models:  splitCSV(l.get(prefix+"MODELS", "")),
+		})
+	}
+	return providers
+}
+
+// checkUnknown reports settings of the config file that nothing reads,
+// which are most likely misspelt.
+func (l *loader) checkUnknown() {
+	unknown := make([]string, 0)
+	for key := range l.file {
+		if _, ok := l.settings[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, key := range unknown {
+		err := fmt.Errorf("unknown setting %s in %s", key, l.fileName)
+		if suggestion := l.closest(key); suggestion != "" {
+			err = fmt.Errorf("%w, did you mean %s?", err, suggestion)
+		}
+		l.problems = append(l.problems, err)
+	}
+}
+
+// closest returns the known setting nearest to key, or "" when none is
+// close enough to be a typo.
+func (l *loader) closest(key string) string {
+	best, bestDistance := "", 3
+	for known := range l.settings {
+		if distance := editDistance(key, known); distance < bestDistance || (distance == bestDistance && best != "" && known < best) {
+			best, bestDistance = known, distance
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// envName turns a name into the form of an environment variable.
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(strings.TrimSpace(name)))
+}
+
+func splitCSV(value string) []string {
+	parts := strings.Split(value, ",")
+	origins := make([]string, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed != "" {
+			origins = append(origins, trimmed)
+		}
+	}
+	return origins
+}
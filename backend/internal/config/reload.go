@@ -0,0 +1,33 @@
+package config
+
+import "sort"
+
+// reloadable are the settings a running server applies again on SIGHUP;
+// the others are read once at startup.
+var reloadable = map[string]bool{
+	"CORS_ALLOWED_ORIGINS": true,
+	"CORS_UPLOAD_ORIGINS":  true,
+	"CORS_STREAM_ORIGINS":  true,
+	"RATE_LIMIT_AUTH":      true,
+	"RATE_LIMIT_API":       true,
+	"RATE_LIMIT_UPLOAD":    true,
+	"RATE_LIMIT_PARSE":     true,
+}
+
+// RestartRequired lists the settings that differ in next but only take
+// effect after a restart.
+func (c Config) RestartRequired(next Config) []string {
+	changed := make([]string, 0)
+	for key, value := range next.settings {
+		if !reloadable[key] && c.settings[key] != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range c.settings {
+		if _, ok := next.settings[key]; !ok && !reloadable[key] {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
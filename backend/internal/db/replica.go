@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that Reader answers the primary, for requests
+// that must see their own recent writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Reader returns the connection for a read that tolerates replication lag:
+// the replica when there is one and ctx does not ask for the primary.
+// Writes, and reads that decide writes, always use the primary.
+func Reader(ctx context.Context, primary, replica *sql.DB) *sql.DB {
+	if replica == nil {
+		return primary
+	}
+	if pinned, _ := ctx.Value(primaryKey{}).(bool); pinned {
+		return primary
+	}
+	return replica
+}
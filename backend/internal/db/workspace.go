@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type workspaceKey struct{}
+
+// WithWorkspace scopes the queries run with ctx to the workspace id, see
+// Workspace.
+func WithWorkspace(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, id)
+}
+
+// WorkspaceID returns the workspace ctx is scoped to.
+func WorkspaceID(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(workspaceKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// Workspace is the workspace of ctx as a query argument. Queries compare it
+// with workspace_id, so outside a workspace it is NULL and matches nothing;
+// the few queries that also run in background work, where there is none,
+// say so with an explicit IS NULL.
+func Workspace(ctx context.Context) any {
+	if id, ok := WorkspaceID(ctx); ok {
+		return id
+	}
+	return nil
+}
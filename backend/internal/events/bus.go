@@ -0,0 +1,91 @@
+// Package events carries domain events from the module that causes them to
+// the modules that react to them, so a handler that updates a task does not
+// need to know who wants to hear about it:
+//
+//	bus.Publish(ctx, events.TaskUpdated{...})      // projects
+//	events.On(bus, func(ctx, e events.TaskUpdated) // notifications
+//
+// Subscribers run synchronously, in the order they subscribed, on the
+// publisher's goroutine; slow work belongs in a job (see package jobs). A
+// failing subscriber is logged and never fails the publisher or the other
+// subscribers.
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Event is a fact about the domain, named after what happened.
+type Event interface {
+	EventName() string
+}
+
+// Handler reacts to an event.
+type Handler func(ctx context.Context, event Event) error
+
+// Bus delivers published events to their subscribers.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]Handler
+	all         []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string][]Handler)}
+}
+
+// Subscribe calls handler for every event named name.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[name] = append(b.subscribers[name], handler)
+}
+
+// SubscribeAll calls handler for every event, after the subscribers of its
+// name.
+func (b *Bus) SubscribeAll(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+// On subscribes handler to the events of type T.
+func On[T Event](b *Bus, handler func(ctx context.Context, event T) error) {
+	var zero T
+	b.Subscribe(zero.EventName(), func(ctx context.Context, event Event) error {
+		typed, ok := event.(T)
+		if !ok {
+			return fmt.Errorf("unexpected %T for %s", event, zero.EventName())
+		}
+		return handler(ctx, typed)
+	})
+}
+
+// Publish delivers event to its subscribers. A nil bus drops it.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.subscribers[event.EventName()]...), b.all...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := deliver(ctx, handler, event); err != nil {
+			log.Printf("event %s subscriber failed: %v", event.EventName(), err)
+		}
+	}
+}
+
+func deliver(ctx context.Context, handler Handler, event Event) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handler(ctx, event)
+}
@@ -0,0 +1,161 @@
+package events
+
+import "github.com/google/uuid"
+
+// ProjectCreated is published after a project was created.
+type ProjectCreated struct {
+	ProjectID uuid.UUID `json:"projectId"`
+	ActorID   uuid.UUID `json:"actorId"`
+	Title     string    `json:"title"`
+}
+
+func (ProjectCreated) EventName() string { return "project.created" }
+
+// TaskUpdated is published after a task was changed. AddedAssignees are the
+// users newly assigned by the change, already members of the project.
+type TaskUpdated struct {
+	TaskID         uuid.UUID   `json:"taskId"`
+	ProjectID      uuid.UUID   `json:"projectId"`
+	ActorID        uuid.UUID   `json:"actorId"`
+	Title          string      `json:"title"`
+	Status         string      `json:"status"`
+	AddedAssignees []uuid.UUID `json:"addedAssignees"`
+	Delegated      bool        `json:"delegated"` // the assignees were delegated the task
+}
+
+func (TaskUpdated) EventName() string { return "task.updated" }
+
+// TaskCommented is published after a comment was left on a task. Mentioned
+// are the members named in the comment, Watchers the other members.
+type TaskCommented struct {
+	TaskID    uuid.UUID   `json:"taskId"`
+	ProjectID uuid.UUID   `json:"projectId"`
+	CommentID uuid.UUID   `json:"commentId"`
+	ActorID   uuid.UUID   `json:"actorId"`
+	Watchers  []uuid.UUID `json:"watchers"`
+	Mentioned []uuid.UUID `json:"mentioned"`
+}
+
+func (TaskCommented) EventName() string { return "task.commented" }
+
+// ReportCommented is published after a comment was left on a delay report.
+// RepliedTo is the author of the comment answered, uuid.Nil for top-level
+// comments; Watchers are the other members.
+type ReportCommented struct {
+	ReportID  uuid.UUID   `json:"reportId"`
+	ProjectID uuid.UUID   `json:"projectId"`
+	TaskID    *uuid.UUID  `json:"taskId,omitempty"` // task the report is about, if any
+	CommentID uuid.UUID   `json:"commentId"`
+	ActorID   uuid.UUID   `json:"actorId"`
+	Watchers  []uuid.UUID `json:"watchers"`
+	RepliedTo uuid.UUID   `json:"repliedTo"`
+}
+
+func (ReportCommented) EventName() string { return "report.commented" }
+
+// MemberAdded is published after a user was added to a project or given a
+// new role in it.
+type MemberAdded struct {
+	ProjectID uuid.UUID `json:"projectId"`
+	UserID    uuid.UUID `json:"userId"`
+	ActorID   uuid.UUID `json:"actorId"`
+	Role      string    `json:"role"`
+}
+
+func (MemberAdded) EventName() string { return "project.member_added" }
+
+// RolesUpdated is published after the manager and members of a project were
+// replaced at once.
+type RolesUpdated struct {
+	ProjectID    uuid.UUID   `json:"projectId"`
+	ProjectTitle string      `json:"projectTitle"`
+	ActorID      uuid.UUID   `json:"actorId"`
+	ManagerID    *uuid.UUID  `json:"managerId,omitempty"`
+	MemberIDs    []uuid.UUID `json:"memberIds"`
+}
+
+func (RolesUpdated) EventName() string { return "project.roles_updated" }
+
+// ExpenseCreated is published after an expense was recorded.
+type ExpenseCreated struct {
+	ExpenseID uuid.UUID `json:"expenseId"`
+	ProjectID uuid.UUID `json:"projectId"`
+	ActorID   uuid.UUID `json:"actorId"`
+	Title     string    `json:"title"`
+	Amount    int64     `json:"amount"`
+}
+
+func (ExpenseCreated) EventName() string { return "expense.created" }
+
+// ProjectUpdated is published after the settings of a project were changed.
+type ProjectUpdated struct {
+	ProjectID uuid.UUID `json:"projectId"`
+	ActorID   uuid.UUID `json:"actorId"`
+	Title     string    `json:"title"`
+}
+
+func (ProjectUpdated) EventName() string { return "project.updated" }
+
+// TaskCreated is published after a task was added to a stage.
+type TaskCreated struct {
+	TaskID    uuid.UUID `json:"taskId"`
+	ProjectID uuid.UUID `json:"projectId"`
+	ActorID   uuid.UUID `json:"actorId"`
+	Title     string    `json:"title"`
+}
+
+func (TaskCreated) EventName() string { return "task.created" }
+
+// TaskDeleted is published after a task was deleted.
+type TaskDeleted struct {
+	TaskID  uuid.UUID `json:"taskId"`
+	ActorID uuid.UUID `json:"actorId"`
+}
+
+func (TaskDeleted) EventName() string { return "task.deleted" }
+
+// PageSaved is published after a project page was created or edited.
+type PageSaved struct {
+	PageID    uuid.UUID `json:"pageId"`
+	ProjectID uuid.UUID `json:"projectId"`
+	ActorID   uuid.UUID `json:"actorId"`
+	Title     string    `json:"title"`
+}
+
+func (PageSaved) EventName() string { return "page.saved" }
+
+// ChatMessageSent is published after a message was posted in a chat thread.
+type ChatMessageSent struct {
+	MessageID uuid.UUID `json:"messageId"`
+	ThreadID  uuid.UUID `json:"threadId"`
+	SenderID  uuid.UUID `json:"senderId"`
+}
+
+func (ChatMessageSent) EventName() string { return "chat.message_sent" }
+
+// FileIndexed is published after the text of a project file was extracted.
+type FileIndexed struct {
+	FileID  uuid.UUID `json:"fileId"`
+	Version int       `json:"version"`
+	Status  string    `json:"status"` // index status, see projectfiles
+}
+
+func (FileIndexed) EventName() string { return "file.indexed" }
+
+// Names lists the names of the domain events, for subscribers that filter
+// by name.
+var Names = []string{
+	ProjectCreated{}.EventName(),
+	TaskUpdated{}.EventName(),
+	TaskCommented{}.EventName(),
+	ReportCommented{}.EventName(),
+	MemberAdded{}.EventName(),
+	RolesUpdated{}.EventName(),
+	ExpenseCreated{}.EventName(),
+	ProjectUpdated{}.EventName(),
+	TaskCreated{}.EventName(),
+	TaskDeleted{}.EventName(),
+	PageSaved{}.EventName(),
+	ChatMessageSent{}.EventName(),
+	FileIndexed{}.EventName(),
+}
@@ -0,0 +1,223 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+	"tm-platform-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxSignURLs = 100
+
+// AccessChecker reports whether an object is referenced by some feature
+// (project files, chat messages, ...) and whether userID may read it there.
+type AccessChecker func(ctx context.Context, userID uuid.UUID, key string) (referenced bool, allowed bool, err error)
+
+type Handler struct {
+	repo     *Repository
+	store    storage.Storage
+	signer   *storage.URLSigner
+	checkers []AccessChecker
+}
+
+func NewHandler(repo *Repository, store storage.Storage, signer *storage.URLSigner, checkers ...AccessChecker) *Handler {
+	return &Handler{repo: repo, store: store, signer: signer, checkers: checkers}
+}
+
+type signURLsRequest struct {
+	URLs []string `json:"urls"`
+}
+
+type signedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type signURLsResponse struct {
+	URLs      map[string]string `json:"urls"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// CanAccess decides whether userID may read the object at key. The uploader
+// always can; objects referenced by project files or chats follow the rules
+// of those features; anything else (avatars, covers) is readable by every
+// signed-in user.
+func (h *Handler) CanAccess(ctx context.Context, userID uuid.UUID, key string) (bool, error) {
+	object, err := h.repo.GetObjectByKey(ctx, key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if err == nil && object.UserID != nil && *object.UserID == userID {
+		return true, nil
+	}
+
+	referenced := false
+	for _, check := range h.checkers {
+		isReferenced, allowed, err := check(ctx, userID, key)
+		if err != nil {
+			return false, err
+		}
+		if allowed {
+			return true, nil
+		}
+		referenced = referenced || isReferenced
+	}
+
+	return !referenced, nil
+}
+
+// Download streams a stored object after checking that the requester may
+// read it.
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	object, ok := h.loadAccessibleObject(w, r)
+	if !ok {
+		return
+	}
+
+	body, info, err := h.store.Open(r.Context(), object.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, "file_not_found_in_storage", "file not found in storage")
+			return
+		}
+		log.Printf("open stored file failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to download file")
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(object.Key)}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	storage.ServeObject(w, r, body, info)
+}
+
+// SignedURL returns an expiring direct link to a stored object.
+func (h *Handler) SignedURL(w http.ResponseWriter, r *http.Request) {
+	object, ok := h.loadAccessibleObject(w, r)
+	if !ok {
+		return
+	}
+
+	url, expiresAt := h.signer.Sign(object.Key)
+	writeJSON(w, http.StatusOK, signedURLResponse{URL: url, ExpiresAt: expiresAt})
+}
+
+// SignURLs turns stored /uploads/ URLs into expiring links. URLs the
+// requester may not read, or that do not point at storage, are left out.
+func (h *Handler) SignURLs(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req signURLsRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	if len(req.URLs) > maxSignURLs {
+		problem.Write(w, http.StatusBadRequest, "too_many_urls", "too many urls")
+		return
+	}
+
+	response := signURLsResponse{URLs: make(map[string]string, len(req.URLs))}
+	for _, rawURL := range req.URLs {
+		if _, done := response.URLs[rawURL]; done {
+			continue
+		}
+		key, ok := storage.KeyFromURL(rawURL)
+		if !ok {
+			continue
+		}
+
+		allowed, err := h.CanAccess(r.Context(), userID, key)
+		if err != nil {
+			log.Printf("check file access failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to sign urls")
+			return
+		}
+		if !allowed {
+			continue
+		}
+
+		signed, expiresAt := h.signer.Sign(key)
+		response.URLs[rawURL] = signed
+		response.ExpiresAt = expiresAt
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) loadAccessibleObject(w http.ResponseWriter, r *http.Request) (Object, bool) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return Object{}, false
+	}
+
+	objectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return Object{}, false
+	}
+
+	object, err := h.repo.GetObject(r.Context(), objectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return Object{}, false
+		}
+		log.Printf("get stored file failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load file")
+		return Object{}, false
+	}
+
+	allowed, err := h.CanAccess(r.Context(), userID, object.Key)
+	if err != nil {
+		log.Printf("check file access failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load file")
+		return Object{}, false
+	}
+	if !allowed {
+		// Hide the existence of files the requester cannot see.
+		problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+		return Object{}, false
+	}
+
+	return object, true
+}
+
+func userIDFromRequest(r *http.Request) (uuid.UUID, error) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok || strings.TrimSpace(userIDStr) == "" {
+		return uuid.Nil, errors.New("unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid token subject")
+	}
+
+	return userID, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,14 @@
+package files
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/files/{id}/download", Tag: "files", Summary: "Download a stored object", ContentType: "application/octet-stream"},
+	{Method: http.MethodGet, Path: "/files/{id}/url", Tag: "files", Summary: "Get an expiring direct link to a stored object", Response: signedURLResponse{}},
+	{Method: http.MethodPost, Path: "/files/signed-urls", Tag: "files", Summary: "Sign several stored URLs at once", Body: signURLsRequest{}, Response: signURLsResponse{}},
+}
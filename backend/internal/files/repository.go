@@ -0,0 +1,60 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Object is a stored file as recorded at upload time.
+type Object struct {
+	ID        uuid.UUID
+	Key       string
+	UserID    *uuid.UUID
+	Size      int64
+	CreatedAt time.Time
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetObject(ctx context.Context, id uuid.UUID) (Object, error) {
+	return scanObject(r.db.QueryRowContext(
+		ctx,
+		`SELECT id, object_key, user_id, size, created_at
+		 FROM storage_objects
+		 WHERE id = $1`,
+		id,
+	))
+}
+
+func (r *Repository) GetObjectByKey(ctx context.Context, key string) (Object, error) {
+	return scanObject(r.db.QueryRowContext(
+		ctx,
+		`SELECT id, object_key, user_id, size, created_at
+		 FROM storage_objects
+		 WHERE object_key = $1`,
+		key,
+	))
+}
+
+func scanObject(row *sql.Row) (Object, error) {
+	var (
+		object Object
+		userID uuid.NullUUID
+	)
+	if err := row.Scan(&object.ID, &object.Key, &userID, &object.Size, &object.CreatedAt); err != nil {
+		return Object{}, err
+	}
+	if userID.Valid {
+		object.UserID = &userID.UUID
+	}
+	return object, nil
+}
@@ -0,0 +1,92 @@
+// Package graphapi serves a read-only GraphQL API next to the REST one, so
+// clients can fetch a project with its stages, tasks and members in one
+// round trip. The schema is in schema.graphql; nested lists are loaded by
+// per-request batching loaders, one query per level rather than per parent.
+package graphapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/request"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+const (
+	maxQueryDepth  = 10
+	maxQueryLength = 10 << 10
+	// maxParallelism bounds the resolvers run at once; it is also the most
+	// parents a loader can batch per round.
+	maxParallelism = 50
+)
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string         `json:"query" validate:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+type Handler struct {
+	schema   *graphql.Schema
+	projects *projects.Repository
+}
+
+func NewHandler(projectsRepo *projects.Repository, notificationsRepo *notifications.Repository) *Handler {
+	schema := graphql.MustParseSchema(
+		schemaSource,
+		&rootResolver{projects: projectsRepo, notifications: notificationsRepo},
+		graphql.MaxDepth(maxQueryDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+		graphql.MaxParallelism(maxParallelism),
+	)
+	return &Handler{schema: schema, projects: projectsRepo}
+}
+
+// Serve executes one query. Like other GraphQL servers it answers 200 with
+// the errors of the query in the body; only requests that are not queries
+// get problem answers.
+func (h *Handler) Serve(w http.ResponseWriter, r *http.Request) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	var req Request
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	ctx := withLoaders(r.Context(), newLoaders(h.projects, userID))
+	ctx = context.WithValue(ctx, requesterKey{}, userID)
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Schema is the SDL of the API, served for client code generation.
+func (h *Handler) Schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(schemaSource))
+}
@@ -0,0 +1,109 @@
+package graphapi
+
+import (
+	"context"
+	"time"
+
+	"tm-platform-backend/internal/projects"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait is how long a loader collects keys before querying them.
+const loaderWait = 2 * time.Millisecond
+
+type tasksKey struct {
+	StageID uuid.UUID
+	First   int
+}
+
+type commentsKey struct {
+	TaskID uuid.UUID
+	Last   int
+}
+
+// loaders batch the nested lists of one request. They cache what they load,
+// so they live as long as the request.
+type loaders struct {
+	stages   *dataloader.Loader[uuid.UUID, []projects.Stage]
+	tasks    *dataloader.Loader[tasksKey, []projects.Task]
+	members  *dataloader.Loader[uuid.UUID, []projects.ProjectMemberResponse]
+	comments *dataloader.Loader[commentsKey, []projects.TaskCommentResponse]
+}
+
+func newLoaders(repo *projects.Repository, requesterID uuid.UUID) *loaders {
+	return &loaders{
+		stages: newLoader(func(ctx context.Context, projectIDs []uuid.UUID) (map[uuid.UUID][]projects.Stage, error) {
+			return repo.ListStagesByProjects(ctx, requesterID, projectIDs)
+		}),
+		tasks: newLoader(func(ctx context.Context, keys []tasksKey) (map[tasksKey][]projects.Task, error) {
+			// one query per distinct limit, almost always one
+			stageIDs := make(map[int][]uuid.UUID)
+			for _, key := range keys {
+				stageIDs[key.First] = append(stageIDs[key.First], key.StageID)
+			}
+			out := make(map[tasksKey][]projects.Task, len(keys))
+			for first, ids := range stageIDs {
+				byStage, err := repo.ListTasksByStages(ctx, requesterID, ids, first)
+				if err != nil {
+					return nil, err
+				}
+				for stageID, tasks := range byStage {
+					out[tasksKey{StageID: stageID, First: first}] = tasks
+				}
+			}
+			return out, nil
+		}),
+		members: newLoader(func(ctx context.Context, projectIDs []uuid.UUID) (map[uuid.UUID][]projects.ProjectMemberResponse, error) {
+			return repo.ListMembersByProjects(ctx, requesterID, projectIDs)
+		}),
+		comments: newLoader(func(ctx context.Context, keys []commentsKey) (map[commentsKey][]projects.TaskCommentResponse, error) {
+			// one query per distinct limit, almost always one
+			taskIDs := make(map[int][]uuid.UUID)
+			for _, key := range keys {
+				taskIDs[key.Last] = append(taskIDs[key.Last], key.TaskID)
+			}
+			out := make(map[commentsKey][]projects.TaskCommentResponse, len(keys))
+			for last, ids := range taskIDs {
+				byTask, err := repo.ListRecentTaskComments(ctx, requesterID, ids, last)
+				if err != nil {
+					return nil, err
+				}
+				for taskID, comments := range byTask {
+					out[commentsKey{TaskID: taskID, Last: last}] = comments
+				}
+			}
+			return out, nil
+		}),
+	}
+}
+
+// newLoader batches fetch, which returns the values of the keys it found;
+// the others load as the zero value.
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *dataloader.Loader[K, V] {
+	batch := func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		values, err := fetch(ctx, keys)
+		results := make([]*dataloader.Result[V], len(keys))
+		for i, key := range keys {
+			if err != nil {
+				results[i] = &dataloader.Result[V]{Error: err}
+				continue
+			}
+			results[i] = &dataloader.Result[V]{Data: values[key]}
+		}
+		return results
+	}
+	return dataloader.NewBatchedLoader(batch, dataloader.WithWait[K, V](loaderWait))
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
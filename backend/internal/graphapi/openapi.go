@@ -0,0 +1,13 @@
+package graphapi
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/graphql", Tag: "graphql", Summary: "Run a GraphQL query over projects, stages, tasks, members, comments and notifications", Body: Request{}, Response: map[string]any{}},
+	{Method: http.MethodGet, Path: "/graphql/schema", Tag: "graphql", Summary: "Get the GraphQL schema (SDL)", ContentType: "text/plain"},
+}
@@ -0,0 +1,381 @@
+package graphapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/projects"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	maxTasks    = 500
+	maxComments = 100
+)
+
+var (
+	// projectsPageLimits bound the pages of Query.projects, as on GET /projects
+	projectsPageLimits = pagination.Limits{Default: 50, Max: 200}
+	// notificationsPageLimits bound the pages of Query.notifications
+	notificationsPageLimits = pagination.Limits{Default: 50, Max: 200}
+)
+
+// errInternal stands in for failures whose details stay in the log.
+var errInternal = errors.New("internal error")
+
+func internal(op string, err error) error {
+	log.Printf("graphql %s failed: %v", op, err)
+	return errInternal
+}
+
+type requesterKey struct{}
+
+func requesterFrom(ctx context.Context) uuid.UUID {
+	id, _ := ctx.Value(requesterKey{}).(uuid.UUID)
+	return id
+}
+
+type rootResolver struct {
+	projects      *projects.Repository
+	notifications *notifications.Repository
+}
+
+// newPage reads the first and after arguments of a connection field.
+func newPage(first *int32, after *string, limits pagination.Limits) (pagination.Page, error) {
+	limit, cursor := 0, ""
+	if first != nil {
+		if *first < 1 {
+			return pagination.Page{}, pagination.ErrInvalidLimit
+		}
+		limit = int(*first)
+	}
+	if after != nil {
+		cursor = *after
+	}
+	return pagination.NewPage(limit, cursor, limits)
+}
+
+func (r *rootResolver) Projects(ctx context.Context, args struct {
+	First *int32
+	After *string
+}) (*projectConnectionResolver, error) {
+	page, err := newPage(args.First, args.After, projectsPageLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.projects.ListByOwnerPage(ctx, requesterFrom(ctx), page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, internal("list projects", err)
+	}
+	return &projectConnectionResolver{list: list}, nil
+}
+
+func (r *rootResolver) Project(ctx context.Context, args struct{ ID graphql.ID }) (*projectResolver, error) {
+	projectID, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, errors.New("invalid project id")
+	}
+	project, err := r.projects.GetByID(ctx, requesterFrom(ctx), projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internal("get project", err)
+	}
+	return &projectResolver{project: project}, nil
+}
+
+func (r *rootResolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+	taskID, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, errors.New("invalid task id")
+	}
+	task, err := r.projects.GetTaskByID(ctx, requesterFrom(ctx), taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internal("get task", err)
+	}
+	return &taskResolver{task: task}, nil
+}
+
+func (r *rootResolver) Notifications(ctx context.Context, args struct {
+	First      *int32
+	After      *string
+	UnreadOnly *bool
+}) (*notificationConnectionResolver, error) {
+	page, err := newPage(args.First, args.After, notificationsPageLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.notifications.ListByUser(ctx, requesterFrom(ctx), args.UnreadOnly != nil && *args.UnreadOnly, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, internal("list notifications", err)
+	}
+	return &notificationConnectionResolver{list: list}, nil
+}
+
+type projectConnectionResolver struct {
+	list pagination.List[projects.Project]
+}
+
+func (r *projectConnectionResolver) Items() []*projectResolver {
+	out := make([]*projectResolver, 0, len(r.list.Items))
+	for _, item := range r.list.Items {
+		out = append(out, &projectResolver{project: item})
+	}
+	return out
+}
+
+func (r *projectConnectionResolver) NextCursor() *string {
+	if r.list.Next == "" {
+		return nil
+	}
+	return &r.list.Next
+}
+
+func (r *projectConnectionResolver) TotalCount() *int32 {
+	if r.list.Total == nil {
+		return nil
+	}
+	total := int32(*r.list.Total)
+	return &total
+}
+
+type projectResolver struct {
+	project projects.Project
+}
+
+func (r *projectResolver) ID() graphql.ID           { return graphql.ID(r.project.ID.String()) }
+func (r *projectResolver) OwnerID() graphql.ID      { return graphql.ID(r.project.OwnerID.String()) }
+func (r *projectResolver) Title() string            { return r.project.Title }
+func (r *projectResolver) Description() *string     { return r.project.Description }
+func (r *projectResolver) CoverURL() *string        { return r.project.CoverURL }
+func (r *projectResolver) IconURL() *string         { return r.project.IconURL }
+func (r *projectResolver) Status() string           { return string(r.project.Status) }
+func (r *projectResolver) StartDate() *graphql.Time { return timePtr(r.project.StartDate) }
+func (r *projectResolver) Deadline() *graphql.Time  { return timePtr(r.project.Deadline) }
+func (r *projectResolver) EndDate() *graphql.Time   { return timePtr(r.project.EndDate) }
+func (r *projectResolver) DurationDays() int32      { return int32(r.project.DurationDays) }
+func (r *projectResolver) TotalBudget() float64     { return float64(r.project.TotalBudget) }
+func (r *projectResolver) SpentBudget() float64     { return float64(r.project.SpentBudget) }
+func (r *projectResolver) RemainingBudget() float64 { return float64(r.project.RemainingBudget) }
+func (r *projectResolver) ProgressPercent() float64 { return r.project.ProgressPercent }
+func (r *projectResolver) CreatedAt() graphql.Time  { return graphql.Time{Time: r.project.CreatedAt} }
+func (r *projectResolver) UpdatedAt() graphql.Time  { return graphql.Time{Time: r.project.UpdatedAt} }
+
+func (r *projectResolver) CurrentUserRole() *string {
+	if r.project.CurrentUserRole == "" {
+		return nil
+	}
+	role := string(r.project.CurrentUserRole)
+	return &role
+}
+
+func (r *projectResolver) Stages(ctx context.Context) ([]*stageResolver, error) {
+	stages, err := loadersFrom(ctx).stages.Load(ctx, r.project.ID)()
+	if err != nil {
+		return nil, internal("load stages", err)
+	}
+	out := make([]*stageResolver, 0, len(stages))
+	for _, stage := range stages {
+		out = append(out, &stageResolver{stage: stage})
+	}
+	return out, nil
+}
+
+func (r *projectResolver) Members(ctx context.Context) ([]*memberResolver, error) {
+	members, err := loadersFrom(ctx).members.Load(ctx, r.project.ID)()
+	if err != nil {
+		return nil, internal("load members", err)
+	}
+	out := make([]*memberResolver, 0, len(members))
+	for _, member := range members {
+		out = append(out, &memberResolver{member: member})
+	}
+	return out, nil
+}
+
+type stageResolver struct {
+	stage projects.Stage
+}
+
+func (r *stageResolver) ID() graphql.ID        { return graphql.ID(r.stage.ID.String()) }
+func (r *stageResolver) ProjectID() graphql.ID { return graphql.ID(r.stage.ProjectID.String()) }
+func (r *stageResolver) Title() string         { return r.stage.Title }
+func (r *stageResolver) OrderIndex() int32     { return int32(r.stage.OrderIndex) }
+
+func (r *stageResolver) Tasks(ctx context.Context, args struct{ First int32 }) ([]*taskResolver, error) {
+	first := int(args.First)
+	if first < 1 {
+		return []*taskResolver{}, nil
+	}
+	if first > maxTasks {
+		first = maxTasks
+	}
+
+	tasks, err := loadersFrom(ctx).tasks.Load(ctx, tasksKey{StageID: r.stage.ID, First: first})()
+	if err != nil {
+		return nil, internal("load tasks", err)
+	}
+	out := make([]*taskResolver, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, &taskResolver{task: task})
+	}
+	return out, nil
+}
+
+type taskResolver struct {
+	task projects.Task
+}
+
+func (r *taskResolver) ID() graphql.ID           { return graphql.ID(r.task.ID.String()) }
+func (r *taskResolver) StageID() graphql.ID      { return graphql.ID(r.task.StageID.String()) }
+func (r *taskResolver) ProjectID() graphql.ID    { return graphql.ID(r.task.ProjectID.String()) }
+func (r *taskResolver) Title() string            { return r.task.Title }
+func (r *taskResolver) Status() string           { return r.task.Status }
+func (r *taskResolver) StartDate() *graphql.Time { return timePtr(r.task.StartDate) }
+func (r *taskResolver) Deadline() *graphql.Time  { return timePtr(r.task.Deadline) }
+func (r *taskResolver) OrderIndex() int32        { return int32(r.task.OrderIndex) }
+func (r *taskResolver) UpdatedAt() graphql.Time  { return graphql.Time{Time: r.task.UpdatedAt} }
+
+func (r *taskResolver) Comments(ctx context.Context, args struct{ Last int32 }) ([]*commentResolver, error) {
+	last := int(args.Last)
+	if last < 1 {
+		return []*commentResolver{}, nil
+	}
+	if last > maxComments {
+		last = maxComments
+	}
+
+	comments, err := loadersFrom(ctx).comments.Load(ctx, commentsKey{TaskID: r.task.ID, Last: last})()
+	if err != nil {
+		return nil, internal("load comments", err)
+	}
+	out := make([]*commentResolver, 0, len(comments))
+	for _, comment := range comments {
+		out = append(out, &commentResolver{comment: comment})
+	}
+	return out, nil
+}
+
+type memberResolver struct {
+	member projects.ProjectMemberResponse
+}
+
+func (r *memberResolver) Role() string { return string(r.member.Role) }
+
+func (r *memberResolver) User() *userResolver {
+	return &userResolver{id: r.member.User.ID, email: r.member.User.Email, fullName: r.member.User.FullName}
+}
+
+type userResolver struct {
+	id       uuid.UUID
+	email    string
+	fullName string
+}
+
+func (r *userResolver) ID() graphql.ID { return graphql.ID(r.id.String()) }
+func (r *userResolver) Email() string  { return r.email }
+
+func (r *userResolver) FullName() *string {
+	if r.fullName == "" {
+		return nil
+	}
+	return &r.fullName
+}
+
+type commentResolver struct {
+	comment projects.TaskCommentResponse
+}
+
+func (r *commentResolver) ID() graphql.ID          { return graphql.ID(r.comment.ID.String()) }
+func (r *commentResolver) TaskID() graphql.ID      { return graphql.ID(r.comment.TaskID.String()) }
+func (r *commentResolver) Message() string         { return r.comment.Message }
+func (r *commentResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.comment.CreatedAt} }
+
+func (r *commentResolver) Author() *userResolver {
+	return &userResolver{id: r.comment.Author.ID, email: r.comment.Author.Email}
+}
+
+type notificationConnectionResolver struct {
+	list pagination.List[notifications.Notification]
+}
+
+func (r *notificationConnectionResolver) Items() []*notificationResolver {
+	out := make([]*notificationResolver, 0, len(r.list.Items))
+	for _, item := range r.list.Items {
+		out = append(out, &notificationResolver{notification: item})
+	}
+	return out
+}
+
+func (r *notificationConnectionResolver) NextCursor() *string {
+	if r.list.Next == "" {
+		return nil
+	}
+	return &r.list.Next
+}
+
+func (r *notificationConnectionResolver) TotalCount() *int32 {
+	if r.list.Total == nil {
+		return nil
+	}
+	total := int32(*r.list.Total)
+	return &total
+}
+
+type notificationResolver struct {
+	notification notifications.Notification
+}
+
+func (r *notificationResolver) ID() graphql.ID        { return graphql.ID(r.notification.ID.String()) }
+func (r *notificationResolver) Kind() string          { return string(r.notification.Kind) }
+func (r *notificationResolver) Title() string         { return r.notification.Title }
+func (r *notificationResolver) Body() string          { return r.notification.Body }
+func (r *notificationResolver) Link() string          { return r.notification.Link }
+func (r *notificationResolver) EntityType() string    { return r.notification.EntityType }
+func (r *notificationResolver) EntityID() *graphql.ID { return idPtr(r.notification.EntityID) }
+func (r *notificationResolver) ActorID() *graphql.ID  { return idPtr(r.notification.ActorID) }
+func (r *notificationResolver) ReadAt() *graphql.Time { return timePtr(r.notification.ReadAt) }
+func (r *notificationResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.notification.CreatedAt}
+}
+
+func (r *notificationResolver) ActorEmail() *string {
+	if r.notification.ActorEmail == "" {
+		return nil
+	}
+	return &r.notification.ActorEmail
+}
+
+func timePtr(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+func idPtr(id *uuid.UUID) *graphql.ID {
+	if id == nil {
+		return nil
+	}
+	out := graphql.ID(id.String())
+	return &out
+}
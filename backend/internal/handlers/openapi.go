@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"tm-platform-backend/internal/openapi"
+
+	"github.com/google/uuid"
+)
+
+type uploadResponse struct {
+	URL            string     `json:"url"`
+	FileName       string     `json:"fileName"`
+	StoredFileName string     `json:"storedFileName"`
+	Size           int64      `json:"size,omitempty"`
+	FileID         *uuid.UUID `json:"fileId,omitempty"`
+}
+
+type partURLResponse struct {
+	URL        string    `json:"url"`
+	Method     string    `json:"method"`
+	PartNumber int       `json:"partNumber"`
+	Size       int64     `json:"size"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// APIOperations lists the endpoints of UploadHandler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/upload", Tag: "files", Summary: "Upload a file in one request", Form: []openapi.Param{
+		{Name: "file", Kind: "binary", Required: true},
+		{Name: "type", Kind: "string", Description: "File kind, decides the folder and the allowed formats", Required: true},
+		{Name: "project_id", Kind: "string", Description: "Project UUID the upload counts against; the uploader must own or manage it"},
+	}, Response: uploadResponse{}},
+	{Method: http.MethodPost, Path: "/upload/sessions", Tag: "files", Summary: "Start a resumable upload", Body: createUploadSessionRequest{}, Status: http.StatusCreated, Response: uploadSessionResponse{}},
+	{Method: http.MethodGet, Path: "/upload/sessions/{id}", Tag: "files", Summary: "Get the progress of a resumable upload", Response: uploadSessionResponse{}},
+	{Method: http.MethodPatch, Path: "/upload/sessions/{id}", Tag: "files", Summary: "Send the next chunk at the Upload-Offset header", Response: uploadSessionResponse{}},
+	{Method: http.MethodDelete, Path: "/upload/sessions/{id}", Tag: "files", Summary: "Abort a resumable upload", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/upload/sessions/{id}/parts/{part}/url", Tag: "files", Summary: "Presign a direct upload URL for one part", Response: partURLResponse{}},
+	{Method: http.MethodPost, Path: "/upload/sessions/{id}/complete", Tag: "files", Summary: "Finish a resumable upload", Body: completeUploadSessionRequest{}, Response: uploadResponse{}},
+}
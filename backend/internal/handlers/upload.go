@@ -1,73 +1,72 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"mime/multipart"
+	"log"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/quotas"
+	"tm-platform-backend/internal/storage"
 	"tm-platform-backend/internal/utils"
+
+	"github.com/google/uuid"
 )
 
 const (
+	// maxFileSize caps single-request uploads; larger files go through
+	// resumable upload sessions.
 	maxFileSize    int64 = 50 << 20
 	maxRequestSize int64 = maxFileSize + (1 << 20)
+
+	maxNameAttempts = 10
 )
 
-var allowedExtensions = map[string]map[string]struct{}{
-	"image": {
-		".png":  {},
-		".jpg":  {},
-		".jpeg": {},
-		".webp": {},
-	},
-	"video": {
-		".mp4": {},
-		".mov": {},
-	},
-	"file": {
-		".pdf":  {},
-		".doc":  {},
-		".docx": {},
-		".xls":  {},
-	},
-}
+// ProjectAccess reports whether a user may add files to a project.
+type ProjectAccess func(ctx context.Context, userID, projectID uuid.UUID) (bool, error)
 
 type UploadHandler struct {
-	baseDir string
+	store         storage.Storage
+	sessions      *UploadSessionRepository
+	quotas        *quotas.Repository
+	policy        *UploadPolicy
+	projectAccess ProjectAccess
 }
 
-func NewUploadHandler(baseDir string) (*UploadHandler, error) {
-	if strings.TrimSpace(baseDir) == "" {
-		baseDir = "uploads"
+func NewUploadHandler(store storage.Storage, sessions *UploadSessionRepository, quotaRepo *quotas.Repository, policy *UploadPolicy, projectAccess ProjectAccess) (*UploadHandler, error) {
+	if store == nil {
+		return nil, errors.New("storage is required")
 	}
-
-	folders := []string{
-		baseDir,
-		filepath.Join(baseDir, "images"),
-		filepath.Join(baseDir, "videos"),
-		filepath.Join(baseDir, "files"),
+	if policy == nil {
+		return nil, errors.New("upload policy is required")
 	}
-
-	for _, folder := range folders {
-		if err := utils.EnsureFolder(folder); err != nil {
-			return nil, err
-		}
+	if projectAccess == nil {
+		return nil, errors.New("project access check is required")
 	}
 
-	return &UploadHandler{baseDir: baseDir}, nil
+	return &UploadHandler{store: store, sessions: sessions, quotas: quotaRepo, policy: policy, projectAccess: projectAccess}, nil
 }
 
 func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uploadUserID(w, r)
+	if !ok {
+		return
+	}
+
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 
 	reader, err := r.MultipartReader()
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid multipart form"})
+		problem.Write(w, http.StatusBadRequest, "invalid_multipart_form", "invalid multipart form")
 		return
 	}
 
@@ -77,6 +76,7 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		fileSize  int64
 		fileName  string
 		fileFound bool
+		projectID *uuid.UUID
 	)
 
 	defer func() {
@@ -93,7 +93,7 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 		if nextErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid multipart form"})
+			problem.Write(w, http.StatusBadRequest, "invalid_multipart_form", "invalid multipart form")
 			return
 		}
 
@@ -108,6 +108,19 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 				}
 				fileType = strings.ToLower(strings.TrimSpace(string(typeBytes)))
 				return nil
+			case "project_id":
+				raw, err := io.ReadAll(io.LimitReader(part, 64))
+				if err != nil {
+					return err
+				}
+				if value := strings.TrimSpace(string(raw)); value != "" {
+					parsed, err := uuid.Parse(value)
+					if err != nil {
+						return errors.New("invalid project_id")
+					}
+					projectID = &parsed
+				}
+				return nil
 			case "file":
 				if fileFound {
 					return errors.New("only one file is allowed")
@@ -123,7 +136,8 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 					return err
 				}
 
-				limited := io.LimitReader(part, maxFileSize+1)
+				sizeLimit := min(h.policy.MaxSize(), maxFileSize)
+				limited := io.LimitReader(part, sizeLimit+1)
 				written, err := io.Copy(f, limited)
 				if err != nil {
 					_ = f.Close()
@@ -133,12 +147,12 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 				if written == 0 {
 					_ = f.Close()
 					_ = os.Remove(f.Name())
-					return errors.New("empty file")
+					return &UploadValidationError{Message: "empty file", Code: validationEmptyFile, Field: "file"}
 				}
-				if written > maxFileSize {
+				if written > sizeLimit {
 					_ = f.Close()
 					_ = os.Remove(f.Name())
-					return errors.New("file exceeds 50MB limit")
+					return fileTooLargeError(sizeLimit)
 				}
 
 				tmpFile = f
@@ -151,57 +165,166 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 				return nil
 			}
 		}(); err != nil {
-			status := http.StatusBadRequest
-			if strings.Contains(err.Error(), "50MB") {
-				status = http.StatusRequestEntityTooLarge
-			}
-			writeJSON(w, status, map[string]string{"error": err.Error()})
+			writeUploadError(w, err)
 			return
 		}
 	}
 
 	if !fileFound {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "file is required"})
+		problem.Write(w, http.StatusBadRequest, "file_required", "file is required")
 		return
 	}
 	if fileType == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type is required"})
+		problem.Write(w, http.StatusBadRequest, "type_required", "type is required")
 		return
 	}
 
-	folderName := fileTypeFolder(fileType)
-	if folderName == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid type"})
+	if err := h.policy.ValidateName(fileType, fileName); err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	if err := h.policy.ValidateSize(fileType, fileSize, maxFileSize); err != nil {
+		writeUploadError(w, err)
 		return
 	}
 
-	if err := validateExtension(fileName, fileType); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	head := make([]byte, sniffLength)
+	n, err := tmpFile.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		problem.Error(w, http.StatusInternalServerError, "failed to process file")
+		return
+	}
+	if err := h.policy.ValidateContent(fileName, head[:n]); err != nil {
+		writeUploadError(w, err)
 		return
 	}
 
-	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process file"})
+	if projectID != nil && !h.checkProjectAccess(w, r, userID, *projectID) {
+		return
+	}
+	reservation, ok := h.reserveQuota(w, r, userID, projectID, fileSize)
+	if !ok {
 		return
 	}
+	defer h.releaseQuota(r.Context(), reservation)
 
-	header := &multipart.FileHeader{
-		Filename: fileName,
-		Size:     fileSize,
+	objectKey, err := h.saveObject(r.Context(), tmpFile, fileSize, fileName, fileTypeFolder(fileType))
+	if err != nil {
+		log.Printf("upload save failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to save file")
+		return
+	}
+	response := map[string]string{
+		"url":            storage.PublicURL(objectKey),
+		"fileName":       fileName,
+		"storedFileName": path.Base(objectKey),
 	}
-	targetFolder := filepath.Join(h.baseDir, folderName)
+	objectID, ok := h.recordObject(w, r, objectKey, userID, projectID, fileSize)
+	if !ok {
+		return
+	}
+	if objectID != uuid.Nil {
+		response["fileId"] = objectID.String()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *UploadHandler) saveObject(ctx context.Context, file *os.File, size int64, originalName string, folder string) (string, error) {
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(originalName)))
+
+	for i := 0; i < maxNameAttempts; i++ {
+		key, err := utils.BuildObjectKey(folder, originalName)
+		if err != nil {
+			return "", err
+		}
 
-	_, savedFileName, err := utils.SaveUploadedFile(tmpFile, header, targetFolder)
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+
+		err = h.store.Put(ctx, key, file, size, contentType)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return key, nil
+	}
+
+	return "", fmt.Errorf("failed to generate a unique filename after %d attempts", maxNameAttempts)
+}
+
+// checkProjectAccess writes an error response and returns false when the
+// user may not add files to the project. Projects the user cannot write to
+// are reported as not found, as in projectfiles.
+func (h *UploadHandler) checkProjectAccess(w http.ResponseWriter, r *http.Request, userID, projectID uuid.UUID) bool {
+	allowed, err := h.projectAccess(r.Context(), userID, projectID)
+	if err != nil {
+		log.Printf("check upload project access failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check project access")
+		return false
+	}
+	if !allowed {
+		problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+		return false
+	}
+	return true
+}
+
+// reserveQuota holds size bytes of the user's and project's quota until
+// releaseQuota. It writes an error response and returns false when they
+// would exceed the quota.
+func (h *UploadHandler) reserveQuota(w http.ResponseWriter, r *http.Request, userID uuid.UUID, projectID *uuid.UUID, size int64) (uuid.UUID, bool) {
+	if h.quotas == nil {
+		return uuid.Nil, true
+	}
+
+	reservation, err := h.quotas.Reserve(r.Context(), userID, projectID, size)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to save file"})
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			problem.Error(w, http.StatusRequestEntityTooLarge, err.Error())
+			return uuid.Nil, false
+		}
+		log.Printf("storage quota check failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check storage quota")
+		return uuid.Nil, false
+	}
+	return reservation, true
+}
+
+// releaseQuota gives back a reservation once the object it was for is
+// recorded, or was never stored. A reservation that cannot be released
+// holds the quota until it expires.
+func (h *UploadHandler) releaseQuota(ctx context.Context, reservation uuid.UUID) {
+	if h.quotas == nil {
 		return
 	}
+	if err := h.quotas.Release(context.WithoutCancel(ctx), reservation); err != nil {
+		log.Printf("release storage reservation %s failed: %v", reservation, err)
+	}
+}
 
-	writeJSON(w, http.StatusOK, map[string]string{
-		"url":            "/uploads/" + folderName + "/" + savedFileName,
-		"fileName":       fileName,
-		"storedFileName": savedFileName,
-	})
+// recordObject registers a stored object for quota accounting and access
+// checks. An object that could not be recorded would be stored without
+// counting towards any quota, so it is deleted again and an error response
+// written; recordObject then returns false. Without quotas there is nothing
+// to record and the id is uuid.Nil.
+func (h *UploadHandler) recordObject(w http.ResponseWriter, r *http.Request, key string, userID uuid.UUID, projectID *uuid.UUID, size int64) (uuid.UUID, bool) {
+	if h.quotas == nil {
+		return uuid.Nil, true
+	}
+	objectID, err := h.quotas.RecordObject(r.Context(), key, userID, projectID, size)
+	if err != nil {
+		log.Printf("record storage usage for %s failed: %v", key, err)
+		if err := h.store.Delete(context.WithoutCancel(r.Context()), key); err != nil {
+			log.Printf("delete unrecorded upload %s failed: %v", key, err)
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to save file")
+		return uuid.Nil, false
+	}
+	return objectID, true
 }
 
 func fileTypeFolder(fileType string) string {
@@ -217,24 +340,6 @@ func fileTypeFolder(fileType string) string {
 	}
 }
 
-func validateExtension(originalName string, fileType string) error {
-	ext := strings.ToLower(filepath.Ext(originalName))
-	if ext == "" {
-		return errors.New("missing file extension")
-	}
-
-	allowedForType, ok := allowedExtensions[fileType]
-	if !ok {
-		return errors.New("invalid type")
-	}
-
-	if _, allowed := allowedForType[ext]; !allowed {
-		return errors.New("unsupported file extension")
-	}
-
-	return nil
-}
-
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
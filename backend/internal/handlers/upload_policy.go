@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tm-platform-backend/internal/problem"
+)
+
+// sniffLength is how much of a file is inspected to detect its real type.
+const sniffLength = 512
+
+const (
+	validationInvalidType          = "invalid_type"
+	validationMissingExtension     = "missing_extension"
+	validationUnsupportedExtension = "unsupported_extension"
+	validationDoubleExtension      = "double_extension"
+	validationDangerousFile        = "dangerous_file"
+	validationContentMismatch      = "content_mismatch"
+	validationFileTooLarge         = "file_too_large"
+	validationEmptyFile            = "empty_file"
+)
+
+const (
+	contentTypeOLE       = "application/x-ole-storage"
+	contentTypeZip       = "application/zip"
+	contentTypeQuickTime = "video/quicktime"
+)
+
+// extensionContentTypes lists the sniffed content types accepted for each
+// extension. Only extensions listed here can be allowed by an UploadPolicy,
+// so every accepted upload is checked against its real content.
+var extensionContentTypes = map[string][]string{
+	".png":  {"image/png"},
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".webp": {"image/webp"},
+	".gif":  {"image/gif"},
+	".mp4":  {"video/mp4"},
+	".mov":  {contentTypeQuickTime, "video/mp4"},
+	".webm": {"video/webm"},
+	".pdf":  {"application/pdf"},
+	".doc":  {contentTypeOLE},
+	".xls":  {contentTypeOLE},
+	".ppt":  {contentTypeOLE},
+	".docx": {contentTypeZip},
+	".xlsx": {contentTypeZip},
+	".pptx": {contentTypeZip},
+	".txt":  {"text/plain"},
+	".csv":  {"text/plain"},
+}
+
+// dangerousExtensions are rejected anywhere in a file name, so that
+// "report.pdf.exe" and "report.exe.pdf" are both refused.
+var dangerousExtensions = map[string]struct{}{
+	".exe": {}, ".dll": {}, ".com": {}, ".scr": {}, ".msi": {}, ".bat": {},
+	".cmd": {}, ".ps1": {}, ".vbs": {}, ".js": {}, ".mjs": {}, ".jar": {},
+	".sh": {}, ".php": {}, ".phtml": {}, ".asp": {}, ".aspx": {}, ".jsp": {},
+	".py": {}, ".pl": {}, ".cgi": {}, ".hta": {}, ".html": {}, ".htm": {},
+	".xhtml": {}, ".svg": {}, ".apk": {}, ".lnk": {},
+}
+
+// dangerousContentTypes are refused regardless of the extension they arrive
+// with.
+var dangerousContentTypes = map[string]struct{}{
+	"application/x-msdownload":  {},
+	"application/x-elf":         {},
+	"application/x-mach-binary": {},
+	"text/x-shellscript":        {},
+	"text/html":                 {},
+	"text/xml":                  {},
+	"image/svg+xml":             {},
+}
+
+// UploadTypePolicy configures one upload type ("image", "video", "file").
+type UploadTypePolicy struct {
+	Extensions []string
+	MaxSize    int64
+}
+
+// UploadPolicy decides which files the upload endpoints accept.
+type UploadPolicy struct {
+	types map[string]uploadTypeRule
+}
+
+type uploadTypeRule struct {
+	extensions map[string]struct{}
+	maxSize    int64
+}
+
+// UploadValidationError describes why an upload was rejected. It is written
+// to the client as is.
+type UploadValidationError struct {
+	Message           string   `json:"error"`
+	Code              string   `json:"code"`
+	Field             string   `json:"field"`
+	DetectedType      string   `json:"detectedType,omitempty"`
+	MaxSize           int64    `json:"maxSize,omitempty"`
+	AllowedExtensions []string `json:"allowedExtensions,omitempty"`
+}
+
+func (e *UploadValidationError) Error() string {
+	return e.Message
+}
+
+func NewUploadPolicy(types map[string]UploadTypePolicy) (*UploadPolicy, error) {
+	policy := &UploadPolicy{types: make(map[string]uploadTypeRule, len(types))}
+
+	for fileType, config := range types {
+		if fileTypeFolder(fileType) == "" {
+			return nil, fmt.Errorf("unknown upload type %q", fileType)
+		}
+		if config.MaxSize <= 0 {
+			return nil, fmt.Errorf("max size for %s uploads must be > 0", fileType)
+		}
+
+		extensions := make(map[string]struct{}, len(config.Extensions))
+		for _, raw := range config.Extensions {
+			ext := strings.ToLower(strings.TrimSpace(raw))
+			if ext == "" {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			if _, dangerous := dangerousExtensions[ext]; dangerous {
+				return nil, fmt.Errorf("extension %s cannot be allowed for uploads", ext)
+			}
+			if _, known := extensionContentTypes[ext]; !known {
+				return nil, fmt.Errorf("extension %s has no known content signature", ext)
+			}
+			extensions[ext] = struct{}{}
+		}
+		if len(extensions) == 0 {
+			return nil, fmt.Errorf("no extensions allowed for %s uploads", fileType)
+		}
+
+		policy.types[fileType] = uploadTypeRule{extensions: extensions, maxSize: config.MaxSize}
+	}
+
+	if len(policy.types) == 0 {
+		return nil, errors.New("upload policy allows no types")
+	}
+
+	return policy, nil
+}
+
+// MaxSize returns the largest size accepted for any type.
+func (p *UploadPolicy) MaxSize() int64 {
+	var largest int64
+	for _, rule := range p.types {
+		largest = max(largest, rule.maxSize)
+	}
+	return largest
+}
+
+// ValidateName checks the type and the file name's extensions.
+func (p *UploadPolicy) ValidateName(fileType, originalName string) error {
+	rule, ok := p.types[fileType]
+	if !ok {
+		return &UploadValidationError{Message: "invalid type", Code: validationInvalidType, Field: "type"}
+	}
+
+	name := strings.ToLower(strings.TrimSpace(filepath.Base(originalName)))
+	ext := filepath.Ext(name)
+	if ext == "" || ext == name {
+		return &UploadValidationError{Message: "missing file extension", Code: validationMissingExtension, Field: "file"}
+	}
+
+	segments := strings.Split(strings.TrimPrefix(name, "."), ".")
+	for _, segment := range segments[1:] {
+		inner := "." + segment
+		if _, dangerous := dangerousExtensions[inner]; dangerous {
+			return &UploadValidationError{Message: "file type is not allowed", Code: validationDangerousFile, Field: "file"}
+		}
+	}
+	for _, segment := range segments[1 : len(segments)-1] {
+		if _, known := extensionContentTypes["."+segment]; known {
+			return &UploadValidationError{Message: "file name has more than one extension", Code: validationDoubleExtension, Field: "file"}
+		}
+	}
+
+	if _, allowed := rule.extensions[ext]; !allowed {
+		return &UploadValidationError{
+			Message:           "unsupported file extension",
+			Code:              validationUnsupportedExtension,
+			Field:             "file",
+			AllowedExtensions: rule.allowedExtensions(),
+		}
+	}
+
+	return nil
+}
+
+// ValidateSize checks size against the limit of fileType and, when ceiling is
+// positive, against the limit of the endpoint itself.
+func (p *UploadPolicy) ValidateSize(fileType string, size, ceiling int64) error {
+	rule, ok := p.types[fileType]
+	if !ok {
+		return &UploadValidationError{Message: "invalid type", Code: validationInvalidType, Field: "type"}
+	}
+	if size <= 0 {
+		return &UploadValidationError{Message: "empty file", Code: validationEmptyFile, Field: "file"}
+	}
+
+	limit := rule.maxSize
+	if ceiling > 0 {
+		limit = min(limit, ceiling)
+	}
+	if size > limit {
+		return fileTooLargeError(limit)
+	}
+	return nil
+}
+
+// ValidateContent sniffs the first bytes of a file and checks that they match
+// its extension.
+func (p *UploadPolicy) ValidateContent(originalName string, head []byte) error {
+	detected := sniffContentType(head)
+	if _, dangerous := dangerousContentTypes[detected]; dangerous {
+		return &UploadValidationError{Message: "file type is not allowed", Code: validationDangerousFile, Field: "file", DetectedType: detected}
+	}
+
+	ext := strings.ToLower(filepath.Ext(originalName))
+	for _, accepted := range extensionContentTypes[ext] {
+		if detected == accepted {
+			return nil
+		}
+	}
+
+	return &UploadValidationError{
+		Message:      "file content does not match its extension",
+		Code:         validationContentMismatch,
+		Field:        "file",
+		DetectedType: detected,
+	}
+}
+
+func (r uploadTypeRule) allowedExtensions() []string {
+	extensions := make([]string, 0, len(r.extensions))
+	for ext := range r.extensions {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+func fileTooLargeError(limit int64) *UploadValidationError {
+	return &UploadValidationError{
+		Message: "file exceeds " + formatSizeLimit(limit) + " limit",
+		Code:    validationFileTooLarge,
+		Field:   "file",
+		MaxSize: limit,
+	}
+}
+
+// sniffContentType extends http.DetectContentType with the formats it does
+// not recognise: legacy Office documents, QuickTime and executables.
+func sniffContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}):
+		return contentTypeOLE
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return "application/x-msdownload"
+	case bytes.HasPrefix(head, []byte{0x7F, 'E', 'L', 'F'}):
+		return "application/x-elf"
+	case bytes.HasPrefix(head, []byte{0xCF, 0xFA, 0xED, 0xFE}),
+		bytes.HasPrefix(head, []byte{0xCE, 0xFA, 0xED, 0xFE}),
+		bytes.HasPrefix(head, []byte{0xCA, 0xFE, 0xBA, 0xBE}):
+		return "application/x-mach-binary"
+	case bytes.HasPrefix(head, []byte("#!")):
+		return "text/x-shellscript"
+	case len(head) >= 12 && string(head[4:8]) == "ftyp" && string(head[8:12]) == "qt  ":
+		return contentTypeQuickTime
+	}
+
+	detected := http.DetectContentType(head)
+	if semicolon := strings.IndexByte(detected, ';'); semicolon >= 0 {
+		detected = detected[:semicolon]
+	}
+	detected = strings.TrimSpace(detected)
+
+	if detected == "text/plain" && bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return detected
+}
+
+func formatSizeLimit(size int64) string {
+	switch {
+	case size >= 1<<30 && size%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", size>>30)
+	case size >= 1<<20:
+		return fmt.Sprintf("%dMB", size>>20)
+	default:
+		return fmt.Sprintf("%dKB", max(size>>10, 1))
+	}
+}
+
+// writeUploadError writes validation errors with their details and falls back
+// to a plain 400 for anything else.
+func writeUploadError(w http.ResponseWriter, err error) {
+	var validationErr *UploadValidationError
+	if !errors.As(err, &validationErr) {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch validationErr.Code {
+	case validationFileTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case validationContentMismatch, validationDangerousFile, validationUnsupportedExtension:
+		status = http.StatusUnsupportedMediaType
+	}
+	problem.WriteWith(w, status, validationErr.Code, validationErr.Message, validationErr)
+}
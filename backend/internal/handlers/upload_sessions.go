@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+	"tm-platform-backend/internal/storage"
+	"tm-platform-backend/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// uploadChunkSize stays above the 5MB minimum part size required by S3.
+	uploadChunkSize       int64 = 8 << 20
+	maxResumableFileSize  int64 = 2 << 30
+	uploadSessionTTL            = 24 * time.Hour
+	uploadPartURLTTL            = 15 * time.Minute
+	uploadOffsetHeader          = "Upload-Offset"
+	expiredSessionsPerRun       = 20
+)
+
+type createUploadSessionRequest struct {
+	Type     string `json:"type"`
+	FileName string `json:"fileName"`
+	Size     int64  `json:"size"`
+}
+
+type completeUploadSessionRequest struct {
+	Parts []completedPartRequest `json:"parts"`
+}
+
+type completedPartRequest struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+}
+
+type uploadSessionResponse struct {
+	ID               uuid.UUID `json:"id"`
+	FileName         string    `json:"fileName"`
+	Type             string    `json:"type"`
+	Size             int64     `json:"size"`
+	ChunkSize        int64     `json:"chunkSize"`
+	Offset           int64     `json:"offset"`
+	TotalParts       int       `json:"totalParts"`
+	UploadedParts    []int     `json:"uploadedParts"`
+	Status           string    `json:"status"`
+	PresignSupported bool      `json:"presignSupported"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+// CreateUploadSession starts a resumable upload. The client then sends the
+// file in chunkSize pieces via PatchUploadSession (or straight to storage via
+// presigned part URLs) and finishes with CompleteUploadSession.
+func (h *UploadHandler) CreateUploadSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uploadUserID(w, r)
+	if !ok {
+		return
+	}
+
+	multipart, ok := h.multipartStore(w)
+	if !ok {
+		return
+	}
+
+	var req createUploadSessionRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	fileType := strings.ToLower(strings.TrimSpace(req.Type))
+	folderName := fileTypeFolder(fileType)
+	if folderName == "" {
+		problem.Write(w, http.StatusBadRequest, "invalid_type", "invalid type")
+		return
+	}
+
+	fileName := ""
+	if name := strings.TrimSpace(req.FileName); name != "" {
+		fileName = filepath.Base(name)
+	}
+	if fileName == "" || fileName == "." {
+		problem.Write(w, http.StatusBadRequest, "file_name_required", "fileName is required")
+		return
+	}
+	if err := h.policy.ValidateName(fileType, fileName); err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	if req.Size <= 0 {
+		problem.Write(w, http.StatusBadRequest, "invalid_size", "size must be > 0")
+		return
+	}
+	if err := h.policy.ValidateSize(fileType, req.Size, maxResumableFileSize); err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	h.abortExpiredSessions(r.Context())
+	// the active session holds the quota once created
+	reservation, ok := h.reserveQuota(w, r, userID, nil, req.Size)
+	if !ok {
+		return
+	}
+	defer h.releaseQuota(r.Context(), reservation)
+
+	key, err := utils.BuildObjectKey(folderName, fileName)
+	if err != nil {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName)))
+
+	multipartID, err := multipart.CreateMultipart(r.Context(), key, contentType)
+	if err != nil {
+		log.Printf("create multipart upload failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to start upload")
+		return
+	}
+
+	session, err := h.sessions.Create(r.Context(), UploadSession{
+		UserID:      userID,
+		ObjectKey:   key,
+		MultipartID: multipartID,
+		FileName:    fileName,
+		FileType:    fileType,
+		ContentType: contentType,
+		TotalSize:   req.Size,
+		ChunkSize:   uploadChunkSize,
+		ExpiresAt:   time.Now().Add(uploadSessionTTL),
+	})
+	if err != nil {
+		_ = multipart.AbortMultipart(r.Context(), key, multipartID)
+		log.Printf("create upload session failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to start upload")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, h.sessionResponse(session))
+}
+
+func (h *UploadHandler) GetUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadSession(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(sessionOffset(session), 10))
+	writeJSON(w, http.StatusOK, h.sessionResponse(session))
+}
+
+// PatchUploadSession appends the next chunk. The Upload-Offset header must
+// match the session offset, so a client that lost its connection asks for the
+// session first and resumes from the returned offset.
+func (h *UploadHandler) PatchUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+	multipart, ok := h.multipartStore(w)
+	if !ok {
+		return
+	}
+
+	offset := sessionOffset(session)
+	requestedOffset, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get(uploadOffsetHeader)), 10, 64)
+	if err != nil || requestedOffset < 0 {
+		problem.Write(w, http.StatusBadRequest, "invalid_upload_offset_header", "invalid Upload-Offset header")
+		return
+	}
+	if requestedOffset != offset {
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+		problem.WriteWith(w, http.StatusConflict, "offset_mismatch", "offset mismatch", map[string]any{"offset": offset})
+		return
+	}
+	if offset >= session.TotalSize {
+		problem.Write(w, http.StatusBadRequest, "upload_is_already_complete", "upload is already complete")
+		return
+	}
+
+	partNumber := int(offset/session.ChunkSize) + 1
+	expected := partSize(session, partNumber)
+	if r.ContentLength != expected {
+		problem.WriteWith(w, http.StatusBadRequest, "chunk_size_mismatch", "chunk size mismatch", map[string]any{"expected": expected})
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, expected)
+	etag, err := multipart.UploadPart(r.Context(), session.ObjectKey, session.MultipartID, partNumber, body, expected)
+	if err != nil {
+		log.Printf("upload part failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to store chunk")
+		return
+	}
+
+	part := UploadSessionPart{PartNumber: partNumber, ETag: etag, Size: expected}
+	if err := h.sessions.SavePart(r.Context(), session.ID, part); err != nil {
+		log.Printf("save upload part failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to store chunk")
+		return
+	}
+	session.Parts = append(session.Parts, part)
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(sessionOffset(session), 10))
+	writeJSON(w, http.StatusOK, h.sessionResponse(session))
+}
+
+// PresignUploadPart returns a URL the client can PUT a part to directly when
+// the storage backend supports it. The returned ETag header of that PUT must
+// be sent back in CompleteUploadSession.
+func (h *UploadHandler) PresignUploadPart(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+
+	presigner, ok := h.store.(storage.PartPresigner)
+	if !ok {
+		problem.Error(w, http.StatusNotImplemented, "storage does not support presigned uploads")
+		return
+	}
+
+	partNumber, err := strconv.Atoi(chi.URLParam(r, "part"))
+	if err != nil || partNumber <= 0 || partNumber > totalParts(session) {
+		problem.Write(w, http.StatusBadRequest, "invalid_part_number", "invalid part number")
+		return
+	}
+
+	url, err := presigner.PresignPart(session.ObjectKey, session.MultipartID, partNumber, uploadPartURLTTL)
+	if err != nil {
+		log.Printf("presign upload part failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to presign part")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"url":        url,
+		"method":     http.MethodPut,
+		"partNumber": partNumber,
+		"size":       partSize(session, partNumber),
+		"expiresAt":  time.Now().Add(uploadPartURLTTL),
+	})
+}
+
+func (h *UploadHandler) CompleteUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+	multipart, ok := h.multipartStore(w)
+	if !ok {
+		return
+	}
+
+	var req completeUploadSessionRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, request.ErrEmptyBody) {
+		request.WriteError(w, err)
+		return
+	}
+
+	parts := make([]storage.CompletedPart, 0, totalParts(session))
+	if len(req.Parts) > 0 {
+		for _, part := range req.Parts {
+			parts = append(parts, storage.CompletedPart{PartNumber: part.PartNumber, ETag: strings.TrimSpace(part.ETag)})
+		}
+	} else {
+		for _, part := range session.Parts {
+			parts = append(parts, storage.CompletedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+		}
+	}
+
+	if err := validateCompletedParts(parts, totalParts(session)); err != nil {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := multipart.CompleteMultipart(r.Context(), session.ObjectKey, session.MultipartID, parts); err != nil {
+		log.Printf("complete multipart upload failed: %v", err)
+		problem.Error(w, http.StatusBadGateway, "failed to assemble file")
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), session.ObjectKey)
+	if err != nil {
+		log.Printf("stat completed upload failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to assemble file")
+		return
+	}
+	if info.Size != session.TotalSize {
+		_ = h.store.Delete(r.Context(), session.ObjectKey)
+		_ = h.sessions.SetStatus(r.Context(), session.ID, uploadSessionAborted)
+		problem.Write(w, http.StatusBadRequest, "uploaded_size_does_not_match_declared_size", "uploaded size does not match declared size")
+		return
+	}
+	if err := h.validateStoredContent(r.Context(), session); err != nil {
+		var validationErr *UploadValidationError
+		if !errors.As(err, &validationErr) {
+			log.Printf("validate completed upload failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to assemble file")
+			return
+		}
+		_ = h.store.Delete(r.Context(), session.ObjectKey)
+		_ = h.sessions.SetStatus(r.Context(), session.ID, uploadSessionAborted)
+		writeUploadError(w, err)
+		return
+	}
+
+	objectID, ok := h.recordObject(w, r, session.ObjectKey, session.UserID, nil, info.Size)
+	if !ok {
+		_ = h.sessions.SetStatus(r.Context(), session.ID, uploadSessionAborted)
+		return
+	}
+	if err := h.sessions.SetStatus(r.Context(), session.ID, uploadSessionCompleted); err != nil {
+		log.Printf("complete upload session failed: %v", err)
+	}
+	response := map[string]any{
+		"url":            storage.PublicURL(session.ObjectKey),
+		"fileName":       session.FileName,
+		"storedFileName": path.Base(session.ObjectKey),
+		"size":           info.Size,
+	}
+	if objectID != uuid.Nil {
+		response["fileId"] = objectID
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *UploadHandler) AbortUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadSession(w, r)
+	if !ok {
+		return
+	}
+	if session.Status != uploadSessionActive {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if multipart, isMultipart := h.store.(storage.MultipartStorage); isMultipart {
+		if err := multipart.AbortMultipart(r.Context(), session.ObjectKey, session.MultipartID); err != nil {
+			log.Printf("abort multipart upload failed: %v", err)
+		}
+	}
+	if err := h.sessions.SetStatus(r.Context(), session.ID, uploadSessionAborted); err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to abort upload")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) loadSession(w http.ResponseWriter, r *http.Request) (UploadSession, bool) {
+	userID, ok := uploadUserID(w, r)
+	if !ok {
+		return UploadSession{}, false
+	}
+	if h.sessions == nil {
+		problem.Error(w, http.StatusNotImplemented, "resumable uploads are not configured")
+		return UploadSession{}, false
+	}
+
+	sessionID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_session_id", "invalid session id")
+		return UploadSession{}, false
+	}
+
+	session, err := h.sessions.Get(r.Context(), userID, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "upload_session_not_found", "upload session not found")
+			return UploadSession{}, false
+		}
+		log.Printf("load upload session failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load upload session")
+		return UploadSession{}, false
+	}
+
+	return session, true
+}
+
+func (h *UploadHandler) loadActiveSession(w http.ResponseWriter, r *http.Request) (UploadSession, bool) {
+	session, ok := h.loadSession(w, r)
+	if !ok {
+		return UploadSession{}, false
+	}
+	if session.Status != uploadSessionActive {
+		problem.Error(w, http.StatusConflict, "upload session is "+session.Status)
+		return UploadSession{}, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		problem.Write(w, http.StatusGone, "upload_session_expired", "upload session expired")
+		return UploadSession{}, false
+	}
+	return session, true
+}
+
+func (h *UploadHandler) multipartStore(w http.ResponseWriter) (storage.MultipartStorage, bool) {
+	multipart, ok := h.store.(storage.MultipartStorage)
+	if !ok || h.sessions == nil {
+		problem.Error(w, http.StatusNotImplemented, "resumable uploads are not supported")
+		return nil, false
+	}
+	return multipart, true
+}
+
+// abortExpiredSessions releases staged parts of abandoned uploads. It runs
+// opportunistically when new sessions are created.
+func (h *UploadHandler) abortExpiredSessions(ctx context.Context) {
+	multipart, ok := h.store.(storage.MultipartStorage)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessions.ListExpired(ctx, expiredSessionsPerRun)
+	if err != nil {
+		log.Printf("list expired upload sessions failed: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		if err := multipart.AbortMultipart(ctx, session.ObjectKey, session.MultipartID); err != nil {
+			log.Printf("abort expired upload %s failed: %v", session.ID, err)
+			continue
+		}
+		_ = h.sessions.SetStatus(ctx, session.ID, uploadSessionAborted)
+	}
+}
+
+func (h *UploadHandler) sessionResponse(session UploadSession) uploadSessionResponse {
+	uploaded := make([]int, 0, len(session.Parts))
+	for _, part := range session.Parts {
+		uploaded = append(uploaded, part.PartNumber)
+	}
+	_, presign := h.store.(storage.PartPresigner)
+
+	return uploadSessionResponse{
+		ID:               session.ID,
+		FileName:         session.FileName,
+		Type:             session.FileType,
+		Size:             session.TotalSize,
+		ChunkSize:        session.ChunkSize,
+		Offset:           sessionOffset(session),
+		TotalParts:       totalParts(session),
+		UploadedParts:    uploaded,
+		Status:           session.Status,
+		PresignSupported: presign,
+		ExpiresAt:        session.ExpiresAt,
+	}
+}
+
+// sessionOffset is the number of bytes received contiguously from the start.
+func sessionOffset(session UploadSession) int64 {
+	sizes := make(map[int]int64, len(session.Parts))
+	for _, part := range session.Parts {
+		sizes[part.PartNumber] = part.Size
+	}
+
+	var offset int64
+	for number := 1; ; number++ {
+		size, ok := sizes[number]
+		if !ok {
+			return offset
+		}
+		offset += size
+	}
+}
+
+func totalParts(session UploadSession) int {
+	return int((session.TotalSize + session.ChunkSize - 1) / session.ChunkSize)
+}
+
+func partSize(session UploadSession, partNumber int) int64 {
+	start := int64(partNumber-1) * session.ChunkSize
+	remaining := session.TotalSize - start
+	if remaining < session.ChunkSize {
+		return remaining
+	}
+	return session.ChunkSize
+}
+
+// validateStoredContent checks the real type of an assembled upload, which
+// could not be inspected while its parts were sent.
+func (h *UploadHandler) validateStoredContent(ctx context.Context, session UploadSession) error {
+	body, _, err := h.store.Open(ctx, session.ObjectKey)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return h.policy.ValidateContent(session.FileName, head[:n])
+}
+
+func validateCompletedParts(parts []storage.CompletedPart, expected int) error {
+	if len(parts) != expected {
+		return errors.New("upload is incomplete")
+	}
+
+	seen := make(map[int]struct{}, len(parts))
+	for _, part := range parts {
+		if part.PartNumber <= 0 || part.PartNumber > expected {
+			return errors.New("invalid part number")
+		}
+		if _, dup := seen[part.PartNumber]; dup {
+			return errors.New("duplicate part number")
+		}
+		if part.ETag == "" {
+			return errors.New("part etag is required")
+		}
+		seen[part.PartNumber] = struct{}{}
+	}
+	return nil
+}
+
+func uploadUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, "invalid_token_subject", "invalid token subject")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
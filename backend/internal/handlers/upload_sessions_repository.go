@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	uploadSessionActive    = "active"
+	uploadSessionCompleted = "completed"
+	uploadSessionAborted   = "aborted"
+)
+
+type UploadSession struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	ObjectKey   string
+	MultipartID string
+	FileName    string
+	FileType    string
+	ContentType string
+	TotalSize   int64
+	ChunkSize   int64
+	Status      string
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Parts       []UploadSessionPart
+}
+
+type UploadSessionPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// UploadSessionRepository keeps the state of resumable uploads so they can be
+// continued after a dropped connection or a backend restart.
+type UploadSessionRepository struct {
+	db *sql.DB
+}
+
+func NewUploadSessionRepository(db *sql.DB) *UploadSessionRepository {
+	return &UploadSessionRepository{db: db}
+}
+
+const uploadSessionColumns = `id, user_id, object_key, multipart_id, file_name, file_type, content_type,
+	total_size, chunk_size, status, expires_at, created_at, updated_at`
+
+func (r *UploadSessionRepository) Create(ctx context.Context, session UploadSession) (UploadSession, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO upload_sessions (user_id, object_key, multipart_id, file_name, file_type, content_type, total_size, chunk_size, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING `+uploadSessionColumns,
+		session.UserID,
+		session.ObjectKey,
+		session.MultipartID,
+		session.FileName,
+		session.FileType,
+		session.ContentType,
+		session.TotalSize,
+		session.ChunkSize,
+		session.ExpiresAt,
+	)
+	return scanUploadSession(row)
+}
+
+func (r *UploadSessionRepository) Get(ctx context.Context, userID, id uuid.UUID) (UploadSession, error) {
+	session, err := scanUploadSession(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+uploadSessionColumns+`
+		 FROM upload_sessions
+		 WHERE id = $1 AND user_id = $2`,
+		id,
+		userID,
+	))
+	if err != nil {
+		return UploadSession{}, err
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT part_number, etag, size
+		 FROM upload_session_parts
+		 WHERE session_id = $1
+		 ORDER BY part_number`,
+		id,
+	)
+	if err != nil {
+		return UploadSession{}, err
+	}
+	defer rows.Close()
+
+	session.Parts = make([]UploadSessionPart, 0)
+	for rows.Next() {
+		var part UploadSessionPart
+		if err := rows.Scan(&part.PartNumber, &part.ETag, &part.Size); err != nil {
+			return UploadSession{}, err
+		}
+		session.Parts = append(session.Parts, part)
+	}
+
+	if err := rows.Err(); err != nil {
+		return UploadSession{}, err
+	}
+
+	return session, nil
+}
+
+func (r *UploadSessionRepository) SavePart(ctx context.Context, sessionID uuid.UUID, part UploadSessionPart) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO upload_session_parts (session_id, part_number, etag, size)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (session_id, part_number)
+		 DO UPDATE SET etag = EXCLUDED.etag, size = EXCLUDED.size, created_at = now()`,
+		sessionID,
+		part.PartNumber,
+		part.ETag,
+		part.Size,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `UPDATE upload_sessions SET updated_at = now() WHERE id = $1`, sessionID)
+	return err
+}
+
+func (r *UploadSessionRepository) SetStatus(ctx context.Context, id uuid.UUID, status string) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE upload_sessions SET status = $2, updated_at = now() WHERE id = $1`,
+		id,
+		status,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *UploadSessionRepository) ListExpired(ctx context.Context, limit int) ([]UploadSession, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+uploadSessionColumns+`
+		 FROM upload_sessions
+		 WHERE status = 'active' AND expires_at < now()
+		 ORDER BY expires_at
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]UploadSession, 0)
+	for rows.Next() {
+		session, err := scanUploadSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+type uploadSessionScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUploadSession(row uploadSessionScanner) (UploadSession, error) {
+	var session UploadSession
+	if err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.ObjectKey,
+		&session.MultipartID,
+		&session.FileName,
+		&session.FileType,
+		&session.ContentType,
+		&session.TotalSize,
+		&session.ChunkSize,
+		&session.Status,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	); err != nil {
+		return UploadSession{}, err
+	}
+	return session, nil
+}
@@ -0,0 +1,173 @@
+// Package health probes the dependencies of the backend for /ready. Each
+// dependency has an impact: a failing critical one makes the backend not
+// ready, answered with 503 so load balancers take it out of rotation; a
+// failing degraded one only marks the backend degraded, still 200, since
+// the rest of the API works without it. A backend shutting down is not
+// ready either, whatever its dependencies say.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Impact is what a failing dependency does to the backend.
+type Impact string
+
+const (
+	ImpactCritical Impact = "critical"
+	ImpactDegraded Impact = "degraded"
+)
+
+// Overall statuses of a Report.
+const (
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"
+	StatusNotReady = "not_ready"
+)
+
+// Statuses of a single dependency.
+const (
+	CheckOK       = "ok"
+	CheckError    = "error"
+	CheckDisabled = "disabled"
+)
+
+// Dependency is something the backend talks to. A nil Probe means it is not
+// configured.
+type Dependency struct {
+	Name   string
+	Impact Impact
+	Probe  func(ctx context.Context) error
+}
+
+// Check is the outcome of probing a dependency.
+type Check struct {
+	Name      string `json:"name"`
+	Impact    Impact `json:"impact"`
+	Status    string `json:"status"` // ok, error or disabled
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the answer of /ready.
+type Report struct {
+	Status       string    `json:"status"` // ready, degraded or not_ready
+	Draining     bool      `json:"draining,omitempty"`
+	Dependencies []Check   `json:"dependencies"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// Checker probes dependencies, all at once and each bounded by timeout. A
+// report is reused for cacheTTL so frequent probes don't load the
+// dependencies; concurrent callers wait for a single probe.
+type Checker struct {
+	dependencies []Dependency
+	timeout      time.Duration
+	cacheTTL     time.Duration
+
+	mu   sync.Mutex
+	last *Report
+
+	draining atomic.Bool
+}
+
+func NewChecker(timeout, cacheTTL time.Duration, dependencies ...Dependency) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{dependencies: dependencies, timeout: timeout, cacheTTL: cacheTTL}
+}
+
+// Drain marks the backend not ready from now on, so load balancers stop
+// sending it requests before it shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) Check(ctx context.Context) Report {
+	report := c.check(ctx)
+	if c.draining.Load() {
+		report.Status = StatusNotReady
+		report.Draining = true
+	}
+	return report
+}
+
+func (c *Checker) check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.cacheTTL {
+		return *c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Dependencies: make([]Check, len(c.dependencies))}
+	var wg sync.WaitGroup
+	for i, dependency := range c.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Dependencies[i] = probe(ctx, dependency)
+		}()
+	}
+	wg.Wait()
+	report.CheckedAt = time.Now().UTC()
+
+	report.Status = StatusReady
+	for _, check := range report.Dependencies {
+		if check.Status != CheckError {
+			continue
+		}
+		if check.Impact == ImpactCritical {
+			report.Status = StatusNotReady
+			break
+		}
+		report.Status = StatusDegraded
+	}
+
+	// A cancelled request says nothing about the dependencies
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		c.last = &report
+	}
+	return report
+}
+
+func probe(ctx context.Context, dependency Dependency) Check {
+	check := Check{Name: dependency.Name, Impact: dependency.Impact, Status: CheckDisabled}
+	if dependency.Probe == nil {
+		return check
+	}
+
+	started := time.Now()
+	err := dependency.Probe(ctx)
+	check.LatencyMs = time.Since(started).Milliseconds()
+	check.Status = CheckOK
+	if err != nil {
+		check.Status = CheckError
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// ServeHTTP answers the report, with 503 when the backend is not ready.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+
+	status := http.StatusOK
+	if report.Status == StatusNotReady {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+)
+
+// SMTP probes a mail server at addr (host:port): it connects, waits for the
+// greeting and says goodbye without sending anything.
+func SMTP(addr string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		client, err := smtp.NewClient(conn, host)
+		if err != nil {
+			return err
+		}
+		return client.Quit()
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -25,17 +27,21 @@ func NewHandler(repo *Repository, authRepo *auth.Repository) *Handler {
 }
 
 type createNodeRequest struct {
-	Title    string  `json:"title"`
-	Type     string  `json:"type"`
-	ParentID *string `json:"parent_id"`
-	Position *int    `json:"position"`
+	Title        string  `json:"title"`
+	Type         string  `json:"type"`
+	ParentID     *string `json:"parent_id"`
+	Position     *int    `json:"position"`
+	RoleTitle    *string `json:"role_title"`
+	IsVacancy    bool    `json:"is_vacancy"`
+	HiringStatus *string `json:"hiring_status"`
 }
 
 type updateNodeRequest struct {
-	Title     *string `json:"title"`
-	ParentID  *string `json:"parent_id"`
-	Position  *int    `json:"position"`
-	RoleTitle *string `json:"role_title"`
+	Title        *string `json:"title"`
+	ParentID     *string `json:"parent_id"`
+	Position     *int    `json:"position"`
+	RoleTitle    *string `json:"role_title"`
+	HiringStatus *string `json:"hiring_status"`
 }
 
 type assignUserRequest struct {
@@ -44,10 +50,12 @@ type assignUserRequest struct {
 }
 
 type permissionsResponse struct {
-	CanEdit       bool `json:"can_edit"`
-	CanAddRole    bool `json:"can_add_role"`
-	CanAddDept    bool `json:"can_add_department"`
-	CanAssignUser bool `json:"can_assign_user"`
+	CanEdit            bool `json:"can_edit"`
+	CanAddRole         bool `json:"can_add_role"`
+	CanAddDept         bool `json:"can_add_department"`
+	CanAssignUser      bool `json:"can_assign_user"`
+	CanViewAllProjects bool `json:"can_view_all_projects"`
+	CanApproveExpenses bool `json:"can_approve_expenses"`
 }
 
 type treeResponse struct {
@@ -57,6 +65,11 @@ type treeResponse struct {
 	Tree          []*TreeNode         `json:"tree"`
 }
 
+type previewResponse struct {
+	DryRun        bool           `json:"dry_run"`
+	AffectedUsers []AffectedUser `json:"affected_users"`
+}
+
 type catalogsResponse struct {
 	Departments []CatalogItem `json:"departments"`
 	Roles       []CatalogItem `json:"roles"`
@@ -66,38 +79,46 @@ func (h *Handler) GetTree(w http.ResponseWriter, r *http.Request) {
 	user, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "user not found"})
+			problem.Write(w, http.StatusUnauthorized, "user_not_found", "user not found")
 			return
 		}
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	nodes, err := h.repo.ListNodes(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load hierarchy tree"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load hierarchy tree")
 		return
 	}
 
 	departments, err := h.repo.ListDepartmentCatalog(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load hierarchy departments"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load hierarchy departments")
 		return
 	}
 
 	roles, err := h.repo.ListRoleCatalog(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load hierarchy roles"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load hierarchy roles")
+		return
+	}
+
+	rolePermissions, err := h.repo.GetUserPermissions(r.Context(), user.ID)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to load hierarchy permissions")
 		return
 	}
 
 	tree := buildTree(nodes)
 	writeJSON(w, http.StatusOK, treeResponse{
 		Permissions: permissionsResponse{
-			CanEdit:       canManage,
-			CanAddRole:    canManage,
-			CanAddDept:    canManage,
-			CanAssignUser: canManage,
+			CanEdit:            canManage,
+			CanAddRole:         canManage,
+			CanAddDept:         canManage,
+			CanAssignUser:      canManage,
+			CanViewAllProjects: rolePermissions.CanViewAllProjects,
+			CanApproveExpenses: rolePermissions.CanApproveExpenses,
 		},
 		CurrentUserID: user.ID.String(),
 		Catalogs: catalogsResponse{
@@ -111,51 +132,69 @@ func (h *Handler) GetTree(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AssignUser(w http.ResponseWriter, r *http.Request) {
 	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 	if !canManage {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	var req assignUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.NodeID == nil || strings.TrimSpace(*req.NodeID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "node_id is required"})
+		problem.Write(w, http.StatusBadRequest, "node_id_required", "node_id is required")
 		return
 	}
 	if req.UserID == nil || strings.TrimSpace(*req.UserID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "user_id is required")
 		return
 	}
 
 	nodeID, err := uuid.Parse(strings.TrimSpace(*req.NodeID))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid node_id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_node_id", "invalid node_id")
 		return
 	}
 	userID, err := uuid.Parse(strings.TrimSpace(*req.UserID))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user_id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user_id")
+		return
+	}
+
+	if isDryRun(r) {
+		affected, previewErr := h.repo.PreviewAssignUser(r.Context(), nodeID, userID)
+		if previewErr != nil {
+			if errors.Is(previewErr, sql.ErrNoRows) {
+				problem.Write(w, http.StatusBadRequest, "node_or_user_not_found", "node or user not found")
+				return
+			}
+			if strings.Contains(strings.ToLower(previewErr.Error()), "cannot") {
+				problem.Error(w, http.StatusBadRequest, previewErr.Error())
+				return
+			}
+			problem.Error(w, http.StatusInternalServerError, "failed to preview assignment")
+			return
+		}
+		writeJSON(w, http.StatusOK, previewResponse{DryRun: true, AffectedUsers: affected})
 		return
 	}
 
 	node, err := h.repo.AssignUserToNode(r.Context(), nodeID, userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "node or user not found"})
+			problem.Write(w, http.StatusBadRequest, "node_or_user_not_found", "node or user not found")
 			return
 		}
 		if strings.Contains(strings.ToLower(err.Error()), "cannot") {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			problem.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to assign user"})
+		problem.Error(w, http.StatusInternalServerError, "failed to assign user")
 		return
 	}
 
@@ -165,58 +204,84 @@ func (h *Handler) AssignUser(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateNode(w http.ResponseWriter, r *http.Request) {
 	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 	if !canManage {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	var req createNodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	title := strings.TrimSpace(req.Title)
+	if title == "" && req.IsVacancy && req.RoleTitle != nil {
+		title = strings.TrimSpace(*req.RoleTitle)
+	}
 	if title == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is required"})
+		problem.Write(w, http.StatusBadRequest, "title_required", "title is required")
 		return
 	}
 	if len(title) > 180 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is too long"})
+		problem.Write(w, http.StatusBadRequest, "title_too_long", "title is too long")
 		return
 	}
 
 	typeValue := NodeType(strings.ToLower(strings.TrimSpace(req.Type)))
-	if typeValue != NodeTypeDepartment {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be department"})
+	switch {
+	case typeValue == NodeTypeDepartment && !req.IsVacancy:
+	case typeValue == NodeTypeUser && req.IsVacancy:
+	default:
+		problem.Write(w, http.StatusBadRequest, "invalid_type", "type must be department or a user vacancy")
 		return
 	}
 
+	hiringStatus := ""
+	if req.IsVacancy {
+		if req.RoleTitle == nil || strings.TrimSpace(*req.RoleTitle) == "" {
+			problem.Write(w, http.StatusBadRequest, "role_title_required", "role_title is required for vacancy")
+			return
+		}
+		hiringStatus = HiringStatusOpen
+		if req.HiringStatus != nil {
+			normalized, ok := normalizeHiringStatus(*req.HiringStatus)
+			if !ok {
+				problem.Write(w, http.StatusBadRequest, "invalid_hiring_status", "hiring_status must be open, interviewing, offer, or on_hold")
+				return
+			}
+			hiringStatus = normalized
+		}
+	}
+
 	parentID, err := parseOptionalUUID(req.ParentID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid parent_id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_parent_id", "invalid parent_id")
 		return
 	}
 
 	node, err := h.repo.CreateNode(r.Context(), createNodeInput{
-		Title:    title,
-		Type:     typeValue,
-		ParentID: parentID,
-		Position: req.Position,
+		Title:        title,
+		Type:         typeValue,
+		ParentID:     parentID,
+		Position:     req.Position,
+		RoleTitle:    req.RoleTitle,
+		IsVacancy:    req.IsVacancy,
+		HiringStatus: hiringStatus,
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "parent node not found"})
+			problem.Write(w, http.StatusBadRequest, "parent_node_not_found", "parent node not found")
 			return
 		}
 		if strings.Contains(strings.ToLower(err.Error()), "cannot") {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			problem.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create hierarchy node"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create hierarchy node")
 		return
 	}
 
@@ -226,40 +291,29 @@ func (h *Handler) CreateNode(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 	if !canManage {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	nodeID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid node id"})
-		return
-	}
-
-	bodyBytes, err := ioReadAll(r)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		problem.Write(w, http.StatusBadRequest, "invalid_node_id", "invalid node id")
 		return
 	}
 
 	var req updateNodeRequest
-	if err := json.Unmarshal(bodyBytes, &req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
-		return
-	}
-
-	var rawFields map[string]json.RawMessage
-	if err := json.Unmarshal(bodyBytes, &rawFields); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	fields, err := request.DecodeFields(r, &req)
+	if err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	var title *string
-	if _, ok := rawFields["title"]; ok {
+	if fields.Has("title") {
 		if req.Title == nil {
 			empty := ""
 			title = &empty
@@ -271,51 +325,82 @@ func (h *Handler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 
 	parentSet := false
 	var parentID *uuid.UUID
-	if _, ok := rawFields["parent_id"]; ok {
+	if fields.Has("parent_id") {
 		parentSet = true
 		parentID, err = parseOptionalUUID(req.ParentID)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid parent_id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_parent_id", "invalid parent_id")
 			return
 		}
 	}
 
 	if title != nil {
 		if strings.TrimSpace(*title) == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is required"})
+			problem.Write(w, http.StatusBadRequest, "title_required", "title is required")
 			return
 		}
 		if len(*title) > 180 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is too long"})
+			problem.Write(w, http.StatusBadRequest, "title_too_long", "title is too long")
 			return
 		}
 	}
 
 	roleSet := false
 	var roleTitle *string
-	if _, ok := rawFields["role_title"]; ok {
+	if fields.Has("role_title") {
 		roleSet = true
 		roleTitle = req.RoleTitle
 	}
 
-	node, err := h.repo.UpdateNode(r.Context(), nodeID, updateNodeInput{
-		Title:     title,
-		ParentSet: parentSet,
-		ParentID:  parentID,
-		Position:  req.Position,
-		RoleTitle: roleTitle,
-		RoleSet:   roleSet,
-	})
+	var hiringStatus *string
+	if req.HiringStatus != nil {
+		normalized, ok := normalizeHiringStatus(*req.HiringStatus)
+		if !ok {
+			problem.Write(w, http.StatusBadRequest, "invalid_hiring_status", "hiring_status must be open, interviewing, offer, or on_hold")
+			return
+		}
+		hiringStatus = &normalized
+	}
+
+	input := updateNodeInput{
+		Title:        title,
+		ParentSet:    parentSet,
+		ParentID:     parentID,
+		Position:     req.Position,
+		RoleTitle:    roleTitle,
+		RoleSet:      roleSet,
+		HiringStatus: hiringStatus,
+	}
+
+	if isDryRun(r) {
+		affected, previewErr := h.repo.PreviewUpdateNode(r.Context(), nodeID, input)
+		if previewErr != nil {
+			if errors.Is(previewErr, sql.ErrNoRows) {
+				problem.Write(w, http.StatusNotFound, "node_not_found", "node not found")
+				return
+			}
+			if strings.Contains(strings.ToLower(previewErr.Error()), "cannot") {
+				problem.Error(w, http.StatusBadRequest, previewErr.Error())
+				return
+			}
+			problem.Error(w, http.StatusInternalServerError, "failed to preview node update")
+			return
+		}
+		writeJSON(w, http.StatusOK, previewResponse{DryRun: true, AffectedUsers: affected})
+		return
+	}
+
+	node, err := h.repo.UpdateNode(r.Context(), nodeID, input)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "node not found"})
+			problem.Write(w, http.StatusNotFound, "node_not_found", "node not found")
 			return
 		}
-		if strings.Contains(strings.ToLower(err.Error()), "subtree") {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if strings.Contains(strings.ToLower(err.Error()), "cannot") {
+			problem.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update node"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update node")
 		return
 	}
 
@@ -325,30 +410,30 @@ func (h *Handler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteNode(w http.ResponseWriter, r *http.Request) {
 	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 	if !canManage {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	nodeID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid node id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_node_id", "invalid node id")
 		return
 	}
 
 	if err := h.repo.DeleteNode(r.Context(), nodeID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "node not found"})
+			problem.Write(w, http.StatusNotFound, "node_not_found", "node not found")
 			return
 		}
 		if strings.Contains(strings.ToLower(err.Error()), "cannot") {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			problem.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete node"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete node")
 		return
 	}
 
@@ -362,40 +447,307 @@ type updateStatusRequest struct {
 func (h *Handler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 	if !canManage {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 		return
 	}
 
 	nodeID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid node id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_node_id", "invalid node id")
 		return
 	}
 
 	var req updateStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	status := strings.ToLower(strings.TrimSpace(req.Status))
 	if status != "free" && status != "busy" && status != "sick" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be free, busy, or sick"})
+		problem.Write(w, http.StatusBadRequest, "invalid_status", "status must be free, busy, or sick")
 		return
 	}
 
 	if err := h.repo.UpdateStatus(r.Context(), nodeID, status); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update status"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update status")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": status})
 }
 
+type setDepartmentHeadRequest struct {
+	IsHead *bool `json:"is_department_head" validate:"required"`
+}
+
+func (h *Handler) SetDepartmentHead(w http.ResponseWriter, r *http.Request) {
+	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !canManage {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return
+	}
+
+	nodeID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_node_id", "invalid node id")
+		return
+	}
+
+	var req setDepartmentHeadRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	node, err := h.repo.SetDepartmentHead(r.Context(), nodeID, *req.IsHead)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "node_not_found", "node not found")
+			return
+		}
+		if strings.Contains(strings.ToLower(err.Error()), "cannot") {
+			problem.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to update department head")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapDBNode(node))
+}
+
+func (h *Handler) GetWorkload(w http.ResponseWriter, r *http.Request) {
+	currentUser, _, err := h.resolveCurrentUserAndPermission(r.Context())
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	isHead, err := h.repo.IsDepartmentHead(r.Context(), currentUser.ID)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to load workload")
+		return
+	}
+	if !isHead {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return
+	}
+
+	items, err := h.repo.ListHeadWorkload(r.Context(), currentUser.ID)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to load workload")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) GetManagementChain(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := h.resolveCurrentUserAndPermission(r.Context()); err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
+		return
+	}
+
+	chain, err := h.repo.GetManagementChain(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "user_is_not_placed_in_hierarchy", "user is not placed in hierarchy")
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to load management chain")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, chain)
+}
+
+const maxReorderItems = 500
+
+type reorderItemRequest struct {
+	NodeID   string  `json:"node_id" validate:"required,uuid"`
+	ParentID *string `json:"parent_id"`
+	Position *int    `json:"position" validate:"required"`
+}
+
+type reorderRequest struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+func (h *Handler) ReorderNodes(w http.ResponseWriter, r *http.Request) {
+	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !canManage {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return
+	}
+
+	bodyBytes, err := ioReadAll(r)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidPayload, "invalid payload")
+		return
+	}
+
+	// Accept both {"items": [...]} and a bare array.
+	var req reorderRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		if arrErr := json.Unmarshal(bodyBytes, &req.Items); arrErr != nil {
+			problem.Write(w, http.StatusBadRequest, problem.CodeInvalidPayload, "invalid payload")
+			return
+		}
+	}
+
+	if len(req.Items) == 0 {
+		problem.Write(w, http.StatusBadRequest, "items_required", "items are required")
+		return
+	}
+	if len(req.Items) > maxReorderItems {
+		problem.Write(w, http.StatusBadRequest, "too_many_items", "too many items")
+		return
+	}
+
+	items := make([]reorderNodeInput, 0, len(req.Items))
+	seen := make(map[uuid.UUID]struct{}, len(req.Items))
+	for _, raw := range req.Items {
+		var item reorderItemRequest
+		fields, err := request.UnmarshalFields(raw, &item)
+		if err != nil {
+			request.WriteError(w, err)
+			return
+		}
+
+		nodeID := uuid.MustParse(strings.TrimSpace(item.NodeID))
+		if _, dup := seen[nodeID]; dup {
+			problem.Write(w, http.StatusBadRequest, "duplicate_node", "cannot reorder the same node twice")
+			return
+		}
+		seen[nodeID] = struct{}{}
+
+		input := reorderNodeInput{NodeID: nodeID, Position: *item.Position}
+		if fields.Has("parent_id") {
+			parentID, err := parseOptionalUUID(item.ParentID)
+			if err != nil {
+				problem.Write(w, http.StatusBadRequest, "invalid_parent_id", "invalid parent_id")
+				return
+			}
+			input.ParentSet = true
+			input.ParentID = parentID
+		}
+
+		items = append(items, input)
+	}
+
+	if err := h.repo.ReorderNodes(r.Context(), items); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "node_not_found", "node not found")
+			return
+		}
+		if strings.Contains(strings.ToLower(err.Error()), "cannot") {
+			problem.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to reorder nodes")
+		return
+	}
+
+	nodes, err := h.repo.ListNodes(r.Context())
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to load hierarchy tree")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"tree": buildTree(nodes)})
+}
+
+type updateRolePermissionsRequest struct {
+	CanManageHierarchy *bool `json:"can_manage_hierarchy"`
+	CanViewAllProjects *bool `json:"can_view_all_projects"`
+	CanApproveExpenses *bool `json:"can_approve_expenses"`
+}
+
+func (h *Handler) UpdateRolePermissions(w http.ResponseWriter, r *http.Request) {
+	_, canManage, err := h.resolveCurrentUserAndPermission(r.Context())
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !canManage {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return
+	}
+
+	roleID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_role_id", "invalid role id")
+		return
+	}
+
+	var req updateRolePermissionsRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	current, err := h.findRoleCatalogItem(r.Context(), roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "role_not_found", "role not found")
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to load role")
+		return
+	}
+
+	permissions := *current.Permissions
+	if req.CanManageHierarchy != nil {
+		permissions.CanManageHierarchy = *req.CanManageHierarchy
+	}
+	if req.CanViewAllProjects != nil {
+		permissions.CanViewAllProjects = *req.CanViewAllProjects
+	}
+	if req.CanApproveExpenses != nil {
+		permissions.CanApproveExpenses = *req.CanApproveExpenses
+	}
+
+	item, err := h.repo.UpdateRolePermissions(r.Context(), roleID, permissions)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to update role permissions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (h *Handler) findRoleCatalogItem(ctx context.Context, id uuid.UUID) (CatalogItem, error) {
+	roles, err := h.repo.ListRoleCatalog(ctx)
+	if err != nil {
+		return CatalogItem{}, err
+	}
+	for _, role := range roles {
+		if role.ID == id {
+			return role, nil
+		}
+	}
+	return CatalogItem{}, sql.ErrNoRows
+}
+
 func buildTree(nodes []dbNode) []*TreeNode {
 	mapped := make(map[uuid.UUID]*TreeNode, len(nodes))
 	for _, item := range nodes {
@@ -422,16 +774,23 @@ func buildTree(nodes []dbNode) []*TreeNode {
 
 func mapDBNode(item dbNode) *TreeNode {
 	node := &TreeNode{
-		ID:       item.ID,
-		Title:    item.Title,
-		Type:     item.Type,
-		ParentID: item.ParentID,
-		UserID:   item.UserID,
-		Position: item.Position,
-		Level:    item.Level,
-		Path:     item.Path,
-		Status:   item.Status,
-		Children: []*TreeNode{},
+		ID:        item.ID,
+		Title:     item.Title,
+		Type:      item.Type,
+		ParentID:  item.ParentID,
+		UserID:    item.UserID,
+		Position:  item.Position,
+		Level:     item.Level,
+		Path:      item.Path,
+		Status:    item.Status,
+		IsHead:    item.IsHead,
+		IsVacancy: item.IsVacancy,
+		Children:  []*TreeNode{},
+	}
+
+	if item.HiringStatus.Valid {
+		hiring := item.HiringStatus.String
+		node.Hiring = &hiring
 	}
 
 	if item.RoleTitle.Valid {
@@ -486,7 +845,19 @@ func (h *Handler) resolveCurrentUserAndPermission(ctx context.Context) (auth.Use
 		return auth.User{}, false, err
 	}
 
-	if hasManageAccess(user) {
+	isAdmin, err := h.repo.IsWorkspaceAdmin(ctx, user.ID)
+	if err != nil {
+		return auth.User{}, false, err
+	}
+	if isAdmin {
+		return user, true, nil
+	}
+
+	rolePermissions, err := h.repo.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		return auth.User{}, false, err
+	}
+	if rolePermissions.CanManageHierarchy {
 		return user, true, nil
 	}
 
@@ -515,22 +886,20 @@ func (h *Handler) resolveCurrentUserAndPermission(ctx context.Context) (auth.Use
 	return user, false, nil
 }
 
-func hasManageAccess(user auth.User) bool {
-	if user.Role != nil {
-		normalizedRole := strings.ToLower(strings.TrimSpace(*user.Role))
-		switch normalizedRole {
-		case "owner", "ceo", "hr", "hr manager", "hr_manager", "human resources", "hr specialist", "hr_specialist":
-			return true
-		}
+func normalizeHiringStatus(value string) (string, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	switch normalized {
+	case HiringStatusOpen, HiringStatusInterviewing, HiringStatusOffer, HiringStatusOnHold:
+		return normalized, true
 	}
+	return "", false
+}
 
-	if user.DepartmentName != nil {
-		normalizedDepartment := strings.ToLower(strings.TrimSpace(*user.DepartmentName))
-		if strings.Contains(normalizedDepartment, "hr") || strings.Contains(normalizedDepartment, "human resources") || strings.Contains(normalizedDepartment, "кадр") {
-			return true
-		}
+func isDryRun(r *http.Request) bool {
+	switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("dry_run"))) {
+	case "1", "true", "yes":
+		return true
 	}
-
 	return false
 }
 
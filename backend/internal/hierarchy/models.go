@@ -30,28 +30,103 @@ type TreeNode struct {
 	Path      string      `json:"path"`
 	Status    string      `json:"status"`
 	RoleTitle *string     `json:"role_title,omitempty"`
+	IsHead    bool        `json:"is_department_head"`
+	IsVacancy bool        `json:"is_vacancy"`
+	Hiring    *string     `json:"hiring_status,omitempty"`
 	User      *TreeUser   `json:"user,omitempty"`
 	Children  []*TreeNode `json:"children"`
 }
 
 type CatalogItem struct {
-	ID       uuid.UUID `json:"id"`
-	Name     string    `json:"name"`
-	IsSystem bool      `json:"is_system"`
+	ID          uuid.UUID        `json:"id"`
+	Name        string           `json:"name"`
+	IsSystem    bool             `json:"is_system"`
+	Permissions *RolePermissions `json:"permissions,omitempty"`
 }
 
-type createNodeInput struct {
-	Title    string
-	Type     NodeType
-	ParentID *uuid.UUID
-	Position *int
+// RolePermissions are the platform capabilities attached to a role catalog
+// entry. A user gets the union of permissions of every role they hold.
+type RolePermissions struct {
+	CanManageHierarchy bool `json:"can_manage_hierarchy"`
+	CanViewAllProjects bool `json:"can_view_all_projects"`
+	CanApproveExpenses bool `json:"can_approve_expenses"`
 }
 
-type updateNodeInput struct {
-	Title     *string
+type WorkloadItem struct {
+	UserID            uuid.UUID `json:"user_id"`
+	Email             string    `json:"email"`
+	FullName          *string   `json:"full_name,omitempty"`
+	NodeID            uuid.UUID `json:"node_id"`
+	Status            string    `json:"status"`
+	RoleTitle         *string   `json:"role_title,omitempty"`
+	ProjectsCount     int       `json:"projects_count"`
+	OpenTasksCount    int       `json:"open_tasks_count"`
+	OverdueTasksCount int       `json:"overdue_tasks_count"`
+}
+
+// ChainMember is one step of a user's management chain. Relation is
+// "department_head" for heads of enclosing departments and "ceo" for the
+// company root user.
+type ChainMember struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	FullName  *string   `json:"full_name,omitempty"`
+	AvatarURL *string   `json:"avatar_url,omitempty"`
+	NodeID    uuid.UUID `json:"node_id"`
+	Scope     string    `json:"scope"`
+	RoleTitle *string   `json:"role_title,omitempty"`
+	Relation  string    `json:"relation"`
+	Level     int       `json:"level"`
+}
+
+// AffectedUser describes how a hierarchy change would re-route a user.
+type AffectedUser struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	Email    string        `json:"email"`
+	FullName *string       `json:"full_name,omitempty"`
+	NodeID   uuid.UUID     `json:"node_id"`
+	Before   UserPlacement `json:"before"`
+	After    UserPlacement `json:"after"`
+}
+
+type UserPlacement struct {
+	ManagerID      *uuid.UUID `json:"manager_id,omitempty"`
+	ManagerName    *string    `json:"manager_name,omitempty"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	DepartmentName *string    `json:"department_name,omitempty"`
+}
+
+type reorderNodeInput struct {
+	NodeID    uuid.UUID
 	ParentSet bool
 	ParentID  *uuid.UUID
-	Position  *int
-	RoleTitle *string
-	RoleSet   bool
+	Position  int
+}
+
+// Hiring statuses of a vacancy node.
+const (
+	HiringStatusOpen         = "open"
+	HiringStatusInterviewing = "interviewing"
+	HiringStatusOffer        = "offer"
+	HiringStatusOnHold       = "on_hold"
+)
+
+type createNodeInput struct {
+	Title        string
+	Type         NodeType
+	ParentID     *uuid.UUID
+	Position     *int
+	RoleTitle    *string
+	IsVacancy    bool
+	HiringStatus string
+}
+
+type updateNodeInput struct {
+	Title        *string
+	ParentSet    bool
+	ParentID     *uuid.UUID
+	Position     *int
+	RoleTitle    *string
+	RoleSet      bool
+	HiringStatus *string
 }
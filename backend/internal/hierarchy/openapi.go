@@ -0,0 +1,28 @@
+package hierarchy
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+var dryRunParam = openapi.Param{Name: "dry_run", Kind: "boolean", Description: "Only list the users the change would affect"}
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/hierarchy/tree", Tag: "hierarchy", Summary: "Get the org tree with catalogs and the permissions of the caller", Response: treeResponse{}},
+	{Method: http.MethodPatch, Path: "/hierarchy/assign-user", Tag: "hierarchy", Summary: "Assign a user to a node, or unassign it", Query: []openapi.Param{dryRunParam}, Body: assignUserRequest{}, Response: TreeNode{}},
+	{Method: http.MethodPost, Path: "/hierarchy/nodes", Tag: "hierarchy", Summary: "Create a node", Body: createNodeRequest{}, Status: http.StatusCreated, Response: TreeNode{}},
+	{Method: http.MethodPost, Path: "/hierarchy/nodes/reorder", Tag: "hierarchy", Summary: "Move and reorder nodes in one change", Body: struct {
+		Items []reorderItemRequest `json:"items"`
+	}{}, Response: struct {
+		Tree []*TreeNode `json:"tree"`
+	}{}},
+	{Method: http.MethodPatch, Path: "/hierarchy/nodes/{id}", Tag: "hierarchy", Summary: "Update a node", Query: []openapi.Param{dryRunParam}, Body: updateNodeRequest{}, Response: TreeNode{}},
+	{Method: http.MethodDelete, Path: "/hierarchy/nodes/{id}", Tag: "hierarchy", Summary: "Delete a node", Response: openapi.Status{}},
+	{Method: http.MethodPatch, Path: "/hierarchy/nodes/{id}/status", Tag: "hierarchy", Summary: "Set the hiring status of a vacancy", Body: updateStatusRequest{}, Response: openapi.Status{}},
+	{Method: http.MethodPatch, Path: "/hierarchy/nodes/{id}/head", Tag: "hierarchy", Summary: "Mark a node as the head of its department", Body: setDepartmentHeadRequest{}, Response: TreeNode{}},
+	{Method: http.MethodGet, Path: "/hierarchy/workload", Tag: "hierarchy", Summary: "Get the task workload of the department of the caller", Response: WorkloadItem{}, List: true},
+	{Method: http.MethodGet, Path: "/users/{id}/management-chain", Tag: "hierarchy", Summary: "List the managers of a user up to the root", Response: ChainMember{}, List: true},
+	{Method: http.MethodPatch, Path: "/hierarchy/roles/{id}/permissions", Tag: "hierarchy", Summary: "Update the permissions of a role", Body: updateRolePermissionsRequest{}, Response: CatalogItem{}},
+}
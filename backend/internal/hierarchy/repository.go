@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"strings"
 
+	"tm-platform-backend/internal/db"
+
 	"github.com/google/uuid"
 )
 
@@ -15,16 +17,19 @@ type Repository struct {
 }
 
 type dbNode struct {
-	ID        uuid.UUID
-	Title     string
-	Type      NodeType
-	ParentID  *uuid.UUID
-	UserID    *uuid.UUID
-	Position  int
-	Level     int
-	Path      string
-	Status    string
-	RoleTitle sql.NullString
+	ID           uuid.UUID
+	Title        string
+	Type         NodeType
+	ParentID     *uuid.UUID
+	UserID       *uuid.UUID
+	Position     int
+	Level        int
+	Path         string
+	Status       string
+	RoleTitle    sql.NullString
+	IsHead       bool
+	IsVacancy    bool
+	HiringStatus sql.NullString
 
 	UserEmail     sql.NullString
 	UserFullName  sql.NullString
@@ -50,6 +55,9 @@ func (r *Repository) ListNodes(ctx context.Context) ([]dbNode, error) {
 			n.path,
 			n.status,
 			n.role_title,
+			n.is_department_head,
+			n.is_vacancy,
+			n.hiring_status,
 			u.email,
 			u.full_name,
 			u.avatar_url,
@@ -57,7 +65,8 @@ func (r *Repository) ListNodes(ctx context.Context) ([]dbNode, error) {
 			u.manager_id
 		FROM hierarchy_nodes n
 		LEFT JOIN users u ON u.id = n.user_id
-		ORDER BY n.level ASC, n.path ASC, n.position ASC, n.title ASC`)
+		WHERE n.workspace_id = $1
+		ORDER BY n.level ASC, n.path ASC, n.position ASC, n.title ASC`, db.Workspace(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +86,9 @@ func (r *Repository) ListNodes(ctx context.Context) ([]dbNode, error) {
 			&item.Path,
 			&item.Status,
 			&item.RoleTitle,
+			&item.IsHead,
+			&item.IsVacancy,
+			&item.HiringStatus,
 			&item.UserEmail,
 			&item.UserFullName,
 			&item.UserAvatarURL,
@@ -108,6 +120,9 @@ func (r *Repository) GetNodeByID(ctx context.Context, id uuid.UUID) (dbNode, err
 			n.path,
 			n.status,
 			n.role_title,
+			n.is_department_head,
+			n.is_vacancy,
+			n.hiring_status,
 			u.email,
 			u.full_name,
 			u.avatar_url,
@@ -115,7 +130,7 @@ func (r *Repository) GetNodeByID(ctx context.Context, id uuid.UUID) (dbNode, err
 			u.manager_id
 		FROM hierarchy_nodes n
 		LEFT JOIN users u ON u.id = n.user_id
-		WHERE n.id = $1`, id)
+		WHERE n.id = $1 AND n.workspace_id = $2`, id, db.Workspace(ctx))
 
 	var item dbNode
 	err := row.Scan(
@@ -129,6 +144,9 @@ func (r *Repository) GetNodeByID(ctx context.Context, id uuid.UUID) (dbNode, err
 		&item.Path,
 		&item.Status,
 		&item.RoleTitle,
+		&item.IsHead,
+		&item.IsVacancy,
+		&item.HiringStatus,
 		&item.UserEmail,
 		&item.UserFullName,
 		&item.UserAvatarURL,
@@ -154,7 +172,7 @@ func (r *Repository) CreateNode(ctx context.Context, input createNodeInput) (dbN
 	if input.ParentID != nil {
 		var parentLevel int
 		var parentType NodeType
-		if scanErr := tx.QueryRowContext(ctx, `SELECT level, path, type FROM hierarchy_nodes WHERE id = $1`, *input.ParentID).Scan(&parentLevel, &pathPrefix, &parentType); scanErr != nil {
+		if scanErr := tx.QueryRowContext(ctx, `SELECT level, path, type FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, *input.ParentID, db.Workspace(ctx)).Scan(&parentLevel, &pathPrefix, &parentType); scanErr != nil {
 			err = scanErr
 			return dbNode{}, err
 		}
@@ -163,6 +181,9 @@ func (r *Repository) CreateNode(ctx context.Context, input createNodeInput) (dbN
 			return dbNode{}, err
 		}
 		level = parentLevel + 1
+	} else if input.IsVacancy {
+		err = errors.New("cannot create vacancy without parent node")
+		return dbNode{}, err
 	}
 
 	position := 0
@@ -170,11 +191,25 @@ func (r *Repository) CreateNode(ctx context.Context, input createNodeInput) (dbN
 		position = *input.Position
 	}
 
+	var roleTitle sql.NullString
+	if input.RoleTitle != nil && strings.TrimSpace(*input.RoleTitle) != "" {
+		roleTitle = sql.NullString{String: strings.TrimSpace(*input.RoleTitle), Valid: true}
+		if _, catalogErr := ensureRoleCatalogEntryTx(ctx, tx, roleTitle.String); catalogErr != nil {
+			err = catalogErr
+			return dbNode{}, err
+		}
+	}
+
+	var hiringStatus sql.NullString
+	if input.IsVacancy {
+		hiringStatus = sql.NullString{String: input.HiringStatus, Valid: true}
+	}
+
 	var id uuid.UUID
 	insertErr := tx.QueryRowContext(ctx, `
-		INSERT INTO hierarchy_nodes (title, type, parent_id, user_id, position, level, path)
-		VALUES ($1, $2, $3, NULL, $4, $5, '')
-		RETURNING id`, input.Title, input.Type, input.ParentID, position, level).Scan(&id)
+		INSERT INTO hierarchy_nodes (title, type, parent_id, user_id, position, level, path, role_title, is_vacancy, hiring_status, workspace_id)
+		VALUES ($1, $2, $3, NULL, $4, $5, '', $6, $7, $8, $9)
+		RETURNING id`, input.Title, input.Type, input.ParentID, position, level, roleTitle, input.IsVacancy, hiringStatus, db.Workspace(ctx)).Scan(&id)
 	if insertErr != nil {
 		err = insertErr
 		return dbNode{}, err
@@ -191,8 +226,8 @@ func (r *Repository) CreateNode(ctx context.Context, input createNodeInput) (dbN
 	}
 
 	if input.Type == NodeTypeDepartment {
-		if _, catalogErr := ensureDepartmentCatalogEntryTx(ctx, tx, input.Title); catalogErr != nil {
-			err = catalogErr
+		if _, syncErr := syncDepartmentForNodeTx(ctx, tx, id, input.Title, newPath); syncErr != nil {
+			err = syncErr
 			return dbNode{}, err
 		}
 	}
@@ -216,22 +251,36 @@ func (r *Repository) UpdateNode(ctx context.Context, id uuid.UUID, input updateN
 		}
 	}()
 
+	if err = updateNodeTx(ctx, tx, id, input); err != nil {
+		return dbNode{}, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return dbNode{}, err
+	}
+
+	return r.GetNodeByID(ctx, id)
+}
+
+func updateNodeTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, input updateNodeInput) error {
 	var currentTitle string
 	var currentType NodeType
 	var currentParentID *uuid.UUID
 	var currentPosition int
 	var currentLevel int
 	var currentPath string
-	if scanErr := tx.QueryRowContext(ctx, `SELECT title, type, parent_id, position, level, path FROM hierarchy_nodes WHERE id = $1`, id).Scan(
+	var currentIsVacancy bool
+	if scanErr := tx.QueryRowContext(ctx, `SELECT title, type, parent_id, position, level, path, is_vacancy FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, id, db.Workspace(ctx)).Scan(
 		&currentTitle,
 		&currentType,
 		&currentParentID,
 		&currentPosition,
 		&currentLevel,
 		&currentPath,
+		&currentIsVacancy,
 	); scanErr != nil {
-		err = scanErr
-		return dbNode{}, err
+		return scanErr
 	}
 
 	newTitle := currentTitle
@@ -255,14 +304,12 @@ func (r *Repository) UpdateNode(ctx context.Context, id uuid.UUID, input updateN
 		parentPath := ""
 		parentLevel := -1
 		if newParentID != nil {
-			if scanErr := tx.QueryRowContext(ctx, `SELECT path, level FROM hierarchy_nodes WHERE id = $1`, *newParentID).Scan(&parentPath, &parentLevel); scanErr != nil {
-				err = scanErr
-				return dbNode{}, err
+			if scanErr := tx.QueryRowContext(ctx, `SELECT path, level FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, *newParentID, db.Workspace(ctx)).Scan(&parentPath, &parentLevel); scanErr != nil {
+				return scanErr
 			}
 
 			if parentPath == currentPath || strings.HasPrefix(parentPath, currentPath+".") {
-				err = errors.New("cannot move node into its own subtree")
-				return dbNode{}, err
+				return errors.New("cannot move node into its own subtree")
 			}
 		}
 
@@ -280,20 +327,20 @@ func (r *Repository) UpdateNode(ctx context.Context, id uuid.UUID, input updateN
 		if input.RoleTitle != nil && strings.TrimSpace(*input.RoleTitle) != "" {
 			newRoleTitle = sql.NullString{String: strings.TrimSpace(*input.RoleTitle), Valid: true}
 			if _, catalogErr := ensureRoleCatalogEntryTx(ctx, tx, newRoleTitle.String); catalogErr != nil {
-				err = catalogErr
-				return dbNode{}, err
+				return catalogErr
 			}
 		}
 		if _, execErr := tx.ExecContext(ctx, `UPDATE hierarchy_nodes SET role_title = $2 WHERE id = $1`, id, newRoleTitle); execErr != nil {
-			err = execErr
-			return dbNode{}, err
+			return execErr
 		}
 	}
 
-	if currentType == NodeTypeDepartment && input.Title != nil {
-		if _, catalogErr := ensureDepartmentCatalogEntryTx(ctx, tx, newTitle); catalogErr != nil {
-			err = catalogErr
-			return dbNode{}, err
+	if input.HiringStatus != nil {
+		if !currentIsVacancy {
+			return errors.New("cannot set hiring status on a filled position")
+		}
+		if _, execErr := tx.ExecContext(ctx, `UPDATE hierarchy_nodes SET hiring_status = $2 WHERE id = $1`, id, *input.HiringStatus); execErr != nil {
+			return execErr
 		}
 	}
 
@@ -305,8 +352,7 @@ func (r *Repository) UpdateNode(ctx context.Context, id uuid.UUID, input updateN
 			level = $5,
 			path = $6
 		WHERE id = $1`, id, newTitle, newParentID, newPosition, newLevel, newPath); execErr != nil {
-		err = execErr
-		return dbNode{}, err
+		return execErr
 	}
 
 	if currentPath != newPath || currentLevel != newLevel {
@@ -315,17 +361,341 @@ func (r *Repository) UpdateNode(ctx context.Context, id uuid.UUID, input updateN
 			SET level = $3 + (level - $4),
 				path = $2 || SUBSTRING(path FROM LENGTH($1) + 1)
 			WHERE path LIKE $1 || '.%'`, currentPath, newPath, newLevel, currentLevel); execErr != nil {
-			err = execErr
-			return dbNode{}, err
+			return execErr
+		}
+	}
+
+	// Moving a subtree or renaming a department changes who manages the
+	// users inside it and which department they belong to.
+	if currentPath != newPath || (currentType == NodeTypeDepartment && newTitle != currentTitle) {
+		if syncErr := syncSubtreeDepartmentsTx(ctx, tx, newPath); syncErr != nil {
+			return syncErr
+		}
+		if syncErr := syncSubtreeUsersTx(ctx, tx, newPath); syncErr != nil {
+			return syncErr
+		}
+	}
+
+	return nil
+}
+
+// ReorderNodes applies a batch of parent/position changes in a single
+// transaction, in the order given, so a drag-and-drop reorganization either
+// lands entirely or not at all.
+func (r *Repository) ReorderNodes(ctx context.Context, items []reorderNodeInput) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, item := range items {
+		position := item.Position
+		if err = updateNodeTx(ctx, tx, item.NodeID, updateNodeInput{
+			ParentSet: item.ParentSet,
+			ParentID:  item.ParentID,
+			Position:  &position,
+		}); err != nil {
+			return err
 		}
 	}
 
 	if commitErr := tx.Commit(); commitErr != nil {
 		err = commitErr
-		return dbNode{}, err
+		return err
 	}
 
-	return r.GetNodeByID(ctx, id)
+	return nil
+}
+
+// syncSubtreeDepartmentsTx re-links every department node at or below
+// rootPath to its row in the departments table, picking up renames and the
+// new parent department after a move.
+func syncSubtreeDepartmentsTx(ctx context.Context, tx *sql.Tx, rootPath string) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, title, path
+		FROM hierarchy_nodes
+		WHERE type = 'department'
+		  AND (path = $1 OR path LIKE $1 || '.%')
+		ORDER BY level ASC`, rootPath)
+	if err != nil {
+		return err
+	}
+
+	type departmentNode struct {
+		id    uuid.UUID
+		title string
+		path  string
+	}
+	nodes := make([]departmentNode, 0)
+	for rows.Next() {
+		var item departmentNode
+		if scanErr := rows.Scan(&item.id, &item.title, &item.path); scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		nodes = append(nodes, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range nodes {
+		if _, err := syncDepartmentForNodeTx(ctx, tx, item.id, item.title, item.path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncDepartmentForNodeTx keeps a department node backed by a row of the
+// canonical departments table. A linked row is renamed along with the node;
+// otherwise a row with the same name is adopted or created. The row's parent
+// mirrors the nearest enclosing department node.
+func syncDepartmentForNodeTx(ctx context.Context, tx *sql.Tx, nodeID uuid.UUID, title, path string) (*uuid.UUID, error) {
+	normalized := normalizeCatalogName(title)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var departmentID uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM departments WHERE hierarchy_node_id = $1`, nodeID).Scan(&departmentID)
+	switch {
+	case err == nil:
+		var clashID uuid.UUID
+		clashErr := tx.QueryRowContext(ctx, `
+			SELECT id
+			FROM departments
+			WHERE LOWER(TRIM(name)) = LOWER(TRIM($1))
+			  AND id <> $2
+			  AND workspace_id = $3
+			LIMIT 1`, normalized, departmentID, db.Workspace(ctx)).Scan(&clashID)
+		if clashErr == nil {
+			return nil, errors.New("cannot rename department: name is already used by another department")
+		}
+		if !errors.Is(clashErr, sql.ErrNoRows) {
+			return nil, clashErr
+		}
+		if _, execErr := tx.ExecContext(ctx, `UPDATE departments SET name = $2 WHERE id = $1`, departmentID, normalized); execErr != nil {
+			return nil, execErr
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		adoptedID, adoptErr := ensureDepartmentIDByNameTx(ctx, tx, normalized)
+		if adoptErr != nil {
+			return nil, adoptErr
+		}
+		departmentID = *adoptedID
+		if _, execErr := tx.ExecContext(ctx, `
+			UPDATE departments
+			SET hierarchy_node_id = $2
+			WHERE id = $1
+			  AND hierarchy_node_id IS NULL`, departmentID, nodeID); execErr != nil {
+			return nil, execErr
+		}
+	default:
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE departments
+		SET parent_id = (
+			SELECT pd.id
+			FROM hierarchy_nodes anc
+			JOIN departments pd ON pd.hierarchy_node_id = anc.id
+			WHERE anc.type = 'department'
+			  AND $2 LIKE anc.path || '.%'
+			ORDER BY anc.level DESC
+			LIMIT 1
+		)
+		WHERE id = $1
+		  AND hierarchy_node_id = $3`, departmentID, path, nodeID); err != nil {
+		return nil, err
+	}
+
+	return &departmentID, nil
+}
+
+// syncSubtreeUsersTx recomputes manager_id and department_id of every user
+// placed at or below rootPath from their position in the tree.
+func syncSubtreeUsersTx(ctx context.Context, tx *sql.Tx, rootPath string) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT user_id, path
+		FROM hierarchy_nodes
+		WHERE type = 'user'
+		  AND user_id IS NOT NULL
+		  AND (path = $1 OR path LIKE $1 || '.%')`, rootPath)
+	if err != nil {
+		return err
+	}
+
+	type placedUser struct {
+		userID uuid.UUID
+		path   string
+	}
+	placed := make([]placedUser, 0)
+	for rows.Next() {
+		var item placedUser
+		if scanErr := rows.Scan(&item.userID, &item.path); scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		placed = append(placed, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range placed {
+		parentPath := ""
+		if idx := strings.LastIndex(item.path, "."); idx > 0 {
+			parentPath = item.path[:idx]
+		}
+
+		managerID, err := resolveNearestManagerIDTx(ctx, tx, parentPath)
+		if err != nil {
+			return err
+		}
+		departmentID, err := resolveNearestDepartmentIDTx(ctx, tx, parentPath)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users
+			SET manager_id = $2,
+				department_id = $3
+			WHERE id = $1`, item.userID, managerID, departmentID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PreviewUpdateNode runs UpdateNode inside a transaction that is always rolled
+// back and reports the users whose manager or department would change.
+func (r *Repository) PreviewUpdateNode(ctx context.Context, id uuid.UUID, input updateNodeInput) ([]AffectedUser, error) {
+	return r.preview(ctx, func(tx *sql.Tx) (uuid.UUID, error) {
+		return id, updateNodeTx(ctx, tx, id, input)
+	})
+}
+
+// PreviewAssignUser is the dry-run counterpart of AssignUserToNode.
+func (r *Repository) PreviewAssignUser(ctx context.Context, parentNodeID, userID uuid.UUID) ([]AffectedUser, error) {
+	return r.preview(ctx, func(tx *sql.Tx) (uuid.UUID, error) {
+		return assignUserTx(ctx, tx, parentNodeID, userID)
+	})
+}
+
+func (r *Repository) preview(ctx context.Context, apply func(tx *sql.Tx) (uuid.UUID, error)) ([]AffectedUser, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is ever committed here.
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rootNodeID, err := apply(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT n.id, n.user_id
+		FROM hierarchy_nodes root
+		JOIN hierarchy_nodes n ON n.path = root.path OR n.path LIKE root.path || '.%'
+		WHERE root.id = $1
+		  AND n.user_id IS NOT NULL
+		ORDER BY n.level ASC, n.position ASC`, rootNodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]AffectedUser, 0)
+	for rows.Next() {
+		var item AffectedUser
+		if scanErr := rows.Scan(&item.NodeID, &item.UserID); scanErr != nil {
+			rows.Close()
+			return nil, scanErr
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	affected := make([]AffectedUser, 0, len(items))
+	for _, item := range items {
+		// r.db still sees the committed state while tx holds the new one.
+		if err := loadUserPlacement(ctx, r.db, item.UserID, &item, &item.Before); err != nil {
+			return nil, err
+		}
+		if err := loadUserPlacement(ctx, tx, item.UserID, &item, &item.After); err != nil {
+			return nil, err
+		}
+		if uuidPtrEqual(item.Before.ManagerID, item.After.ManagerID) && uuidPtrEqual(item.Before.DepartmentID, item.After.DepartmentID) {
+			continue
+		}
+		affected = append(affected, item)
+	}
+
+	return affected, nil
+}
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func loadUserPlacement(ctx context.Context, q rowQueryer, userID uuid.UUID, user *AffectedUser, placement *UserPlacement) error {
+	var fullName sql.NullString
+	var managerName sql.NullString
+	var departmentName sql.NullString
+	if err := q.QueryRowContext(ctx, `
+		SELECT
+			u.email,
+			u.full_name,
+			u.manager_id,
+			COALESCE(NULLIF(BTRIM(m.full_name), ''), m.email),
+			u.department_id,
+			d.name
+		FROM users u
+		LEFT JOIN users m ON m.id = u.manager_id
+		LEFT JOIN departments d ON d.id = u.department_id
+		WHERE u.id = $1`, userID).Scan(
+		&user.Email,
+		&fullName,
+		&placement.ManagerID,
+		&managerName,
+		&placement.DepartmentID,
+		&departmentName,
+	); err != nil {
+		return err
+	}
+
+	if fullName.Valid && strings.TrimSpace(fullName.String) != "" {
+		name := strings.TrimSpace(fullName.String)
+		user.FullName = &name
+	}
+	if managerName.Valid {
+		name := managerName.String
+		placement.ManagerName = &name
+	}
+	if departmentName.Valid {
+		name := departmentName.String
+		placement.DepartmentName = &name
+	}
+
+	return nil
 }
 
 func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID uuid.UUID) (dbNode, error) {
@@ -339,47 +709,82 @@ func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID
 		}
 	}()
 
+	resultNodeID, err := assignUserTx(ctx, tx, parentNodeID, userID)
+	if err != nil {
+		return dbNode{}, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return dbNode{}, err
+	}
+
+	return r.GetNodeByID(ctx, resultNodeID)
+}
+
+// assignUserTx places userID under parentNodeID and returns the id of the node
+// now holding the user.
+func assignUserTx(ctx context.Context, tx *sql.Tx, parentNodeID, userID uuid.UUID) (uuid.UUID, error) {
 	var parentType NodeType
 	var parentLevel int
 	var parentPath string
-	if scanErr := tx.QueryRowContext(ctx, `SELECT type, level, path FROM hierarchy_nodes WHERE id = $1`, parentNodeID).Scan(&parentType, &parentLevel, &parentPath); scanErr != nil {
-		err = scanErr
-		return dbNode{}, err
+	var targetIsVacancy bool
+	var targetParentID *uuid.UUID
+	if scanErr := tx.QueryRowContext(ctx, `SELECT type, level, path, is_vacancy, parent_id FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, parentNodeID, db.Workspace(ctx)).Scan(&parentType, &parentLevel, &parentPath, &targetIsVacancy, &targetParentID); scanErr != nil {
+		return uuid.Nil, scanErr
+	}
+
+	var isMember bool
+	if scanErr := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+		)`, db.Workspace(ctx), userID).Scan(&isMember); scanErr != nil {
+		return uuid.Nil, scanErr
+	}
+	if !isMember {
+		return uuid.Nil, errors.New("cannot assign a user who is not a member of the workspace")
+	}
+
+	// Assigning onto a vacancy fills the open position in place: the node
+	// keeps its id, position and role title, and the user lands under the
+	// vacancy's parent.
+	var vacancyNodeID *uuid.UUID
+	if parentType == NodeTypeUser && targetIsVacancy && targetParentID != nil {
+		filled := parentNodeID
+		vacancyNodeID = &filled
+		parentNodeID = *targetParentID
+		if scanErr := tx.QueryRowContext(ctx, `SELECT type, level, path FROM hierarchy_nodes WHERE id = $1`, parentNodeID).Scan(&parentType, &parentLevel, &parentPath); scanErr != nil {
+			return uuid.Nil, scanErr
+		}
 	}
 	if parentType == NodeTypeUser {
-		err = errors.New("user cannot be assigned under a user node")
-		return dbNode{}, err
+		return uuid.Nil, errors.New("user cannot be assigned under a user node")
 	}
 
-	if parentType == NodeTypeCompany {
+	if parentType == NodeTypeCompany && vacancyNodeID == nil {
 		var previousCompanyUserID *uuid.UUID
 		if prevScanErr := tx.QueryRowContext(ctx, `SELECT user_id FROM hierarchy_nodes WHERE id = $1`, parentNodeID).Scan(&previousCompanyUserID); prevScanErr != nil {
-			err = prevScanErr
-			return dbNode{}, err
+			return uuid.Nil, prevScanErr
 		}
 
 		var existingNodeID uuid.UUID
-		lookupErr := tx.QueryRowContext(ctx, `SELECT id FROM hierarchy_nodes WHERE user_id = $1`, userID).Scan(&existingNodeID)
+		lookupErr := tx.QueryRowContext(ctx, `SELECT id FROM hierarchy_nodes WHERE user_id = $1 AND workspace_id = $2`, userID, db.Workspace(ctx)).Scan(&existingNodeID)
 		if lookupErr != nil && !errors.Is(lookupErr, sql.ErrNoRows) {
-			err = lookupErr
-			return dbNode{}, err
+			return uuid.Nil, lookupErr
 		}
 
 		if lookupErr == nil && existingNodeID != parentNodeID {
 			if _, execErr := tx.ExecContext(ctx, `DELETE FROM hierarchy_nodes WHERE id = $1`, existingNodeID); execErr != nil {
-				err = execErr
-				return dbNode{}, err
+				return uuid.Nil, execErr
 			}
 		}
 
 		if _, execErr := tx.ExecContext(ctx, `UPDATE hierarchy_nodes SET user_id = $2 WHERE id = $1`, parentNodeID, userID); execErr != nil {
-			err = execErr
-			return dbNode{}, err
+			return uuid.Nil, execErr
 		}
 
 		if _, execErr := tx.ExecContext(ctx, `UPDATE users SET manager_id = NULL, department_id = NULL, role = 'ceo' WHERE id = $1`, userID); execErr != nil {
-			err = execErr
-			return dbNode{}, err
+			return uuid.Nil, execErr
 		}
 
 		if previousCompanyUserID != nil && *previousCompanyUserID != userID {
@@ -390,24 +795,17 @@ func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID
 					ELSE role
 				END
 				WHERE id = $1`, *previousCompanyUserID); execErr != nil {
-				err = execErr
-				return dbNode{}, err
+				return uuid.Nil, execErr
 			}
 		}
 
-		if commitErr := tx.Commit(); commitErr != nil {
-			err = commitErr
-			return dbNode{}, err
-		}
-
-		return r.GetNodeByID(ctx, parentNodeID)
+		return parentNodeID, nil
 	}
 
 	var email string
 	var fullName sql.NullString
 	if scanErr := tx.QueryRowContext(ctx, `SELECT email, full_name FROM users WHERE id = $1`, userID).Scan(&email, &fullName); scanErr != nil {
-		err = scanErr
-		return dbNode{}, err
+		return uuid.Nil, scanErr
 	}
 
 	title := strings.TrimSpace(fullName.String)
@@ -420,58 +818,70 @@ func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID
 
 	position := 0
 	if scanErr := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(position), -1) + 1 FROM hierarchy_nodes WHERE parent_id = $1`, parentNodeID).Scan(&position); scanErr != nil {
-		err = scanErr
-		return dbNode{}, err
+		return uuid.Nil, scanErr
 	}
 
 	var existingNodeID uuid.UUID
-	lookupErr := tx.QueryRowContext(ctx, `SELECT id FROM hierarchy_nodes WHERE user_id = $1`, userID).Scan(&existingNodeID)
+	lookupErr := tx.QueryRowContext(ctx, `SELECT id FROM hierarchy_nodes WHERE user_id = $1 AND workspace_id = $2`, userID, db.Workspace(ctx)).Scan(&existingNodeID)
 	if lookupErr != nil && !errors.Is(lookupErr, sql.ErrNoRows) {
-		err = lookupErr
-		return dbNode{}, err
+		return uuid.Nil, lookupErr
 	}
 	if lookupErr == nil {
 		var existingType NodeType
 		if scanErr := tx.QueryRowContext(ctx, `SELECT type FROM hierarchy_nodes WHERE id = $1`, existingNodeID).Scan(&existingType); scanErr != nil {
-			err = scanErr
-			return dbNode{}, err
+			return uuid.Nil, scanErr
 		}
 
 		if existingType == NodeTypeCompany && parentType != NodeTypeCompany {
-			err = errors.New("cannot move current CEO from company root; assign another CEO first")
-			return dbNode{}, err
+			return uuid.Nil, errors.New("cannot move current CEO from company root; assign another CEO first")
 		}
 	}
 
 	var resultNodeID uuid.UUID
-	if errors.Is(lookupErr, sql.ErrNoRows) {
+	if vacancyNodeID != nil {
+		resultNodeID = *vacancyNodeID
+		if lookupErr == nil {
+			if _, execErr := tx.ExecContext(ctx, `DELETE FROM hierarchy_nodes WHERE id = $1`, existingNodeID); execErr != nil {
+				return uuid.Nil, execErr
+			}
+		}
+
+		if _, execErr := tx.ExecContext(ctx, `
+			UPDATE hierarchy_nodes
+			SET title = $2,
+				user_id = $3,
+				is_vacancy = false,
+				hiring_status = NULL,
+				status = 'free'
+			WHERE id = $1`, resultNodeID, title, userID); execErr != nil {
+			return uuid.Nil, execErr
+		}
+	} else if errors.Is(lookupErr, sql.ErrNoRows) {
 		insertErr := tx.QueryRowContext(ctx, `
-			INSERT INTO hierarchy_nodes (title, type, parent_id, user_id, position, level, path)
-			VALUES ($1, 'user', $2, $3, $4, $5, '')
+			INSERT INTO hierarchy_nodes (title, type, parent_id, user_id, position, level, path, workspace_id)
+			VALUES ($1, 'user', $2, $3, $4, $5, '', $6)
 			RETURNING id`,
 			title,
 			parentNodeID,
 			userID,
 			position,
 			parentLevel+1,
+			db.Workspace(ctx),
 		).Scan(&resultNodeID)
 		if insertErr != nil {
-			err = insertErr
-			return dbNode{}, err
+			return uuid.Nil, insertErr
 		}
 
 		newPath := fmt.Sprintf("%s.%s", parentPath, resultNodeID.String())
 		if _, execErr := tx.ExecContext(ctx, `UPDATE hierarchy_nodes SET path = $2 WHERE id = $1`, resultNodeID, newPath); execErr != nil {
-			err = execErr
-			return dbNode{}, err
+			return uuid.Nil, execErr
 		}
 	} else {
 		resultNodeID = existingNodeID
 		var oldPath string
 		var oldLevel int
 		if scanErr := tx.QueryRowContext(ctx, `SELECT path, level FROM hierarchy_nodes WHERE id = $1`, existingNodeID).Scan(&oldPath, &oldLevel); scanErr != nil {
-			err = scanErr
-			return dbNode{}, err
+			return uuid.Nil, scanErr
 		}
 
 		newPath := fmt.Sprintf("%s.%s", parentPath, existingNodeID.String())
@@ -483,8 +893,7 @@ func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID
 				level = $5,
 				path = $6
 			WHERE id = $1`, existingNodeID, title, parentNodeID, position, parentLevel+1, newPath); execErr != nil {
-			err = execErr
-			return dbNode{}, err
+			return uuid.Nil, execErr
 		}
 
 		if oldPath != newPath || oldLevel != parentLevel+1 {
@@ -493,28 +902,24 @@ func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID
 				SET level = $3 + (level - $4),
 					path = $2 || SUBSTRING(path FROM LENGTH($1) + 1)
 				WHERE path LIKE $1 || '.%'`, oldPath, newPath, parentLevel+1, oldLevel); execErr != nil {
-				err = execErr
-				return dbNode{}, err
+				return uuid.Nil, execErr
 			}
 		}
 	}
 
 	managerID, resolveManagerErr := resolveNearestManagerIDTx(ctx, tx, parentPath)
 	if resolveManagerErr != nil {
-		err = resolveManagerErr
-		return dbNode{}, err
+		return uuid.Nil, resolveManagerErr
 	}
 
 	departmentID, resolveDepartmentErr := resolveNearestDepartmentIDTx(ctx, tx, parentPath)
 	if resolveDepartmentErr != nil {
-		err = resolveDepartmentErr
-		return dbNode{}, err
+		return uuid.Nil, resolveDepartmentErr
 	}
 
 	departmentTitle, resolveDepartmentTitleErr := resolveNearestDepartmentTitleTx(ctx, tx, parentPath)
 	if resolveDepartmentTitleErr != nil {
-		err = resolveDepartmentTitleErr
-		return dbNode{}, err
+		return uuid.Nil, resolveDepartmentTitleErr
 	}
 
 	autoRole := inferAutoSystemRole(parentType, departmentTitle)
@@ -529,26 +934,246 @@ func (r *Repository) AssignUserToNode(ctx context.Context, parentNodeID, userID
 				ELSE role
 			END
 		WHERE id = $1`, userID, managerID, departmentID, autoRole); execErr != nil {
-		err = execErr
+		return uuid.Nil, execErr
+	}
+
+	return resultNodeID, nil
+}
+
+func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE hierarchy_nodes SET status = $2 WHERE id = $1 AND workspace_id = $3`, id, status, db.Workspace(ctx))
+	return err
+}
+
+func (r *Repository) SetDepartmentHead(ctx context.Context, id uuid.UUID, isHead bool) (dbNode, error) {
+	var nodeType NodeType
+	var userID *uuid.UUID
+	if err := r.db.QueryRowContext(ctx, `SELECT type, user_id FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, id, db.Workspace(ctx)).Scan(&nodeType, &userID); err != nil {
 		return dbNode{}, err
 	}
+	if isHead && (nodeType != NodeTypeUser || userID == nil) {
+		return dbNode{}, errors.New("cannot mark non-user node as department head")
+	}
 
-	if commitErr := tx.Commit(); commitErr != nil {
-		err = commitErr
+	if _, err := r.db.ExecContext(ctx, `UPDATE hierarchy_nodes SET is_department_head = $2 WHERE id = $1`, id, isHead); err != nil {
 		return dbNode{}, err
 	}
 
-	return r.GetNodeByID(ctx, resultNodeID)
+	return r.GetNodeByID(ctx, id)
 }
 
-func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE hierarchy_nodes SET status = $2 WHERE id = $1`, id, status)
-	return err
+// ListHeadWorkload returns the workload of every user placed inside the
+// departments headed by headUserID. The head scope is the subtree of the
+// head node's parent, so the head sees the whole department they lead.
+func (r *Repository) ListHeadWorkload(ctx context.Context, headUserID uuid.UUID) ([]WorkloadItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (u.id)
+			u.id,
+			u.email,
+			u.full_name,
+			member.id,
+			member.status,
+			member.role_title,
+			(
+				SELECT COUNT(DISTINCT pm.project_id)::int
+				FROM project_members pm
+				WHERE pm.user_id = u.id
+			),
+			(
+				SELECT COUNT(*)::int
+				FROM stage_tasks t
+				JOIN project_stages s ON s.id = t.stage_id
+				JOIN project_members pm ON pm.project_id = s.project_id AND pm.user_id = u.id
+				WHERE t.status <> 'done'
+				  AND (t.blocks::text ILIKE '%' || u.id::text || '%' OR t.blocks::text ILIKE '%' || u.email || '%')
+			),
+			(
+				SELECT COUNT(*)::int
+				FROM stage_tasks t
+				JOIN project_stages s ON s.id = t.stage_id
+				JOIN project_members pm ON pm.project_id = s.project_id AND pm.user_id = u.id
+				WHERE t.status <> 'done'
+				  AND t.deadline IS NOT NULL
+				  AND t.deadline < now()
+				  AND (t.blocks::text ILIKE '%' || u.id::text || '%' OR t.blocks::text ILIKE '%' || u.email || '%')
+			)
+		FROM hierarchy_nodes head
+		JOIN hierarchy_nodes scope ON scope.id = head.parent_id
+		JOIN hierarchy_nodes member ON member.path LIKE scope.path || '.%'
+		JOIN users u ON u.id = member.user_id
+		WHERE head.user_id = $1
+		  AND head.workspace_id = $2
+		  AND head.is_department_head
+		  AND member.type = 'user'
+		  AND member.user_id <> $1
+		ORDER BY u.id, member.level ASC`, headUserID, db.Workspace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]WorkloadItem, 0)
+	for rows.Next() {
+		var item WorkloadItem
+		var fullName sql.NullString
+		var roleTitle sql.NullString
+		if err := rows.Scan(
+			&item.UserID,
+			&item.Email,
+			&fullName,
+			&item.NodeID,
+			&item.Status,
+			&roleTitle,
+			&item.ProjectsCount,
+			&item.OpenTasksCount,
+			&item.OverdueTasksCount,
+		); err != nil {
+			return nil, err
+		}
+		if fullName.Valid && strings.TrimSpace(fullName.String) != "" {
+			name := strings.TrimSpace(fullName.String)
+			item.FullName = &name
+		}
+		if roleTitle.Valid && strings.TrimSpace(roleTitle.String) != "" {
+			title := strings.TrimSpace(roleTitle.String)
+			item.RoleTitle = &title
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// GetManagementChain walks the ancestors of the user's node from the closest
+// department up to the company root and returns the heads of every enclosing
+// department followed by the CEO. Returns sql.ErrNoRows when the user has no
+// node in the hierarchy.
+func (r *Repository) GetManagementChain(ctx context.Context, userID uuid.UUID) ([]ChainMember, error) {
+	var subjectPath string
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT path
+		FROM hierarchy_nodes
+		WHERE user_id = $1
+		  AND workspace_id = $2
+		ORDER BY level ASC
+		LIMIT 1`, userID, db.Workspace(ctx)).Scan(&subjectPath); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			u.id,
+			u.email,
+			u.full_name,
+			u.avatar_url,
+			n.id,
+			anc.title,
+			n.role_title,
+			anc.type,
+			anc.level
+		FROM hierarchy_nodes anc
+		JOIN hierarchy_nodes n ON (
+			(anc.type = 'company' AND n.id = anc.id)
+			OR (anc.type = 'department' AND n.parent_id = anc.id AND n.type = 'user' AND n.is_department_head)
+		)
+		JOIN users u ON u.id = n.user_id
+		WHERE $1 LIKE anc.path || '.%'
+		ORDER BY anc.level DESC, n.position ASC, n.title ASC`, subjectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := map[uuid.UUID]struct{}{userID: {}}
+	items := make([]ChainMember, 0)
+	for rows.Next() {
+		var item ChainMember
+		var fullName sql.NullString
+		var avatarURL sql.NullString
+		var roleTitle sql.NullString
+		var scopeType NodeType
+		if err := rows.Scan(
+			&item.UserID,
+			&item.Email,
+			&fullName,
+			&avatarURL,
+			&item.NodeID,
+			&item.Scope,
+			&roleTitle,
+			&scopeType,
+			&item.Level,
+		); err != nil {
+			return nil, err
+		}
+		if _, ok := seen[item.UserID]; ok {
+			continue
+		}
+		seen[item.UserID] = struct{}{}
+
+		item.Relation = "department_head"
+		if scopeType == NodeTypeCompany {
+			item.Relation = "ceo"
+		}
+		if fullName.Valid && strings.TrimSpace(fullName.String) != "" {
+			name := strings.TrimSpace(fullName.String)
+			item.FullName = &name
+		}
+		if avatarURL.Valid && strings.TrimSpace(avatarURL.String) != "" {
+			avatar := strings.TrimSpace(avatarURL.String)
+			item.AvatarURL = &avatar
+		}
+		if roleTitle.Valid && strings.TrimSpace(roleTitle.String) != "" {
+			title := strings.TrimSpace(roleTitle.String)
+			item.RoleTitle = &title
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *Repository) IsDepartmentHead(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var isHead bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM hierarchy_nodes
+			WHERE user_id = $1
+			  AND workspace_id = $2
+			  AND is_department_head
+		)`, userID, db.Workspace(ctx)).Scan(&isHead)
+	if err != nil {
+		return false, err
+	}
+
+	return isHead, nil
+}
+
+// IsWorkspaceAdmin reports whether userID administers the current workspace.
+func (r *Repository) IsWorkspaceAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM workspace_members
+			WHERE workspace_id = $1
+			  AND user_id = $2
+			  AND role = 'admin'
+		)`, db.Workspace(ctx), userID).Scan(&isAdmin)
+	return isAdmin, err
 }
 
 func (r *Repository) GetNodeUserID(ctx context.Context, id uuid.UUID) (*uuid.UUID, error) {
 	var userID *uuid.UUID
-	err := r.db.QueryRowContext(ctx, `SELECT user_id FROM hierarchy_nodes WHERE id = $1`, id).Scan(&userID)
+	err := r.db.QueryRowContext(ctx, `SELECT user_id FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, id, db.Workspace(ctx)).Scan(&userID)
 	return userID, err
 }
 
@@ -559,7 +1184,8 @@ func (r *Repository) HasAssignedHierarchyUser(ctx context.Context) (bool, error)
 			SELECT 1
 			FROM hierarchy_nodes
 			WHERE user_id IS NOT NULL
-		)`).Scan(&hasAssigned)
+			  AND workspace_id = $1
+		)`, db.Workspace(ctx)).Scan(&hasAssigned)
 	if err != nil {
 		return false, err
 	}
@@ -575,7 +1201,8 @@ func (r *Repository) HasCompanyAssignedUser(ctx context.Context) (bool, error) {
 			FROM hierarchy_nodes
 			WHERE type = 'company'
 			  AND user_id IS NOT NULL
-		)`).Scan(&hasCompanyAssigned)
+			  AND workspace_id = $1
+		)`, db.Workspace(ctx)).Scan(&hasCompanyAssigned)
 	if err != nil {
 		return false, err
 	}
@@ -585,7 +1212,7 @@ func (r *Repository) HasCompanyAssignedUser(ctx context.Context) (bool, error) {
 
 func (r *Repository) DeleteNode(ctx context.Context, id uuid.UUID) error {
 	var nodeType NodeType
-	if err := r.db.QueryRowContext(ctx, `SELECT type FROM hierarchy_nodes WHERE id = $1`, id).Scan(&nodeType); err != nil {
+	if err := r.db.QueryRowContext(ctx, `SELECT type FROM hierarchy_nodes WHERE id = $1 AND workspace_id = $2`, id, db.Workspace(ctx)).Scan(&nodeType); err != nil {
 		return err
 	}
 	if nodeType == NodeTypeCompany {
@@ -599,8 +1226,9 @@ func (r *Repository) DeleteNode(ctx context.Context, id uuid.UUID) error {
 func (r *Repository) ListDepartmentCatalog(ctx context.Context) ([]CatalogItem, error) {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, name, is_system
-		FROM hierarchy_department_catalog
-		ORDER BY is_system DESC, name ASC`)
+		FROM departments
+		WHERE workspace_id = $1
+		ORDER BY is_system DESC, name ASC`, db.Workspace(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -620,9 +1248,10 @@ func (r *Repository) ListDepartmentCatalog(ctx context.Context) ([]CatalogItem,
 
 func (r *Repository) ListRoleCatalog(ctx context.Context) ([]CatalogItem, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, name, is_system
+		SELECT id, name, is_system, can_manage_hierarchy, can_view_all_projects, can_approve_expenses
 		FROM hierarchy_role_catalog
-		ORDER BY is_system DESC, name ASC`)
+		WHERE workspace_id = $1
+		ORDER BY is_system DESC, name ASC`, db.Workspace(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -631,31 +1260,79 @@ func (r *Repository) ListRoleCatalog(ctx context.Context) ([]CatalogItem, error)
 	items := make([]CatalogItem, 0)
 	for rows.Next() {
 		var item CatalogItem
-		if scanErr := rows.Scan(&item.ID, &item.Name, &item.IsSystem); scanErr != nil {
+		var permissions RolePermissions
+		if scanErr := rows.Scan(
+			&item.ID,
+			&item.Name,
+			&item.IsSystem,
+			&permissions.CanManageHierarchy,
+			&permissions.CanViewAllProjects,
+			&permissions.CanApproveExpenses,
+		); scanErr != nil {
 			return nil, scanErr
 		}
+		item.Permissions = &permissions
 		items = append(items, item)
 	}
 
 	return items, rows.Err()
 }
 
-func ensureDepartmentCatalogEntryTx(ctx context.Context, tx *sql.Tx, title string) (*uuid.UUID, error) {
-	normalized := normalizeCatalogName(title)
-	if normalized == "" {
-		return nil, nil
+func (r *Repository) UpdateRolePermissions(ctx context.Context, id uuid.UUID, permissions RolePermissions) (CatalogItem, error) {
+	var item CatalogItem
+	var updated RolePermissions
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE hierarchy_role_catalog
+		SET can_manage_hierarchy = $2,
+			can_view_all_projects = $3,
+			can_approve_expenses = $4
+		WHERE id = $1
+		  AND workspace_id = $5
+		RETURNING id, name, is_system, can_manage_hierarchy, can_view_all_projects, can_approve_expenses`,
+		id,
+		permissions.CanManageHierarchy,
+		permissions.CanViewAllProjects,
+		permissions.CanApproveExpenses,
+		db.Workspace(ctx),
+	).Scan(
+		&item.ID,
+		&item.Name,
+		&item.IsSystem,
+		&updated.CanManageHierarchy,
+		&updated.CanViewAllProjects,
+		&updated.CanApproveExpenses,
+	)
+	if err != nil {
+		return CatalogItem{}, err
 	}
 
-	var id uuid.UUID
-	if err := tx.QueryRowContext(ctx, `
-		INSERT INTO hierarchy_department_catalog (name, is_system)
-		VALUES ($1, false)
-		ON CONFLICT (name)
-		DO UPDATE SET name = EXCLUDED.name
-		RETURNING id`, normalized).Scan(&id); err != nil {
-		return nil, err
+	item.Permissions = &updated
+	return item, nil
+}
+
+// GetUserPermissions resolves the permissions granted to a user through the
+// role catalog entries of the nodes they occupy in the current workspace. The
+// user assigned to the company root node holds every permission.
+func (r *Repository) GetUserPermissions(ctx context.Context, userID uuid.UUID) (RolePermissions, error) {
+	var permissions RolePermissions
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(bool_or(n.type = 'company' OR c.can_manage_hierarchy), false),
+			COALESCE(bool_or(n.type = 'company' OR c.can_view_all_projects), false),
+			COALESCE(bool_or(n.type = 'company' OR c.can_approve_expenses), false)
+		FROM hierarchy_nodes n
+		LEFT JOIN hierarchy_role_catalog c ON c.workspace_id = n.workspace_id AND c.name = BTRIM(n.role_title)
+		WHERE n.user_id = $1
+		  AND n.workspace_id = $2`, userID, db.Workspace(ctx)).Scan(
+		&permissions.CanManageHierarchy,
+		&permissions.CanViewAllProjects,
+		&permissions.CanApproveExpenses,
+	)
+	if err != nil {
+		return RolePermissions{}, err
 	}
-	return &id, nil
+
+	return permissions, nil
 }
 
 func ensureRoleCatalogEntryTx(ctx context.Context, tx *sql.Tx, title string) (*uuid.UUID, error) {
@@ -666,11 +1343,11 @@ func ensureRoleCatalogEntryTx(ctx context.Context, tx *sql.Tx, title string) (*u
 
 	var id uuid.UUID
 	if err := tx.QueryRowContext(ctx, `
-		INSERT INTO hierarchy_role_catalog (name, is_system)
-		VALUES ($1, false)
-		ON CONFLICT (name)
+		INSERT INTO hierarchy_role_catalog (name, is_system, workspace_id)
+		VALUES ($1, false, $2)
+		ON CONFLICT (workspace_id, name)
 		DO UPDATE SET name = EXCLUDED.name
-		RETURNING id`, normalized).Scan(&id); err != nil {
+		RETURNING id`, normalized, db.Workspace(ctx)).Scan(&id); err != nil {
 		return nil, err
 	}
 	return &id, nil
@@ -687,17 +1364,18 @@ func ensureDepartmentIDByNameTx(ctx context.Context, tx *sql.Tx, title string) (
 		SELECT id
 		FROM departments
 		WHERE LOWER(TRIM(name)) = LOWER(TRIM($1))
+		  AND workspace_id = $2
 		ORDER BY id ASC
-		LIMIT 1`, normalized).Scan(&id); err == nil {
+		LIMIT 1`, normalized, db.Workspace(ctx)).Scan(&id); err == nil {
 		return &id, nil
 	} else if !errors.Is(err, sql.ErrNoRows) {
 		return nil, err
 	}
 
 	if err := tx.QueryRowContext(ctx, `
-		INSERT INTO departments (name)
-		VALUES ($1)
-		RETURNING id`, normalized).Scan(&id); err != nil {
+		INSERT INTO departments (name, workspace_id)
+		VALUES ($1, $2)
+		RETURNING id`, normalized, db.Workspace(ctx)).Scan(&id); err != nil {
 		return nil, err
 	}
 
@@ -733,19 +1411,24 @@ func resolveNearestDepartmentIDTx(ctx context.Context, tx *sql.Tx, parentPath st
 	}
 
 	var departmentTitle string
+	var linkedID *uuid.UUID
 	err := tx.QueryRowContext(ctx, `
-		SELECT title
-		FROM hierarchy_nodes
-		WHERE type = 'department'
-		  AND ($1 = path OR $1 LIKE path || '.%')
-		ORDER BY level DESC
-		LIMIT 1`, parentPath).Scan(&departmentTitle)
+		SELECT n.title, d.id
+		FROM hierarchy_nodes n
+		LEFT JOIN departments d ON d.hierarchy_node_id = n.id
+		WHERE n.type = 'department'
+		  AND ($1 = n.path OR $1 LIKE n.path || '.%')
+		ORDER BY n.level DESC
+		LIMIT 1`, parentPath).Scan(&departmentTitle, &linkedID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if linkedID != nil {
+		return linkedID, nil
+	}
 
 	return ensureDepartmentIDByNameTx(ctx, tx, departmentTitle)
 }
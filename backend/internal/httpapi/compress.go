@@ -0,0 +1,283 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest body worth compressing; below it the
+// framing outweighs the saving.
+const compressMinSize = 1024
+
+// brotliLevel trades ratio for speed; the higher levels cost too much CPU
+// for responses built on every request.
+const brotliLevel = 4
+
+// encoder compresses a response in one content coding.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders pools the writers of the codings Compress offers, by name.
+var encoders = map[string]*sync.Pool{
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotliLevel)
+	}},
+	"gzip": {New: func() any {
+		gz, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return gz
+	}},
+}
+
+// preferredEncodings are the codings Compress offers, best first; the first
+// of those a client rates highest is used.
+var preferredEncodings = []string{"br", "gzip"}
+
+// Compress encodes text and JSON responses with Brotli or gzip, whichever
+// the client's Accept-Encoding rates higher, Brotli on a tie. Bodies under
+// compressMinSize, event streams, range responses and anything already
+// encoded pass through untouched.
+//
+// A compressed body is a different representation, so its ETag gets the
+// coding appended ("<tag>.gzip"). Tags sent back in If-None-Match, If-Match
+// and If-Range lose the suffix before handlers see them, so conditional
+// requests work alike whichever coding the client got.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"If-None-Match", "If-Match", "If-Range"} {
+			if value := r.Header.Get(name); value != "" {
+				r.Header.Set(name, decodeTags(value))
+			}
+		}
+		if r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: negotiateEncoding(r.Header.Get("Accept-Encoding"))}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the coding of preferredEncodings an
+// Accept-Encoding header rates highest, or "" when it admits none. Codings
+// it does not name are admitted with the weight of "*", if that is given.
+func negotiateEncoding(header string) string {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[name] = q
+	}
+
+	best, bestWeight := "", 0.0
+	for _, name := range preferredEncodings {
+		weight, ok := weights[name]
+		if !ok {
+			weight = weights["*"]
+		}
+		if weight > bestWeight {
+			best, bestWeight = name, weight
+		}
+	}
+	return best
+}
+
+// encodeTag returns the ETag of the representation of tag compressed with
+// encoding.
+func encodeTag(tag, encoding string) string {
+	if !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+		return tag
+	}
+	return strings.TrimSuffix(tag, `"`) + "." + encoding + `"`
+}
+
+// decodeTags strips the coding encodeTag appended from every tag of a
+// conditional request header. Tags are base64url, which has no dots, so
+// the suffix cannot be part of a tag.
+func decodeTags(header string) string {
+	parts := strings.Split(header, ",")
+	for i, part := range parts {
+		candidate := strings.TrimSpace(part)
+		for _, encoding := range preferredEncodings {
+			if suffix := "." + encoding + `"`; strings.HasSuffix(candidate, suffix) {
+				candidate = strings.TrimSuffix(candidate, suffix) + `"`
+				break
+			}
+		}
+		parts[i] = candidate
+	}
+	return strings.Join(parts, ", ")
+}
+
+// compressible reports whether responses of contentType shrink enough to
+// compress. Event streams are left alone so every event reaches the client
+// when it is flushed.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml",
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// compressWriter holds the start of the body back until it knows whether
+// the response is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string // the coding the client accepts best, "" for none
+
+	status  int
+	buf     []byte
+	held    bool // the body is held back in buf to be compressed
+	decided bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	switch {
+	case cw.decided, status < http.StatusOK:
+		// informational responses go out as they come
+		cw.ResponseWriter.WriteHeader(status)
+	case cw.status == 0:
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	if !cw.held {
+		if !cw.eligible() {
+			if err := cw.start(false); err != nil {
+				return 0, err
+			}
+			return cw.ResponseWriter.Write(p)
+		}
+		cw.held = true
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// eligible reports whether the response may be compressed, judging by
+// what the handler set so far
+func (cw *compressWriter) eligible() bool {
+	header := cw.Header()
+	if !compressible(header.Get("Content-Type")) {
+		return false
+	}
+	header.Add("Vary", "Accept-Encoding")
+	switch cw.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	return cw.encoding != "" && header.Get("Content-Encoding") == "" && header.Get("Content-Range") == ""
+}
+
+// start sends the header and what was held back, compressed when compress
+// is set.
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	if compress {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		if tag := header.Get("ETag"); tag != "" {
+			header.Set("ETag", encodeTag(tag, cw.encoding))
+		}
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	held := cw.buf
+	cw.buf = nil
+	if len(held) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(held)
+	} else {
+		_, err = cw.ResponseWriter.Write(held)
+	}
+	return err
+}
+
+// Flush sends what was held back, compressed when it is already big enough.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		_ = cw.start(cw.held && len(cw.buf) >= compressMinSize)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close ends the response: a body that stayed under compressMinSize is
+// sent as it is.
+func (cw *compressWriter) Close() {
+	if !cw.decided && cw.status != 0 {
+		_ = cw.start(false)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Close()
+		cw.enc.Reset(io.Discard)
+		encoders[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,123 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"GZIP", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip;q=0.5", "br"},
+		{"gzip;q=1, br;q=0.5", "gzip"},
+		{"br;q=0", ""},
+		{"br;q=0, gzip", "gzip"},
+		{"*", "br"},
+		{"*;q=0.1", "br"},
+		{"*;q=0", ""},
+		{"br;q=0, *", "gzip"},
+		{"gzip;q=0.8, *;q=0.9", "br"},
+		{"identity", ""},
+		{"deflate, identity", ""},
+		{"gzip;q=abc", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		encoding string
+		want     string
+	}{
+		{`"abc"`, "gzip", `"abc.gzip"`},
+		{`"abc"`, "br", `"abc.br"`},
+		{`W/"abc"`, "br", `W/"abc.br"`},
+		{`abc`, "gzip", `abc`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+" "+tt.encoding, func(t *testing.T) {
+			if got := encodeTag(tt.tag, tt.encoding); got != tt.want {
+				t.Errorf("encodeTag(%q, %q) = %q, want %q", tt.tag, tt.encoding, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTags(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{`"abc.gzip"`, `"abc"`},
+		{`"abc.br"`, `"abc"`},
+		{`W/"abc.br"`, `W/"abc"`},
+		{`"abc"`, `"abc"`},
+		{`"abc.br", "def.gzip", "ghi"`, `"abc", "def", "ghi"`},
+		{`*`, `*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := decodeTags(tt.header); got != tt.want {
+				t.Errorf("decodeTags(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressETag(t *testing.T) {
+	body := `{"items":"` + strings.Repeat("a", compressMinSize) + `"}`
+
+	tests := []struct {
+		acceptEncoding string
+		wantEncoding   string
+		wantTag        string
+	}{
+		{"gzip", "gzip", `"abc.gzip"`},
+		{"gzip, br", "br", `"abc.br"`},
+		{"identity", "", `"abc"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			var seenIfNoneMatch string
+			handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seenIfNoneMatch = r.Header.Get("If-None-Match")
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("ETag", `"abc"`)
+				_, _ = w.Write([]byte(body))
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			r.Header.Set("If-None-Match", tt.wantTag)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantTag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantTag)
+			}
+			// the tag sent back reaches the handler without the coding
+			if seenIfNoneMatch != `"abc"` {
+				t.Errorf("handler saw If-None-Match %q, want %q", seenIfNoneMatch, `"abc"`)
+			}
+		})
+	}
+}
@@ -3,39 +3,169 @@ package httpapi
 import (
 	"net/http"
 	"strings"
+	"sync/atomic"
 )
 
-func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
-	allowedSet := make(map[string]struct{}, len(allowedOrigins))
-	for _, origin := range allowedOrigins {
+// CORSRules are what a browser on an allowed origin may do with a group of
+// routes.
+type CORSRules struct {
+	Methods       string
+	AllowHeaders  string
+	ExposeHeaders string
+	// Credentials lets the browser send cookies and read the response of a
+	// credentialed request. Their origins have to be listed one by one.
+	Credentials bool
+}
+
+var (
+	// APICORS covers the JSON API.
+	APICORS = CORSRules{
+		Methods:       "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		AllowHeaders:  "Content-Type, Authorization, X-Requested-With, Upload-Offset, If-Match, If-None-Match, X-Workspace-ID",
+		ExposeHeaders: "Upload-Offset, Link, X-Next-Cursor, X-Total-Count, ETag, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining",
+		Credentials:   true,
+	}
+	// UploadsCORS covers files under /uploads/. Access is granted by the
+	// signature in the link, so no credentials are sent and nothing but reads
+	// is allowed.
+	UploadsCORS = CORSRules{
+		Methods:       "GET, HEAD, OPTIONS",
+		AllowHeaders:  "Range, If-None-Match, If-Range",
+		ExposeHeaders: "Content-Length, Content-Range, Accept-Ranges, ETag",
+	}
+	// StreamsCORS covers the event streams, which are only read.
+	StreamsCORS = CORSRules{
+		Methods:      "GET, OPTIONS",
+		AllowHeaders: "Authorization, Last-Event-ID, X-Workspace-ID",
+		Credentials:  true,
+	}
+)
+
+// CORSPolicy is the set of origins allowed to use a group of routes under
+// its rules. "*" allows every origin. Set replaces the origins while the
+// server runs.
+type CORSPolicy struct {
+	rules CORSRules
+	set   atomic.Pointer[map[string]struct{}]
+}
+
+func NewCORSPolicy(rules CORSRules, origins []string) *CORSPolicy {
+	p := &CORSPolicy{rules: rules}
+	p.Set(origins)
+	return p
+}
+
+func (p *CORSPolicy) Set(origins []string) {
+	allowedSet := make(map[string]struct{}, len(origins))
+	for _, origin := range origins {
 		trimmed := strings.TrimSpace(origin)
 		if trimmed != "" {
 			allowedSet[trimmed] = struct{}{}
 		}
 	}
+	p.set.Store(&allowedSet)
+}
+
+func (p *CORSPolicy) Allowed(origin string) bool {
+	_, ok := (*p.set.Load())[origin]
+	return ok || p.wildcard()
+}
+
+// Handler answers preflight requests and sets the CORS headers of the
+// requests next serves.
+func (p *CORSPolicy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.apply(w, r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
+// apply sets the CORS headers of r, reporting false for a preflight request
+// from an origin that is not allowed.
+func (p *CORSPolicy) apply(w http.ResponseWriter, r *http.Request) bool {
+	origin := strings.TrimSpace(r.Header.Get("Origin"))
+	if origin != "" {
+		if !p.Allowed(origin) {
+			return r.Method != http.MethodOptions
+		}
+		w.Header().Add("Vary", "Origin")
+		if p.wildcard() && !p.rules.Credentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+	}
+	w.Header().Set("Access-Control-Allow-Methods", p.rules.Methods)
+	w.Header().Set("Access-Control-Allow-Headers", p.rules.AllowHeaders)
+	if p.rules.ExposeHeaders != "" {
+		w.Header().Set("Access-Control-Expose-Headers", p.rules.ExposeHeaders)
+	}
+	if p.rules.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func (p *CORSPolicy) wildcard() bool {
+	_, ok := (*p.set.Load())["*"]
+	return ok
+}
+
+// CORSPolicies are the CORS policies of the routes of the router; uploads
+// are served outside it and apply Uploads themselves.
+type CORSPolicies struct {
+	API     *CORSPolicy
+	Uploads *CORSPolicy
+	Streams *CORSPolicy
+}
+
+// CORSOrigins lists the allowed origins of each policy.
+type CORSOrigins struct {
+	API     []string
+	Uploads []string
+	Streams []string
+}
+
+func NewCORSPolicies(origins CORSOrigins) *CORSPolicies {
+	return &CORSPolicies{
+		API:     NewCORSPolicy(APICORS, origins.API),
+		Uploads: NewCORSPolicy(UploadsCORS, origins.Uploads),
+		Streams: NewCORSPolicy(StreamsCORS, origins.Streams),
+	}
+}
+
+// Set replaces the allowed origins of every policy.
+func (c *CORSPolicies) Set(origins CORSOrigins) {
+	c.API.Set(origins.API)
+	c.Uploads.Set(origins.Uploads)
+	c.Streams.Set(origins.Streams)
+}
+
+// isStreamPath reports whether path is an event stream. Preflight requests
+// are answered before routing, so streams are told apart by path.
+func isStreamPath(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(path, "/"), "/events")
+}
+
+// CORSMiddleware applies the stream policy to event streams and the API
+// policy to every other route.
+func CORSMiddleware(policies *CORSPolicies) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
+		api := policies.API.Handler(next)
+		streams := policies.Streams.Handler(next)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := strings.TrimSpace(r.Header.Get("Origin"))
-			if origin != "" {
-				if _, ok := allowedSet[origin]; ok {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Vary", "Origin")
-				} else if r.Method == http.MethodOptions {
-					w.WriteHeader(http.StatusForbidden)
-					return
-				}
-			}
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusOK)
+			if isStreamPath(r.URL.Path) {
+				streams.ServeHTTP(w, r)
 				return
 			}
-
-			next.ServeHTTP(w, r)
+			api.ServeHTTP(w, r)
 		})
 	}
 }
@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"tm-platform-backend/internal/account"
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/chats"
+	"tm-platform-backend/internal/files"
+	"tm-platform-backend/internal/graphapi"
+	"tm-platform-backend/internal/handlers"
+	"tm-platform-backend/internal/hierarchy"
+	"tm-platform-backend/internal/jobs"
+	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/openapi"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projectfiles"
+	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/retention"
+	"tm-platform-backend/internal/search"
+	"tm-platform-backend/internal/webhooks"
+	"tm-platform-backend/internal/workspaces"
+)
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument []byte
+	openAPIErr      error
+)
+
+// OpenAPIDocument returns the OpenAPI document of the API as JSON. It is
+// built once, on first use.
+func OpenAPIDocument() ([]byte, error) {
+	openAPIOnce.Do(func() {
+		openAPIDocument, openAPIErr = json.MarshalIndent(openapi.Document(
+			APIPrefix,
+			auth.APIOperations,
+			hierarchy.APIOperations,
+			projects.APIOperations,
+			chats.APIOperations,
+			notifications.APIOperations,
+			projectfiles.APIOperations,
+			handlers.APIOperations,
+			files.APIOperations,
+			jobs.APIOperations,
+			webhooks.APIOperations,
+			graphapi.APIOperations,
+			search.APIOperations,
+			workspaces.APIOperations,
+			retention.APIOperations,
+			account.APIOperations,
+		), "", "  ")
+	})
+	return openAPIDocument, openAPIErr
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	document, err := OpenAPIDocument()
+	if err != nil {
+		log.Printf("build openapi document failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to build openapi document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_, _ = w.Write(document)
+}
@@ -1,60 +1,133 @@
 package httpapi
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+
+	"github.com/redis/go-redis/v9"
 )
 
-type rateLimitEntry struct {
-	count   int
-	resetAt time.Time
+// RateLimit allows Requests per Window. A zero Requests disables the limit.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
 }
 
-func RateLimitByIP(limit int, window time.Duration) func(http.Handler) http.Handler {
-	if limit <= 0 {
-		limit = 60
+// RateLimitGroups are the limits of the route groups.
+type RateLimitGroups struct {
+	Auth   RateLimit // login, register and refresh, per IP
+	API    RateLimit // every authenticated route, per user
+	Upload RateLimit // uploads and upload sessions, per user
+	Parse  RateLimit // document parse jobs, per user
+}
+
+func (g RateLimitGroups) limit(group string) RateLimit {
+	switch group {
+	case "auth":
+		return g.Auth
+	case "api":
+		return g.API
+	case "upload":
+		return g.Upload
+	case "parse":
+		return g.Parse
 	}
-	if window <= 0 {
-		window = time.Minute
+	return RateLimit{}
+}
+
+// RateLimits applies the limits of the route groups, counted in a store.
+// Set replaces the limits while the server runs.
+type RateLimits struct {
+	store  RateLimitStore
+	groups atomic.Pointer[RateLimitGroups]
+}
+
+func NewRateLimits(store RateLimitStore, groups RateLimitGroups) *RateLimits {
+	l := &RateLimits{store: store}
+	l.Set(groups)
+	return l
+}
+
+func (l *RateLimits) Set(groups RateLimitGroups) {
+	l.groups.Store(&groups)
+}
+
+// RateLimitStore counts requests in fixed windows.
+type RateLimitStore interface {
+	// Hit counts one request against key and returns the count of the
+	// current window and the time left until it resets.
+	Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+}
+
+// RateLimitKey identifies who a request is counted against.
+type RateLimitKey func(r *http.Request) string
+
+// ByIP counts requests per client IP.
+func ByIP(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// ByUser counts requests per authenticated user, and per IP before
+// authentication.
+func ByUser(r *http.Request) string {
+	if userID, ok := auth.UserIDFromContext(r.Context()); ok && userID != "" {
+		return "user:" + userID
 	}
+	return ByIP(r)
+}
 
-	var (
-		mu      sync.Mutex
-		entries = map[string]rateLimitEntry{}
-	)
+// Limit returns a middleware allowing the limit of group (auth, api, upload
+// or parse) per key, as set when the request comes in. Requests over the
+// limit are answered 429 with Retry-After. When the store fails the request
+// is let through, so an outage of Redis does not take the API down with it.
+func (l *RateLimits) Limit(group string, key RateLimitKey) func(http.Handler) http.Handler {
+	if l.store == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			now := time.Now().UTC()
-			ip := clientIP(r)
+			limit := l.groups.Load().limit(group)
+			if limit.Requests <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if limit.Window <= 0 {
+				limit.Window = time.Minute
+			}
 
-			mu.Lock()
-			entry := entries[ip]
-			if entry.resetAt.IsZero() || now.After(entry.resetAt) {
-				entry = rateLimitEntry{count: 0, resetAt: now.Add(window)}
+			count, resetIn, err := l.store.Hit(r.Context(), "ratelimit:"+group+":"+key(r), limit.Window)
+			if err != nil {
+				log.Printf("rate limit %s failed: %v", group, err)
+				next.ServeHTTP(w, r)
+				return
 			}
-			entry.count++
-			entries[ip] = entry
 
-			for key, e := range entries {
-				if now.After(e.resetAt.Add(window)) {
-					delete(entries, key)
-				}
+			remaining := int64(limit.Requests) - count
+			if remaining < 0 {
+				remaining = 0
 			}
-			mu.Unlock()
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
 
-			if entry.count > limit {
-				retryAfter := int(time.Until(entry.resetAt).Seconds())
+			if count > int64(limit.Requests) {
+				retryAfter := int(resetIn.Round(time.Second) / time.Second)
 				if retryAfter < 1 {
-					retryAfter = int(window.Seconds())
+					retryAfter = 1
 				}
-				w.Header().Set("Retry-After", strconvItoa(retryAfter))
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				_, _ = w.Write([]byte(`{"error":"rate limit exceeded"}`))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				problem.Write(w, http.StatusTooManyRequests, problem.CodeRateLimited, "rate limit exceeded")
 				return
 			}
 
@@ -63,36 +136,102 @@ func RateLimitByIP(limit int, window time.Duration) func(http.Handler) http.Hand
 	}
 }
 
-func clientIP(r *http.Request) string {
-	host := strings.TrimSpace(r.RemoteAddr)
-	if parsed, _, err := net.SplitHostPort(host); err == nil && parsed != "" {
-		return parsed
+type rateLimitEntry struct {
+	count   int64
+	resetAt time.Time
+}
+
+// memorySweepInterval is how often MemoryRateLimitStore drops the counts of
+// windows that have ended.
+const memorySweepInterval = time.Minute
+
+// MemoryRateLimitStore keeps the counts in process. Each replica counts on
+// its own, so use RedisRateLimitStore when running several.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	entries   map[string]rateLimitEntry
+	nextSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{entries: map[string]rateLimitEntry{}}
+}
+
+func (s *MemoryRateLimitStore) Hit(_ context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// an ended window is restarted by the next hit of its key; keys that
+	// stop coming are dropped by the sweep
+	entry := s.entries[key]
+	if entry.resetAt.IsZero() || now.After(entry.resetAt) {
+		entry = rateLimitEntry{count: 0, resetAt: now.Add(window)}
 	}
-	if host == "" {
-		return "unknown"
+	entry.count++
+	s.entries[key] = entry
+
+	if now.After(s.nextSweep) {
+		s.sweep(now)
 	}
-	return host
+
+	return entry.count, entry.resetAt.Sub(now), nil
+}
+
+// sweep drops the entries whose window has ended. It walks every key, so
+// Hit runs it at most once per memorySweepInterval.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if now.After(entry.resetAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.nextSweep = now.Add(memorySweepInterval)
+}
+
+// rateLimitScript increments the counter of a window and starts its expiry
+// on the first hit, atomically so replicas never leave a counter without TTL.
+var rateLimitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// RedisRateLimitStore keeps the counts in Redis, shared by all replicas.
+type RedisRateLimitStore struct {
+	client *redis.Client
 }
 
-func strconvItoa(v int) string {
-	if v == 0 {
-		return "0"
+func NewRedisRateLimitStore(client *redis.Client) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+func (s *RedisRateLimitStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	values, err := rateLimitScript.Run(ctx, s.client, []string{key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
 	}
-	neg := false
-	if v < 0 {
-		neg = true
-		v = -v
+	if len(values) != 2 {
+		return 0, 0, errors.New("unexpected rate limit reply")
 	}
-	out := make([]byte, 0, 12)
-	for v > 0 {
-		out = append(out, byte('0'+(v%10)))
-		v /= 10
+
+	resetIn := time.Duration(values[1]) * time.Millisecond
+	if resetIn < 0 {
+		resetIn = window
 	}
-	if neg {
-		out = append(out, '-')
+	return values[0], resetIn, nil
+}
+
+func clientIP(r *http.Request) string {
+	host := strings.TrimSpace(r.RemoteAddr)
+	if parsed, _, err := net.SplitHostPort(host); err == nil && parsed != "" {
+		return parsed
 	}
-	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
-		out[i], out[j] = out[j], out[i]
+	if host == "" {
+		return "unknown"
 	}
-	return string(out)
+	return host
 }
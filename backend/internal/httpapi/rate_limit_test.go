@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreHit(t *testing.T) {
+	s := NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		count, resetIn, err := s.Hit(ctx, "user:a", time.Minute)
+		if err != nil {
+			t.Fatalf("Hit() error = %v", err)
+		}
+		if count != want {
+			t.Errorf("Hit() #%d count = %d, want %d", want, count, want)
+		}
+		if resetIn <= 0 || resetIn > time.Minute {
+			t.Errorf("Hit() #%d resets in %v, want within the window", want, resetIn)
+		}
+	}
+	if count, _, _ := s.Hit(ctx, "user:b", time.Minute); count != 1 {
+		t.Errorf("Hit() of another key count = %d, want 1", count)
+	}
+
+	// the next hit after the window ends starts a new one
+	entry := s.entries["user:a"]
+	entry.resetAt = time.Now().Add(-time.Second)
+	s.entries["user:a"] = entry
+	if count, _, _ := s.Hit(ctx, "user:a", time.Minute); count != 1 {
+		t.Errorf("Hit() after the window count = %d, want 1", count)
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	s := NewMemoryRateLimitStore()
+	ctx := context.Background()
+	s.Hit(ctx, "ip:1", time.Minute)
+
+	ended := rateLimitEntry{count: 5, resetAt: time.Now().Add(-time.Second)}
+	s.entries["ip:2"] = ended
+
+	// swept on the first hit, so not again until the interval has passed
+	s.Hit(ctx, "ip:1", time.Minute)
+	if _, ok := s.entries["ip:2"]; !ok {
+		t.Fatal("entry swept before the sweep interval passed")
+	}
+
+	s.nextSweep = time.Now().Add(-time.Second)
+	s.Hit(ctx, "ip:1", time.Minute)
+	if _, ok := s.entries["ip:2"]; ok {
+		t.Error("entry of an ended window kept after the sweep")
+	}
+	if _, ok := s.entries["ip:1"]; !ok {
+		t.Error("entry of a running window swept")
+	}
+}
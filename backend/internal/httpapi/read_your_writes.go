@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/db"
+)
+
+// ReadYourWrites pins writes, and the reads of a user for window after each
+// of their writes, to the primary database, so lists served from the
+// replica never miss what the user just created. Writes are remembered in
+// store, shared by the replicas of the server when it is Redis; a nil store
+// only pins the writes themselves.
+func ReadYourWrites(store cache.Store, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := auth.UserIDFromContext(r.Context())
+			key := "db:wrote:" + userID
+			remember := store != nil && window > 0 && userID != ""
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				if !remember {
+					break
+				}
+				if _, err := store.Get(r.Context(), key); err == nil {
+					r = r.WithContext(db.WithPrimary(r.Context()))
+				}
+			default:
+				// a write reads what it is about to change from the primary
+				r = r.WithContext(db.WithPrimary(r.Context()))
+				if !remember {
+					break
+				}
+				if err := store.Set(r.Context(), key, []byte("1"), window); err != nil {
+					log.Printf("remember write failed: %v", err)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,58 +2,94 @@ package httpapi
 
 import (
 	"net/http"
-	"time"
 
+	"tm-platform-backend/internal/account"
 	"tm-platform-backend/internal/aichat"
 	"tm-platform-backend/internal/auth"
 	"tm-platform-backend/internal/chats"
+	"tm-platform-backend/internal/files"
+	"tm-platform-backend/internal/graphapi"
 	"tm-platform-backend/internal/handlers"
 	"tm-platform-backend/internal/hierarchy"
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/jobs"
 	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/problem"
 	"tm-platform-backend/internal/projectfiles"
 	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/quotas"
+	"tm-platform-backend/internal/retention"
+	"tm-platform-backend/internal/search"
+	"tm-platform-backend/internal/webhooks"
+	"tm-platform-backend/internal/workspaces"
 	"tm-platform-backend/internal/zhcp"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, projectsHandler *projects.HTTPHandler, uploadHandler *handlers.UploadHandler, projectFilesHandler *projectfiles.Handler, zhcpHandler *zhcp.Handler, aiChatHandler *aichat.Handler, notificationsHandler *notifications.Handler, chatsHandler *chats.Handler, authSvc *auth.Service, allowedOrigins []string, readyCheck func() error) http.Handler {
+// APIPrefix is the path the API is served under. Breaking changes ship as a
+// new version next to it, so clients move when they are ready.
+const APIPrefix = "/api/v1"
+
+func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, projectsHandler *projects.HTTPHandler, uploadHandler *handlers.UploadHandler, projectFilesHandler *projectfiles.Handler, zhcpHandler *zhcp.Handler, aiChatHandler *aichat.Handler, notificationsHandler *notifications.Handler, chatsHandler *chats.Handler, quotaHandler *quotas.Handler, filesHandler *files.Handler, jobsHandler *jobs.Handler, webhooksHandler *webhooks.Handler, graphHandler *graphapi.Handler, searchHandler *search.Handler, workspacesHandler *workspaces.Handler, retentionHandler *retention.Handler, accountHandler *account.Handler, authSvc *auth.Service, limits *RateLimits, readYourWrites func(http.Handler) http.Handler, localize func(http.Handler) http.Handler, workspaceScope func(http.Handler) http.Handler, cors *CORSPolicies, ready http.Handler) http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(CORSMiddleware(allowedOrigins))
+	r.Use(CORSMiddleware(cors))
+	r.Use(contentPolicy)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
+	r.Use(Compress)
 	r.Use(middleware.Recoverer)
+	r.Use(i18n.Middleware(nil))
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, http.StatusNotFound, "route_not_found", "route not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	})
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if readyCheck != nil {
-			if err := readyCheck(); err != nil {
-				w.WriteHeader(http.StatusServiceUnavailable)
-				_, _ = w.Write([]byte("not-ready"))
-				return
-			}
-		}
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ready"))
-	})
+	if ready != nil {
+		r.Method(http.MethodGet, "/ready", ready)
+	} else {
+		r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ready"))
+		})
+	}
+
+	api := chi.NewRouter()
+	api.Get("/openapi.json", serveOpenAPI)
 
-	r.Route("/auth", func(r chi.Router) {
-		r.Use(RateLimitByIP(30, time.Minute))
+	api.Route("/auth", func(r chi.Router) {
+		r.Use(limits.Limit("auth", ByIP))
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
 		r.Post("/refresh", authHandler.Refresh)
 	})
 
-	r.Group(func(r chi.Router) {
+	api.Group(func(r chi.Router) {
 		r.Use(auth.JwtMiddleware(authSvc))
-		r.With(RateLimitByIP(20, time.Minute)).Post("/upload", uploadHandler.Upload)
+		r.Use(localize)
+		r.Use(limits.Limit("api", ByUser))
+		if readYourWrites != nil {
+			r.Use(readYourWrites)
+		}
+		r.Use(workspaceScope)
+		r.With(limits.Limit("upload", ByUser)).Post("/upload", uploadHandler.Upload)
+		r.With(limits.Limit("upload", ByUser)).Post("/upload/sessions", uploadHandler.CreateUploadSession)
+		r.Get("/upload/sessions/{id}", uploadHandler.GetUploadSession)
+		r.Patch("/upload/sessions/{id}", uploadHandler.PatchUploadSession)
+		r.Delete("/upload/sessions/{id}", uploadHandler.AbortUploadSession)
+		r.Get("/upload/sessions/{id}/parts/{part}/url", uploadHandler.PresignUploadPart)
+		r.Post("/upload/sessions/{id}/complete", uploadHandler.CompleteUploadSession)
 		r.Get("/notifications", notificationsHandler.List)
 		r.Delete("/notifications", notificationsHandler.DeleteAll)
 		r.Get("/notifications/unread-count", notificationsHandler.UnreadCount)
@@ -62,6 +98,22 @@ func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, p
 		r.Get("/ai-chat/messages", aiChatHandler.ListMessages)
 		r.Post("/ai-chat/messages", aiChatHandler.AppendMessage)
 		r.Delete("/ai-chat/messages", aiChatHandler.ResetMessages)
+		r.Get("/ai-chat/models", aiChatHandler.ListModels)
+		r.Get("/ai-chat/usage", aiChatHandler.Usage)
+		r.Get("/ai-chat/prompts", aiChatHandler.ListPrompts)
+		r.Get("/ai-chat/prompts/{persona}/{locale}", aiChatHandler.ListPromptVersions)
+		r.Post("/ai-chat/prompts/{persona}/{locale}", aiChatHandler.CreatePromptVersion)
+		r.Delete("/ai-chat/prompts/{persona}/{locale}", aiChatHandler.ResetPrompt)
+		r.Post("/ai-chat/prompts/{persona}/{locale}/versions/{version}/activate", aiChatHandler.ActivatePromptVersion)
+		r.Get("/ai-chat/conversations", aiChatHandler.ListConversations)
+		r.Post("/ai-chat/conversations", aiChatHandler.CreateConversation)
+		r.Get("/ai-chat/conversations/{id}", aiChatHandler.GetConversation)
+		r.Patch("/ai-chat/conversations/{id}", aiChatHandler.UpdateConversation)
+		r.Delete("/ai-chat/conversations/{id}", aiChatHandler.DeleteConversation)
+		r.Post("/ai-chat/conversations/{id}/messages", aiChatHandler.SendConversationMessage)
+		r.Get("/ai-chat/conversations/{id}/export", aiChatHandler.ExportConversation)
+		r.Post("/ai-chat/conversations/{id}/actions/{actionId}/confirm", aiChatHandler.ConfirmToolAction)
+		r.Post("/ai-chat/conversations/{id}/actions/{actionId}/reject", aiChatHandler.RejectToolAction)
 		r.Post("/chats/presence", chatsHandler.TouchPresence)
 		r.Get("/chats/unread-count", chatsHandler.UnreadCount)
 		r.Get("/chats/users", chatsHandler.ListUsers)
@@ -76,7 +128,12 @@ func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, p
 		r.Post("/zhcp/parse-context", zhcpHandler.ParseContext)
 		r.Post("/zhcp/create-project-from-context", zhcpHandler.CreateProjectFromContext)
 		r.Post("/zhcp/create-task-from-context", zhcpHandler.CreateTaskFromContext)
+		r.Get("/zhcp/jobs/{id}", zhcpHandler.GetJob)
+		r.Get("/zhcp/jobs/{id}/result", zhcpHandler.GetJobResult)
+		r.Get("/zhcp/jobs/{id}/events", zhcpHandler.StreamJob)
 		r.Get("/users", authHandler.ListUsers)
+		r.Get("/me/export", accountHandler.Export)
+		r.Delete("/me", accountHandler.Delete)
 		r.Post("/departments", authHandler.CreateDepartment)
 		r.Get("/departments", authHandler.ListDepartments)
 		r.Route("/projects", func(r chi.Router) {
@@ -95,8 +152,13 @@ func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, p
 			r.Get("/{id}/pages", projectsHandler.ListPages)
 			r.Get("/{id}/pages/{pageId}", projectsHandler.GetPage)
 			r.Patch("/{id}/pages/{pageId}", projectsHandler.UpdatePage)
+			r.Post("/{id}/ai-summary", aiChatHandler.ProjectSummary)
+			r.Post("/{id}/ai-task-suggestions", aiChatHandler.TaskSuggestions)
+			r.Post("/{id}/ai-task-suggestions/accept", aiChatHandler.AcceptTaskSuggestions)
 			r.Post("/{id}/expenses", projectsHandler.CreateExpense)
 			r.Get("/{id}/expenses", projectsHandler.ListExpenses)
+			r.Post("/{id}/meetings", projectsHandler.CreateMeeting)
+			r.Get("/{id}/meetings", projectsHandler.ListMeetings)
 			r.Get("/{id}/members", projectsHandler.ListMembers)
 			r.Patch("/{id}/roles", projectsHandler.UpdateRoles)
 			r.Post("/{id}/members", projectsHandler.UpsertMember)
@@ -104,6 +166,12 @@ func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, p
 			r.With(projectsHandler.RequireEditAccess("id")).Post("/{id}/stages", projectsHandler.CreateStage)
 			r.With(projectsHandler.RequireEditAccess("id")).Delete("/{id}/stages/{stageId}", projectsHandler.DeleteStageInProject)
 			r.Get("/{id}/stages", projectsHandler.ListStages)
+			r.With(projectsHandler.RequireEditAccess("id")).Post("/{id}/zhcp-import", zhcpHandler.ImportResult)
+			r.With(projectsHandler.RequireEditAccess("id")).Post("/{id}/zhcp-import/preview", zhcpHandler.PreviewImport)
+			r.With(projectsHandler.RequireEditAccess("id")).Post("/{id}/zhcp-sync", zhcpHandler.SyncResult)
+			r.With(projectsHandler.RequireEditAccess("id"), limits.Limit("parse", ByUser)).Post("/{id}/zhcp-jobs", zhcpHandler.SubmitDocument)
+			r.With(projectsHandler.RequireEditAccess("id"), limits.Limit("parse", ByUser)).Post("/{id}/files/{fileId}/parse", zhcpHandler.ParseFile)
+			r.Get("/{id}/files/search", projectFilesHandler.Search)
 		})
 		r.Delete("/expenses/{id}", projectsHandler.DeleteExpense)
 		r.Patch("/stages/{id}", projectsHandler.UpdateStage)
@@ -119,21 +187,74 @@ func NewRouter(authHandler *auth.Handler, hierarchyHandler *hierarchy.Handler, p
 		r.Patch("/tasks/{id}", projectsHandler.UpdateTask)
 		r.Delete("/tasks/{id}", projectsHandler.DeleteTask)
 		r.Post("/project-files", projectFilesHandler.Create)
+		r.Get("/project-files", projectFilesHandler.ListByProject)
+		r.Get("/storage/usage", quotaHandler.MyUsage)
+		r.Get("/admin/storage/usage", quotaHandler.AdminUsage)
+		r.Get("/admin/jobs", jobsHandler.List)
+		r.Get("/admin/jobs/{id}", jobsHandler.Get)
+		r.Post("/admin/jobs/{id}/retry", jobsHandler.Retry)
+		r.Get("/admin/webhooks", webhooksHandler.List)
+		r.Post("/admin/webhooks", webhooksHandler.Create)
+		r.Get("/admin/webhooks/{id}", webhooksHandler.Get)
+		r.Patch("/admin/webhooks/{id}", webhooksHandler.Update)
+		r.Delete("/admin/webhooks/{id}", webhooksHandler.Delete)
+		r.Get("/admin/webhooks/{id}/deliveries", webhooksHandler.ListDeliveries)
+		r.Post("/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver", webhooksHandler.Redeliver)
+		r.Get("/admin/retention/rules", retentionHandler.ListRules)
+		r.Patch("/admin/retention/rules/{kind}", retentionHandler.UpdateRule)
+		r.Get("/admin/retention/report", retentionHandler.Report)
+		r.Post("/admin/retention/run", retentionHandler.Run)
+		r.Post("/graphql", graphHandler.Serve)
+		r.Get("/graphql/schema", graphHandler.Schema)
+		r.Get("/search", searchHandler.Search)
+		r.Get("/workspaces", workspacesHandler.List)
+		r.Post("/workspaces", workspacesHandler.Create)
+		r.Get("/workspaces/{id}", workspacesHandler.Get)
+		r.Patch("/workspaces/{id}", workspacesHandler.Update)
+		r.Get("/workspaces/{id}/members", workspacesHandler.ListMembers)
+		r.Post("/workspaces/{id}/members", workspacesHandler.AddMember)
+		r.Patch("/workspaces/{id}/members/{userId}", workspacesHandler.UpdateMember)
+		r.Delete("/workspaces/{id}/members/{userId}", workspacesHandler.RemoveMember)
+		r.Get("/project-files/trash", projectFilesHandler.ListTrash)
+		r.Get("/project-files/{id}", projectFilesHandler.Get)
+		r.Delete("/project-files/{id}", projectFilesHandler.Delete)
+		r.Post("/project-files/{id}/restore", projectFilesHandler.RestoreFromTrash)
+		r.Patch("/project-files/{id}/visibility", projectFilesHandler.UpdateVisibility)
+		r.Get("/project-files/{id}/download", projectFilesHandler.DownloadVersion)
+		r.Get("/project-files/{id}/parse-jobs", zhcpHandler.ListFileJobs)
+		r.Get("/project-files/{id}/versions", projectFilesHandler.ListVersions)
+		r.Get("/project-files/{id}/versions/{version}/download", projectFilesHandler.DownloadVersion)
+		r.Post("/project-files/{id}/versions/{version}/restore", projectFilesHandler.RestoreVersion)
+		r.Get("/project-files/{id}/comments", projectFilesHandler.ListComments)
+		r.Post("/project-files/{id}/comments", projectFilesHandler.CreateComment)
+		r.Delete("/project-files/{id}/comments/{commentId}", projectFilesHandler.DeleteComment)
 		r.Get("/documents", projectFilesHandler.ListDocuments)
+		r.Get("/files/{id}/download", filesHandler.Download)
+		r.Get("/files/{id}/url", filesHandler.SignedURL)
+		r.Post("/files/signed-urls", filesHandler.SignURLs)
 		r.Get("/workspace/context", projectsHandler.WorkspaceContext)
 		r.Get("/users/{id}", authHandler.GetUserProfile)
 		r.Patch("/users/{id}/profile", authHandler.UpdateUserProfile)
 		r.Put("/users/{id}/hierarchy", authHandler.UpdateUserHierarchy)
 		r.Get("/users/{id}/manager", authHandler.GetUserManager)
 		r.Get("/users/{id}/subordinates", authHandler.GetUserSubordinates)
+		r.Get("/users/{id}/management-chain", hierarchyHandler.GetManagementChain)
 		r.Get("/hierarchy", authHandler.GetHierarchy)
 		r.Get("/hierarchy/tree", hierarchyHandler.GetTree)
 		r.Patch("/hierarchy/assign-user", hierarchyHandler.AssignUser)
 		r.Post("/hierarchy/nodes", hierarchyHandler.CreateNode)
+		r.Post("/hierarchy/nodes/reorder", hierarchyHandler.ReorderNodes)
 		r.Patch("/hierarchy/nodes/{id}", hierarchyHandler.UpdateNode)
 		r.Delete("/hierarchy/nodes/{id}", hierarchyHandler.DeleteNode)
 		r.Patch("/hierarchy/nodes/{id}/status", hierarchyHandler.UpdateStatus)
+		r.Patch("/hierarchy/nodes/{id}/head", hierarchyHandler.SetDepartmentHead)
+		r.Get("/hierarchy/workload", hierarchyHandler.GetWorkload)
+		r.Patch("/hierarchy/roles/{id}/permissions", hierarchyHandler.UpdateRolePermissions)
 	})
 
+	r.Mount(APIPrefix, api)
+	// Clients written before APIPrefix call the same routes without it
+	r.Mount("/", legacyPaths(api))
+
 	return r
 }
@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiContentPolicy keeps JSON responses from being rendered or framed as a
+// page should a browser be led to open one.
+const apiContentPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// SecurityHeaders sets the headers every response carries: nosniff, a
+// referrer policy and, on HTTPS requests, Strict-Transport-Security for
+// hstsMaxAge (0 leaves it out). Requests count as HTTPS when they arrived
+// over TLS or the proxy in front says so in X-Forwarded-Proto.
+func SecurityHeaders(hstsMaxAge time.Duration) func(http.Handler) http.Handler {
+	hsts := "max-age=" + strconv.FormatInt(int64(hstsMaxAge/time.Second), 10)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			if hstsMaxAge > 0 && isHTTPS(r) {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// contentPolicy sets the Content-Security-Policy of the API. Handlers that
+// serve stored files replace it with the policy of the file.
+func contentPolicy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", apiContentPolicy)
+		w.Header().Set("X-Frame-Options", "DENY")
+		next.ServeHTTP(w, r)
+	})
+}
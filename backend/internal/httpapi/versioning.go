@@ -0,0 +1,25 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// legacyPaths serves the unversioned paths clients used before APIPrefix,
+// with the same routes, middleware and rate limits as APIPrefix. Answers
+// to known routes carry Deprecation and a Link to the versioned path so
+// clients can find and move off them.
+func legacyPaths(api *chi.Mux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.Match(chi.NewRouteContext(), r.Method, r.URL.Path) {
+			successor := APIPrefix + r.URL.EscapedPath()
+			if r.URL.RawQuery != "" {
+				successor += "?" + r.URL.RawQuery
+			}
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		api.ServeHTTP(w, r)
+	})
+}
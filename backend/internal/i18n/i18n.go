@@ -0,0 +1,163 @@
+// Package i18n translates the texts the backend generates, such as
+// notifications and error details, into Russian, Kazakh or English. Texts
+// are looked up by key in the message catalogs under catalog/, falling back
+// to Russian and then to the key itself.
+//
+// Catalog entries refer to arguments as {name}. {name:prefix} translates
+// the argument as the key prefix.name instead, for codes such as roles:
+//
+//	"notification.member_added.body": "Вам назначена роль: {role:role}"
+//	"role.manager": "Менеджер"
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Locale string
+
+const (
+	Russian Locale = "ru"
+	Kazakh  Locale = "kk"
+	English Locale = "en"
+
+	// Default is the locale of users who have not chosen one and whose
+	// requests do not say which they prefer.
+	Default = Russian
+)
+
+// Supported lists the locales with a catalog.
+var Supported = []Locale{Russian, Kazakh, English}
+
+//go:embed catalog/*.json
+var catalogFiles embed.FS
+
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[Locale]map[string]string {
+	loaded := make(map[Locale]map[string]string, len(Supported))
+	for _, locale := range Supported {
+		raw, err := catalogFiles.ReadFile("catalog/" + string(locale) + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: read %s catalog: %v", locale, err))
+		}
+		entries := make(map[string]string)
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			panic(fmt.Sprintf("i18n: parse %s catalog: %v", locale, err))
+		}
+		loaded[locale] = entries
+	}
+	return loaded
+}
+
+// Parse returns the supported locale of a language tag such as "kk" or
+// "ru-RU".
+func Parse(tag string) (Locale, bool) {
+	language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	language, _, _ = strings.Cut(language, "_")
+	if language == "kz" {
+		language = string(Kazakh)
+	}
+	locale := Locale(language)
+	_, ok := catalogs[locale]
+	return locale, ok
+}
+
+// Lookup returns the entry of key in the catalog of locale, or of the
+// default locale.
+func Lookup(locale Locale, key string) (string, bool) {
+	if text, ok := catalogs[locale][key]; ok {
+		return text, true
+	}
+	text, ok := catalogs[Default][key]
+	return text, ok
+}
+
+var placeholder = regexp.MustCompile(`\{([A-Za-z0-9_]+)(?::([A-Za-z0-9_.]+))?\}`)
+
+// T translates key into locale, filling in args.
+func T(locale Locale, key string, args map[string]string) string {
+	text, ok := Lookup(locale, key)
+	if !ok {
+		return key
+	}
+	return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		parts := placeholder.FindStringSubmatch(match)
+		value, ok := args[parts[1]]
+		if !ok {
+			return match
+		}
+		if parts[2] != "" {
+			if translated, ok := Lookup(locale, parts[2]+"."+value); ok {
+				return translated
+			}
+		}
+		return value
+	})
+}
+
+// Message is a text to translate when it is shown, or a literal Text, such
+// as the preview of a chat message, shown as is.
+type Message struct {
+	Key  string            `json:"key,omitempty"`
+	Args map[string]string `json:"args,omitempty"`
+	Text string            `json:"text,omitempty"`
+}
+
+// M is the message of key with args given as name, value pairs.
+func M(key string, args ...string) Message {
+	message := Message{Key: key}
+	if len(args) > 0 {
+		message.Args = make(map[string]string, len(args)/2)
+		for i := 0; i+1 < len(args); i += 2 {
+			message.Args[args[i]] = args[i+1]
+		}
+	}
+	return message
+}
+
+// Text is a message shown as is in every locale.
+func Text(text string) Message {
+	return Message{Text: text}
+}
+
+// Translated reports whether m is looked up in the catalogs.
+func (m Message) Translated() bool {
+	return m.Key != ""
+}
+
+func (m Message) Render(locale Locale) string {
+	if m.Key == "" {
+		return m.Text
+	}
+	return T(locale, m.Key, m.Args)
+}
+
+// MarshalJSON encodes a literal message as a plain string.
+func (m Message) MarshalJSON() ([]byte, error) {
+	if m.Key == "" {
+		return json.Marshal(m.Text)
+	}
+	type message Message
+	return json.Marshal(message(m))
+}
+
+// UnmarshalJSON accepts plain strings as literal messages.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*m = Message{Text: text}
+		return nil
+	}
+	type message Message
+	var decoded message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*m = Message(decoded)
+	return nil
+}
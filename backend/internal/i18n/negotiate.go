@@ -0,0 +1,93 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithLocale returns a copy of ctx carrying locale.
+func WithLocale(ctx context.Context, locale Locale) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale negotiated for a request, or Default.
+func FromContext(ctx context.Context) Locale {
+	if locale, ok := ctx.Value(contextKey{}).(Locale); ok {
+		return locale
+	}
+	return Default
+}
+
+// FromAcceptLanguage returns the supported locale the Accept-Language
+// header prefers most.
+func FromAcceptLanguage(header string) (Locale, bool) {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if tag != "" && quality > 0 {
+			candidates = append(candidates, candidate{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	for _, c := range candidates {
+		if locale, ok := Parse(c.tag); ok {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Middleware negotiates the locale of each request: the one profile returns
+// for the requester, else the one Accept-Language prefers. It is put in the
+// request context and announced in Content-Language, which also makes
+// problem details translated. profile may be nil, and a later Middleware
+// overrides an earlier one, so the router can negotiate from the header
+// for every request and from the profile once the requester is known.
+func Middleware(profile func(r *http.Request) (Locale, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale, ok := Locale(""), false
+			if profile != nil {
+				locale, ok = profile(r)
+			}
+			if !ok {
+				locale, ok = FromAcceptLanguage(r.Header.Get("Accept-Language"))
+			}
+			if ok {
+				w.Header().Set("Content-Language", string(locale))
+				addVary(w.Header(), "Accept-Language")
+				r = r.WithContext(WithLocale(r.Context(), locale))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
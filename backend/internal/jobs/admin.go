@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, dedupe_key, last_error, created_at, updated_at, finished_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (Job, error) {
+	var job Job
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&job.Payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.DedupeKey,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	return job, err
+}
+
+// listKey is the sort key of List
+type listKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// List returns jobs newest first, only those with status and kind when they
+// are set.
+func (q *Queue) List(ctx context.Context, status Status, kind string, page pagination.Page) (pagination.List[Job], error) {
+	var key listKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[Job]{}, err
+	}
+
+	query := `SELECT ` + jobColumns + `, COUNT(*) OVER ()
+		FROM background_jobs
+		WHERE TRUE`
+	args := []any{page.Fetch()}
+	if status != "" {
+		args = append(args, string(status))
+		query += fmt.Sprintf(` AND status = $%d`, len(args))
+	}
+	if kind = strings.TrimSpace(kind); kind != "" {
+		args = append(args, kind)
+		query += fmt.Sprintf(` AND kind = $%d`, len(args))
+	}
+	if after {
+		args = append(args, key.CreatedAt, key.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT $1`
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return pagination.List[Job]{}, err
+	}
+	defer rows.Close()
+
+	var total int
+	items := make([]Job, 0)
+	for rows.Next() {
+		job, err := scanJob(pagination.Scanner{Rows: rows, Dest: &total})
+		if err != nil {
+			return pagination.List[Job]{}, err
+		}
+		items = append(items, job)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.List[Job]{}, err
+	}
+
+	list, err := pagination.Cut(items, page, func(i int) any {
+		return listKey{CreatedAt: items[i].CreatedAt, ID: items[i].ID}
+	})
+	if err != nil {
+		return pagination.List[Job]{}, err
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
+}
+
+func (q *Queue) Get(ctx context.Context, id uuid.UUID) (Job, error) {
+	job, err := scanJob(q.db.QueryRowContext(
+		ctx,
+		`SELECT `+jobColumns+` FROM background_jobs WHERE id = $1`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+	return job, err
+}
+
+// Retry queues a failed job again with a fresh set of attempts.
+func (q *Queue) Retry(ctx context.Context, id uuid.UUID) (Job, error) {
+	job, err := scanJob(q.db.QueryRowContext(
+		ctx,
+		`UPDATE background_jobs
+		 SET status = 'queued', attempts = 0, run_at = now(), finished_at = NULL, updated_at = now()
+		 WHERE id = $1 AND status = 'failed'
+		 RETURNING `+jobColumns,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, getErr := q.Get(ctx, id); getErr != nil {
+			return Job{}, getErr
+		}
+		return Job{}, ErrNotFailed
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
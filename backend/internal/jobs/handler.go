@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/problem"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminFunc reports whether a user may inspect and retry jobs.
+type AdminFunc func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+// Handler serves the admin view of the queue.
+type Handler struct {
+	queue   *Queue
+	isAdmin AdminFunc
+}
+
+func NewHandler(queue *Queue, isAdmin AdminFunc) *Handler {
+	return &Handler{queue: queue, isAdmin: isAdmin}
+}
+
+// listPageLimits bound the pages of List
+var listPageLimits = pagination.Limits{Default: 50, Max: 200}
+
+// List lists jobs newest first, filtered by ?status= and ?kind=; admins
+// look at ?status=failed.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	status := Status(strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status"))))
+	switch status {
+	case "", StatusQueued, StatusRunning, StatusDone, StatusFailed:
+	default:
+		problem.Write(w, http.StatusBadRequest, "invalid_status", "status must be queued, running, done or failed")
+		return
+	}
+	page, err := pagination.Parse(r, listPageLimits)
+	if err != nil {
+		pagination.WriteError(w, err)
+		return
+	}
+
+	jobs, err := h.queue.List(r.Context(), status, r.URL.Query().Get("kind"), page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
+		log.Printf("list jobs failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+
+	pagination.SetHeaders(w, r, jobs)
+	writeJSON(w, http.StatusOK, jobs.Items)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	jobID, ok := jobIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.queue.Get(r.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "job not found")
+			return
+		}
+		log.Printf("get job failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load job")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// Retry queues a failed job again.
+func (h *Handler) Retry(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	jobID, ok := jobIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.queue.Retry(r.Context(), jobID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "job not found")
+		case errors.Is(err, ErrNotFailed):
+			problem.Write(w, http.StatusConflict, "job_not_failed", "only failed jobs can be retried")
+		default:
+			log.Printf("retry job failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to retry job")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// authorize answers the request itself unless the requester is an admin.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return false
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return false
+	}
+
+	allowed, err := h.isAdmin(r.Context(), userID)
+	if err != nil {
+		log.Printf("check jobs admin failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check access")
+		return false
+	}
+	if !allowed {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return false
+	}
+	return true
+}
+
+func jobIDFromRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	jobID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_job_id", "invalid job id")
+		return uuid.Nil, false
+	}
+	return jobID, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,511 @@
+// Package jobs runs deferred work from a queue kept in Postgres, so queued
+// work survives restarts and is shared by all replicas. Packages register a
+// handler per job kind and enqueue JSON payloads for it:
+//
+//	queue.Register("notifications.fanout", fanout)
+//	queue.Enqueue(ctx, "notifications.fanout", payload)
+//
+// Workers claim due jobs with FOR UPDATE SKIP LOCKED. A failing job is
+// retried with exponential backoff until it runs out of attempts and stays
+// failed, listed for admins, until someone retries it.
+//
+// On shutdown, Drain stops claiming jobs and lets running ones finish; jobs
+// still running at its deadline are cancelled and queued again at once,
+// without using up an attempt, so another replica picks them up.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+var (
+	ErrNotFound   = errors.New("job not found")
+	ErrNotFailed  = errors.New("job has not failed")
+	ErrNoHandler  = errors.New("no handler registered for job kind")
+	ErrEmptyKind  = errors.New("job kind is required")
+	errNoDueJobs  = errors.New("no due jobs")
+	errJobPanic   = errors.New("job panicked")
+	errJobTimeout = errors.New("job timed out")
+)
+
+const (
+	defaultMaxAttempts = 5
+	pollInterval       = 5 * time.Second
+	maintainInterval   = time.Minute
+	jobTimeout         = 5 * time.Minute
+	// running jobs whose worker has not finished them within leaseTimeout
+	// belong to a replica that died and are queued again
+	leaseTimeout = 2 * jobTimeout
+	retryBase    = 30 * time.Second
+	retryMax     = time.Hour
+	doneRetained = 7 * 24 * time.Hour
+	// how long Drain waits for cancelled jobs to return and be queued again
+	releaseTimeout = 5 * time.Second
+)
+
+// Job is a queued unit of work.
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      Status          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	RunAt       time.Time       `json:"runAt"`
+	DedupeKey   *string         `json:"dedupeKey,omitempty"`
+	LastError   *string         `json:"lastError,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// HandlerFunc runs a job with its payload. A returned error schedules a
+// retry. ctx is cancelled when the job times out or the server shuts down
+// before it finishes; the job then runs again, so work it committed must be
+// safe to repeat.
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+type recurring struct {
+	kind     string
+	interval time.Duration
+}
+
+// Queue enqueues jobs and runs the registered handlers.
+type Queue struct {
+	db   *sql.DB
+	wake chan struct{}
+
+	mu        sync.RWMutex
+	handlers  map[string]HandlerFunc
+	recurring []recurring
+
+	// stop is closed by Drain; workers finish their job and return
+	stop       chan struct{}
+	stopOnce   sync.Once
+	workers    sync.WaitGroup
+	cancelJobs context.CancelFunc
+}
+
+func NewQueue(db *sql.DB) *Queue {
+	return &Queue{
+		db:       db,
+		wake:     make(chan struct{}, 1),
+		handlers: make(map[string]HandlerFunc),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register sets the handler of kind. Register before Start.
+func (q *Queue) Register(kind string, handler HandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+// Every enqueues a job of kind once per interval, for periodic work such as
+// digests and reminders. Replicas share the schedule: each interval slot is
+// enqueued once however many replicas run.
+func (q *Queue) Every(kind string, interval time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.recurring = append(q.recurring, recurring{kind: kind, interval: interval})
+}
+
+type enqueueOptions struct {
+	runAt       time.Time
+	maxAttempts int
+	dedupeKey   string
+}
+
+// EnqueueOption adjusts a job passed to Enqueue.
+type EnqueueOption func(*enqueueOptions)
+
+// At runs the job no earlier than t.
+func At(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) { o.runAt = t }
+}
+
+// After runs the job no earlier than d from now.
+func After(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) { o.runAt = time.Now().Add(d) }
+}
+
+// MaxAttempts bounds how often the job runs before it is marked failed.
+func MaxAttempts(n int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		if n > 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// Unique drops the job when one with the same key was already enqueued and
+// has not been purged yet.
+func Unique(key string) EnqueueOption {
+	return func(o *enqueueOptions) { o.dedupeKey = strings.TrimSpace(key) }
+}
+
+// Enqueue stores a job of kind with payload encoded as JSON. It reports
+// false when Unique dropped the job.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (bool, error) {
+	kind = strings.TrimSpace(kind)
+	if kind == "" {
+		return false, ErrEmptyKind
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("encode %s payload: %w", kind, err)
+	}
+
+	options := enqueueOptions{runAt: time.Now(), maxAttempts: defaultMaxAttempts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var dedupeKey *string
+	if options.dedupeKey != "" {
+		dedupeKey = &options.dedupeKey
+	}
+
+	result, err := q.db.ExecContext(
+		ctx,
+		`INSERT INTO background_jobs (kind, payload, max_attempts, run_at, dedupe_key)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING`,
+		kind,
+		raw,
+		options.maxAttempts,
+		options.runAt.UTC(),
+		dedupeKey,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if !options.runAt.After(time.Now()) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return true, nil
+}
+
+// Start runs n workers, and the scheduler of recurring jobs, until ctx is
+// cancelled or Drain is called.
+func (q *Queue) Start(ctx context.Context, n int) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	q.mu.Lock()
+	q.cancelJobs = cancel
+	q.mu.Unlock()
+
+	for i := 0; i < max(1, n); i++ {
+		q.workers.Add(1)
+		go q.work(ctx, jobCtx)
+	}
+	go q.maintain(ctx)
+}
+
+// Drain stops claiming jobs and waits for the running ones to finish. When
+// ctx is done first, the running jobs are cancelled and queued again, and
+// Drain returns ctx.Err().
+func (q *Queue) Drain(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.RLock()
+	cancel := q.cancelJobs
+	q.mu.RUnlock()
+	if cancel != nil {
+		cancel()
+	}
+	select {
+	case <-done:
+	case <-time.After(releaseTimeout):
+		log.Printf("jobs still running after drain; the lease will queue them again")
+	}
+	return ctx.Err()
+}
+
+func (q *Queue) stopping() bool {
+	select {
+	case <-q.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// work claims jobs until ctx is cancelled or the queue drains, running them
+// with jobCtx.
+func (q *Queue) work(ctx, jobCtx context.Context) {
+	defer q.workers.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.stop:
+			return
+		case <-timer.C:
+		case <-q.wake:
+		}
+
+		for ctx.Err() == nil && !q.stopping() {
+			job, err := q.claim(ctx)
+			if errors.Is(err, errNoDueJobs) {
+				break
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("claim job failed: %v", err)
+				}
+				break
+			}
+			q.run(jobCtx, job)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(pollInterval)
+	}
+}
+
+// claim marks the next due job running and returns it.
+func (q *Queue) claim(ctx context.Context) (Job, error) {
+	var job Job
+	err := q.db.QueryRowContext(
+		ctx,
+		`UPDATE background_jobs
+		 SET status = 'running', attempts = attempts + 1, locked_at = now(), updated_at = now()
+		 WHERE id = (
+		 	SELECT id FROM background_jobs
+		 	WHERE status = 'queued' AND run_at <= now()
+		 	ORDER BY run_at
+		 	LIMIT 1
+		 	FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING id, kind, payload, attempts, max_attempts`,
+	).Scan(&job.ID, &job.Kind, &job.Payload, &job.Attempts, &job.MaxAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, errNoDueJobs
+	}
+	return job, err
+}
+
+func (q *Queue) run(ctx context.Context, job Job) {
+	q.mu.RLock()
+	handler := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	var err error
+	if handler == nil {
+		err = fmt.Errorf("%w: %s", ErrNoHandler, job.Kind)
+	} else {
+		err = q.call(ctx, handler, job)
+	}
+
+	// the job ran, so record its outcome even when ctx was cancelled since
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if ctx.Err() != nil && err != nil {
+		// shutting down: the job was cut short rather than failing
+		if releaseErr := q.release(finishCtx, job); releaseErr != nil {
+			log.Printf("release job %s failed: %v", job.ID, releaseErr)
+		}
+		return
+	}
+	if err == nil {
+		err = q.finish(finishCtx, job.ID)
+		if err != nil {
+			log.Printf("finish job %s failed: %v", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("job %s (%s) attempt %d/%d failed: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, err)
+	if retryErr := q.fail(finishCtx, job, err); retryErr != nil {
+		log.Printf("record job %s failure failed: %v", job.ID, retryErr)
+	}
+}
+
+func (q *Queue) call(ctx context.Context, handler HandlerFunc, job Job) (err error) {
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%w: %v", errJobPanic, recovered)
+		}
+	}()
+
+	err = handler(jobCtx, job.Payload)
+	if err != nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %v", errJobTimeout, err)
+	}
+	return err
+}
+
+func (q *Queue) finish(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(
+		ctx,
+		`UPDATE background_jobs
+		 SET status = 'done', locked_at = NULL, last_error = NULL, finished_at = now(), updated_at = now()
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+// fail queues the job again after its backoff, or marks it failed when it
+// has no attempts left.
+func (q *Queue) fail(ctx context.Context, job Job, cause error) error {
+	if job.Attempts >= job.MaxAttempts {
+		_, err := q.db.ExecContext(
+			ctx,
+			`UPDATE background_jobs
+			 SET status = 'failed', locked_at = NULL, last_error = $2, finished_at = now(), updated_at = now()
+			 WHERE id = $1`,
+			job.ID,
+			cause.Error(),
+		)
+		return err
+	}
+
+	_, err := q.db.ExecContext(
+		ctx,
+		`UPDATE background_jobs
+		 SET status = 'queued', locked_at = NULL, last_error = $2, run_at = $3, updated_at = now()
+		 WHERE id = $1`,
+		job.ID,
+		cause.Error(),
+		time.Now().Add(Backoff(job.Attempts)).UTC(),
+	)
+	return err
+}
+
+// release queues a job interrupted by shutdown again to run right away,
+// giving back the attempt it was claimed with. A release that fails leaves
+// the job to the lease.
+func (q *Queue) release(ctx context.Context, job Job) error {
+	_, err := q.db.ExecContext(
+		ctx,
+		`UPDATE background_jobs
+		 SET status = 'queued', attempts = GREATEST(attempts - 1, 0), locked_at = NULL, run_at = now(), updated_at = now()
+		 WHERE id = $1 AND status = 'running'`,
+		job.ID,
+	)
+	return err
+}
+
+// Backoff is the delay before the retry that follows attempt: 30s doubling
+// per attempt, capped at an hour.
+func Backoff(attempt int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempt && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
+
+// maintain enqueues recurring jobs, requeues jobs of dead workers and purges
+// old finished jobs.
+func (q *Queue) maintain(ctx context.Context) {
+	ticker := time.NewTicker(maintainInterval)
+	defer ticker.Stop()
+
+	for {
+		q.schedule(ctx)
+		if err := q.reclaim(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("reclaim stale jobs failed: %v", err)
+		}
+		if err := q.purge(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("purge finished jobs failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) schedule(ctx context.Context) {
+	q.mu.RLock()
+	scheduled := append([]recurring(nil), q.recurring...)
+	q.mu.RUnlock()
+
+	now := time.Now().UTC()
+	for _, job := range scheduled {
+		slot := now.Truncate(job.interval)
+		key := fmt.Sprintf("every:%s:%d", job.kind, slot.Unix())
+		if _, err := q.Enqueue(ctx, job.kind, struct{}{}, At(slot), MaxAttempts(1), Unique(key)); err != nil && ctx.Err() == nil {
+			log.Printf("schedule %s failed: %v", job.kind, err)
+		}
+	}
+}
+
+func (q *Queue) reclaim(ctx context.Context) error {
+	_, err := q.db.ExecContext(
+		ctx,
+		`UPDATE background_jobs
+		 SET status = 'queued', locked_at = NULL, updated_at = now()
+		 WHERE status = 'running' AND locked_at < $1`,
+		time.Now().Add(-leaseTimeout).UTC(),
+	)
+	return err
+}
+
+func (q *Queue) purge(ctx context.Context) error {
+	_, err := q.db.ExecContext(
+		ctx,
+		`DELETE FROM background_jobs WHERE status = 'done' AND finished_at < $1`,
+		time.Now().Add(-doneRetained).UTC(),
+	)
+	return err
+}
@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/admin/jobs", Tag: "admin", Summary: "List background jobs, newest first (admins only)", Query: []openapi.Param{
+		{Name: "status", Kind: "string", Description: "queued, running, done or failed"},
+		{Name: "kind", Kind: "string", Description: "Only jobs of this kind"},
+	}, Response: Job{}, List: true, Page: &listPageLimits},
+	{Method: http.MethodGet, Path: "/admin/jobs/{id}", Tag: "admin", Summary: "Get a background job (admins only)", Response: Job{}},
+	{Method: http.MethodPost, Path: "/admin/jobs/{id}/retry", Tag: "admin", Summary: "Queue a failed background job again (admins only)", Response: Job{}},
+}
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ModelInfo is a model users may choose for a conversation.
+type ModelInfo struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Default  bool   `json:"default"`
+}
+
+// Catalog routes completions to the configured providers. Only allowlisted
+// provider/model pairs are used; anything else falls back to the default.
+type Catalog struct {
+	providers       map[string]catalogProvider
+	order           []string
+	defaultProvider string
+	defaultModel    string
+}
+
+type catalogProvider struct {
+	client Client
+	models []string
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{providers: make(map[string]catalogProvider)}
+}
+
+// Add makes a provider and its models available. The first provider added
+// becomes the default unless SetDefault is called.
+func (c *Catalog) Add(name string, client Client, models []string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || client == nil {
+		return errors.New("provider name and client are required")
+	}
+	if _, exists := c.providers[name]; exists {
+		return fmt.Errorf("provider %s added twice", name)
+	}
+
+	allowed := make([]string, 0, len(models))
+	for _, model := range models {
+		if model = strings.TrimSpace(model); model != "" {
+			allowed = append(allowed, model)
+		}
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("provider %s has no models", name)
+	}
+
+	c.providers[name] = catalogProvider{client: client, models: allowed}
+	c.order = append(c.order, name)
+	if c.defaultProvider == "" {
+		c.defaultProvider, c.defaultModel = name, allowed[0]
+	}
+	return nil
+}
+
+// SetDefault chooses the model used when a conversation has none or its
+// choice is no longer allowed. An empty model picks the provider's first one.
+func (c *Catalog) SetDefault(provider, model string) error {
+	entry, ok := c.providers[provider]
+	if !ok {
+		return fmt.Errorf("default provider %s is not configured", provider)
+	}
+	if model == "" {
+		model = entry.models[0]
+	}
+	if !c.Allowed(provider, model) {
+		return fmt.Errorf("default model %s is not allowed for %s", model, provider)
+	}
+	c.defaultProvider, c.defaultModel = provider, model
+	return nil
+}
+
+func (c *Catalog) Empty() bool {
+	return len(c.providers) == 0
+}
+
+// Allowed reports whether provider/model is on the allowlist.
+func (c *Catalog) Allowed(provider, model string) bool {
+	entry, ok := c.providers[provider]
+	if !ok {
+		return false
+	}
+	for _, allowed := range entry.models {
+		if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the provider and model to use for a requested pair,
+// falling back to the default for empty or disallowed choices.
+func (c *Catalog) Resolve(provider, model string) (string, string) {
+	if c.Allowed(provider, model) {
+		return provider, model
+	}
+	return c.defaultProvider, c.defaultModel
+}
+
+// Models lists the allowlisted models in configuration order.
+func (c *Catalog) Models() []ModelInfo {
+	models := make([]ModelInfo, 0)
+	for _, name := range c.order {
+		for _, model := range c.providers[name].models {
+			models = append(models, ModelInfo{
+				Provider: name,
+				Model:    model,
+				Default:  name == c.defaultProvider && model == c.defaultModel,
+			})
+		}
+	}
+	return models
+}
+
+// Complete sends req to the provider and model it names, resolved against
+// the allowlist.
+func (c *Catalog) Complete(ctx context.Context, req Request) (Response, error) {
+	if c.Empty() {
+		return Response{}, ErrNotConfigured
+	}
+
+	provider, model := c.Resolve(req.Provider, req.Model)
+	req.Provider, req.Model = provider, model
+
+	response, err := c.providers[provider].client.Complete(ctx, req)
+	if err != nil {
+		return Response{}, err
+	}
+	response.Provider = provider
+	if response.Model == "" {
+		response.Model = model
+	}
+	return response, nil
+}
@@ -0,0 +1,79 @@
+// Package llm talks to chat-completion models used by the AI assistant.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	// RoleTool carries the result of a tool call back to the model.
+	RoleTool Role = "tool"
+)
+
+var ErrNotConfigured = errors.New("llm provider is not configured")
+
+type Message struct {
+	Role    Role
+	Content string
+	// ToolCalls are the calls an assistant message asked for.
+	ToolCalls []ToolCall
+	// ToolCallID links a RoleTool message to the call it answers.
+	ToolCallID string
+}
+
+// Tool is a function the model may call. Parameters is a JSON Schema object.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// ToolCall is a call the model asked for; Arguments is a JSON object.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+type Request struct {
+	// Provider picks a provider when the client is a Catalog.
+	Provider string
+	// Model overrides the client's default model when set.
+	Model       string
+	Messages    []Message
+	Temperature float64
+	MaxTokens   int
+	// Tools the model may call instead of answering.
+	Tools []Tool
+}
+
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type Response struct {
+	Content   string
+	ToolCalls []ToolCall
+	Provider  string
+	Model     string
+	Usage     Usage
+}
+
+// Client produces a completion for a conversation.
+type Client interface {
+	Complete(ctx context.Context, req Request) (Response, error)
+}
+
+// Embedder turns texts into vectors for semantic search. The result has one
+// vector per input, in the same order.
+type Embedder interface {
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+}
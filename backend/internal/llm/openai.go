@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// openAIAPI holds what the OpenAI-compatible clients share: the endpoint,
+// credentials and the HTTP client.
+type openAIAPI struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newOpenAIAPI(baseURL, apiKey string, timeout time.Duration) openAIAPI {
+	trimmed := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if trimmed == "" {
+		trimmed = defaultOpenAIBaseURL
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+
+	return openAIAPI{
+		baseURL:    trimmed,
+		apiKey:     strings.TrimSpace(apiKey),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type openAIError struct {
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// post sends payload to path and decodes a successful response into out.
+func (a openAIAPI) post(ctx context.Context, path string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr openAIError
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error != nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("llm returned status %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("llm returned status %d: %s", resp.StatusCode, snippet(raw))
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode llm response: %w: %s", err, snippet(raw))
+	}
+	return nil
+}
+
+func snippet(raw []byte) string {
+	value := strings.TrimSpace(string(raw))
+	if len(value) > 300 {
+		value = value[:300]
+	}
+	return value
+}
+
+// OpenAIClient calls any OpenAI-compatible /chat/completions endpoint
+// (OpenAI, DeepSeek, Ollama, vLLM, ...).
+type OpenAIClient struct {
+	api   openAIAPI
+	model string
+}
+
+func NewOpenAIClient(baseURL, apiKey, model string, timeout time.Duration) *OpenAIClient {
+	return &OpenAIClient{
+		api:   newOpenAIAPI(baseURL, apiKey, timeout),
+		model: strings.TrimSpace(model),
+	}
+}
+
+type chatCompletionMessage struct {
+	Role       string               `json:"role"`
+	Content    string               `json:"content"`
+	ToolCalls  []chatCompletionCall `json:"tool_calls,omitempty"`
+	ToolCallID string               `json:"tool_call_id,omitempty"`
+}
+
+type chatCompletionCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatCompletionTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type chatCompletionRequest struct {
+	Model       string                  `json:"model"`
+	Messages    []chatCompletionMessage `json:"messages"`
+	Tools       []chatCompletionTool    `json:"tools,omitempty"`
+	Temperature *float64                `json:"temperature,omitempty"`
+	MaxTokens   int                     `json:"max_tokens,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message chatCompletionMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *OpenAIClient) Complete(ctx context.Context, req Request) (Response, error) {
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		model = c.model
+	}
+	if model == "" {
+		return Response{}, ErrNotConfigured
+	}
+
+	payload := chatCompletionRequest{Model: model, MaxTokens: req.MaxTokens}
+	if req.Temperature > 0 {
+		temperature := req.Temperature
+		payload.Temperature = &temperature
+	}
+	for _, message := range req.Messages {
+		encoded := chatCompletionMessage{Role: string(message.Role), Content: message.Content, ToolCallID: message.ToolCallID}
+		for _, call := range message.ToolCalls {
+			wireCall := chatCompletionCall{ID: call.ID, Type: "function"}
+			wireCall.Function.Name = call.Name
+			wireCall.Function.Arguments = call.Arguments
+			encoded.ToolCalls = append(encoded.ToolCalls, wireCall)
+		}
+		payload.Messages = append(payload.Messages, encoded)
+	}
+	for _, tool := range req.Tools {
+		wireTool := chatCompletionTool{Type: "function"}
+		wireTool.Function.Name = tool.Name
+		wireTool.Function.Description = tool.Description
+		wireTool.Function.Parameters = tool.Parameters
+		payload.Tools = append(payload.Tools, wireTool)
+	}
+
+	var decoded chatCompletionResponse
+	if err := c.api.post(ctx, "/chat/completions", payload, &decoded); err != nil {
+		return Response{}, err
+	}
+	if len(decoded.Choices) == 0 {
+		return Response{}, fmt.Errorf("llm returned no choices")
+	}
+
+	result := Response{
+		Content: strings.TrimSpace(decoded.Choices[0].Message.Content),
+		Model:   decoded.Model,
+		Usage: Usage{
+			PromptTokens:     decoded.Usage.PromptTokens,
+			CompletionTokens: decoded.Usage.CompletionTokens,
+		},
+	}
+	for _, call := range decoded.Choices[0].Message.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	if result.Model == "" {
+		result.Model = model
+	}
+	return result, nil
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type OpenAIEmbedder struct {
+	api   openAIAPI
+	model string
+}
+
+func NewOpenAIEmbedder(baseURL, apiKey, model string, timeout time.Duration) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		api:   newOpenAIAPI(baseURL, apiKey, timeout),
+		model: strings.TrimSpace(model),
+	}
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	if e.model == "" {
+		return nil, ErrNotConfigured
+	}
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	var decoded embeddingsResponse
+	if err := e.api.post(ctx, "/embeddings", embeddingsRequest{Model: e.model, Input: inputs}, &decoded); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, item := range decoded.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("llm returned embedding for unknown input %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("llm returned no embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProviderConfig configures one LLM provider.
+type ProviderConfig struct {
+	BaseURL string
+	APIKey  string
+	// Models lists the models users may pick; the first one is the
+	// provider's default.
+	Models  []string
+	Timeout time.Duration
+}
+
+// ProviderConstructor is a function type for creating providers
+type ProviderConstructor func(config ProviderConfig) (Client, error)
+
+// providerRegistry holds constructors for different provider types
+var providerRegistry = make(map[string]ProviderConstructor)
+
+// RegisterProvider registers a provider constructor
+func RegisterProvider(name string, constructor ProviderConstructor) {
+	providerRegistry[name] = constructor
+}
+
+// CreateProvider creates a provider using the registered constructor
+func CreateProvider(name string, config ProviderConfig) (Client, error) {
+	constructor, exists := providerRegistry[name]
+	if !exists {
+		return nil, fmt.Errorf("provider %s not registered", name)
+	}
+	return constructor(config)
+}
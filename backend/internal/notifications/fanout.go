@@ -0,0 +1,89 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// JobFanout is the job kind delivering a Fanout.
+const JobFanout = "notifications.fanout"
+
+// Fanout is one notification sent to several users. Title and Body decode
+// from plain strings too, as enqueued before they were translated.
+type Fanout struct {
+	UserIDs    []uuid.UUID  `json:"userIds"`
+	ActorID    *uuid.UUID   `json:"actorId,omitempty"`
+	Kind       Kind         `json:"kind"`
+	Title      i18n.Message `json:"title"`
+	Body       i18n.Message `json:"body"`
+	Link       string       `json:"link"`
+	EntityType string       `json:"entityType"`
+	EntityID   *uuid.UUID   `json:"entityId,omitempty"`
+}
+
+// Deliver creates the notification of f for all its users at once, so a
+// retried delivery never notifies anyone twice.
+func (r *Repository) Deliver(ctx context.Context, f Fanout) error {
+	if len(f.UserIDs) == 0 {
+		return nil
+	}
+
+	titleMessage, bodyMessage, err := storedMessages(f.Title, f.Body)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, userID := range f.UserIDs {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO notifications (user_id, actor_id, kind, title, body, link, entity_type, entity_id, title_message, body_message)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			userID,
+			f.ActorID,
+			string(f.Kind),
+			f.Title.Render(i18n.Default),
+			f.Body.Render(i18n.Default),
+			f.Link,
+			f.EntityType,
+			f.EntityID,
+			titleMessage,
+			bodyMessage,
+		); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(f.UserIDs))
+	for _, userID := range f.UserIDs {
+		keys = append(keys, unreadCacheKey(userID))
+	}
+	cache.Invalidate(ctx, r.cache, keys...)
+	return nil
+}
+
+// RegisterJobs lets queue deliver fan-outs enqueued with JobFanout.
+func (r *Repository) RegisterJobs(queue *jobs.Queue) {
+	queue.Register(JobFanout, func(ctx context.Context, payload json.RawMessage) error {
+		var f Fanout
+		if err := json.Unmarshal(payload, &f); err != nil {
+			return fmt.Errorf("decode fanout: %w", err)
+		}
+		return r.Deliver(ctx, f)
+	})
+}
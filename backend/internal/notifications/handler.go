@@ -2,11 +2,13 @@ package notifications
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/problem"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -20,45 +22,52 @@ func NewHandler(repo *Repository) *Handler {
 	return &Handler{repo: repo}
 }
 
+// listPageLimits bound the pages of List
+var listPageLimits = pagination.Limits{Default: 100, Max: 200}
+
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	unreadOnly := strings.EqualFold(strings.TrimSpace(r.URL.Query().Get("unreadOnly")), "true")
-	limit := 100
-	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
-		if parsed, err := strconv.Atoi(raw); err == nil {
-			limit = parsed
-		}
+	page, err := pagination.Parse(r, listPageLimits)
+	if err != nil {
+		pagination.WriteError(w, err)
+		return
 	}
 
-	items, err := h.repo.ListByUser(r.Context(), userID, unreadOnly, limit)
+	items, err := h.repo.ListByUser(r.Context(), userID, unreadOnly, page)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list notifications"})
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
+		problem.Error(w, http.StatusInternalServerError, "failed to list notifications")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, items)
+	pagination.SetHeaders(w, r, items)
+	writeJSON(w, http.StatusOK, items.Items)
 }
 
 func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	notificationID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid notification id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_notification_id", "invalid notification id")
 		return
 	}
 
 	if err := h.repo.MarkRead(r.Context(), userID, notificationID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to mark notification as read"})
+		problem.Error(w, http.StatusInternalServerError, "failed to mark notification as read")
 		return
 	}
 
@@ -68,12 +77,12 @@ func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	if err := h.repo.MarkAllRead(r.Context(), userID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to mark all notifications as read"})
+		problem.Error(w, http.StatusInternalServerError, "failed to mark all notifications as read")
 		return
 	}
 
@@ -83,13 +92,13 @@ func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteAll(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	deleted, err := h.repo.DeleteAll(r.Context(), userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete notifications"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete notifications")
 		return
 	}
 
@@ -102,13 +111,13 @@ func (h *Handler) DeleteAll(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UnreadCount(w http.ResponseWriter, r *http.Request) {
 	userID, ok := userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	count, err := h.repo.UnreadCount(r.Context(), userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to count unread notifications"})
+		problem.Error(w, http.StatusInternalServerError, "failed to count unread notifications")
 		return
 	}
 
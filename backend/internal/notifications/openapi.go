@@ -0,0 +1,21 @@
+package notifications
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/notifications", Tag: "notifications", Summary: "List the notifications of the caller, newest first", Query: []openapi.Param{
+		{Name: "unreadOnly", Kind: "boolean", Description: "Only unread notifications"},
+	}, Response: Notification{}, List: true, Page: &listPageLimits},
+	{Method: http.MethodDelete, Path: "/notifications", Tag: "notifications", Summary: "Delete all notifications of the caller", Response: struct {
+		Status  string `json:"status"`
+		Deleted int    `json:"deleted"`
+	}{}},
+	{Method: http.MethodGet, Path: "/notifications/unread-count", Tag: "notifications", Summary: "Count unread notifications", Response: openapi.Count{}},
+	{Method: http.MethodPost, Path: "/notifications/read-all", Tag: "notifications", Summary: "Mark all notifications read", Response: openapi.Status{}},
+	{Method: http.MethodPost, Path: "/notifications/{id}/read", Tag: "notifications", Summary: "Mark a notification read", Response: openapi.Status{}},
+}
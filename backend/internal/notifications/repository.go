@@ -3,8 +3,13 @@ package notifications
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/pagination"
+
 	"github.com/google/uuid"
 )
 
@@ -17,6 +22,9 @@ const (
 	KindProjectMember  Kind = "project_member"
 	KindTaskComment    Kind = "task_comment"
 	KindCallInvite     Kind = "call_invite"
+	KindFileComment    Kind = "file_comment"
+	KindDocumentParsed Kind = "document_parsed"
+	KindTaskDeadline   Kind = "task_deadline"
 )
 
 type Notification struct {
@@ -35,53 +43,129 @@ type Notification struct {
 }
 
 type Repository struct {
-	db *sql.DB
+	db    *sql.DB
+	cache cache.Store
 }
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(ctx context.Context, userID uuid.UUID, actorID *uuid.UUID, kind Kind, title, body, link, entityType string, entityID *uuid.UUID) error {
-	_, err := r.db.ExecContext(
+// WithCache caches unread counts in store.
+func (r *Repository) WithCache(store cache.Store) *Repository {
+	r.cache = store
+	return r
+}
+
+const unreadCacheTTL = time.Minute
+
+func unreadCacheKey(userID uuid.UUID) string {
+	return "notifications:unread:" + userID.String()
+}
+
+// Create notifies userID. Translated title and body messages are shown in
+// the language of the reader.
+func (r *Repository) Create(ctx context.Context, userID uuid.UUID, actorID *uuid.UUID, kind Kind, title, body i18n.Message, link, entityType string, entityID *uuid.UUID) error {
+	titleMessage, bodyMessage, err := storedMessages(title, body)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(
 		ctx,
-		`INSERT INTO notifications (user_id, actor_id, kind, title, body, link, entity_type, entity_id)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO notifications (user_id, actor_id, kind, title, body, link, entity_type, entity_id, title_message, body_message)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
 		userID,
 		actorID,
 		string(kind),
-		title,
-		body,
+		title.Render(i18n.Default),
+		body.Render(i18n.Default),
 		link,
 		entityType,
 		entityID,
+		titleMessage,
+		bodyMessage,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	cache.Invalidate(ctx, r.cache, unreadCacheKey(userID))
+	return nil
+}
+
+// storedMessages encodes the messages to translate; literal ones are only
+// kept as title and body.
+func storedMessages(title, body i18n.Message) (any, any, error) {
+	encode := func(m i18n.Message) (any, error) {
+		if !m.Translated() {
+			return nil, nil
+		}
+		return json.Marshal(m)
+	}
+	titleMessage, err := encode(title)
+	if err != nil {
+		return nil, nil, err
+	}
+	bodyMessage, err := encode(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return titleMessage, bodyMessage, nil
 }
 
-func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]Notification, error) {
-	if limit <= 0 || limit > 200 {
-		limit = 100
+// localize replaces text with message, when there is one, in locale.
+func localize(text *string, message []byte, locale i18n.Locale) {
+	if len(message) == 0 {
+		return
+	}
+	var m i18n.Message
+	if err := json.Unmarshal(message, &m); err == nil {
+		*text = m.Render(locale)
 	}
+}
 
-	query := `SELECT n.id, n.user_id, n.actor_id, COALESCE(u.email, ''), n.kind, n.title, n.body, n.link, n.entity_type, n.entity_id, n.read_at, n.created_at
+// listKey is the sort key of ListByUser
+type listKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// ListByUser lists the notifications of userID in the locale of ctx.
+func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, page pagination.Page) (pagination.List[Notification], error) {
+	var key listKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[Notification]{}, err
+	}
+
+	query := `SELECT n.id, n.user_id, n.actor_id, COALESCE(u.email, ''), n.kind, n.title, n.body, n.title_message, n.body_message, n.link, n.entity_type, n.entity_id, n.read_at, n.created_at, COUNT(*) OVER ()
 		FROM notifications n
 		LEFT JOIN users u ON u.id = n.actor_id
 		WHERE n.user_id = $1`
+	args := []any{userID, page.Fetch()}
 	if unreadOnly {
 		query += ` AND n.read_at IS NULL`
 	}
-	query += ` ORDER BY n.created_at DESC LIMIT $2`
+	if after {
+		query += ` AND (n.created_at, n.id) < ($3, $4)`
+		args = append(args, key.CreatedAt, key.ID)
+	}
+	query += ` ORDER BY n.created_at DESC, n.id DESC LIMIT $2`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return pagination.List[Notification]{}, err
 	}
 	defer rows.Close()
 
-	items := make([]Notification, 0)
+	var (
+		items  []Notification
+		total  int
+		locale = i18n.FromContext(ctx)
+	)
 	for rows.Next() {
 		var n Notification
+		var titleMessage, bodyMessage []byte
 		var actorID sql.NullString
 		var actorEmail sql.NullString
 		var entityID sql.NullString
@@ -94,15 +178,20 @@ func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnl
 			&n.Kind,
 			&n.Title,
 			&n.Body,
+			&titleMessage,
+			&bodyMessage,
 			&n.Link,
 			&n.EntityType,
 			&entityID,
 			&readAt,
 			&n.CreatedAt,
+			&total,
 		); err != nil {
-			return nil, err
+			return pagination.List[Notification]{}, err
 		}
 
+		localize(&n.Title, titleMessage, locale)
+		localize(&n.Body, bodyMessage, locale)
 		if actorID.Valid {
 			if parsed, parseErr := uuid.Parse(actorID.String); parseErr == nil {
 				n.ActorID = &parsed
@@ -124,7 +213,20 @@ func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnl
 		items = append(items, n)
 	}
 
-	return items, rows.Err()
+	if err := rows.Err(); err != nil {
+		return pagination.List[Notification]{}, err
+	}
+
+	list, err := pagination.Cut(items, page, func(i int) any {
+		return listKey{CreatedAt: items[i].CreatedAt, ID: items[i].ID}
+	})
+	if err != nil {
+		return pagination.List[Notification]{}, err
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
 }
 
 func (r *Repository) MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error {
@@ -137,7 +239,12 @@ func (r *Repository) MarkRead(ctx context.Context, userID, notificationID uuid.U
 		notificationID,
 		userID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	cache.Invalidate(ctx, r.cache, unreadCacheKey(userID))
+	return nil
 }
 
 func (r *Repository) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
@@ -149,7 +256,12 @@ func (r *Repository) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
 		   AND read_at IS NULL`,
 		userID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	cache.Invalidate(ctx, r.cache, unreadCacheKey(userID))
+	return nil
 }
 
 func (r *Repository) DeleteAll(ctx context.Context, userID uuid.UUID) (int, error) {
@@ -168,21 +280,74 @@ func (r *Repository) DeleteAll(ctx context.Context, userID uuid.UUID) (int, erro
 		return 0, err
 	}
 
+	cache.Invalidate(ctx, r.cache, unreadCacheKey(userID))
 	return int(affected), nil
 }
 
 func (r *Repository) UnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	return cache.Load(ctx, r.cache, unreadCacheKey(userID), unreadCacheTTL, func() (int, error) {
+		var count int
+		err := r.db.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*)::int
+			 FROM notifications
+			 WHERE user_id = $1
+			   AND read_at IS NULL`,
+			userID,
+		).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		return count, nil
+	})
+}
+
+// CountCreatedBefore counts the notifications PurgeCreatedBefore would
+// delete.
+func (r *Repository) CountCreatedBefore(ctx context.Context, cutoff time.Time) (int, error) {
 	var count int
 	err := r.db.QueryRowContext(
 		ctx,
-		`SELECT COUNT(*)::int
-		 FROM notifications
-		 WHERE user_id = $1
-		   AND read_at IS NULL`,
-		userID,
+		`SELECT COUNT(*)::int FROM notifications WHERE created_at < $1`,
+		cutoff,
 	).Scan(&count)
+	return count, err
+}
+
+// PurgeCreatedBefore deletes up to limit notifications created before cutoff,
+// read or not, and returns how many it deleted.
+func (r *Repository) PurgeCreatedBefore(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`DELETE FROM notifications
+		 WHERE id IN (
+		 	SELECT id FROM notifications
+		 	WHERE created_at < $1
+		 	ORDER BY created_at
+		 	LIMIT $2
+		 	FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING user_id`,
+		cutoff,
+		limit,
+	)
 	if err != nil {
 		return 0, err
 	}
-	return count, nil
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return 0, err
+		}
+		keys = append(keys, unreadCacheKey(userID))
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	cache.Invalidate(ctx, r.cache, keys...)
+	return len(keys), nil
 }
@@ -0,0 +1,182 @@
+package notifications
+
+import (
+	"context"
+	"log"
+
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// Subscriber notifies the users concerned by domain events. Notifications
+// are delivered through the job queue when there is one, so publishers do
+// not wait for them.
+type Subscriber struct {
+	repo *Repository
+	jobs *jobs.Queue
+}
+
+func NewSubscriber(repo *Repository, queue *jobs.Queue) *Subscriber {
+	return &Subscriber{repo: repo, jobs: queue}
+}
+
+// Register subscribes s to the events it notifies about.
+func (s *Subscriber) Register(bus *events.Bus) {
+	events.On(bus, s.projectCreated)
+	events.On(bus, s.taskUpdated)
+	events.On(bus, s.taskCommented)
+	events.On(bus, s.reportCommented)
+	events.On(bus, s.memberAdded)
+	events.On(bus, s.rolesUpdated)
+}
+
+func (s *Subscriber) projectCreated(ctx context.Context, e events.ProjectCreated) error {
+	return s.send(ctx, []uuid.UUID{e.ActorID}, uuid.Nil, KindProjectCreated,
+		i18n.M("notification.project_created.title"),
+		i18n.M("notification.project_created.body", "title", e.Title),
+		"/project-overview/"+e.ProjectID.String(),
+		"project", e.ProjectID)
+}
+
+func (s *Subscriber) taskUpdated(ctx context.Context, e events.TaskUpdated) error {
+	if len(e.AddedAssignees) == 0 {
+		return nil
+	}
+
+	kind, message := KindTaskAssigned, "notification.task_assigned"
+	if e.Delegated {
+		kind, message = KindTaskDelegated, "notification.task_delegated"
+	}
+	return s.send(ctx, e.AddedAssignees, e.ActorID, kind,
+		i18n.M(message+".title"),
+		i18n.M(message+".body", "title", e.Title),
+		"/project/task-"+e.TaskID.String(),
+		"task", e.TaskID)
+}
+
+func (s *Subscriber) taskCommented(ctx context.Context, e events.TaskCommented) error {
+	link := "/project/task-" + e.TaskID.String() + "?commentId=" + e.CommentID.String()
+	if err := s.send(ctx, e.Watchers, e.ActorID, KindTaskComment,
+		i18n.M("notification.task_comment.title"),
+		i18n.M("notification.task_comment.body"),
+		link, "task", e.TaskID); err != nil {
+		return err
+	}
+	return s.send(ctx, e.Mentioned, e.ActorID, KindTaskComment,
+		i18n.M("notification.task_mention.title"),
+		i18n.M("notification.task_mention.body"),
+		link, "task", e.TaskID)
+}
+
+func (s *Subscriber) reportCommented(ctx context.Context, e events.ReportCommented) error {
+	link := "/project/" + e.ProjectID.String() + "/reports?reportId=" + e.ReportID.String() + "&commentId=" + e.CommentID.String()
+	if e.TaskID != nil {
+		link = "/project/task-" + e.TaskID.String() + "/reports?reportId=" + e.ReportID.String() + "&commentId=" + e.CommentID.String()
+	}
+
+	if err := s.send(ctx, e.Watchers, e.ActorID, KindTaskComment,
+		i18n.M("notification.report_comment.title"),
+		i18n.M("notification.report_comment.body"),
+		link, "delay_report", e.ReportID); err != nil {
+		return err
+	}
+	if e.RepliedTo == uuid.Nil {
+		return nil
+	}
+	return s.send(ctx, []uuid.UUID{e.RepliedTo}, e.ActorID, KindTaskComment,
+		i18n.M("notification.report_reply.title"),
+		i18n.M("notification.report_reply.body"),
+		link, "delay_report", e.ReportID)
+}
+
+func (s *Subscriber) memberAdded(ctx context.Context, e events.MemberAdded) error {
+	return s.send(ctx, []uuid.UUID{e.UserID}, e.ActorID, KindProjectMember,
+		i18n.M("notification.member_added.title"),
+		i18n.M("notification.member_added.body", "role", roleKey(e.Role)),
+		"/project-overview/"+e.ProjectID.String(),
+		"project", e.ProjectID)
+}
+
+func (s *Subscriber) rolesUpdated(ctx context.Context, e events.RolesUpdated) error {
+	roleBody := func(role string) i18n.Message {
+		if e.ProjectTitle != "" {
+			return i18n.M("notification.roles_updated.body_project", "role", role, "project", e.ProjectTitle)
+		}
+		return i18n.M("notification.roles_updated.body", "role", role)
+	}
+	link := "/project-overview/" + e.ProjectID.String()
+
+	if e.ManagerID != nil {
+		if err := s.send(ctx, []uuid.UUID{*e.ManagerID}, e.ActorID, KindProjectMember,
+			i18n.M("notification.roles_updated.title"),
+			roleBody("manager"),
+			link, "project", e.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	memberTargets := make([]uuid.UUID, 0, len(e.MemberIDs))
+	for _, memberID := range e.MemberIDs {
+		if e.ManagerID != nil && memberID == *e.ManagerID {
+			continue
+		}
+		memberTargets = append(memberTargets, memberID)
+	}
+	return s.send(ctx, memberTargets, e.ActorID, KindProjectMember,
+		i18n.M("notification.roles_updated.title"),
+		roleBody("member"),
+		link, "project", e.ProjectID)
+}
+
+// roleKey is the catalog suffix of a project role, role.<key>.
+func roleKey(role string) string {
+	switch role {
+	case "owner", "manager":
+		return role
+	default:
+		return "member"
+	}
+}
+
+// send notifies userIDs, except the actor, once each.
+func (s *Subscriber) send(ctx context.Context, userIDs []uuid.UUID, actorID uuid.UUID, kind Kind, title, body i18n.Message, link, entityType string, entityID uuid.UUID) error {
+	fanout := Fanout{
+		UserIDs:    make([]uuid.UUID, 0, len(userIDs)),
+		Kind:       kind,
+		Title:      title,
+		Body:       body,
+		Link:       link,
+		EntityType: entityType,
+		EntityID:   &entityID,
+	}
+	if actorID != uuid.Nil {
+		fanout.ActorID = &actorID
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if userID == uuid.Nil || userID == actorID {
+			continue
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		fanout.UserIDs = append(fanout.UserIDs, userID)
+	}
+	if len(fanout.UserIDs) == 0 {
+		return nil
+	}
+
+	if s.jobs != nil {
+		_, err := s.jobs.Enqueue(ctx, JobFanout, fanout)
+		if err == nil {
+			return nil
+		}
+		log.Printf("notification enqueue failed, delivering inline: %v", err)
+	}
+	return s.repo.Deliver(ctx, fanout)
+}
@@ -0,0 +1,412 @@
+// Package openapi builds the OpenAPI document of the HTTP API. Each handler
+// package lists its operations next to its handlers, with the Go types they
+// decode and encode; schemas are generated from those types, so the document
+// follows their json tags.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/request"
+
+	"github.com/google/uuid"
+)
+
+// Version is the version of the API the document describes
+const Version = "1.0.0"
+
+// Operation describes one endpoint.
+type Operation struct {
+	Method  string
+	Path    string // chi pattern, e.g. /projects/{id}/stages
+	Tag     string
+	Summary string
+	Public  bool // no bearer token needed
+
+	Query []Param
+	Form  []Param // multipart form fields
+	Body  any     // JSON request body
+
+	Status      int                // success status, 200 when zero
+	Response    any                // JSON response body, none when nil
+	List        bool               // response is an array of Response
+	Page        *pagination.Limits // List is paged with ?limit= and ?cursor=
+	Conditional bool               // ETag on reads, If-Match on updates, see package etag
+	ContentType string             // non-JSON response, e.g. text/event-stream
+}
+
+// Param is a query parameter or form field.
+type Param struct {
+	Name        string
+	Kind        string // string, integer, number, boolean or binary
+	Description string
+	Required    bool
+}
+
+// Problem is the body of every error answer, see package problem.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Errors lists the failed fields of validation_failed problems
+	Errors []request.FieldError `json:"errors,omitempty"`
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Document returns the OpenAPI 3 document of the operations, served under
+// basePath.
+func Document(basePath string, groups ...[]Operation) map[string]any {
+	schemas := &schemaBuilder{components: make(map[string]any), names: make(map[string]reflect.Type)}
+	schemas.components["Problem"] = schemas.inline(reflect.TypeOf(Problem{}))
+
+	paths := make(map[string]any)
+	tags := make(map[string]bool)
+	for _, group := range groups {
+		for _, op := range group {
+			item, ok := paths[op.Path].(map[string]any)
+			if !ok {
+				item = make(map[string]any)
+				paths[op.Path] = item
+			}
+			item[strings.ToLower(op.Method)] = op.spec(schemas)
+			tags[op.Tag] = true
+		}
+	}
+
+	tagList := make([]map[string]any, 0, len(tags))
+	for tag := range tags {
+		tagList = append(tagList, map[string]any{"name": tag})
+	}
+	sort.Slice(tagList, func(i, j int) bool { return tagList[i]["name"].(string) < tagList[j]["name"].(string) })
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "TM Platform API",
+			"version":     Version,
+			"description": "Projects, tasks, files, chats, notifications and the org hierarchy of TM Platform. Errors are RFC 7807 problem details.",
+		},
+		"servers":  []any{map[string]any{"url": basePath}},
+		"tags":     tagList,
+		"paths":    paths,
+		"security": []any{map[string]any{"bearer": []string{}}},
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// spec returns the OpenAPI operation object of op
+func (op Operation) spec(schemas *schemaBuilder) map[string]any {
+	operation := map[string]any{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op),
+	}
+	if op.Public {
+		operation["security"] = []any{}
+	}
+
+	var parameters []any
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		parameters = append(parameters, map[string]any{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, param := range op.Query {
+		parameters = append(parameters, map[string]any{
+			"name": param.Name, "in": "query", "required": param.Required, "description": param.Description, "schema": param.schema(),
+		})
+	}
+	if op.Conditional {
+		header := "If-Match"
+		description := "ETag of the version the update applies to"
+		if op.Method == http.MethodGet {
+			header, description = "If-None-Match", "ETag of a cached copy, answered 304 while it is current"
+		}
+		parameters = append(parameters, map[string]any{
+			"name": header, "in": "header", "required": false, "description": description, "schema": map[string]any{"type": "string"},
+		})
+	}
+	if op.Page != nil {
+		parameters = append(parameters,
+			map[string]any{
+				"name": "limit", "in": "query", "required": false,
+				"description": fmt.Sprintf("Page size, %d by default and at most %d", op.Page.Default, op.Page.Max),
+				"schema":      map[string]any{"type": "integer", "minimum": 1, "maximum": op.Page.Max},
+			},
+			map[string]any{
+				"name": "cursor", "in": "query", "required": false,
+				"description": "Cursor of the page, from the " + pagination.HeaderNextCursor + " header of the previous one",
+				"schema":      map[string]any{"type": "string"},
+			},
+		)
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	switch {
+	case op.Body != nil:
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(op.Body))},
+			},
+		}
+	case len(op.Form) > 0:
+		properties := make(map[string]any)
+		required := make([]string, 0)
+		for _, field := range op.Form {
+			properties[field.Name] = field.schema()
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"multipart/form-data": map[string]any{"schema": schema}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case op.ContentType != "":
+		response["content"] = map[string]any{
+			op.ContentType: map[string]any{"schema": map[string]any{"type": "string"}},
+		}
+	case op.Response != nil:
+		schema := schemas.schema(reflect.TypeOf(op.Response))
+		if op.List {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		response["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+	}
+	if op.Page != nil {
+		response["headers"] = map[string]any{
+			pagination.HeaderNextCursor: map[string]any{
+				"description": "Cursor of the next page, absent on the last one",
+				"schema":      map[string]any{"type": "string"},
+			},
+			pagination.HeaderTotalCount: map[string]any{
+				"description": "Number of items over all pages, on the first page only",
+				"schema":      map[string]any{"type": "integer"},
+			},
+			"Link": map[string]any{
+				"description": `URL of the next page with rel="next"`,
+				"schema":      map[string]any{"type": "string"},
+			},
+		}
+	}
+
+	responses := map[string]any{
+		strconv.Itoa(status): response,
+		"default": map[string]any{
+			"description": "Error",
+			"content": map[string]any{
+				"application/problem+json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+			},
+		},
+	}
+	if op.Conditional {
+		response["headers"] = map[string]any{
+			"ETag": map[string]any{"description": "Version of the returned representation", "schema": map[string]any{"type": "string"}},
+		}
+		if op.Method == http.MethodGet {
+			responses[strconv.Itoa(http.StatusNotModified)] = map[string]any{"description": http.StatusText(http.StatusNotModified)}
+		}
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+// schema returns the schema of a query parameter or form field
+func (p Param) schema() map[string]any {
+	schema := map[string]any{"type": p.Kind}
+	if p.Kind == "binary" {
+		schema = map[string]any{"type": "string", "format": "binary"}
+	}
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+	return schema
+}
+
+// operationID names an operation after its method and path, e.g.
+// getProjectsByIdStages, for the methods of generated clients
+func operationID(op Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, segment := range strings.Split(op.Path, "/") {
+		if segment == "" {
+			continue
+		}
+		if match := pathParamPattern.FindStringSubmatch(segment); match != nil {
+			b.WriteString("By")
+			segment = match[1]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// schemaBuilder turns Go types into JSON schemas. Named structs become
+// components referenced by $ref, anonymous ones are inlined.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[string]reflect.Type
+}
+
+// schema returns the schema of values of t as encoding/json writes them
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case uuidType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case rawType:
+		return map[string]any{} // any JSON value
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.inline(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + b.component(t)}
+	default:
+		return map[string]any{} // interfaces hold any JSON value
+	}
+}
+
+// component registers the schema of the named struct t and returns its
+// component name, prefixed with the package when two packages share a name
+func (b *schemaBuilder) component(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if known, ok := b.names[name]; ok && known != t {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	if _, ok := b.names[name]; ok {
+		return name
+	}
+
+	// Registered before its fields so recursive types end in a $ref
+	b.names[name] = t
+	b.components[name] = nil
+	b.components[name] = b.inline(t)
+	return name
+}
+
+// inline returns the object schema of the struct t, with the fields
+// encoding/json writes. Fields without omitempty are required.
+func (b *schemaBuilder) inline(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// Status is the body of answers that only report a status, e.g.
+// {"status": "deleted"}.
+type Status struct {
+	Status string `json:"status"`
+}
+
+// Count is the body of the unread counters, e.g. {"count": 3}.
+type Count struct {
+	Count int `json:"count"`
+}
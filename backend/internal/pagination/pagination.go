@@ -0,0 +1,180 @@
+// Package pagination pages list endpoints with opaque cursors. A request
+// asks for up to limit items with ?limit=, capped per endpoint, and continues
+// with the cursor of the previous answer in ?cursor=. The body stays a JSON
+// array; paging travels in headers:
+//
+//	X-Next-Cursor: <cursor of the next page, absent on the last one>
+//	Link: </api/v1/notifications?cursor=...&limit=50>; rel="next"
+//	X-Total-Count: <number of items over all pages, first page only>
+//
+// A cursor encodes the sort key of the last item returned, so pages stay
+// stable while items are added in front of them.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tm-platform-backend/internal/problem"
+)
+
+// Response headers carrying the paging state.
+const (
+	HeaderNextCursor = "X-Next-Cursor"
+	HeaderTotalCount = "X-Total-Count"
+)
+
+var (
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Limits bound the page size of an endpoint.
+type Limits struct {
+	Default int
+	Max     int
+}
+
+// Page is the slice of a list a request asks for.
+type Page struct {
+	Limit  int
+	cursor string
+}
+
+// Parse reads the limit and cursor query parameters of r. Limits above the
+// maximum are capped rather than rejected.
+func Parse(r *http.Request, limits Limits) (Page, error) {
+	query := r.URL.Query()
+	limit := 0
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return Page{}, ErrInvalidLimit
+		}
+		limit = parsed
+	}
+	return NewPage(limit, strings.TrimSpace(query.Get("cursor")), limits)
+}
+
+// NewPage is the page of up to limit items after cursor, for callers that
+// do not take them from the query string. A zero limit asks for the default.
+func NewPage(limit int, cursor string, limits Limits) (Page, error) {
+	page := Page{Limit: limits.Default}
+	if limit < 0 {
+		return Page{}, ErrInvalidLimit
+	}
+	if limit > 0 {
+		page.Limit = limit
+	}
+	if limits.Max > 0 && page.Limit > limits.Max {
+		page.Limit = limits.Max
+	}
+
+	if cursor != "" {
+		if _, err := base64.RawURLEncoding.DecodeString(cursor); err != nil {
+			return Page{}, ErrInvalidCursor
+		}
+		page.cursor = cursor
+	}
+	return page, nil
+}
+
+// First reports whether the page starts the list.
+func (p Page) First() bool {
+	return p.cursor == ""
+}
+
+// Fetch is the number of rows to query for the page: one more than the
+// limit, so Cut can tell whether another page follows.
+func (p Page) Fetch() int {
+	return p.Limit + 1
+}
+
+// Key decodes the sort key of the cursor into dst. It returns false on the
+// first page, which has no cursor.
+func (p Page) Key(dst any) (bool, error) {
+	if p.cursor == "" {
+		return false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(p.cursor)
+	if err != nil {
+		return false, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return false, ErrInvalidCursor
+	}
+	return true, nil
+}
+
+// List is one page of items.
+type List[T any] struct {
+	Items []T
+	Next  string // cursor of the next page, empty on the last one
+	Total *int   // number of items over all pages, when known
+}
+
+// Cut trims items, queried with Fetch rows, to the page and sets the cursor
+// of the next page from the sort key of its last item.
+func Cut[T any](items []T, page Page, key func(i int) any) (List[T], error) {
+	list := List[T]{Items: items}
+	if list.Items == nil {
+		list.Items = make([]T, 0)
+	}
+	if len(items) <= page.Limit {
+		return list, nil
+	}
+
+	list.Items = items[:page.Limit]
+	raw, err := json.Marshal(key(page.Limit - 1))
+	if err != nil {
+		return List[T]{}, err
+	}
+	list.Next = base64.RawURLEncoding.EncodeToString(raw)
+	return list, nil
+}
+
+// SetHeaders describes the page of list in the headers of the answer to r.
+func SetHeaders[T any](w http.ResponseWriter, r *http.Request, list List[T]) {
+	if list.Total != nil {
+		w.Header().Set(HeaderTotalCount, strconv.Itoa(*list.Total))
+	}
+	if list.Next == "" {
+		return
+	}
+
+	w.Header().Set(HeaderNextCursor, list.Next)
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", list.Next)
+	next.RawQuery = query.Encode()
+	w.Header().Add("Link", "<"+next.RequestURI()+`>; rel="next"`)
+}
+
+// WriteError answers a request whose paging parameters Parse or Key
+// rejected.
+func WriteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidLimit):
+		problem.Write(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+	case errors.Is(err, ErrInvalidCursor):
+		problem.Write(w, http.StatusBadRequest, "invalid_cursor", "invalid cursor")
+	default:
+		problem.Error(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// Scanner appends the destination of a trailing COUNT(*) OVER () column to
+// every Scan, so rows can be read by scan functions that do not know it.
+type Scanner struct {
+	Rows interface{ Scan(dest ...any) error }
+	Dest *int
+}
+
+// Scan implements the scanner interface of the repositories.
+func (s Scanner) Scan(dest ...any) error {
+	return s.Rows.Scan(append(dest, s.Dest)...)
+}
@@ -0,0 +1,119 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+)
+
+type testKey struct {
+	Name string `json:"name"`
+	ID   int    `json:"id"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	limits := Limits{Default: 2, Max: 10}
+	items := []testKey{{"c", 3}, {"b", 2}, {"a", 1}}
+
+	tests := []struct {
+		name     string
+		fetched  []testKey
+		wantLen  int
+		wantNext *testKey
+	}{
+		{"more rows than the limit", items, 2, &testKey{"b", 2}},
+		{"exactly the limit", items[:2], 2, nil},
+		{"short page", items[:1], 1, nil},
+		{"no rows", nil, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := NewPage(0, "", limits)
+			if err != nil {
+				t.Fatalf("NewPage() error = %v", err)
+			}
+			list, err := Cut(tt.fetched, page, func(i int) any { return tt.fetched[i] })
+			if err != nil {
+				t.Fatalf("Cut() error = %v", err)
+			}
+			if list.Items == nil || len(list.Items) != tt.wantLen {
+				t.Fatalf("Cut() returned %v, want %d items", list.Items, tt.wantLen)
+			}
+			if tt.wantNext == nil {
+				if list.Next != "" {
+					t.Errorf("Cut() next = %q, want none", list.Next)
+				}
+				return
+			}
+
+			next, err := NewPage(0, list.Next, limits)
+			if err != nil {
+				t.Fatalf("NewPage(%q) error = %v", list.Next, err)
+			}
+			if next.First() {
+				t.Errorf("page after %q reports First", list.Next)
+			}
+			var key testKey
+			ok, err := next.Key(&key)
+			if err != nil || !ok {
+				t.Fatalf("Key() = %v, %v, want true, nil", ok, err)
+			}
+			if key != *tt.wantNext {
+				t.Errorf("Key() decoded %+v, want %+v", key, *tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+	tests := []struct {
+		name      string
+		limit     int
+		cursor    string
+		wantLimit int
+		wantErr   error
+	}{
+		{"default limit", 0, "", 20, nil},
+		{"requested limit", 50, "", 50, nil},
+		{"limit capped", 500, "", 100, nil},
+		{"negative limit", -1, "", 0, ErrInvalidLimit},
+		{"cursor not base64url", 0, "not a cursor!", 0, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := NewPage(tt.limit, tt.cursor, limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewPage() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && page.Limit != tt.wantLimit {
+				t.Errorf("NewPage() limit = %d, want %d", page.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		wantOK  bool
+		wantErr error
+	}{
+		{"first page", "", false, nil},
+		{"encoded key", "eyJuYW1lIjoiYiIsImlkIjoyfQ", true, nil},
+		{"base64 of something else", "bm90IGpzb24", false, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := NewPage(0, tt.cursor, Limits{Default: 10})
+			if err != nil {
+				t.Fatalf("NewPage() error = %v", err)
+			}
+			var key testKey
+			ok, err := page.Key(&key)
+			if ok != tt.wantOK || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Key() = %v, %v, want %v, %v", ok, err, tt.wantOK, tt.wantErr)
+			}
+		})
+	}
+}
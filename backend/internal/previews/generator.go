@@ -0,0 +1,191 @@
+package previews
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tm-platform-backend/internal/storage"
+)
+
+const (
+	StatusPending     = "pending"
+	StatusReady       = "ready"
+	StatusFailed      = "failed"
+	StatusUnsupported = "unsupported"
+
+	// MaxDimension bounds the longer side of generated previews in pixels.
+	MaxDimension = 320
+
+	maxSourcePixels = 50_000_000
+	previewPrefix   = "previews/"
+	jpegQuality     = 80
+)
+
+var ErrUnsupported = errors.New("preview is not supported for this file")
+
+// Generator renders small JPEG previews for stored files: a thumbnail for
+// images and the first page for PDF and office documents. Document previews
+// rely on pdftoppm (poppler) and, for office formats, LibreOffice being on
+// PATH; without them those formats are reported as unsupported.
+type Generator struct {
+	store       storage.Storage
+	pdftoppm    string
+	libreOffice string
+}
+
+func NewGenerator(store storage.Storage) *Generator {
+	g := &Generator{store: store}
+	if bin, err := exec.LookPath("pdftoppm"); err == nil {
+		g.pdftoppm = bin
+	}
+	for _, name := range []string{"soffice", "libreoffice"} {
+		if bin, err := exec.LookPath(name); err == nil {
+			g.libreOffice = bin
+			break
+		}
+	}
+	return g
+}
+
+// Generate renders a preview for the object at sourceKey and returns the
+// storage key of the stored preview.
+func (g *Generator) Generate(ctx context.Context, sourceKey string) (string, error) {
+	ext := strings.ToLower(path.Ext(sourceKey))
+
+	var (
+		preview []byte
+		err     error
+	)
+	switch ext {
+	case ".png", ".jpg", ".jpeg":
+		preview, err = g.imagePreview(ctx, sourceKey)
+	case ".pdf":
+		preview, err = g.documentPreview(ctx, sourceKey, false)
+	case ".doc", ".docx", ".xls", ".xlsx":
+		preview, err = g.documentPreview(ctx, sourceKey, true)
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+
+	previewKey := previewPrefix + strings.TrimSuffix(sourceKey, path.Ext(sourceKey)) + ".jpg"
+	_ = g.store.Delete(ctx, previewKey)
+	if err := g.store.Put(ctx, previewKey, bytes.NewReader(preview), int64(len(preview)), "image/jpeg"); err != nil {
+		return "", err
+	}
+	return previewKey, nil
+}
+
+func (g *Generator) imagePreview(ctx context.Context, sourceKey string) ([]byte, error) {
+	body, _, err := g.store.Open(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return thumbnailJPEG(data)
+}
+
+func (g *Generator) documentPreview(ctx context.Context, sourceKey string, needsConversion bool) ([]byte, error) {
+	if g.pdftoppm == "" || (needsConversion && g.libreOffice == "") {
+		return nil, ErrUnsupported
+	}
+
+	workDir, err := os.MkdirTemp("", "tm-platform-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	sourcePath := filepath.Join(workDir, "source"+path.Ext(sourceKey))
+	if err := g.download(ctx, sourceKey, sourcePath); err != nil {
+		return nil, err
+	}
+
+	pdfPath := sourcePath
+	if needsConversion {
+		cmd := exec.CommandContext(ctx, g.libreOffice, "--headless", "--convert-to", "pdf", "--outdir", workDir, sourcePath)
+		cmd.Env = append(os.Environ(), "HOME="+workDir)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("convert document: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		pdfPath = filepath.Join(workDir, "source.pdf")
+	}
+
+	outPrefix := filepath.Join(workDir, "page")
+	cmd := exec.CommandContext(
+		ctx,
+		g.pdftoppm,
+		"-f", "1",
+		"-l", "1",
+		"-singlefile",
+		"-jpeg",
+		"-jpegopt", "quality="+strconv.Itoa(jpegQuality),
+		"-scale-to", strconv.Itoa(MaxDimension),
+		pdfPath,
+		outPrefix,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("render first page: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return os.ReadFile(outPrefix + ".jpg")
+}
+
+func (g *Generator) download(ctx context.Context, key, target string) error {
+	body, _, err := g.store.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func thumbnailJPEG(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, errors.New("image dimensions are out of range")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, downscale(src, MaxDimension), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
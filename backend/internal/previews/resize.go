@@ -0,0 +1,64 @@
+package previews
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// downscale shrinks src so that its longer side is at most maxSide, averaging
+// the source pixels covered by each target pixel. Smaller images are only
+// flattened onto a white background.
+func downscale(src image.Image, maxSide int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > maxSide || srcH > maxSide {
+		if srcW >= srcH {
+			dstW = maxSide
+			dstH = max(1, srcH*maxSide/srcW)
+		} else {
+			dstH = maxSide
+			dstW = max(1, srcW*maxSide/srcH)
+		}
+	}
+
+	// JPEG has no alpha channel, so transparent areas are drawn over white.
+	flat := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+	if dstW == srcW && dstH == srcH {
+		return flat
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := y * srcH / dstH
+		y1 := max(y0+1, (y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := x * srcW / dstW
+			x1 := max(x0+1, (x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := flat.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(flat.Pix[offset])
+					g += uint64(flat.Pix[offset+1])
+					b += uint64(flat.Pix[offset+2])
+					a += uint64(flat.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			target := dst.PixOffset(x, y)
+			dst.Pix[target] = uint8(r / n)
+			dst.Pix[target+1] = uint8(g / n)
+			dst.Pix[target+2] = uint8(b / n)
+			dst.Pix[target+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,101 @@
+package previews
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"tm-platform-backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const generateTimeout = 2 * time.Minute
+
+type Job struct {
+	FileID    uuid.UUID
+	Version   int
+	SourceURL string
+}
+
+type Result struct {
+	Job
+	PreviewURL *string
+	Status     string
+}
+
+// SaveFunc persists the outcome of a job.
+type SaveFunc func(ctx context.Context, result Result) error
+
+// Worker generates previews in the background so uploads return immediately.
+type Worker struct {
+	gen  *Generator
+	save SaveFunc
+	jobs chan Job
+}
+
+func NewWorker(gen *Generator, save SaveFunc, queueSize int) *Worker {
+	if queueSize <= 0 {
+		queueSize = 128
+	}
+	return &Worker{gen: gen, save: save, jobs: make(chan Job, queueSize)}
+}
+
+// Start runs n goroutines that process jobs until ctx is cancelled.
+func (w *Worker) Start(ctx context.Context, n int) {
+	for i := 0; i < max(1, n); i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-w.jobs:
+					w.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue schedules a job without blocking. Jobs dropped because the queue is
+// full stay pending in the database and are picked up on the next start.
+func (w *Worker) Enqueue(job Job) bool {
+	if w == nil {
+		return false
+	}
+	select {
+	case w.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job Job) {
+	jobCtx, cancel := context.WithTimeout(ctx, generateTimeout)
+	defer cancel()
+
+	result := Result{Job: job, Status: StatusUnsupported}
+	if key, ok := storage.KeyFromURL(job.SourceURL); ok {
+		previewKey, err := w.gen.Generate(jobCtx, key)
+		switch {
+		case err == nil:
+			url := storage.PublicURL(previewKey)
+			result.PreviewURL = &url
+			result.Status = StatusReady
+		case errors.Is(err, ErrUnsupported):
+			result.Status = StatusUnsupported
+		default:
+			log.Printf("preview generation for file %s failed: %v", job.FileID, err)
+			result.Status = StatusFailed
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if err := w.save(ctx, result); err != nil {
+		log.Printf("save preview for file %s failed: %v", job.FileID, err)
+	}
+}
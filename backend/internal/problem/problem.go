@@ -0,0 +1,157 @@
+// Package problem writes error responses as RFC 7807 problem details with a
+// machine-readable code, e.g.
+//
+//	{"type":"about:blank","title":"Not Found","status":404,
+//	 "code":"project_not_found","detail":"project not found",
+//	 "error":"project not found"}
+//
+// The error member repeats the detail for clients written against the
+// earlier {"error": "..."} bodies.
+//
+// When the response announces a Content-Language, set by i18n.Middleware,
+// the detail of codes with a "problem.<code>" catalog entry is translated.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tm-platform-backend/internal/i18n"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Codes shared by several handlers. Most codes are specific to the request,
+// like project_not_found or invalid_task_id.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeInvalidPayload     = "invalid_payload"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeProjectNotFound    = "project_not_found"
+	CodeConflict           = "conflict"
+	CodeEditConflict       = "edit_conflict"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeUnsupportedFormat  = "unsupported_format"
+	CodeUnprocessable      = "unprocessable_entity"
+	CodePreconditionFailed = "precondition_failed"
+	CodeRateLimited        = "rate_limited"
+	CodeInternal           = "internal_error"
+	CodeBadGateway         = "bad_gateway"
+	CodeUnavailable        = "service_unavailable"
+)
+
+// Details is an RFC 7807 problem.
+type Details struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Write answers with a problem of the status, code and detail.
+func Write(w http.ResponseWriter, status int, code, detail string) {
+	write(w, status, newDetails(status, code, localize(w, code, detail)))
+}
+
+// Error answers with a problem whose code follows from the status, for
+// errors without a more specific code such as internal failures.
+func Error(w http.ResponseWriter, status int, detail string) {
+	Write(w, status, StatusCode(status), detail)
+}
+
+// WriteWith answers with a problem extended by the JSON members of ext, a
+// struct or map. The standard members take precedence over those of ext.
+func WriteWith(w http.ResponseWriter, status int, code, detail string, ext any) {
+	details := newDetails(status, code, localize(w, code, detail))
+
+	body := make(map[string]any)
+	if ext != nil {
+		if raw, err := json.Marshal(ext); err == nil {
+			_ = json.Unmarshal(raw, &body)
+		}
+	}
+	body["type"] = details.Type
+	body["title"] = details.Title
+	body["status"] = details.Status
+	body["code"] = details.Code
+	if details.Detail != "" {
+		body["detail"] = details.Detail
+		body["error"] = details.Error
+	}
+	write(w, status, body)
+}
+
+// StatusCode returns the generic code of an HTTP status.
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedFormat
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// localize translates detail into the language of the response.
+func localize(w http.ResponseWriter, code, detail string) string {
+	if code == "" || detail == "" {
+		return detail
+	}
+	locale, ok := i18n.Parse(w.Header().Get("Content-Language"))
+	if !ok {
+		return detail
+	}
+	if translated, ok := i18n.Lookup(locale, "problem."+code); ok {
+		return translated
+	}
+	return detail
+}
+
+func newDetails(status int, code, detail string) Details {
+	if code == "" {
+		code = StatusCode(status)
+	}
+	return Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+		Error:  detail,
+	}
+}
+
+func write(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,270 @@
+package projectfiles
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// chunkRunes and chunkOverlapRunes size the passages that are embedded
+	// and later quoted to the assistant.
+	chunkRunes        = 1200
+	chunkOverlapRunes = 200
+	maxChunksPerFile  = 256
+	embedBatchSize    = 32
+)
+
+// Embedder turns texts into vectors for semantic search.
+type Embedder interface {
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// Chunk is an embedded passage of a document.
+type Chunk struct {
+	Index     int
+	Content   string
+	Embedding []float32
+}
+
+// ChunkMatch is a passage found by SearchChunks.
+type ChunkMatch struct {
+	FileID     uuid.UUID
+	FileName   string
+	ChunkIndex int
+	Content    string
+	Score      float64
+}
+
+type pendingEmbedding struct {
+	FileID  uuid.UUID
+	Version int
+	Text    string
+}
+
+// ReplaceChunks stores the embedded chunks of a file version, replacing any
+// previous ones. Chunks for a version that has since been replaced are
+// dropped.
+func (r *Repository) ReplaceChunks(ctx context.Context, fileID uuid.UUID, version int, chunks []Chunk) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var current int
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT version FROM project_files WHERE id = $1 FOR UPDATE`,
+		fileID,
+	).Scan(&current); err != nil {
+		return err
+	}
+	if current != version {
+		return tx.Rollback()
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM project_file_chunks WHERE file_id = $1`, fileID); err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO project_file_chunks (id, file_id, chunk_index, content, embedding)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			uuid.New(),
+			fileID,
+			chunk.Index,
+			chunk.Content,
+			encodeEmbedding(chunk.Embedding),
+		); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(
+		ctx,
+		`UPDATE project_files SET embedded_version = $2 WHERE id = $1`,
+		fileID,
+		version,
+	); err != nil {
+		return err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return err
+	}
+
+	return nil
+}
+
+// SearchChunks returns the passages of the project's documents closest to
+// query that userID is allowed to read, best first.
+func (r *Repository) SearchChunks(ctx context.Context, userID, projectID uuid.UUID, query []float32, limit int) ([]ChunkMatch, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT pf.id, pf.name, c.chunk_index, c.content, c.embedding
+		 FROM project_file_chunks c
+		 JOIN project_files pf ON pf.id = c.file_id
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.project_id = $1
+		   AND pf.embedded_version = pf.version
+		   AND `+fileReadAccess("$2"),
+		projectID,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]ChunkMatch, 0)
+	for rows.Next() {
+		var (
+			match     ChunkMatch
+			embedding []byte
+		)
+		if err := rows.Scan(&match.FileID, &match.FileName, &match.ChunkIndex, &match.Content, &embedding); err != nil {
+			return nil, err
+		}
+		match.Score = cosineSimilarity(query, decodeEmbedding(embedding))
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// listPendingEmbeddings returns indexed files whose current version has not
+// been embedded yet.
+func (r *Repository) listPendingEmbeddings(ctx context.Context, limit int) ([]pendingEmbedding, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, version, COALESCE(content_text, '')
+		 FROM project_files
+		 WHERE index_status = 'ready'
+		   AND embedded_version IS DISTINCT FROM version
+		   AND deleted_at IS NULL
+		 ORDER BY updated_at
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make([]pendingEmbedding, 0)
+	for rows.Next() {
+		var item pendingEmbedding
+		if err := rows.Scan(&item.FileID, &item.Version, &item.Text); err != nil {
+			return nil, err
+		}
+		pending = append(pending, item)
+	}
+
+	return pending, rows.Err()
+}
+
+// embedText splits text into overlapping passages and embeds them.
+func embedText(ctx context.Context, embedder Embedder, text string) ([]Chunk, error) {
+	passages := chunkText(text, chunkRunes, chunkOverlapRunes)
+	if len(passages) > maxChunksPerFile {
+		passages = passages[:maxChunksPerFile]
+	}
+
+	chunks := make([]Chunk, 0, len(passages))
+	for start := 0; start < len(passages); start += embedBatchSize {
+		batch := passages[start:min(start+embedBatchSize, len(passages))]
+		vectors, err := embedder.Embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(batch) {
+			return nil, errors.New("embedder returned a wrong number of vectors")
+		}
+		for i, vector := range vectors {
+			chunks = append(chunks, Chunk{Index: start + i, Content: batch[i], Embedding: vector})
+		}
+	}
+	return chunks, nil
+}
+
+// chunkText splits text into passages of about size runes that overlap by
+// overlap runes, preferring to cut at whitespace.
+func chunkText(text string, size, overlap int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	chunks := make([]string, 0, len(runes)/max(size-overlap, 1)+1)
+
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			for cut := end; cut > start+size/2; cut-- {
+				if unicode.IsSpace(runes[cut]) {
+					end = cut
+					break
+				}
+			}
+		}
+
+		if chunk := strings.Join(strings.Fields(string(runes[start:end])), " "); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end >= len(runes) {
+			break
+		}
+		start = max(end-overlap, start+1)
+	}
+
+	return chunks
+}
+
+func encodeEmbedding(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(value))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vector
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
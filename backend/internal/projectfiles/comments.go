@@ -0,0 +1,179 @@
+package projectfiles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// GetFile returns a single project file the requester is allowed to see.
+func (r *Repository) GetFile(ctx context.Context, userID, fileID uuid.UUID) (ProjectFile, error) {
+	file, err := scanProjectFile(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+qualifiedProjectFileColumns+`
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.id = $1 AND `+fileReadAccess("$2"),
+		fileID,
+		userID,
+	))
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	if err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM project_file_comments WHERE file_id = $1`,
+		file.ID,
+	).Scan(&file.CommentCount); err != nil {
+		return ProjectFile{}, err
+	}
+
+	return file, nil
+}
+
+func (r *Repository) ListComments(ctx context.Context, userID, fileID uuid.UUID) ([]FileComment, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT c.id, c.file_id, c.author_id,
+		        COALESCE(NULLIF(BTRIM(u.full_name), ''), u.email, ''),
+		        c.body, c.page, c.version, c.created_at
+		 FROM project_file_comments c
+		 JOIN project_files pf ON pf.id = c.file_id
+		 JOIN projects p ON p.id = pf.project_id
+		 LEFT JOIN users u ON u.id = c.author_id
+		 WHERE c.file_id = $1 AND `+fileReadAccess("$2")+`
+		 ORDER BY c.created_at, c.id`,
+		fileID,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]FileComment, 0)
+	for rows.Next() {
+		var comment FileComment
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.FileID,
+			&comment.AuthorID,
+			&comment.AuthorName,
+			&comment.Body,
+			&comment.Page,
+			&comment.Version,
+			&comment.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// CreateComment adds a comment, optionally anchored to a page, to the current
+// version of a file the author can see.
+func (r *Repository) CreateComment(ctx context.Context, authorID, fileID uuid.UUID, body string, page *int) (FileComment, error) {
+	var comment FileComment
+	err := r.db.QueryRowContext(
+		ctx,
+		`WITH inserted AS (
+		 	INSERT INTO project_file_comments (file_id, author_id, body, page, version)
+		 	SELECT pf.id, $2, $3, $4, pf.version
+		 	FROM project_files pf
+		 	JOIN projects p ON p.id = pf.project_id
+		 	WHERE pf.id = $1 AND `+fileReadAccess("$2")+`
+		 	RETURNING id, file_id, author_id, body, page, version, created_at
+		 )
+		 SELECT i.id, i.file_id, i.author_id,
+		        COALESCE(NULLIF(BTRIM(u.full_name), ''), u.email, ''),
+		        i.body, i.page, i.version, i.created_at
+		 FROM inserted i
+		 LEFT JOIN users u ON u.id = i.author_id`,
+		fileID,
+		authorID,
+		body,
+		page,
+	).Scan(
+		&comment.ID,
+		&comment.FileID,
+		&comment.AuthorID,
+		&comment.AuthorName,
+		&comment.Body,
+		&comment.Page,
+		&comment.Version,
+		&comment.CreatedAt,
+	)
+	if err != nil {
+		return FileComment{}, err
+	}
+
+	return comment, nil
+}
+
+// DeleteComment removes a comment. Authors can delete their own comments;
+// project owners and managers can delete any.
+func (r *Repository) DeleteComment(ctx context.Context, requesterID, fileID, commentID uuid.UUID) error {
+	var authorID uuid.UUID
+	var canManage bool
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT c.author_id, `+projectManageAccess("$3")+`
+		 FROM project_file_comments c
+		 JOIN project_files pf ON pf.id = c.file_id
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE c.id = $1 AND c.file_id = $2 AND `+fileReadAccess("$3"),
+		commentID,
+		fileID,
+		requesterID,
+	).Scan(&authorID, &canManage)
+	if err != nil {
+		return err
+	}
+	if authorID != requesterID && !canManage {
+		return ErrForbidden
+	}
+
+	_, err = r.db.ExecContext(ctx, `DELETE FROM project_file_comments WHERE id = $1`, commentID)
+	return err
+}
+
+// commentCounts returns the number of comments per file in a project.
+func (r *Repository) commentCounts(ctx context.Context, projectID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT c.file_id, COUNT(*)
+		 FROM project_file_comments c
+		 JOIN project_files pf ON pf.id = c.file_id
+		 WHERE pf.project_id = $1
+		 GROUP BY c.file_id`,
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			fileID uuid.UUID
+			count  int
+		)
+		if err := rows.Scan(&fileID, &count); err != nil {
+			return nil, err
+		}
+		counts[fileID] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -4,11 +4,24 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/previews"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/quotas"
+	"tm-platform-backend/internal/request"
+	"tm-platform-backend/internal/storage"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
@@ -19,98 +32,723 @@ var allowedFileTypes = map[string]struct{}{
 }
 
 type Handler struct {
-	repo *Repository
+	repo              *Repository
+	store             storage.Storage
+	previews          *previews.Worker
+	quotas            *quotas.Repository
+	indexer           *Indexer
+	notificationsRepo *notifications.Repository
+	trashRetention    time.Duration
 }
 
-func NewHandler(repo *Repository) *Handler {
-	return &Handler{repo: repo}
+func NewHandler(repo *Repository, store storage.Storage, previewWorker *previews.Worker, quotaRepo *quotas.Repository, indexer *Indexer, notificationsRepo *notifications.Repository, trashRetention time.Duration) *Handler {
+	if trashRetention <= 0 {
+		trashRetention = DefaultTrashRetention
+	}
+	return &Handler{repo: repo, store: store, previews: previewWorker, quotas: quotaRepo, indexer: indexer, notificationsRepo: notificationsRepo, trashRetention: trashRetention}
+}
+
+const (
+	minSearchQueryLength = 2
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 100
+
+	maxCommentLength = 4000
+)
+
+var allowedVisibilities = map[string]struct{}{
+	VisibilityMembers:  {},
+	VisibilityManagers: {},
+	VisibilityUsers:    {},
 }
 
 type createProjectFileRequest struct {
-	ProjectID string `json:"project_id"`
-	URL       string `json:"url"`
-	Type      string `json:"type"`
-	Name      string `json:"name"`
-	Size      int64  `json:"size"`
+	ProjectID  string   `json:"project_id"`
+	URL        string   `json:"url"`
+	Type       string   `json:"type"`
+	Name       string   `json:"name"`
+	Size       int64    `json:"size"`
+	Visibility string   `json:"visibility"`
+	UserIDs    []string `json:"user_ids"`
+}
+
+type createCommentRequest struct {
+	Body string `json:"body"`
+	Page *int   `json:"page"`
+}
+
+type updateVisibilityRequest struct {
+	Visibility string   `json:"visibility"`
+	UserIDs    []string `json:"user_ids"`
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	ownerID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	var req createProjectFileRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	projectID, err := uuid.Parse(strings.TrimSpace(req.ProjectID))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project_id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project_id")
 		return
 	}
 
 	url := strings.TrimSpace(req.URL)
 	if url == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url is required"})
+		problem.Write(w, http.StatusBadRequest, "url_required", "url is required")
 		return
 	}
 
 	fileType := strings.ToLower(strings.TrimSpace(req.Type))
 	if _, ok := allowedFileTypes[fileType]; !ok {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid type"})
+		problem.Write(w, http.StatusBadRequest, "invalid_type", "invalid type")
 		return
 	}
 
 	name := strings.TrimSpace(req.Name)
 	if name == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
+		problem.Write(w, http.StatusBadRequest, "name_required", "name is required")
 		return
 	}
 
-	if req.Size <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "size must be > 0"})
+	visibility, allowedUsers, err := parseVisibility(req.Visibility, req.UserIDs)
+	if err != nil {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	size := req.Size
+	if key, ok := storage.KeyFromURL(url); ok && h.store != nil {
+		info, err := h.store.Stat(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				problem.Write(w, http.StatusBadRequest, "file_not_found_in_storage", "file not found in storage")
+				return
+			}
+			log.Printf("project file stat failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to save project file")
+			return
+		}
+		if info.Size > 0 {
+			size = info.Size
+		}
+
+		if h.quotas != nil {
+			if err := h.quotas.AttachToProject(r.Context(), key, projectID); err != nil {
+				if errors.Is(err, quotas.ErrQuotaExceeded) {
+					problem.Error(w, http.StatusRequestEntityTooLarge, err.Error())
+					return
+				}
+				log.Printf("attach project file to quota failed: %v", err)
+				problem.Error(w, http.StatusInternalServerError, "failed to save project file")
+				return
+			}
+		}
+	}
+
+	if size <= 0 {
+		problem.Write(w, http.StatusBadRequest, "invalid_size", "size must be > 0")
 		return
 	}
 
 	file, err := h.repo.Create(r.Context(), ownerID, CreateProjectFileInput{
-		ProjectID: projectID,
-		URL:       url,
-		Type:      fileType,
-		Name:      name,
-		Size:      req.Size,
+		ProjectID:    projectID,
+		URL:          url,
+		Type:         fileType,
+		Name:         name,
+		Size:         size,
+		Visibility:   visibility,
+		AllowedUsers: allowedUsers,
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+			return
+		}
+		if strings.Contains(err.Error(), "cannot") {
+			problem.Error(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to save project file"})
+		problem.Error(w, http.StatusInternalServerError, "failed to save project file")
 		return
 	}
 
+	h.schedulePreview(file)
 	writeJSON(w, http.StatusCreated, file)
 }
 
 func (h *Handler) ListDocuments(w http.ResponseWriter, r *http.Request) {
 	ownerID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	documents, err := h.repo.ListDocumentsByOwner(r.Context(), ownerID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch documents"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch documents")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, documents)
 }
 
+func (h *Handler) ListByProject(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(r.URL.Query().Get("project_id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project_id")
+		return
+	}
+
+	files, err := h.repo.ListByProject(r.Context(), userID, projectID)
+	if err != nil {
+		log.Printf("list project files failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch project files")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, files)
+}
+
+// Search finds files of a project by name and document content.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) < minSearchQueryLength {
+		problem.Write(w, http.StatusBadRequest, "query_too_short", "q must be at least 2 characters")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = min(parsed, maxSearchLimit)
+		}
+	}
+
+	results, err := h.repo.Search(r.Context(), userID, projectID, query, limit)
+	if err != nil {
+		log.Printf("search project files failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to search project files")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// Get returns a file record together with its comments.
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	file, err := h.repo.GetFile(r.Context(), userID, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return
+		}
+		log.Printf("get project file failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch project file")
+		return
+	}
+
+	comments, err := h.repo.ListComments(r.Context(), userID, fileID)
+	if err != nil {
+		log.Printf("list project file comments failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch project file")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, FileDetails{ProjectFile: file, Comments: comments})
+}
+
+func (h *Handler) ListComments(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	if _, err := h.repo.GetFile(r.Context(), userID, fileID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return
+		}
+		log.Printf("get project file failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch comments")
+		return
+	}
+
+	comments, err := h.repo.ListComments(r.Context(), userID, fileID)
+	if err != nil {
+		log.Printf("list project file comments failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch comments")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comments)
+}
+
+// CreateComment adds a comment to a file and notifies the uploader.
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	var req createCommentRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		problem.Write(w, http.StatusBadRequest, "body_required", "body is required")
+		return
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		problem.Write(w, http.StatusBadRequest, "body_too_long", "body is too long")
+		return
+	}
+	if req.Page != nil && *req.Page <= 0 {
+		problem.Write(w, http.StatusBadRequest, "invalid_page", "page must be > 0")
+		return
+	}
+
+	file, err := h.repo.GetFile(r.Context(), userID, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return
+		}
+		log.Printf("get project file failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create comment")
+		return
+	}
+
+	comment, err := h.repo.CreateComment(r.Context(), userID, fileID, body, req.Page)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return
+		}
+		log.Printf("create project file comment failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create comment")
+		return
+	}
+
+	if h.notificationsRepo != nil && file.UploadedBy != nil && *file.UploadedBy != userID {
+		preview := body
+		if utf8.RuneCountInString(preview) > 120 {
+			preview = string([]rune(preview)[:120]) + "..."
+		}
+
+		actor := userID
+		link := "/project/" + file.ProjectID.String() + "?fileId=" + file.ID.String() + "&commentId=" + comment.ID.String()
+		if err := h.notificationsRepo.Create(
+			r.Context(),
+			*file.UploadedBy,
+			&actor,
+			notifications.KindFileComment,
+			i18n.M("notification.file_comment.title", "file", file.Name),
+			i18n.Text(preview),
+			link,
+			"project_file",
+			&file.ID,
+		); err != nil {
+			log.Printf("project file comment notification failed: %v", err)
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	commentID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "commentId")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_comment_id", "invalid comment id")
+		return
+	}
+
+	if err := h.repo.DeleteComment(r.Context(), userID, fileID, commentID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusNotFound, "comment_not_found", "comment not found")
+		case errors.Is(err, ErrForbidden):
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		default:
+			log.Printf("delete project file comment failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to delete comment")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UpdateVisibility(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	var req updateVisibilityRequest
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	if strings.TrimSpace(req.Visibility) == "" {
+		problem.Write(w, http.StatusBadRequest, "visibility_required", "visibility is required")
+		return
+	}
+
+	visibility, allowedUsers, err := parseVisibility(req.Visibility, req.UserIDs)
+	if err != nil {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file, err := h.repo.SetVisibility(r.Context(), userID, fileID, visibility, allowedUsers)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+		case strings.Contains(err.Error(), "cannot"):
+			problem.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("update project file visibility failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to update visibility")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, file)
+}
+
+// Delete moves a file to the project's trash, from where it can be restored
+// until the retention period ends.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	if err := h.repo.MoveToTrash(r.Context(), userID, fileID, h.trashRetention); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+		case errors.Is(err, ErrForbidden):
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		default:
+			log.Printf("move project file to trash failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to delete file")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(r.URL.Query().Get("project_id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project_id")
+		return
+	}
+
+	files, err := h.repo.ListTrash(r.Context(), userID, projectID)
+	if err != nil {
+		log.Printf("list project file trash failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch trash")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (h *Handler) RestoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	file, err := h.repo.RestoreFromTrash(r.Context(), userID, fileID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusNotFound, "file_not_found_in_trash", "file not found in trash")
+		case errors.Is(err, ErrForbidden):
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		case strings.Contains(err.Error(), "cannot"):
+			problem.Error(w, http.StatusConflict, err.Error())
+		default:
+			log.Printf("restore project file from trash failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to restore file")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, file)
+}
+
+func (h *Handler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	versions, err := h.repo.ListVersions(r.Context(), userID, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return
+		}
+		log.Printf("list project file versions failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch file versions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+func (h *Handler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, version, ok := parseFileVersionParams(w, r)
+	if !ok {
+		return
+	}
+
+	file, err := h.repo.RestoreVersion(r.Context(), userID, fileID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusNotFound, "file_version_not_found", "file version not found")
+		case strings.Contains(err.Error(), "cannot"):
+			problem.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("restore project file version failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to restore file version")
+		}
+		return
+	}
+
+	h.schedulePreview(file)
+	writeJSON(w, http.StatusOK, file)
+}
+
+// DownloadVersion streams a stored version as an attachment; without a
+// version in the path the current one is served. Files that live outside our
+// storage are redirected to.
+func (h *Handler) DownloadVersion(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	version := 0
+	if rawVersion := chi.URLParam(r, "version"); rawVersion != "" {
+		var ok bool
+		if _, version, ok = parseFileVersionParams(w, r); !ok {
+			return
+		}
+	}
+
+	item, name, err := h.repo.GetVersion(r.Context(), userID, fileID, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_version_not_found", "file version not found")
+			return
+		}
+		log.Printf("get project file version failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to download file")
+		return
+	}
+
+	key, inStorage := storage.KeyFromURL(item.URL)
+	if !inStorage || h.store == nil {
+		http.Redirect(w, r, item.URL, http.StatusFound)
+		return
+	}
+
+	body, info, err := h.store.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, "file_not_found_in_storage", "file not found in storage")
+			return
+		}
+		log.Printf("open project file version failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to download file")
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	storage.ServeObject(w, r, body, info)
+}
+
+func (h *Handler) schedulePreview(file ProjectFile) {
+	h.previews.Enqueue(previews.Job{FileID: file.ID, Version: file.Version, SourceURL: file.URL})
+	h.indexer.Enqueue(file)
+}
+
+func parseVisibility(raw string, rawUserIDs []string) (string, []uuid.UUID, error) {
+	visibility := strings.ToLower(strings.TrimSpace(raw))
+	if visibility == "" {
+		visibility = VisibilityMembers
+	}
+	if _, ok := allowedVisibilities[visibility]; !ok {
+		return "", nil, errors.New("invalid visibility")
+	}
+
+	if visibility != VisibilityUsers {
+		if len(rawUserIDs) > 0 {
+			return "", nil, errors.New("user_ids are only allowed with users visibility")
+		}
+		return visibility, nil, nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(rawUserIDs))
+	seen := make(map[uuid.UUID]struct{}, len(rawUserIDs))
+	for _, rawID := range rawUserIDs {
+		userID, err := uuid.Parse(strings.TrimSpace(rawID))
+		if err != nil {
+			return "", nil, errors.New("invalid user_ids")
+		}
+		if _, dup := seen[userID]; dup {
+			continue
+		}
+		seen[userID] = struct{}{}
+		userIDs = append(userIDs, userID)
+	}
+	if len(userIDs) == 0 {
+		return "", nil, errors.New("user_ids are required for users visibility")
+	}
+
+	return visibility, userIDs, nil
+}
+
+func parseFileVersionParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, int, bool) {
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return uuid.Nil, 0, false
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(chi.URLParam(r, "version")))
+	if err != nil || version <= 0 {
+		problem.Write(w, http.StatusBadRequest, "invalid_version", "invalid version")
+		return uuid.Nil, 0, false
+	}
+
+	return fileID, version, true
+}
+
 func userIDFromRequest(r *http.Request) (uuid.UUID, error) {
 	userIDStr, ok := auth.UserIDFromContext(r.Context())
 	if !ok || strings.TrimSpace(userIDStr) == "" {
@@ -0,0 +1,220 @@
+package projectfiles
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	IndexStatusPending     = "pending"
+	IndexStatusReady       = "ready"
+	IndexStatusFailed      = "failed"
+	IndexStatusUnsupported = "unsupported"
+
+	// maxIndexedFileSize matches the upload limit of the parser service.
+	maxIndexedFileSize int64 = 32 << 20
+	// maxIndexedTextBytes keeps the generated tsvector under PostgreSQL's
+	// 1MB limit.
+	maxIndexedTextBytes = 512 << 10
+	extractTimeout      = 2 * time.Minute
+)
+
+// TextExtractor turns a document into plain text.
+type TextExtractor interface {
+	ExtractText(ctx context.Context, filename string, body io.Reader) (string, error)
+}
+
+type indexJob struct {
+	FileID  uuid.UUID
+	Version int
+	URL     string
+}
+
+// Indexer extracts text from uploaded PDF/DOCX files in the background and
+// stores it for full-text search. When an embedder is set, the text is also
+// chunked and embedded for the AI assistant.
+type Indexer struct {
+	repo      *Repository
+	store     storage.Storage
+	extractor TextExtractor
+	embedder  Embedder
+	jobs      chan indexJob
+	events    *events.Bus
+}
+
+// NewIndexer creates an indexer. embedder may be nil to skip embeddings.
+func NewIndexer(repo *Repository, store storage.Storage, extractor TextExtractor, embedder Embedder, queueSize int) *Indexer {
+	if queueSize <= 0 {
+		queueSize = 128
+	}
+	return &Indexer{repo: repo, store: store, extractor: extractor, embedder: embedder, jobs: make(chan indexJob, queueSize)}
+}
+
+// WithEvents publishes a FileIndexed event on bus for every file indexed.
+func (i *Indexer) WithEvents(bus *events.Bus) *Indexer {
+	i.events = bus
+	return i
+}
+
+// Start runs n goroutines that process jobs until ctx is cancelled.
+func (i *Indexer) Start(ctx context.Context, n int) {
+	for worker := 0; worker < max(1, n); worker++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-i.jobs:
+					i.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue schedules a file for indexing without blocking. Files that do not
+// fit in the queue stay pending and are picked up by EnqueuePending.
+func (i *Indexer) Enqueue(file ProjectFile) bool {
+	if i == nil {
+		return false
+	}
+	select {
+	case i.jobs <- indexJob{FileID: file.ID, Version: file.Version, URL: file.URL}:
+		return true
+	default:
+		return false
+	}
+}
+
+// EnqueuePending schedules files left pending by a previous run.
+func (i *Indexer) EnqueuePending(ctx context.Context, limit int) {
+	files, err := i.repo.ListPendingIndex(ctx, limit)
+	if err != nil {
+		log.Printf("list pending index failed: %v", err)
+		return
+	}
+	for _, file := range files {
+		if !i.Enqueue(file) {
+			return
+		}
+	}
+}
+
+func (i *Indexer) process(ctx context.Context, job indexJob) {
+	jobCtx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+
+	text, err := i.extract(jobCtx, job.URL)
+	status := IndexStatusReady
+	var content *string
+	switch {
+	case err == nil:
+		content = &text
+	case errors.Is(err, errNotIndexable):
+		status = IndexStatusUnsupported
+	default:
+		log.Printf("index project file %s failed: %v", job.FileID, err)
+		status = IndexStatusFailed
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if err := i.repo.SetIndexedText(ctx, job.FileID, job.Version, content, status); err != nil {
+		log.Printf("save index for project file %s failed: %v", job.FileID, err)
+		return
+	}
+	i.events.Publish(ctx, events.FileIndexed{FileID: job.FileID, Version: job.Version, Status: status})
+	if content != nil {
+		i.embed(ctx, job.FileID, job.Version, *content)
+	}
+}
+
+// EmbedPending embeds indexed files that have no chunks for their current
+// version, e.g. because the embedder was unavailable when they were indexed.
+func (i *Indexer) EmbedPending(ctx context.Context, limit int) {
+	if i == nil || i.embedder == nil {
+		return
+	}
+	pending, err := i.repo.listPendingEmbeddings(ctx, limit)
+	if err != nil {
+		log.Printf("list pending embeddings failed: %v", err)
+		return
+	}
+	for _, item := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		i.embed(ctx, item.FileID, item.Version, item.Text)
+	}
+}
+
+func (i *Indexer) embed(ctx context.Context, fileID uuid.UUID, version int, text string) {
+	if i.embedder == nil {
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+
+	chunks, err := embedText(jobCtx, i.embedder, text)
+	if err != nil {
+		log.Printf("embed project file %s failed: %v", fileID, err)
+		return
+	}
+	if err := i.repo.ReplaceChunks(ctx, fileID, version, chunks); err != nil {
+		log.Printf("save chunks for project file %s failed: %v", fileID, err)
+	}
+}
+
+var errNotIndexable = errors.New("file cannot be indexed")
+
+func (i *Indexer) extract(ctx context.Context, url string) (string, error) {
+	key, ok := storage.KeyFromURL(url)
+	if !ok || i.extractor == nil {
+		return "", errNotIndexable
+	}
+
+	switch strings.ToLower(path.Ext(key)) {
+	case ".pdf", ".docx":
+	default:
+		return "", errNotIndexable
+	}
+
+	body, info, err := i.store.Open(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	if info.Size > maxIndexedFileSize {
+		return "", errNotIndexable
+	}
+
+	text, err := i.extractor.ExtractText(ctx, path.Base(key), body)
+	if err != nil {
+		return "", err
+	}
+	return truncateUTF8(strings.TrimSpace(text), maxIndexedTextBytes), nil
+}
+
+func truncateUTF8(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
@@ -6,32 +6,106 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	VisibilityMembers  = "members"
+	VisibilityManagers = "managers"
+	VisibilityUsers    = "users"
+)
+
 type ProjectFile struct {
-	ID        uuid.UUID `json:"id"`
-	ProjectID uuid.UUID `json:"project_id"`
-	URL       string    `json:"url"`
-	Type      string    `json:"type"`
-	Name      string    `json:"name"`
-	Size      int64     `json:"size"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            uuid.UUID   `json:"id"`
+	ProjectID     uuid.UUID   `json:"project_id"`
+	URL           string      `json:"url"`
+	Type          string      `json:"type"`
+	Name          string      `json:"name"`
+	Size          int64       `json:"size"`
+	Version       int         `json:"version"`
+	UploadedBy    *uuid.UUID  `json:"uploaded_by,omitempty"`
+	PreviewURL    *string     `json:"preview_url"`
+	PreviewStatus string      `json:"preview_status"`
+	Visibility    string      `json:"visibility"`
+	AllowedUsers  []uuid.UUID `json:"allowed_user_ids,omitempty"`
+	CommentCount  int         `json:"comment_count"`
+	Parse         *ParseState `json:"parse,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// ParseState is the latest parse-and-import job of a file.
+type ParseState struct {
+	JobID       uuid.UUID  `json:"job_id"`
+	Status      string     `json:"status"`
+	Progress    int        `json:"progress"`
+	Error       string     `json:"error,omitempty"`
+	FileVersion *int       `json:"file_version,omitempty"`
+	Outdated    bool       `json:"outdated"` // a newer version of the file was uploaded since
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+type FileComment struct {
+	ID         uuid.UUID `json:"id"`
+	FileID     uuid.UUID `json:"file_id"`
+	AuthorID   uuid.UUID `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	Body       string    `json:"body"`
+	Page       *int      `json:"page,omitempty"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FileDetails struct {
+	ProjectFile
+	Comments []FileComment `json:"comments"`
+}
+
+type TrashedFile struct {
+	ProjectFile
+	DeletedAt time.Time  `json:"deleted_at"`
+	DeletedBy *uuid.UUID `json:"deleted_by,omitempty"`
+	PurgeAt   time.Time  `json:"purge_at"`
+}
+
+type SearchResult struct {
+	ProjectFile
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type FileVersion struct {
+	ID             uuid.UUID  `json:"id"`
+	FileID         uuid.UUID  `json:"file_id"`
+	Version        int        `json:"version"`
+	URL            string     `json:"url"`
+	Type           string     `json:"type"`
+	Size           int64      `json:"size"`
+	UploadedBy     *uuid.UUID `json:"uploaded_by,omitempty"`
+	UploadedByName string     `json:"uploaded_by_name,omitempty"`
+	RestoredFrom   *int       `json:"restored_from,omitempty"`
+	IsCurrent      bool       `json:"is_current"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 type Document struct {
-	ID          uuid.UUID `json:"id"`
-	ProjectID   uuid.UUID `json:"project_id"`
-	ProjectName string    `json:"project_name"`
-	URL         string    `json:"url"`
-	Type        string    `json:"type"`
-	Name        string    `json:"name"`
-	Size        int64     `json:"size"`
-	CreatedAt   time.Time `json:"created_at"`
-	Status      string    `json:"status"`
+	ID            uuid.UUID `json:"id"`
+	ProjectID     uuid.UUID `json:"project_id"`
+	ProjectName   string    `json:"project_name"`
+	URL           string    `json:"url"`
+	Type          string    `json:"type"`
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"`
+	Version       int       `json:"version"`
+	PreviewURL    *string   `json:"preview_url"`
+	PreviewStatus string    `json:"preview_status"`
+	CreatedAt     time.Time `json:"created_at"`
+	Status        string    `json:"status"`
 }
 
 type CreateProjectFileInput struct {
-	ProjectID uuid.UUID
-	URL       string
-	Type      string
-	Name      string
-	Size      int64
+	ProjectID    uuid.UUID
+	URL          string
+	Type         string
+	Name         string
+	Size         int64
+	Visibility   string
+	AllowedUsers []uuid.UUID
 }
@@ -0,0 +1,32 @@
+package projectfiles
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+var projectIDParam = openapi.Param{Name: "project_id", Kind: "string", Description: "Project UUID", Required: true}
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/project-files", Tag: "files", Summary: "Attach an uploaded file to a project", Body: createProjectFileRequest{}, Status: http.StatusCreated, Response: ProjectFile{}},
+	{Method: http.MethodGet, Path: "/project-files", Tag: "files", Summary: "List the files of a project the caller can see", Query: []openapi.Param{projectIDParam}, Response: ProjectFile{}, List: true},
+	{Method: http.MethodGet, Path: "/project-files/trash", Tag: "files", Summary: "List the deleted files of a project", Query: []openapi.Param{projectIDParam}, Response: TrashedFile{}, List: true},
+	{Method: http.MethodGet, Path: "/project-files/{id}", Tag: "files", Summary: "Get a file with its comments", Response: FileDetails{}},
+	{Method: http.MethodDelete, Path: "/project-files/{id}", Tag: "files", Summary: "Move a file to the trash", Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/project-files/{id}/restore", Tag: "files", Summary: "Restore a file from the trash", Response: ProjectFile{}},
+	{Method: http.MethodPatch, Path: "/project-files/{id}/visibility", Tag: "files", Summary: "Change who can see a file", Body: updateVisibilityRequest{}, Response: ProjectFile{}},
+	{Method: http.MethodGet, Path: "/project-files/{id}/download", Tag: "files", Summary: "Download the current version of a file", ContentType: "application/octet-stream"},
+	{Method: http.MethodGet, Path: "/project-files/{id}/versions", Tag: "files", Summary: "List the versions of a file", Response: FileVersion{}, List: true},
+	{Method: http.MethodGet, Path: "/project-files/{id}/versions/{version}/download", Tag: "files", Summary: "Download a version of a file", ContentType: "application/octet-stream"},
+	{Method: http.MethodPost, Path: "/project-files/{id}/versions/{version}/restore", Tag: "files", Summary: "Make an old version current", Response: ProjectFile{}},
+	{Method: http.MethodGet, Path: "/project-files/{id}/comments", Tag: "files", Summary: "List the comments of a file", Response: FileComment{}, List: true},
+	{Method: http.MethodPost, Path: "/project-files/{id}/comments", Tag: "files", Summary: "Comment on a file", Body: createCommentRequest{}, Status: http.StatusCreated, Response: FileComment{}},
+	{Method: http.MethodDelete, Path: "/project-files/{id}/comments/{commentId}", Tag: "files", Summary: "Delete a comment", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/projects/{id}/files/search", Tag: "files", Summary: "Search the text of the files of a project", Query: []openapi.Param{
+		{Name: "q", Kind: "string", Description: "Search query", Required: true},
+		{Name: "limit", Kind: "integer", Description: "Page size, 20 by default and at most 100"},
+	}, Response: SearchResult{}, List: true},
+	{Method: http.MethodGet, Path: "/documents", Tag: "files", Summary: "List the documents uploaded by the caller", Response: Document{}, List: true},
+}
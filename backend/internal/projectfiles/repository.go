@@ -3,44 +3,503 @@ package projectfiles
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/previews"
+	"tm-platform-backend/internal/storage"
 
 	"github.com/google/uuid"
 )
 
 type Repository struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB
 }
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithReplica sends the heavy reads of the repository to the read replica
+// conn, see db.Reader.
+func (r *Repository) WithReplica(conn *sql.DB) *Repository {
+	r.replica = conn
+	return r
+}
+
+func (r *Repository) reader(ctx context.Context) *sql.DB {
+	return db.Reader(ctx, r.db, r.replica)
+}
+
+const (
+	projectFileColumns = `id, project_id, url, type, name, size, version, uploaded_by,
+		preview_url, preview_status, visibility, created_at, updated_at`
+	qualifiedProjectFileColumns = `pf.id, pf.project_id, pf.url, pf.type, pf.name, pf.size, pf.version, pf.uploaded_by,
+		pf.preview_url, pf.preview_status, pf.visibility, pf.created_at, pf.updated_at`
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// Create stores a project file. Re-uploading a file with the same name into
+// the same project keeps the existing record and adds a new version to it;
+// visibility is only taken from the input for new files.
 func (r *Repository) Create(ctx context.Context, ownerID uuid.UUID, input CreateProjectFileInput) (ProjectFile, error) {
-	row := r.db.QueryRowContext(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProjectFile{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Locking the project serializes concurrent uploads of the same name.
+	var projectID uuid.UUID
+	if err = tx.QueryRowContext(
 		ctx,
-		`INSERT INTO project_files (project_id, url, type, name, size)
-		 SELECT p.id, $2, $3, $4, $5
-		 FROM projects p
-		 WHERE p.id = $1 AND p.owner_id = $6
-		 RETURNING id, project_id, url, type, name, size, created_at`,
+		`SELECT id FROM projects WHERE id = $1 AND owner_id = $2 FOR UPDATE`,
 		input.ProjectID,
-		input.URL,
-		input.Type,
-		input.Name,
-		input.Size,
 		ownerID,
-	)
+	).Scan(&projectID); err != nil {
+		return ProjectFile{}, err
+	}
+
+	var existingID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT id
+		 FROM project_files
+		 WHERE project_id = $1 AND lower(name) = lower($2) AND deleted_at IS NULL
+		 ORDER BY created_at DESC
+		 LIMIT 1`,
+		projectID,
+		input.Name,
+	).Scan(&existingID)
 
 	var file ProjectFile
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		file, err = scanProjectFile(tx.QueryRowContext(
+			ctx,
+			`INSERT INTO project_files (project_id, url, type, name, size, version, uploaded_by, visibility)
+			 VALUES ($1, $2, $3, $4, $5, 1, $6, $7)
+			 RETURNING `+projectFileColumns,
+			projectID,
+			input.URL,
+			input.Type,
+			input.Name,
+			input.Size,
+			ownerID,
+			input.Visibility,
+		))
+		if err == nil && file.Visibility == VisibilityUsers {
+			err = grantAccessTx(ctx, tx, file.ID, input.AllowedUsers)
+		}
+	case err != nil:
+		return ProjectFile{}, err
+	default:
+		file, err = scanProjectFile(tx.QueryRowContext(
+			ctx,
+			`UPDATE project_files
+			 SET url = $2, type = $3, size = $4, version = version + 1, uploaded_by = $5,
+			     preview_url = NULL, preview_status = 'pending',
+			     content_text = NULL, index_status = 'pending', updated_at = now()
+			 WHERE id = $1
+			 RETURNING `+projectFileColumns,
+			existingID,
+			input.URL,
+			input.Type,
+			input.Size,
+			ownerID,
+		))
+	}
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	if err = insertVersionTx(ctx, tx, file, nil); err != nil {
+		return ProjectFile{}, err
+	}
+
+	if file.Visibility == VisibilityUsers {
+		if file.AllowedUsers, err = listAllowedUsers(ctx, tx, file.ID); err != nil {
+			return ProjectFile{}, err
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return ProjectFile{}, err
+	}
+
+	return file, nil
+}
+
+// ListByProject returns the project files the requester is allowed to see.
+func (r *Repository) ListByProject(ctx context.Context, userID, projectID uuid.UUID) ([]ProjectFile, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+qualifiedProjectFileColumns+`
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.project_id = $1 AND `+fileReadAccess("$2")+`
+		 ORDER BY pf.created_at DESC`,
+		projectID,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]ProjectFile, 0)
+	for rows.Next() {
+		file, err := scanProjectFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts, err := r.commentCounts(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	states, err := r.parseStates(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].CommentCount = counts[files[i].ID]
+		if state, ok := states[files[i].ID]; ok {
+			state.Outdated = state.FileVersion != nil && *state.FileVersion < files[i].Version
+			files[i].Parse = &state
+		}
+	}
+
+	return files, nil
+}
+
+// parseStates returns the latest parse job of each file of a project.
+func (r *Repository) parseStates(ctx context.Context, projectID uuid.UUID) (map[uuid.UUID]ParseState, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT DISTINCT ON (file_id) file_id, id, status, progress, error, file_version, finished_at
+		 FROM zhcp_parse_jobs
+		 WHERE project_id = $1 AND file_id IS NOT NULL
+		 ORDER BY file_id, created_at DESC`,
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[uuid.UUID]ParseState)
+	for rows.Next() {
+		var (
+			fileID uuid.UUID
+			state  ParseState
+		)
+		if err := rows.Scan(&fileID, &state.JobID, &state.Status, &state.Progress, &state.Error, &state.FileVersion, &state.FinishedAt); err != nil {
+			return nil, err
+		}
+		states[fileID] = state
+	}
+	return states, rows.Err()
+}
+
+// SetVisibility changes who can see a file. Only the project owner and
+// managers may do this; explicitly listed users must belong to the project.
+func (r *Repository) SetVisibility(ctx context.Context, requesterID, fileID uuid.UUID, visibility string, userIDs []uuid.UUID) (ProjectFile, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProjectFile{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var lockedID uuid.UUID
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT pf.id
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.id = $1 AND pf.deleted_at IS NULL AND `+projectManageAccess("$2")+`
+		 FOR UPDATE OF pf`,
+		fileID,
+		requesterID,
+	).Scan(&lockedID); err != nil {
+		return ProjectFile{}, err
+	}
+
+	file, err := scanProjectFile(tx.QueryRowContext(
+		ctx,
+		`UPDATE project_files
+		 SET visibility = $2, updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+projectFileColumns,
+		fileID,
+		visibility,
+	))
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM project_file_access WHERE file_id = $1`, fileID); err != nil {
+		return ProjectFile{}, err
+	}
+	if visibility == VisibilityUsers {
+		if err = grantAccessTx(ctx, tx, fileID, userIDs); err != nil {
+			return ProjectFile{}, err
+		}
+		if file.AllowedUsers, err = listAllowedUsers(ctx, tx, fileID); err != nil {
+			return ProjectFile{}, err
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return ProjectFile{}, err
+	}
+
+	return file, nil
+}
+
+// PublicAccessAllowed reports whether the object behind key may be served
+// from the public /uploads path. Objects that belong to project files with
+// restricted visibility must go through the access-checked download endpoint.
+func (r *Repository) PublicAccessAllowed(ctx context.Context, key string) (bool, error) {
+	url := storage.PublicURL(key)
+
+	var restricted bool
+	if err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1
+		 	FROM project_files pf
+		 	WHERE (pf.visibility <> 'members' OR pf.deleted_at IS NOT NULL)
+		 	  AND (
+		 	  	pf.preview_url = $1
+		 	  	OR EXISTS (
+		 	  		SELECT 1 FROM project_file_versions v
+		 	  		WHERE v.file_id = pf.id AND v.url = $1
+		 	  	)
+		 	  )
+		 )`,
+		url,
+	).Scan(&restricted); err != nil {
+		return false, err
+	}
+
+	return !restricted, nil
+}
+
+// ObjectAccess reports whether the object at key belongs to a project file
+// (any version or its preview) and, if so, whether userID may read one of
+// those files.
+func (r *Repository) ObjectAccess(ctx context.Context, userID uuid.UUID, key string) (referenced bool, allowed bool, err error) {
+	url := storage.PublicURL(key)
+	err = r.db.QueryRowContext(
+		ctx,
+		`WITH referencing AS (
+		 	SELECT pf.id
+		 	FROM project_files pf
+		 	WHERE pf.url = $1
+		 	   OR pf.preview_url = $1
+		 	   OR EXISTS (
+		 	   	SELECT 1 FROM project_file_versions v
+		 	   	WHERE v.file_id = pf.id AND v.url = $1
+		 	   )
+		 )
+		 SELECT
+		 	EXISTS (SELECT 1 FROM referencing),
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM referencing ref
+		 		JOIN project_files pf ON pf.id = ref.id
+		 		JOIN projects p ON p.id = pf.project_id
+		 		WHERE `+fileReadAccess("$2")+`
+		 	)`,
+		url,
+		userID,
+	).Scan(&referenced, &allowed)
+	return referenced, allowed, err
+}
+
+func (r *Repository) ListVersions(ctx context.Context, userID, fileID uuid.UUID) ([]FileVersion, error) {
+	var currentVersion int
+	if err := r.db.QueryRowContext(
+		ctx,
+		`SELECT pf.version
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.id = $1 AND `+fileReadAccess("$2"),
+		fileID,
+		userID,
+	).Scan(&currentVersion); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT v.id, v.file_id, v.version, v.url, v.type, v.size, v.uploaded_by,
+		        COALESCE(NULLIF(TRIM(u.full_name), ''), u.email, ''),
+		        v.restored_from, v.created_at
+		 FROM project_file_versions v
+		 LEFT JOIN users u ON u.id = v.uploaded_by
+		 WHERE v.file_id = $1
+		 ORDER BY v.version DESC`,
+		fileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]FileVersion, 0)
+	for rows.Next() {
+		item, err := scanFileVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		item.IsCurrent = item.Version == currentVersion
+		versions = append(versions, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetVersion returns a single version together with the file name, for
+// downloads. A zero version selects the current one.
+func (r *Repository) GetVersion(ctx context.Context, userID, fileID uuid.UUID, version int) (FileVersion, string, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT v.id, v.file_id, v.version, v.url, v.type, v.size, v.uploaded_by,
+		        COALESCE(NULLIF(TRIM(u.full_name), ''), u.email, ''),
+		        v.restored_from, v.created_at, pf.name, v.version = pf.version
+		 FROM project_file_versions v
+		 JOIN project_files pf ON pf.id = v.file_id
+		 JOIN projects p ON p.id = pf.project_id
+		 LEFT JOIN users u ON u.id = v.uploaded_by
+		 WHERE v.file_id = $1
+		   AND v.version = CASE WHEN $3::int > 0 THEN $3::int ELSE pf.version END
+		   AND `+fileReadAccess("$2"),
+		fileID,
+		userID,
+		version,
+	)
+
+	var (
+		item FileVersion
+		name string
+	)
 	if err := row.Scan(
-		&file.ID,
-		&file.ProjectID,
-		&file.URL,
-		&file.Type,
-		&file.Name,
-		&file.Size,
-		&file.CreatedAt,
+		&item.ID,
+		&item.FileID,
+		&item.Version,
+		&item.URL,
+		&item.Type,
+		&item.Size,
+		&item.UploadedBy,
+		&item.UploadedByName,
+		&item.RestoredFrom,
+		&item.CreatedAt,
+		&name,
+		&item.IsCurrent,
 	); err != nil {
+		return FileVersion{}, "", err
+	}
+
+	return item, name, nil
+}
+
+// RestoreVersion makes an old version current again. History stays linear:
+// the restored content is recorded as a new version.
+func (r *Repository) RestoreVersion(ctx context.Context, ownerID, fileID uuid.UUID, version int) (ProjectFile, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProjectFile{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var currentVersion int
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT pf.version
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.id = $1 AND p.owner_id = $2 AND pf.deleted_at IS NULL
+		 FOR UPDATE OF pf`,
+		fileID,
+		ownerID,
+	).Scan(&currentVersion); err != nil {
+		return ProjectFile{}, err
+	}
+
+	if version == currentVersion {
+		err = errors.New("cannot restore the current version")
+		return ProjectFile{}, err
+	}
+
+	var (
+		url      string
+		fileType string
+		size     int64
+	)
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT url, type, size FROM project_file_versions WHERE file_id = $1 AND version = $2`,
+		fileID,
+		version,
+	).Scan(&url, &fileType, &size); err != nil {
+		return ProjectFile{}, err
+	}
+
+	file, err := scanProjectFile(tx.QueryRowContext(
+		ctx,
+		`UPDATE project_files
+		 SET url = $2, type = $3, size = $4, version = version + 1, uploaded_by = $5,
+			     preview_url = NULL, preview_status = 'pending',
+			     content_text = NULL, index_status = 'pending', updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+projectFileColumns,
+		fileID,
+		url,
+		fileType,
+		size,
+		ownerID,
+	))
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	if err = insertVersionTx(ctx, tx, file, &version); err != nil {
+		return ProjectFile{}, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
 		return ProjectFile{}, err
 	}
 
@@ -48,14 +507,16 @@ func (r *Repository) Create(ctx context.Context, ownerID uuid.UUID, input Create
 }
 
 func (r *Repository) ListDocumentsByOwner(ctx context.Context, ownerID uuid.UUID) ([]Document, error) {
-	rows, err := r.db.QueryContext(
+	rows, err := r.reader(ctx).QueryContext(
 		ctx,
-		`SELECT pf.id, pf.project_id, p.title, pf.url, pf.type, pf.name, pf.size, pf.created_at
+		`SELECT pf.id, pf.project_id, p.title, pf.url, pf.type, pf.name, pf.size, pf.version,
+		        pf.preview_url, pf.preview_status, pf.created_at
 		 FROM project_files pf
 		 JOIN projects p ON p.id = pf.project_id
-		 WHERE p.owner_id = $1
+		 WHERE p.owner_id = $1 AND p.workspace_id = $2 AND pf.deleted_at IS NULL
 		 ORDER BY pf.created_at DESC`,
 		ownerID,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -73,6 +534,9 @@ func (r *Repository) ListDocumentsByOwner(ctx context.Context, ownerID uuid.UUID
 			&doc.Type,
 			&doc.Name,
 			&doc.Size,
+			&doc.Version,
+			&doc.PreviewURL,
+			&doc.PreviewStatus,
 			&doc.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -87,3 +551,324 @@ func (r *Repository) ListDocumentsByOwner(ctx context.Context, ownerID uuid.UUID
 
 	return documents, nil
 }
+
+// SetPreview stores a generated preview. Results for a version that has since
+// been replaced are ignored.
+func (r *Repository) SetPreview(ctx context.Context, result previews.Result) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE project_files
+		 SET preview_url = $3, preview_status = $4
+		 WHERE id = $1 AND version = $2`,
+		result.FileID,
+		result.Version,
+		result.PreviewURL,
+		result.Status,
+	)
+	return err
+}
+
+// ListPendingPreviews returns files still waiting for a preview, oldest first.
+func (r *Repository) ListPendingPreviews(ctx context.Context, limit int) ([]previews.Job, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, version, url
+		 FROM project_files
+		 WHERE preview_status = 'pending' AND deleted_at IS NULL
+		 ORDER BY updated_at
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]previews.Job, 0)
+	for rows.Next() {
+		var job previews.Job
+		if err := rows.Scan(&job.FileID, &job.Version, &job.SourceURL); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// SetIndexedText stores the extracted document text used for full-text
+// search. Results for a replaced version are ignored.
+func (r *Repository) SetIndexedText(ctx context.Context, fileID uuid.UUID, version int, text *string, status string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE project_files
+		 SET content_text = $3, index_status = $4
+		 WHERE id = $1 AND version = $2`,
+		fileID,
+		version,
+		text,
+		status,
+	)
+	return err
+}
+
+// ListPendingIndex returns files whose text has not been extracted yet.
+func (r *Repository) ListPendingIndex(ctx context.Context, limit int) ([]ProjectFile, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+projectFileColumns+`
+		 FROM project_files
+		 WHERE index_status = 'pending' AND deleted_at IS NULL
+		 ORDER BY updated_at
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]ProjectFile, 0)
+	for rows.Next() {
+		file, err := scanProjectFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Search finds project files whose name or extracted content matches query,
+// best matches first.
+func (r *Repository) Search(ctx context.Context, userID, projectID uuid.UUID, query string, limit int) ([]SearchResult, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT `+qualifiedProjectFileColumns+`,
+		        ts_rank(pf.search_vector, q.query) AS rank,
+		        CASE
+		        	WHEN pf.content_text IS NULL THEN ''
+		        	ELSE ts_headline('simple', pf.content_text, q.query,
+		        		'StartSel=**, StopSel=**, MaxWords=30, MinWords=10, MaxFragments=2')
+		        END
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 CROSS JOIN websearch_to_tsquery('simple', $3) AS q(query)
+		 WHERE pf.project_id = $1
+		   AND `+fileReadAccess("$2")+`
+		   AND (pf.search_vector @@ q.query OR pf.name ILIKE $4 ESCAPE '\')
+		 ORDER BY rank DESC, pf.updated_at DESC
+		 LIMIT $5`,
+		projectID,
+		userID,
+		query,
+		"%"+escapeLike(query)+"%",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]SearchResult, 0)
+	for rows.Next() {
+		var item SearchResult
+		if err := rows.Scan(
+			&item.ID,
+			&item.ProjectID,
+			&item.URL,
+			&item.Type,
+			&item.Name,
+			&item.Size,
+			&item.Version,
+			&item.UploadedBy,
+			&item.PreviewURL,
+			&item.PreviewStatus,
+			&item.Visibility,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.Rank,
+			&item.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
+func insertVersionTx(ctx context.Context, tx *sql.Tx, file ProjectFile, restoredFrom *int) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO project_file_versions (file_id, version, url, type, size, uploaded_by, restored_from)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		file.ID,
+		file.Version,
+		file.URL,
+		file.Type,
+		file.Size,
+		file.UploadedBy,
+		restoredFrom,
+	)
+	return err
+}
+
+func grantAccessTx(ctx context.Context, tx *sql.Tx, fileID uuid.UUID, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO project_file_access (file_id, user_id)
+			 SELECT pf.id, $2
+			 FROM project_files pf
+			 JOIN projects p ON p.id = pf.project_id
+			 WHERE pf.id = $1
+			   AND (
+			   	p.owner_id = $2
+			   	OR EXISTS (
+			   		SELECT 1 FROM project_members pm
+			   		WHERE pm.project_id = p.id AND pm.user_id = $2
+			   	)
+			   )
+			 ON CONFLICT (file_id, user_id) DO NOTHING`,
+			fileID,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return errors.New("cannot grant file access to a user outside the project")
+		}
+	}
+	return nil
+}
+
+func listAllowedUsers(ctx context.Context, tx *sql.Tx, fileID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT user_id FROM project_file_access WHERE file_id = $1 ORDER BY created_at, user_id`,
+		fileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+
+	return users, rows.Err()
+}
+
+// ReadAccess is fileReadAccess for queries outside the package, which must
+// alias project_files pf and projects p the same way.
+func ReadAccess(requesterParam string) string {
+	return fileReadAccess(requesterParam)
+}
+
+// fileReadAccess matches project files (aliased pf, project aliased p) the
+// requester may see. Trashed files are excluded. The owner and the uploader
+// always can; members are filtered by the file's visibility.
+func fileReadAccess(requesterParam string) string {
+	return `(pf.deleted_at IS NULL AND (
+		 	p.owner_id = ` + requesterParam + `
+		 	OR pf.uploaded_by = ` + requesterParam + `
+		 	OR EXISTS (
+		 		SELECT 1 FROM project_members pm
+		 		WHERE pm.project_id = p.id
+		 		  AND pm.user_id = ` + requesterParam + `
+		 		  AND (
+		 		  	pf.visibility = 'members'
+		 		  	OR (pf.visibility = 'managers' AND pm.role IN ('owner', 'manager'))
+		 		  	OR (pf.visibility = 'users' AND EXISTS (
+		 		  		SELECT 1 FROM project_file_access fa
+		 		  		WHERE fa.file_id = pf.id AND fa.user_id = ` + requesterParam + `
+		 		  	))
+		 		  )
+		 	)
+		 ))`
+}
+
+// projectManageAccess matches projects (aliased p) the requester owns or
+// manages.
+func projectManageAccess(requesterParam string) string {
+	return `(
+		 	p.owner_id = ` + requesterParam + `
+		 	OR EXISTS (
+		 		SELECT 1 FROM project_members pm
+		 		WHERE pm.project_id = p.id
+		 		  AND pm.user_id = ` + requesterParam + `
+		 		  AND pm.role IN ('owner', 'manager')
+		 	)
+		 )`
+}
+
+func scanProjectFile(row rowScanner) (ProjectFile, error) {
+	var file ProjectFile
+	if err := row.Scan(
+		&file.ID,
+		&file.ProjectID,
+		&file.URL,
+		&file.Type,
+		&file.Name,
+		&file.Size,
+		&file.Version,
+		&file.UploadedBy,
+		&file.PreviewURL,
+		&file.PreviewStatus,
+		&file.Visibility,
+		&file.CreatedAt,
+		&file.UpdatedAt,
+	); err != nil {
+		return ProjectFile{}, err
+	}
+	return file, nil
+}
+
+func scanFileVersion(row rowScanner) (FileVersion, error) {
+	var item FileVersion
+	if err := row.Scan(
+		&item.ID,
+		&item.FileID,
+		&item.Version,
+		&item.URL,
+		&item.Type,
+		&item.Size,
+		&item.UploadedBy,
+		&item.UploadedByName,
+		&item.RestoredFrom,
+		&item.CreatedAt,
+	); err != nil {
+		return FileVersion{}, err
+	}
+	return item, nil
+}
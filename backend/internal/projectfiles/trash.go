@@ -0,0 +1,333 @@
+package projectfiles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"tm-platform-backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTrashRetention is how long trashed files can be restored.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// MoveToTrash hides a file from the project and schedules it for removal
+// after retention. The uploader and project owners/managers may do this.
+func (r *Repository) MoveToTrash(ctx context.Context, requesterID, fileID uuid.UUID, retention time.Duration) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var allowed bool
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT pf.uploaded_by IS NOT DISTINCT FROM $2 OR `+projectManageAccess("$2")+`
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.id = $1 AND `+fileReadAccess("$2")+`
+		 FOR UPDATE OF pf`,
+		fileID,
+		requesterID,
+	).Scan(&allowed); err != nil {
+		return err
+	}
+	if !allowed {
+		err = ErrForbidden
+		return err
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`UPDATE project_files
+		 SET deleted_at = now(), deleted_by = $2, purge_at = now() + $3 * interval '1 second'
+		 WHERE id = $1`,
+		fileID,
+		requesterID,
+		int64(retention/time.Second),
+	); err != nil {
+		return err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return err
+	}
+
+	return nil
+}
+
+// ListTrash returns the trashed files of a project: all of them for owners
+// and managers, only their own uploads for other members.
+func (r *Repository) ListTrash(ctx context.Context, requesterID, projectID uuid.UUID) ([]TrashedFile, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+qualifiedProjectFileColumns+`, pf.deleted_at, pf.deleted_by, pf.purge_at
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.project_id = $1
+		   AND pf.deleted_at IS NOT NULL
+		   AND (pf.uploaded_by = $2 OR `+projectManageAccess("$2")+`)
+		 ORDER BY pf.deleted_at DESC`,
+		projectID,
+		requesterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]TrashedFile, 0)
+	for rows.Next() {
+		var item TrashedFile
+		if err := rows.Scan(
+			&item.ID,
+			&item.ProjectID,
+			&item.URL,
+			&item.Type,
+			&item.Name,
+			&item.Size,
+			&item.Version,
+			&item.UploadedBy,
+			&item.PreviewURL,
+			&item.PreviewStatus,
+			&item.Visibility,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.DeletedAt,
+			&item.DeletedBy,
+			&item.PurgeAt,
+		); err != nil {
+			return nil, err
+		}
+		files = append(files, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// RestoreFromTrash brings a trashed file back into its project.
+func (r *Repository) RestoreFromTrash(ctx context.Context, requesterID, fileID uuid.UUID) (file ProjectFile, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProjectFile{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		allowed   bool
+		projectID uuid.UUID
+		name      string
+	)
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT pf.uploaded_by IS NOT DISTINCT FROM $2 OR `+projectManageAccess("$2")+`,
+		        pf.project_id, pf.name
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE pf.id = $1
+		   AND pf.deleted_at IS NOT NULL
+		   AND (
+		   	p.owner_id = $2
+		   	OR EXISTS (
+		   		SELECT 1 FROM project_members pm
+		   		WHERE pm.project_id = p.id AND pm.user_id = $2
+		   	)
+		   )
+		 FOR UPDATE OF pf`,
+		fileID,
+		requesterID,
+	).Scan(&allowed, &projectID, &name); err != nil {
+		return ProjectFile{}, err
+	}
+	if !allowed {
+		err = ErrForbidden
+		return ProjectFile{}, err
+	}
+
+	var nameTaken bool
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1 FROM project_files
+		 	WHERE project_id = $1 AND lower(name) = lower($2) AND deleted_at IS NULL
+		 )`,
+		projectID,
+		name,
+	).Scan(&nameTaken); err != nil {
+		return ProjectFile{}, err
+	}
+	if nameTaken {
+		err = errors.New("cannot restore a file while another file with the same name exists")
+		return ProjectFile{}, err
+	}
+
+	file, err = scanProjectFile(tx.QueryRowContext(
+		ctx,
+		`UPDATE project_files
+		 SET deleted_at = NULL, deleted_by = NULL, purge_at = NULL, updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+projectFileColumns,
+		fileID,
+	))
+	if err != nil {
+		return ProjectFile{}, err
+	}
+
+	if file.Visibility == VisibilityUsers {
+		if file.AllowedUsers, err = listAllowedUsers(ctx, tx, file.ID); err != nil {
+			return ProjectFile{}, err
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return ProjectFile{}, err
+	}
+
+	return file, nil
+}
+
+// expiredTrash matches the trashed files whose restore window has passed or
+// that were trashed before $1, when it is not NULL.
+const expiredTrash = `(purge_at <= now() OR deleted_at <= $1::timestamptz)`
+
+// CountExpiredTrash counts the files PurgeExpiredTrash would delete.
+func (r *Repository) CountExpiredTrash(ctx context.Context, trashedBefore *time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*)::int FROM project_files WHERE `+expiredTrash,
+		trashedBefore,
+	).Scan(&count)
+	return count, err
+}
+
+// PurgeExpiredTrash permanently deletes up to limit files whose retention has
+// passed, or that were trashed before trashedBefore when it is set. It
+// returns how many files were deleted and the storage keys that are no longer
+// referenced by any project file or chat message.
+func (r *Repository) PurgeExpiredTrash(ctx context.Context, trashedBefore *time.Time, limit int) (purged int, keys []string, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id
+		 FROM project_files
+		 WHERE `+expiredTrash+`
+		 ORDER BY deleted_at
+		 LIMIT $2
+		 FOR UPDATE SKIP LOCKED`,
+		trashedBefore,
+		limit,
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+	fileIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		fileIDs = append(fileIDs, id)
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return 0, nil, err
+	}
+	rows.Close()
+
+	urls := make(map[string]struct{})
+	for _, fileID := range fileIDs {
+		if err = collectFileURLsTx(ctx, tx, fileID, urls); err != nil {
+			return 0, nil, err
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM project_files WHERE id = $1`, fileID); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for url := range urls {
+		key, ok := storage.KeyFromURL(url)
+		if !ok {
+			continue
+		}
+
+		var referenced bool
+		if err = tx.QueryRowContext(
+			ctx,
+			`SELECT EXISTS (SELECT 1 FROM project_files WHERE url = $1 OR preview_url = $1)
+			     OR EXISTS (SELECT 1 FROM project_file_versions WHERE url = $1)
+			     OR EXISTS (SELECT 1 FROM chat_messages WHERE attachment_url = $1)`,
+			url,
+		).Scan(&referenced); err != nil {
+			return 0, nil, err
+		}
+		if referenced {
+			continue
+		}
+
+		if _, err = tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = $1`, key); err != nil {
+			return 0, nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return 0, nil, err
+	}
+
+	return len(fileIDs), keys, nil
+}
+
+func collectFileURLsTx(ctx context.Context, tx *sql.Tx, fileID uuid.UUID, urls map[string]struct{}) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT url FROM project_file_versions WHERE file_id = $1
+		 UNION
+		 SELECT url FROM project_files WHERE id = $1
+		 UNION
+		 SELECT preview_url FROM project_files WHERE id = $1 AND preview_url IS NOT NULL`,
+		fileID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return err
+		}
+		urls[url] = struct{}{}
+	}
+	return rows.Err()
+}
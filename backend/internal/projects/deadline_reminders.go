@@ -0,0 +1,121 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/jobs"
+	"tm-platform-backend/internal/notifications"
+
+	"github.com/google/uuid"
+)
+
+// JobDeadlineReminders is the job kind reminding assignees of open tasks
+// whose deadline is near.
+const JobDeadlineReminders = "projects.deadline_reminders"
+
+const (
+	// deadlineReminderWindow is how long before its deadline a task is
+	// reminded of
+	deadlineReminderWindow = 24 * time.Hour
+	deadlineReminderBatch  = 200
+	// deadlineReminderLayout shows the deadline in the zone of its project,
+	// naming the offset since assignees may sit elsewhere
+	deadlineReminderLayout = "02.01.2006 15:04 (UTC-07:00)"
+)
+
+type dueTask struct {
+	ID       uuid.UUID
+	Title    string
+	Deadline time.Time
+	Blocks   []byte
+}
+
+// listTasksDueSoon returns open tasks whose deadline falls within window and
+// that were not reminded of it yet.
+func (r *Repository) listTasksDueSoon(ctx context.Context, window time.Duration, limit int) ([]dueTask, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT t.id, t.title, t.deadline, t.blocks, p.timezone
+		 FROM stage_tasks t
+		 JOIN project_stages s ON s.id = t.stage_id
+		 JOIN projects p ON p.id = s.project_id
+		 WHERE t.status <> 'done'
+		   AND t.deadline_reminded_at IS NULL
+		   AND t.deadline > now()
+		   AND t.deadline <= $1
+		 ORDER BY t.deadline
+		 LIMIT $2`,
+		time.Now().Add(window).UTC(),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]dueTask, 0)
+	for rows.Next() {
+		var (
+			task     dueTask
+			timezone string
+		)
+		if err := rows.Scan(&task.ID, &task.Title, &task.Deadline, &task.Blocks, &timezone); err != nil {
+			return nil, err
+		}
+		task.Deadline = task.Deadline.In(loadLocation(timezone))
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (r *Repository) markDeadlineReminded(ctx context.Context, taskID uuid.UUID) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE stage_tasks SET deadline_reminded_at = now() WHERE id = $1`,
+		taskID,
+	)
+	return err
+}
+
+// DeadlineReminders returns the handler of JobDeadlineReminders. Each task is
+// reminded of once per deadline; moving the deadline re-arms the reminder.
+func DeadlineReminders(repo *Repository, notificationsRepo *notifications.Repository) jobs.HandlerFunc {
+	return func(ctx context.Context, _ json.RawMessage) error {
+		tasks, err := repo.listTasksDueSoon(ctx, deadlineReminderWindow, deadlineReminderBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, task := range tasks {
+			assigneeIDs, err := repo.ResolveUserIDsByRefs(ctx, assigneesFromBlocks(task.Blocks))
+			if err != nil {
+				return err
+			}
+
+			taskID := task.ID
+			if err := notificationsRepo.Deliver(ctx, notifications.Fanout{
+				UserIDs:    assigneeIDs,
+				Kind:       notifications.KindTaskDeadline,
+				Title:      i18n.M("notification.task_deadline.title"),
+				Body:       i18n.M("notification.task_deadline.body", "title", task.Title, "deadline", task.Deadline.Format(deadlineReminderLayout)),
+				Link:       "/project/task-" + task.ID.String(),
+				EntityType: "task",
+				EntityID:   &taskID,
+			}); err != nil {
+				return err
+			}
+			if err := repo.markDeadlineReminded(ctx, task.ID); err != nil {
+				return err
+			}
+		}
+
+		if len(tasks) > 0 {
+			log.Printf("deadline reminders sent for %d tasks", len(tasks))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,201 @@
+package projects
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// The List*By* methods below read the children of many parents in one
+// query, for the batching loaders of the GraphQL API. Parents the requester
+// cannot read get no children.
+
+func idStrings(ids []uuid.UUID) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.String())
+	}
+	return out
+}
+
+// ListStagesByProjects returns the stages of projectIDs by project.
+func (r *Repository) ListStagesByProjects(ctx context.Context, requesterID uuid.UUID, projectIDs []uuid.UUID) (map[uuid.UUID][]Stage, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT s.id, s.project_id, s.title, s.order_index
+		 FROM project_stages s
+		 WHERE s.project_id = ANY($1::uuid[])
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("s.project_id", "$2")+`
+		   )
+		 ORDER BY s.project_id, s.order_index ASC, s.created_at ASC`,
+		idStrings(projectIDs),
+		requesterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stages := make(map[uuid.UUID][]Stage, len(projectIDs))
+	for rows.Next() {
+		var stage Stage
+		if err := rows.Scan(&stage.ID, &stage.ProjectID, &stage.Title, &stage.OrderIndex); err != nil {
+			return nil, err
+		}
+		stages[stage.ProjectID] = append(stages[stage.ProjectID], stage)
+	}
+
+	return stages, rows.Err()
+}
+
+// ListTasksByStages returns the first limit tasks of each of stageIDs by
+// stage.
+func (r *Repository) ListTasksByStages(ctx context.Context, requesterID uuid.UUID, stageIDs []uuid.UUID, limit int) (map[uuid.UUID][]Task, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT id, stage_id, project_id, title, status, start_date, deadline, order_index, blocks, updated_at, timezone
+		 FROM (
+		 	SELECT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at, t.created_at,
+		 	       (SELECT timezone FROM projects WHERE id = s.project_id) AS timezone,
+		 	       ROW_NUMBER() OVER (PARTITION BY t.stage_id ORDER BY t.order_index ASC, t.created_at ASC, t.id ASC) AS position
+		 	FROM stage_tasks t
+		 	JOIN project_stages s ON s.id = t.stage_id
+		 	WHERE t.stage_id = ANY($1::uuid[])
+		 	  AND (
+		 		EXISTS (
+		 			SELECT 1
+		 			FROM project_members pm
+		 			WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		 		)
+		 		OR `+hierarchyReadAccess("s.project_id", "$2")+`
+		 	  )
+		 ) ranked
+		 WHERE position <= $3
+		 ORDER BY stage_id, position`,
+		idStrings(stageIDs),
+		requesterID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make(map[uuid.UUID][]Task, len(stageIDs))
+	for rows.Next() {
+		task, scanErr := scanTask(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		tasks[task.StageID] = append(tasks[task.StageID], task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// ListMembersByProjects returns the members of projectIDs by project, with
+// the owner, as ListMembersByProject does.
+func (r *Repository) ListMembersByProjects(ctx context.Context, requesterID uuid.UUID, projectIDs []uuid.UUID) (map[uuid.UUID][]ProjectMemberResponse, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`WITH allowed AS (
+			SELECT p.id, p.owner_id, p.created_at
+			FROM projects p
+			WHERE p.id = ANY($1::uuid[])
+			  AND (
+				p.owner_id = $2
+				OR EXISTS (
+					SELECT 1
+					FROM project_members me
+					WHERE me.project_id = p.id AND me.user_id = $2
+				)
+			  )
+		),
+		members AS (
+			SELECT pm.project_id, u.id, u.email, COALESCE(u.full_name, '') AS full_name, pm.role, pm.created_at
+			FROM allowed a
+			JOIN project_members pm ON pm.project_id = a.id
+			JOIN users u ON u.id = pm.user_id
+			UNION ALL
+			SELECT a.id, u_owner.id, u_owner.email, COALESCE(u_owner.full_name, ''), 'owner'::text, a.created_at
+			FROM allowed a
+			JOIN users u_owner ON u_owner.id = a.owner_id
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM project_members pm_owner
+				WHERE pm_owner.project_id = a.id
+				  AND pm_owner.user_id = a.owner_id
+			)
+		)
+		SELECT m.project_id, m.id, m.email, m.full_name, m.role
+		FROM members m
+		ORDER BY m.project_id, m.created_at ASC, m.email ASC`,
+		idStrings(projectIDs),
+		requesterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[uuid.UUID][]ProjectMemberResponse, len(projectIDs))
+	for rows.Next() {
+		var (
+			projectID uuid.UUID
+			member    ProjectMemberResponse
+			role      string
+		)
+		if err := rows.Scan(&projectID, &member.User.ID, &member.User.Email, &member.User.FullName, &role); err != nil {
+			return nil, err
+		}
+		member.Role = ProjectMemberRole(role)
+		members[projectID] = append(members[projectID], member)
+	}
+
+	return members, rows.Err()
+}
+
+// ListRecentTaskComments returns up to limit of the latest comments of each
+// of taskIDs by task, oldest first.
+func (r *Repository) ListRecentTaskComments(ctx context.Context, requesterID uuid.UUID, taskIDs []uuid.UUID, limit int) (map[uuid.UUID][]TaskCommentResponse, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT id, task_id, project_id, user_id, message, created_at, author_id, author_email
+		 FROM (
+		 	SELECT tc.id, tc.task_id, s.project_id, tc.user_id, tc.message, tc.created_at, u.id AS author_id, u.email AS author_email,
+		 	       ROW_NUMBER() OVER (PARTITION BY tc.task_id ORDER BY tc.created_at DESC, tc.id DESC) AS position
+		 	FROM task_comments tc
+		 	JOIN stage_tasks t ON t.id = tc.task_id
+		 	JOIN project_stages s ON s.id = t.stage_id
+		 	JOIN project_members pm ON pm.project_id = s.project_id AND pm.user_id = $2
+		 	JOIN users u ON u.id = tc.user_id
+		 	WHERE tc.task_id = ANY($1::uuid[])
+		 ) recent
+		 WHERE position <= $3
+		 ORDER BY task_id, created_at ASC, id ASC`,
+		idStrings(taskIDs),
+		requesterID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make(map[uuid.UUID][]TaskCommentResponse, len(taskIDs))
+	for rows.Next() {
+		comment, scanErr := scanTaskCommentResponse(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		comments[comment.TaskID] = append(comments[comment.TaskID], comment)
+	}
+
+	return comments, rows.Err()
+}
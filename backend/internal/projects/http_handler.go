@@ -1,7 +1,6 @@
 package projects
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"log"
@@ -12,12 +11,23 @@ import (
 	"time"
 
 	"tm-platform-backend/internal/auth"
-	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/etag"
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// Page sizes of the list endpoints.
+var (
+	projectsPageLimits     = pagination.Limits{Default: 50, Max: 200}
+	expensesPageLimits     = pagination.Limits{Default: 100, Max: 500}
+	taskCommentsPageLimits = pagination.Limits{Default: 100, Max: 500}
+)
+
 var taskCommentMentionPattern = regexp.MustCompile(`(?i)(?:^|\s)@([a-z0-9._%+\-]+(?:@[a-z0-9.\-]+\.[a-z]{2,})?)`)
 
 func extractMentionedRefs(message string) map[string]struct{} {
@@ -37,19 +47,16 @@ func extractMentionedRefs(message string) map[string]struct{} {
 }
 
 type updateProjectHTTPReq struct {
-	Title                *string         `json:"title"`
-	Budget               *int64          `json:"budget"`
-	CoverURL             *string         `json:"coverUrl"`
-	CoverURLAlt          *string         `json:"cover_url"`
-	IconURL              *string         `json:"iconUrl"`
-	IconURLAlt           *string         `json:"icon_url"`
-	StartDate            *string         `json:"startDate"`
-	StartDateAlt         *string         `json:"start_date"`
-	Deadline             *string         `json:"deadline"`
-	ExpectedUpdatedAt    *string         `json:"expectedUpdatedAt"`
-	ExpectedUpdatedAtAlt *string         `json:"expected_updated_at"`
-	BlocksJSON           json.RawMessage `json:"blocks_json"`
-	Blocks               json.RawMessage `json:"blocks"`
+	Title             *string         `json:"title"`
+	Budget            *int64          `json:"budget"`
+	CoverURL          *string         `json:"coverUrl"`
+	IconURL           *string         `json:"iconUrl"`
+	StartDate         *string         `json:"startDate" validate:"date"`
+	Deadline          *string         `json:"deadline" validate:"date"`
+	Timezone          *string         `json:"timezone" validate:"timezone"`
+	ExpectedUpdatedAt *string         `json:"expectedUpdatedAt"` // superseded by If-Match
+	BlocksJSON        json.RawMessage `json:"blocks_json"`
+	Blocks            json.RawMessage `json:"blocks"`
 }
 
 func buildProjectUpdateInput(req updateProjectHTTPReq, current Project) (ProjectInput, error) {
@@ -71,21 +78,25 @@ func buildProjectUpdateInput(req updateProjectHTTPReq, current Project) (Project
 	}
 
 	coverURL := current.CoverURL
-	if req.CoverURL != nil || req.CoverURLAlt != nil {
-		value := firstNonNilString(req.CoverURL, req.CoverURLAlt)
-		coverURL = normalizeOptionalStringPtr(value)
+	if req.CoverURL != nil {
+		coverURL = normalizeOptionalStringPtr(req.CoverURL)
 	}
 
 	iconURL := current.IconURL
-	if req.IconURL != nil || req.IconURLAlt != nil {
-		value := firstNonNilString(req.IconURL, req.IconURLAlt)
-		iconURL = normalizeOptionalStringPtr(value)
+	if req.IconURL != nil {
+		iconURL = normalizeOptionalStringPtr(req.IconURL)
+	}
+
+	timezone := ""
+	loc := loadLocation(current.Timezone)
+	if req.Timezone != nil && strings.TrimSpace(*req.Timezone) != "" {
+		timezone = strings.TrimSpace(*req.Timezone)
+		loc = loadLocation(timezone)
 	}
 
 	startDate := current.StartDate
-	if req.StartDate != nil || req.StartDateAlt != nil {
-		value := firstNonNilString(req.StartDate, req.StartDateAlt)
-		parsed, err := parseDateString(derefOrEmpty(value))
+	if req.StartDate != nil {
+		parsed, err := ParseDate(*req.StartDate, loc)
 		if err != nil {
 			return ProjectInput{}, errors.New("invalid startDate")
 		}
@@ -98,7 +109,7 @@ func buildProjectUpdateInput(req updateProjectHTTPReq, current Project) (Project
 	}
 	deadline := currentDeadline
 	if req.Deadline != nil {
-		parsed, err := parseDateString(derefOrEmpty(req.Deadline))
+		parsed, err := ParseDeadline(derefOrEmpty(req.Deadline), loc)
 		if err != nil {
 			return ProjectInput{}, errors.New("invalid deadline")
 		}
@@ -124,6 +135,7 @@ func buildProjectUpdateInput(req updateProjectHTTPReq, current Project) (Project
 		Status:      current.Status,
 		TotalBudget: budget,
 		Blocks:      blocks,
+		Timezone:    timezone,
 	}, nil
 }
 
@@ -138,15 +150,6 @@ func normalizeOptionalStringPtr(value *string) *string {
 	return &trimmed
 }
 
-func firstNonNilString(values ...*string) *string {
-	for _, value := range values {
-		if value != nil {
-			return value
-		}
-	}
-	return nil
-}
-
 func derefOrEmpty(value *string) string {
 	if value == nil {
 		return ""
@@ -155,8 +158,8 @@ func derefOrEmpty(value *string) string {
 }
 
 type HTTPHandler struct {
-	repo              *Repository
-	notificationsRepo *notifications.Repository
+	repo   *Repository
+	events *events.Bus
 }
 
 type workspaceStageItem struct {
@@ -194,44 +197,10 @@ type workspaceContextResponse struct {
 	LoadedAt      time.Time              `json:"loaded_at"`
 }
 
-func NewHTTPHandler(repo *Repository, notificationsRepo *notifications.Repository) *HTTPHandler {
-	return &HTTPHandler{repo: repo, notificationsRepo: notificationsRepo}
-}
-
-func (h *HTTPHandler) notifyUsers(ctx context.Context, userIDs []uuid.UUID, actorID uuid.UUID, kind notifications.Kind, title, body, link, entityType string, entityID *uuid.UUID) {
-	if h.notificationsRepo == nil {
-		return
-	}
-
-	seen := make(map[uuid.UUID]struct{}, len(userIDs))
-	for _, userID := range userIDs {
-		if userID == uuid.Nil || userID == actorID {
-			continue
-		}
-		if _, ok := seen[userID]; ok {
-			continue
-		}
-		seen[userID] = struct{}{}
-
-		var actor *uuid.UUID
-		if actorID != uuid.Nil {
-			actor = &actorID
-		}
-		if err := h.notificationsRepo.Create(ctx, userID, actor, kind, title, body, link, entityType, entityID); err != nil {
-			log.Printf("notification create failed: %v", err)
-		}
-	}
-}
-
-func roleTitle(role ProjectMemberRole) string {
-	switch role {
-	case ProjectMemberRoleOwner:
-		return "Владелец"
-	case ProjectMemberRoleManager:
-		return "Менеджер"
-	default:
-		return "Участник"
-	}
+// NewHTTPHandler publishes what the handlers change on bus, where
+// notifications and other modules subscribe.
+func NewHTTPHandler(repo *Repository, bus *events.Bus) *HTTPHandler {
+	return &HTTPHandler{repo: repo, events: bus}
 }
 
 func (h *HTTPHandler) RequireEditAccess(projectIDParam string) func(http.Handler) http.Handler {
@@ -239,24 +208,24 @@ func (h *HTTPHandler) RequireEditAccess(projectIDParam string) func(http.Handler
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userID, err := userIDFromRequest(r)
 			if err != nil {
-				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+				problem.Error(w, http.StatusUnauthorized, err.Error())
 				return
 			}
 
 			projectID, err := uuid.Parse(chi.URLParam(r, projectIDParam))
 			if err != nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+				problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 				return
 			}
 
 			allowed, err := h.repo.HasEditAccess(r.Context(), userID, projectID)
 			if err != nil {
 				log.Printf("RequireEditAccess failed: %v", err)
-				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate access"})
+				problem.Error(w, http.StatusInternalServerError, "failed to validate access")
 				return
 			}
 			if !allowed {
-				writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+				problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 				return
 			}
 
@@ -266,16 +235,15 @@ func (h *HTTPHandler) RequireEditAccess(projectIDParam string) func(http.Handler
 }
 
 type CreateProjectRequest struct {
-	Title        string          `json:"title"`
-	Budget       int64           `json:"budget"`
-	StartDate    string          `json:"startDate"`
-	StartDateAlt string          `json:"start_date"`
-	Deadline     string          `json:"deadline"`
-	CoverUrl     string          `json:"coverUrl"`
-	CoverUrlAlt  string          `json:"cover_url"`
-	IconUrl      string          `json:"iconUrl"`
-	IconUrlAlt   string          `json:"icon_url"`
-	Blocks       json.RawMessage `json:"blocks"`
+	Title     string          `json:"title" validate:"required"`
+	Budget    int64           `json:"budget" validate:"min=0"`
+	StartDate string          `json:"startDate" validate:"date"`
+	Deadline  string          `json:"deadline" validate:"date"`
+	CoverUrl  string          `json:"coverUrl"`
+	IconUrl   string          `json:"iconUrl"`
+	Blocks    json.RawMessage `json:"blocks"`
+	// Timezone defaults to the creator's, see Repository.UserLocation
+	Timezone string `json:"timezone" validate:"timezone"`
 }
 
 type createStageRequest struct {
@@ -289,28 +257,23 @@ type updateStageRequest struct {
 }
 
 type createTaskRequest struct {
-	Title        string  `json:"title"`
-	Status       string  `json:"status"`
-	StartDate    *string `json:"startDate"`
-	StartDateAlt *string `json:"start_date"`
-	Deadline     *string `json:"deadline"`
-	OrderIndex   *int    `json:"order_index"`
+	Title      string  `json:"title"`
+	Status     string  `json:"status"`
+	StartDate  *string `json:"startDate" validate:"date"`
+	Deadline   *string `json:"deadline" validate:"date"`
+	OrderIndex *int    `json:"order_index"`
 }
 
 type updateTaskRequest struct {
-	Title                *string         `json:"title"`
-	Status               *string         `json:"status"`
-	StartDate            *string         `json:"startDate"`
-	StartDateAlt         *string         `json:"start_date"`
-	Deadline             *string         `json:"deadline"`
-	StageID              *string         `json:"stageId"`
-	StageIDAlt           *string         `json:"stage_id"`
-	AssignmentMode       *string         `json:"assignmentMode"`
-	AssignmentModeAlt    *string         `json:"assignment_mode"`
-	OrderIndex           *int            `json:"order_index"`
-	ExpectedUpdatedAt    *string         `json:"expectedUpdatedAt"`
-	ExpectedUpdatedAtAlt *string         `json:"expected_updated_at"`
-	Blocks               json.RawMessage `json:"blocks"`
+	Title             *string         `json:"title"`
+	Status            *string         `json:"status"`
+	StartDate         *string         `json:"startDate" validate:"date"`
+	Deadline          *string         `json:"deadline" validate:"date"`
+	StageID           *string         `json:"stageId" validate:"uuid"`
+	AssignmentMode    *string         `json:"assignmentMode"`
+	OrderIndex        *int            `json:"order_index"`
+	ExpectedUpdatedAt *string         `json:"expectedUpdatedAt"` // superseded by If-Match
+	Blocks            json.RawMessage `json:"blocks"`
 }
 
 type createExpenseHTTPReq struct {
@@ -318,16 +281,22 @@ type createExpenseHTTPReq struct {
 	Amount *int64  `json:"amount"`
 }
 
+type createMeetingHTTPReq struct {
+	Title           string   `json:"title"`
+	StartsAt        string   `json:"starts_at"`
+	DurationMinutes int      `json:"duration_minutes"`
+	Notes           string   `json:"notes"`
+	Attendees       []string `json:"attendees"`
+}
+
 type upsertProjectMemberReq struct {
 	UserID *string `json:"userId"`
 	Role   *string `json:"role"`
 }
 
 type updateProjectRolesReq struct {
-	ManagerID    *string  `json:"managerId"`
-	ManagerIDAlt *string  `json:"manager_id"`
-	MemberIDs    []string `json:"memberIds"`
-	MemberIDsAlt []string `json:"member_ids"`
+	ManagerID *string  `json:"managerId" validate:"uuid"`
+	MemberIDs []string `json:"memberIds" validate:"uuid"`
 }
 
 type createProjectPageReq struct {
@@ -337,11 +306,9 @@ type createProjectPageReq struct {
 }
 
 type createDelayReportReq struct {
-	StageID    *string `json:"stageId"`
-	StageIDAlt *string `json:"stage_id"`
-	TaskID     *string `json:"taskId"`
-	TaskIDAlt  *string `json:"task_id"`
-	Message    *string `json:"message"`
+	StageID *string `json:"stageId" validate:"uuid"`
+	TaskID  *string `json:"taskId" validate:"uuid"`
+	Message *string `json:"message"`
 }
 
 type createTaskCommentReq struct {
@@ -353,9 +320,8 @@ type createReportChatReq struct {
 }
 
 type createDelayReportCommentReq struct {
-	Message     *string `json:"message"`
-	ParentID    *string `json:"parentId"`
-	ParentIDAlt *string `json:"parent_id"`
+	Message  *string `json:"message"`
+	ParentID *string `json:"parentId" validate:"uuid"`
 }
 
 type updateProjectPageReq struct {
@@ -380,53 +346,45 @@ func (h *HTTPHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	var req CreateProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
-	if strings.TrimSpace(req.Title) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is required"})
-		return
+	timezone := strings.TrimSpace(req.Timezone)
+	loc := loadLocation(timezone)
+	if timezone == "" {
+		if loc, err = h.repo.UserLocation(r.Context(), userID); err != nil {
+			log.Printf("CreateProject load time zone failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to create project")
+			return
+		}
 	}
 
-	startDate, err := parseDateString(req.StartDate)
+	startDate, err := ParseDate(req.StartDate, loc)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid startDate"})
+		problem.Write(w, http.StatusBadRequest, "invalid_start_date", "invalid startDate")
 		return
 	}
-	if startDate == nil && strings.TrimSpace(req.StartDateAlt) != "" {
-		startDate, err = parseDateString(req.StartDateAlt)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid startDate"})
-			return
-		}
-	}
 
-	deadline, err := parseDateString(req.Deadline)
+	deadline, err := ParseDeadline(req.Deadline, loc)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid deadline"})
+		problem.Write(w, http.StatusBadRequest, "invalid_deadline", "invalid deadline")
 		return
 	}
 
 	coverValue := strings.TrimSpace(req.CoverUrl)
-	if coverValue == "" {
-		coverValue = strings.TrimSpace(req.CoverUrlAlt)
-	}
 	var coverURL *string
 	if coverValue != "" {
 		coverURL = &coverValue
 	}
 
 	iconValue := strings.TrimSpace(req.IconUrl)
-	if iconValue == "" {
-		iconValue = strings.TrimSpace(req.IconUrlAlt)
-	}
 	var iconURL *string
 	if iconValue != "" {
 		iconURL = &iconValue
@@ -445,27 +403,22 @@ func (h *HTTPHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		StartDate:   startDate,
 		Deadline:    deadline,
 		EndDate:     deadline,
+		Timezone:    loc.String(),
 		Status:      ProjectStatusActive,
 		TotalBudget: req.Budget,
 		Blocks:      blocks,
 	})
 	if err != nil {
 		log.Printf("CreateProject failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create project"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create project")
 		return
 	}
 
-	h.notifyUsers(
-		r.Context(),
-		[]uuid.UUID{userID},
-		uuid.Nil,
-		notifications.KindProjectCreated,
-		"Проект создан",
-		"Вы успешно создали новый проект: "+project.Title,
-		"/project-overview/"+project.ID.String(),
-		"project",
-		&project.ID,
-	)
+	h.events.Publish(r.Context(), events.ProjectCreated{
+		ProjectID: project.ID,
+		ActorID:   userID,
+		Title:     project.Title,
+	})
 
 	writeJSON(w, http.StatusCreated, project.Response())
 }
@@ -473,48 +426,59 @@ func (h *HTTPHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	projects, err := h.repo.ListByOwner(r.Context(), userID)
+	page, err := pagination.Parse(r, projectsPageLimits)
+	if err != nil {
+		pagination.WriteError(w, err)
+		return
+	}
+
+	projects, err := h.repo.ListByOwnerPage(r.Context(), userID, page)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
 		log.Printf("ListProjects failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch projects"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch projects")
 		return
 	}
 
-	responses := make([]ProjectResponse, 0, len(projects))
-	for _, project := range projects {
+	responses := make([]ProjectResponse, 0, len(projects.Items))
+	for _, project := range projects.Items {
 		responses = append(responses, project.Response())
 	}
 
-	writeJSON(w, http.StatusOK, responses)
+	pagination.SetHeaders(w, r, projects)
+	etag.WriteJSON(w, r, http.StatusOK, responses)
 }
 
 func (h *HTTPHandler) WorkspaceContext(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projects, err := h.repo.ListByOwner(r.Context(), userID)
 	if err != nil {
 		log.Printf("WorkspaceContext projects failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load projects"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load projects")
 		return
 	}
 	stages, err := h.repo.ListStagesByUser(r.Context(), userID)
 	if err != nil {
 		log.Printf("WorkspaceContext stages failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load stages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load stages")
 		return
 	}
 	tasks, err := h.repo.ListTasksByUser(r.Context(), userID)
 	if err != nil {
 		log.Printf("WorkspaceContext tasks failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load tasks"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load tasks")
 		return
 	}
 
@@ -613,133 +577,133 @@ func (h *HTTPHandler) WorkspaceContext(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	projectID, err := uuid.Parse(id)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	project, err := h.repo.GetByID(r.Context(), userID, projectID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
 			return
 		}
 		log.Printf("GetProject failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load project"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load project")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, project.Response())
+	etag.WriteJSON(w, r, http.StatusOK, project.Response())
 }
 
 func (h *HTTPHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	projectID, err := uuid.Parse(id)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req updateProjectHTTPReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.BlocksJSON != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "page updates must use PATCH /projects/:projectId/pages/:pageId"})
+		problem.Write(w, http.StatusBadRequest, "use_patch", "page updates must use PATCH /projects/:projectId/pages/:pageId")
 		return
 	}
 
 	currentProject, err := h.repo.GetByID(r.Context(), userID, projectID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
 			return
 		}
 		log.Printf("UpdateProject load failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load project"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load project")
 		return
 	}
 
-	expectedUpdatedAt, err := parseExpectedUpdatedAt(req.ExpectedUpdatedAt, req.ExpectedUpdatedAtAlt)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if expectedUpdatedAt != nil && !currentProject.UpdatedAt.UTC().Equal(expectedUpdatedAt.UTC()) {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": "данные проекта изменились в другой вкладке, обновите страницу"})
+	if !checkVersion(w, r, currentProject.Response(), currentProject.UpdatedAt, req.ExpectedUpdatedAt, "данные проекта изменились в другой вкладке, обновите страницу") {
 		return
 	}
 
 	updateInput, err := buildProjectUpdateInput(req, currentProject)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	project, err := h.repo.Update(r.Context(), userID, projectID, updateInput)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
 			return
 		}
 		log.Printf("UpdateProject failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update project"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update project")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, project.Response())
+	h.events.Publish(r.Context(), events.ProjectUpdated{
+		ProjectID: project.ID,
+		ActorID:   userID,
+		Title:     project.Title,
+	})
+
+	etag.WriteJSON(w, r, http.StatusOK, project.Response())
 }
 
 func (h *HTTPHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	projectID, err := uuid.Parse(id)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	if err := h.repo.Delete(r.Context(), userID, projectID); err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
 			return
 		}
 		log.Printf("DeleteProject failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete project"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete project")
 		return
 	}
 
@@ -749,19 +713,19 @@ func (h *HTTPHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req createProjectPageReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
@@ -775,99 +739,106 @@ func (h *HTTPHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
 	page, err := h.repo.CreatePage(r.Context(), userID, projectID, title, blocks)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found or forbidden"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found or forbidden")
 			return
 		}
 		log.Printf("CreatePage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create page"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create page")
 		return
 	}
 
+	h.events.Publish(r.Context(), events.PageSaved{
+		PageID:    page.ID,
+		ProjectID: page.ProjectID,
+		ActorID:   userID,
+		Title:     page.Title,
+	})
+
 	writeJSON(w, http.StatusCreated, page)
 }
 
 func (h *HTTPHandler) ListPages(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	pages, err := h.repo.ListPagesByProject(r.Context(), userID, projectID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found or forbidden"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found or forbidden")
 			return
 		}
 		log.Printf("ListPages failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list pages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to list pages")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, pages)
+	etag.WriteJSON(w, r, http.StatusOK, pages)
 }
 
 func (h *HTTPHandler) GetPage(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	pageID, err := uuid.Parse(chi.URLParam(r, "pageId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid page id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_page_id", "invalid page id")
 		return
 	}
 
 	page, err := h.repo.GetPageByProjectID(r.Context(), userID, projectID, pageID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "page not found"})
+			problem.Write(w, http.StatusNotFound, "page_not_found", "page not found")
 			return
 		}
 		log.Printf("GetPage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load page"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load page")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, page)
+	etag.WriteJSON(w, r, http.StatusOK, page)
 }
 
 func (h *HTTPHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	pageID, err := uuid.Parse(chi.URLParam(r, "pageId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid page id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_page_id", "invalid page id")
 		return
 	}
 
 	var req updateProjectPageReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
@@ -878,41 +849,64 @@ func (h *HTTPHandler) UpdatePage(w http.ResponseWriter, r *http.Request) {
 
 	blocks := normalizePageBlocks(req.BlocksJSON, req.Blocks)
 
+	if r.Header.Get("If-Match") != "" {
+		currentPage, err := h.repo.GetPageByProjectID(r.Context(), userID, projectID, pageID)
+		if err != nil {
+			if IsNotFound(err) {
+				problem.Write(w, http.StatusNotFound, "page_not_found", "page not found or forbidden")
+				return
+			}
+			log.Printf("UpdatePage load failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to load page")
+			return
+		}
+		if !checkVersion(w, r, currentPage, currentPage.UpdatedAt, nil, "страница изменилась в другой вкладке, обновите страницу") {
+			return
+		}
+	}
+
 	page, err := h.repo.UpdatePageByProjectID(r.Context(), userID, projectID, pageID, title, blocks)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "page not found or forbidden"})
+			problem.Write(w, http.StatusNotFound, "page_not_found", "page not found or forbidden")
 			return
 		}
 		log.Printf("UpdatePage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update page"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update page")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, page)
+	h.events.Publish(r.Context(), events.PageSaved{
+		PageID:    page.ID,
+		ProjectID: page.ProjectID,
+		ActorID:   userID,
+		Title:     page.Title,
+	})
+
+	etag.WriteJSON(w, r, http.StatusOK, page)
 }
 
 func (h *HTTPHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req createExpenseHTTPReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.Amount == nil || *req.Amount <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "amount must be > 0"})
+		problem.Write(w, http.StatusBadRequest, "invalid_amount", "amount must be > 0")
 		return
 	}
 
@@ -924,83 +918,175 @@ func (h *HTTPHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 	expense, err := h.repo.CreateExpense(r.Context(), userID, projectID, userID, title, *req.Amount)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
 			return
 		}
 		log.Printf("CreateExpense failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create expense"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create expense")
 		return
 	}
 
+	h.events.Publish(r.Context(), events.ExpenseCreated{
+		ExpenseID: expense.ID,
+		ProjectID: expense.ProjectID,
+		ActorID:   userID,
+		Title:     expense.Title,
+		Amount:    expense.Amount,
+	})
+
 	writeJSON(w, http.StatusCreated, expense)
 }
 
 func (h *HTTPHandler) ListExpenses(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
-	expenses, err := h.repo.ListExpenses(r.Context(), userID, projectID)
+	page, err := pagination.Parse(r, expensesPageLimits)
 	if err != nil {
+		pagination.WriteError(w, err)
+		return
+	}
+
+	expenses, err := h.repo.ListExpensesPage(r.Context(), userID, projectID, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
 		log.Printf("ListExpenses failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch expenses"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch expenses")
+		return
+	}
+
+	pagination.SetHeaders(w, r, expenses)
+	writeJSON(w, http.StatusOK, expenses.Items)
+}
+
+func (h *HTTPHandler) CreateMeeting(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, expenses)
+	var req createMeetingHTTPReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, strings.TrimSpace(req.StartsAt))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_starts_at", "invalid starts_at")
+		return
+	}
+
+	meeting, err := h.repo.CreateMeeting(r.Context(), userID, projectID, NewMeeting{
+		Title:           req.Title,
+		StartsAt:        startsAt,
+		DurationMinutes: req.DurationMinutes,
+		Notes:           req.Notes,
+		Attendees:       req.Attendees,
+	})
+	if err != nil {
+		switch {
+		case IsNotFound(err):
+			problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can schedule meetings")
+		case errors.Is(err, ErrMeetingTitleRequired), errors.Is(err, ErrMeetingInvalidTime), errors.Is(err, ErrAttendeeNotMember):
+			problem.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("CreateMeeting failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to create meeting")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, meeting)
+}
+
+// ListMeetings returns the project's meetings that have not started more than
+// a day ago.
+func (h *HTTPHandler) ListMeetings(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		problem.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return
+	}
+
+	meetings, err := h.repo.ListMeetings(r.Context(), userID, projectID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		log.Printf("ListMeetings failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch meetings")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, meetings)
 }
 
 func (h *HTTPHandler) CreateDelayReport(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req createDelayReportReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.Message == nil || strings.TrimSpace(*req.Message) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message is required"})
+		problem.Write(w, http.StatusBadRequest, "message_required", "message is required")
 		return
 	}
 
 	message := strings.TrimSpace(*req.Message)
 
 	var stageID *uuid.UUID
-	stageIDRaw := firstNonNilString(req.StageID, req.StageIDAlt)
+	stageIDRaw := req.StageID
 	if stageIDRaw != nil && strings.TrimSpace(*stageIDRaw) != "" {
 		parsedStageID, parseErr := uuid.Parse(strings.TrimSpace(*stageIDRaw))
 		if parseErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 			return
 		}
 		stageID = &parsedStageID
 	}
 
 	var taskID *uuid.UUID
-	taskIDRaw := firstNonNilString(req.TaskID, req.TaskIDAlt)
+	taskIDRaw := req.TaskID
 	if taskIDRaw != nil && strings.TrimSpace(*taskIDRaw) != "" {
 		parsedTaskID, parseErr := uuid.Parse(strings.TrimSpace(*taskIDRaw))
 		if parseErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 			return
 		}
 		taskID = &parsedTaskID
@@ -1010,15 +1096,15 @@ func (h *HTTPHandler) CreateDelayReport(w http.ResponseWriter, r *http.Request)
 		canWrite, checkErr := h.repo.CanWriteTaskDiscussion(r.Context(), requesterID, *taskID)
 		if checkErr != nil {
 			if IsNotFound(checkErr) {
-				writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+				problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 				return
 			}
 			log.Printf("CreateDelayReport permission check failed: %v", checkErr)
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate permissions"})
+			problem.Error(w, http.StatusInternalServerError, "failed to validate permissions")
 			return
 		}
 		if !canWrite {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 	}
@@ -1026,11 +1112,11 @@ func (h *HTTPHandler) CreateDelayReport(w http.ResponseWriter, r *http.Request)
 	report, err := h.repo.CreateDelayReport(r.Context(), projectID, requesterID, stageID, taskID, message)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("CreateDelayReport failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create delay report"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create delay report")
 		return
 	}
 
@@ -1040,45 +1126,44 @@ func (h *HTTPHandler) CreateDelayReport(w http.ResponseWriter, r *http.Request)
 func (h *HTTPHandler) CreateTaskComment(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	var req createTaskCommentReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.Message == nil || strings.TrimSpace(*req.Message) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message is required"})
+		problem.Write(w, http.StatusBadRequest, "message_required", "message is required")
 		return
 	}
 
 	comment, err := h.repo.CreateTaskComment(r.Context(), requesterID, taskID, strings.TrimSpace(*req.Message))
 	if err != nil {
 		if errors.Is(err, ErrTaskCommentForbidden) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 			return
 		}
 		log.Printf("CreateTaskComment failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create comment"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create comment")
 		return
 	}
 
 	members, membersErr := h.repo.ListMembersByProject(r.Context(), requesterID, comment.ProjectID)
 	if membersErr == nil {
-		commentLink := "/project/task-" + comment.TaskID.String() + "?commentId=" + comment.ID.String()
 		mentionedRefs := extractMentionedRefs(comment.Message)
 		mentionedTargets := make([]uuid.UUID, 0, len(mentionedRefs))
 		mentionedSet := make(map[uuid.UUID]struct{}, len(mentionedRefs))
@@ -1106,31 +1191,14 @@ func (h *HTTPHandler) CreateTaskComment(w http.ResponseWriter, r *http.Request)
 			targets = append(targets, memberID)
 		}
 
-		h.notifyUsers(
-			r.Context(),
-			targets,
-			requesterID,
-			notifications.KindTaskComment,
-			"Новый комментарий в задаче",
-			"В задаче появился новый комментарий",
-			commentLink,
-			"task",
-			&comment.TaskID,
-		)
-
-		if len(mentionedTargets) > 0 {
-			h.notifyUsers(
-				r.Context(),
-				mentionedTargets,
-				requesterID,
-				notifications.KindTaskComment,
-				"Вас упомянули в комментарии",
-				"В задаче вас упомянули в комментарии",
-				commentLink,
-				"task",
-				&comment.TaskID,
-			)
-		}
+		h.events.Publish(r.Context(), events.TaskCommented{
+			TaskID:    comment.TaskID,
+			ProjectID: comment.ProjectID,
+			CommentID: comment.ID,
+			ActorID:   requesterID,
+			Watchers:  targets,
+			Mentioned: mentionedTargets,
+		})
 	}
 
 	writeJSON(w, http.StatusCreated, comment)
@@ -1139,66 +1207,77 @@ func (h *HTTPHandler) CreateTaskComment(w http.ResponseWriter, r *http.Request)
 func (h *HTTPHandler) ListTaskComments(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
-	comments, err := h.repo.ListTaskComments(r.Context(), requesterID, taskID)
+	page, err := pagination.Parse(r, taskCommentsPageLimits)
 	if err != nil {
+		pagination.WriteError(w, err)
+		return
+	}
+
+	comments, err := h.repo.ListTaskComments(r.Context(), requesterID, taskID, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListTaskComments failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch comments"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch comments")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, comments)
+	pagination.SetHeaders(w, r, comments)
+	writeJSON(w, http.StatusOK, comments.Items)
 }
 
 func (h *HTTPHandler) CreateTaskReportChatMessage(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	var req createReportChatReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.Message == nil || strings.TrimSpace(*req.Message) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message is required"})
+		problem.Write(w, http.StatusBadRequest, "message_required", "message is required")
 		return
 	}
 
 	message, err := h.repo.CreateTaskReportChatMessage(r.Context(), requesterID, taskID, strings.TrimSpace(*req.Message))
 	if err != nil {
 		if errors.Is(err, ErrTaskCommentForbidden) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 			return
 		}
 		log.Printf("CreateTaskReportChatMessage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create message"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create message")
 		return
 	}
 
@@ -1208,24 +1287,24 @@ func (h *HTTPHandler) CreateTaskReportChatMessage(w http.ResponseWriter, r *http
 func (h *HTTPHandler) ListTaskReportChatMessages(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	messages, err := h.repo.ListTaskReportChatMessages(r.Context(), requesterID, taskID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListTaskReportChatMessages failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch chat messages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch chat messages")
 		return
 	}
 
@@ -1235,24 +1314,24 @@ func (h *HTTPHandler) ListTaskReportChatMessages(w http.ResponseWriter, r *http.
 func (h *HTTPHandler) ListTaskHistory(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	history, err := h.repo.ListTaskHistory(r.Context(), requesterID, taskID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListTaskHistory failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch history"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch history")
 		return
 	}
 
@@ -1262,24 +1341,24 @@ func (h *HTTPHandler) ListTaskHistory(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) ListDelayReports(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	reports, err := h.repo.ListDelayReports(r.Context(), requesterID, projectID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListDelayReports failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch delay reports"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch delay reports")
 		return
 	}
 
@@ -1289,35 +1368,35 @@ func (h *HTTPHandler) ListDelayReports(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) CreateProjectReportChatMessage(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req createReportChatReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.Message == nil || strings.TrimSpace(*req.Message) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message is required"})
+		problem.Write(w, http.StatusBadRequest, "message_required", "message is required")
 		return
 	}
 
 	message, err := h.repo.CreateProjectReportChatMessage(r.Context(), requesterID, projectID, strings.TrimSpace(*req.Message))
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("CreateProjectReportChatMessage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create message"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create message")
 		return
 	}
 
@@ -1327,24 +1406,24 @@ func (h *HTTPHandler) CreateProjectReportChatMessage(w http.ResponseWriter, r *h
 func (h *HTTPHandler) ListProjectReportChatMessages(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	messages, err := h.repo.ListProjectReportChatMessages(r.Context(), requesterID, projectID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListProjectReportChatMessages failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch chat messages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch chat messages")
 		return
 	}
 
@@ -1354,39 +1433,39 @@ func (h *HTTPHandler) ListProjectReportChatMessages(w http.ResponseWriter, r *ht
 func (h *HTTPHandler) CreateDelayReportComment(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	reportID, err := uuid.Parse(chi.URLParam(r, "reportId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid report id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_report_id", "invalid report id")
 		return
 	}
 
 	var req createDelayReportCommentReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.Message == nil || strings.TrimSpace(*req.Message) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message is required"})
+		problem.Write(w, http.StatusBadRequest, "message_required", "message is required")
 		return
 	}
 
 	var parentID *uuid.UUID
-	parentIDRaw := firstNonNilString(req.ParentID, req.ParentIDAlt)
+	parentIDRaw := req.ParentID
 	if parentIDRaw != nil && strings.TrimSpace(*parentIDRaw) != "" {
 		parsedParentID, parseErr := uuid.Parse(strings.TrimSpace(*parentIDRaw))
 		if parseErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid parent id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_parent_id", "invalid parent id")
 			return
 		}
 		parentID = &parsedParentID
@@ -1395,20 +1474,19 @@ func (h *HTTPHandler) CreateDelayReportComment(w http.ResponseWriter, r *http.Re
 	comment, err := h.repo.CreateDelayReportComment(r.Context(), requesterID, projectID, reportID, parentID, strings.TrimSpace(*req.Message))
 	if err != nil {
 		if errors.Is(err, ErrDelayReportCommentForbidden) || IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("CreateDelayReportComment failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create comment"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create comment")
 		return
 	}
 
 	members, membersErr := h.repo.ListMembersByProject(r.Context(), requesterID, projectID)
 	if membersErr == nil {
-		commentLink := "/project/" + projectID.String() + "/reports?reportId=" + reportID.String() + "&commentId=" + comment.ID.String()
 		reportTaskID, reportTaskErr := h.repo.ResolveDelayReportTaskID(r.Context(), requesterID, projectID, reportID)
-		if reportTaskErr == nil && reportTaskID != nil {
-			commentLink = "/project/task-" + reportTaskID.String() + "/reports?reportId=" + reportID.String() + "&commentId=" + comment.ID.String()
+		if reportTaskErr != nil {
+			reportTaskID = nil
 		}
 		targets := make([]uuid.UUID, 0, len(members))
 		replyTarget := uuid.Nil
@@ -1433,31 +1511,15 @@ func (h *HTTPHandler) CreateDelayReportComment(w http.ResponseWriter, r *http.Re
 			targets = append(targets, memberID)
 		}
 
-		h.notifyUsers(
-			r.Context(),
-			targets,
-			requesterID,
-			notifications.KindTaskComment,
-			"Новый комментарий к отчету",
-			"В отчете появился новый комментарий",
-			commentLink,
-			"delay_report",
-			&reportID,
-		)
-
-		if replyTarget != uuid.Nil {
-			h.notifyUsers(
-				r.Context(),
-				[]uuid.UUID{replyTarget},
-				requesterID,
-				notifications.KindTaskComment,
-				"Ответ на ваш комментарий",
-				"В отчете ответили на ваш комментарий",
-				commentLink,
-				"delay_report",
-				&reportID,
-			)
-		}
+		h.events.Publish(r.Context(), events.ReportCommented{
+			ReportID:  reportID,
+			ProjectID: projectID,
+			TaskID:    reportTaskID,
+			CommentID: comment.ID,
+			ActorID:   requesterID,
+			Watchers:  targets,
+			RepliedTo: replyTarget,
+		})
 	}
 
 	writeJSON(w, http.StatusCreated, comment)
@@ -1466,30 +1528,30 @@ func (h *HTTPHandler) CreateDelayReportComment(w http.ResponseWriter, r *http.Re
 func (h *HTTPHandler) ListDelayReportComments(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	reportID, err := uuid.Parse(chi.URLParam(r, "reportId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid report id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_report_id", "invalid report id")
 		return
 	}
 
 	comments, err := h.repo.ListDelayReportComments(r.Context(), requesterID, projectID, reportID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListDelayReportComments failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch comments"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch comments")
 		return
 	}
 
@@ -1499,24 +1561,24 @@ func (h *HTTPHandler) ListDelayReportComments(w http.ResponseWriter, r *http.Req
 func (h *HTTPHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	members, err := h.repo.ListMembersByProject(r.Context(), requesterID, projectID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("ListMembers failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch members"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch members")
 		return
 	}
 
@@ -1526,37 +1588,34 @@ func (h *HTTPHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req updateProjectRolesReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
-	managerIDRaw := firstNonNilString(req.ManagerID, req.ManagerIDAlt)
+	managerIDRaw := req.ManagerID
 	var managerID *uuid.UUID
 	if managerIDRaw != nil && strings.TrimSpace(*managerIDRaw) != "" {
 		parsedManagerID, parseErr := uuid.Parse(strings.TrimSpace(*managerIDRaw))
 		if parseErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid manager id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_manager_id", "invalid manager id")
 			return
 		}
 		managerID = &parsedManagerID
 	}
 
 	memberIDsRaw := req.MemberIDs
-	if len(memberIDsRaw) == 0 && len(req.MemberIDsAlt) > 0 {
-		memberIDsRaw = req.MemberIDsAlt
-	}
 
 	memberIDs := make([]uuid.UUID, 0, len(memberIDsRaw))
 	seen := make(map[uuid.UUID]struct{}, len(memberIDsRaw))
@@ -1567,7 +1626,7 @@ func (h *HTTPHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
 		}
 		memberID, parseErr := uuid.Parse(trimmed)
 		if parseErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid member id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_member_id", "invalid member id")
 			return
 		}
 		if _, exists := seen[memberID]; exists {
@@ -1579,15 +1638,19 @@ func (h *HTTPHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.repo.UpdateRoles(r.Context(), requesterID, projectID, managerID, memberIDs); err != nil {
 		if errors.Is(err, ErrCannotAssignOwnerAsManager) {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "owner cannot be assigned as manager"})
+			problem.Write(w, http.StatusBadRequest, "owner_cannot_be_assigned_as_manager", "owner cannot be assigned as manager")
+			return
+		}
+		if errors.Is(err, ErrNotWorkspaceMember) {
+			problem.Write(w, http.StatusBadRequest, "not_workspace_member", "user is not a member of the workspace")
 			return
 		}
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("UpdateRoles failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update roles"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update roles")
 		return
 	}
 
@@ -1595,43 +1658,13 @@ func (h *HTTPHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
 	if projectItem, getErr := h.repo.GetByID(r.Context(), requesterID, projectID); getErr == nil {
 		projectTitle = strings.TrimSpace(projectItem.Title)
 	}
-	projectTitlePart := ""
-	if projectTitle != "" {
-		projectTitlePart = " в проекте «" + projectTitle + "»"
-	}
-
-	if managerID != nil {
-		h.notifyUsers(
-			r.Context(),
-			[]uuid.UUID{*managerID},
-			requesterID,
-			notifications.KindProjectMember,
-			"Обновлены роли в проекте",
-			"Вам назначена роль: "+roleTitle(ProjectMemberRoleManager)+projectTitlePart,
-			"/project-overview/"+projectID.String(),
-			"project",
-			&projectID,
-		)
-	}
-
-	memberTargets := make([]uuid.UUID, 0, len(memberIDs))
-	for _, memberID := range memberIDs {
-		if managerID != nil && memberID == *managerID {
-			continue
-		}
-		memberTargets = append(memberTargets, memberID)
-	}
-	h.notifyUsers(
-		r.Context(),
-		memberTargets,
-		requesterID,
-		notifications.KindProjectMember,
-		"Обновлены роли в проекте",
-		"Вам назначена роль: "+roleTitle(ProjectMemberRoleMember)+projectTitlePart,
-		"/project-overview/"+projectID.String(),
-		"project",
-		&projectID,
-	)
+	h.events.Publish(r.Context(), events.RolesUpdated{
+		ProjectID:    projectID,
+		ProjectTitle: projectTitle,
+		ActorID:      requesterID,
+		ManagerID:    managerID,
+		MemberIDs:    memberIDs,
+	})
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -1639,69 +1672,68 @@ func (h *HTTPHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) UpsertMember(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req upsertProjectMemberReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	if req.UserID == nil || strings.TrimSpace(*req.UserID) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "userId is required"})
+		problem.Write(w, http.StatusBadRequest, "user_id_required", "userId is required")
 		return
 	}
 
 	memberUserID, err := uuid.Parse(strings.TrimSpace(*req.UserID))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
 	if req.Role == nil || strings.TrimSpace(*req.Role) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "role is required"})
+		problem.Write(w, http.StatusBadRequest, "role_required", "role is required")
 		return
 	}
 
 	role := ProjectMemberRole(strings.ToLower(strings.TrimSpace(*req.Role)))
 	if !role.Valid() {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid role"})
+		problem.Write(w, http.StatusBadRequest, "invalid_role", "invalid role")
 		return
 	}
 
 	if err := h.repo.UpsertMember(r.Context(), requesterID, projectID, memberUserID, role); err != nil {
 		if errors.Is(err, ErrCannotAssignOwnerAsManager) {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "owner cannot be assigned as manager"})
+			problem.Write(w, http.StatusBadRequest, "owner_cannot_be_assigned_as_manager", "owner cannot be assigned as manager")
+			return
+		}
+		if errors.Is(err, ErrNotWorkspaceMember) {
+			problem.Write(w, http.StatusBadRequest, "not_workspace_member", "user is not a member of the workspace")
 			return
 		}
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
 			return
 		}
 		log.Printf("UpsertMember failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to save member"})
+		problem.Error(w, http.StatusInternalServerError, "failed to save member")
 		return
 	}
 
-	h.notifyUsers(
-		r.Context(),
-		[]uuid.UUID{memberUserID},
-		requesterID,
-		notifications.KindProjectMember,
-		"Вы добавлены в проект",
-		"Вам назначена роль: "+roleTitle(role),
-		"/project-overview/"+projectID.String(),
-		"project",
-		&projectID,
-	)
+	h.events.Publish(r.Context(), events.MemberAdded{
+		ProjectID: projectID,
+		UserID:    memberUserID,
+		ActorID:   requesterID,
+		Role:      string(role),
+	})
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -1709,29 +1741,29 @@ func (h *HTTPHandler) UpsertMember(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
 	requesterID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	memberUserID, err := uuid.Parse(chi.URLParam(r, "userId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
 		return
 	}
 
 	if err := h.repo.DeleteMember(r.Context(), requesterID, projectID, memberUserID); err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "member not found or forbidden"})
+			problem.Write(w, http.StatusNotFound, "member_not_found", "member not found or forbidden")
 			return
 		}
 		log.Printf("DeleteMember failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete member"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete member")
 		return
 	}
 
@@ -1741,23 +1773,23 @@ func (h *HTTPHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	expenseID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid expense id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_expense_id", "invalid expense id")
 		return
 	}
 
 	if err := h.repo.DeleteExpense(r.Context(), userID, expenseID); err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "expense not found"})
+			problem.Write(w, http.StatusNotFound, "expense_not_found", "expense not found")
 			return
 		}
 		log.Printf("DeleteExpense failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete expense"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete expense")
 		return
 	}
 
@@ -1767,25 +1799,25 @@ func (h *HTTPHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) CreateStage(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	var req createStageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is required"})
+		problem.Write(w, http.StatusBadRequest, "title_required", "title is required")
 		return
 	}
 
@@ -1797,11 +1829,11 @@ func (h *HTTPHandler) CreateStage(w http.ResponseWriter, r *http.Request) {
 	stage, err := h.repo.CreateStage(r.Context(), userID, projectID, title, orderIndex)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "project not found"})
+			problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
 			return
 		}
 		log.Printf("CreateStage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create stage"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create stage")
 		return
 	}
 
@@ -1811,42 +1843,42 @@ func (h *HTTPHandler) CreateStage(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) ListStages(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	stages, err := h.repo.ListStagesByProject(r.Context(), userID, projectID)
 	if err != nil {
 		log.Printf("ListStages failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch stages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch stages")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, stages)
+	etag.WriteJSON(w, r, http.StatusOK, stages)
 }
 
 func (h *HTTPHandler) UpdateStage(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	stageID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 		return
 	}
 
 	var req updateStageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
@@ -1855,7 +1887,7 @@ func (h *HTTPHandler) UpdateStage(w http.ResponseWriter, r *http.Request) {
 		title = strings.TrimSpace(*req.Title)
 	}
 	if title == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is required"})
+		problem.Write(w, http.StatusBadRequest, "title_required", "title is required")
 		return
 	}
 
@@ -1867,11 +1899,11 @@ func (h *HTTPHandler) UpdateStage(w http.ResponseWriter, r *http.Request) {
 	stage, err := h.repo.UpdateStage(r.Context(), userID, stageID, title, orderIndex)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "stage not found"})
+			problem.Write(w, http.StatusNotFound, "stage_not_found", "stage not found")
 			return
 		}
 		log.Printf("UpdateStage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update stage"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update stage")
 		return
 	}
 
@@ -1881,23 +1913,23 @@ func (h *HTTPHandler) UpdateStage(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) DeleteStage(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	stageID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 		return
 	}
 
 	if err := h.repo.DeleteStage(r.Context(), userID, stageID); err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "stage not found"})
+			problem.Write(w, http.StatusNotFound, "stage_not_found", "stage not found")
 			return
 		}
 		log.Printf("DeleteStage failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete stage"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete stage")
 		return
 	}
 
@@ -1907,29 +1939,29 @@ func (h *HTTPHandler) DeleteStage(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) DeleteStageInProject(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid project id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
 		return
 	}
 
 	stageID, err := uuid.Parse(chi.URLParam(r, "stageId"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 		return
 	}
 
 	if err := h.repo.DeleteStageByProject(r.Context(), userID, projectID, stageID); err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "stage not found"})
+			problem.Write(w, http.StatusNotFound, "stage_not_found", "stage not found")
 			return
 		}
 		log.Printf("DeleteStageInProject failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete stage"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete stage")
 		return
 	}
 
@@ -1939,19 +1971,19 @@ func (h *HTTPHandler) DeleteStageInProject(w http.ResponseWriter, r *http.Reques
 func (h *HTTPHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	stageID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 		return
 	}
 
 	var req createTaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
@@ -1964,16 +1996,22 @@ func (h *HTTPHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		status = "todo"
 	}
 
-	startDateRaw := firstNonNilString(req.StartDate, req.StartDateAlt)
-	startDate, err := parseOptionalDate(startDateRaw)
+	loc, err := h.repo.StageLocation(r.Context(), stageID)
+	if err != nil {
+		log.Printf("CreateTask load time zone failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create task")
+		return
+	}
+
+	startDate, err := ParseDate(derefOrEmpty(req.StartDate), loc)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid startDate"})
+		problem.Write(w, http.StatusBadRequest, "invalid_start_date", "invalid startDate")
 		return
 	}
 
-	deadline, err := parseOptionalDate(req.Deadline)
+	deadline, err := ParseDeadline(derefOrEmpty(req.Deadline), loc)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid deadline"})
+		problem.Write(w, http.StatusBadRequest, "invalid_deadline", "invalid deadline")
 		return
 	}
 
@@ -1985,104 +2023,105 @@ func (h *HTTPHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	task, err := h.repo.CreateTask(r.Context(), userID, stageID, title, status, startDate, deadline, orderIndex)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "stage not found"})
+			problem.Write(w, http.StatusNotFound, "stage_not_found", "stage not found")
 			return
 		}
 		log.Printf("CreateTask failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create task"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create task")
 		return
 	}
 
+	h.events.Publish(r.Context(), events.TaskCreated{
+		TaskID:    task.ID,
+		ProjectID: task.ProjectID,
+		ActorID:   userID,
+		Title:     task.Title,
+	})
+
 	writeJSON(w, http.StatusCreated, task)
 }
 
 func (h *HTTPHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	task, err := h.repo.GetTaskByID(r.Context(), userID, taskID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 			return
 		}
 		log.Printf("GetTask failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load task"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load task")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, task)
+	etag.WriteJSON(w, r, http.StatusOK, task)
 }
 
 func (h *HTTPHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	stageID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 		return
 	}
 
 	tasks, err := h.repo.ListTasksByStage(r.Context(), userID, stageID)
 	if err != nil {
 		log.Printf("ListTasks failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch tasks"})
+		problem.Error(w, http.StatusInternalServerError, "failed to fetch tasks")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, tasks)
+	etag.WriteJSON(w, r, http.StatusOK, tasks)
 }
 
 func (h *HTTPHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	var req updateTaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	currentTask, err := h.repo.GetTaskByID(r.Context(), userID, taskID)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 			return
 		}
 		log.Printf("UpdateTask load failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load task"})
+		problem.Error(w, http.StatusInternalServerError, "failed to load task")
 		return
 	}
 
-	expectedUpdatedAt, err := parseExpectedUpdatedAt(req.ExpectedUpdatedAt, req.ExpectedUpdatedAtAlt)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if expectedUpdatedAt != nil && !currentTask.UpdatedAt.UTC().Equal(expectedUpdatedAt.UTC()) {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": "данные задачи изменились в другой вкладке, обновите страницу"})
+	if !checkVersion(w, r, currentTask, currentTask.UpdatedAt, req.ExpectedUpdatedAt, "данные задачи изменились в другой вкладке, обновите страницу") {
 		return
 	}
 
@@ -2099,16 +2138,22 @@ func (h *HTTPHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		status = strings.TrimSpace(*req.Status)
 	}
 
-	startDateRaw := firstNonNilString(req.StartDate, req.StartDateAlt)
-	startDate, err := parseOptionalDate(startDateRaw)
+	loc, err := h.repo.ProjectLocation(r.Context(), currentTask.ProjectID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid startDate"})
+		log.Printf("UpdateTask load time zone failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to update task")
 		return
 	}
 
-	deadline, err := parseOptionalDate(req.Deadline)
+	startDate, err := ParseDate(derefOrEmpty(req.StartDate), loc)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid deadline"})
+		problem.Write(w, http.StatusBadRequest, "invalid_start_date", "invalid startDate")
+		return
+	}
+
+	deadline, err := ParseDeadline(derefOrEmpty(req.Deadline), loc)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_deadline", "invalid deadline")
 		return
 	}
 
@@ -2118,11 +2163,11 @@ func (h *HTTPHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var stageID *uuid.UUID
-	stageIDRaw := firstNonNilString(req.StageID, req.StageIDAlt)
+	stageIDRaw := req.StageID
 	if stageIDRaw != nil && strings.TrimSpace(*stageIDRaw) != "" {
 		parsedStageID, parseErr := uuid.Parse(strings.TrimSpace(*stageIDRaw))
 		if parseErr != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid stage id"})
+			problem.Write(w, http.StatusBadRequest, "invalid_stage_id", "invalid stage id")
 			return
 		}
 		stageID = &parsedStageID
@@ -2138,14 +2183,21 @@ func (h *HTTPHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	task, err := h.repo.UpdateTask(r.Context(), userID, taskID, title, status, startDate, deadline, stageID, orderIndex, blocks)
 	if err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 			return
 		}
 		log.Printf("UpdateTask failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update task"})
+		problem.Error(w, http.StatusInternalServerError, "failed to update task")
 		return
 	}
 
+	updated := events.TaskUpdated{
+		TaskID:    task.ID,
+		ProjectID: task.ProjectID,
+		ActorID:   userID,
+		Title:     task.Title,
+		Status:    string(task.Status),
+	}
 	if len(newAssignees) > 0 {
 		addedAssignees := make(map[string]struct{}, len(newAssignees))
 		for value := range newAssignees {
@@ -2155,23 +2207,13 @@ func (h *HTTPHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(addedAssignees) > 0 {
-			assignmentModeRaw := firstNonNilString(req.AssignmentMode, req.AssignmentModeAlt)
-			assignmentMode := strings.ToLower(strings.TrimSpace(derefOrEmpty(assignmentModeRaw)))
-			isDelegation := assignmentMode == "delegation" || assignmentMode == "delegate"
-			notificationKind := notifications.KindTaskAssigned
-			notificationTitle := "Вас назначили на проект"
-			notificationBody := "Вам назначена задача: " + task.Title
-			if isDelegation {
-				notificationKind = notifications.KindTaskDelegated
-				notificationTitle = "Вам делегирована задача"
-				notificationBody = "Вам делегирована задача: " + task.Title
-			}
+			assignmentMode := strings.ToLower(strings.TrimSpace(derefOrEmpty(req.AssignmentMode)))
+			updated.Delegated = assignmentMode == "delegation" || assignmentMode == "delegate"
 
 			resolvedAssigneeIDs, resolveErr := h.repo.ResolveUserIDsByRefs(r.Context(), addedAssignees)
 			if resolveErr != nil {
 				log.Printf("UpdateTask assignee resolve failed: %v", resolveErr)
 			} else {
-				notifyIDs := make([]uuid.UUID, 0, len(resolvedAssigneeIDs))
 				for _, assigneeID := range resolvedAssigneeIDs {
 					if assigneeID == uuid.Nil {
 						continue
@@ -2183,50 +2225,41 @@ func (h *HTTPHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 						continue
 					}
 
-					notifyIDs = append(notifyIDs, assigneeID)
+					updated.AddedAssignees = append(updated.AddedAssignees, assigneeID)
 				}
-
-				h.notifyUsers(
-					r.Context(),
-					notifyIDs,
-					userID,
-					notificationKind,
-					notificationTitle,
-					notificationBody,
-					"/project/task-"+task.ID.String(),
-					"task",
-					&task.ID,
-				)
 			}
 		}
 	}
+	h.events.Publish(r.Context(), updated)
 
-	writeJSON(w, http.StatusOK, task)
+	etag.WriteJSON(w, r, http.StatusOK, task)
 }
 
 func (h *HTTPHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromRequest(r)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+		problem.Write(w, http.StatusBadRequest, "invalid_task_id", "invalid task id")
 		return
 	}
 
 	if err := h.repo.DeleteTask(r.Context(), userID, taskID); err != nil {
 		if IsNotFound(err) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+			problem.Write(w, http.StatusNotFound, "task_not_found", "task not found")
 			return
 		}
 		log.Printf("DeleteTask failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete task"})
+		problem.Error(w, http.StatusInternalServerError, "failed to delete task")
 		return
 	}
 
+	h.events.Publish(r.Context(), events.TaskDeleted{TaskID: taskID, ActorID: userID})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -2244,32 +2277,28 @@ func userIDFromRequest(r *http.Request) (uuid.UUID, error) {
 	return parsed, nil
 }
 
-func parseDateString(value string) (*time.Time, error) {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return nil, nil
+// checkVersion reports whether an update of current may go on. It answers
+// 412 when the If-Match header names another version, and 409 when the
+// expectedUpdatedAt body member, which predates If-Match, does.
+func checkVersion(w http.ResponseWriter, r *http.Request, current any, updatedAt time.Time, expected *string, conflict string) bool {
+	if !etag.Matches(r, current) {
+		problem.Write(w, http.StatusPreconditionFailed, problem.CodePreconditionFailed, conflict)
+		return false
 	}
 
-	if parsed, err := time.Parse(time.RFC3339, trimmed); err == nil {
-		return &parsed, nil
-	}
-
-	if parsed, err := time.Parse("2006-01-02", trimmed); err == nil {
-		return &parsed, nil
+	expectedUpdatedAt, err := parseExpectedUpdatedAt(expected)
+	if err != nil {
+		problem.Error(w, http.StatusBadRequest, err.Error())
+		return false
 	}
-
-	return nil, errors.New("invalid date")
-}
-
-func parseOptionalDate(value *string) (*time.Time, error) {
-	if value == nil {
-		return nil, nil
+	if expectedUpdatedAt != nil && !updatedAt.UTC().Equal(expectedUpdatedAt.UTC()) {
+		problem.Write(w, http.StatusConflict, problem.CodeEditConflict, conflict)
+		return false
 	}
-	return parseDateString(*value)
+	return true
 }
 
-func parseExpectedUpdatedAt(values ...*string) (*time.Time, error) {
-	value := firstNonNilString(values...)
+func parseExpectedUpdatedAt(value *string) (*time.Time, error) {
 	if value == nil {
 		return nil, nil
 	}
@@ -0,0 +1,295 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportStage is a stage of a parsed plan imported with ImportStages, with
+// its tasks in document order. Its tasks go to the existing stage StageID
+// when set.
+type ImportStage struct {
+	Title   string
+	StageID *uuid.UUID
+	Tasks   []ImportTask
+}
+
+// ImportTask is a task of an ImportStage. Assignees are user emails of
+// project members, stored the way the task editor stores them.
+type ImportTask struct {
+	Title     string
+	Status    string
+	StartDate *time.Time
+	Deadline  *time.Time
+	Assignees []string
+}
+
+// ImportSummary lists the stages and tasks ImportStages wrote.
+type ImportSummary struct {
+	StagesCreated int             `json:"stagesCreated"`
+	StagesReused  int             `json:"stagesReused"`
+	TasksCreated  int             `json:"tasksCreated"`
+	Stages        []ImportedStage `json:"stages"`
+}
+
+// ImportedStage is a stage an import created, or an existing stage of the
+// same title it added tasks to.
+type ImportedStage struct {
+	Stage   Stage  `json:"stage"`
+	Created bool   `json:"created"`
+	Tasks   []Task `json:"tasks"`
+}
+
+// ImportStages adds the stages and tasks of a parsed plan to a project:
+// either all of them or none. A stage whose title matches an existing stage
+// of the project, ignoring case, gets its tasks appended instead of being
+// created again. Only the project's owners and managers may do this; others
+// get sql.ErrNoRows. Stages of other projects give ErrStageNotInProject and
+// assignees outside the project ErrAssigneeNotMember. The imported values of
+// each task are kept for SyncStages.
+func (r *Repository) ImportStages(ctx context.Context, requesterID, projectID uuid.UUID, stages []ImportStage) (summary ImportSummary, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var allowed bool
+	if allowed, err = managesProject(ctx, tx, requesterID, projectID); err != nil {
+		return ImportSummary{}, err
+	}
+	if !allowed {
+		err = sql.ErrNoRows
+		return ImportSummary{}, err
+	}
+
+	existing, nextOrder, err := stagesByTitle(ctx, tx, projectID)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+
+	summary.Stages = make([]ImportedStage, 0, len(stages))
+	for _, input := range stages {
+		imported := ImportedStage{Tasks: make([]Task, 0, len(input.Tasks))}
+		if imported.Stage, imported.Created, err = importTargetStage(ctx, tx, projectID, input, existing, &nextOrder); err != nil {
+			return ImportSummary{}, err
+		}
+		if imported.Created {
+			summary.StagesCreated++
+		} else {
+			summary.StagesReused++
+		}
+
+		for _, task := range input.Tasks {
+			var item Task
+			if item, err = insertImportTask(ctx, tx, projectID, imported.Stage.ID, sourceKey(input.Title), task); err != nil {
+				return ImportSummary{}, err
+			}
+			imported.Tasks = append(imported.Tasks, item)
+			summary.TasksCreated++
+		}
+
+		summary.Stages = append(summary.Stages, imported)
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return ImportSummary{}, err
+	}
+
+	return summary, nil
+}
+
+// importTargetStage returns the stage the tasks of an imported stage go to:
+// the one it names, an existing stage of the same title, or a new stage
+// appended after the others.
+func importTargetStage(ctx context.Context, tx *sql.Tx, projectID uuid.UUID, input ImportStage, existing map[string]Stage, nextOrder *int) (Stage, bool, error) {
+	var stage Stage
+	if input.StageID != nil {
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT id, project_id, title, order_index
+			 FROM project_stages
+			 WHERE id = $1 AND project_id = $2`,
+			*input.StageID,
+			projectID,
+		).Scan(&stage.ID, &stage.ProjectID, &stage.Title, &stage.OrderIndex)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrStageNotInProject
+		}
+		return stage, false, err
+	}
+
+	title := strings.TrimSpace(input.Title)
+	key := strings.ToLower(title)
+	if found, ok := existing[key]; ok {
+		return found, false, nil
+	}
+
+	if err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO project_stages (project_id, title, order_index)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, project_id, title, order_index`,
+		projectID,
+		title,
+		*nextOrder,
+	).Scan(&stage.ID, &stage.ProjectID, &stage.Title, &stage.OrderIndex); err != nil {
+		return Stage{}, false, err
+	}
+	*nextOrder++
+	existing[key] = stage
+	return stage, true, nil
+}
+
+// insertImportTask adds an imported task to the end of a stage and records
+// it as imported from sourceStage, so a later sync can tell what changed.
+func insertImportTask(ctx context.Context, tx *sql.Tx, projectID, stageID uuid.UUID, sourceStage string, task ImportTask) (Task, error) {
+	status := strings.TrimSpace(task.Status)
+	if status == "" {
+		status = "todo"
+	}
+
+	assignees := make([]string, 0, len(task.Assignees))
+	for value := range normalizeAssigneeValues(task.Assignees) {
+		member, err := isProjectMemberEmail(ctx, tx, projectID, value)
+		if err != nil {
+			return Task{}, err
+		}
+		if !member {
+			return Task{}, ErrAssigneeNotMember
+		}
+		assignees = append(assignees, value)
+	}
+
+	blocks, err := taskMetaBlocks(assignees)
+	if err != nil {
+		return Task{}, err
+	}
+
+	title := strings.TrimSpace(task.Title)
+	item, err := scanTask(tx.QueryRowContext(
+		ctx,
+		`WITH inserted AS (
+		 	INSERT INTO stage_tasks (stage_id, title, status, start_date, deadline, order_index, blocks)
+		 	SELECT $1, $2, $3, $4, $5,
+		 	       COALESCE((SELECT MAX(order_index) + 1 FROM stage_tasks WHERE stage_id = $1), 0),
+		 	       $6::jsonb
+		 	RETURNING id, stage_id, title, status, start_date, deadline, order_index, blocks, updated_at
+		 )
+		 SELECT i.id, i.stage_id, s.project_id, i.title, i.status, i.start_date, i.deadline, i.order_index, i.blocks, i.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
+		 FROM inserted i
+		 JOIN project_stages s ON s.id = i.stage_id`,
+		stageID,
+		title,
+		status,
+		nullTime(task.StartDate),
+		nullTime(task.Deadline),
+		blocks,
+	))
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := recordImportedTask(ctx, tx, projectID, item.ID, sourceStage, ImportTask{
+		Title:     title,
+		Status:    status,
+		StartDate: task.StartDate,
+		Deadline:  task.Deadline,
+	}); err != nil {
+		return Task{}, err
+	}
+	return item, nil
+}
+
+// recordImportedTask stores the values a task was last imported with.
+func recordImportedTask(ctx context.Context, tx *sql.Tx, projectID, taskID uuid.UUID, sourceStage string, task ImportTask) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO project_imported_tasks (project_id, task_id, source_stage, source_title, title, status, start_date, deadline)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (project_id, source_stage, source_title) DO UPDATE
+		 SET task_id = EXCLUDED.task_id,
+		     title = EXCLUDED.title,
+		     status = EXCLUDED.status,
+		     start_date = EXCLUDED.start_date,
+		     deadline = EXCLUDED.deadline,
+		     imported_at = now()`,
+		projectID,
+		taskID,
+		sourceStage,
+		sourceKey(task.Title),
+		strings.TrimSpace(task.Title),
+		task.Status,
+		nullTime(task.StartDate),
+		nullTime(task.Deadline),
+	)
+	return err
+}
+
+// sourceKey is how an imported stage or task title is matched across
+// versions of a document.
+func sourceKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// managesProject reports whether the user owns or manages the project.
+func managesProject(ctx context.Context, tx *sql.Tx, userID, projectID uuid.UUID) (bool, error) {
+	var allowed bool
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1
+		 	FROM projects p
+		 	LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+		 	WHERE p.id = $1
+		 	  AND (p.owner_id = $2 OR pm.role IN ('owner', 'manager'))
+		 )`,
+		projectID,
+		userID,
+	).Scan(&allowed)
+	return allowed, err
+}
+
+// stagesByTitle returns the stages of a project by their lowercased title,
+// the first one of each title, and the order index after the last stage.
+func stagesByTitle(ctx context.Context, tx *sql.Tx, projectID uuid.UUID) (map[string]Stage, int, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id, project_id, title, order_index
+		 FROM project_stages
+		 WHERE project_id = $1
+		 ORDER BY order_index ASC`,
+		projectID,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	stages := make(map[string]Stage)
+	nextOrder := 1
+	for rows.Next() {
+		var stage Stage
+		if err := rows.Scan(&stage.ID, &stage.ProjectID, &stage.Title, &stage.OrderIndex); err != nil {
+			return nil, 0, err
+		}
+		key := strings.ToLower(strings.TrimSpace(stage.Title))
+		if _, ok := stages[key]; !ok {
+			stages[key] = stage
+		}
+		if stage.OrderIndex >= nextOrder {
+			nextOrder = stage.OrderIndex + 1
+		}
+	}
+	return stages, nextOrder, rows.Err()
+}
@@ -0,0 +1,251 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMeetingTitleRequired = errors.New("cannot schedule a meeting without a title")
+	ErrMeetingInvalidTime   = errors.New("cannot schedule a meeting without a start time and a positive duration")
+	ErrAttendeeNotMember    = errors.New("cannot invite someone outside the project to a meeting")
+)
+
+type MeetingAttendee struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+type Meeting struct {
+	ID              uuid.UUID         `json:"id"`
+	ProjectID       uuid.UUID         `json:"project_id"`
+	Title           string            `json:"title"`
+	StartsAt        time.Time         `json:"starts_at"`
+	DurationMinutes int               `json:"duration_minutes"`
+	Notes           string            `json:"notes"`
+	CreatedBy       *uuid.UUID        `json:"created_by,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	Attendees       []MeetingAttendee `json:"attendees"`
+}
+
+// NewMeeting describes a meeting created with CreateMeeting. Attendees are
+// user emails of project members.
+type NewMeeting struct {
+	Title           string
+	StartsAt        time.Time
+	DurationMinutes int
+	Notes           string
+	Attendees       []string
+}
+
+// CreateMeeting schedules a project meeting. Only the project's owners and
+// managers may do this; others get sql.ErrNoRows.
+func (r *Repository) CreateMeeting(ctx context.Context, requesterID, projectID uuid.UUID, meeting NewMeeting) (created Meeting, err error) {
+	title := strings.TrimSpace(meeting.Title)
+	if title == "" {
+		return Meeting{}, ErrMeetingTitleRequired
+	}
+	if meeting.StartsAt.IsZero() || meeting.DurationMinutes <= 0 {
+		return Meeting{}, ErrMeetingInvalidTime
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Meeting{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var allowed bool
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1
+		 	FROM projects p
+		 	LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+		 	WHERE p.id = $1
+		 	  AND (p.owner_id = $2 OR pm.role IN ('owner', 'manager'))
+		 )`,
+		projectID,
+		requesterID,
+	).Scan(&allowed); err != nil {
+		return Meeting{}, err
+	}
+	if !allowed {
+		err = sql.ErrNoRows
+		return Meeting{}, err
+	}
+
+	var createdBy uuid.NullUUID
+	if err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO project_meetings (project_id, title, starts_at, duration_minutes, notes, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, project_id, title, starts_at, duration_minutes, notes, created_by, created_at`,
+		projectID,
+		title,
+		meeting.StartsAt,
+		meeting.DurationMinutes,
+		strings.TrimSpace(meeting.Notes),
+		requesterID,
+	).Scan(
+		&created.ID,
+		&created.ProjectID,
+		&created.Title,
+		&created.StartsAt,
+		&created.DurationMinutes,
+		&created.Notes,
+		&createdBy,
+		&created.CreatedAt,
+	); err != nil {
+		return Meeting{}, err
+	}
+	if createdBy.Valid {
+		created.CreatedBy = &createdBy.UUID
+	}
+
+	created.Attendees = make([]MeetingAttendee, 0, len(meeting.Attendees))
+	for email := range normalizeAssigneeValues(meeting.Attendees) {
+		var attendee MeetingAttendee
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT u.id, u.email
+			 FROM users u
+			 JOIN projects p ON p.id = $1
+			 WHERE LOWER(u.email) = $2
+			   AND (
+			   	p.owner_id = u.id
+			   	OR EXISTS (
+			   		SELECT 1 FROM project_members pm
+			   		WHERE pm.project_id = p.id AND pm.user_id = u.id
+			   	)
+			   )`,
+			projectID,
+			email,
+		).Scan(&attendee.ID, &attendee.Email)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAttendeeNotMember
+			return Meeting{}, err
+		}
+		if err != nil {
+			return Meeting{}, err
+		}
+
+		if _, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO project_meeting_attendees (meeting_id, user_id) VALUES ($1, $2)`,
+			created.ID,
+			attendee.ID,
+		); err != nil {
+			return Meeting{}, err
+		}
+		created.Attendees = append(created.Attendees, attendee)
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return Meeting{}, err
+	}
+
+	return created, nil
+}
+
+// ListMeetings returns the meetings of a project starting from the given
+// time, earliest first.
+func (r *Repository) ListMeetings(ctx context.Context, requesterID, projectID uuid.UUID, from time.Time) ([]Meeting, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT m.id, m.project_id, m.title, m.starts_at, m.duration_minutes, m.notes, m.created_by, m.created_at
+		 FROM project_meetings m
+		 WHERE m.project_id = $1
+		   AND m.starts_at >= $3
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = m.project_id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("m.project_id", "$2")+`
+		   )
+		 ORDER BY m.starts_at ASC, m.id ASC`,
+		projectID,
+		requesterID,
+		from,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meetings := make([]Meeting, 0)
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			meeting   Meeting
+			createdBy uuid.NullUUID
+		)
+		if err := rows.Scan(
+			&meeting.ID,
+			&meeting.ProjectID,
+			&meeting.Title,
+			&meeting.StartsAt,
+			&meeting.DurationMinutes,
+			&meeting.Notes,
+			&createdBy,
+			&meeting.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			meeting.CreatedBy = &createdBy.UUID
+		}
+		meeting.Attendees = make([]MeetingAttendee, 0)
+		index[meeting.ID] = len(meetings)
+		meetings = append(meetings, meeting)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(meetings) == 0 {
+		return meetings, nil
+	}
+
+	attendeeRows, err := r.db.QueryContext(
+		ctx,
+		`SELECT a.meeting_id, u.id, u.email
+		 FROM project_meeting_attendees a
+		 JOIN project_meetings m ON m.id = a.meeting_id
+		 JOIN users u ON u.id = a.user_id
+		 WHERE m.project_id = $1 AND m.starts_at >= $2
+		 ORDER BY u.email ASC`,
+		projectID,
+		from,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer attendeeRows.Close()
+
+	for attendeeRows.Next() {
+		var (
+			meetingID uuid.UUID
+			attendee  MeetingAttendee
+		)
+		if err := attendeeRows.Scan(&meetingID, &attendee.ID, &attendee.Email); err != nil {
+			return nil, err
+		}
+		if i, ok := index[meetingID]; ok {
+			meetings[i].Attendees = append(meetings[i].Attendees, attendee)
+		}
+	}
+
+	return meetings, attendeeRows.Err()
+}
@@ -57,6 +57,9 @@ type Project struct {
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	DurationDays    int
+	// Timezone is the IANA zone bare dates of the project are read in; the
+	// times above are in it
+	Timezone string
 }
 
 type ProjectResponse struct {
@@ -85,6 +88,7 @@ type ProjectResponse struct {
 	UpdatedAt            time.Time         `json:"updatedAt"`
 	UpdatedAtSnake       time.Time         `json:"updated_at"`
 	DurationDays         int               `json:"duration_days,omitempty"`
+	Timezone             string            `json:"timezone"`
 }
 
 func (p Project) Response() ProjectResponse {
@@ -124,6 +128,7 @@ func (p Project) Response() ProjectResponse {
 		UpdatedAt:            p.UpdatedAt,
 		UpdatedAtSnake:       p.UpdatedAt,
 		DurationDays:         p.DurationDays,
+		Timezone:             p.Timezone,
 	}
 }
 
@@ -152,8 +157,9 @@ type ProjectMember struct {
 }
 
 type ProjectMemberUser struct {
-	ID    uuid.UUID `json:"id"`
-	Email string    `json:"email"`
+	ID       uuid.UUID `json:"id"`
+	Email    string    `json:"email"`
+	FullName string    `json:"full_name,omitempty"`
 }
 
 type ProjectMemberResponse struct {
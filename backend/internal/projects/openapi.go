@@ -0,0 +1,57 @@
+package projects
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of HTTPHandler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/projects", Tag: "projects", Summary: "List the projects of the caller", Response: ProjectResponse{}, List: true, Page: &projectsPageLimits},
+	{Method: http.MethodPost, Path: "/projects", Tag: "projects", Summary: "Create a project", Body: CreateProjectRequest{}, Status: http.StatusCreated, Response: ProjectResponse{}},
+	{Method: http.MethodGet, Path: "/projects/{id}", Tag: "projects", Summary: "Get a project", Response: ProjectResponse{}, Conditional: true},
+	{Method: http.MethodPatch, Path: "/projects/{id}", Tag: "projects", Summary: "Update a project", Body: updateProjectHTTPReq{}, Response: ProjectResponse{}, Conditional: true},
+	{Method: http.MethodDelete, Path: "/projects/{id}", Tag: "projects", Summary: "Delete a project", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/workspace/context", Tag: "projects", Summary: "Get the projects, stages and tasks of the caller in one call", Response: workspaceContextResponse{}},
+
+	{Method: http.MethodGet, Path: "/projects/{id}/members", Tag: "projects", Summary: "List project members", Response: ProjectMemberResponse{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/members", Tag: "projects", Summary: "Add a member or change their role", Body: upsertProjectMemberReq{}, Response: openapi.Status{}},
+	{Method: http.MethodDelete, Path: "/projects/{id}/members/{userId}", Tag: "projects", Summary: "Remove a member", Status: http.StatusNoContent},
+	{Method: http.MethodPatch, Path: "/projects/{id}/roles", Tag: "projects", Summary: "Replace the managers and members of a project", Body: updateProjectRolesReq{}, Response: openapi.Status{}},
+
+	{Method: http.MethodGet, Path: "/projects/{id}/pages", Tag: "projects", Summary: "List project pages", Response: ProjectPage{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/pages", Tag: "projects", Summary: "Create a project page", Body: createProjectPageReq{}, Status: http.StatusCreated, Response: ProjectPage{}},
+	{Method: http.MethodGet, Path: "/projects/{id}/pages/{pageId}", Tag: "projects", Summary: "Get a project page", Response: ProjectPage{}, Conditional: true},
+	{Method: http.MethodPatch, Path: "/projects/{id}/pages/{pageId}", Tag: "projects", Summary: "Update a project page", Body: updateProjectPageReq{}, Response: ProjectPage{}, Conditional: true},
+
+	{Method: http.MethodGet, Path: "/projects/{id}/expenses", Tag: "projects", Summary: "List project expenses", Response: ProjectExpense{}, List: true, Page: &expensesPageLimits},
+	{Method: http.MethodPost, Path: "/projects/{id}/expenses", Tag: "projects", Summary: "Record an expense", Body: createExpenseHTTPReq{}, Status: http.StatusCreated, Response: ProjectExpense{}},
+	{Method: http.MethodDelete, Path: "/expenses/{id}", Tag: "projects", Summary: "Delete an expense", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/projects/{id}/meetings", Tag: "projects", Summary: "List upcoming meetings", Response: Meeting{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/meetings", Tag: "projects", Summary: "Schedule a meeting", Body: createMeetingHTTPReq{}, Status: http.StatusCreated, Response: Meeting{}},
+
+	{Method: http.MethodGet, Path: "/projects/{id}/delay-report", Tag: "projects", Summary: "List delay reports", Response: DelayReportResponse{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/delay-report", Tag: "projects", Summary: "Report a delay", Body: createDelayReportReq{}, Status: http.StatusCreated, Response: DelayReportResponse{}},
+	{Method: http.MethodGet, Path: "/projects/{id}/delay-report/{reportId}/comments", Tag: "projects", Summary: "List the comments of a delay report", Response: DelayReportCommentResponse{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/delay-report/{reportId}/comments", Tag: "projects", Summary: "Comment on a delay report", Body: createDelayReportCommentReq{}, Status: http.StatusCreated, Response: DelayReportCommentResponse{}},
+	{Method: http.MethodGet, Path: "/projects/{id}/report-chat", Tag: "projects", Summary: "List the report chat of a project", Response: ReportChatMessageResponse{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/report-chat", Tag: "projects", Summary: "Post to the report chat of a project", Body: createReportChatReq{}, Status: http.StatusCreated, Response: ReportChatMessageResponse{}},
+
+	{Method: http.MethodGet, Path: "/projects/{id}/stages", Tag: "projects", Summary: "List project stages", Response: Stage{}, List: true},
+	{Method: http.MethodPost, Path: "/projects/{id}/stages", Tag: "projects", Summary: "Create a stage", Body: createStageRequest{}, Status: http.StatusCreated, Response: Stage{}},
+	{Method: http.MethodDelete, Path: "/projects/{id}/stages/{stageId}", Tag: "projects", Summary: "Delete a stage of the project", Status: http.StatusNoContent},
+	{Method: http.MethodPatch, Path: "/stages/{id}", Tag: "projects", Summary: "Update a stage", Body: updateStageRequest{}, Response: Stage{}},
+	{Method: http.MethodDelete, Path: "/stages/{id}", Tag: "projects", Summary: "Delete a stage", Status: http.StatusNoContent},
+
+	{Method: http.MethodGet, Path: "/stages/{id}/tasks", Tag: "tasks", Summary: "List the tasks of a stage", Response: Task{}, List: true},
+	{Method: http.MethodPost, Path: "/stages/{id}/tasks", Tag: "tasks", Summary: "Create a task", Body: createTaskRequest{}, Status: http.StatusCreated, Response: Task{}},
+	{Method: http.MethodGet, Path: "/tasks/{id}", Tag: "tasks", Summary: "Get a task", Response: Task{}, Conditional: true},
+	{Method: http.MethodPatch, Path: "/tasks/{id}", Tag: "tasks", Summary: "Update a task", Body: updateTaskRequest{}, Response: Task{}, Conditional: true},
+	{Method: http.MethodDelete, Path: "/tasks/{id}", Tag: "tasks", Summary: "Delete a task", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/tasks/{id}/comments", Tag: "tasks", Summary: "List task comments", Response: TaskCommentResponse{}, List: true, Page: &taskCommentsPageLimits},
+	{Method: http.MethodPost, Path: "/tasks/{id}/comment", Tag: "tasks", Summary: "Comment on a task", Body: createTaskCommentReq{}, Status: http.StatusCreated, Response: TaskCommentResponse{}},
+	{Method: http.MethodGet, Path: "/tasks/{id}/history", Tag: "tasks", Summary: "List the delay reports of a task", Response: DelayReportResponse{}, List: true},
+	{Method: http.MethodGet, Path: "/tasks/{id}/report-chat", Tag: "tasks", Summary: "List the report chat of a task", Response: ReportChatMessageResponse{}, List: true},
+	{Method: http.MethodPost, Path: "/tasks/{id}/report-chat", Tag: "tasks", Summary: "Post to the report chat of a task", Body: createReportChatReq{}, Status: http.StatusCreated, Response: ReportChatMessageResponse{}},
+}
@@ -7,19 +7,59 @@ import (
 	"strings"
 	"time"
 
+	"tm-platform-backend/internal/cache"
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/pagination"
+
 	"github.com/google/uuid"
 )
 
 type Repository struct {
-	db *sql.DB
+	db       *sql.DB
+	replica  *sql.DB
+	cache    cache.Store
+	timezone *time.Location
 }
 
-var ErrCannotAssignOwnerAsManager = errors.New("owner cannot be manager")
+var (
+	ErrCannotAssignOwnerAsManager = errors.New("owner cannot be manager")
+	ErrNotWorkspaceMember         = errors.New("user is not a member of the project's workspace")
+)
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithCache caches budget summaries and member lists in store.
+func (r *Repository) WithCache(store cache.Store) *Repository {
+	r.cache = store
+	return r
+}
+
+// WithReplica sends the heavy reads of the repository to the read replica
+// conn, see db.Reader.
+func (r *Repository) WithReplica(conn *sql.DB) *Repository {
+	r.replica = conn
+	return r
+}
+
+func (r *Repository) reader(ctx context.Context) *sql.DB {
+	return db.Reader(ctx, r.db, r.replica)
+}
+
+const (
+	budgetCacheTTL  = 5 * time.Minute
+	membersCacheTTL = 5 * time.Minute
+)
+
+func budgetCacheKey(projectID uuid.UUID) string {
+	return "projects:budget:" + projectID.String()
+}
+
+func membersCacheKey(projectID uuid.UUID) string {
+	return "projects:members:" + projectID.String()
+}
+
 type ProjectInput struct {
 	Title       string
 	Description *string
@@ -31,6 +71,9 @@ type ProjectInput struct {
 	Status      ProjectStatus
 	TotalBudget int64
 	Blocks      []byte
+	// Timezone is the IANA zone of the project; empty keeps the current one,
+	// or takes the owner's on create
+	Timezone string
 }
 
 type rowScanner interface {
@@ -50,6 +93,7 @@ func scanProject(scanner rowScanner) (Project, error) {
 		blocks      []byte
 		createdAt   time.Time
 		updatedAt   time.Time
+		timezone    string
 	)
 
 	err := scanner.Scan(
@@ -67,11 +111,15 @@ func scanProject(scanner rowScanner) (Project, error) {
 		&blocks,
 		&createdAt,
 		&updatedAt,
+		&timezone,
 	)
 	if err != nil {
 		return Project{}, err
 	}
 
+	loc := loadLocation(timezone)
+	project.Timezone = loc.String()
+
 	if description.Valid {
 		project.Description = &description.String
 	}
@@ -81,18 +129,12 @@ func scanProject(scanner rowScanner) (Project, error) {
 	if iconURL.Valid {
 		project.IconURL = &iconURL.String
 	}
-	if startDate.Valid {
-		project.StartDate = &startDate.Time
-	}
-	if deadline.Valid {
-		project.Deadline = &deadline.Time
-	}
-	if endDate.Valid {
-		project.EndDate = &endDate.Time
-	}
+	project.StartDate = timeIn(startDate, loc)
+	project.Deadline = timeIn(deadline, loc)
+	project.EndDate = timeIn(endDate, loc)
 	project.Blocks = blocks
-	project.CreatedAt = createdAt
-	project.UpdatedAt = updatedAt
+	project.CreatedAt = createdAt.In(loc)
+	project.UpdatedAt = updatedAt.In(loc)
 
 	project.Status = ProjectStatus(status)
 	endForDuration := project.Deadline
@@ -113,6 +155,10 @@ func (r *Repository) Create(ctx context.Context, ownerID uuid.UUID, input Projec
 		deadline = input.EndDate
 	}
 	endDate := deadline
+	timezone, err := r.projectTimezone(ctx, ownerID, input.Timezone)
+	if err != nil {
+		return Project{}, err
+	}
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -122,9 +168,9 @@ func (r *Repository) Create(ctx context.Context, ownerID uuid.UUID, input Projec
 
 	row := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO projects (owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		 RETURNING id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at`,
+		`INSERT INTO projects (owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, workspace_id, timezone)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, (SELECT workspace_id FROM workspace_members WHERE user_id = $1 ORDER BY joined_at ASC LIMIT 1)), $13)
+		 RETURNING id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at, timezone`,
 		ownerID,
 		input.Title,
 		nullString(input.Description),
@@ -136,6 +182,8 @@ func (r *Repository) Create(ctx context.Context, ownerID uuid.UUID, input Projec
 		string(input.Status),
 		input.TotalBudget,
 		blocks,
+		db.Workspace(ctx),
+		timezone,
 	)
 
 	project, err := scanProject(row)
@@ -178,6 +226,10 @@ func (r *Repository) CreateWithID(ctx context.Context, ownerID, projectID uuid.U
 		deadline = input.EndDate
 	}
 	endDate := deadline
+	timezone, err := r.projectTimezone(ctx, ownerID, input.Timezone)
+	if err != nil {
+		return Project{}, err
+	}
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -187,9 +239,9 @@ func (r *Repository) CreateWithID(ctx context.Context, ownerID, projectID uuid.U
 
 	row := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO projects (id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		 RETURNING id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at`,
+		`INSERT INTO projects (id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, workspace_id, timezone)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, COALESCE($13, (SELECT workspace_id FROM workspace_members WHERE user_id = $2 ORDER BY joined_at ASC LIMIT 1)), $14)
+		 RETURNING id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at, timezone`,
 		projectID,
 		ownerID,
 		input.Title,
@@ -202,6 +254,8 @@ func (r *Repository) CreateWithID(ctx context.Context, ownerID, projectID uuid.U
 		string(input.Status),
 		input.TotalBudget,
 		blocks,
+		db.Workspace(ctx),
+		timezone,
 	)
 
 	project, err := scanProject(row)
@@ -237,15 +291,20 @@ func (r *Repository) CreateWithID(ctx context.Context, ownerID, projectID uuid.U
 func (r *Repository) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]Project, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at
+		`SELECT id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at, timezone
 		 FROM projects
-		 WHERE EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = projects.id AND pm.user_id = $1
-		 )
+		 WHERE workspace_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = projects.id AND pm.user_id = $1
+		 	)
+		 	OR `+hierarchyReadAccess("projects.id", "$1")+`
+		   )
 		 ORDER BY start_date DESC NULLS LAST, id DESC`,
 		ownerID,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -270,16 +329,103 @@ func (r *Repository) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]Proj
 	return projects, rows.Err()
 }
 
+// projectKey is the sort key of ListByOwnerPage
+type projectKey struct {
+	StartDate *time.Time `json:"startDate,omitempty"`
+	ID        uuid.UUID  `json:"id"`
+}
+
+// after returns the condition selecting the projects that follow k, by
+// start date descending with undated projects last, and its arguments from
+// $4 on.
+func (k projectKey) after() (string, []any) {
+	if k.StartDate == nil {
+		return ` AND start_date IS NULL AND id < $4`, []any{k.ID}
+	}
+	return ` AND (start_date < $4 OR (start_date = $4 AND id < $5) OR start_date IS NULL)`, []any{*k.StartDate, k.ID}
+}
+
+// ListByOwnerPage is ListByOwner one page at a time.
+func (r *Repository) ListByOwnerPage(ctx context.Context, ownerID uuid.UUID, page pagination.Page) (pagination.List[Project], error) {
+	var key projectKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[Project]{}, err
+	}
+
+	query := `SELECT id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at, timezone, COUNT(*) OVER ()
+		 FROM projects
+		 WHERE (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = projects.id AND pm.user_id = $1
+		 	)
+		 	OR ` + hierarchyReadAccess("projects.id", "$1") + `
+		 )
+		 AND workspace_id = $3`
+	args := []any{ownerID, page.Fetch(), db.Workspace(ctx)}
+	if after {
+		predicate, keyArgs := key.after()
+		query += predicate
+		args = append(args, keyArgs...)
+	}
+	query += ` ORDER BY start_date DESC NULLS LAST, id DESC LIMIT $2`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return pagination.List[Project]{}, err
+	}
+	defer rows.Close()
+
+	var (
+		projects []Project
+		total    int
+	)
+	for rows.Next() {
+		project, err := scanProject(pagination.Scanner{Rows: rows, Dest: &total})
+		if err != nil {
+			return pagination.List[Project]{}, err
+		}
+		projects = append(projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.List[Project]{}, err
+	}
+
+	list, err := pagination.Cut(projects, page, func(i int) any {
+		return projectKey{StartDate: projects[i].StartDate, ID: projects[i].ID}
+	})
+	if err != nil {
+		return pagination.List[Project]{}, err
+	}
+	for i := range list.Items {
+		if err := r.populateProjectBudget(ctx, ownerID, &list.Items[i]); err != nil {
+			return pagination.List[Project]{}, err
+		}
+		if err := r.populateProjectRole(ctx, ownerID, &list.Items[i]); err != nil {
+			return pagination.List[Project]{}, err
+		}
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
+}
+
 func (r *Repository) GetByID(ctx context.Context, ownerID, projectID uuid.UUID) (Project, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at
+		`SELECT id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at, timezone
 		 FROM projects
 		 WHERE id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = projects.id AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = projects.id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("projects.id", "$2")+`
 		   )`,
 		projectID,
 		ownerID,
@@ -322,6 +468,7 @@ func (r *Repository) Update(ctx context.Context, ownerID, projectID uuid.UUID, i
 			 status = $10,
 			 total_budget = $11,
 			 blocks = $12,
+			 timezone = COALESCE(NULLIF($13, ''), timezone),
 			 updated_at = now()
 		 WHERE id = $1
 		   AND EXISTS (
@@ -331,7 +478,7 @@ func (r *Repository) Update(ctx context.Context, ownerID, projectID uuid.UUID, i
 		 	  AND pm.user_id = $2
 		 	  AND pm.role IN ('owner', 'manager')
 		   )
-		 RETURNING id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at`,
+		 RETURNING id, owner_id, title, description, cover_url, icon_url, start_date, deadline, end_date, status, total_budget, blocks, created_at, updated_at, timezone`,
 		projectID,
 		ownerID,
 		input.Title,
@@ -344,12 +491,14 @@ func (r *Repository) Update(ctx context.Context, ownerID, projectID uuid.UUID, i
 		string(input.Status),
 		input.TotalBudget,
 		blocks,
+		input.Timezone,
 	)
 
 	project, err := scanProject(row)
 	if err != nil {
 		return Project{}, err
 	}
+	cache.Invalidate(ctx, r.cache, budgetCacheKey(projectID))
 	if err := r.populateProjectBudget(ctx, ownerID, &project); err != nil {
 		return Project{}, err
 	}
@@ -386,6 +535,7 @@ func (r *Repository) Delete(ctx context.Context, ownerID, projectID uuid.UUID) e
 		return sql.ErrNoRows
 	}
 
+	cache.Invalidate(ctx, r.cache, budgetCacheKey(projectID), membersCacheKey(projectID))
 	return nil
 }
 
@@ -396,11 +546,14 @@ func (r *Repository) CreateExpense(ctx context.Context, ownerID, projectID, crea
 		 SELECT p.id, $3, $4, $5
 		 FROM projects p
 		 WHERE p.id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = p.id
-		 	  AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = p.id
+		 		  AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyPermission("can_approve_expenses", "p.id", "$2")+`
 		   )
 		 RETURNING id, project_id, title, amount, created_by, created_at`,
 		projectID,
@@ -410,7 +563,12 @@ func (r *Repository) CreateExpense(ctx context.Context, ownerID, projectID, crea
 		createdBy,
 	)
 
-	return scanExpense(row)
+	expense, err := scanExpense(row)
+	if err != nil {
+		return ProjectExpense{}, err
+	}
+	cache.Invalidate(ctx, r.cache, budgetCacheKey(expense.ProjectID))
+	return expense, nil
 }
 
 func (r *Repository) ListExpenses(ctx context.Context, ownerID, projectID uuid.UUID) ([]ProjectExpense, error) {
@@ -419,10 +577,13 @@ func (r *Repository) ListExpenses(ctx context.Context, ownerID, projectID uuid.U
 		`SELECT e.id, e.project_id, e.title, e.amount, e.created_by, e.created_at
 		 FROM project_expenses e
 		 WHERE e.project_id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = e.project_id AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = e.project_id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("e.project_id", "$2")+`
 		   )
 		 ORDER BY e.created_at DESC, e.id DESC`,
 		projectID,
@@ -445,6 +606,71 @@ func (r *Repository) ListExpenses(ctx context.Context, ownerID, projectID uuid.U
 	return expenses, rows.Err()
 }
 
+// expenseKey is the sort key of ListExpensesPage
+type expenseKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// ListExpensesPage is ListExpenses one page at a time.
+func (r *Repository) ListExpensesPage(ctx context.Context, ownerID, projectID uuid.UUID, page pagination.Page) (pagination.List[ProjectExpense], error) {
+	var key expenseKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[ProjectExpense]{}, err
+	}
+
+	query := `SELECT e.id, e.project_id, e.title, e.amount, e.created_by, e.created_at, COUNT(*) OVER ()
+		 FROM project_expenses e
+		 WHERE e.project_id = $1
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = e.project_id AND pm.user_id = $2
+		 	)
+		 	OR ` + hierarchyReadAccess("e.project_id", "$2") + `
+		   )`
+	args := []any{projectID, ownerID, page.Fetch()}
+	if after {
+		query += ` AND (e.created_at, e.id) < ($4, $5)`
+		args = append(args, key.CreatedAt, key.ID)
+	}
+	query += ` ORDER BY e.created_at DESC, e.id DESC LIMIT $3`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return pagination.List[ProjectExpense]{}, err
+	}
+	defer rows.Close()
+
+	var (
+		expenses []ProjectExpense
+		total    int
+	)
+	for rows.Next() {
+		expense, err := scanExpense(pagination.Scanner{Rows: rows, Dest: &total})
+		if err != nil {
+			return pagination.List[ProjectExpense]{}, err
+		}
+		expenses = append(expenses, expense)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.List[ProjectExpense]{}, err
+	}
+
+	list, err := pagination.Cut(expenses, page, func(i int) any {
+		return expenseKey{CreatedAt: expenses[i].CreatedAt, ID: expenses[i].ID}
+	})
+	if err != nil {
+		return pagination.List[ProjectExpense]{}, err
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
+}
+
 func (r *Repository) GetBudget(ctx context.Context, ownerID, projectID uuid.UUID) (BudgetSummary, error) {
 	row := r.db.QueryRowContext(
 		ctx,
@@ -453,10 +679,13 @@ func (r *Repository) GetBudget(ctx context.Context, ownerID, projectID uuid.UUID
 		 FROM projects p
 		 LEFT JOIN project_expenses e ON e.project_id = p.id
 		 WHERE p.id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = p.id AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = p.id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("p.id", "$2")+`
 		   )
 		 GROUP BY p.total_budget`,
 		projectID,
@@ -473,30 +702,30 @@ func (r *Repository) GetBudget(ctx context.Context, ownerID, projectID uuid.UUID
 }
 
 func (r *Repository) DeleteExpense(ctx context.Context, ownerID, expenseID uuid.UUID) error {
-	result, err := r.db.ExecContext(
+	var projectID uuid.UUID
+	err := r.db.QueryRowContext(
 		ctx,
 		`DELETE FROM project_expenses e
-		 USING projects p, project_members pm
 		 WHERE e.id = $1
-		   AND p.id = e.project_id
-		   AND pm.project_id = p.id
-		   AND pm.user_id = $2
-		   AND pm.role IN ('owner', 'manager')`,
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = e.project_id
+		 		  AND pm.user_id = $2
+		 		  AND pm.role IN ('owner', 'manager')
+		 	)
+		 	OR `+hierarchyPermission("can_approve_expenses", "e.project_id", "$2")+`
+		   )
+		 RETURNING e.project_id`,
 		expenseID,
 		ownerID,
-	)
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
+	).Scan(&projectID)
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
 
+	cache.Invalidate(ctx, r.cache, budgetCacheKey(projectID))
 	return nil
 }
 
@@ -534,10 +763,13 @@ func (r *Repository) ListStagesByProject(ctx context.Context, ownerID, projectID
 		`SELECT s.id, s.project_id, s.title, s.order_index
 		 FROM project_stages s
 		 WHERE s.project_id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("s.project_id", "$2")+`
 		   )
 		 ORDER BY s.order_index ASC, s.created_at ASC`,
 		projectID,
@@ -566,9 +798,12 @@ func (r *Repository) ListStagesByUser(ctx context.Context, userID uuid.UUID) ([]
 		`SELECT DISTINCT s.id, s.project_id, s.title, s.order_index
 		 FROM project_stages s
 		 JOIN project_members pm ON pm.project_id = s.project_id
+		 JOIN projects p ON p.id = s.project_id
 		 WHERE pm.user_id = $1
+		   AND p.workspace_id = $2
 		 ORDER BY s.project_id, s.order_index ASC, s.id ASC`,
 		userID,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -684,7 +919,7 @@ func (r *Repository) CreateTask(ctx context.Context, ownerID, stageID uuid.UUID,
 		 	  )
 	 		RETURNING id, stage_id, title, status, start_date, deadline, order_index, blocks, updated_at
 		 )
-		 SELECT i.id, i.stage_id, s.project_id, i.title, i.status, i.start_date, i.deadline, i.order_index, i.blocks, i.updated_at
+		 SELECT i.id, i.stage_id, s.project_id, i.title, i.status, i.start_date, i.deadline, i.order_index, i.blocks, i.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
 		 FROM inserted i
 		 JOIN project_stages s ON s.id = i.stage_id`,
 		stageID,
@@ -702,14 +937,17 @@ func (r *Repository) CreateTask(ctx context.Context, ownerID, stageID uuid.UUID,
 func (r *Repository) GetTaskByID(ctx context.Context, ownerID, taskID uuid.UUID) (Task, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at
+		`SELECT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
 		 FROM stage_tasks t
 		 JOIN project_stages s ON s.id = t.stage_id
 		 WHERE t.id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("s.project_id", "$2")+`
 		   )`,
 		taskID,
 		ownerID,
@@ -721,14 +959,17 @@ func (r *Repository) GetTaskByID(ctx context.Context, ownerID, taskID uuid.UUID)
 func (r *Repository) ListTasksByStage(ctx context.Context, ownerID, stageID uuid.UUID) ([]Task, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at
+		`SELECT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
 		 FROM stage_tasks t
 		 JOIN project_stages s ON s.id = t.stage_id
 		 WHERE t.stage_id = $1
-		   AND EXISTS (
-		 	SELECT 1
-		 	FROM project_members pm
-		 	WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		   AND (
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = s.project_id AND pm.user_id = $2
+		 	)
+		 	OR `+hierarchyReadAccess("s.project_id", "$2")+`
 		   )
 		 ORDER BY t.order_index ASC, t.created_at ASC`,
 		stageID,
@@ -754,13 +995,16 @@ func (r *Repository) ListTasksByStage(ctx context.Context, ownerID, stageID uuid
 func (r *Repository) ListTasksByUser(ctx context.Context, userID uuid.UUID) ([]Task, error) {
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT DISTINCT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at
+		`SELECT DISTINCT t.id, t.stage_id, s.project_id, t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
 		 FROM stage_tasks t
 		 JOIN project_stages s ON s.id = t.stage_id
 		 JOIN project_members pm ON pm.project_id = s.project_id
+		 JOIN projects p ON p.id = s.project_id
 		 WHERE pm.user_id = $1
+		   AND p.workspace_id = $2
 		 ORDER BY s.project_id, t.stage_id, t.order_index ASC, t.id ASC`,
 		userID,
+		db.Workspace(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -799,6 +1043,7 @@ func (r *Repository) UpdateTask(ctx context.Context, ownerID, taskID uuid.UUID,
 			 status = $3,
 			 start_date = $4,
 			 deadline = $5,
+			 deadline_reminded_at = CASE WHEN t.deadline IS DISTINCT FROM $5 THEN NULL ELSE t.deadline_reminded_at END,
 			 stage_id = COALESCE($9, t.stage_id),
 			 order_index = $6,
 			 blocks = $7,
@@ -826,7 +1071,8 @@ func (r *Repository) UpdateTask(ctx context.Context, ownerID, taskID uuid.UUID,
 				  )
 			 )
 		   )
-		 RETURNING t.id, t.stage_id, (SELECT project_id FROM project_stages WHERE id = t.stage_id), t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at`,
+		 RETURNING t.id, t.stage_id, (SELECT project_id FROM project_stages WHERE id = t.stage_id), t.title, t.status, t.start_date, t.deadline, t.order_index, t.blocks, t.updated_at,
+		  (SELECT p_zone.timezone FROM projects p_zone JOIN project_stages s_zone ON s_zone.project_id = p_zone.id WHERE s_zone.id = t.stage_id)`,
 		taskID,
 		title,
 		status,
@@ -879,6 +1125,7 @@ func scanTask(scanner rowScanner) (Task, error) {
 		deadline  sql.NullTime
 		blocks    []byte
 		updatedAt time.Time
+		timezone  string
 	)
 
 	err := scanner.Scan(
@@ -892,21 +1139,19 @@ func scanTask(scanner rowScanner) (Task, error) {
 		&task.OrderIndex,
 		&blocks,
 		&updatedAt,
+		&timezone,
 	)
 	if err != nil {
 		return Task{}, err
 	}
-	if startDate.Valid {
-		task.StartDate = &startDate.Time
-	}
-	if deadline.Valid {
-		task.Deadline = &deadline.Time
-	}
+	loc := loadLocation(timezone)
+	task.StartDate = timeIn(startDate, loc)
+	task.Deadline = timeIn(deadline, loc)
 	if len(blocks) == 0 {
 		blocks = []byte("[]")
 	}
 	task.Blocks = blocks
-	task.UpdatedAt = updatedAt
+	task.UpdatedAt = updatedAt.In(loc)
 	return task, nil
 }
 
@@ -928,6 +1173,91 @@ func IsNotFound(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
 }
 
+// ReadAccess returns an SQL predicate that is true when the requester may
+// read the project in projectIDColumn: as a member or through the org
+// hierarchy. Other packages filter their own queries with it.
+func ReadAccess(projectIDColumn, requesterParam string) string {
+	return `(
+		 	EXISTS (
+		 		SELECT 1
+		 		FROM project_members pm
+		 		WHERE pm.project_id = ` + projectIDColumn + ` AND pm.user_id = ` + requesterParam + `
+		 	)
+		 	OR ` + hierarchyReadAccess(projectIDColumn, requesterParam) + `
+		 )`
+}
+
+// hierarchyReadAccess returns an SQL predicate that grants read access to a
+// project through the org hierarchy: either the requester holds a role with
+// can_view_all_projects, or they are flagged as department head and the
+// project owner sits inside that head's department subtree.
+func hierarchyReadAccess(projectIDColumn, requesterParam string) string {
+	return `(
+		 	` + hierarchyPermission("can_view_all_projects", projectIDColumn, requesterParam) + `
+		 	OR EXISTS (
+		 		SELECT 1
+		 		FROM projects hp
+		 		JOIN hierarchy_nodes owner_node ON owner_node.user_id = hp.owner_id AND owner_node.workspace_id = hp.workspace_id
+		 		JOIN hierarchy_nodes head_node ON head_node.user_id = ` + requesterParam + ` AND head_node.is_department_head AND head_node.workspace_id = hp.workspace_id
+		 		JOIN hierarchy_nodes head_scope ON head_scope.id = head_node.parent_id
+		 		WHERE hp.id = ` + projectIDColumn + `
+		 		  AND owner_node.path LIKE head_scope.path || '.%'
+		 	)
+		 )`
+}
+
+// hierarchyPermission returns an SQL predicate that is true when the requester
+// holds the given hierarchy_role_catalog permission column through any node
+// they occupy in the org chart of the project's workspace. The company root
+// user holds every permission.
+func hierarchyPermission(permissionColumn, projectIDColumn, requesterParam string) string {
+	return `EXISTS (
+		 	SELECT 1
+		 	FROM hierarchy_nodes perm_node
+		 	LEFT JOIN hierarchy_role_catalog perm_role
+		 	  ON perm_role.workspace_id = perm_node.workspace_id AND perm_role.name = BTRIM(perm_node.role_title)
+		 	WHERE perm_node.user_id = ` + requesterParam + `
+		 	  AND perm_node.workspace_id = (SELECT perm_project.workspace_id FROM projects perm_project WHERE perm_project.id = ` + projectIDColumn + `)
+		 	  AND (perm_node.type = 'company' OR perm_role.` + permissionColumn + `)
+		 )`
+}
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// requireWorkspaceMembers returns ErrNotWorkspaceMember unless all the users
+// belong to the workspace of the project.
+func requireWorkspaceMembers(ctx context.Context, q rowQuerier, projectID uuid.UUID, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var missing bool
+	if err := q.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1
+		 	FROM unnest($2::uuid[]) AS candidate(user_id)
+		 	WHERE NOT EXISTS (
+		 		SELECT 1
+		 		FROM projects p
+		 		JOIN workspace_members wm ON wm.workspace_id = p.workspace_id
+		 		WHERE p.id = $1
+		 		  AND wm.user_id = candidate.user_id
+		 	)
+		 )`,
+		projectID,
+		idStrings(userIDs),
+	).Scan(&missing); err != nil {
+		return err
+	}
+	if missing {
+		return ErrNotWorkspaceMember
+	}
+	return nil
+}
+
 func (r *Repository) isProjectMember(ctx context.Context, userID, projectID uuid.UUID) error {
 	var exists int
 	err := r.db.QueryRowContext(
@@ -1064,9 +1394,10 @@ func (r *Repository) ListDelayReports(ctx context.Context, requesterID, projectI
 }
 
 func (r *Repository) ListMembersByProject(ctx context.Context, requesterID, projectID uuid.UUID) ([]ProjectMemberResponse, error) {
-	rows, err := r.db.QueryContext(
+	var allowed bool
+	if err := r.db.QueryRowContext(
 		ctx,
-		`WITH access AS (
+		`SELECT EXISTS (
 			SELECT 1
 			FROM projects p
 			WHERE p.id = $1
@@ -1078,13 +1409,31 @@ func (r *Repository) ListMembersByProject(ctx context.Context, requesterID, proj
 					WHERE me.project_id = p.id AND me.user_id = $2
 				)
 			  )
-		), members AS (
-			SELECT u.id, u.email, pm.role, pm.created_at
+		)`,
+		projectID,
+		requesterID,
+	).Scan(&allowed); err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return make([]ProjectMemberResponse, 0), nil
+	}
+
+	return cache.Load(ctx, r.cache, membersCacheKey(projectID), membersCacheTTL, func() ([]ProjectMemberResponse, error) {
+		return r.listMembers(ctx, projectID)
+	})
+}
+
+func (r *Repository) listMembers(ctx context.Context, projectID uuid.UUID) ([]ProjectMemberResponse, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`WITH members AS (
+			SELECT u.id, u.email, COALESCE(u.full_name, '') AS full_name, pm.role, pm.created_at
 			FROM project_members pm
 			JOIN users u ON u.id = pm.user_id
 			WHERE pm.project_id = $1
 			UNION ALL
-			SELECT u_owner.id, u_owner.email, 'owner'::text, p.created_at
+			SELECT u_owner.id, u_owner.email, COALESCE(u_owner.full_name, ''), 'owner'::text, p.created_at
 			FROM projects p
 			JOIN users u_owner ON u_owner.id = p.owner_id
 			WHERE p.id = $1
@@ -1095,12 +1444,10 @@ func (r *Repository) ListMembersByProject(ctx context.Context, requesterID, proj
 				  AND pm_owner.user_id = p.owner_id
 			  )
 		)
-		SELECT m.id, m.email, m.role
+		SELECT m.id, m.email, m.full_name, m.role
 		FROM members m
-		WHERE EXISTS (SELECT 1 FROM access)
 		ORDER BY m.created_at ASC, m.email ASC`,
 		projectID,
-		requesterID,
 	)
 	if err != nil {
 		return nil, err
@@ -1111,7 +1458,7 @@ func (r *Repository) ListMembersByProject(ctx context.Context, requesterID, proj
 	for rows.Next() {
 		var member ProjectMemberResponse
 		var role string
-		if err := rows.Scan(&member.User.ID, &member.User.Email, &role); err != nil {
+		if err := rows.Scan(&member.User.ID, &member.User.Email, &member.User.FullName, &role); err != nil {
 			return nil, err
 		}
 		member.Role = ProjectMemberRole(role)
@@ -1140,10 +1487,16 @@ func (r *Repository) ResolveUserIDsByRefs(ctx context.Context, refs map[string]s
 			ctx,
 			`SELECT id
 			 FROM users
-			 WHERE lower(email) = $1
-			    OR lower(id::text) = $1
+			 WHERE (lower(email) = $1 OR lower(id::text) = $1)
+			   AND EXISTS (
+			 	SELECT 1
+			 	FROM workspace_members wm
+			 	WHERE wm.user_id = users.id
+			 	  AND wm.workspace_id = $2
+			   )
 			 LIMIT 1`,
 			normalized,
+			db.Workspace(ctx),
 		).Scan(&userID); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				continue
@@ -1170,6 +1523,7 @@ func (r *Repository) EnsureMember(ctx context.Context, requesterID, projectID, u
 		 	SELECT 1
 		 	FROM projects p
 		 	LEFT JOIN project_members me ON me.project_id = p.id AND me.user_id = $3
+		 	JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = $2
 		 	WHERE p.id = $1
 		 	  AND (
 		 		p.owner_id = $3
@@ -1211,6 +1565,7 @@ func (r *Repository) EnsureMember(ctx context.Context, requesterID, projectID, u
 		return sql.ErrNoRows
 	}
 
+	cache.Invalidate(ctx, r.cache, membersCacheKey(projectID))
 	return nil
 }
 
@@ -1218,6 +1573,9 @@ func (r *Repository) UpsertMember(ctx context.Context, requesterID, projectID, u
 	if role == ProjectMemberRoleManager {
 		return r.DelegateProject(ctx, requesterID, projectID, userID)
 	}
+	if err := requireWorkspaceMembers(ctx, r.db, projectID, userID); err != nil {
+		return err
+	}
 
 	result, err := r.db.ExecContext(
 		ctx,
@@ -1252,6 +1610,7 @@ func (r *Repository) UpsertMember(ctx context.Context, requesterID, projectID, u
 		return sql.ErrNoRows
 	}
 
+	cache.Invalidate(ctx, r.cache, membersCacheKey(projectID))
 	return nil
 }
 
@@ -1282,6 +1641,14 @@ func (r *Repository) UpdateRoles(ctx context.Context, requesterID, projectID uui
 		return err
 	}
 
+	candidates := append([]uuid.UUID(nil), memberIDs...)
+	if managerID != nil {
+		candidates = append(candidates, *managerID)
+	}
+	if err := requireWorkspaceMembers(ctx, tx, projectID, candidates...); err != nil {
+		return err
+	}
+
 	if managerID != nil {
 		var managerCurrentRole string
 		err = tx.QueryRowContext(
@@ -1409,6 +1776,7 @@ func (r *Repository) UpdateRoles(ctx context.Context, requesterID, projectID uui
 		return err
 	}
 
+	cache.Invalidate(ctx, r.cache, membersCacheKey(projectID))
 	return nil
 }
 
@@ -1435,6 +1803,9 @@ func (r *Repository) DelegateProject(ctx context.Context, requesterID, projectID
 		}
 		return err
 	}
+	if err := requireWorkspaceMembers(ctx, tx, projectID, newManagerID); err != nil {
+		return err
+	}
 
 	var currentRole string
 	err = tx.QueryRowContext(
@@ -1480,6 +1851,7 @@ func (r *Repository) DelegateProject(ctx context.Context, requesterID, projectID
 		return err
 	}
 
+	cache.Invalidate(ctx, r.cache, membersCacheKey(projectID))
 	return nil
 }
 
@@ -1513,6 +1885,7 @@ func (r *Repository) DeleteMember(ctx context.Context, requesterID, projectID, u
 		return sql.ErrNoRows
 	}
 
+	cache.Invalidate(ctx, r.cache, membersCacheKey(projectID))
 	return nil
 }
 
@@ -1678,7 +2051,7 @@ func (r *Repository) populateProjectBudget(ctx context.Context, ownerID uuid.UUI
 		return nil
 	}
 
-	summary, err := r.GetBudget(ctx, ownerID, project.ID)
+	summary, err := r.projectBudget(ctx, project.ID)
 	if err != nil {
 		return err
 	}
@@ -1689,6 +2062,28 @@ func (r *Repository) populateProjectBudget(ctx context.Context, ownerID uuid.UUI
 	return nil
 }
 
+// projectBudget is the budget summary of a project the caller was already
+// granted access to.
+func (r *Repository) projectBudget(ctx context.Context, projectID uuid.UUID) (BudgetSummary, error) {
+	return cache.Load(ctx, r.cache, budgetCacheKey(projectID), budgetCacheTTL, func() (BudgetSummary, error) {
+		var summary BudgetSummary
+		err := r.db.QueryRowContext(
+			ctx,
+			`SELECT p.total_budget,
+			 COALESCE((SELECT SUM(e.amount) FROM project_expenses e WHERE e.project_id = p.id), 0)
+			 FROM projects p
+			 WHERE p.id = $1`,
+			projectID,
+		).Scan(&summary.TotalBudget, &summary.SpentBudget)
+		if err != nil {
+			return BudgetSummary{}, err
+		}
+		summary.RemainingBudget = summary.TotalBudget - summary.SpentBudget
+		summary.ProgressPercent = calculateProgressPercent(summary.SpentBudget, summary.TotalBudget)
+		return summary, nil
+	})
+}
+
 func (r *Repository) HasEditAccess(ctx context.Context, userID, projectID uuid.UUID) (bool, error) {
 	var exists int
 	err := r.db.QueryRowContext(
@@ -1725,6 +2120,10 @@ func (r *Repository) populateProjectRole(ctx context.Context, userID uuid.UUID,
 		project.ID,
 		userID,
 	).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Hierarchy readers (heads, can_view_all_projects) are not project members.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,95 @@
+package projects
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tm-platform-backend/internal/pagination"
+)
+
+func TestProjectKeyAfter(t *testing.T) {
+	id := uuid.MustParse("6f1c2d9e-4b7a-4c1e-9f0d-2a3b4c5d6e7f")
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		key           projectKey
+		wantPredicate string
+		wantArgs      []any
+	}{
+		{
+			name:          "dated project",
+			key:           projectKey{StartDate: &start, ID: id},
+			wantPredicate: ` AND (start_date < $4 OR (start_date = $4 AND id < $5) OR start_date IS NULL)`,
+			wantArgs:      []any{start, id},
+		},
+		{
+			name:          "undated project",
+			key:           projectKey{ID: id},
+			wantPredicate: ` AND start_date IS NULL AND id < $4`,
+			wantArgs:      []any{id},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predicate, args := tt.key.after()
+			if predicate != tt.wantPredicate {
+				t.Errorf("after() predicate = %q, want %q", predicate, tt.wantPredicate)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("after() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// An undated project must come back from its cursor without a start date,
+// so the next page continues among the undated projects.
+func TestProjectKeyCursor(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	dated := projectKey{StartDate: &start, ID: uuid.New()}
+	undated := projectKey{ID: uuid.New()}
+
+	tests := []struct {
+		name string
+		last projectKey
+	}{
+		{"dated project last", dated},
+		{"undated project last", undated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := pagination.Limits{Default: 1}
+			page, err := pagination.NewPage(0, "", limits)
+			if err != nil {
+				t.Fatalf("NewPage() error = %v", err)
+			}
+			rows := []projectKey{tt.last, {ID: uuid.New()}}
+			list, err := pagination.Cut(rows, page, func(i int) any { return rows[i] })
+			if err != nil {
+				t.Fatalf("Cut() error = %v", err)
+			}
+
+			next, err := pagination.NewPage(0, list.Next, limits)
+			if err != nil {
+				t.Fatalf("NewPage(%q) error = %v", list.Next, err)
+			}
+			var key projectKey
+			if ok, err := next.Key(&key); err != nil || !ok {
+				t.Fatalf("Key() = %v, %v, want true, nil", ok, err)
+			}
+			if key.ID != tt.last.ID {
+				t.Errorf("Key() id = %v, want %v", key.ID, tt.last.ID)
+			}
+			switch {
+			case tt.last.StartDate == nil && key.StartDate != nil:
+				t.Errorf("Key() start date = %v, want none", *key.StartDate)
+			case tt.last.StartDate != nil && (key.StartDate == nil || !key.StartDate.Equal(*tt.last.StartDate)):
+				t.Errorf("Key() start date = %v, want %v", key.StartDate, *tt.last.StartDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a task of a sync is left alone
+const (
+	SyncConflictEdited    = "edited"    // changed in the project since it was imported
+	SyncConflictDeleted   = "deleted"   // deleted from the project since it was imported
+	SyncConflictUntracked = "untracked" // the stage has a task of that title that was not imported
+)
+
+// SyncTaskValues are the fields of a task a sync compares.
+type SyncTaskValues struct {
+	Title     string     `json:"title"`
+	Status    string     `json:"status"`
+	StartDate *time.Time `json:"startDate,omitempty"`
+	Deadline  *time.Time `json:"deadline,omitempty"`
+}
+
+// SyncedTask is a task a sync updated, with the fields that changed.
+type SyncedTask struct {
+	Task    Task     `json:"task"`
+	Changes []string `json:"changes"`
+}
+
+// SyncConflict is a task the document changed that a sync did not update.
+type SyncConflict struct {
+	TaskID   *uuid.UUID      `json:"taskId,omitempty"`
+	Stage    string          `json:"stage"`
+	Title    string          `json:"title"`
+	Reason   string          `json:"reason"`
+	Imported *SyncTaskValues `json:"imported,omitempty"`
+	Current  *SyncTaskValues `json:"current,omitempty"`
+	Parsed   SyncTaskValues  `json:"parsed"`
+}
+
+// SyncRemovedTask is a task of the previous import the document no longer
+// has. Syncs report these but keep them.
+type SyncRemovedTask struct {
+	TaskID *uuid.UUID `json:"taskId,omitempty"`
+	Stage  string     `json:"stage"`
+	Title  string     `json:"title"`
+}
+
+// SyncReport lists what SyncStages did.
+type SyncReport struct {
+	StagesCreated int               `json:"stagesCreated"`
+	Added         []Task            `json:"added"`
+	Updated       []SyncedTask      `json:"updated"`
+	Unchanged     int               `json:"unchanged"`
+	Conflicts     []SyncConflict    `json:"conflicts"`
+	Removed       []SyncRemovedTask `json:"removed"`
+}
+
+type importedTask struct {
+	taskID   *uuid.UUID
+	stage    string
+	imported SyncTaskValues
+	current  *SyncTaskValues
+}
+
+// SyncStages applies a new version of an imported document to a project in
+// one transaction. Tasks are matched to the previous import by stage and
+// title: new ones are added and changed dates or status are written, unless
+// the task was edited in the project since, which is reported as a conflict
+// instead. Tasks the document dropped are reported, never deleted. Access
+// and errors are those of ImportStages.
+func (r *Repository) SyncStages(ctx context.Context, requesterID, projectID uuid.UUID, stages []ImportStage) (report SyncReport, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var allowed bool
+	if allowed, err = managesProject(ctx, tx, requesterID, projectID); err != nil {
+		return SyncReport{}, err
+	}
+	if !allowed {
+		err = sql.ErrNoRows
+		return SyncReport{}, err
+	}
+
+	existing, nextOrder, err := stagesByTitle(ctx, tx, projectID)
+	if err != nil {
+		return SyncReport{}, err
+	}
+
+	var previous map[string]importedTask
+	if previous, err = importedTasks(ctx, tx, projectID); err != nil {
+		return SyncReport{}, err
+	}
+
+	report.Added = make([]Task, 0)
+	report.Updated = make([]SyncedTask, 0)
+	report.Conflicts = make([]SyncConflict, 0)
+	report.Removed = make([]SyncRemovedTask, 0)
+
+	for _, input := range stages {
+		var (
+			stage   Stage
+			created bool
+		)
+		if stage, created, err = importTargetStage(ctx, tx, projectID, input, existing, &nextOrder); err != nil {
+			return SyncReport{}, err
+		}
+		if created {
+			report.StagesCreated++
+		}
+
+		sourceStage := sourceKey(input.Title)
+		for _, task := range input.Tasks {
+			parsed := SyncTaskValues{
+				Title:     strings.TrimSpace(task.Title),
+				Status:    strings.TrimSpace(task.Status),
+				StartDate: task.StartDate,
+				Deadline:  task.Deadline,
+			}
+			if parsed.Status == "" {
+				parsed.Status = "todo"
+			}
+
+			key := sourceStage + "\x00" + sourceKey(task.Title)
+			prev, ok := previous[key]
+			delete(previous, key)
+
+			if !ok {
+				var untracked *uuid.UUID
+				if untracked, err = stageTaskByTitle(ctx, tx, stage.ID, parsed.Title); err != nil {
+					return SyncReport{}, err
+				}
+				if untracked != nil {
+					report.Conflicts = append(report.Conflicts, SyncConflict{
+						TaskID: untracked,
+						Stage:  stage.Title,
+						Title:  parsed.Title,
+						Reason: SyncConflictUntracked,
+						Parsed: parsed,
+					})
+					continue
+				}
+
+				var item Task
+				if item, err = insertImportTask(ctx, tx, projectID, stage.ID, sourceStage, task); err != nil {
+					return SyncReport{}, err
+				}
+				report.Added = append(report.Added, item)
+				continue
+			}
+
+			changes := syncChanges(prev.imported, parsed)
+			if len(changes) == 0 {
+				report.Unchanged++
+				continue
+			}
+
+			conflict := SyncConflict{
+				TaskID:   prev.taskID,
+				Stage:    stage.Title,
+				Title:    parsed.Title,
+				Imported: &prev.imported,
+				Current:  prev.current,
+				Parsed:   parsed,
+			}
+			switch {
+			case prev.taskID == nil:
+				conflict.Reason = SyncConflictDeleted
+				report.Conflicts = append(report.Conflicts, conflict)
+				continue
+			case len(syncChanges(prev.imported, *prev.current)) > 0:
+				conflict.Reason = SyncConflictEdited
+				report.Conflicts = append(report.Conflicts, conflict)
+				continue
+			}
+
+			var item Task
+			item, err = scanTask(tx.QueryRowContext(
+				ctx,
+				`WITH updated AS (
+				 	UPDATE stage_tasks
+				 	SET status = $2, start_date = $3, deadline = $4,
+				 	    deadline_reminded_at = CASE WHEN deadline IS DISTINCT FROM $4 THEN NULL ELSE deadline_reminded_at END,
+				 	    updated_at = now()
+				 	WHERE id = $1
+				 	RETURNING id, stage_id, title, status, start_date, deadline, order_index, blocks, updated_at
+				 )
+				 SELECT u.id, u.stage_id, s.project_id, u.title, u.status, u.start_date, u.deadline, u.order_index, u.blocks, u.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
+				 FROM updated u
+				 JOIN project_stages s ON s.id = u.stage_id`,
+				*prev.taskID,
+				parsed.Status,
+				nullTime(parsed.StartDate),
+				nullTime(parsed.Deadline),
+			))
+			if err != nil {
+				return SyncReport{}, err
+			}
+			if err = recordImportedTask(ctx, tx, projectID, item.ID, sourceStage, ImportTask{
+				Title:     parsed.Title,
+				Status:    parsed.Status,
+				StartDate: parsed.StartDate,
+				Deadline:  parsed.Deadline,
+			}); err != nil {
+				return SyncReport{}, err
+			}
+			report.Updated = append(report.Updated, SyncedTask{Task: item, Changes: changes})
+		}
+	}
+
+	for _, prev := range previous {
+		report.Removed = append(report.Removed, SyncRemovedTask{
+			TaskID: prev.taskID,
+			Stage:  prev.stage,
+			Title:  prev.imported.Title,
+		})
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return SyncReport{}, err
+	}
+
+	return report, nil
+}
+
+// importedTasks returns the previous import of a project by source stage and
+// title, each with the current values of its task if it still exists.
+func importedTasks(ctx context.Context, tx *sql.Tx, projectID uuid.UUID) (map[string]importedTask, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT i.source_stage, i.source_title, i.title, i.status, i.start_date, i.deadline,
+		        t.id, COALESCE(s.title, ''), COALESCE(t.title, ''), COALESCE(t.status, ''), t.start_date, t.deadline
+		 FROM project_imported_tasks i
+		 LEFT JOIN stage_tasks t ON t.id = i.task_id
+		 LEFT JOIN project_stages s ON s.id = t.stage_id
+		 WHERE i.project_id = $1`,
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make(map[string]importedTask)
+	for rows.Next() {
+		var (
+			task                          importedTask
+			sourceStage, sourceTitle      string
+			importedStart, importedDue    sql.NullTime
+			taskID                        uuid.NullUUID
+			stageTitle                    string
+			current                       SyncTaskValues
+			currentStart, currentDeadline sql.NullTime
+		)
+		if err := rows.Scan(
+			&sourceStage, &sourceTitle, &task.imported.Title, &task.imported.Status, &importedStart, &importedDue,
+			&taskID, &stageTitle, &current.Title, &current.Status, &currentStart, &currentDeadline,
+		); err != nil {
+			return nil, err
+		}
+		task.stage = sourceStage
+		task.imported.StartDate = timePtr(importedStart)
+		task.imported.Deadline = timePtr(importedDue)
+		if taskID.Valid {
+			id := taskID.UUID
+			task.taskID = &id
+			task.stage = stageTitle
+			current.StartDate = timePtr(currentStart)
+			current.Deadline = timePtr(currentDeadline)
+			task.current = &current
+		}
+		tasks[sourceStage+"\x00"+sourceTitle] = task
+	}
+	return tasks, rows.Err()
+}
+
+// stageTaskByTitle returns a task of the stage with the title, ignoring case.
+func stageTaskByTitle(ctx context.Context, tx *sql.Tx, stageID uuid.UUID, title string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT id
+		 FROM stage_tasks
+		 WHERE stage_id = $1 AND lower(btrim(title)) = lower($2)
+		 ORDER BY order_index ASC
+		 LIMIT 1`,
+		stageID,
+		title,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// syncChanges lists the fields that differ between two versions of a task.
+func syncChanges(from, to SyncTaskValues) []string {
+	changes := make([]string, 0, 4)
+	if sourceKey(from.Title) != sourceKey(to.Title) {
+		changes = append(changes, "title")
+	}
+	if from.Status != to.Status {
+		changes = append(changes, "status")
+	}
+	if !sameTime(from.StartDate, to.StartDate) {
+		changes = append(changes, "start_date")
+	}
+	if !sameTime(from.Deadline, to.Deadline) {
+		changes = append(changes, "deadline")
+	}
+	return changes
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func timePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := value.Time
+	return &t
+}
@@ -0,0 +1,173 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrStageNotInProject = errors.New("cannot create a task in a stage of another project")
+	ErrAssigneeNotMember = errors.New("cannot assign a task to someone outside the project")
+	ErrTaskTitleRequired = errors.New("cannot create a task without a title")
+)
+
+const taskMetaBlockID = "__task_meta__"
+
+// NewTask describes a task created with CreateTasks. Assignees are user
+// emails, stored the way the task editor stores them.
+type NewTask struct {
+	StageID   uuid.UUID
+	Title     string
+	Status    string
+	Deadline  *time.Time
+	Assignees []string
+}
+
+// CreateTasks creates several tasks in the stages of a project: either all of
+// them or none. Only the project's owners and managers may do this; others
+// get sql.ErrNoRows.
+func (r *Repository) CreateTasks(ctx context.Context, requesterID, projectID uuid.UUID, tasks []NewTask) (created []Task, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var allowed bool
+	if allowed, err = managesProject(ctx, tx, requesterID, projectID); err != nil {
+		return nil, err
+	}
+	if !allowed {
+		err = sql.ErrNoRows
+		return nil, err
+	}
+
+	created = make([]Task, 0, len(tasks))
+	for _, task := range tasks {
+		title := strings.TrimSpace(task.Title)
+		if title == "" {
+			err = ErrTaskTitleRequired
+			return nil, err
+		}
+		status := strings.TrimSpace(task.Status)
+		if status == "" {
+			status = "todo"
+		}
+
+		var inProject bool
+		if err = tx.QueryRowContext(
+			ctx,
+			`SELECT EXISTS (SELECT 1 FROM project_stages WHERE id = $1 AND project_id = $2)`,
+			task.StageID,
+			projectID,
+		).Scan(&inProject); err != nil {
+			return nil, err
+		}
+		if !inProject {
+			err = ErrStageNotInProject
+			return nil, err
+		}
+
+		assignees := make([]string, 0, len(task.Assignees))
+		for value := range normalizeAssigneeValues(task.Assignees) {
+			var member bool
+			if member, err = isProjectMemberEmail(ctx, tx, projectID, value); err != nil {
+				return nil, err
+			}
+			if !member {
+				err = ErrAssigneeNotMember
+				return nil, err
+			}
+			assignees = append(assignees, value)
+		}
+
+		var blocks []byte
+		if blocks, err = taskMetaBlocks(assignees); err != nil {
+			return nil, err
+		}
+
+		var item Task
+		item, err = scanTask(tx.QueryRowContext(
+			ctx,
+			`WITH inserted AS (
+			 	INSERT INTO stage_tasks (stage_id, title, status, deadline, order_index, blocks)
+			 	SELECT $1, $2, $3, $4,
+			 	       COALESCE((SELECT MAX(order_index) + 1 FROM stage_tasks WHERE stage_id = $1), 0),
+			 	       $5::jsonb
+			 	RETURNING id, stage_id, title, status, start_date, deadline, order_index, blocks, updated_at
+			 )
+			 SELECT i.id, i.stage_id, s.project_id, i.title, i.status, i.start_date, i.deadline, i.order_index, i.blocks, i.updated_at, (SELECT timezone FROM projects WHERE id = s.project_id)
+			 FROM inserted i
+			 JOIN project_stages s ON s.id = i.stage_id`,
+			task.StageID,
+			title,
+			status,
+			nullTime(task.Deadline),
+			blocks,
+		))
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, item)
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// isProjectMemberEmail reports whether the lowercased email belongs to the
+// project's owner or one of its members.
+func isProjectMemberEmail(ctx context.Context, tx *sql.Tx, projectID uuid.UUID, email string) (bool, error) {
+	var member bool
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1
+		 	FROM users u
+		 	JOIN projects p ON p.id = $1
+		 	WHERE LOWER(u.email) = $2
+		 	  AND (
+		 	  	p.owner_id = u.id
+		 	  	OR EXISTS (
+		 	  		SELECT 1 FROM project_members pm
+		 	  		WHERE pm.project_id = p.id AND pm.user_id = u.id
+		 	  	)
+		 	  )
+		 )`,
+		projectID,
+		email,
+	).Scan(&member)
+	return member, err
+}
+
+// taskMetaBlocks returns the blocks of a new task holding only the editor's
+// meta block with its assignees.
+func taskMetaBlocks(assignees []string) ([]byte, error) {
+	if len(assignees) == 0 {
+		return []byte("[]"), nil
+	}
+
+	payload, err := json.Marshal(taskMetaPayload{Assignees: assignees})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal([]map[string]string{{
+		"id":      taskMetaBlockID,
+		"type":    "text",
+		"content": string(payload),
+	}})
+}
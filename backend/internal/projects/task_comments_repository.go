@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"strings"
+	"time"
+
+	"tm-platform-backend/internal/pagination"
 
 	"github.com/google/uuid"
 )
@@ -197,37 +200,66 @@ func (r *Repository) CreateTaskComment(ctx context.Context, requesterID, taskID
 	return scanTaskCommentResponse(row)
 }
 
-func (r *Repository) ListTaskComments(ctx context.Context, requesterID, taskID uuid.UUID) ([]TaskCommentResponse, error) {
+// taskCommentKey is the sort key of ListTaskComments
+type taskCommentKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func (r *Repository) ListTaskComments(ctx context.Context, requesterID, taskID uuid.UUID, page pagination.Page) (pagination.List[TaskCommentResponse], error) {
+	var key taskCommentKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[TaskCommentResponse]{}, err
+	}
 	if err := r.ensureTaskMember(ctx, requesterID, taskID); err != nil {
-		return nil, err
+		return pagination.List[TaskCommentResponse]{}, err
 	}
 
-	rows, err := r.db.QueryContext(
-		ctx,
-		`SELECT tc.id, tc.task_id, s.project_id, tc.user_id, tc.message, tc.created_at, u.id, u.email
+	query := `SELECT tc.id, tc.task_id, s.project_id, tc.user_id, tc.message, tc.created_at, u.id, u.email, COUNT(*) OVER ()
 		 FROM task_comments tc
 		 JOIN stage_tasks t ON t.id = tc.task_id
 		 JOIN project_stages s ON s.id = t.stage_id
 		 JOIN users u ON u.id = tc.user_id
-		 WHERE tc.task_id = $1
-		 ORDER BY tc.created_at ASC, tc.id ASC`,
-		taskID,
-	)
+		 WHERE tc.task_id = $1`
+	args := []any{taskID, page.Fetch()}
+	if after {
+		query += ` AND (tc.created_at, tc.id) > ($3, $4)`
+		args = append(args, key.CreatedAt, key.ID)
+	}
+	query += ` ORDER BY tc.created_at ASC, tc.id ASC LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return pagination.List[TaskCommentResponse]{}, err
 	}
 	defer rows.Close()
 
-	comments := make([]TaskCommentResponse, 0)
+	var (
+		comments []TaskCommentResponse
+		total    int
+	)
 	for rows.Next() {
-		comment, scanErr := scanTaskCommentResponse(rows)
+		comment, scanErr := scanTaskCommentResponse(pagination.Scanner{Rows: rows, Dest: &total})
 		if scanErr != nil {
-			return nil, scanErr
+			return pagination.List[TaskCommentResponse]{}, scanErr
 		}
 		comments = append(comments, comment)
 	}
+	if err := rows.Err(); err != nil {
+		return pagination.List[TaskCommentResponse]{}, err
+	}
 
-	return comments, rows.Err()
+	list, err := pagination.Cut(comments, page, func(i int) any {
+		return taskCommentKey{CreatedAt: comments[i].CreatedAt, ID: comments[i].ID}
+	})
+	if err != nil {
+		return pagination.List[TaskCommentResponse]{}, err
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
 }
 
 func (r *Repository) ListTaskHistory(ctx context.Context, requesterID, taskID uuid.UUID) ([]DelayReportResponse, error) {
@@ -0,0 +1,158 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// dateOnly is the layout of the bare dates clients send for start dates and
+// deadlines.
+const dateOnly = "2006-01-02"
+
+// WithDefaultTimezone sets the zone of projects whose owner chose none, UTC
+// when unset.
+func (r *Repository) WithDefaultTimezone(loc *time.Location) *Repository {
+	r.timezone = loc
+	return r
+}
+
+func (r *Repository) defaultLocation() *time.Location {
+	if r.timezone == nil {
+		return time.UTC
+	}
+	return r.timezone
+}
+
+// loadLocation returns the zone named name. Names are validated on the way
+// in, so an unknown one means the zone database of the host lacks it; the
+// dates are then shown in UTC rather than failing the read.
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("load time zone %q failed: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// Location returns the zone of the project.
+func (p Project) Location() *time.Location {
+	return loadLocation(p.Timezone)
+}
+
+func timeIn(value sql.NullTime, loc *time.Location) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	local := value.Time.In(loc)
+	return &local
+}
+
+// UserLocation returns the zone the user chose in their profile, or the
+// default one.
+func (r *Repository) UserLocation(ctx context.Context, userID uuid.UUID) (*time.Location, error) {
+	var name sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT timezone FROM users WHERE id = $1`, userID).Scan(&name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if !name.Valid || name.String == "" {
+		return r.defaultLocation(), nil
+	}
+	return loadLocation(name.String), nil
+}
+
+// projectTimezone returns the zone a new project of ownerID gets: the
+// requested one, else the owner's.
+func (r *Repository) projectTimezone(ctx context.Context, ownerID uuid.UUID, requested string) (string, error) {
+	if requested != "" {
+		return requested, nil
+	}
+	loc, err := r.UserLocation(ctx, ownerID)
+	if err != nil {
+		return "", err
+	}
+	return loc.String(), nil
+}
+
+// ProjectLocation returns the zone of the project, UTC when it is not found.
+func (r *Repository) ProjectLocation(ctx context.Context, projectID uuid.UUID) (*time.Location, error) {
+	return r.location(ctx, `SELECT timezone FROM projects WHERE id = $1`, projectID)
+}
+
+// StageLocation returns the zone of the project of the stage.
+func (r *Repository) StageLocation(ctx context.Context, stageID uuid.UUID) (*time.Location, error) {
+	return r.location(
+		ctx,
+		`SELECT p.timezone
+		 FROM project_stages s
+		 JOIN projects p ON p.id = s.project_id
+		 WHERE s.id = $1`,
+		stageID,
+	)
+}
+
+func (r *Repository) location(ctx context.Context, query string, id uuid.UUID) (*time.Location, error) {
+	var name string
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(name), nil
+}
+
+// StartOfDay returns midnight of the calendar day of day in loc.
+func StartOfDay(day time.Time, loc *time.Location) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, 0, 0, 0, 0, loc)
+}
+
+// EndOfDay returns the last second of the calendar day of day in loc, the
+// moment a deadline given as a bare date runs out.
+func EndOfDay(day time.Time, loc *time.Location) time.Time {
+	return StartOfDay(day, loc).AddDate(0, 0, 1).Add(-time.Second)
+}
+
+// ParseDate parses an RFC 3339 timestamp, or a bare date read as the start
+// of that day in loc. Blank values are nil.
+func ParseDate(value string, loc *time.Location) (*time.Time, error) {
+	return parseDate(value, loc, StartOfDay)
+}
+
+// ParseDeadline is ParseDate for deadlines: a bare date runs until the end
+// of that day in loc, so a task due today is not overdue before the day is
+// over wherever its members are.
+func ParseDeadline(value string, loc *time.Location) (*time.Time, error) {
+	return parseDate(value, loc, EndOfDay)
+}
+
+func parseDate(value string, loc *time.Location, day func(time.Time, *time.Location) time.Time) (*time.Time, error) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, trimmed); err == nil {
+		local := parsed.In(loc)
+		return &local, nil
+	}
+
+	if parsed, err := time.Parse(dateOnly, trimmed); err == nil {
+		local := day(parsed, loc)
+		return &local, nil
+	}
+
+	return nil, errors.New("invalid date")
+}
@@ -0,0 +1,138 @@
+package quotas
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTopLimit = 20
+	maxTopLimit     = 200
+)
+
+// AdminFunc reports whether a user may see the usage of the whole
+// deployment.
+type AdminFunc func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+type Handler struct {
+	repo    *Repository
+	isAdmin AdminFunc
+}
+
+func NewHandler(repo *Repository, isAdmin AdminFunc) *Handler {
+	return &Handler{repo: repo, isAdmin: isAdmin}
+}
+
+type myUsageResponse struct {
+	Usage
+	Projects []ProjectUsage `json:"projects"`
+}
+
+type adminUsageResponse struct {
+	Total    Usage          `json:"total"`
+	Projects []ProjectUsage `json:"projects"`
+	Users    []UserUsage    `json:"users"`
+}
+
+// MyUsage returns the requester's usage and quota together with the usage of
+// the projects they own.
+func (h *Handler) MyUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	usage, err := h.repo.GetUserUsage(r.Context(), userID)
+	if err != nil {
+		log.Printf("get storage usage failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+
+	projects, err := h.repo.ListOwnedProjectUsage(r.Context(), userID)
+	if err != nil {
+		log.Printf("list project storage usage failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, myUsageResponse{Usage: usage, Projects: projects})
+}
+
+// AdminUsage lists the projects and users that consume the most space.
+func (h *Handler) AdminUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	allowed, err := h.isAdmin(r.Context(), userID)
+	if err != nil {
+		log.Printf("check storage admin failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+	if !allowed {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return
+	}
+
+	limit := defaultTopLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = min(parsed, maxTopLimit)
+		}
+	}
+
+	total, err := h.repo.TotalUsage(r.Context())
+	if err != nil {
+		log.Printf("total storage usage failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+	projects, err := h.repo.ListTopProjects(r.Context(), limit)
+	if err != nil {
+		log.Printf("top project storage usage failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+	users, err := h.repo.ListTopUsers(r.Context(), limit)
+	if err != nil {
+		log.Printf("top user storage usage failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adminUsageResponse{Total: total, Projects: projects, Users: users})
+}
+
+func userIDFromRequest(r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,361 @@
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"tm-platform-backend/internal/db"
+
+	"github.com/google/uuid"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// reservationTTL bounds how long a reservation holds quota; it outlives any
+// upload, so only those of crashed requests expire.
+const reservationTTL = 15 * time.Minute
+
+// Limits holds the configured quotas in bytes. Zero means unlimited.
+type Limits struct {
+	UserBytes    int64
+	ProjectBytes int64
+}
+
+type Usage struct {
+	UsedBytes   int64  `json:"used_bytes"`
+	QuotaBytes  *int64 `json:"quota_bytes"`
+	ObjectCount int    `json:"object_count"`
+}
+
+type ProjectUsage struct {
+	ProjectID   uuid.UUID `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	Usage
+}
+
+type UserUsage struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	FullName string    `json:"full_name"`
+	Usage
+}
+
+// Repository tracks how much storage every uploaded object takes and who it
+// is charged to, and enforces the configured limits.
+type Repository struct {
+	db      *sql.DB
+	replica *sql.DB
+	limits  Limits
+}
+
+func NewRepository(db *sql.DB, limits Limits) *Repository {
+	return &Repository{db: db, limits: limits}
+}
+
+// WithReplica sends the heavy reads of the repository to the read replica
+// conn, see db.Reader.
+func (r *Repository) WithReplica(conn *sql.DB) *Repository {
+	r.replica = conn
+	return r
+}
+
+func (r *Repository) reader(ctx context.Context) *sql.DB {
+	return db.Reader(ctx, r.db, r.replica)
+}
+
+// RecordObject charges a freshly stored object to its uploader and,
+// optionally, to a project. It returns the object's id.
+func (r *Repository) RecordObject(ctx context.Context, key string, userID uuid.UUID, projectID *uuid.UUID, size int64) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO storage_objects (object_key, user_id, project_id, size)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (object_key)
+		 DO UPDATE SET user_id = EXCLUDED.user_id, project_id = COALESCE(EXCLUDED.project_id, storage_objects.project_id), size = EXCLUDED.size
+		 RETURNING id`,
+		key,
+		userID,
+		projectID,
+		size,
+	).Scan(&id)
+	return id, err
+}
+
+// Reserve holds size bytes of the user's quota, and of the project's when
+// given, for an upload about to be stored, failing with ErrQuotaExceeded
+// when they do not fit. The user and project rows are locked while usage
+// is summed, so concurrent uploads see each other's reservations. The
+// caller releases the reservation once the object is recorded or the
+// upload failed. Without quotas nothing is reserved and the id is uuid.Nil.
+func (r *Repository) Reserve(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, size int64) (id uuid.UUID, err error) {
+	if r.limits.UserBytes <= 0 && (projectID == nil || r.limits.ProjectBytes <= 0) {
+		return uuid.Nil, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if r.limits.UserBytes > 0 {
+		if _, err = tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return uuid.Nil, err
+		}
+		if err = r.checkUser(ctx, tx, userID, size); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if projectID != nil {
+		if _, err = tx.ExecContext(ctx, `SELECT 1 FROM projects WHERE id = $1 FOR UPDATE`, *projectID); err != nil {
+			return uuid.Nil, err
+		}
+		if err = r.checkProject(ctx, tx, *projectID, size); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO storage_reservations (user_id, project_id, size, expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id`,
+		userID,
+		projectID,
+		size,
+		time.Now().Add(reservationTTL),
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// Release gives back the quota held by a reservation, dropping expired
+// reservations along with it. Releasing uuid.Nil does nothing.
+func (r *Repository) Release(ctx context.Context, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM storage_reservations WHERE id = $1 OR expires_at <= now()`, id)
+	return err
+}
+
+// checkUser verifies that size more bytes keep the user within quota.
+// Active resumable uploads and reservations count towards it.
+func (r *Repository) checkUser(ctx context.Context, q queryRower, userID uuid.UUID, size int64) error {
+	var used int64
+	if err := q.QueryRowContext(
+		ctx,
+		`SELECT
+		 	COALESCE((SELECT SUM(size) FROM storage_objects WHERE user_id = $1), 0)
+		 	+ COALESCE((
+		 		SELECT SUM(total_size) FROM upload_sessions
+		 		WHERE user_id = $1 AND status = 'active' AND expires_at > now()
+		 	), 0)
+		 	+ COALESCE((
+		 		SELECT SUM(size) FROM storage_reservations
+		 		WHERE user_id = $1 AND expires_at > now()
+		 	), 0)`,
+		userID,
+	).Scan(&used); err != nil {
+		return err
+	}
+	if used+size > r.limits.UserBytes {
+		return fmt.Errorf("%w: user limit of %d bytes", ErrQuotaExceeded, r.limits.UserBytes)
+	}
+	return nil
+}
+
+// AttachToProject charges an already stored object to a project, failing
+// when that would exceed the project quota. Unknown keys are ignored.
+func (r *Repository) AttachToProject(ctx context.Context, key string, projectID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Locking the project serializes concurrent attachments against its quota.
+	if _, err = tx.ExecContext(ctx, `SELECT 1 FROM projects WHERE id = $1 FOR UPDATE`, projectID); err != nil {
+		return err
+	}
+
+	var (
+		size           int64
+		currentProject uuid.NullUUID
+	)
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT size, project_id FROM storage_objects WHERE object_key = $1 FOR UPDATE`,
+		key,
+	).Scan(&size, &currentProject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if currentProject.Valid && currentProject.UUID == projectID {
+		err = tx.Commit()
+		return err
+	}
+
+	if err = r.checkProject(ctx, tx, projectID, size); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE storage_objects SET project_id = $2 WHERE object_key = $1`, key, projectID); err != nil {
+		return err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		err = commitErr
+		return err
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *Repository) checkProject(ctx context.Context, q queryRower, projectID uuid.UUID, size int64) error {
+	if r.limits.ProjectBytes <= 0 {
+		return nil
+	}
+
+	var used int64
+	if err := q.QueryRowContext(
+		ctx,
+		`SELECT
+		 	COALESCE((SELECT SUM(size) FROM storage_objects WHERE project_id = $1), 0)
+		 	+ COALESCE((
+		 		SELECT SUM(size) FROM storage_reservations
+		 		WHERE project_id = $1 AND expires_at > now()
+		 	), 0)`,
+		projectID,
+	).Scan(&used); err != nil {
+		return err
+	}
+	if used+size > r.limits.ProjectBytes {
+		return fmt.Errorf("%w: project limit of %d bytes", ErrQuotaExceeded, r.limits.ProjectBytes)
+	}
+	return nil
+}
+
+func (r *Repository) GetUserUsage(ctx context.Context, userID uuid.UUID) (Usage, error) {
+	usage := Usage{QuotaBytes: quotaPtr(r.limits.UserBytes)}
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(SUM(size), 0), COUNT(*) FROM storage_objects WHERE user_id = $1`,
+		userID,
+	).Scan(&usage.UsedBytes, &usage.ObjectCount)
+	return usage, err
+}
+
+// ListOwnedProjectUsage returns usage of the projects the user owns.
+func (r *Repository) ListOwnedProjectUsage(ctx context.Context, ownerID uuid.UUID) ([]ProjectUsage, error) {
+	return r.listProjectUsage(ctx, `WHERE p.owner_id = $1`, ``, ownerID)
+}
+
+// ListTopProjects returns the projects consuming the most storage.
+func (r *Repository) ListTopProjects(ctx context.Context, limit int) ([]ProjectUsage, error) {
+	return r.listProjectUsage(ctx, `WHERE so.project_id IS NOT NULL`, `LIMIT $1`, limit)
+}
+
+func (r *Repository) listProjectUsage(ctx context.Context, where, limitClause string, args ...any) ([]ProjectUsage, error) {
+	query := `SELECT p.id, p.title, COALESCE(SUM(so.size), 0), COUNT(so.object_key)
+		 FROM projects p
+		 LEFT JOIN storage_objects so ON so.project_id = p.id
+		 ` + where + `
+		 GROUP BY p.id, p.title
+		 ORDER BY COALESCE(SUM(so.size), 0) DESC, p.title
+		 ` + limitClause
+
+	rows, err := r.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]ProjectUsage, 0)
+	for rows.Next() {
+		item := ProjectUsage{Usage: Usage{QuotaBytes: quotaPtr(r.limits.ProjectBytes)}}
+		if err := rows.Scan(&item.ProjectID, &item.ProjectName, &item.UsedBytes, &item.ObjectCount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ListTopUsers returns the users consuming the most storage.
+func (r *Repository) ListTopUsers(ctx context.Context, limit int) ([]UserUsage, error) {
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT u.id, u.email, COALESCE(u.full_name, ''), SUM(so.size), COUNT(*)
+		 FROM storage_objects so
+		 JOIN users u ON u.id = so.user_id
+		 GROUP BY u.id, u.email, u.full_name
+		 ORDER BY SUM(so.size) DESC, u.email
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]UserUsage, 0)
+	for rows.Next() {
+		item := UserUsage{Usage: Usage{QuotaBytes: quotaPtr(r.limits.UserBytes)}}
+		if err := rows.Scan(&item.UserID, &item.Email, &item.FullName, &item.UsedBytes, &item.ObjectCount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// TotalUsage sums every tracked object.
+func (r *Repository) TotalUsage(ctx context.Context) (Usage, error) {
+	var usage Usage
+	err := r.reader(ctx).QueryRowContext(
+		ctx,
+		`SELECT COALESCE(SUM(size), 0), COUNT(*) FROM storage_objects`,
+	).Scan(&usage.UsedBytes, &usage.ObjectCount)
+	return usage, err
+}
+
+func quotaPtr(limit int64) *int64 {
+	if limit <= 0 {
+		return nil
+	}
+	return &limit
+}
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// structField is a member of a struct as encoding/json sees it.
+type structField struct {
+	name string
+	typ  reflect.Type
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]structField, by folded name
+
+// normalize renames the object keys of raw to the json tags of t, the type
+// raw will be decoded into. Members of json.RawMessage and interface fields
+// are opaque documents and are left as they are.
+func normalize(raw any, t reflect.Type) any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		return raw
+	}
+
+	switch value := raw.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			return normalizeObject(value, structFields(t))
+		case reflect.Map:
+			for key, member := range value {
+				value[key] = normalize(member, t.Elem())
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range value {
+				value[i] = normalize(item, t.Elem())
+			}
+		}
+	}
+	return raw
+}
+
+func normalizeObject(object map[string]any, fields map[string]structField) map[string]any {
+	result := make(map[string]any, len(object))
+	for key, member := range object {
+		field, ok := fields[fold(key)]
+		if !ok {
+			result[key] = member
+			continue
+		}
+		if key != field.name {
+			if _, exact := object[field.name]; exact {
+				continue // the canonical spelling wins
+			}
+		}
+		result[field.name] = normalize(member, field.typ)
+	}
+	return result
+}
+
+// structFields returns the members of the struct t by folded name, with
+// the members of embedded structs promoted like encoding/json does.
+func structFields(t reflect.Type) map[string]structField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]structField)
+	}
+
+	fields := make(map[string]structField)
+	collectFields(t, fields)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, fields map[string]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectFields(embedded, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, taken := fields[fold(name)]; !taken {
+			fields[fold(name)] = structField{name: name, typ: field.Type}
+		}
+	}
+}
+
+// jsonName returns the member name in the json tag of field, "" when the
+// tag leaves it to the field name and "-" when the field is skipped.
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "-"
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
@@ -0,0 +1,192 @@
+// Package request decodes and validates JSON request bodies.
+//
+// Clients send the same member as startDate or start_date, so Decode matches
+// object keys to json tags regardless of case style and handlers declare
+// each field once, under its canonical name. When a body carries both
+// spellings, the one matching the tag wins. Fields are then checked against
+// their validate tags:
+//
+//	Title    string   `json:"title" validate:"required,max=200"`
+//	ParentID *string  `json:"parent_id" validate:"uuid"`
+//	UserIDs  []string `json:"user_ids" validate:"max=50,uuid"`
+//
+// Rules are required, min=N and max=N (length of strings and slices, value
+// of numbers), oneof=a b c, uuid, date (YYYY-MM-DD or RFC 3339), timezone
+// (an IANA name such as Asia/Almaty) and email.
+// Format rules apply to each element of string slices and skip blank
+// strings; every rule but required skips nil pointers and absent values.
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"tm-platform-backend/internal/problem"
+)
+
+// ErrEmptyBody is returned when the request has no body. Handlers whose
+// body is optional check for it with errors.Is.
+var ErrEmptyBody = errors.New("request body is empty")
+
+// ErrBodyTooLarge is returned when the body is longer than MaxBodyBytes.
+var ErrBodyTooLarge = errors.New("request body is too large")
+
+// MaxBodyBytes bounds the JSON bodies Decode reads. It leaves room for a
+// parsed project structure sent back for import.
+const MaxBodyBytes = 10 << 20
+
+// FieldError is a member of the request that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every member of the request that failed validation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return "invalid payload"
+	}
+	return e.Fields[0].Message
+}
+
+// Fields is the set of top-level members present in a body, by json tag, so
+// partial updates can tell a null member from an absent one.
+type Fields map[string]bool
+
+// Has reports whether the body carried the member name.
+func (f Fields) Has(name string) bool {
+	return f[name]
+}
+
+// Decode reads the JSON body of r into dst, a pointer to a struct, and
+// validates it.
+func Decode(r *http.Request, dst any) error {
+	_, err := DecodeFields(r, dst)
+	return err
+}
+
+// DecodeFields is Decode that also returns the members the body carried.
+func DecodeFields(r *http.Request, dst any) (Fields, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, err
+	}
+	if len(body) > MaxBodyBytes {
+		return nil, ErrBodyTooLarge
+	}
+	return UnmarshalFields(body, dst)
+}
+
+// Unmarshal decodes and validates a JSON document already read, such as one
+// item of a batch.
+func Unmarshal(data []byte, dst any) error {
+	_, err := UnmarshalFields(data, dst)
+	return err
+}
+
+// UnmarshalFields is Unmarshal that also returns the members data carried.
+func UnmarshalFields(data []byte, dst any) (Fields, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyBody
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw any
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	target := reflect.TypeOf(dst)
+	if target == nil || target.Kind() != reflect.Ptr {
+		return nil, errors.New("request: Decode needs a pointer")
+	}
+	raw = normalize(raw, target.Elem())
+
+	fields := make(Fields)
+	if object, ok := raw.(map[string]any); ok {
+		for key := range object {
+			fields[key] = true
+		}
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(normalized, dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return nil, &ValidationError{Fields: []FieldError{{
+				Field:   typeErr.Field,
+				Code:    "type",
+				Message: typeErr.Field + " must be " + article(typeErr.Type.Kind()),
+			}}}
+		}
+		return nil, err
+	}
+
+	var errs []FieldError
+	validate(reflect.ValueOf(dst).Elem(), "", &errs)
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+	return fields, nil
+}
+
+// WriteError answers a failed Decode: validation errors as 400
+// validation_failed with the failed fields in an errors member, a body over
+// MaxBodyBytes as 413 payload_too_large, anything else as 400
+// invalid_payload.
+func WriteError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		problem.WriteWith(w, http.StatusBadRequest, problem.CodeValidationFailed, validationErr.Error(), map[string]any{
+			"errors": validationErr.Fields,
+		})
+		return
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "request body is too large")
+		return
+	}
+	problem.Write(w, http.StatusBadRequest, problem.CodeInvalidPayload, "invalid payload")
+}
+
+func article(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// fold reduces a member name to the form shared by its spellings, e.g.
+// startDate, start_date and StartDate all fold to startdate.
+func fold(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
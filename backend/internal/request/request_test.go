@@ -0,0 +1,158 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type taskRequest struct {
+	Title     string  `json:"title" validate:"required,max=10"`
+	StartDate *string `json:"start_date" validate:"date"`
+	Priority  string  `json:"priority" validate:"oneof=low high"`
+}
+
+func newRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+}
+
+func TestDecodeFieldsUnknownFields(t *testing.T) {
+	var req taskRequest
+	fields, err := DecodeFields(newRequest(`{"title":"Кровля","startDate":"2025-03-01","assignee":"ivanov"}`), &req)
+	if err != nil {
+		t.Fatalf("DecodeFields() error = %v", err)
+	}
+	if req.Title != "Кровля" || req.StartDate == nil || *req.StartDate != "2025-03-01" {
+		t.Errorf("DecodeFields() = %+v, want the known fields set", req)
+	}
+	if !fields.Has("start_date") {
+		t.Errorf("Fields = %v, want start_date under its tag", fields)
+	}
+	if !fields.Has("assignee") {
+		t.Errorf("Fields = %v, want the unknown member kept", fields)
+	}
+}
+
+func TestDecodeBodies(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"empty", "", ErrEmptyBody},
+		{"whitespace only", " \n\t", ErrEmptyBody},
+		{"at the limit", `{"title":"a"}` + strings.Repeat(" ", MaxBodyBytes-len(`{"title":"a"}`)), nil},
+		{"over the limit", `{"title":"a"}` + strings.Repeat(" ", MaxBodyBytes), ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req taskRequest
+			if err := Decode(newRequest(tt.body), &req); !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyOverMaxBytesReader(t *testing.T) {
+	r := newRequest(`{"title":"Кровля"}`)
+	r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 4)
+
+	var req taskRequest
+	if err := Decode(r, &req); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Decode() error = %v, want %v", err, ErrBodyTooLarge)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantDetail string
+		wantFields []FieldError
+	}{
+		{
+			name:       "validation failed",
+			body:       `{"title":"","start_date":"01.03.2025","priority":"urgent"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "validation_failed",
+			wantDetail: "title is required",
+			wantFields: []FieldError{
+				{Field: "title", Code: "required", Message: "title is required"},
+				{Field: "start_date", Code: "date", Message: "start_date must be a date as YYYY-MM-DD or RFC 3339"},
+				{Field: "priority", Code: "oneof", Message: "priority must be one of low, high"},
+			},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"title":5}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "validation_failed",
+			wantDetail: "title must be a string",
+			wantFields: []FieldError{{Field: "title", Code: "type", Message: "title must be a string"}},
+		},
+		{
+			name:       "malformed JSON",
+			body:       `{"title":`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_payload",
+			wantDetail: "invalid payload",
+		},
+		{
+			name:       "empty body",
+			body:       "",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_payload",
+			wantDetail: "invalid payload",
+		},
+		{
+			name:       "body too large",
+			body:       strings.Repeat(" ", MaxBodyBytes+1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "payload_too_large",
+			wantDetail: "request body is too large",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req taskRequest
+			err := Decode(newRequest(tt.body), &req)
+			if err == nil {
+				t.Fatal("Decode() error = nil")
+			}
+
+			rec := httptest.NewRecorder()
+			WriteError(rec, err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var got struct {
+				Status int          `json:"status"`
+				Code   string       `json:"code"`
+				Detail string       `json:"detail"`
+				Errors []FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("problem body %q: %v", rec.Body.String(), err)
+			}
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode || got.Detail != tt.wantDetail {
+				t.Errorf("problem = %d %s %q, want %d %s %q",
+					got.Status, got.Code, got.Detail, tt.wantStatus, tt.wantCode, tt.wantDetail)
+			}
+			if len(got.Errors) != len(tt.wantFields) {
+				t.Fatalf("errors = %+v, want %+v", got.Errors, tt.wantFields)
+			}
+			for i := range got.Errors {
+				if got.Errors[i] != tt.wantFields[i] {
+					t.Errorf("errors[%d] = %+v, want %+v", i, got.Errors[i], tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
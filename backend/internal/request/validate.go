@@ -0,0 +1,215 @@
+package request
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// validate checks the fields of the struct v against their validate tags,
+// descending into nested structs, and appends the failures to errs.
+func validate(v reflect.Value, path string, errs *[]FieldError) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		validateStruct(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validate(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func validateStruct(v reflect.Value, path string, errs *[]FieldError) {
+	t := v.Type()
+	if t == reflect.TypeOf(time.Time{}) {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			validate(v.Field(i), path, errs)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+
+		value := v.Field(i)
+		if tag := field.Tag.Get("validate"); tag != "" {
+			if failure, ok := checkRules(value, strings.Split(tag, ",")); !ok {
+				failure.Field = name
+				failure.Message = name + " " + failure.Message
+				*errs = append(*errs, failure)
+				continue
+			}
+		}
+		if field.Type != rawMessageType {
+			validate(value, name, errs)
+		}
+	}
+}
+
+// checkRules runs the rules against value and returns the first failure
+func checkRules(value reflect.Value, rules []string) (FieldError, bool) {
+	present := true
+	if value.Kind() == reflect.Ptr {
+		present = !value.IsNil()
+		if present {
+			value = value.Elem()
+		}
+	} else {
+		present = !value.IsZero()
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "required" {
+			if !present || blank(value) {
+				return FieldError{Code: "required", Message: "is required"}, false
+			}
+			continue
+		}
+		if !present {
+			continue
+		}
+
+		switch name {
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic("request: bad validate rule " + rule)
+			}
+			size, unit := measure(value)
+			if name == "min" && size < limit {
+				return FieldError{Code: "min", Message: "must be at least " + arg + unit}, false
+			}
+			if name == "max" && size > limit {
+				return FieldError{Code: "max", Message: "must be at most " + arg + unit}, false
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			if ok := eachString(value, func(s string) bool { return contains(options, s) }); !ok {
+				return FieldError{Code: "oneof", Message: "must be one of " + strings.Join(options, ", ")}, false
+			}
+		case "uuid":
+			if ok := eachString(value, func(s string) bool { _, err := uuid.Parse(s); return err == nil }); !ok {
+				return FieldError{Code: "uuid", Message: "must be a UUID"}, false
+			}
+		case "date":
+			if ok := eachString(value, validDate); !ok {
+				return FieldError{Code: "date", Message: "must be a date as YYYY-MM-DD or RFC 3339"}, false
+			}
+		case "timezone":
+			if ok := eachString(value, validTimezone); !ok {
+				return FieldError{Code: "timezone", Message: "must be an IANA time zone such as Asia/Almaty"}, false
+			}
+		case "email":
+			if ok := eachString(value, func(s string) bool { _, err := mail.ParseAddress(s); return err == nil }); !ok {
+				return FieldError{Code: "email", Message: "must be an email address"}, false
+			}
+		default:
+			panic("request: unknown validate rule " + rule)
+		}
+	}
+	return FieldError{}, true
+}
+
+// blank reports whether value carries nothing: a blank string or an empty
+// slice or map
+func blank(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return false
+}
+
+// measure returns what min and max compare: the length of strings and
+// slices, the value of numbers
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(strings.TrimSpace(value.String()))), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	panic("request: min and max need a string, slice or number, not " + value.Kind().String())
+}
+
+// eachString runs check on the string value or on each element of a string
+// slice, skipping blank strings
+func eachString(value reflect.Value, check func(string) bool) bool {
+	switch value.Kind() {
+	case reflect.String:
+		trimmed := strings.TrimSpace(value.String())
+		return trimmed == "" || check(trimmed)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if !eachString(value.Index(i), check) {
+				return false
+			}
+		}
+		return true
+	case reflect.Ptr:
+		return value.IsNil() || eachString(value.Elem(), check)
+	}
+	panic("request: format rules need a string or string slice, not " + value.Kind().String())
+}
+
+func validDate(value string) bool {
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return true
+	}
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+// validTimezone reports whether value names an IANA time zone. Local is
+// refused: it is whatever zone the server runs in.
+func validTimezone(value string) bool {
+	if value == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(value)
+	return err == nil
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
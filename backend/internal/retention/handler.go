@@ -0,0 +1,158 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/jobs"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminFunc reports whether a user may configure retention.
+type AdminFunc func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+type Handler struct {
+	repo    *Repository
+	purger  *Purger
+	queue   *jobs.Queue
+	isAdmin AdminFunc
+}
+
+func NewHandler(repo *Repository, purger *Purger, queue *jobs.Queue, isAdmin AdminFunc) *Handler {
+	return &Handler{repo: repo, purger: purger, queue: queue, isAdmin: isAdmin}
+}
+
+// updateRuleReq changes a rule; keepDays may be null for the trash only.
+type updateRuleReq struct {
+	Enabled  *bool `json:"enabled"`
+	KeepDays *int  `json:"keepDays" validate:"omitempty,min=1,max=36500"`
+}
+
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+
+	rules, err := h.repo.List(r.Context())
+	if err != nil {
+		log.Printf("list retention rules failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to list retention rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.repo.Get(r.Context(), Kind(strings.TrimSpace(chi.URLParam(r, "kind"))))
+	if err != nil {
+		writeRuleError(w, err)
+		return
+	}
+
+	var req updateRuleReq
+	fields, err := request.DecodeFields(r, &req)
+	if err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if fields.Has("keepDays") {
+		if req.KeepDays == nil && rule.Kind != KindTrash {
+			problem.Write(w, http.StatusBadRequest, "invalid_keep_days", "keepDays is required for this rule")
+			return
+		}
+		rule.KeepDays = req.KeepDays
+	}
+
+	updated, err := h.repo.Update(r.Context(), rule, userID)
+	if err != nil {
+		writeRuleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// Report is a dry run of the purge.
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+
+	report, err := h.purger.DryRun(r.Context())
+	if err != nil {
+		log.Printf("retention dry run failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to build retention report")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Run queues a purge now instead of waiting for the next scheduled one.
+func (h *Handler) Run(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+
+	if _, err := h.queue.Enqueue(r.Context(), JobPurge, struct{}{}, jobs.MaxAttempts(1)); err != nil {
+		log.Printf("enqueue retention purge failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to queue retention purge")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
+
+func writeRuleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "retention rule not found")
+		return
+	}
+	log.Printf("update retention rule failed: %v", err)
+	problem.Error(w, http.StatusInternalServerError, "failed to update retention rule")
+}
+
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+
+	allowed, err := h.isAdmin(r.Context(), userID)
+	if err != nil {
+		log.Printf("check retention admin failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check access")
+		return uuid.Nil, false
+	}
+	if !allowed {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
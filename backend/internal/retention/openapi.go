@@ -0,0 +1,15 @@
+package retention
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/admin/retention/rules", Tag: "admin", Summary: "List the retention rules (admins only)", Response: Rule{}, List: true},
+	{Method: http.MethodPatch, Path: "/admin/retention/rules/{kind}", Tag: "admin", Summary: "Enable, disable or change the retention of notifications, chat_attachments or trash (admins only)", Body: updateRuleReq{}, Response: Rule{}},
+	{Method: http.MethodGet, Path: "/admin/retention/report", Tag: "admin", Summary: "Dry run: what each retention rule would remove now (admins only)", Response: Report{}},
+	{Method: http.MethodPost, Path: "/admin/retention/run", Tag: "admin", Summary: "Queue a retention purge now (admins only)", Status: http.StatusAccepted, Response: openapi.Status{}},
+}
@@ -0,0 +1,180 @@
+// Package retention purges old data by the rules admins configure: old
+// notifications, old chat attachments and trashed project files. The purge
+// runs as a recurring job; a dry run reports what it would remove without
+// touching anything.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tm-platform-backend/internal/chats"
+	"tm-platform-backend/internal/jobs"
+	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/projectfiles"
+	"tm-platform-backend/internal/storage"
+)
+
+// JobPurge is the job kind applying the enabled rules. It is meant to be run
+// every hour or so with jobs.Queue.Every.
+const JobPurge = "retention.purge"
+
+const purgeBatch = 100
+
+// Report says how much each rule removes. In a dry run Items is what the
+// rule would remove now, whether it is enabled or not.
+type Report struct {
+	DryRun bool         `json:"dryRun"`
+	At     time.Time    `json:"at"`
+	Rules  []RuleReport `json:"rules"`
+}
+
+type RuleReport struct {
+	Kind     Kind       `json:"kind"`
+	Enabled  bool       `json:"enabled"`
+	KeepDays *int       `json:"keepDays"`
+	Cutoff   *time.Time `json:"cutoff,omitempty"`
+	Items    int        `json:"items"`
+}
+
+// Purger applies the rules to the repositories holding the data, and deletes
+// the stored objects nothing refers to anymore.
+type Purger struct {
+	rules         *Repository
+	notifications *notifications.Repository
+	chats         *chats.Repository
+	files         *projectfiles.Repository
+	store         storage.Storage
+}
+
+func NewPurger(rules *Repository, notificationsRepo *notifications.Repository, chatsRepo *chats.Repository, filesRepo *projectfiles.Repository, store storage.Storage) *Purger {
+	return &Purger{
+		rules:         rules,
+		notifications: notificationsRepo,
+		chats:         chatsRepo,
+		files:         filesRepo,
+		store:         store,
+	}
+}
+
+// Register sets the handler of JobPurge on queue.
+func (p *Purger) Register(queue *jobs.Queue) {
+	queue.Register(JobPurge, func(ctx context.Context, _ json.RawMessage) error {
+		report, err := p.Run(ctx)
+		for _, rule := range report.Rules {
+			if rule.Items > 0 {
+				log.Printf("retention purged %d %s", rule.Items, rule.Kind)
+			}
+		}
+		return err
+	})
+}
+
+// DryRun reports what Run would remove now.
+func (p *Purger) DryRun(ctx context.Context) (Report, error) {
+	rules, err := p.rules.List(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{DryRun: true, At: time.Now().UTC(), Rules: make([]RuleReport, 0, len(rules))}
+	for _, rule := range rules {
+		item := newRuleReport(rule, report.At)
+		if item.Items, err = p.count(ctx, rule.Kind, item.Cutoff); err != nil {
+			return Report{}, fmt.Errorf("count %s: %w", rule.Kind, err)
+		}
+		report.Rules = append(report.Rules, item)
+	}
+	return report, nil
+}
+
+// Run applies every enabled rule. A failing rule does not stop the others;
+// the report covers what was removed either way.
+func (p *Purger) Run(ctx context.Context) (Report, error) {
+	rules, err := p.rules.List(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{At: time.Now().UTC(), Rules: make([]RuleReport, 0, len(rules))}
+	var errs []error
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		item := newRuleReport(rule, report.At)
+		item.Items, err = p.purge(ctx, rule.Kind, item.Cutoff)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge %s: %w", rule.Kind, err))
+		}
+		report.Rules = append(report.Rules, item)
+	}
+	return report, errors.Join(errs...)
+}
+
+func newRuleReport(rule Rule, now time.Time) RuleReport {
+	return RuleReport{
+		Kind:     rule.Kind,
+		Enabled:  rule.Enabled,
+		KeepDays: rule.KeepDays,
+		Cutoff:   rule.Cutoff(now),
+	}
+}
+
+func (p *Purger) count(ctx context.Context, kind Kind, cutoff *time.Time) (int, error) {
+	switch kind {
+	case KindNotifications:
+		return p.notifications.CountCreatedBefore(ctx, *cutoff)
+	case KindChatAttachments:
+		return p.chats.CountAttachmentsBefore(ctx, *cutoff)
+	case KindTrash:
+		return p.files.CountExpiredTrash(ctx, cutoff)
+	default:
+		return 0, fmt.Errorf("unknown retention rule %q", kind)
+	}
+}
+
+// purge removes batches until one comes back short, so a large backlog is
+// worked off in one run without holding long transactions.
+func (p *Purger) purge(ctx context.Context, kind Kind, cutoff *time.Time) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		var (
+			purged int
+			keys   []string
+			err    error
+		)
+		switch kind {
+		case KindNotifications:
+			purged, err = p.notifications.PurgeCreatedBefore(ctx, *cutoff, purgeBatch)
+		case KindChatAttachments:
+			purged, keys, err = p.chats.PurgeAttachmentsBefore(ctx, *cutoff, purgeBatch)
+		case KindTrash:
+			purged, keys, err = p.files.PurgeExpiredTrash(ctx, cutoff, purgeBatch)
+		default:
+			err = fmt.Errorf("unknown retention rule %q", kind)
+		}
+		if err != nil {
+			return total, err
+		}
+		total += purged
+		p.deleteObjects(ctx, keys)
+
+		if purged < purgeBatch {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+func (p *Purger) deleteObjects(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := p.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("delete purged object %s failed: %v", key, err)
+		}
+	}
+}
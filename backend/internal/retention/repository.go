@@ -0,0 +1,118 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("retention rule not found")
+
+// Kind names what a rule purges.
+type Kind string
+
+const (
+	// KindNotifications deletes notifications, read or not, older than
+	// KeepDays.
+	KindNotifications Kind = "notifications"
+	// KindChatAttachments removes the attachments of chat messages older
+	// than KeepDays; the messages stay.
+	KindChatAttachments Kind = "chat_attachments"
+	// KindTrash hard-deletes trashed project files once their restore window
+	// ends, or KeepDays after they were trashed if that comes first.
+	KindTrash Kind = "trash"
+)
+
+// Rule is the retention of one kind of data. KeepDays is nil only for the
+// trash.
+type Rule struct {
+	Kind      Kind       `json:"kind"`
+	Enabled   bool       `json:"enabled"`
+	KeepDays  *int       `json:"keepDays"`
+	UpdatedBy *uuid.UUID `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Cutoff is the moment before which the rule purges data, nil when it has
+// no KeepDays.
+func (r Rule) Cutoff(now time.Time) *time.Time {
+	if r.KeepDays == nil {
+		return nil
+	}
+	cutoff := now.Add(-time.Duration(*r.KeepDays) * 24 * time.Hour)
+	return &cutoff
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const ruleColumns = `kind, enabled, keep_days, updated_by, updated_at`
+
+func (r *Repository) List(ctx context.Context) ([]Rule, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+ruleColumns+` FROM retention_rules ORDER BY kind`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]Rule, 0)
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *Repository) Get(ctx context.Context, kind Kind) (Rule, error) {
+	rule, err := scanRule(r.db.QueryRowContext(ctx, `SELECT `+ruleColumns+` FROM retention_rules WHERE kind = $1`, string(kind)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Rule{}, ErrNotFound
+	}
+	return rule, err
+}
+
+// Update saves Enabled and KeepDays of rule.
+func (r *Repository) Update(ctx context.Context, rule Rule, updatedBy uuid.UUID) (Rule, error) {
+	updated, err := scanRule(r.db.QueryRowContext(
+		ctx,
+		`UPDATE retention_rules
+		 SET enabled = $2, keep_days = $3, updated_by = $4, updated_at = now()
+		 WHERE kind = $1
+		 RETURNING `+ruleColumns,
+		string(rule.Kind),
+		rule.Enabled,
+		rule.KeepDays,
+		updatedBy,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Rule{}, ErrNotFound
+	}
+	return updated, err
+}
+
+type ruleScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRule(scanner ruleScanner) (Rule, error) {
+	var (
+		rule Rule
+		kind string
+	)
+	if err := scanner.Scan(&kind, &rule.Enabled, &rule.KeepDays, &rule.UpdatedBy, &rule.UpdatedAt); err != nil {
+		return Rule{}, err
+	}
+	rule.Kind = Kind(kind)
+	return rule, nil
+}
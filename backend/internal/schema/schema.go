@@ -0,0 +1,152 @@
+// Package schema applies the migrations embedded in the binary with
+// golang-migrate. The version is kept in schema_migrations, as the migrate
+// CLI keeps it, so databases migrated with the CLI carry on from where they
+// are.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+var (
+	ErrPending = errors.New("database schema has pending migrations")
+	ErrDirty   = errors.New("database schema is dirty")
+)
+
+// Status describes the schema of a database against the embedded
+// migrations.
+type Status struct {
+	Version uint // applied version, 0 before the first migration
+	Dirty   bool // the last migration failed halfway
+	Latest  uint // version of the newest embedded migration
+	Pending []uint
+}
+
+// open connects a migrator to the database at dsn. Closing it closes its
+// own connection.
+func open(dsn string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read embedded migrations: %w", err)
+	}
+
+	conn, err := db.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := pgx.WithInstance(conn, &pgx.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", source, "pgx5", driver)
+}
+
+func closeMigrator(m *migrate.Migrate, err error) error {
+	sourceErr, dbErr := m.Close()
+	return errors.Join(err, sourceErr, dbErr)
+}
+
+// Up applies all pending migrations.
+func Up(dsn string) (err error) {
+	m, err := open(dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { err = closeMigrator(m, err) }()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Down reverts the last steps migrations.
+func Down(dsn string, steps int) (err error) {
+	if steps < 1 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	m, err := open(dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { err = closeMigrator(m, err) }()
+
+	if err := m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Current returns the status of the database at dsn.
+func Current(dsn string) (status Status, err error) {
+	m, err := open(dsn)
+	if err != nil {
+		return Status{}, err
+	}
+	defer func() { err = closeMigrator(m, err) }()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	versions, err := embeddedVersions()
+	if err != nil {
+		return Status{}, err
+	}
+	for _, v := range versions {
+		status.Latest = max(status.Latest, v)
+		if v > version {
+			status.Pending = append(status.Pending, v)
+		}
+	}
+	return status, nil
+}
+
+// Check reports ErrDirty or ErrPending unless the database at dsn is on the
+// newest embedded migration.
+func Check(dsn string) error {
+	status, err := Current(dsn)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, status.Version)
+	}
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrPending, status.Version, status.Latest)
+	}
+	return nil
+}
+
+func embeddedVersions() ([]uint, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	var versions []uint
+	version, err := source.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = source.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
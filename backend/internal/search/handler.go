@@ -0,0 +1,101 @@
+// Package search indexes projects, tasks, pages, task comments, chat
+// messages and project file texts in one Postgres full-text index (Russian
+// dictionary) and answers GET /search over all of them, filtered by what the
+// requester may read. The index is updated from domain events as entities
+// change and reconciled periodically with the tables it mirrors.
+package search
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLimit   = 20
+	maxLimit       = 50
+	maxQueryLength = 200
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// Search answers ?q= with the best matches, optionally only of ?kinds=
+// (comma separated) and ?projectId=.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	q := Query{Text: strings.TrimSpace(query.Get("q")), Limit: defaultLimit}
+	if q.Text == "" {
+		problem.Write(w, http.StatusBadRequest, "query_required", "q is required")
+		return
+	}
+	if utf8.RuneCountInString(q.Text) > maxQueryLength {
+		problem.Write(w, http.StatusBadRequest, "query_too_long", "q must be at most 200 characters")
+		return
+	}
+
+	if raw := strings.TrimSpace(query.Get("kinds")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			kind := Kind(strings.TrimSpace(part))
+			if _, ok := sources[kind]; !ok {
+				problem.Write(w, http.StatusBadRequest, "invalid_kind", "unknown kind: "+string(kind))
+				return
+			}
+			q.Kinds = append(q.Kinds, kind)
+		}
+	}
+	if raw := strings.TrimSpace(query.Get("projectId")); raw != "" {
+		projectID, err := uuid.Parse(raw)
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+			return
+		}
+		q.ProjectID = &projectID
+	}
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			problem.Write(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+			return
+		}
+		q.Limit = min(limit, maxLimit)
+	}
+
+	results, err := h.repo.Search(r.Context(), userID, q)
+	if err != nil {
+		log.Printf("search failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to search")
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
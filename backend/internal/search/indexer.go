@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// Job kinds of the indexer. JobReconcile is meant to be run every few
+// minutes with jobs.Queue.Every.
+const (
+	JobIndex     = "search.index"
+	JobReconcile = "search.reconcile"
+)
+
+type indexJob struct {
+	Kind Kind      `json:"kind"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// Indexer keeps the search index up to date from domain events. Documents
+// are rebuilt through the job queue when there is one, so publishers do not
+// wait for them.
+type Indexer struct {
+	repo *Repository
+	jobs *jobs.Queue
+}
+
+func NewIndexer(repo *Repository, queue *jobs.Queue) *Indexer {
+	return &Indexer{repo: repo, jobs: queue}
+}
+
+// Register subscribes i to the events that change indexed entities and lets
+// queue run its jobs.
+func (i *Indexer) Register(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.ProjectCreated) error {
+		return i.index(ctx, KindProject, e.ProjectID)
+	})
+	events.On(bus, func(ctx context.Context, e events.ProjectUpdated) error {
+		return i.index(ctx, KindProject, e.ProjectID)
+	})
+	events.On(bus, func(ctx context.Context, e events.TaskCreated) error {
+		return i.index(ctx, KindTask, e.TaskID)
+	})
+	events.On(bus, func(ctx context.Context, e events.TaskUpdated) error {
+		return i.index(ctx, KindTask, e.TaskID)
+	})
+	events.On(bus, func(ctx context.Context, e events.TaskDeleted) error {
+		return i.index(ctx, KindTask, e.TaskID)
+	})
+	events.On(bus, func(ctx context.Context, e events.TaskCommented) error {
+		return i.index(ctx, KindComment, e.CommentID)
+	})
+	events.On(bus, func(ctx context.Context, e events.PageSaved) error {
+		return i.index(ctx, KindPage, e.PageID)
+	})
+	events.On(bus, func(ctx context.Context, e events.ChatMessageSent) error {
+		return i.index(ctx, KindMessage, e.MessageID)
+	})
+	events.On(bus, func(ctx context.Context, e events.FileIndexed) error {
+		return i.index(ctx, KindFile, e.FileID)
+	})
+
+	if i.jobs != nil {
+		i.jobs.Register(JobIndex, i.runIndex)
+		i.jobs.Register(JobReconcile, func(ctx context.Context, _ json.RawMessage) error {
+			return i.repo.Reconcile(ctx)
+		})
+	}
+}
+
+func (i *Indexer) index(ctx context.Context, kind Kind, id uuid.UUID) error {
+	if i.jobs != nil {
+		_, err := i.jobs.Enqueue(ctx, JobIndex, indexJob{Kind: kind, ID: id})
+		if err == nil {
+			return nil
+		}
+		log.Printf("search index enqueue failed, indexing inline: %v", err)
+	}
+	return i.repo.Reindex(ctx, kind, id)
+}
+
+func (i *Indexer) runIndex(ctx context.Context, raw json.RawMessage) error {
+	var job indexJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("decode index job: %w", err)
+	}
+	return i.repo.Reindex(ctx, job.Kind, job.ID)
+}
@@ -0,0 +1,17 @@
+package search
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/search", Tag: "search", Summary: "Search projects, tasks, pages, comments, chat messages and files the caller can read, best matches first", Query: []openapi.Param{
+		{Name: "q", Kind: "string", Description: "Search terms; quotes, OR and -term are understood", Required: true},
+		{Name: "kinds", Kind: "string", Description: "Comma-separated kinds to search: project, task, page, comment, message, file; all when absent"},
+		{Name: "projectId", Kind: "string", Description: "Only results of this project"},
+		{Name: "limit", Kind: "integer", Description: "At most 50; 20 when absent"},
+	}, Response: Result{}, List: true},
+}
@@ -0,0 +1,257 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/projectfiles"
+	"tm-platform-backend/internal/projects"
+
+	"github.com/google/uuid"
+)
+
+// Kind is the kind of entity a search document stands for.
+type Kind string
+
+const (
+	KindProject Kind = "project"
+	KindTask    Kind = "task"
+	KindPage    Kind = "page"
+	KindComment Kind = "comment" // task comment
+	KindMessage Kind = "message" // chat message
+	KindFile    Kind = "file"    // project file, with its extracted text
+)
+
+// Kinds lists every kind of document, in the order Reconcile indexes them.
+var Kinds = []Kind{KindProject, KindTask, KindPage, KindComment, KindMessage, KindFile}
+
+// sources select the document of every entity of a kind from the tables the
+// entity lives in, as entity_id, project_id, thread_id, parent_id, title,
+// body and updated_at. Entities they do not return have no document.
+var sources = map[Kind]string{
+	KindProject: `SELECT p.id AS entity_id, p.id AS project_id, NULL::uuid AS thread_id, NULL::uuid AS parent_id,
+		        p.title AS title, COALESCE(p.description, '') AS body, p.updated_at AS updated_at
+		 FROM projects p`,
+	KindTask: `SELECT t.id AS entity_id, s.project_id AS project_id, NULL::uuid AS thread_id, t.stage_id AS parent_id,
+		        t.title AS title, search_blocks_text(t.blocks) AS body, t.updated_at AS updated_at
+		 FROM stage_tasks t
+		 JOIN project_stages s ON s.id = t.stage_id`,
+	KindPage: `SELECT pg.id AS entity_id, pg.project_id AS project_id, NULL::uuid AS thread_id, NULL::uuid AS parent_id,
+		        pg.title AS title, search_blocks_text(pg.blocks_json) AS body, pg.updated_at AS updated_at
+		 FROM project_pages pg`,
+	// comments carry the title of their task and are refreshed when it
+	// changes
+	KindComment: `SELECT tc.id AS entity_id, s.project_id AS project_id, NULL::uuid AS thread_id, tc.task_id AS parent_id,
+		        t.title AS title, tc.message AS body, GREATEST(tc.created_at, t.updated_at) AS updated_at
+		 FROM task_comments tc
+		 JOIN stage_tasks t ON t.id = tc.task_id
+		 JOIN project_stages s ON s.id = t.stage_id`,
+	KindMessage: `SELECT m.id AS entity_id, NULL::uuid AS project_id, m.thread_id AS thread_id, NULL::uuid AS parent_id,
+		        COALESCE(m.attachment_name, '') AS title, COALESCE(m.text, '') AS body, m.created_at AS updated_at
+		 FROM chat_messages m`,
+	KindFile: `SELECT pf.id AS entity_id, pf.project_id AS project_id, NULL::uuid AS thread_id, NULL::uuid AS parent_id,
+		        pf.name AS title, COALESCE(pf.content_text, '') AS body, pf.updated_at AS updated_at
+		 FROM project_files pf
+		 WHERE pf.deleted_at IS NULL`,
+}
+
+// upsert returns the statement writing the documents of source that pass
+// filter, with the kind as $1.
+func upsert(source, filter string) string {
+	return fmt.Sprintf(`INSERT INTO search_documents (kind, entity_id, project_id, thread_id, parent_id, title, body, updated_at)
+	 SELECT $1::text, src.entity_id, src.project_id, src.thread_id, src.parent_id, src.title, src.body, src.updated_at
+	 FROM (%s) src
+	 %s
+	 ON CONFLICT (kind, entity_id) DO UPDATE
+	 SET project_id = EXCLUDED.project_id,
+	     thread_id = EXCLUDED.thread_id,
+	     parent_id = EXCLUDED.parent_id,
+	     title = EXCLUDED.title,
+	     body = EXCLUDED.body,
+	     updated_at = EXCLUDED.updated_at`, source, filter)
+}
+
+// Result is a document matching a search.
+type Result struct {
+	Kind      Kind       `json:"kind"`
+	ID        uuid.UUID  `json:"id"`
+	ProjectID *uuid.UUID `json:"projectId,omitempty"`
+	ThreadID  *uuid.UUID `json:"threadId,omitempty"` // chat thread of a message
+	ParentID  *uuid.UUID `json:"parentId,omitempty"` // stage of a task, task of a comment
+	Title     string     `json:"title"`
+	Snippet   string     `json:"snippet"` // matched terms wrapped in **
+	Rank      float64    `json:"rank"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Query is a search request.
+type Query struct {
+	Text      string
+	Kinds     []Kind     // all kinds when empty
+	ProjectID *uuid.UUID // only documents of this project
+	Limit     int
+}
+
+type Repository struct {
+	db      *sql.DB
+	replica *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// WithReplica runs searches on the read replica conn, see db.Reader.
+func (r *Repository) WithReplica(conn *sql.DB) *Repository {
+	r.replica = conn
+	return r
+}
+
+func (r *Repository) reader(ctx context.Context) *sql.DB {
+	return db.Reader(ctx, r.db, r.replica)
+}
+
+// Reindex rebuilds the document of one entity from its tables, or removes it
+// when the entity is gone. A task removed takes the documents of its
+// comments with it.
+func (r *Repository) Reindex(ctx context.Context, kind Kind, entityID uuid.UUID) error {
+	source, ok := sources[kind]
+	if !ok {
+		return nil
+	}
+
+	result, err := r.db.ExecContext(ctx, upsert(source, `WHERE src.entity_id = $2`), string(kind), entityID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM search_documents WHERE kind = $1 AND entity_id = $2`, string(kind), entityID); err != nil {
+		return err
+	}
+	if kind == KindTask {
+		_, err = r.db.ExecContext(ctx, `DELETE FROM search_documents WHERE kind = $1 AND parent_id = $2`, string(KindComment), entityID)
+	}
+	return err
+}
+
+// Reconcile brings the whole index up to date: documents older than their
+// entity are rebuilt, missing ones added and those of deleted entities
+// removed. It catches up with writes that published no event, and builds
+// the index from scratch the first time.
+func (r *Repository) Reconcile(ctx context.Context) error {
+	for _, kind := range Kinds {
+		source := sources[kind]
+		if _, err := r.db.ExecContext(ctx, upsert(source, `LEFT JOIN search_documents d ON d.kind = $1 AND d.entity_id = src.entity_id
+	 WHERE d.entity_id IS NULL OR d.updated_at < src.updated_at`), string(kind)); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(
+			ctx,
+			`DELETE FROM search_documents d
+			 WHERE d.kind = $1
+			   AND NOT EXISTS (SELECT 1 FROM (`+source+`) src WHERE src.entity_id = d.entity_id)`,
+			string(kind),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search returns the documents of the current workspace matching q that
+// userID may read, best first. Projects, tasks, pages and comments follow
+// project read access, messages thread membership and files their visibility.
+func (r *Repository) Search(ctx context.Context, userID uuid.UUID, q Query) ([]Result, error) {
+	kinds := make([]string, 0, len(Kinds))
+	for _, kind := range q.Kinds {
+		kinds = append(kinds, string(kind))
+	}
+	if len(kinds) == 0 {
+		for _, kind := range Kinds {
+			kinds = append(kinds, string(kind))
+		}
+	}
+	var projectID any
+	if q.ProjectID != nil {
+		projectID = *q.ProjectID
+	}
+
+	// the headline is costly on long bodies, so it is built for the page only
+	rows, err := r.reader(ctx).QueryContext(
+		ctx,
+		`SELECT hit.kind, hit.entity_id, hit.project_id, hit.thread_id, hit.parent_id, hit.title,
+		        ts_headline('russian', hit.body, hit.query,
+		        	'StartSel=**, StopSel=**, MaxWords=30, MinWords=10, MaxFragments=2'),
+		        hit.rank, hit.updated_at
+		 FROM (
+		 	SELECT d.kind, d.entity_id, d.project_id, d.thread_id, d.parent_id, d.title, d.body, d.updated_at,
+		 	       q.query, ts_rank(d.search_vector, q.query) AS rank
+		 	FROM search_documents d
+		 	CROSS JOIN websearch_to_tsquery('russian', $2) AS q(query)
+		 	WHERE d.search_vector @@ q.query
+		 	  AND d.kind = ANY($3::text[])
+		 	  AND ($4::uuid IS NULL OR d.project_id = $4)
+		 	  AND (
+		 	  	EXISTS (SELECT 1 FROM projects wp WHERE wp.id = d.project_id AND wp.workspace_id = $6)
+		 	  	OR EXISTS (SELECT 1 FROM chat_threads wt WHERE wt.id = d.thread_id AND wt.workspace_id = $6)
+		 	  )
+		 	  AND (
+		 	  	(d.kind IN ('project', 'task', 'page', 'comment') AND `+projects.ReadAccess("d.project_id", "$1")+`)
+		 	  	OR (d.kind = 'message' AND EXISTS (
+		 	  		SELECT 1 FROM chat_thread_members ctm
+		 	  		WHERE ctm.thread_id = d.thread_id AND ctm.user_id = $1
+		 	  	))
+		 	  	OR (d.kind = 'file' AND EXISTS (
+		 	  		SELECT 1
+		 	  		FROM project_files pf
+		 	  		JOIN projects p ON p.id = pf.project_id
+		 	  		WHERE pf.id = d.entity_id
+		 	  		  AND `+projectfiles.ReadAccess("$1")+`
+		 	  	))
+		 	  )
+		 	ORDER BY rank DESC, d.updated_at DESC
+		 	LIMIT $5
+		 ) hit
+		 ORDER BY hit.rank DESC, hit.updated_at DESC`,
+		userID,
+		q.Text,
+		kinds,
+		projectID,
+		q.Limit,
+		db.Workspace(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Result, 0)
+	for rows.Next() {
+		var result Result
+		if err := rows.Scan(
+			&result.Kind,
+			&result.ID,
+			&result.ProjectID,
+			&result.ThreadID,
+			&result.ParentID,
+			&result.Title,
+			&result.Snippet,
+			&result.Rank,
+			&result.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"tm-platform-backend/internal/utils"
+)
+
+// Local keeps objects on the filesystem under a base directory.
+type Local struct {
+	baseDir string
+}
+
+func NewLocal(baseDir string) (*Local, error) {
+	if baseDir == "" {
+		baseDir = "uploads"
+	}
+	if err := utils.EnsureFolder(baseDir); err != nil {
+		return nil, err
+	}
+	return &Local{baseDir: baseDir}, nil
+}
+
+func (s *Local) Put(_ context.Context, key string, body io.Reader, _ int64, _ string) error {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := utils.EnsureFolder(filepath.Dir(fullPath)); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(fullPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		_ = out.Close()
+		_ = os.Remove(fullPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(fullPath)
+		return err
+	}
+	return nil
+}
+
+func (s *Local) Open(_ context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ObjectInfo{}, ErrNotFound
+		}
+		return nil, ObjectInfo{}, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, ObjectInfo{}, err
+	}
+	if stat.IsDir() {
+		_ = f.Close()
+		return nil, ObjectInfo{}, ErrNotFound
+	}
+
+	return f, localInfo(key, stat), nil
+}
+
+func (s *Local) Stat(_ context.Context, key string) (ObjectInfo, error) {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, err
+	}
+	if stat.IsDir() {
+		return ObjectInfo{}, ErrNotFound
+	}
+
+	return localInfo(key, stat), nil
+}
+
+func (s *Local) Delete(_ context.Context, key string) error {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Local) resolve(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.baseDir, filepath.FromSlash(cleaned)), nil
+}
+
+func localInfo(key string, stat os.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ModTime:     stat.ModTime(),
+	}
+}
+
+const localMultipartDir = ".multipart"
+
+func (s *Local) CreateMultipart(_ context.Context, key string, _ string) (string, error) {
+	if _, err := CleanKey(key); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(buf)
+
+	if err := utils.EnsureFolder(filepath.Join(s.baseDir, localMultipartDir, uploadID)); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+func (s *Local) UploadPart(_ context.Context, _ string, uploadID string, partNumber int, body io.Reader, _ int64) (string, error) {
+	dir, err := s.multipartDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if partNumber <= 0 {
+		return "", errors.New("invalid part number")
+	}
+
+	tmp, err := os.CreateTemp(dir, "part-*.tmp")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	// Renaming over an existing part makes retries of the same part safe.
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, partFileName(partNumber))); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *Local) CompleteMultipart(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	dir, err := s.multipartDir(uploadID)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		return errors.New("no parts to complete")
+	}
+
+	ordered := append([]CompletedPart(nil), parts...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].PartNumber < ordered[j].PartNumber })
+
+	readers := make([]io.Reader, 0, len(ordered))
+	files := make([]*os.File, 0, len(ordered))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, part := range ordered {
+		f, err := os.Open(filepath.Join(dir, partFileName(part.PartNumber)))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("part %d is missing", part.PartNumber)
+			}
+			return err
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+	}
+
+	if err := s.Put(ctx, key, io.MultiReader(readers...), -1, ""); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+func (s *Local) AbortMultipart(_ context.Context, _ string, uploadID string) error {
+	dir, err := s.multipartDir(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (s *Local) multipartDir(uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", errors.New("invalid upload id")
+	}
+	return filepath.Join(s.baseDir, localMultipartDir, uploadID), nil
+}
+
+func partFileName(partNumber int) string {
+	return fmt.Sprintf("%06d.part", partNumber)
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type s3InitiateMultipartResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompleteMultipart struct {
+	XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletePart `xml:"Part"`
+}
+
+type s3CompletePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3ErrorResult struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func (s *S3) CreateMultipart(ctx context.Context, key string, contentType string) (string, error) {
+	req, err := s.newRequestWithQuery(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := s3Error(resp); err != nil {
+		return "", err
+	}
+
+	var result s3InitiateMultipartResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", errors.New("s3 did not return an upload id")
+	}
+	return result.UploadID, nil
+}
+
+func (s *S3) UploadPart(ctx context.Context, key, uploadID string, partNumber int, body io.Reader, size int64) (string, error) {
+	req, err := s.newRequestWithQuery(ctx, http.MethodPut, key, partQuery(uploadID, partNumber), body)
+	if err != nil {
+		return "", err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := s3Error(resp); err != nil {
+		return "", err
+	}
+
+	return strings.Trim(resp.Header.Get("ETag"), `"`), nil
+}
+
+func (s *S3) CompleteMultipart(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	if len(parts) == 0 {
+		return errors.New("no parts to complete")
+	}
+
+	payload := s3CompleteMultipart{Parts: make([]s3CompletePart, 0, len(parts))}
+	for _, part := range parts {
+		payload.Parts = append(payload.Parts, s3CompletePart{
+			PartNumber: part.PartNumber,
+			ETag:       `"` + strings.Trim(part.ETag, `"`) + `"`,
+		})
+	}
+	sort.Slice(payload.Parts, func(i, j int) bool { return payload.Parts[i].PartNumber < payload.Parts[j].PartNumber })
+
+	body, err := xml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequestWithQuery(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := s3Error(resp); err != nil {
+		return err
+	}
+
+	// S3 may report a failed completion with a 200 status and an error body.
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	var failure s3ErrorResult
+	if xml.Unmarshal(respBody, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("s3 complete multipart failed: %s: %s", failure.Code, failure.Message)
+	}
+	return nil
+}
+
+func (s *S3) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	req, err := s.newRequestWithQuery(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = s3Error(resp)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *S3) PresignPart(key, uploadID string, partNumber int, expires time.Duration) (string, error) {
+	return s.presign(http.MethodPut, key, partQuery(uploadID, partNumber), expires, time.Now().UTC())
+}
+
+func partQuery(uploadID string, partNumber int) url.Values {
+	return url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/problem"
+)
+
+// PublicPrefix is the URL prefix under which stored objects are served by the
+// backend, regardless of the storage driver.
+const PublicPrefix = "/uploads/"
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Storage persists uploaded objects under slash-separated keys such as
+// "images/1700000000_ab12cd34.png".
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// CompletedPart identifies one uploaded part of a multipart upload.
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// MultipartStorage is implemented by backends that can assemble an object
+// from separately uploaded parts, so large files never have to be buffered
+// in one request.
+type MultipartStorage interface {
+	CreateMultipart(ctx context.Context, key string, contentType string) (string, error)
+	UploadPart(ctx context.Context, key, uploadID string, partNumber int, body io.Reader, size int64) (string, error)
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []CompletedPart) error
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+// PartPresigner is implemented by backends that let clients upload parts
+// directly, bypassing the backend process.
+type PartPresigner interface {
+	PresignPart(key, uploadID string, partNumber int, expires time.Duration) (string, error)
+}
+
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+type Config struct {
+	Driver   string
+	LocalDir string
+
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool
+}
+
+// New builds the storage selected by cfg.Driver ("local" or "s3").
+func New(cfg Config) (Storage, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Driver)) {
+	case "", "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3", "minio":
+		return NewS3(S3Options{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// pingKey is looked up by Ping; it never exists.
+const pingKey = "health/ping"
+
+// Ping checks that store answers, by looking up an object that does not
+// exist: not finding it is the expected answer.
+func Ping(ctx context.Context, store Storage) error {
+	_, err := store.Stat(ctx, pingKey)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// PublicURL returns the URL clients use to fetch the object.
+func PublicURL(key string) string {
+	return PublicPrefix + key
+}
+
+// KeyFromURL extracts the object key from a URL produced by PublicURL.
+// Absolute URLs are accepted as long as their path starts with PublicPrefix.
+func KeyFromURL(rawURL string) (string, bool) {
+	value := strings.TrimSpace(rawURL)
+	if idx := strings.Index(value, "://"); idx >= 0 {
+		rest := value[idx+3:]
+		slash := strings.Index(rest, "/")
+		if slash < 0 {
+			return "", false
+		}
+		value = rest[slash:]
+	}
+	if q := strings.IndexAny(value, "?#"); q >= 0 {
+		value = value[:q]
+	}
+	if !strings.HasPrefix(value, PublicPrefix) {
+		return "", false
+	}
+
+	key, err := CleanKey(strings.TrimPrefix(value, PublicPrefix))
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// CleanKey normalizes a key and rejects anything that could escape the
+// storage root. Dot-prefixed segments are reserved for internal staging data.
+func CleanKey(key string) (string, error) {
+	trimmed := strings.TrimSpace(key)
+	if trimmed == "" || strings.Contains(trimmed, "\\") || strings.HasPrefix(trimmed, "/") {
+		return "", ErrInvalidKey
+	}
+
+	cleaned := path.Clean(trimmed)
+	for _, segment := range strings.Split(cleaned, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", ErrInvalidKey
+		}
+	}
+	return cleaned, nil
+}
+
+// AccessGuard decides whether an object may be served from the public path.
+type AccessGuard func(r *http.Request, key string) (bool, error)
+
+// Handler serves GET/HEAD requests for stored objects. It is meant to be
+// mounted behind http.StripPrefix(PublicPrefix, ...). A nil guard allows
+// every object.
+func Handler(store Storage, guard AccessGuard) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			problem.Write(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+
+		key, err := CleanKey(r.URL.Path)
+		if err != nil {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "file not found")
+			return
+		}
+
+		if guard != nil {
+			allowed, err := guard(r, key)
+			if err != nil {
+				problem.Error(w, http.StatusInternalServerError, "failed to check file access")
+				return
+			}
+			if !allowed {
+				problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+				return
+			}
+		}
+
+		body, info, err := store.Open(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "file not found")
+				return
+			}
+			problem.Error(w, http.StatusBadGateway, "failed to read file")
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Cache-Control", cacheControl(r))
+		ServeObject(w, r, body, info)
+	})
+}
+
+// objectPolicy is the Content-Security-Policy of a stored object. Uploads
+// are untrusted, so an HTML or SVG file opened from a link runs no scripts,
+// loads nothing from elsewhere and posts no forms. The sandbox makes it an
+// opaque origin as well, except for PDFs, which the viewers of some
+// browsers refuse to render sandboxed.
+func objectPolicy(contentType string) string {
+	policy := "default-src 'none'; img-src 'self' data:; media-src 'self'; style-src 'unsafe-inline'; form-action 'none'; base-uri 'none'"
+	if mediaType, _, _ := strings.Cut(contentType, ";"); strings.TrimSpace(strings.ToLower(mediaType)) == "application/pdf" {
+		return policy
+	}
+	return policy + "; sandbox"
+}
+
+// ServeObject writes an opened object to w, honouring range requests when the
+// body supports seeking and If-None-Match against its ETag.
+func ServeObject(w http.ResponseWriter, r *http.Request, body io.Reader, info ObjectInfo) {
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", objectPolicy(info.ContentType))
+	current := objectTag(info)
+	w.Header().Set("ETag", current)
+
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, path.Base(info.Key), info.ModTime, seeker)
+		return
+	}
+
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size))
+	}
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if noneMatch(r, current) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = io.Copy(w, body)
+}
+
+// objectTag returns the ETag of a stored object. Keys are never reused for
+// other content, so the key, size and modification time identify it without
+// reading the body.
+func objectTag(info ObjectInfo) string {
+	sum := sha256.Sum256([]byte(info.Key + "\n" + strconv.FormatInt(info.Size, 10) + "\n" + strconv.FormatInt(info.ModTime.UnixNano(), 10)))
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:12]) + `"`
+}
+
+// noneMatch reports whether the If-None-Match header of r names current
+func noneMatch(r *http.Request, current string) bool {
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == current {
+			return true
+		}
+	}
+	return false
+}
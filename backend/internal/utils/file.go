@@ -4,9 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
-	"fmt"
-	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -14,61 +11,24 @@ import (
 	"time"
 )
 
-const maxNameAttempts = 10
-
-func SaveUploadedFile(file multipart.File, header *multipart.FileHeader, folder string) (string, string, error) {
-	if file == nil {
-		return "", "", errors.New("file is required")
-	}
-	if header == nil {
-		return "", "", errors.New("file header is required")
-	}
-
-	ext := strings.ToLower(filepath.Ext(header.Filename))
+// BuildObjectKey returns a fresh storage key such as
+// "images/1700000000_ab12cd34.png" for an uploaded file name.
+func BuildObjectKey(folder string, originalName string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(originalName))
 	if ext == "" {
-		return "", "", errors.New("missing file extension")
+		return "", errors.New("missing file extension")
 	}
 
-	if err := EnsureFolder(folder); err != nil {
-		return "", "", err
+	fileName, err := buildFileName(ext)
+	if err != nil {
+		return "", err
 	}
 
-	for i := 0; i < maxNameAttempts; i++ {
-		fileName, err := buildFileName(ext)
-		if err != nil {
-			return "", "", err
-		}
-
-		fullPath := filepath.Join(folder, fileName)
-		out, err := os.OpenFile(fullPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
-		if err != nil {
-			if errors.Is(err, os.ErrExist) {
-				continue
-			}
-			return "", "", err
-		}
-
-		if _, err := file.Seek(0, io.SeekStart); err != nil {
-			_ = out.Close()
-			_ = os.Remove(fullPath)
-			return "", "", err
-		}
-
-		if _, err := io.Copy(out, file); err != nil {
-			_ = out.Close()
-			_ = os.Remove(fullPath)
-			return "", "", err
-		}
-
-		if err := out.Close(); err != nil {
-			_ = os.Remove(fullPath)
-			return "", "", err
-		}
-
-		return fullPath, fileName, nil
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		return fileName, nil
 	}
-
-	return "", "", fmt.Errorf("failed to generate a unique filename after %d attempts", maxNameAttempts)
+	return folder + "/" + fileName, nil
 }
 
 func EnsureFolder(path string) error {
@@ -0,0 +1,242 @@
+// Package webhooks delivers domain events to endpoints registered by admins,
+// as signed JSON POSTs:
+//
+//	POST <url>
+//	X-Webhook-Event: task.updated
+//	X-Webhook-Delivery: <delivery id>
+//	X-Webhook-Timestamp: 1767225600
+//	X-Webhook-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret>
+//
+//	{"event":"task.updated","occurredAt":"...","data":{...}}
+//
+// A webhook only hears the events of its own workspace. Each delivery is
+// sent by a job, retried with backoff until the endpoint answers 2xx or the
+// attempts run out, and logged for the admins.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// JobDeliver is the job kind sending one delivery.
+const JobDeliver = "webhooks.deliver"
+
+const (
+	// drainLimit is how much of an answer is read, and thrown away, so the
+	// connection can be reused.
+	drainLimit          = 4 << 10
+	maxDeliveryAttempts = 6
+	deliveryTimeout     = 10 * time.Second
+)
+
+type envelope struct {
+	Event      string       `json:"event"`
+	OccurredAt time.Time    `json:"occurredAt"`
+	Data       events.Event `json:"data"`
+}
+
+type deliverJob struct {
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	WebhookID   uuid.UUID `json:"webhookId"`
+	DeliveryID  uuid.UUID `json:"deliveryId"`
+}
+
+// Dispatcher records a delivery for every webhook an event concerns and
+// sends them through the job queue.
+type Dispatcher struct {
+	repo   *Repository
+	jobs   *jobs.Queue
+	client *http.Client
+}
+
+func NewDispatcher(repo *Repository, queue *jobs.Queue) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		jobs:   queue,
+		client: newClient(),
+	}
+}
+
+// Register subscribes d to every event on bus and lets queue send its
+// deliveries.
+func (d *Dispatcher) Register(bus *events.Bus) {
+	bus.SubscribeAll(d.publish)
+	d.jobs.Register(JobDeliver, d.deliver)
+}
+
+func (d *Dispatcher) publish(ctx context.Context, event events.Event) error {
+	workspaceID, ok, err := d.eventWorkspace(ctx, event)
+	if err != nil || !ok {
+		return err
+	}
+	webhooks, err := d.repo.List(db.WithWorkspace(ctx, workspaceID))
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Wants(event.EventName()) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(envelope{Event: event.EventName(), OccurredAt: time.Now().UTC(), Data: event})
+			if err != nil {
+				return err
+			}
+		}
+
+		delivery, err := d.repo.CreateDelivery(ctx, webhook, event.EventName(), payload)
+		if err != nil {
+			return err
+		}
+		if err := d.enqueue(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventWorkspace returns the workspace event happened in. Events published
+// while serving a request run in its workspace; those of background work
+// are traced back to what they are about. ok is false for an event whose
+// subject is gone.
+func (d *Dispatcher) eventWorkspace(ctx context.Context, event events.Event) (uuid.UUID, bool, error) {
+	if workspaceID, ok := db.WorkspaceID(ctx); ok {
+		return workspaceID, true, nil
+	}
+	indexed, ok := event.(events.FileIndexed)
+	if !ok {
+		return uuid.Nil, false, fmt.Errorf("%s published outside a workspace", event.EventName())
+	}
+	workspaceID, err := d.repo.FileWorkspace(ctx, indexed.FileID)
+	if errors.Is(err, ErrNotFound) {
+		return uuid.Nil, false, nil
+	}
+	return workspaceID, err == nil, err
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, delivery Delivery) error {
+	job := deliverJob{WorkspaceID: delivery.WorkspaceID, WebhookID: delivery.WebhookID, DeliveryID: delivery.ID}
+	_, err := d.jobs.Enqueue(ctx, JobDeliver, job, jobs.MaxAttempts(maxDeliveryAttempts))
+	return err
+}
+
+// Redeliver sends a logged delivery again.
+func (d *Dispatcher) Redeliver(ctx context.Context, delivery Delivery) error {
+	if err := d.repo.ResetDelivery(ctx, delivery.ID); err != nil {
+		return err
+	}
+	return d.enqueue(ctx, delivery)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, raw json.RawMessage) error {
+	var job deliverJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return fmt.Errorf("decode delivery job: %w", err)
+	}
+	ctx = db.WithWorkspace(ctx, job.WorkspaceID)
+
+	webhook, err := d.repo.Get(ctx, job.WebhookID)
+	if errors.Is(err, ErrNotFound) {
+		// deleted since, and its deliveries with it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	delivery, err := d.repo.GetDelivery(ctx, job.WebhookID, job.DeliveryID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != DeliveryPending {
+		return nil
+	}
+	if !webhook.Active {
+		return d.repo.RecordAttempt(ctx, delivery.ID, DeliveryFailed, nil, "webhook is disabled")
+	}
+
+	responseStatus, sendErr := d.send(ctx, webhook, delivery)
+	if sendErr == nil {
+		return d.repo.RecordAttempt(ctx, delivery.ID, DeliverySucceeded, responseStatus, "")
+	}
+
+	status := DeliveryPending
+	if delivery.Attempts+1 >= maxDeliveryAttempts {
+		status = DeliveryFailed
+	}
+	if err := d.repo.RecordAttempt(ctx, delivery.ID, status, responseStatus, sendErr.Error()); err != nil {
+		return errors.Join(sendErr, err)
+	}
+	return sendErr
+}
+
+// send posts the delivery and returns the status the endpoint answered.
+// The body of the answer is discarded: it is not the admins' to read, as
+// the endpoint may be anything the URL names.
+func (d *Dispatcher) send(ctx context.Context, webhook Webhook, delivery Delivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TM-Platform-Webhooks/1")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, drainLimit))
+
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("endpoint answered %d", status)
+	}
+	return &status, nil
+}
+
+// Sign returns the X-Webhook-Signature of body sent at timestamp. Receivers
+// compute the same value with their copy of the secret and compare.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSecret returns a random signing secret.
+func newSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"event":"task.created"}`)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		want      string
+	}{
+		{
+			name:      "payload",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			body:      payload,
+			want:      "sha256=aabc548901ea3b50be05eb85dc114164830b27c602dcb16a1623b007eff48c20",
+		},
+		{
+			name:      "another timestamp",
+			secret:    "whsec_test",
+			timestamp: "1700000001",
+			body:      payload,
+			want:      "sha256=9be5be475a44dcc598e76d7686b0aa0b79448ab4d611208ba73f2fd7c1ebb19c",
+		},
+		{
+			name:      "another secret",
+			secret:    "other",
+			timestamp: "1700000000",
+			body:      payload,
+			want:      "sha256=8acebc5f8f05430cf2a7816beafbde9189161256b082db1deb640ec339dc6456",
+		},
+		{
+			name:      "empty body",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			body:      nil,
+			want:      "sha256=5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, tt.body); got != tt.want {
+				t.Errorf("Sign() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuardDial(t *testing.T) {
+	tests := []struct {
+		address string
+		want    error
+	}{
+		{"93.184.216.34:443", nil},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", nil},
+		{"127.0.0.1:80", errInternalAddress},
+		{"[::1]:80", errInternalAddress},
+		{"10.0.0.5:443", errInternalAddress},
+		{"172.16.3.4:443", errInternalAddress},
+		{"192.168.1.1:443", errInternalAddress},
+		{"169.254.169.254:80", errInternalAddress},
+		{"100.64.0.1:443", errInternalAddress},
+		{"0.0.0.0:80", errInternalAddress},
+		{"[::ffff:127.0.0.1]:80", errInternalAddress},
+		{"[fd00::1]:443", errInternalAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := guardDial("tcp", tt.address, nil); !errors.Is(err, tt.want) {
+				t.Errorf("guardDial(%q) = %v, want %v", tt.address, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/events"
+	"tm-platform-backend/internal/pagination"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminFunc reports whether a user may manage webhooks.
+type AdminFunc func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+type Handler struct {
+	repo       *Repository
+	dispatcher *Dispatcher
+	isAdmin    AdminFunc
+}
+
+func NewHandler(repo *Repository, dispatcher *Dispatcher, isAdmin AdminFunc) *Handler {
+	return &Handler{repo: repo, dispatcher: dispatcher, isAdmin: isAdmin}
+}
+
+// deliveriesPageLimits bound the pages of ListDeliveries
+var deliveriesPageLimits = pagination.Limits{Default: 50, Max: 200}
+
+type createWebhookReq struct {
+	URL         string   `json:"url" validate:"required,max=2000"`
+	Events      []string `json:"events" validate:"max=50"`
+	Description string   `json:"description" validate:"max=500"`
+	Active      *bool    `json:"active"`
+}
+
+type updateWebhookReq struct {
+	URL         *string  `json:"url" validate:"max=2000"`
+	Events      []string `json:"events" validate:"max=50"`
+	Description *string  `json:"description" validate:"max=500"`
+	Active      *bool    `json:"active"`
+	// RotateSecret replaces the secret; the new one is answered once
+	RotateSecret bool `json:"rotateSecret"`
+}
+
+// webhookWithSecret answers the creation of a webhook or the rotation of its
+// secret, the only times the secret is shown.
+type webhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+
+	items, err := h.repo.List(r.Context())
+	if err != nil {
+		log.Printf("list webhooks failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	var req createWebhookReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	endpoint, ok := validateURL(w, req.URL)
+	if !ok {
+		return
+	}
+	filter, ok := validateEvents(w, req.Events)
+	if !ok {
+		return
+	}
+	secret, err := newSecret()
+	if err != nil {
+		log.Printf("generate webhook secret failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+
+	webhook, err := h.repo.Create(r.Context(), userID, Webhook{
+		URL:         endpoint,
+		Secret:      secret,
+		Events:      filter,
+		Description: strings.TrimSpace(req.Description),
+		Active:      req.Active == nil || *req.Active,
+	})
+	if err != nil {
+		log.Printf("create webhook failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, webhookWithSecret{Webhook: webhook, Secret: secret})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+	webhook, ok := h.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+	webhook, ok := h.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateWebhookReq
+	fields, err := request.DecodeFields(r, &req)
+	if err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	if req.URL != nil {
+		if webhook.URL, ok = validateURL(w, *req.URL); !ok {
+			return
+		}
+	}
+	if fields.Has("events") {
+		if webhook.Events, ok = validateEvents(w, req.Events); !ok {
+			return
+		}
+	}
+	if req.Description != nil {
+		webhook.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	webhook.Secret = ""
+	if req.RotateSecret {
+		if webhook.Secret, err = newSecret(); err != nil {
+			log.Printf("generate webhook secret failed: %v", err)
+			problem.Error(w, http.StatusInternalServerError, "failed to update webhook")
+			return
+		}
+	}
+
+	updated, err := h.repo.Update(r.Context(), webhook)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "webhook not found")
+			return
+		}
+		log.Printf("update webhook failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to update webhook")
+		return
+	}
+
+	if req.RotateSecret {
+		writeJSON(w, http.StatusOK, webhookWithSecret{Webhook: updated, Secret: webhook.Secret})
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+	webhookID, ok := uuidParam(w, r, "id", "invalid_webhook_id", "invalid webhook id")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), webhookID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "webhook not found")
+			return
+		}
+		log.Printf("delete webhook failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries is the delivery log of a webhook, newest first.
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+	webhook, ok := h.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	page, err := pagination.Parse(r, deliveriesPageLimits)
+	if err != nil {
+		pagination.WriteError(w, err)
+		return
+	}
+
+	deliveries, err := h.repo.ListDeliveries(r.Context(), webhook.ID, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			pagination.WriteError(w, err)
+			return
+		}
+		log.Printf("list webhook deliveries failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+
+	pagination.SetHeaders(w, r, deliveries)
+	writeJSON(w, http.StatusOK, deliveries.Items)
+}
+
+// Redeliver sends a logged delivery again.
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+	webhook, ok := h.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, ok := uuidParam(w, r, "deliveryId", "invalid_delivery_id", "invalid delivery id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.repo.GetDelivery(r.Context(), webhook.ID, deliveryID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "delivery not found")
+			return
+		}
+		log.Printf("get webhook delivery failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to redeliver")
+		return
+	}
+	if err := h.dispatcher.Redeliver(r.Context(), delivery); err != nil {
+		log.Printf("redeliver webhook delivery failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to redeliver")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
+
+func (h *Handler) loadWebhook(w http.ResponseWriter, r *http.Request) (Webhook, bool) {
+	webhookID, ok := uuidParam(w, r, "id", "invalid_webhook_id", "invalid webhook id")
+	if !ok {
+		return Webhook{}, false
+	}
+
+	webhook, err := h.repo.Get(r.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "webhook not found")
+			return Webhook{}, false
+		}
+		log.Printf("get webhook failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load webhook")
+		return Webhook{}, false
+	}
+	return webhook, true
+}
+
+// authorize answers the request itself unless the requester is an admin.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+
+	allowed, err := h.isAdmin(r.Context(), userID)
+	if err != nil {
+		log.Printf("check webhooks admin failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check access")
+		return uuid.Nil, false
+	}
+	if !allowed {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func validateURL(w http.ResponseWriter, raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		problem.Write(w, http.StatusBadRequest, "invalid_url", "url must be an absolute http or https URL")
+		return "", false
+	}
+	// names are checked again on every delivery, when they are resolved
+	host := strings.ToLower(parsed.Hostname())
+	addr, err := netip.ParseAddr(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || (err == nil && internalAddress(addr)) {
+		problem.Write(w, http.StatusBadRequest, "invalid_url", "url must point to a public address")
+		return "", false
+	}
+	return raw, true
+}
+
+// validateEvents normalizes an event filter; empty means every event.
+func validateEvents(w http.ResponseWriter, names []string) ([]string, bool) {
+	known := make(map[string]struct{}, len(events.Names))
+	for _, name := range events.Names {
+		known[name] = struct{}{}
+	}
+
+	filter := make([]string, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, ok := known[name]; !ok {
+			problem.Write(w, http.StatusBadRequest, "unknown_event", "unknown event: "+name)
+			return nil, false
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		filter = append(filter, name)
+	}
+	return filter, true
+}
+
+func uuidParam(w http.ResponseWriter, r *http.Request, name, code, detail string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, name)))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, code, detail)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,18 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/admin/webhooks", Tag: "admin", Summary: "List webhooks (admins only)", Response: Webhook{}, List: true},
+	{Method: http.MethodPost, Path: "/admin/webhooks", Tag: "admin", Summary: "Register a webhook; the answer carries its signing secret, shown only once", Body: createWebhookReq{}, Status: http.StatusCreated, Response: webhookWithSecret{}},
+	{Method: http.MethodGet, Path: "/admin/webhooks/{id}", Tag: "admin", Summary: "Get a webhook (admins only)", Response: Webhook{}},
+	{Method: http.MethodPatch, Path: "/admin/webhooks/{id}", Tag: "admin", Summary: "Update a webhook or rotate its secret (admins only)", Body: updateWebhookReq{}, Response: webhookWithSecret{}},
+	{Method: http.MethodDelete, Path: "/admin/webhooks/{id}", Tag: "admin", Summary: "Delete a webhook and its delivery log (admins only)", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/admin/webhooks/{id}/deliveries", Tag: "admin", Summary: "List the deliveries of a webhook, newest first (admins only)", Response: Delivery{}, List: true, Page: &deliveriesPageLimits},
+	{Method: http.MethodPost, Path: "/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver", Tag: "admin", Summary: "Send a delivery again (admins only)", Status: http.StatusAccepted, Response: openapi.Status{}},
+}
@@ -0,0 +1,366 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("webhook not found")
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Webhook is an endpoint that receives the domain events of its workspace
+// it filters for, every event when Events is empty.
+type Webhook struct {
+	ID          uuid.UUID  `json:"id"`
+	WorkspaceID uuid.UUID  `json:"-"`
+	URL         string     `json:"url"`
+	Secret      string     `json:"-"`
+	Events      []string   `json:"events"`
+	Description string     `json:"description"`
+	Active      bool       `json:"active"`
+	CreatedBy   *uuid.UUID `json:"createdBy,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// Wants reports whether the webhook receives events named name.
+func (w Webhook) Wants(name string) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event sent, or being sent, to a webhook.
+type Delivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhookId"`
+	WorkspaceID    uuid.UUID       `json:"-"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"responseStatus,omitempty"`
+	LastError      string          `json:"lastError"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const webhookColumns = `id, workspace_id, url, secret, events, description, active, created_by, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (Webhook, error) {
+	var (
+		webhook Webhook
+		events  []byte
+	)
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.WorkspaceID,
+		&webhook.URL,
+		&webhook.Secret,
+		&events,
+		&webhook.Description,
+		&webhook.Active,
+		&webhook.CreatedBy,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return Webhook{}, fmt.Errorf("decode webhook events: %w", err)
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	return webhook, nil
+}
+
+// Create registers webhook in the workspace of ctx.
+func (r *Repository) Create(ctx context.Context, createdBy uuid.UUID, webhook Webhook) (Webhook, error) {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return scanWebhook(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO webhooks (workspace_id, url, secret, events, description, active, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+webhookColumns,
+		db.Workspace(ctx),
+		webhook.URL,
+		webhook.Secret,
+		events,
+		webhook.Description,
+		webhook.Active,
+		createdBy,
+	))
+}
+
+// List returns the webhooks of the workspace of ctx.
+func (r *Repository) List(ctx context.Context) ([]Webhook, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE workspace_id = $1 ORDER BY created_at, id`,
+		db.Workspace(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, webhook)
+	}
+	return items, rows.Err()
+}
+
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (Webhook, error) {
+	webhook, err := scanWebhook(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND workspace_id = $2`,
+		id,
+		db.Workspace(ctx),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	return webhook, err
+}
+
+// Update replaces the settings of a webhook; the secret only when it is set.
+func (r *Repository) Update(ctx context.Context, webhook Webhook) (Webhook, error) {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return Webhook{}, err
+	}
+	updated, err := scanWebhook(r.db.QueryRowContext(
+		ctx,
+		`UPDATE webhooks
+		 SET url = $2,
+		     secret = COALESCE(NULLIF($3, ''), secret),
+		     events = $4,
+		     description = $5,
+		     active = $6,
+		     updated_at = now()
+		 WHERE id = $1 AND workspace_id = $7
+		 RETURNING `+webhookColumns,
+		webhook.ID,
+		webhook.URL,
+		webhook.Secret,
+		events,
+		webhook.Description,
+		webhook.Active,
+		db.Workspace(ctx),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	return updated, err
+}
+
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND workspace_id = $2`, id, db.Workspace(ctx))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const deliveryColumns = `id, webhook_id, workspace_id, event, payload, status, attempts, response_status, last_error, created_at, delivered_at`
+
+func scanDelivery(row scanner) (Delivery, error) {
+	var delivery Delivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.WorkspaceID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	)
+	return delivery, err
+}
+
+// CreateDelivery logs an event to be sent to webhook, in its workspace.
+func (r *Repository) CreateDelivery(ctx context.Context, webhook Webhook, event string, payload []byte) (Delivery, error) {
+	return scanDelivery(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, workspace_id, event, payload)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING `+deliveryColumns,
+		webhook.ID,
+		webhook.WorkspaceID,
+		event,
+		payload,
+	))
+}
+
+func (r *Repository) GetDelivery(ctx context.Context, webhookID, deliveryID uuid.UUID) (Delivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2 AND workspace_id = $3`,
+		deliveryID,
+		webhookID,
+		db.Workspace(ctx),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrNotFound
+	}
+	return delivery, err
+}
+
+// RecordAttempt stores the outcome of one attempt to send a delivery.
+func (r *Repository) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, status DeliveryStatus, responseStatus *int, lastError string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $2,
+		     attempts = attempts + 1,
+		     response_status = $3,
+		     last_error = $4,
+		     delivered_at = CASE WHEN $2 = 'succeeded' THEN now() ELSE delivered_at END
+		 WHERE id = $1`,
+		deliveryID,
+		string(status),
+		responseStatus,
+		lastError,
+	)
+	return err
+}
+
+// ResetDelivery makes a delivery pending again, for a manual redelivery.
+func (r *Repository) ResetDelivery(ctx context.Context, deliveryID uuid.UUID) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries SET status = 'pending', attempts = 0 WHERE id = $1`,
+		deliveryID,
+	)
+	return err
+}
+
+// deliveryKey is the sort key of ListDeliveries
+type deliveryKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// FileWorkspace returns the workspace of the project a file belongs to.
+func (r *Repository) FileWorkspace(ctx context.Context, fileID uuid.UUID) (uuid.UUID, error) {
+	var workspaceID uuid.UUID
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT p.workspace_id
+		 FROM project_files f
+		 JOIN projects p ON p.id = f.project_id
+		 WHERE f.id = $1`,
+		fileID,
+	).Scan(&workspaceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrNotFound
+	}
+	return workspaceID, err
+}
+
+// ListDeliveries returns the deliveries of a webhook, newest first.
+func (r *Repository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, page pagination.Page) (pagination.List[Delivery], error) {
+	var key deliveryKey
+	after, err := page.Key(&key)
+	if err != nil {
+		return pagination.List[Delivery]{}, err
+	}
+
+	query := `SELECT ` + deliveryColumns + `, COUNT(*) OVER ()
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND workspace_id = $3`
+	args := []any{webhookID, page.Fetch(), db.Workspace(ctx)}
+	if after {
+		query += ` AND (created_at, id) < ($4, $5)`
+		args = append(args, key.CreatedAt, key.ID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return pagination.List[Delivery]{}, err
+	}
+	defer rows.Close()
+
+	var total int
+	items := make([]Delivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(pagination.Scanner{Rows: rows, Dest: &total})
+		if err != nil {
+			return pagination.List[Delivery]{}, err
+		}
+		items = append(items, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.List[Delivery]{}, err
+	}
+
+	list, err := pagination.Cut(items, page, func(i int) any {
+		return deliveryKey{CreatedAt: items[i].CreatedAt, ID: items[i].ID}
+	})
+	if err != nil {
+		return pagination.List[Delivery]{}, err
+	}
+	if page.First() {
+		list.Total = &total
+	}
+	return list, nil
+}
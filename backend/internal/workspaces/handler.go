@@ -0,0 +1,310 @@
+// Package workspaces serves the organizations of a deployment. Every request
+// runs in one workspace of its user, picked by Middleware; repositories read
+// it with db.Workspace and only see the projects, org chart, departments and
+// chats of that workspace. Workspace admins manage its members; platform
+// admins create workspaces.
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminFunc reports whether a user may create workspaces.
+type AdminFunc func(ctx context.Context, userID uuid.UUID) (bool, error)
+
+type Handler struct {
+	repo    *Repository
+	isAdmin AdminFunc
+}
+
+func NewHandler(repo *Repository, isAdmin AdminFunc) *Handler {
+	return &Handler{repo: repo, isAdmin: isAdmin}
+}
+
+type createWorkspaceReq struct {
+	Name       string `json:"name" validate:"required,max=120"`
+	OpenSignup bool   `json:"openSignup"`
+}
+
+type updateWorkspaceReq struct {
+	Name       *string `json:"name" validate:"max=120"`
+	OpenSignup *bool   `json:"openSignup"`
+}
+
+type addMemberReq struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"oneof=admin member"`
+}
+
+type updateMemberReq struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.repo.ListForUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("list workspaces failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to list workspaces")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	allowed, err := h.isAdmin(r.Context(), userID)
+	if err != nil {
+		log.Printf("check platform admin failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to check access")
+		return
+	}
+	if !allowed {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return
+	}
+
+	var req createWorkspaceReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	workspace, err := h.repo.Create(r.Context(), userID, req.Name, req.OpenSignup)
+	if err != nil {
+		log.Printf("create workspace failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to create workspace")
+		return
+	}
+	writeJSON(w, http.StatusCreated, workspace)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	workspace, ok := h.loadWorkspace(w, r, userID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, workspace)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	workspace, ok := h.authorizeAdmin(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req updateWorkspaceReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		problem.Write(w, http.StatusBadRequest, "invalid_name", "name must not be blank")
+		return
+	}
+
+	if err := h.repo.Update(r.Context(), workspace.ID, req.Name, req.OpenSignup); err != nil {
+		log.Printf("update workspace failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to update workspace")
+		return
+	}
+	h.Get(w, r)
+}
+
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	workspace, ok := h.loadWorkspace(w, r, userID)
+	if !ok {
+		return
+	}
+
+	members, err := h.repo.ListMembers(r.Context(), workspace.ID)
+	if err != nil {
+		log.Printf("list workspace members failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to list members")
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	workspace, ok := h.authorizeAdmin(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req addMemberReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+	role := RoleMember
+	if req.Role != "" {
+		role = Role(req.Role)
+	}
+
+	member, err := h.repo.AddMember(r.Context(), workspace.ID, req.Email, role)
+	if err != nil {
+		writeMemberError(w, "add workspace member", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, member)
+}
+
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	workspace, ok := h.authorizeAdmin(w, r, userID)
+	if !ok {
+		return
+	}
+	memberID, ok := uuidParam(w, r, "userId", "invalid_user_id", "invalid user id")
+	if !ok {
+		return
+	}
+
+	var req updateMemberReq
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return
+	}
+
+	if err := h.repo.SetMemberRole(r.Context(), workspace.ID, memberID, Role(req.Role)); err != nil {
+		writeMemberError(w, "update workspace member", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requesterID(w, r)
+	if !ok {
+		return
+	}
+	workspace, ok := h.authorizeAdmin(w, r, userID)
+	if !ok {
+		return
+	}
+	memberID, ok := uuidParam(w, r, "userId", "invalid_user_id", "invalid user id")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.RemoveMember(r.Context(), workspace.ID, memberID); err != nil {
+		writeMemberError(w, "remove workspace member", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeMemberError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		problem.Write(w, http.StatusNotFound, "user_not_found", "user not found")
+	case errors.Is(err, ErrNotMember):
+		problem.Write(w, http.StatusNotFound, "member_not_found", "member not found")
+	case errors.Is(err, ErrLastAdmin):
+		problem.Write(w, http.StatusConflict, "last_admin", "workspace must keep an admin")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to update members")
+	}
+}
+
+// loadWorkspace answers the request itself unless the requester is a member
+// of the workspace of the path.
+func (h *Handler) loadWorkspace(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (Workspace, bool) {
+	id, ok := uuidParam(w, r, "id", "invalid_workspace_id", "invalid workspace id")
+	if !ok {
+		return Workspace{}, false
+	}
+	workspace, err := h.repo.Get(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "workspace not found")
+			return Workspace{}, false
+		}
+		log.Printf("get workspace failed: %v", err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load workspace")
+		return Workspace{}, false
+	}
+	return workspace, true
+}
+
+// authorizeAdmin is loadWorkspace for its admins only.
+func (h *Handler) authorizeAdmin(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (Workspace, bool) {
+	workspace, ok := h.loadWorkspace(w, r, userID)
+	if !ok {
+		return Workspace{}, false
+	}
+	if workspace.Role != RoleAdmin {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+		return Workspace{}, false
+	}
+	return workspace, true
+}
+
+func requesterID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func uuidParam(w http.ResponseWriter, r *http.Request, name, code, detail string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, name)))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, code, detail)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,61 @@
+package workspaces
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/db"
+	"tm-platform-backend/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+// Header names the workspace a request runs in.
+const Header = "X-Workspace-ID"
+
+// Middleware scopes each request to a workspace of the requester, see
+// db.WithWorkspace: the one named by Header, else the one they joined first.
+// Naming a workspace they are not in is forbidden. A requester in no
+// workspace goes on unscoped, which the repositories treat as seeing
+// nothing, so they can still list and create workspaces.
+func Middleware(repo *Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userIDStr, _ := auth.UserIDFromContext(r.Context())
+			userID, err := uuid.Parse(strings.TrimSpace(userIDStr))
+			if err != nil {
+				problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+				return
+			}
+
+			var requested *uuid.UUID
+			if raw := strings.TrimSpace(r.Header.Get(Header)); raw != "" {
+				id, err := uuid.Parse(raw)
+				if err != nil {
+					problem.Write(w, http.StatusBadRequest, "invalid_workspace_id", "invalid workspace id")
+					return
+				}
+				requested = &id
+			}
+
+			workspaceID, err := repo.Resolve(r.Context(), userID, requested)
+			switch {
+			case err == nil:
+				r = r.WithContext(db.WithWorkspace(r.Context(), workspaceID))
+			case errors.Is(err, ErrNotFound):
+				// in no workspace yet
+			case errors.Is(err, ErrNotMember):
+				problem.Write(w, http.StatusForbidden, "workspace_forbidden", "not a member of the workspace")
+				return
+			default:
+				log.Printf("resolve workspace failed: %v", err)
+				problem.Error(w, http.StatusInternalServerError, "failed to resolve workspace")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
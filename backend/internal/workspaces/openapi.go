@@ -0,0 +1,19 @@
+package workspaces
+
+import (
+	"net/http"
+
+	"tm-platform-backend/internal/openapi"
+)
+
+// APIOperations lists the endpoints of Handler for the OpenAPI document.
+var APIOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/workspaces", Tag: "workspaces", Summary: "List the workspaces of the current user; send one's id in X-Workspace-ID to work in it", Response: Workspace{}, List: true},
+	{Method: http.MethodPost, Path: "/workspaces", Tag: "workspaces", Summary: "Create a workspace administered by the current user (platform admins only)", Body: createWorkspaceReq{}, Status: http.StatusCreated, Response: Workspace{}},
+	{Method: http.MethodGet, Path: "/workspaces/{id}", Tag: "workspaces", Summary: "Get a workspace of the current user", Response: Workspace{}},
+	{Method: http.MethodPatch, Path: "/workspaces/{id}", Tag: "workspaces", Summary: "Rename a workspace or toggle its open signup (workspace admins only)", Body: updateWorkspaceReq{}, Response: Workspace{}},
+	{Method: http.MethodGet, Path: "/workspaces/{id}/members", Tag: "workspaces", Summary: "List the members of a workspace", Response: Member{}, List: true},
+	{Method: http.MethodPost, Path: "/workspaces/{id}/members", Tag: "workspaces", Summary: "Add a registered user to a workspace by email (workspace admins only)", Body: addMemberReq{}, Response: Member{}},
+	{Method: http.MethodPatch, Path: "/workspaces/{id}/members/{userId}", Tag: "workspaces", Summary: "Change the role of a member (workspace admins only)", Body: updateMemberReq{}, Response: openapi.Status{}},
+	{Method: http.MethodDelete, Path: "/workspaces/{id}/members/{userId}", Tag: "workspaces", Summary: "Remove a member and their places in the workspace's projects, chats and org chart (workspace admins only)", Status: http.StatusNoContent},
+}
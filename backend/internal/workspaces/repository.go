@@ -0,0 +1,480 @@
+package workspaces
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/db"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound     = errors.New("workspace not found")
+	ErrNotMember    = errors.New("not a member of the workspace")
+	ErrUserNotFound = errors.New("user not found")
+	ErrLastAdmin    = errors.New("workspace must keep an admin")
+)
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+// Workspace is an organization served by the deployment, as seen by one of
+// its members.
+type Workspace struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	OpenSignup bool      `json:"openSignup"`
+	Role       Role      `json:"role"` // of the requester
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+type Member struct {
+	UserID   uuid.UUID `json:"userId"`
+	Email    string    `json:"email"`
+	FullName *string   `json:"fullName,omitempty"`
+	Role     Role      `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const workspaceColumns = `w.id, w.name, w.open_signup, wm.role, w.created_at, w.updated_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWorkspace(row scanner) (Workspace, error) {
+	var (
+		workspace Workspace
+		role      string
+	)
+	err := row.Scan(&workspace.ID, &workspace.Name, &workspace.OpenSignup, &role, &workspace.CreatedAt, &workspace.UpdatedAt)
+	workspace.Role = Role(role)
+	return workspace, err
+}
+
+// ListForUser returns the workspaces of userID, the one they joined first
+// leading.
+func (r *Repository) ListForUser(ctx context.Context, userID uuid.UUID) ([]Workspace, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+workspaceColumns+`
+		 FROM workspace_members wm
+		 JOIN workspaces w ON w.id = wm.workspace_id
+		 WHERE wm.user_id = $1
+		 ORDER BY wm.joined_at ASC, w.id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]Workspace, 0)
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, workspace)
+	}
+	return items, rows.Err()
+}
+
+// Get returns workspace id as seen by userID, ErrNotFound unless they are a
+// member.
+func (r *Repository) Get(ctx context.Context, userID, id uuid.UUID) (Workspace, error) {
+	workspace, err := scanWorkspace(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+workspaceColumns+`
+		 FROM workspace_members wm
+		 JOIN workspaces w ON w.id = wm.workspace_id
+		 WHERE wm.workspace_id = $1 AND wm.user_id = $2`,
+		id,
+		userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Workspace{}, ErrNotFound
+	}
+	return workspace, err
+}
+
+// Resolve picks the workspace a request of userID runs in: requested when
+// given, which they must be a member of, else the one they joined first.
+// It returns ErrNotMember for a workspace they are not in and ErrNotFound
+// when they are in none.
+func (r *Repository) Resolve(ctx context.Context, userID uuid.UUID, requested *uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	if requested != nil {
+		err := r.db.QueryRowContext(
+			ctx,
+			`SELECT workspace_id FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+			*requested,
+			userID,
+		).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrNotMember
+		}
+		return id, err
+	}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT workspace_id
+		 FROM workspace_members
+		 WHERE user_id = $1
+		 ORDER BY joined_at ASC, workspace_id ASC
+		 LIMIT 1`,
+		userID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrNotFound
+	}
+	return id, err
+}
+
+// IsAdmin reports whether userID administers workspace id.
+func (r *Repository) IsAdmin(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	var allowed bool
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1 FROM workspace_members
+		 	WHERE workspace_id = $1 AND user_id = $2 AND role = 'admin'
+		 )`,
+		id,
+		userID,
+	).Scan(&allowed)
+	return allowed, err
+}
+
+// IsWorkspaceAdmin reports whether userID administers the workspace the
+// request runs in, see db.Workspace. It is what guards the settings that
+// belong to one workspace.
+func (r *Repository) IsWorkspaceAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var allowed bool
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1 FROM workspace_members
+		 	WHERE workspace_id = $1 AND user_id = $2 AND role = 'admin'
+		 )`,
+		db.Workspace(ctx),
+		userID,
+	).Scan(&allowed)
+	return allowed, err
+}
+
+// IsPlatformAdmin reports whether userID administers the deployment: they
+// are an admin of the workspace the request runs in, and it is the primary
+// one. It guards what every workspace shares, such as the job queue,
+// retention, storage totals and creating workspaces.
+func (r *Repository) IsPlatformAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var allowed bool
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		 	SELECT 1 FROM workspace_members
+		 	WHERE workspace_id = $1 AND user_id = $2 AND role = 'admin'
+		 	  AND workspace_id = primary_workspace_id()
+		 )`,
+		db.Workspace(ctx),
+		userID,
+	).Scan(&allowed)
+	return allowed, err
+}
+
+// Create sets up a workspace administered by createdBy: its org chart root,
+// and the system roles and departments of the primary workspace with their
+// permissions, so a new organization starts where the first one did.
+func (r *Repository) Create(ctx context.Context, createdBy uuid.UUID, name string, openSignup bool) (Workspace, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Workspace{}, err
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	if err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO workspaces (name, open_signup, created_by)
+		 VALUES ($1, $2, $3)
+		 RETURNING id`,
+		strings.TrimSpace(name),
+		openSignup,
+		createdBy,
+	).Scan(&id); err != nil {
+		return Workspace{}, err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'admin')`,
+		id,
+		createdBy,
+	); err != nil {
+		return Workspace{}, err
+	}
+
+	var companyNodeID uuid.UUID
+	if err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO hierarchy_nodes (workspace_id, title, type, parent_id, user_id, position, level, path)
+		 VALUES ($1, $2, 'company', NULL, NULL, 0, 0, '')
+		 RETURNING id`,
+		id,
+		strings.TrimSpace(name),
+	).Scan(&companyNodeID); err != nil {
+		return Workspace{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE hierarchy_nodes SET path = id::text WHERE id = $1`, companyNodeID); err != nil {
+		return Workspace{}, err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO hierarchy_role_catalog (workspace_id, name, is_system, can_manage_hierarchy, can_view_all_projects, can_approve_expenses)
+		 SELECT $1, name, true, can_manage_hierarchy, can_view_all_projects, can_approve_expenses
+		 FROM hierarchy_role_catalog
+		 WHERE workspace_id = primary_workspace_id() AND is_system`,
+		id,
+	); err != nil {
+		return Workspace{}, err
+	}
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO departments (workspace_id, name, is_system)
+		 SELECT $1, name, true
+		 FROM departments
+		 WHERE workspace_id = primary_workspace_id() AND is_system`,
+		id,
+	); err != nil {
+		return Workspace{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Workspace{}, err
+	}
+	return r.Get(ctx, createdBy, id)
+}
+
+// Update renames workspace id or changes its signup; nil leaves a field as
+// it is.
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, name *string, openSignup *bool) error {
+	var trimmed *string
+	if name != nil {
+		value := strings.TrimSpace(*name)
+		trimmed = &value
+	}
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE workspaces
+		 SET name = COALESCE($2, name),
+		     open_signup = COALESCE($3, open_signup),
+		     updated_at = now()
+		 WHERE id = $1`,
+		id,
+		trimmed,
+		openSignup,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) ListMembers(ctx context.Context, id uuid.UUID) ([]Member, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT u.id, u.email, u.full_name, wm.role, wm.joined_at
+		 FROM workspace_members wm
+		 JOIN users u ON u.id = wm.user_id
+		 WHERE wm.workspace_id = $1
+		 ORDER BY wm.role ASC, u.email ASC`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]Member, 0)
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func scanMember(row scanner) (Member, error) {
+	var (
+		member   Member
+		fullName sql.NullString
+		role     string
+	)
+	if err := row.Scan(&member.UserID, &member.Email, &fullName, &role, &member.JoinedAt); err != nil {
+		return Member{}, err
+	}
+	if fullName.Valid && strings.TrimSpace(fullName.String) != "" {
+		name := strings.TrimSpace(fullName.String)
+		member.FullName = &name
+	}
+	member.Role = Role(role)
+	return member, nil
+}
+
+// AddMember adds the user with email to workspace id, or changes their role
+// when they are in it already.
+func (r *Repository) AddMember(ctx context.Context, id uuid.UUID, email string, role Role) (Member, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1)`,
+		strings.TrimSpace(email),
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Member{}, ErrUserNotFound
+	}
+	if err != nil {
+		return Member{}, err
+	}
+
+	if role != RoleAdmin {
+		if err := keepAdmin(ctx, r.db, id, userID); err != nil {
+			return Member{}, err
+		}
+	}
+
+	return scanMember(r.db.QueryRowContext(
+		ctx,
+		`WITH member AS (
+		 	INSERT INTO workspace_members (workspace_id, user_id, role)
+		 	VALUES ($1, $2, $3)
+		 	ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		 	RETURNING user_id, role, joined_at
+		 )
+		 SELECT u.id, u.email, u.full_name, m.role, m.joined_at
+		 FROM member m
+		 JOIN users u ON u.id = m.user_id`,
+		id,
+		userID,
+		string(role),
+	))
+}
+
+// SetMemberRole changes the role of a member of workspace id.
+func (r *Repository) SetMemberRole(ctx context.Context, id, userID uuid.UUID, role Role) error {
+	if role != RoleAdmin {
+		if err := keepAdmin(ctx, r.db, id, userID); err != nil {
+			return err
+		}
+	}
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE workspace_members SET role = $3 WHERE workspace_id = $1 AND user_id = $2`,
+		id,
+		userID,
+		string(role),
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// RemoveMember takes userID out of workspace id along with everything that
+// placed them in it: project and chat memberships and their org chart node.
+// What they created stays with the workspace.
+func (r *Repository) RemoveMember(ctx context.Context, id, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := keepAdmin(ctx, tx, id, userID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotMember
+	}
+
+	statements := []string{
+		`DELETE FROM project_members pm
+		 USING projects p
+		 WHERE p.id = pm.project_id AND p.workspace_id = $1 AND pm.user_id = $2`,
+		`DELETE FROM chat_thread_members tm
+		 USING chat_threads t
+		 WHERE t.id = tm.thread_id AND t.workspace_id = $1 AND tm.user_id = $2`,
+		`DELETE FROM hierarchy_nodes WHERE workspace_id = $1 AND user_id = $2 AND type = 'user'`,
+		`UPDATE hierarchy_nodes SET user_id = NULL WHERE workspace_id = $1 AND user_id = $2`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, id, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// keepAdmin returns ErrLastAdmin when userID is the only admin of workspace
+// id, who cannot step down or leave.
+func keepAdmin(ctx context.Context, q queryRower, id, userID uuid.UUID) error {
+	var others bool
+	err := q.QueryRowContext(
+		ctx,
+		`SELECT
+		 	NOT EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2 AND role = 'admin')
+		 	OR EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id <> $2 AND role = 'admin')`,
+		id,
+		userID,
+	).Scan(&others)
+	if err != nil {
+		return err
+	}
+	if !others {
+		return ErrLastAdmin
+	}
+	return nil
+}
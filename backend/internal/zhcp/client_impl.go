@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -14,22 +15,47 @@ import (
 	"time"
 )
 
+// Operations of the parser API, as reported in CallError
+const (
+	opUpload  = "upload"
+	opStatus  = "status"
+	opResult  = "result"
+	opExtract = "extract"
+)
+
+// Timeouts of single calls to the parser. Uploads stream whole documents,
+// the rest are quick lookups.
+const (
+	uploadTimeout = 2 * time.Minute
+	lookupTimeout = 15 * time.Second
+)
+
+// Client calls the zhcp parser. Each call has its own timeout, lookups are
+// retried on transient failures and a circuit breaker fails calls fast while
+// the parser is down. Errors match ErrParserUnavailable or ErrBadDocument.
 type Client struct {
 	baseURL    string
+	apiKey     string
 	httpClient *http.Client
+	breaker    *breaker
 }
 
-func NewClient(baseURL string) *Client {
+// apiKeyHeader carries the key the parser requires when its auth is enabled
+const apiKeyHeader = "X-API-Key"
+
+// NewClient returns a client of the parser at baseURL. apiKey is sent with
+// every call; it may be empty while the parser runs without auth.
+func NewClient(baseURL, apiKey string) *Client {
 	trimmed := strings.TrimRight(strings.TrimSpace(baseURL), "/")
 	if trimmed == "" {
 		trimmed = "http://localhost:8081"
 	}
 
 	return &Client{
-		baseURL: trimmed,
-		httpClient: &http.Client{
-			Timeout: 45 * time.Second,
-		},
+		baseURL:    trimmed,
+		apiKey:     strings.TrimSpace(apiKey),
+		httpClient: &http.Client{},
+		breaker:    &breaker{},
 	}
 }
 
@@ -38,13 +64,19 @@ type parseUploadResponse struct {
 	Status string `json:"status"`
 }
 
-type parseStatusResponse struct {
+// JobStatus is the state of a parse job as the parser reports it.
+type JobStatus struct {
 	JobID    string `json:"jobId"`
-	Status   string `json:"status"`
+	Status   string `json:"status"` // queued, processing, completed, failed or cancelled
+	Stage    string `json:"stage,omitempty"`
 	Progress int    `json:"progress"`
 	Error    string `json:"error"`
 }
 
+// ErrJobNotFound is returned for parse jobs the parser does not know, e.g.
+// ones that expired.
+var ErrJobNotFound = errors.New("parse job not found")
+
 type ParseResultResponse struct {
 	Success          bool              `json:"success"`
 	ProjectStructure *ProjectStructure `json:"project_structure"`
@@ -74,10 +106,17 @@ type ParsedPhase struct {
 }
 
 type ParsedTask struct {
-	Name      string `json:"name"`
-	Status    string `json:"status"`
-	StartDate string `json:"start_date"`
-	EndDate   string `json:"end_date"`
+	Name               string         `json:"name"`
+	Status             string         `json:"status"`
+	StartDate          string         `json:"start_date"`
+	EndDate            string         `json:"end_date"`
+	ResponsiblePersons []ParsedPerson `json:"responsible_persons,omitempty"`
+}
+
+type ParsedPerson struct {
+	Name    string `json:"name"`
+	Role    string `json:"role,omitempty"`
+	Contact string `json:"contact,omitempty"`
 }
 
 func (c *Client) ParseDocument(ctx context.Context, filename string, contentType string, data []byte) (*ParseResultResponse, error) {
@@ -116,25 +155,16 @@ func (c *Client) upload(ctx context.Context, filename string, contentType string
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		raw, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("parser upload failed: %s", strings.TrimSpace(string(raw)))
-	}
-
 	var payload parseUploadResponse
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+	err = c.call(ctx, opUpload, uploadTimeout, false, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req, nil
+	}, decodeJSON(&payload))
+	if err != nil {
 		return "", err
 	}
 	if strings.TrimSpace(payload.JobID) == "" {
@@ -158,10 +188,11 @@ func (c *Client) waitForCompletion(ctx context.Context, jobID string) error {
 		case "completed":
 			return nil
 		case "failed":
-			if status.Error != "" {
-				return fmt.Errorf("parser failed: %s", status.Error)
+			message := strings.TrimSpace(status.Error)
+			if message == "" {
+				message = "parsing failed"
 			}
-			return fmt.Errorf("parser failed")
+			return &CallError{Op: opStatus, Message: message, Kind: ErrBadDocument}
 		}
 
 		select {
@@ -172,72 +203,173 @@ func (c *Client) waitForCompletion(ctx context.Context, jobID string) error {
 	}
 }
 
-func (c *Client) fetchStatus(ctx context.Context, jobID string) (*parseStatusResponse, error) {
+// Status returns the state of a parse job.
+func (c *Client) Status(ctx context.Context, jobID string) (*JobStatus, error) {
+	return c.fetchStatus(ctx, jobID)
+}
+
+func (c *Client) fetchStatus(ctx context.Context, jobID string) (*JobStatus, error) {
 	endpoint, err := c.joinPath("/api/parse/status/" + jobID)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	var payload JobStatus
+	err = c.call(ctx, opStatus, lookupTimeout, true, getRequest(endpoint), decodeJSON(&payload))
+	var callErr *CallError
+	if errors.As(err, &callErr) && callErr.StatusCode == http.StatusNotFound {
+		return nil, ErrJobNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
+	return &payload, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// Result returns the result of a completed parse job, e.g. one queued by
+// another request.
+func (c *Client) Result(ctx context.Context, jobID string) (*ParseResultResponse, error) {
+	return c.fetchResult(ctx, jobID)
+}
+
+func (c *Client) fetchResult(ctx context.Context, jobID string) (*ParseResultResponse, error) {
+	endpoint, err := c.joinPath("/api/parse/result/" + jobID)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		raw, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("parser status failed: %s", strings.TrimSpace(string(raw)))
+	var payload ParseResultResponse
+	err = c.call(ctx, opResult, lookupTimeout, true, getRequest(endpoint), decodeJSON(&payload))
+	var callErr *CallError
+	if errors.As(err, &callErr) && callErr.StatusCode == http.StatusNotFound {
+		return nil, ErrJobNotFound
 	}
-
-	var payload parseStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+	if err != nil {
 		return nil, err
 	}
+	if !payload.Success {
+		message := "unsuccessful result"
+		if payload.Error != nil && strings.TrimSpace(payload.Error.Message) != "" {
+			message = strings.TrimSpace(payload.Error.Message)
+		}
+		return nil, &CallError{Op: opResult, Message: message, Kind: ErrBadDocument}
+	}
+	if payload.ProjectStructure == nil {
+		return nil, &CallError{Op: opResult, Message: "empty project structure", Kind: ErrBadDocument}
+	}
+
 	return &payload, nil
 }
 
-func (c *Client) fetchResult(ctx context.Context, jobID string) (*ParseResultResponse, error) {
-	endpoint, err := c.joinPath("/api/parse/result/" + jobID)
+// Submit queues a document for parsing and returns the parser's job ID
+// without waiting for the result.
+func (c *Client) Submit(ctx context.Context, filename string, body io.Reader) (string, error) {
+	endpoint, err := c.joinPath("/api/parse/upload")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	var payload parseUploadResponse
+	if err := c.call(ctx, opUpload, uploadTimeout, false, multipartRequest(endpoint, filename, body), decodeJSON(&payload)); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(payload.JobID) == "" {
+		return "", fmt.Errorf("parser upload returned empty job id")
+	}
+
+	return payload.JobID, nil
+}
+
+type extractTextResponse struct {
+	Text   string `json:"text"`
+	Format string `json:"format"`
+}
+
+// ExtractText returns the plain text of a PDF or DOCX document using the
+// parser's extractors.
+func (c *Client) ExtractText(ctx context.Context, filename string, body io.Reader) (string, error) {
+	endpoint, err := c.joinPath("/api/extract/text")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
+	var payload extractTextResponse
+	if err := c.call(ctx, opExtract, uploadTimeout, false, multipartRequest(endpoint, filename, body), decodeJSON(&payload)); err != nil {
+		return "", err
+	}
+	return payload.Text, nil
+}
+
+// Ping asks the parser whether it is ready to parse. It bypasses the
+// circuit breaker, so it reports the parser as it is right now.
+func (c *Client) Ping(ctx context.Context) error {
+	endpoint, err := c.joinPath("/ready")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		raw, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("parser result failed: %s", strings.TrimSpace(string(raw)))
+		return fmt.Errorf("parser answered %d", resp.StatusCode)
 	}
+	return nil
+}
 
-	var payload ParseResultResponse
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return nil, err
-	}
-	if !payload.Success {
-		if payload.Error != nil && strings.TrimSpace(payload.Error.Message) != "" {
-			return nil, fmt.Errorf("parser returned unsuccessful result: %s", payload.Error.Message)
+// multipartRequest posts body as the file of a multipart form, streaming it
+// instead of buffering the document. The body can be sent only once.
+func multipartRequest(endpoint, filename string, body io.Reader) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		pipeReader, pipeWriter := io.Pipe()
+		writer := multipart.NewWriter(pipeWriter)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, pipeReader)
+		if err != nil {
+			_ = pipeReader.Close()
+			return nil, err
 		}
-		return nil, fmt.Errorf("parser returned unsuccessful result")
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		go func() {
+			part, err := writer.CreateFormFile("file", filename)
+			if err == nil {
+				_, err = io.Copy(part, body)
+			}
+			if err == nil {
+				err = writer.Close()
+			}
+			_ = pipeWriter.CloseWithError(err)
+		}()
+		return req, nil
 	}
-	if payload.ProjectStructure == nil {
-		return nil, fmt.Errorf("parser returned empty project structure")
+}
+
+// authorize adds the API key to a request to the parser.
+func (c *Client) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
 	}
+}
 
-	return &payload, nil
+func getRequest(endpoint string) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	}
+}
+
+func decodeJSON(dest any) func(resp *http.Response) error {
+	return func(resp *http.Response) error {
+		return json.NewDecoder(resp.Body).Decode(dest)
+	}
 }
 
 func (c *Client) joinPath(p string) (string, error) {
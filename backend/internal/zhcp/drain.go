@@ -0,0 +1,30 @@
+package zhcp
+
+import (
+	"net/http"
+	"strconv"
+
+	"tm-platform-backend/internal/problem"
+)
+
+// drainRetryAfter is the Retry-After, in seconds, of parse requests refused
+// while the server shuts down
+const drainRetryAfter = 5
+
+// StopAccepting refuses documents sent for parsing from now on, so that no
+// parse is forwarded that shutdown would cut off. Refused requests are
+// answered 503 with Retry-After and succeed against another instance.
+func (h *Handler) StopAccepting() {
+	h.draining.Store(true)
+}
+
+// accepting reports whether the handler still forwards documents to the
+// parser, answering the request itself when it does not.
+func (h *Handler) accepting(w http.ResponseWriter) bool {
+	if !h.draining.Load() {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(drainRetryAfter))
+	problem.Write(w, http.StatusServiceUnavailable, "shutting_down", "server is shutting down, retry shortly")
+	return false
+}
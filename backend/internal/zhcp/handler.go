@@ -3,23 +3,36 @@ package zhcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"tm-platform-backend/internal/auth"
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projectfiles"
 	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/request"
+	"tm-platform-backend/internal/storage"
 
 	"github.com/google/uuid"
 )
 
 type Handler struct {
-	client *Client
-	repo   *projects.Repository
+	client  *Client
+	repo    *projects.Repository
+	jobs    *Repository
+	tracker *Tracker
+	files   *projectfiles.Repository
+	store   storage.Storage
+
+	// draining is set by StopAccepting
+	draining atomic.Bool
 }
 
 type parsedTaskRef struct {
@@ -38,20 +51,23 @@ type createTaskFromContextRequest struct {
 	Cursor        int           `json:"cursor"`
 }
 
-func NewHandler(client *Client, repo *projects.Repository) *Handler {
-	return &Handler{client: client, repo: repo}
+func NewHandler(client *Client, repo *projects.Repository, jobs *Repository, tracker *Tracker, files *projectfiles.Repository, store storage.Storage) *Handler {
+	return &Handler{client: client, repo: repo, jobs: jobs, tracker: tracker, files: files, store: store}
 }
 
 func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
 	userID, ok := h.userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !h.accepting(w) {
 		return
 	}
 
 	input, filename, err := h.parseDocumentFromMultipart(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeParseDocumentError(w, err)
 		return
 	}
 
@@ -65,7 +81,7 @@ func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
 
 	project, stagesCreated, tasksCreated, err := h.createProjectFromParsed(r.Context(), userID, input, budget)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -79,19 +95,28 @@ func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ParseContext(w http.ResponseWriter, r *http.Request) {
-	_, ok := h.userIDFromRequest(r)
+	userID, ok := h.userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !h.accepting(w) {
 		return
 	}
 
 	input, filename, err := h.parseDocumentFromMultipart(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeParseDocumentError(w, err)
 		return
 	}
 
-	startDate, deadline := collectProjectDates(input)
+	loc, err := h.repo.UserLocation(r.Context(), userID)
+	if err != nil {
+		problem.Error(w, http.StatusInternalServerError, "failed to load time zone")
+		return
+	}
+
+	startDate, deadline := collectProjectDates(input, loc)
 	if deadline == nil {
 		now := time.Now().UTC()
 		fallback := now.AddDate(0, 1, 0)
@@ -115,13 +140,13 @@ func (h *Handler) ParseContext(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateProjectFromContext(w http.ResponseWriter, r *http.Request) {
 	userID, ok := h.userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req createFromContextRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
@@ -132,7 +157,7 @@ func (h *Handler) CreateProjectFromContext(w http.ResponseWriter, r *http.Reques
 
 	project, stagesCreated, tasksCreated, err := h.createProjectFromParsed(r.Context(), userID, req.ParsedProject, budget)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		problem.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -147,30 +172,31 @@ func (h *Handler) CreateProjectFromContext(w http.ResponseWriter, r *http.Reques
 func (h *Handler) CreateTaskFromContext(w http.ResponseWriter, r *http.Request) {
 	userID, ok := h.userIDFromRequest(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req createTaskFromContextRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
 		return
 	}
 
 	projectID, err := uuid.Parse(strings.TrimSpace(req.ProjectID))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid projectId"})
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid projectId")
 		return
 	}
 
-	if _, err := h.repo.GetByID(r.Context(), userID, projectID); err != nil {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "project is not accessible"})
+	project, err := h.repo.GetByID(r.Context(), userID, projectID)
+	if err != nil {
+		problem.Write(w, http.StatusForbidden, "project_not_accessible", "project is not accessible")
 		return
 	}
 
 	flat := flattenParsedTasks(req.ParsedProject)
 	if len(flat) == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no tasks in parsed context"})
+		problem.Write(w, http.StatusBadRequest, "no_tasks_in_context", "no tasks in parsed context")
 		return
 	}
 
@@ -190,7 +216,7 @@ func (h *Handler) CreateTaskFromContext(w http.ResponseWriter, r *http.Request)
 
 	stages, err := h.repo.ListStagesByProject(r.Context(), userID, projectID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list stages"})
+		problem.Error(w, http.StatusInternalServerError, "failed to list stages")
 		return
 	}
 
@@ -207,7 +233,7 @@ func (h *Handler) CreateTaskFromContext(w http.ResponseWriter, r *http.Request)
 	if !stageFound {
 		createdStage, createStageErr := h.repo.CreateStage(r.Context(), userID, projectID, stageTitle, len(stages)+1)
 		if createStageErr != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create stage"})
+			problem.Error(w, http.StatusInternalServerError, "failed to create stage")
 			return
 		}
 		stage = createdStage
@@ -215,7 +241,7 @@ func (h *Handler) CreateTaskFromContext(w http.ResponseWriter, r *http.Request)
 
 	stageTasks, err := h.repo.ListTasksByStage(r.Context(), userID, stage.ID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list stage tasks"})
+		problem.Error(w, http.StatusInternalServerError, "failed to list stage tasks")
 		return
 	}
 
@@ -224,13 +250,13 @@ func (h *Handler) CreateTaskFromContext(w http.ResponseWriter, r *http.Request)
 		taskTitle = fmt.Sprintf("Задача %d", cursor+1)
 	}
 
-	taskStart, _ := parseFlexibleDate(selected.Task.StartDate)
-	taskDeadline, _ := parseFlexibleDate(selected.Task.EndDate)
+	taskStart, _ := parseFlexibleDate(selected.Task.StartDate, project.Location(), projects.StartOfDay)
+	taskDeadline, _ := parseFlexibleDate(selected.Task.EndDate, project.Location(), projects.EndOfDay)
 	status := normalizeTaskStatus(selected.Task.Status)
 
 	createdTask, err := h.repo.CreateTask(r.Context(), userID, stage.ID, taskTitle, status, taskStart, taskDeadline, len(stageTasks)+1)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create task"})
+		problem.Error(w, http.StatusInternalServerError, "failed to create task")
 		return
 	}
 
@@ -261,6 +287,17 @@ func (h *Handler) userIDFromRequest(r *http.Request) (uuid.UUID, bool) {
 	return userID, true
 }
 
+// writeParseDocumentError answers a failed parseDocumentFromMultipart: parser
+// outages and rejected documents like writeParserError, the rest as bad
+// requests.
+func writeParseDocumentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrParserUnavailable) || errors.Is(err, ErrBadDocument) {
+		writeParserError(w, err, http.StatusBadRequest)
+		return
+	}
+	problem.Error(w, http.StatusBadRequest, err.Error())
+}
+
 func (h *Handler) parseDocumentFromMultipart(r *http.Request) (ParsedProject, string, error) {
 	if err := r.ParseMultipartForm(20 << 20); err != nil {
 		return ParsedProject{}, "", fmt.Errorf("invalid multipart payload")
@@ -287,7 +324,7 @@ func (h *Handler) parseDocumentFromMultipart(r *http.Request) (ParsedProject, st
 
 	result, err := h.client.ParseDocument(parseCtx, header.Filename, header.Header.Get("Content-Type"), data)
 	if err != nil {
-		return ParsedProject{}, "", fmt.Errorf("zhcp parser error: %v", err)
+		return ParsedProject{}, "", fmt.Errorf("zhcp parser error: %w", err)
 	}
 
 	return result.ProjectStructure.Project, header.Filename, nil
@@ -300,7 +337,12 @@ func (h *Handler) createProjectFromParsed(ctx context.Context, userID uuid.UUID,
 		title = "Новый ЖЦП проект"
 	}
 
-	startDate, deadline := collectProjectDates(input)
+	loc, err := h.repo.UserLocation(ctx, userID)
+	if err != nil {
+		return projects.Project{}, 0, 0, err
+	}
+
+	startDate, deadline := collectProjectDates(input, loc)
 	if deadline == nil {
 		now := time.Now().UTC()
 		fallback := now.AddDate(0, 1, 0)
@@ -322,6 +364,7 @@ func (h *Handler) createProjectFromParsed(ctx context.Context, userID uuid.UUID,
 		Status:      projects.ProjectStatusActive,
 		TotalBudget: budget,
 		Blocks:      []byte("[]"),
+		Timezone:    loc.String(),
 	})
 	if err != nil {
 		return projects.Project{}, 0, 0, fmt.Errorf("failed to create project")
@@ -348,8 +391,8 @@ func (h *Handler) createProjectFromParsed(ctx context.Context, userID uuid.UUID,
 				taskTitle = fmt.Sprintf("Задача %d", j+1)
 			}
 
-			taskStart, _ := parseFlexibleDate(task.StartDate)
-			taskDeadline, _ := parseFlexibleDate(task.EndDate)
+			taskStart, _ := parseFlexibleDate(task.StartDate, loc, projects.StartOfDay)
+			taskDeadline, _ := parseFlexibleDate(task.EndDate, loc, projects.EndOfDay)
 			status := normalizeTaskStatus(task.Status)
 			if _, createTaskErr := h.repo.CreateTask(ctx, userID, stage.ID, taskTitle, status, taskStart, taskDeadline, j+1); createTaskErr == nil {
 				tasksCreated++
@@ -370,27 +413,29 @@ func flattenParsedTasks(project ParsedProject) []parsedTaskRef {
 	return flat
 }
 
-func collectProjectDates(project ParsedProject) (*time.Time, *time.Time) {
+// collectProjectDates returns the earliest start and the latest end of the
+// parse result, bare dates read in loc.
+func collectProjectDates(project ParsedProject, loc *time.Location) (*time.Time, *time.Time) {
 	var start *time.Time
 	var deadline *time.Time
 
-	if parsed, ok := parseFlexibleDate(project.Deadline); ok {
+	if parsed, ok := parseFlexibleDate(project.Deadline, loc, projects.EndOfDay); ok {
 		deadline = parsed
 	}
 
 	for _, phase := range project.Phases {
-		if parsed, ok := parseFlexibleDate(phase.StartDate); ok {
+		if parsed, ok := parseFlexibleDate(phase.StartDate, loc, projects.StartOfDay); ok {
 			start = minDate(start, parsed)
 		}
-		if parsed, ok := parseFlexibleDate(phase.EndDate); ok {
+		if parsed, ok := parseFlexibleDate(phase.EndDate, loc, projects.EndOfDay); ok {
 			deadline = maxDate(deadline, parsed)
 		}
 
 		for _, task := range phase.Tasks {
-			if parsed, ok := parseFlexibleDate(task.StartDate); ok {
+			if parsed, ok := parseFlexibleDate(task.StartDate, loc, projects.StartOfDay); ok {
 				start = minDate(start, parsed)
 			}
-			if parsed, ok := parseFlexibleDate(task.EndDate); ok {
+			if parsed, ok := parseFlexibleDate(task.EndDate, loc, projects.EndOfDay); ok {
 				deadline = maxDate(deadline, parsed)
 			}
 		}
@@ -399,17 +444,25 @@ func collectProjectDates(project ParsedProject) (*time.Time, *time.Time) {
 	return start, deadline
 }
 
-func parseFlexibleDate(raw string) (*time.Time, bool) {
+// parseFlexibleDate parses a date of a parse result. Timestamps keep their
+// instant; bare dates become day of that date in loc, projects.StartOfDay
+// or projects.EndOfDay.
+func parseFlexibleDate(raw string, loc *time.Location, day func(time.Time, *time.Location) time.Time) (*time.Time, bool) {
 	value := strings.TrimSpace(raw)
 	if value == "" {
 		return nil, false
 	}
 
-	layouts := []string{"2006-01-02", time.RFC3339, "02.01.2006", "02/01/2006"}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		normalized := parsed.In(loc)
+		return &normalized, true
+	}
+
+	layouts := []string{"2006-01-02", "02.01.2006", "02/01/2006"}
 	for _, layout := range layouts {
 		parsed, err := time.Parse(layout, value)
 		if err == nil {
-			normalized := parsed.UTC()
+			normalized := day(parsed, loc)
 			return &normalized, true
 		}
 	}
@@ -427,7 +480,7 @@ func normalizeTaskStatus(raw string) string {
 	case "delayed", "delay", "просрочено":
 		return "delayed"
 	default:
-		return "planned"
+		return "todo"
 	}
 }
 
@@ -0,0 +1,149 @@
+package zhcp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projects"
+	"tm-platform-backend/internal/request"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// importResultRequest names the parse result to import: a completed job of
+// the parser, or a project structure the client already holds, e.g. from
+// parse-context. Overrides adjust the mapping shown by the preview.
+type importResultRequest struct {
+	JobID         string         `json:"jobId"`
+	ParsedProject *ParsedProject `json:"parsedProject,omitempty"`
+	importOverrides
+}
+
+// PreviewImport shows what importing a parse result into a project would do
+// without writing anything: stages created or reused, tasks with their
+// matched assignees and date conflicts. Overrides are applied like on import.
+func (h *Handler) PreviewImport(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, req, input, ok := h.decodeImportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	preview, err := planImport(r.Context(), h.repo, userID, projectID, input, req.importOverrides)
+	if err != nil {
+		writeImportPlanError(w, projectID, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// ImportResult imports a completed parse result into an existing project:
+// phases become stages and tasks become stage tasks with their dates, status
+// and matched assignees, all in one transaction.
+func (h *Handler) ImportResult(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, req, input, ok := h.decodeImportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	preview, err := planImport(r.Context(), h.repo, userID, projectID, input, req.importOverrides)
+	if err != nil {
+		writeImportPlanError(w, projectID, err)
+		return
+	}
+
+	stages := preview.importStages()
+	if len(stages) == 0 {
+		problem.Write(w, http.StatusBadRequest, "nothing_to_import", "nothing to import in parse result")
+		return
+	}
+
+	summary, err := h.repo.ImportStages(r.Context(), userID, projectID, stages)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can import parse results")
+		case errors.Is(err, projects.ErrStageNotInProject), errors.Is(err, projects.ErrAssigneeNotMember):
+			problem.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("import parse result into project %s failed: %v", projectID, err)
+			problem.Error(w, http.StatusInternalServerError, "failed to import parse result")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"projectId":     projectID,
+		"stagesCreated": summary.StagesCreated,
+		"stagesReused":  summary.StagesReused,
+		"tasksCreated":  summary.TasksCreated,
+		"stages":        summary.Stages,
+		"conflicts":     preview.Conflicts,
+	})
+}
+
+// decodeImportRequest reads the project and the parse result of an import or
+// its preview, answering the request itself when they are invalid.
+func (h *Handler) decodeImportRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, importResultRequest, ParsedProject, bool) {
+	var req importResultRequest
+
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return uuid.Nil, uuid.Nil, req, ParsedProject{}, false
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return uuid.Nil, uuid.Nil, req, ParsedProject{}, false
+	}
+
+	if err := request.Decode(r, &req); err != nil {
+		request.WriteError(w, err)
+		return uuid.Nil, uuid.Nil, req, ParsedProject{}, false
+	}
+
+	switch {
+	case req.ParsedProject != nil:
+		return userID, projectID, req, *req.ParsedProject, true
+	case strings.TrimSpace(req.JobID) != "":
+		jobID, err := uuid.Parse(strings.TrimSpace(req.JobID))
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, "invalid_job_id", "invalid jobId")
+			return uuid.Nil, uuid.Nil, req, ParsedProject{}, false
+		}
+
+		resultCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		result, err := h.client.Result(resultCtx, jobID.String())
+		if err != nil {
+			writeParserError(w, err, http.StatusBadGateway)
+			return uuid.Nil, uuid.Nil, req, ParsedProject{}, false
+		}
+		return userID, projectID, req, result.ProjectStructure.Project, true
+	default:
+		problem.Write(w, http.StatusBadRequest, "job_id_or_parsed_project_required", "jobId or parsedProject is required")
+		return uuid.Nil, uuid.Nil, req, ParsedProject{}, false
+	}
+}
+
+func writeImportPlanError(w http.ResponseWriter, projectID uuid.UUID, err error) {
+	var overrideErr *overrideError
+	switch {
+	case errors.As(err, &overrideErr):
+		problem.Error(w, http.StatusBadRequest, overrideErr.Error())
+	case errors.Is(err, sql.ErrNoRows):
+		problem.Write(w, http.StatusNotFound, problem.CodeProjectNotFound, "project not found")
+	default:
+		log.Printf("plan parse result import into project %s failed: %v", projectID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to plan parse result import")
+	}
+}
@@ -0,0 +1,261 @@
+package zhcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxSubmitBytes limits documents uploaded for parse-and-import
+const maxSubmitBytes = 32 << 20
+
+// submitExtensions are the documents the parser handles
+var submitExtensions = map[string]bool{
+	".pdf":  true,
+	".docx": true,
+	".xlsx": true,
+	".pptx": true,
+	".txt":  true,
+	".md":   true,
+}
+
+// SubmitDocument sends a document uploaded to a project to the parser and
+// returns at once. The tracker imports the result into the project when the
+// parser completes it and notifies the uploader.
+func (h *Handler) SubmitDocument(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !h.accepting(w) {
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubmitBytes)
+	if err := r.ParseMultipartForm(20 << 20); err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_multipart_payload", "invalid multipart payload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "file_required", "file is required")
+		return
+	}
+	defer file.Close()
+
+	if !submitExtensions[strings.ToLower(filepath.Ext(header.Filename))] {
+		problem.Write(w, http.StatusBadRequest, problem.CodeUnsupportedFormat, "supported formats: .pdf, .docx, .xlsx, .pptx, .txt, .md")
+		return
+	}
+
+	h.submit(w, r, projectID, userID, header.Filename, file, nil, nil)
+}
+
+// ParseFile sends a file already uploaded to the project to the parser like
+// SubmitDocument does, linking the job to the file and its current version.
+func (h *Handler) ParseFile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+	if !h.accepting(w) {
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_project_id", "invalid project id")
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "fileId")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	file, err := h.files.GetFile(r.Context(), userID, fileID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("get project file %s failed: %v", fileID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load file")
+		return
+	}
+	if err != nil || file.ProjectID != projectID {
+		problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+		return
+	}
+
+	if !submitExtensions[strings.ToLower(filepath.Ext(file.Name))] {
+		problem.Write(w, http.StatusBadRequest, problem.CodeUnsupportedFormat, "supported formats: .pdf, .docx, .xlsx, .pptx, .txt, .md")
+		return
+	}
+
+	key, ok := storage.KeyFromURL(file.URL)
+	if !ok {
+		problem.Write(w, http.StatusBadRequest, "file_not_stored", "file is not stored on the platform")
+		return
+	}
+
+	body, info, err := h.store.Open(r.Context(), key)
+	if err != nil {
+		log.Printf("open project file %s failed: %v", fileID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to read file")
+		return
+	}
+	defer body.Close()
+	if info.Size > maxSubmitBytes {
+		problem.Write(w, http.StatusRequestEntityTooLarge, "file_too_large", "file is too large to parse")
+		return
+	}
+
+	version := file.Version
+	h.submit(w, r, projectID, userID, file.Name, body, &file.ID, &version)
+}
+
+// submit sends a document to the parser and starts tracking the job.
+func (h *Handler) submit(w http.ResponseWriter, r *http.Request, projectID, userID uuid.UUID, fileName string, body io.Reader, fileID *uuid.UUID, fileVersion *int) {
+	submitCtx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+	parserJobID, err := h.client.Submit(submitCtx, fileName, body)
+	if err != nil {
+		writeParserError(w, err, http.StatusBadGateway)
+		return
+	}
+
+	job, err := h.jobs.CreateJob(r.Context(), projectID, userID, parserJobID, fileName, fileID, fileVersion)
+	if err != nil {
+		log.Printf("create zhcp parse job for project %s failed: %v", projectID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to track parse job")
+		return
+	}
+	h.tracker.Wake()
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetJob returns a parse-and-import job of the requester, with the import
+// summary once it finished.
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	jobID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_job_id", "invalid job id")
+		return
+	}
+
+	job, err := h.jobs.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "parse_job_not_found", "parse job not found")
+			return
+		}
+		log.Printf("get zhcp parse job %s failed: %v", jobID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load parse job")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// ListFileJobs returns the parse jobs of a project file, newest first, for
+// anyone who can see the file.
+func (h *Handler) ListFileJobs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	fileID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_file_id", "invalid file id")
+		return
+	}
+
+	if _, err := h.files.GetFile(r.Context(), userID, fileID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "file_not_found", "file not found")
+			return
+		}
+		log.Printf("get project file %s failed: %v", fileID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load file")
+		return
+	}
+
+	jobs, err := h.jobs.ListFileJobs(r.Context(), fileID)
+	if err != nil {
+		log.Printf("list zhcp parse jobs of file %s failed: %v", fileID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load parse jobs")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// GetJobResult reopens the parse result a job stored, for any member of its
+// project. The parsedProject it returns can be sent back to preview, import
+// or sync it again.
+func (h *Handler) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	jobID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_job_id", "invalid job id")
+		return
+	}
+
+	job, result, err := h.jobs.GetProjectJob(r.Context(), jobID)
+	if err == nil {
+		_, err = h.repo.GetByID(r.Context(), userID, job.ProjectID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "parse_job_not_found", "parse job not found")
+			return
+		}
+		log.Printf("get zhcp parse job %s result failed: %v", jobID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load parse result")
+		return
+	}
+	if len(result) == 0 {
+		problem.Write(w, http.StatusNotFound, "parse_result_not_stored", "parse job has no stored result")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"job":           job,
+		"parsedProject": json.RawMessage(result),
+	})
+}
@@ -0,0 +1,252 @@
+package zhcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a tracked parse job. A job is queued and processing while the
+// parser works on it, then imported into its project or failed.
+const (
+	JobStatusQueued     = "queued"
+	JobStatusProcessing = "processing"
+	JobStatusImported   = "imported"
+	JobStatusFailed     = "failed"
+)
+
+// Job is a document sent to the parser for a project, tracked until its
+// result is imported. FileID names the project file the document came from,
+// if it was one.
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	ProjectID   uuid.UUID       `json:"projectId"`
+	UserID      uuid.UUID       `json:"userId"`
+	ParserJobID string          `json:"parserJobId"`
+	FileName    string          `json:"fileName"`
+	FileID      *uuid.UUID      `json:"fileId,omitempty"`
+	FileVersion *int            `json:"fileVersion,omitempty"`
+	Status      string          `json:"status"`
+	Stage       string          `json:"stage,omitempty"` // the parser's stage while processing
+	Progress    int             `json:"progress"`
+	Error       string          `json:"error,omitempty"`
+	Summary     json.RawMessage `json:"summary,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// Finished reports whether the job was imported or failed.
+func (j Job) Finished() bool {
+	return j.Status == JobStatusImported || j.Status == JobStatusFailed
+}
+
+const jobColumns = `id, project_id, user_id, parser_job_id, file_name, file_id, file_version, status, stage, progress, error, summary, created_at, updated_at, finished_at`
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanJob scans the job columns followed by any extra destinations.
+func scanJob(scanner rowScanner, extra ...any) (Job, error) {
+	var (
+		job     Job
+		summary []byte
+	)
+	dest := []any{
+		&job.ID,
+		&job.ProjectID,
+		&job.UserID,
+		&job.ParserJobID,
+		&job.FileName,
+		&job.FileID,
+		&job.FileVersion,
+		&job.Status,
+		&job.Stage,
+		&job.Progress,
+		&job.Error,
+		&summary,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return Job{}, err
+	}
+	if len(summary) > 0 {
+		job.Summary = summary
+	}
+	return job, nil
+}
+
+// CreateJob starts tracking a parser job submitted by a user for a project.
+// fileID and fileVersion are nil for documents uploaded only to be parsed.
+func (r *Repository) CreateJob(ctx context.Context, projectID, userID uuid.UUID, parserJobID, fileName string, fileID *uuid.UUID, fileVersion *int) (Job, error) {
+	return scanJob(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO zhcp_parse_jobs (project_id, user_id, parser_job_id, file_name, file_id, file_version)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+jobColumns,
+		projectID,
+		userID,
+		parserJobID,
+		fileName,
+		fileID,
+		fileVersion,
+	))
+}
+
+// GetJob returns a job of the user, or sql.ErrNoRows.
+func (r *Repository) GetJob(ctx context.Context, userID, jobID uuid.UUID) (Job, error) {
+	return scanJob(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+jobColumns+`
+		 FROM zhcp_parse_jobs
+		 WHERE id = $1 AND user_id = $2`,
+		jobID,
+		userID,
+	))
+}
+
+// GetProjectJob returns a job by ID whoever submitted it, with the parse
+// result it stored, for callers that check project access themselves.
+func (r *Repository) GetProjectJob(ctx context.Context, jobID uuid.UUID) (Job, json.RawMessage, error) {
+	var result []byte
+	job, err := scanJob(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+jobColumns+`, result
+		 FROM zhcp_parse_jobs
+		 WHERE id = $1`,
+		jobID,
+	), &result)
+	if err != nil {
+		return Job{}, nil, err
+	}
+	return job, result, nil
+}
+
+// ListFileJobs returns the jobs of a project file, newest first.
+func (r *Repository) ListFileJobs(ctx context.Context, fileID uuid.UUID) ([]Job, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+jobColumns+`
+		 FROM zhcp_parse_jobs
+		 WHERE file_id = $1
+		 ORDER BY created_at DESC`,
+		fileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// SaveJobResult keeps the parse result of a job so it can be reopened after
+// the parser forgets it.
+func (r *Repository) SaveJobResult(ctx context.Context, jobID uuid.UUID, result any) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(
+		ctx,
+		`UPDATE zhcp_parse_jobs SET result = $2::jsonb WHERE id = $1`,
+		jobID,
+		payload,
+	)
+	return err
+}
+
+// ListPendingJobs returns the jobs the parser still works on, least recently
+// checked first.
+func (r *Repository) ListPendingJobs(ctx context.Context, limit int) ([]Job, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+jobColumns+`
+		 FROM zhcp_parse_jobs
+		 WHERE status IN ('queued', 'processing')
+		 ORDER BY updated_at
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateJobProgress records the status, stage and progress the parser
+// reports for a pending job.
+func (r *Repository) UpdateJobProgress(ctx context.Context, jobID uuid.UUID, status, stage string, progress int) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE zhcp_parse_jobs
+		 SET status = $2, stage = $3, progress = $4, updated_at = now()
+		 WHERE id = $1 AND status IN ('queued', 'processing')`,
+		jobID,
+		status,
+		stage,
+		progress,
+	)
+	return err
+}
+
+// FinishJob marks a pending job imported with the summary of the import, or
+// failed with its error.
+func (r *Repository) FinishJob(ctx context.Context, jobID uuid.UUID, status string, summary any, errMessage string) error {
+	var payload []byte
+	if summary != nil {
+		var err error
+		if payload, err = json.Marshal(summary); err != nil {
+			return err
+		}
+	}
+
+	progress := 0
+	if status == JobStatusImported {
+		progress = 100
+	}
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE zhcp_parse_jobs
+		 SET status = $2, stage = '', progress = $3, summary = $4::jsonb, error = $5, updated_at = now(), finished_at = now()
+		 WHERE id = $1 AND status IN ('queued', 'processing')`,
+		jobID,
+		status,
+		progress,
+		payload,
+		errMessage,
+	)
+	return err
+}
@@ -0,0 +1,404 @@
+package zhcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/projects"
+
+	"github.com/google/uuid"
+)
+
+// Actions of a previewed stage
+const (
+	stageActionCreate = "create"
+	stageActionReuse  = "reuse"
+	stageActionSkip   = "skip"
+)
+
+// emailPattern finds an email in the contact of a responsible person
+var emailPattern = regexp.MustCompile(`[^\s,;<>()]+@[^\s,;<>()]+\.[^\s,;<>()]+`)
+
+// importOverrides adjust the import of a parse result. Stages and tasks are
+// named by the keys of the preview: p1 for the first phase, p1.t2 for its
+// second task.
+type importOverrides struct {
+	Stages []stageOverride `json:"stages,omitempty"`
+	Tasks  []taskOverride  `json:"tasks,omitempty"`
+}
+
+type stageOverride struct {
+	Key     string  `json:"key"`
+	Title   *string `json:"title,omitempty"`
+	Skip    bool    `json:"skip,omitempty"`
+	StageID string  `json:"stageId,omitempty"` // import into this existing stage instead
+}
+
+type taskOverride struct {
+	Key       string    `json:"key"`
+	Title     *string   `json:"title,omitempty"`
+	Skip      bool      `json:"skip,omitempty"`
+	StageKey  string    `json:"stageKey,omitempty"`  // move to another phase of the result
+	Assignees *[]string `json:"assignees,omitempty"` // emails replacing the detected matches
+}
+
+// importPreview is what importing a parse result into a project would do.
+type importPreview struct {
+	ProjectID          uuid.UUID      `json:"projectId"`
+	Stages             []previewStage `json:"stages"`
+	StagesToCreate     int            `json:"stagesToCreate"`
+	StagesToReuse      int            `json:"stagesToReuse"`
+	TasksToCreate      int            `json:"tasksToCreate"`
+	TasksSkipped       int            `json:"tasksSkipped"`
+	AssigneesMatched   int            `json:"assigneesMatched"`
+	AssigneesUnmatched int            `json:"assigneesUnmatched"`
+	Conflicts          []dateConflict `json:"conflicts"`
+
+	loc *time.Location // zone of the project, bare dates are read in
+}
+
+type previewStage struct {
+	Key       string        `json:"key"`
+	Title     string        `json:"title"`
+	Action    string        `json:"action"`            // create, reuse or skip
+	StageID   *uuid.UUID    `json:"stageId,omitempty"` // existing stage that gets the tasks
+	StartDate *time.Time    `json:"startDate,omitempty"`
+	EndDate   *time.Time    `json:"endDate,omitempty"`
+	Tasks     []previewTask `json:"tasks"`
+}
+
+type previewTask struct {
+	Key         string          `json:"key"`
+	Title       string          `json:"title"`
+	Status      string          `json:"status"`
+	StartDate   *time.Time      `json:"startDate,omitempty"`
+	Deadline    *time.Time      `json:"deadline,omitempty"`
+	Skip        bool            `json:"skip,omitempty"`
+	Responsible []assigneeMatch `json:"responsible"`
+	Assignees   []string        `json:"assignees"`
+}
+
+// assigneeMatch is a responsible person of the document with the project
+// member found for them, if any.
+type assigneeMatch struct {
+	Name      string     `json:"name"`
+	Contact   string     `json:"contact,omitempty"`
+	UserID    *uuid.UUID `json:"userId,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	MatchedBy string     `json:"matchedBy,omitempty"` // email or name
+}
+
+// dateConflict is a date of the result that contradicts another one
+type dateConflict struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// overrideError is an override that does not fit the parse result or the
+// project, answered with 400.
+type overrideError struct {
+	message string
+}
+
+func (e *overrideError) Error() string {
+	return e.message
+}
+
+// planImport previews the import of a parse result into a project with the
+// overrides applied. The same plan is what ImportResult writes.
+func planImport(ctx context.Context, repo *projects.Repository, userID, projectID uuid.UUID, input ParsedProject, overrides importOverrides) (importPreview, error) {
+	project, err := repo.GetByID(ctx, userID, projectID)
+	if err != nil {
+		return importPreview{}, err
+	}
+	stages, err := repo.ListStagesByProject(ctx, userID, projectID)
+	if err != nil {
+		return importPreview{}, err
+	}
+	members, err := repo.ListMembersByProject(ctx, userID, projectID)
+	if err != nil {
+		return importPreview{}, err
+	}
+
+	stageOverrides := make(map[string]stageOverride, len(overrides.Stages))
+	for _, override := range overrides.Stages {
+		stageOverrides[override.Key] = override
+	}
+	taskOverrides := make(map[string]taskOverride, len(overrides.Tasks))
+	for _, override := range overrides.Tasks {
+		taskOverrides[override.Key] = override
+	}
+
+	existingByID := make(map[uuid.UUID]projects.Stage, len(stages))
+	existingByTitle := make(map[string]projects.Stage, len(stages))
+	for _, stage := range stages {
+		existingByID[stage.ID] = stage
+		key := strings.ToLower(strings.TrimSpace(stage.Title))
+		if _, ok := existingByTitle[key]; !ok {
+			existingByTitle[key] = stage
+		}
+	}
+
+	preview := importPreview{
+		ProjectID: projectID,
+		Stages:    make([]previewStage, 0, len(input.Phases)),
+		Conflicts: make([]dateConflict, 0),
+		loc:       project.Location(),
+	}
+	stageIndex := make(map[string]int, len(input.Phases))
+	planned := make(map[string]bool)
+
+	for i, phase := range input.Phases {
+		stage := previewStage{
+			Key:    fmt.Sprintf("p%d", i+1),
+			Title:  strings.TrimSpace(phase.Name),
+			Action: stageActionCreate,
+			Tasks:  make([]previewTask, 0, len(phase.Tasks)),
+		}
+		if stage.Title == "" {
+			stage.Title = fmt.Sprintf("Этап %d", i+1)
+		}
+		stage.StartDate = preview.parseDate(stage.Key, "start date", phase.StartDate, projects.StartOfDay)
+		stage.EndDate = preview.parseDate(stage.Key, "end date", phase.EndDate, projects.EndOfDay)
+		if stage.StartDate != nil && stage.EndDate != nil && stage.StartDate.After(*stage.EndDate) {
+			preview.conflict(stage.Key, "stage starts after it ends")
+		}
+
+		override, ok := stageOverrides[stage.Key]
+		delete(stageOverrides, stage.Key)
+		if ok && override.Title != nil {
+			if title := strings.TrimSpace(*override.Title); title != "" {
+				stage.Title = title
+			}
+		}
+
+		switch {
+		case ok && override.Skip:
+			stage.Action = stageActionSkip
+		case ok && strings.TrimSpace(override.StageID) != "":
+			id, err := uuid.Parse(strings.TrimSpace(override.StageID))
+			existing, found := existingByID[id]
+			if err != nil || !found {
+				return importPreview{}, &overrideError{fmt.Sprintf("stage %s: stageId is not a stage of the project", stage.Key)}
+			}
+			stage.Action = stageActionReuse
+			stage.StageID = &existing.ID
+			stage.Title = existing.Title
+		default:
+			titleKey := strings.ToLower(stage.Title)
+			if existing, found := existingByTitle[titleKey]; found {
+				stage.Action = stageActionReuse
+				stage.StageID = &existing.ID
+			} else if planned[titleKey] {
+				stage.Action = stageActionReuse
+			}
+			planned[titleKey] = true
+		}
+
+		stageIndex[stage.Key] = len(preview.Stages)
+		preview.Stages = append(preview.Stages, stage)
+	}
+	for key := range stageOverrides {
+		return importPreview{}, &overrideError{fmt.Sprintf("unknown stage %q", key)}
+	}
+
+	for i, phase := range input.Phases {
+		phaseStage := preview.Stages[i]
+		for j, parsed := range phase.Tasks {
+			task := previewTask{
+				Key:         fmt.Sprintf("%s.t%d", phaseStage.Key, j+1),
+				Title:       strings.TrimSpace(parsed.Name),
+				Status:      normalizeTaskStatus(parsed.Status),
+				Responsible: make([]assigneeMatch, 0, len(parsed.ResponsiblePersons)),
+				Assignees:   make([]string, 0),
+			}
+			if task.Title == "" {
+				task.Title = fmt.Sprintf("Задача %d", j+1)
+			}
+			task.StartDate = preview.parseDate(task.Key, "start date", parsed.StartDate, projects.StartOfDay)
+			task.Deadline = preview.parseDate(task.Key, "end date", parsed.EndDate, projects.EndOfDay)
+			preview.checkTaskDates(task, phaseStage, project.Deadline)
+
+			for _, person := range parsed.ResponsiblePersons {
+				match := matchAssignee(person, members)
+				if match.UserID != nil {
+					task.Assignees = appendUnique(task.Assignees, match.Email)
+				}
+				task.Responsible = append(task.Responsible, match)
+			}
+
+			target := i
+			override, ok := taskOverrides[task.Key]
+			delete(taskOverrides, task.Key)
+			if ok {
+				if override.Title != nil {
+					if title := strings.TrimSpace(*override.Title); title != "" {
+						task.Title = title
+					}
+				}
+				task.Skip = override.Skip
+				if override.StageKey != "" {
+					index, found := stageIndex[override.StageKey]
+					if !found {
+						return importPreview{}, &overrideError{fmt.Sprintf("task %s: unknown stage %q", task.Key, override.StageKey)}
+					}
+					target = index
+				}
+				if override.Assignees != nil {
+					task.Assignees = make([]string, 0, len(*override.Assignees))
+					for _, email := range *override.Assignees {
+						if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+							task.Assignees = appendUnique(task.Assignees, email)
+						}
+					}
+				}
+			}
+			if preview.Stages[target].Action == stageActionSkip {
+				task.Skip = true
+			}
+
+			preview.Stages[target].Tasks = append(preview.Stages[target].Tasks, task)
+		}
+	}
+	for key := range taskOverrides {
+		return importPreview{}, &overrideError{fmt.Sprintf("unknown task %q", key)}
+	}
+
+	for _, stage := range preview.Stages {
+		switch stage.Action {
+		case stageActionCreate:
+			preview.StagesToCreate++
+		case stageActionReuse:
+			preview.StagesToReuse++
+		}
+		for _, task := range stage.Tasks {
+			if task.Skip {
+				preview.TasksSkipped++
+				continue
+			}
+			preview.TasksToCreate++
+			for _, match := range task.Responsible {
+				if match.UserID != nil {
+					preview.AssigneesMatched++
+				} else {
+					preview.AssigneesUnmatched++
+				}
+			}
+		}
+	}
+
+	return preview, nil
+}
+
+// importStages returns the stages and tasks the preview would create, leaving
+// out skipped ones.
+func (p importPreview) importStages() []projects.ImportStage {
+	stages := make([]projects.ImportStage, 0, len(p.Stages))
+	for _, stage := range p.Stages {
+		if stage.Action == stageActionSkip {
+			continue
+		}
+		imported := projects.ImportStage{
+			Title:   stage.Title,
+			StageID: stage.StageID,
+			Tasks:   make([]projects.ImportTask, 0, len(stage.Tasks)),
+		}
+		for _, task := range stage.Tasks {
+			if task.Skip {
+				continue
+			}
+			imported.Tasks = append(imported.Tasks, projects.ImportTask{
+				Title:     task.Title,
+				Status:    task.Status,
+				StartDate: task.StartDate,
+				Deadline:  task.Deadline,
+				Assignees: task.Assignees,
+			})
+		}
+		stages = append(stages, imported)
+	}
+	return stages
+}
+
+// parseDate parses a date of the result, reporting ones it cannot read
+func (p *importPreview) parseDate(key, field, raw string, day func(time.Time, *time.Location) time.Time) *time.Time {
+	parsed, ok := parseFlexibleDate(raw, p.loc, day)
+	if !ok && strings.TrimSpace(raw) != "" {
+		p.conflict(key, fmt.Sprintf("unrecognized %s %q", field, strings.TrimSpace(raw)))
+	}
+	return parsed
+}
+
+func (p *importPreview) checkTaskDates(task previewTask, stage previewStage, projectDeadline *time.Time) {
+	if task.StartDate != nil && task.Deadline != nil && task.StartDate.After(*task.Deadline) {
+		p.conflict(task.Key, "task starts after its deadline")
+	}
+	if task.StartDate != nil && stage.StartDate != nil && task.StartDate.Before(*stage.StartDate) {
+		p.conflict(task.Key, "task starts before its stage")
+	}
+	if task.Deadline != nil && stage.EndDate != nil && task.Deadline.After(*stage.EndDate) {
+		p.conflict(task.Key, "task ends after its stage")
+	}
+	if task.Deadline != nil && projectDeadline != nil && task.Deadline.After(*projectDeadline) {
+		p.conflict(task.Key, "task deadline is after the project deadline "+projectDeadline.Format("2006-01-02"))
+	}
+}
+
+func (p *importPreview) conflict(key, message string) {
+	p.Conflicts = append(p.Conflicts, dateConflict{Key: key, Message: message})
+}
+
+// matchAssignee finds the project member a responsible person refers to: by
+// an email in their contact first, then by full name in any word order.
+func matchAssignee(person ParsedPerson, members []projects.ProjectMemberResponse) assigneeMatch {
+	match := assigneeMatch{Name: strings.TrimSpace(person.Name), Contact: strings.TrimSpace(person.Contact)}
+
+	if email := strings.ToLower(emailPattern.FindString(person.Contact)); email != "" {
+		for _, member := range members {
+			if strings.ToLower(member.User.Email) == email {
+				match.setMember(member, "email")
+				return match
+			}
+		}
+	}
+
+	name := nameKey(person.Name)
+	if name == "" {
+		return match
+	}
+	for _, member := range members {
+		if nameKey(member.User.FullName) == name {
+			match.setMember(member, "name")
+			return match
+		}
+	}
+	return match
+}
+
+func (m *assigneeMatch) setMember(member projects.ProjectMemberResponse, by string) {
+	id := member.User.ID
+	m.UserID = &id
+	m.Email = strings.ToLower(member.User.Email)
+	m.MatchedBy = by
+}
+
+// nameKey normalizes a full name so "Иванов Иван" and "иван  иванов" compare
+// equal
+func nameKey(name string) string {
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(name, "ё", "е")))
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,225 @@
+package zhcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tm-platform-backend/internal/problem"
+)
+
+// Categories of parser errors, matched with errors.Is
+var (
+	// ErrParserUnavailable is returned when the parser cannot be reached,
+	// times out, answers with a server error or the circuit breaker is open.
+	// Trying again later may succeed.
+	ErrParserUnavailable = errors.New("zhcp parser is unavailable")
+	// ErrBadDocument is returned when the parser rejects a document or fails
+	// to parse it. Trying again will not help.
+	ErrBadDocument = errors.New("zhcp parser rejected the document")
+)
+
+// CallError is an error of a call to the parser.
+type CallError struct {
+	Op         string // upload, status, result or extract
+	StatusCode int    // HTTP status of the parser's answer, 0 without one
+	Message    string
+	Kind       error // ErrParserUnavailable, ErrBadDocument or nil
+	RetryAfter time.Duration
+}
+
+func (e *CallError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("parser %s failed (%d): %s", e.Op, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("parser %s failed: %s", e.Op, e.Message)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Kind
+}
+
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+
+	retryAttempts = 3
+	retryBackoff  = 250 * time.Millisecond
+)
+
+// breaker stops calls to the parser after consecutive failures until a
+// cooldown passes, then lets a single call through to probe it.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns a CallError while the breaker is open.
+func (b *breaker) allow(op string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) || b.probing {
+		wait := time.Until(b.openUntil)
+		if wait < time.Second {
+			wait = time.Second
+		}
+		return &CallError{Op: op, Message: "circuit breaker is open", Kind: ErrParserUnavailable, RetryAfter: wait}
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the outcome of a call the breaker allowed. Only failures of
+// the parser itself count, not rejected documents.
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil || !errors.Is(err, ErrParserUnavailable) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// release ends a probe without an outcome.
+func (b *breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// call sends a request built by newRequest with a timeout of its own and
+// hands a successful answer to decode. Idempotent calls are retried on
+// transient failures with exponential backoff.
+func (c *Client) call(ctx context.Context, op string, timeout time.Duration, idempotent bool, newRequest func(ctx context.Context) (*http.Request, error), decode func(resp *http.Response) error) error {
+	attempts := 1
+	if idempotent {
+		attempts = retryAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryBackoff << (attempt - 1)):
+			}
+		}
+
+		if err = c.breaker.allow(op); err != nil {
+			return err
+		}
+		err = c.attempt(ctx, op, timeout, newRequest, decode)
+		if ctx.Err() != nil {
+			// The caller gave up, which says nothing about the parser
+			c.breaker.release()
+			return ctx.Err()
+		}
+		c.breaker.record(err)
+		if !errors.Is(err, ErrParserUnavailable) {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *Client) attempt(ctx context.Context, op string, timeout time.Duration, newRequest func(ctx context.Context) (*http.Request, error), decode func(resp *http.Response) error) error {
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := newRequest(callCtx)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &CallError{Op: op, Message: err.Error(), Kind: ErrParserUnavailable}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(op, resp)
+	}
+	if err := decode(resp); err != nil {
+		if callCtx.Err() != nil {
+			return &CallError{Op: op, Message: err.Error(), Kind: ErrParserUnavailable}
+		}
+		return err
+	}
+	return nil
+}
+
+// responseError classifies an unsuccessful answer of the parser: server
+// errors and throttling mean it is unavailable, client errors on documents
+// mean it rejected them.
+func responseError(op string, resp *http.Response) error {
+	err := &CallError{Op: op, StatusCode: resp.StatusCode, Message: readErrorMessage(resp)}
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusRequestTimeout:
+		err.Kind = ErrParserUnavailable
+		if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+			err.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	case op == opUpload || op == opExtract:
+		err.Kind = ErrBadDocument
+	}
+	return err
+}
+
+// readErrorMessage returns the error the parser answered with, which it
+// sends as {"error": "..."}.
+func readErrorMessage(resp *http.Response) string {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &payload) == nil && strings.TrimSpace(payload.Error) != "" {
+		return strings.TrimSpace(payload.Error)
+	}
+	if message := strings.TrimSpace(string(raw)); message != "" {
+		return message
+	}
+	return http.StatusText(resp.StatusCode)
+}
+
+// writeParserError answers a request that failed on the parser: 503 with
+// Retry-After when it is unavailable, 422 when it rejected the document and
+// fallback otherwise.
+func writeParserError(w http.ResponseWriter, err error, fallback int) {
+	var callErr *CallError
+	switch {
+	case errors.Is(err, ErrParserUnavailable):
+		retryAfter := breakerCooldown
+		if errors.As(err, &callErr) && callErr.RetryAfter > 0 {
+			retryAfter = callErr.RetryAfter
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+		problem.Write(w, http.StatusServiceUnavailable, "parser_unavailable", "document parser is temporarily unavailable")
+	case errors.Is(err, ErrBadDocument):
+		problem.Write(w, http.StatusUnprocessableEntity, "bad_document", fmt.Sprintf("document could not be parsed: %v", err))
+	default:
+		problem.Error(w, fallback, fmt.Sprintf("zhcp parser error: %v", err))
+	}
+}
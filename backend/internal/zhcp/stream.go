@@ -0,0 +1,165 @@
+package zhcp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tm-platform-backend/internal/problem"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// streamHeartbeat keeps idle job event streams alive through proxies
+const streamHeartbeat = 15 * time.Second
+
+// streamReconnectDelay is how long clients wait before reopening a stream
+// closed by shutdown, long enough for the load balancer to route them to
+// another instance
+const streamReconnectDelay = 3 * time.Second
+
+// jobEvent is the state of a job sent with each stream event
+type jobEvent struct {
+	JobID    uuid.UUID       `json:"jobId"`
+	Status   string          `json:"status"`
+	Stage    string          `json:"stage,omitempty"`
+	Progress int             `json:"progress"`
+	Error    string          `json:"error,omitempty"`
+	Summary  json.RawMessage `json:"summary,omitempty"`
+}
+
+// reconnectEvent asks the client to open the stream again after RetryMs
+type reconnectEvent struct {
+	JobID   uuid.UUID `json:"jobId"`
+	RetryMs int64     `json:"retryMs"`
+}
+
+// StreamJob relays the progress of a parse-and-import job of the requester
+// as server-sent events, so browsers follow the parser through the platform
+// API instead of calling zhcp-server themselves: "progress" for every
+// change, then "imported" or "failed" and the stream ends. The stream is
+// authenticated like any other request, so clients read it with fetch.
+// When the server shuts down the stream ends with "reconnect" instead,
+// telling clients to open it again after retryMs.
+func (h *Handler) StreamJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unauthorized")
+		return
+	}
+
+	jobID, err := uuid.Parse(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_job_id", "invalid job id")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem.Error(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	// Subscribe before the first read so no update is lost in between
+	updates, unsubscribe := h.tracker.Subscribe(jobID)
+	defer unsubscribe()
+
+	job, err := h.jobs.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			problem.Write(w, http.StatusNotFound, "parse_job_not_found", "parse job not found")
+			return
+		}
+		log.Printf("get zhcp parse job %s failed: %v", jobID, err)
+		problem.Error(w, http.StatusInternalServerError, "failed to load parse job")
+		return
+	}
+
+	// The server write timeout would cut off long-running jobs
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("clear write deadline for stream of zhcp parse job %s failed: %v", jobID, err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	// Another instance's tracker may advance the job without signalling
+	// this one, so the job is reloaded at the tracker interval as well.
+	reload := time.NewTicker(h.tracker.interval)
+	defer reload.Stop()
+
+	var last *jobEvent
+	for {
+		event := jobEvent{
+			JobID:    job.ID,
+			Status:   job.Status,
+			Stage:    job.Stage,
+			Progress: job.Progress,
+			Error:    job.Error,
+			Summary:  job.Summary,
+		}
+		if last == nil || event.Status != last.Status || event.Stage != last.Stage || event.Progress != last.Progress {
+			name := "progress"
+			if job.Finished() {
+				name = job.Status
+			}
+			if err := writeEvent(w, name, event); err != nil {
+				return
+			}
+			flusher.Flush()
+			if job.Finished() {
+				return
+			}
+			last = &event
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.tracker.StreamsDone():
+			reconnect := reconnectEvent{JobID: job.ID, RetryMs: streamReconnectDelay.Milliseconds()}
+			if _, err := fmt.Fprintf(w, "retry: %d\n", streamReconnectDelay.Milliseconds()); err != nil {
+				return
+			}
+			if err := writeEvent(w, "reconnect", reconnect); err == nil {
+				flusher.Flush()
+			}
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		case <-updates:
+		case <-reload.C:
+		}
+
+		if job, err = h.jobs.GetJob(r.Context(), userID, jobID); err != nil {
+			if r.Context().Err() == nil {
+				log.Printf("reload zhcp parse job %s failed: %v", jobID, err)
+			}
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
@@ -0,0 +1,59 @@
+package zhcp
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"tm-platform-backend/internal/problem"
+	"tm-platform-backend/internal/projects"
+)
+
+// SyncResult applies an updated version of a document to a project it was
+// imported into before: new tasks are added and moved dates or changed
+// statuses are written, while tasks edited in the project since the last
+// import come back as conflicts. Takes the same payload as ImportResult.
+func (h *Handler) SyncResult(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, req, input, ok := h.decodeImportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	preview, err := planImport(r.Context(), h.repo, userID, projectID, input, req.importOverrides)
+	if err != nil {
+		writeImportPlanError(w, projectID, err)
+		return
+	}
+
+	stages := preview.importStages()
+	if len(stages) == 0 {
+		problem.Write(w, http.StatusBadRequest, "nothing_to_sync", "nothing to sync in parse result")
+		return
+	}
+
+	report, err := h.repo.SyncStages(r.Context(), userID, projectID, stages)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			problem.Write(w, http.StatusForbidden, "not_project_manager", "only project owners and managers can sync parse results")
+		case errors.Is(err, projects.ErrStageNotInProject), errors.Is(err, projects.ErrAssigneeNotMember):
+			problem.Error(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("sync parse result into project %s failed: %v", projectID, err)
+			problem.Error(w, http.StatusInternalServerError, "failed to sync parse result")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"projectId":     projectID,
+		"stagesCreated": report.StagesCreated,
+		"added":         report.Added,
+		"updated":       report.Updated,
+		"unchanged":     report.Unchanged,
+		"conflicts":     report.Conflicts,
+		"removed":       report.Removed,
+		"dateConflicts": preview.Conflicts,
+	})
+}
@@ -0,0 +1,263 @@
+package zhcp
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tm-platform-backend/internal/i18n"
+	"tm-platform-backend/internal/notifications"
+	"tm-platform-backend/internal/projects"
+
+	"github.com/google/uuid"
+)
+
+const (
+	trackerBatch       = 50
+	trackerCallTimeout = 30 * time.Second
+)
+
+// Tracker follows the parse jobs of uploaded project documents, imports each
+// result into its project once the parser completes it and notifies the
+// uploader, so clients don't have to poll the parser.
+type Tracker struct {
+	jobs          *Repository
+	client        *Client
+	projects      *projects.Repository
+	notifications *notifications.Repository
+	interval      time.Duration
+	wake          chan struct{}
+
+	// Job event subscribers, signalled whenever the tracker changes a job
+	subscribersMu sync.Mutex
+	subscribers   map[uuid.UUID]map[chan struct{}]struct{}
+	streamsDone   chan struct{}
+	streamsOnce   sync.Once
+}
+
+func NewTracker(jobs *Repository, client *Client, projectsRepo *projects.Repository, notificationsRepo *notifications.Repository, interval time.Duration) *Tracker {
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+	return &Tracker{
+		jobs:          jobs,
+		client:        client,
+		projects:      projectsRepo,
+		notifications: notificationsRepo,
+		interval:      interval,
+		wake:          make(chan struct{}, 1),
+		subscribers:   make(map[uuid.UUID]map[chan struct{}]struct{}),
+		streamsDone:   make(chan struct{}),
+	}
+}
+
+// Start checks the pending jobs every interval until ctx is cancelled. Jobs
+// are kept in the database, so the ones pending at shutdown are picked up on
+// the next start.
+func (t *Tracker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+
+		for {
+			t.check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-t.wake:
+			}
+		}
+	}()
+}
+
+// Wake makes the tracker check the pending jobs without waiting for the
+// next tick, e.g. after a job was submitted.
+func (t *Tracker) Wake() {
+	if t == nil {
+		return
+	}
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe registers for change notifications of a job. The channel holds
+// at most one pending signal, so a slow reader only sees the latest state.
+func (t *Tracker) Subscribe(jobID uuid.UUID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	t.subscribersMu.Lock()
+	if t.subscribers[jobID] == nil {
+		t.subscribers[jobID] = make(map[chan struct{}]struct{})
+	}
+	t.subscribers[jobID][ch] = struct{}{}
+	t.subscribersMu.Unlock()
+
+	return ch, func() {
+		t.subscribersMu.Lock()
+		delete(t.subscribers[jobID], ch)
+		if len(t.subscribers[jobID]) == 0 {
+			delete(t.subscribers, jobID)
+		}
+		t.subscribersMu.Unlock()
+	}
+}
+
+// StreamsDone is closed by StopStreams.
+func (t *Tracker) StreamsDone() <-chan struct{} {
+	return t.streamsDone
+}
+
+// StopStreams ends all open job event streams with a reconnect hint, so
+// that shutdown does not wait for jobs that may never finish.
+func (t *Tracker) StopStreams() {
+	t.streamsOnce.Do(func() { close(t.streamsDone) })
+}
+
+func (t *Tracker) publish(jobID uuid.UUID) {
+	t.subscribersMu.Lock()
+	defer t.subscribersMu.Unlock()
+
+	for ch := range t.subscribers[jobID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracker) check(ctx context.Context) {
+	pending, err := t.jobs.ListPendingJobs(ctx, trackerBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("list pending zhcp parse jobs failed: %v", err)
+		}
+		return
+	}
+
+	for _, job := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		t.advance(ctx, job)
+	}
+}
+
+// advance records the parser's progress on a job and finishes it once the
+// parser is done. Errors reaching the parser leave the job pending for the
+// next check.
+func (t *Tracker) advance(ctx context.Context, job Job) {
+	callCtx, cancel := context.WithTimeout(ctx, trackerCallTimeout)
+	defer cancel()
+
+	status, err := t.client.Status(callCtx, job.ParserJobID)
+	if errors.Is(err, ErrJobNotFound) {
+		t.fail(ctx, job, "parse job expired before it was imported")
+		return
+	}
+	if err != nil {
+		log.Printf("check zhcp parse job %s failed: %v", job.ID, err)
+		return
+	}
+
+	switch strings.ToLower(status.Status) {
+	case "completed":
+		t.importResult(ctx, job)
+	case "failed":
+		message := strings.TrimSpace(status.Error)
+		if message == "" {
+			message = "parser failed"
+		}
+		t.fail(ctx, job, message)
+	case "cancelled":
+		t.fail(ctx, job, "parse job was cancelled")
+	default:
+		next := JobStatusQueued
+		if strings.ToLower(status.Status) == "processing" {
+			next = JobStatusProcessing
+		}
+		if err := t.jobs.UpdateJobProgress(ctx, job.ID, next, status.Stage, status.Progress); err != nil {
+			log.Printf("update zhcp parse job %s failed: %v", job.ID, err)
+			return
+		}
+		t.publish(job.ID)
+	}
+}
+
+func (t *Tracker) importResult(ctx context.Context, job Job) {
+	callCtx, cancel := context.WithTimeout(ctx, trackerCallTimeout)
+	defer cancel()
+
+	result, err := t.client.Result(callCtx, job.ParserJobID)
+	if errors.Is(err, ErrParserUnavailable) {
+		// Left pending, the next check fetches the result again
+		log.Printf("fetch zhcp parse job %s result failed: %v", job.ID, err)
+		return
+	}
+	if err != nil {
+		t.fail(ctx, job, err.Error())
+		return
+	}
+
+	if err := t.jobs.SaveJobResult(ctx, job.ID, result.ProjectStructure.Project); err != nil {
+		log.Printf("save zhcp parse job %s result failed: %v", job.ID, err)
+	}
+
+	preview, err := planImport(ctx, t.projects, job.UserID, job.ProjectID, result.ProjectStructure.Project, importOverrides{})
+	if err != nil {
+		log.Printf("plan zhcp parse job %s import into project %s failed: %v", job.ID, job.ProjectID, err)
+		t.fail(ctx, job, "failed to import parse result")
+		return
+	}
+
+	stages := preview.importStages()
+	if len(stages) == 0 {
+		t.fail(ctx, job, "no phases in parse result")
+		return
+	}
+
+	// Syncing keeps a re-uploaded version of a document from duplicating
+	// the tasks of the previous one; on a fresh project it imports them all.
+	report, err := t.projects.SyncStages(ctx, job.UserID, job.ProjectID, stages)
+	if err != nil {
+		log.Printf("import zhcp parse job %s into project %s failed: %v", job.ID, job.ProjectID, err)
+		t.fail(ctx, job, "failed to import parse result")
+		return
+	}
+
+	if err := t.jobs.FinishJob(ctx, job.ID, JobStatusImported, report, ""); err != nil {
+		log.Printf("finish zhcp parse job %s failed: %v", job.ID, err)
+		return
+	}
+	t.publish(job.ID)
+	t.notify(ctx, job, i18n.M("notification.document_imported.title", "file", job.FileName),
+		i18n.M("notification.document_imported.body",
+			"added", strconv.Itoa(len(report.Added)),
+			"updated", strconv.Itoa(len(report.Updated)),
+			"conflicts", strconv.Itoa(len(report.Conflicts))))
+}
+
+func (t *Tracker) fail(ctx context.Context, job Job, message string) {
+	if err := t.jobs.FinishJob(ctx, job.ID, JobStatusFailed, nil, message); err != nil {
+		log.Printf("finish zhcp parse job %s failed: %v", job.ID, err)
+		return
+	}
+	t.publish(job.ID)
+	t.notify(ctx, job, i18n.M("notification.document_failed.title", "file", job.FileName), i18n.Text(message))
+}
+
+func (t *Tracker) notify(ctx context.Context, job Job, title, body i18n.Message) {
+	if t.notifications == nil {
+		return
+	}
+	link := "/project/" + job.ProjectID.String() + "?parseJobId=" + job.ID.String()
+	if err := t.notifications.Create(ctx, job.UserID, nil, notifications.KindDocumentParsed, title, body, link, "zhcp_parse_job", &job.ID); err != nil {
+		log.Printf("zhcp parse job notification failed: %v", err)
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_hierarchy_nodes_department_heads;
+ALTER TABLE hierarchy_nodes DROP COLUMN IF EXISTS is_department_head;
@@ -0,0 +1,7 @@
+-- Department heads: a user node flagged as head gets read access to projects
+-- owned by users inside its parent department subtree.
+ALTER TABLE hierarchy_nodes ADD COLUMN IF NOT EXISTS is_department_head BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_hierarchy_nodes_department_heads
+    ON hierarchy_nodes(user_id)
+    WHERE is_department_head;
@@ -0,0 +1,3 @@
+ALTER TABLE hierarchy_role_catalog DROP COLUMN IF EXISTS can_approve_expenses;
+ALTER TABLE hierarchy_role_catalog DROP COLUMN IF EXISTS can_view_all_projects;
+ALTER TABLE hierarchy_role_catalog DROP COLUMN IF EXISTS can_manage_hierarchy;
@@ -0,0 +1,24 @@
+-- Platform permissions attached to hierarchy role catalog entries.
+-- Assigning a catalog role to a node grants these capabilities to its user.
+ALTER TABLE hierarchy_role_catalog ADD COLUMN IF NOT EXISTS can_manage_hierarchy BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE hierarchy_role_catalog ADD COLUMN IF NOT EXISTS can_view_all_projects BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE hierarchy_role_catalog ADD COLUMN IF NOT EXISTS can_approve_expenses BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE hierarchy_role_catalog
+SET can_manage_hierarchy = true,
+    can_view_all_projects = true,
+    can_approve_expenses = true
+WHERE name = 'Генеральный директор';
+
+UPDATE hierarchy_role_catalog
+SET can_view_all_projects = true,
+    can_approve_expenses = true
+WHERE name IN ('Технический директор (главный инженер)', 'Директор по строительству');
+
+UPDATE hierarchy_role_catalog
+SET can_view_all_projects = true
+WHERE name = 'Внутренний аудитор';
+
+UPDATE hierarchy_role_catalog
+SET can_manage_hierarchy = true
+WHERE name IN ('Руководитель HR-отдела', 'HR-специалист');
@@ -0,0 +1,17 @@
+DROP VIEW IF EXISTS hierarchy_department_catalog;
+
+CREATE TABLE IF NOT EXISTS hierarchy_department_catalog (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL UNIQUE,
+    is_system BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO hierarchy_department_catalog (name, is_system)
+SELECT name, is_system
+FROM departments
+ON CONFLICT (name) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_departments_hierarchy_node_id;
+ALTER TABLE departments DROP COLUMN IF EXISTS hierarchy_node_id;
+ALTER TABLE departments DROP COLUMN IF EXISTS is_system;
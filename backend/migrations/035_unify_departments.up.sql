@@ -0,0 +1,79 @@
+-- Make the departments table the single source of truth for departments.
+-- hierarchy_department_catalog becomes a read-only view over it and every
+-- department node in the org chart is linked to exactly one department row.
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS is_system BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS hierarchy_node_id UUID REFERENCES hierarchy_nodes(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_departments_hierarchy_node_id
+    ON departments(hierarchy_node_id)
+    WHERE hierarchy_node_id IS NOT NULL;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM information_schema.tables
+        WHERE table_name = 'hierarchy_department_catalog'
+          AND table_type = 'BASE TABLE'
+    ) THEN
+        INSERT INTO departments (name, is_system)
+        SELECT BTRIM(c.name), c.is_system
+        FROM hierarchy_department_catalog c
+        WHERE BTRIM(c.name) <> ''
+          AND NOT EXISTS (
+              SELECT 1 FROM departments d WHERE LOWER(BTRIM(d.name)) = LOWER(BTRIM(c.name))
+          );
+
+        UPDATE departments d
+        SET is_system = true
+        FROM hierarchy_department_catalog c
+        WHERE c.is_system
+          AND LOWER(BTRIM(d.name)) = LOWER(BTRIM(c.name));
+
+        DROP TABLE hierarchy_department_catalog;
+    END IF;
+END $$;
+
+INSERT INTO departments (name)
+SELECT DISTINCT ON (LOWER(BTRIM(n.title))) BTRIM(n.title)
+FROM hierarchy_nodes n
+WHERE n.type = 'department'
+  AND BTRIM(COALESCE(n.title, '')) <> ''
+  AND NOT EXISTS (
+      SELECT 1 FROM departments d WHERE LOWER(BTRIM(d.name)) = LOWER(BTRIM(n.title))
+  )
+ORDER BY LOWER(BTRIM(n.title)), n.level ASC;
+
+-- Link each department node to its department; the shallowest node wins when
+-- several nodes share a title.
+UPDATE departments d
+SET hierarchy_node_id = linked.node_id
+FROM (
+    SELECT DISTINCT ON (LOWER(BTRIM(n.title)))
+        LOWER(BTRIM(n.title)) AS title_key,
+        n.id AS node_id
+    FROM hierarchy_nodes n
+    WHERE n.type = 'department'
+    ORDER BY LOWER(BTRIM(n.title)), n.level ASC, n.position ASC
+) linked
+WHERE LOWER(BTRIM(d.name)) = linked.title_key
+  AND d.hierarchy_node_id IS NULL;
+
+-- Mirror the org chart nesting into departments.parent_id.
+UPDATE departments d
+SET parent_id = parent_dep.id
+FROM hierarchy_nodes n
+JOIN LATERAL (
+    SELECT pd.id
+    FROM hierarchy_nodes anc
+    JOIN departments pd ON pd.hierarchy_node_id = anc.id
+    WHERE anc.type = 'department'
+      AND n.path LIKE anc.path || '.%'
+    ORDER BY anc.level DESC
+    LIMIT 1
+) parent_dep ON true
+WHERE d.hierarchy_node_id = n.id;
+
+CREATE OR REPLACE VIEW hierarchy_department_catalog AS
+SELECT id, name, is_system, created_at
+FROM departments;
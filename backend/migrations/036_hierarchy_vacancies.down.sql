@@ -0,0 +1,17 @@
+DELETE FROM hierarchy_nodes WHERE is_vacancy;
+
+ALTER TABLE hierarchy_nodes
+    DROP CONSTRAINT IF EXISTS hierarchy_nodes_vacancy_state;
+
+ALTER TABLE hierarchy_nodes
+    DROP CONSTRAINT IF EXISTS hierarchy_nodes_user_for_company_or_user;
+
+ALTER TABLE hierarchy_nodes
+    ADD CONSTRAINT hierarchy_nodes_user_for_company_or_user CHECK (
+        (type = 'user' AND user_id IS NOT NULL)
+        OR (type = 'company')
+        OR (type NOT IN ('user', 'company') AND user_id IS NULL)
+    );
+
+ALTER TABLE hierarchy_nodes DROP COLUMN IF EXISTS hiring_status;
+ALTER TABLE hierarchy_nodes DROP COLUMN IF EXISTS is_vacancy;
@@ -0,0 +1,23 @@
+-- Open positions: user-type nodes without an assigned user.
+ALTER TABLE hierarchy_nodes ADD COLUMN IF NOT EXISTS is_vacancy BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE hierarchy_nodes ADD COLUMN IF NOT EXISTS hiring_status TEXT;
+
+ALTER TABLE hierarchy_nodes
+    DROP CONSTRAINT IF EXISTS hierarchy_nodes_user_for_company_or_user;
+
+ALTER TABLE hierarchy_nodes
+    ADD CONSTRAINT hierarchy_nodes_user_for_company_or_user CHECK (
+        (type = 'user' AND (user_id IS NOT NULL OR is_vacancy))
+        OR (type = 'company')
+        OR (type NOT IN ('user', 'company') AND user_id IS NULL)
+    );
+
+ALTER TABLE hierarchy_nodes
+    DROP CONSTRAINT IF EXISTS hierarchy_nodes_vacancy_state;
+
+ALTER TABLE hierarchy_nodes
+    ADD CONSTRAINT hierarchy_nodes_vacancy_state CHECK (
+        (is_vacancy AND type = 'user' AND user_id IS NULL
+            AND hiring_status IN ('open', 'interviewing', 'offer', 'on_hold'))
+        OR (NOT is_vacancy AND hiring_status IS NULL)
+    );
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_project_files_project_name;
+DROP TABLE IF EXISTS project_file_versions;
+
+ALTER TABLE project_files
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS uploaded_by,
+    DROP COLUMN IF EXISTS version;
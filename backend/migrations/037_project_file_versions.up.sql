@@ -0,0 +1,33 @@
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE project_files pf
+SET uploaded_by = p.owner_id
+FROM projects p
+WHERE p.id = pf.project_id
+  AND pf.uploaded_by IS NULL;
+
+CREATE TABLE IF NOT EXISTS project_file_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_id UUID NOT NULL REFERENCES project_files(id) ON DELETE CASCADE,
+    version INT NOT NULL CHECK (version > 0),
+    url TEXT NOT NULL,
+    type TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    restored_from INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT project_file_versions_file_version_key UNIQUE (file_id, version)
+);
+
+INSERT INTO project_file_versions (file_id, version, url, type, size, uploaded_by, created_at)
+SELECT pf.id, pf.version, pf.url, pf.type, pf.size, pf.uploaded_by, pf.created_at
+FROM project_files pf
+WHERE NOT EXISTS (
+    SELECT 1 FROM project_file_versions v WHERE v.file_id = pf.id
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_files_project_name
+    ON project_files(project_id, lower(name));
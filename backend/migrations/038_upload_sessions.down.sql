@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS upload_session_parts;
+DROP INDEX IF EXISTS idx_upload_sessions_active_expires;
+DROP INDEX IF EXISTS idx_upload_sessions_user_status;
+DROP TABLE IF EXISTS upload_sessions;
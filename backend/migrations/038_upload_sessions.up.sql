@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    multipart_id TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    file_type TEXT NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    total_size BIGINT NOT NULL CHECK (total_size > 0),
+    chunk_size BIGINT NOT NULL CHECK (chunk_size > 0),
+    status TEXT NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT upload_sessions_status_check CHECK (status IN ('active', 'completed', 'aborted'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_user_status
+    ON upload_sessions(user_id, status);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_active_expires
+    ON upload_sessions(expires_at)
+    WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS upload_session_parts (
+    session_id UUID NOT NULL REFERENCES upload_sessions(id) ON DELETE CASCADE,
+    part_number INT NOT NULL CHECK (part_number > 0),
+    etag TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (session_id, part_number)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_project_files_preview_pending;
+
+ALTER TABLE project_files
+    DROP CONSTRAINT IF EXISTS project_files_preview_status_check,
+    DROP COLUMN IF EXISTS preview_status,
+    DROP COLUMN IF EXISTS preview_url;
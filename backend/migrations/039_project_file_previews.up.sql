@@ -0,0 +1,14 @@
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS preview_url TEXT,
+    ADD COLUMN IF NOT EXISTS preview_status TEXT NOT NULL DEFAULT 'pending';
+
+ALTER TABLE project_files
+    DROP CONSTRAINT IF EXISTS project_files_preview_status_check;
+
+ALTER TABLE project_files
+    ADD CONSTRAINT project_files_preview_status_check
+    CHECK (preview_status IN ('pending', 'ready', 'failed', 'unsupported'));
+
+CREATE INDEX IF NOT EXISTS idx_project_files_preview_pending
+    ON project_files(updated_at)
+    WHERE preview_status = 'pending';
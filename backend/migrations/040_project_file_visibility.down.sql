@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_project_file_versions_url;
+DROP INDEX IF EXISTS idx_project_file_access_user;
+DROP TABLE IF EXISTS project_file_access;
+
+ALTER TABLE project_files
+    DROP CONSTRAINT IF EXISTS project_files_visibility_check,
+    DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,22 @@
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'members';
+
+ALTER TABLE project_files
+    DROP CONSTRAINT IF EXISTS project_files_visibility_check;
+
+ALTER TABLE project_files
+    ADD CONSTRAINT project_files_visibility_check
+    CHECK (visibility IN ('members', 'managers', 'users'));
+
+CREATE TABLE IF NOT EXISTS project_file_access (
+    file_id UUID NOT NULL REFERENCES project_files(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (file_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_file_access_user
+    ON project_file_access(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_project_file_versions_url
+    ON project_file_versions(url);
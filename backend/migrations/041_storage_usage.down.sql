@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_storage_objects_project;
+DROP INDEX IF EXISTS idx_storage_objects_user;
+DROP TABLE IF EXISTS storage_objects;
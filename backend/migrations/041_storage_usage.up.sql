@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS storage_objects (
+    object_key TEXT PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_objects_user ON storage_objects(user_id);
+CREATE INDEX IF NOT EXISTS idx_storage_objects_project ON storage_objects(project_id);
+
+INSERT INTO storage_objects (object_key, user_id, project_id, size, created_at)
+SELECT DISTINCT ON (v.url)
+    substring(v.url FROM length('/uploads/') + 1),
+    v.uploaded_by,
+    pf.project_id,
+    v.size,
+    v.created_at
+FROM project_file_versions v
+JOIN project_files pf ON pf.id = v.file_id
+WHERE v.url LIKE '/uploads/%'
+ORDER BY v.url, v.created_at
+ON CONFLICT (object_key) DO NOTHING;
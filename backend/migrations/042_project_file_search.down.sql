@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_project_files_index_pending;
+DROP INDEX IF EXISTS idx_project_files_search;
+
+ALTER TABLE project_files
+    DROP COLUMN IF EXISTS search_vector,
+    DROP CONSTRAINT IF EXISTS project_files_index_status_check,
+    DROP COLUMN IF EXISTS index_status,
+    DROP COLUMN IF EXISTS content_text;
@@ -0,0 +1,30 @@
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS content_text TEXT,
+    ADD COLUMN IF NOT EXISTS index_status TEXT NOT NULL DEFAULT 'pending';
+
+ALTER TABLE project_files
+    DROP CONSTRAINT IF EXISTS project_files_index_status_check;
+
+ALTER TABLE project_files
+    ADD CONSTRAINT project_files_index_status_check
+    CHECK (index_status IN ('pending', 'ready', 'failed', 'unsupported'));
+
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A')
+        || setweight(to_tsvector('simple', COALESCE(content_text, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_project_files_search
+    ON project_files USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_project_files_index_pending
+    ON project_files(updated_at)
+    WHERE index_status = 'pending';
+
+UPDATE project_files
+SET index_status = 'unsupported'
+WHERE index_status = 'pending'
+  AND lower(url) NOT LIKE '%.pdf'
+  AND lower(url) NOT LIKE '%.docx';
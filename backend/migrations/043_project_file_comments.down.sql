@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_project_file_comments_file_created;
+DROP TABLE IF EXISTS project_file_comments;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS project_file_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_id UUID NOT NULL REFERENCES project_files(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    page INT CHECK (page IS NULL OR page > 0),
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_file_comments_file_created
+    ON project_file_comments(file_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_chat_messages_attachment_url;
+DROP INDEX IF EXISTS idx_storage_objects_id;
+
+ALTER TABLE storage_objects
+    DROP COLUMN IF EXISTS id;
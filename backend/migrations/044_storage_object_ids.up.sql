@@ -0,0 +1,8 @@
+ALTER TABLE storage_objects
+    ADD COLUMN IF NOT EXISTS id UUID NOT NULL DEFAULT uuid_generate_v4();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_storage_objects_id ON storage_objects(id);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_attachment_url
+    ON chat_messages(attachment_url)
+    WHERE attachment_url IS NOT NULL;
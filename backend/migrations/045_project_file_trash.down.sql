@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_project_files_purge;
+DROP INDEX IF EXISTS idx_project_files_trash;
+
+ALTER TABLE project_files
+    DROP COLUMN IF EXISTS purge_at,
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,12 @@
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS purge_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_project_files_trash
+    ON project_files(project_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_project_files_purge
+    ON project_files(purge_at)
+    WHERE purge_at IS NOT NULL;
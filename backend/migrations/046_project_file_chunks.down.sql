@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_project_files_embedding_pending;
+
+DROP TABLE IF EXISTS project_file_chunks;
+
+ALTER TABLE project_files
+    DROP COLUMN IF EXISTS embedded_version;
@@ -0,0 +1,18 @@
+-- Embedded chunks of project documents used to ground AI answers. Vectors are
+-- stored as packed little-endian float32 so no database extension is needed.
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS embedded_version INT;
+
+CREATE TABLE IF NOT EXISTS project_file_chunks (
+    id UUID PRIMARY KEY,
+    file_id UUID NOT NULL REFERENCES project_files(id) ON DELETE CASCADE,
+    chunk_index INT NOT NULL,
+    content TEXT NOT NULL,
+    embedding BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (file_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_files_embedding_pending
+    ON project_files(updated_at)
+    WHERE index_status = 'ready' AND embedded_version IS DISTINCT FROM version;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_project_meeting_attendees_user;
+DROP TABLE IF EXISTS project_meeting_attendees;
+DROP INDEX IF EXISTS idx_project_meetings_project_starts;
+DROP TABLE IF EXISTS project_meetings;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS project_meetings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    duration_minutes INT NOT NULL CHECK (duration_minutes > 0),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_meetings_project_starts
+    ON project_meetings(project_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS project_meeting_attendees (
+    meeting_id UUID NOT NULL REFERENCES project_meetings(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (meeting_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_meeting_attendees_user
+    ON project_meeting_attendees(user_id);
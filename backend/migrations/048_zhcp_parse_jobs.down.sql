@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_zhcp_parse_jobs_pending;
+DROP INDEX IF EXISTS idx_zhcp_parse_jobs_project_created;
+DROP TABLE IF EXISTS zhcp_parse_jobs;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS zhcp_parse_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parser_job_id TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued',
+    progress INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    summary JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_zhcp_parse_jobs_project_created
+    ON zhcp_parse_jobs(project_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_zhcp_parse_jobs_pending
+    ON zhcp_parse_jobs(updated_at)
+    WHERE status IN ('queued', 'processing');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_project_imported_tasks_task_id;
+DROP TABLE IF EXISTS project_imported_tasks;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS project_imported_tasks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES stage_tasks(id) ON DELETE SET NULL,
+    source_stage TEXT NOT NULL,
+    source_title TEXT NOT NULL,
+    title TEXT NOT NULL,
+    status TEXT NOT NULL,
+    start_date TIMESTAMPTZ,
+    deadline TIMESTAMPTZ,
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, source_stage, source_title)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_imported_tasks_task_id ON project_imported_tasks(task_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_zhcp_parse_jobs_file_created;
+
+ALTER TABLE zhcp_parse_jobs
+    DROP COLUMN IF EXISTS result,
+    DROP COLUMN IF EXISTS file_version,
+    DROP COLUMN IF EXISTS file_id;
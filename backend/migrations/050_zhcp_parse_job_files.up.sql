@@ -0,0 +1,8 @@
+ALTER TABLE zhcp_parse_jobs
+    ADD COLUMN IF NOT EXISTS file_id UUID REFERENCES project_files(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS file_version INT,
+    ADD COLUMN IF NOT EXISTS result JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_zhcp_parse_jobs_file_created
+    ON zhcp_parse_jobs(file_id, created_at DESC)
+    WHERE file_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE zhcp_parse_jobs
+    DROP COLUMN IF EXISTS stage;
@@ -0,0 +1,2 @@
+ALTER TABLE zhcp_parse_jobs
+    ADD COLUMN IF NOT EXISTS stage TEXT NOT NULL DEFAULT '';
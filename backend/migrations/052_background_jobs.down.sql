@@ -0,0 +1,4 @@
+ALTER TABLE stage_tasks
+    DROP COLUMN IF EXISTS deadline_reminded_at;
+
+DROP TABLE IF EXISTS background_jobs;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS background_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    status TEXT NOT NULL DEFAULT 'queued',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    dedupe_key TEXT,
+    locked_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ,
+    CONSTRAINT background_jobs_status_check CHECK (status IN ('queued', 'running', 'done', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_background_jobs_due ON background_jobs(run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_background_jobs_status ON background_jobs(status, created_at DESC, id DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_background_jobs_dedupe_key ON background_jobs(dedupe_key) WHERE dedupe_key IS NOT NULL;
+
+ALTER TABLE stage_tasks
+    ADD COLUMN IF NOT EXISTS deadline_reminded_at TIMESTAMPTZ;
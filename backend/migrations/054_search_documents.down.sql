@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_search_documents_thread;
+DROP INDEX IF EXISTS idx_search_documents_project;
+DROP INDEX IF EXISTS idx_search_documents_vector;
+DROP TABLE IF EXISTS search_documents;
+DROP FUNCTION IF EXISTS search_blocks_text(JSONB);
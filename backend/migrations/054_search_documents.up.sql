@@ -0,0 +1,29 @@
+-- search_blocks_text is the plain text of an editor block list, without the
+-- hidden task metadata block.
+CREATE OR REPLACE FUNCTION search_blocks_text(blocks JSONB) RETURNS TEXT
+LANGUAGE SQL IMMUTABLE AS $$
+    SELECT COALESCE(string_agg(block->>'content', ' '), '')
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(blocks) = 'array' THEN blocks ELSE '[]'::jsonb END) AS block
+    WHERE COALESCE(block->>'id', '') <> '__task_meta__'
+$$;
+
+CREATE TABLE IF NOT EXISTS search_documents (
+    kind TEXT NOT NULL,
+    entity_id UUID NOT NULL,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    thread_id UUID REFERENCES chat_threads(id) ON DELETE CASCADE,
+    parent_id UUID,
+    title TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title), 'A')
+        || setweight(to_tsvector('russian', body), 'B')
+    ) STORED,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (kind, entity_id),
+    CONSTRAINT search_documents_kind_check CHECK (kind IN ('project', 'task', 'page', 'comment', 'message', 'file'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_documents_vector ON search_documents USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_search_documents_project ON search_documents(project_id);
+CREATE INDEX IF NOT EXISTS idx_search_documents_thread ON search_documents(thread_id);
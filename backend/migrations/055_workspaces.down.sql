@@ -0,0 +1,43 @@
+DROP TRIGGER IF EXISTS hierarchy_nodes_workspace_member ON hierarchy_nodes;
+DROP TRIGGER IF EXISTS chat_thread_members_workspace_member ON chat_thread_members;
+DROP TRIGGER IF EXISTS project_members_workspace_member ON project_members;
+DROP FUNCTION IF EXISTS check_hierarchy_user_workspace();
+DROP FUNCTION IF EXISTS check_chat_member_workspace();
+DROP FUNCTION IF EXISTS check_project_member_workspace();
+DROP FUNCTION IF EXISTS check_workspace_member(UUID, UUID, TEXT);
+
+-- Only the primary workspace survives the way back.
+DELETE FROM workspaces WHERE id <> primary_workspace_id();
+
+ALTER TABLE chat_direct_threads DROP CONSTRAINT IF EXISTS chat_direct_threads_pkey;
+ALTER TABLE chat_direct_threads ADD PRIMARY KEY (user_a_id, user_b_id);
+
+DROP INDEX IF EXISTS ux_hierarchy_nodes_workspace_user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_hierarchy_nodes_user_id
+    ON hierarchy_nodes(user_id)
+    WHERE user_id IS NOT NULL;
+
+DROP INDEX IF EXISTS ux_hierarchy_role_catalog_workspace_name;
+ALTER TABLE hierarchy_role_catalog ADD CONSTRAINT hierarchy_role_catalog_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS ux_departments_workspace_name;
+ALTER TABLE departments ADD CONSTRAINT departments_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_webhooks_workspace;
+DROP INDEX IF EXISTS idx_chat_threads_workspace;
+DROP INDEX IF EXISTS idx_hierarchy_nodes_workspace;
+DROP INDEX IF EXISTS idx_projects_workspace;
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE chat_direct_threads DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE chat_threads DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE hierarchy_role_catalog DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE departments DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE hierarchy_nodes DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE projects DROP COLUMN IF EXISTS workspace_id;
+
+DROP FUNCTION IF EXISTS primary_workspace_id();
+DROP INDEX IF EXISTS idx_workspace_members_user;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
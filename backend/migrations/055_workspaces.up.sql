@@ -0,0 +1,169 @@
+-- Workspaces let one deployment serve several organizations. Projects, the
+-- org chart, departments, role permissions, chats and webhooks belong to
+-- exactly one workspace; users are shared and join workspaces through
+-- workspace_members.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+    -- newly registered users join the workspaces that have open signup
+    open_signup BOOLEAN NOT NULL DEFAULT false,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workspace_id, user_id),
+    CONSTRAINT workspace_members_role_check CHECK (role IN ('admin', 'member'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user
+    ON workspace_members(user_id, joined_at);
+
+-- The first workspace belongs to whoever runs the deployment: its admins and
+-- org chart hold the platform-wide administration rights.
+CREATE OR REPLACE FUNCTION primary_workspace_id() RETURNS UUID
+LANGUAGE SQL STABLE AS $$
+    SELECT id FROM workspaces ORDER BY created_at ASC, id ASC LIMIT 1
+$$;
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE hierarchy_nodes ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE hierarchy_role_catalog ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE chat_threads ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE chat_direct_threads ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+
+-- Everything that exists so far becomes the primary workspace, named after
+-- the company root of the org chart. Every user joins it; the CEO and the
+-- owner/CEO system roles administer it.
+DO $$
+DECLARE
+    default_workspace_id UUID;
+BEGIN
+    IF EXISTS (SELECT 1 FROM workspaces) THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO workspaces (name, open_signup)
+    VALUES (
+        COALESCE(
+            (SELECT NULLIF(BTRIM(title), '') FROM hierarchy_nodes WHERE type = 'company' ORDER BY position ASC LIMIT 1),
+            'Company'
+        ),
+        true
+    )
+    RETURNING id INTO default_workspace_id;
+
+    INSERT INTO workspace_members (workspace_id, user_id, role)
+    SELECT
+        default_workspace_id,
+        u.id,
+        CASE
+            WHEN LOWER(BTRIM(COALESCE(u.role, ''))) IN ('owner', 'ceo')
+              OR EXISTS (SELECT 1 FROM hierarchy_nodes n WHERE n.type = 'company' AND n.user_id = u.id)
+            THEN 'admin'
+            ELSE 'member'
+        END
+    FROM users u
+    ON CONFLICT (workspace_id, user_id) DO NOTHING;
+
+    UPDATE projects SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE hierarchy_nodes SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE departments SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE hierarchy_role_catalog SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE chat_threads SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE chat_direct_threads SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE webhooks SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+    UPDATE webhook_deliveries SET workspace_id = default_workspace_id WHERE workspace_id IS NULL;
+END $$;
+
+ALTER TABLE projects ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE hierarchy_nodes ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE departments ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE hierarchy_role_catalog ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE chat_threads ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE chat_direct_threads ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE webhooks ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE webhook_deliveries ALTER COLUMN workspace_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_projects_workspace ON projects(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_hierarchy_nodes_workspace ON hierarchy_nodes(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_chat_threads_workspace ON chat_threads(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_workspace ON webhooks(workspace_id);
+
+-- Names, user placements and direct threads are unique per workspace only.
+ALTER TABLE departments DROP CONSTRAINT IF EXISTS departments_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_departments_workspace_name
+    ON departments(workspace_id, name);
+
+ALTER TABLE hierarchy_role_catalog DROP CONSTRAINT IF EXISTS hierarchy_role_catalog_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_hierarchy_role_catalog_workspace_name
+    ON hierarchy_role_catalog(workspace_id, name);
+
+DROP INDEX IF EXISTS ux_hierarchy_nodes_user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_hierarchy_nodes_workspace_user_id
+    ON hierarchy_nodes(workspace_id, user_id)
+    WHERE user_id IS NOT NULL;
+
+ALTER TABLE chat_direct_threads DROP CONSTRAINT IF EXISTS chat_direct_threads_pkey;
+ALTER TABLE chat_direct_threads ADD PRIMARY KEY (workspace_id, user_a_id, user_b_id);
+
+-- Only members of a workspace can be placed in its projects, chats and org
+-- chart. The repositories check it first to answer cleanly; these triggers
+-- make sure no write path slips through.
+CREATE OR REPLACE FUNCTION check_workspace_member(workspace UUID, member UUID, target TEXT) RETURNS VOID
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM workspace_members wm
+        WHERE wm.workspace_id = workspace AND wm.user_id = member
+    ) THEN
+        RAISE EXCEPTION 'user % is not a member of the workspace of this %', member, target
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'workspace_member_required';
+    END IF;
+END $$;
+
+CREATE OR REPLACE FUNCTION check_project_member_workspace() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM check_workspace_member((SELECT workspace_id FROM projects WHERE id = NEW.project_id), NEW.user_id, 'project');
+    RETURN NEW;
+END $$;
+
+CREATE OR REPLACE FUNCTION check_chat_member_workspace() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM check_workspace_member((SELECT workspace_id FROM chat_threads WHERE id = NEW.thread_id), NEW.user_id, 'chat');
+    RETURN NEW;
+END $$;
+
+CREATE OR REPLACE FUNCTION check_hierarchy_user_workspace() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NEW.user_id IS NOT NULL THEN
+        PERFORM check_workspace_member(NEW.workspace_id, NEW.user_id, 'org chart');
+    END IF;
+    RETURN NEW;
+END $$;
+
+DROP TRIGGER IF EXISTS project_members_workspace_member ON project_members;
+CREATE TRIGGER project_members_workspace_member
+    BEFORE INSERT OR UPDATE OF project_id, user_id ON project_members
+    FOR EACH ROW EXECUTE FUNCTION check_project_member_workspace();
+
+DROP TRIGGER IF EXISTS chat_thread_members_workspace_member ON chat_thread_members;
+CREATE TRIGGER chat_thread_members_workspace_member
+    BEFORE INSERT OR UPDATE OF thread_id, user_id ON chat_thread_members
+    FOR EACH ROW EXECUTE FUNCTION check_chat_member_workspace();
+
+DROP TRIGGER IF EXISTS hierarchy_nodes_workspace_member ON hierarchy_nodes;
+CREATE TRIGGER hierarchy_nodes_workspace_member
+    BEFORE INSERT OR UPDATE OF workspace_id, user_id ON hierarchy_nodes
+    FOR EACH ROW EXECUTE FUNCTION check_hierarchy_user_workspace();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_project_files_deleted;
+DROP INDEX IF EXISTS idx_chat_messages_attachment_created;
+DROP INDEX IF EXISTS idx_notifications_created;
+DROP TABLE IF EXISTS retention_rules;
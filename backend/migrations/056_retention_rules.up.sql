@@ -0,0 +1,29 @@
+-- Retention rules say how long data is kept before the retention job purges
+-- it. keep_days is required except for the trash, which otherwise keeps files
+-- until their restore window ends.
+CREATE TABLE IF NOT EXISTS retention_rules (
+    kind TEXT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    keep_days INT,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT retention_rules_kind_check CHECK (kind IN ('notifications', 'chat_attachments', 'trash')),
+    CONSTRAINT retention_rules_keep_days_check CHECK (keep_days > 0 OR (keep_days IS NULL AND kind = 'trash'))
+);
+
+INSERT INTO retention_rules (kind, enabled, keep_days) VALUES
+    ('notifications', true, 90),
+    ('chat_attachments', false, 180),
+    ('trash', true, NULL)
+ON CONFLICT (kind) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_created
+    ON notifications(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_attachment_created
+    ON chat_messages(created_at)
+    WHERE attachment_url IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_project_files_deleted
+    ON project_files(deleted_at)
+    WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Deleted accounts keep their row, anonymized, so what they wrote keeps an
+-- author; deleted_at marks them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+ALTER TABLE notifications DROP COLUMN IF EXISTS body_message;
+ALTER TABLE notifications DROP COLUMN IF EXISTS title_message;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
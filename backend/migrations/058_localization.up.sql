@@ -0,0 +1,8 @@
+-- The language users chose for generated texts; NULL follows their browser.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT
+    CHECK (locale IN ('ru', 'kk', 'en'));
+
+-- Notifications keep their message keys and arguments, so they are shown in
+-- the language of the reader; title and body stay rendered in Russian.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS title_message JSONB;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS body_message JSONB;
@@ -0,0 +1,2 @@
+ALTER TABLE projects DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
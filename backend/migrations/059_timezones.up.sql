@@ -0,0 +1,5 @@
+-- IANA time zone names. Date-only deadlines are read in the time zone of
+-- their project; a user's zone seeds the projects they create, NULL falls
+-- back to DEFAULT_TIMEZONE. Existing projects keep the UTC reading they had.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
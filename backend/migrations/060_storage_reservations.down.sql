@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_storage_reservations_project;
+DROP INDEX IF EXISTS idx_storage_reservations_user;
+DROP TABLE IF EXISTS storage_reservations;
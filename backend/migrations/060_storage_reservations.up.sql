@@ -0,0 +1,14 @@
+-- Quota held by uploads between the quota check and the recording of the
+-- stored object, so concurrent uploads cannot together exceed a quota.
+-- Reservations left behind by a crashed upload stop counting when they
+-- expire.
+CREATE TABLE IF NOT EXISTS storage_reservations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_reservations_user ON storage_reservations(user_id);
+CREATE INDEX IF NOT EXISTS idx_storage_reservations_project ON storage_reservations(project_id);
@@ -0,0 +1,10 @@
+// Package migrations embeds the schema migrations, so the server binary
+// carries and applies them itself.
+package migrations
+
+import "embed"
+
+// FS holds the NNN_name.up.sql and NNN_name.down.sql files.
+//
+//go:embed *.sql
+var FS embed.FS
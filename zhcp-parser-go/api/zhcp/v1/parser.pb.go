@@ -0,0 +1,572 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: api/zhcp/v1/parser.proto
+
+package zhcpv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UploadDocumentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*UploadDocumentRequest_Metadata
+	//	*UploadDocumentRequest_Chunk
+	Data          isUploadDocumentRequest_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadDocumentRequest) Reset() {
+	*x = UploadDocumentRequest{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadDocumentRequest) ProtoMessage() {}
+
+func (x *UploadDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadDocumentRequest.ProtoReflect.Descriptor instead.
+func (*UploadDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UploadDocumentRequest) GetData() isUploadDocumentRequest_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadDocumentRequest) GetMetadata() *UploadMetadata {
+	if x != nil {
+		if x, ok := x.Data.(*UploadDocumentRequest_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *UploadDocumentRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*UploadDocumentRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isUploadDocumentRequest_Data interface {
+	isUploadDocumentRequest_Data()
+}
+
+type UploadDocumentRequest_Metadata struct {
+	Metadata *UploadMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type UploadDocumentRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*UploadDocumentRequest_Metadata) isUploadDocumentRequest_Data() {}
+
+func (*UploadDocumentRequest_Chunk) isUploadDocumentRequest_Data() {}
+
+type UploadMetadata struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Original file name; its extension selects the document format.
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Optional webhook notified when the job finishes.
+	CallbackUrl string `protobuf:"bytes,2,opt,name=callback_url,json=callbackUrl,proto3" json:"callback_url,omitempty"`
+	// Parse again even if an identical document has a cached result.
+	Force         bool `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadMetadata) Reset() {
+	*x = UploadMetadata{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadMetadata) ProtoMessage() {}
+
+func (x *UploadMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadMetadata.ProtoReflect.Descriptor instead.
+func (*UploadMetadata) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UploadMetadata) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type ParseTextRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Text  string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// "text" (default) or "markdown".
+	Format        string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	CallbackUrl   string `protobuf:"bytes,3,opt,name=callback_url,json=callbackUrl,proto3" json:"callback_url,omitempty"`
+	Force         bool   `protobuf:"varint,4,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParseTextRequest) Reset() {
+	*x = ParseTextRequest{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParseTextRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseTextRequest) ProtoMessage() {}
+
+func (x *ParseTextRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseTextRequest.ProtoReflect.Descriptor instead.
+func (*ParseTextRequest) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ParseTextRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ParseTextRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ParseTextRequest) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+func (x *ParseTextRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type SubmitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitResponse) Reset() {
+	*x = SubmitResponse{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResponse) ProtoMessage() {}
+
+func (x *SubmitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResponse.ProtoReflect.Descriptor instead.
+func (*SubmitResponse) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *SubmitResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type JobStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	// queued, processing, completed, failed or cancelled.
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// validation, extraction, llm, transformation or done.
+	Stage         string `protobuf:"bytes,3,opt,name=stage,proto3" json:"stage,omitempty"`
+	Progress      int32  `protobuf:"varint,4,opt,name=progress,proto3" json:"progress,omitempty"`
+	Error         string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStatus) Reset() {
+	*x = JobStatus{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatus) ProtoMessage() {}
+
+func (x *JobStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatus.ProtoReflect.Descriptor instead.
+func (*JobStatus) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *JobStatus) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobStatus) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *JobStatus) GetProgress() int32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *JobStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ParseResultResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	// The ParseResult as returned by GET /api/parse/result/{jobId}.
+	ResultJson    []byte `protobuf:"bytes,2,opt,name=result_json,json=resultJson,proto3" json:"result_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParseResultResponse) Reset() {
+	*x = ParseResultResponse{}
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParseResultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseResultResponse) ProtoMessage() {}
+
+func (x *ParseResultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_zhcp_v1_parser_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseResultResponse.ProtoReflect.Descriptor instead.
+func (*ParseResultResponse) Descriptor() ([]byte, []int) {
+	return file_api_zhcp_v1_parser_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ParseResultResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *ParseResultResponse) GetResultJson() []byte {
+	if x != nil {
+		return x.ResultJson
+	}
+	return nil
+}
+
+var File_api_zhcp_v1_parser_proto protoreflect.FileDescriptor
+
+const file_api_zhcp_v1_parser_proto_rawDesc = "" +
+	"\n" +
+	"\x18api/zhcp/v1/parser.proto\x12\azhcp.v1\"n\n" +
+	"\x15UploadDocumentRequest\x125\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x17.zhcp.v1.UploadMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04data\"e\n" +
+	"\x0eUploadMetadata\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12!\n" +
+	"\fcallback_url\x18\x02 \x01(\tR\vcallbackUrl\x12\x14\n" +
+	"\x05force\x18\x03 \x01(\bR\x05force\"w\n" +
+	"\x10ParseTextRequest\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12!\n" +
+	"\fcallback_url\x18\x03 \x01(\tR\vcallbackUrl\x12\x14\n" +
+	"\x05force\x18\x04 \x01(\bR\x05force\"?\n" +
+	"\x0eSubmitResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"&\n" +
+	"\rGetJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\x82\x01\n" +
+	"\tJobStatus\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05stage\x18\x03 \x01(\tR\x05stage\x12\x1a\n" +
+	"\bprogress\x18\x04 \x01(\x05R\bprogress\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"M\n" +
+	"\x13ParseResultResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x1f\n" +
+	"\vresult_json\x18\x02 \x01(\fR\n" +
+	"resultJson2\xd3\x02\n" +
+	"\rParserService\x12K\n" +
+	"\x0eUploadDocument\x12\x1e.zhcp.v1.UploadDocumentRequest\x1a\x17.zhcp.v1.SubmitResponse(\x01\x12?\n" +
+	"\tParseText\x12\x19.zhcp.v1.ParseTextRequest\x1a\x17.zhcp.v1.SubmitResponse\x127\n" +
+	"\tGetStatus\x12\x16.zhcp.v1.GetJobRequest\x1a\x12.zhcp.v1.JobStatus\x12A\n" +
+	"\tGetResult\x12\x16.zhcp.v1.GetJobRequest\x1a\x1c.zhcp.v1.ParseResultResponse\x128\n" +
+	"\bWatchJob\x12\x16.zhcp.v1.GetJobRequest\x1a\x12.zhcp.v1.JobStatus0\x01B#Z!zhcp-parser-go/api/zhcp/v1;zhcpv1b\x06proto3"
+
+var (
+	file_api_zhcp_v1_parser_proto_rawDescOnce sync.Once
+	file_api_zhcp_v1_parser_proto_rawDescData []byte
+)
+
+func file_api_zhcp_v1_parser_proto_rawDescGZIP() []byte {
+	file_api_zhcp_v1_parser_proto_rawDescOnce.Do(func() {
+		file_api_zhcp_v1_parser_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_zhcp_v1_parser_proto_rawDesc), len(file_api_zhcp_v1_parser_proto_rawDesc)))
+	})
+	return file_api_zhcp_v1_parser_proto_rawDescData
+}
+
+var file_api_zhcp_v1_parser_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_api_zhcp_v1_parser_proto_goTypes = []any{
+	(*UploadDocumentRequest)(nil), // 0: zhcp.v1.UploadDocumentRequest
+	(*UploadMetadata)(nil),        // 1: zhcp.v1.UploadMetadata
+	(*ParseTextRequest)(nil),      // 2: zhcp.v1.ParseTextRequest
+	(*SubmitResponse)(nil),        // 3: zhcp.v1.SubmitResponse
+	(*GetJobRequest)(nil),         // 4: zhcp.v1.GetJobRequest
+	(*JobStatus)(nil),             // 5: zhcp.v1.JobStatus
+	(*ParseResultResponse)(nil),   // 6: zhcp.v1.ParseResultResponse
+}
+var file_api_zhcp_v1_parser_proto_depIdxs = []int32{
+	1, // 0: zhcp.v1.UploadDocumentRequest.metadata:type_name -> zhcp.v1.UploadMetadata
+	0, // 1: zhcp.v1.ParserService.UploadDocument:input_type -> zhcp.v1.UploadDocumentRequest
+	2, // 2: zhcp.v1.ParserService.ParseText:input_type -> zhcp.v1.ParseTextRequest
+	4, // 3: zhcp.v1.ParserService.GetStatus:input_type -> zhcp.v1.GetJobRequest
+	4, // 4: zhcp.v1.ParserService.GetResult:input_type -> zhcp.v1.GetJobRequest
+	4, // 5: zhcp.v1.ParserService.WatchJob:input_type -> zhcp.v1.GetJobRequest
+	3, // 6: zhcp.v1.ParserService.UploadDocument:output_type -> zhcp.v1.SubmitResponse
+	3, // 7: zhcp.v1.ParserService.ParseText:output_type -> zhcp.v1.SubmitResponse
+	5, // 8: zhcp.v1.ParserService.GetStatus:output_type -> zhcp.v1.JobStatus
+	6, // 9: zhcp.v1.ParserService.GetResult:output_type -> zhcp.v1.ParseResultResponse
+	5, // 10: zhcp.v1.ParserService.WatchJob:output_type -> zhcp.v1.JobStatus
+	6, // [6:11] is the sub-list for method output_type
+	1, // [1:6] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_zhcp_v1_parser_proto_init() }
+func file_api_zhcp_v1_parser_proto_init() {
+	if File_api_zhcp_v1_parser_proto != nil {
+		return
+	}
+	file_api_zhcp_v1_parser_proto_msgTypes[0].OneofWrappers = []any{
+		(*UploadDocumentRequest_Metadata)(nil),
+		(*UploadDocumentRequest_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_zhcp_v1_parser_proto_rawDesc), len(file_api_zhcp_v1_parser_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_zhcp_v1_parser_proto_goTypes,
+		DependencyIndexes: file_api_zhcp_v1_parser_proto_depIdxs,
+		MessageInfos:      file_api_zhcp_v1_parser_proto_msgTypes,
+	}.Build()
+	File_api_zhcp_v1_parser_proto = out.File
+	file_api_zhcp_v1_parser_proto_goTypes = nil
+	file_api_zhcp_v1_parser_proto_depIdxs = nil
+}
@@ -0,0 +1,292 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: api/zhcp/v1/parser.proto
+
+package zhcpv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ParserService_UploadDocument_FullMethodName = "/zhcp.v1.ParserService/UploadDocument"
+	ParserService_ParseText_FullMethodName      = "/zhcp.v1.ParserService/ParseText"
+	ParserService_GetStatus_FullMethodName      = "/zhcp.v1.ParserService/GetStatus"
+	ParserService_GetResult_FullMethodName      = "/zhcp.v1.ParserService/GetResult"
+	ParserService_WatchJob_FullMethodName       = "/zhcp.v1.ParserService/WatchJob"
+)
+
+// ParserServiceClient is the client API for ParserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ParserService is the gRPC counterpart of the /api/parse HTTP endpoints for
+// service-to-service callers. Jobs are shared with the HTTP API, so a job
+// submitted over one transport can be watched over the other.
+type ParserServiceClient interface {
+	// UploadDocument streams a document: the first message carries the
+	// metadata, every following one a chunk of the file.
+	UploadDocument(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadDocumentRequest, SubmitResponse], error)
+	// ParseText queues raw text or Markdown for parsing.
+	ParseText(ctx context.Context, in *ParseTextRequest, opts ...grpc.CallOption) (*SubmitResponse, error)
+	// GetStatus returns the current state of a job.
+	GetStatus(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	// GetResult returns the result of a completed job.
+	GetResult(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*ParseResultResponse, error)
+	// WatchJob streams every progress change of a job and ends once it has
+	// completed or failed.
+	WatchJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobStatus], error)
+}
+
+type parserServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewParserServiceClient(cc grpc.ClientConnInterface) ParserServiceClient {
+	return &parserServiceClient{cc}
+}
+
+func (c *parserServiceClient) UploadDocument(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadDocumentRequest, SubmitResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ParserService_ServiceDesc.Streams[0], ParserService_UploadDocument_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadDocumentRequest, SubmitResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ParserService_UploadDocumentClient = grpc.ClientStreamingClient[UploadDocumentRequest, SubmitResponse]
+
+func (c *parserServiceClient) ParseText(ctx context.Context, in *ParseTextRequest, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, ParserService_ParseText_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parserServiceClient) GetStatus(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatus)
+	err := c.cc.Invoke(ctx, ParserService_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parserServiceClient) GetResult(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*ParseResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ParseResultResponse)
+	err := c.cc.Invoke(ctx, ParserService_GetResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parserServiceClient) WatchJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ParserService_ServiceDesc.Streams[1], ParserService_WatchJob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetJobRequest, JobStatus]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ParserService_WatchJobClient = grpc.ServerStreamingClient[JobStatus]
+
+// ParserServiceServer is the server API for ParserService service.
+// All implementations must embed UnimplementedParserServiceServer
+// for forward compatibility.
+//
+// ParserService is the gRPC counterpart of the /api/parse HTTP endpoints for
+// service-to-service callers. Jobs are shared with the HTTP API, so a job
+// submitted over one transport can be watched over the other.
+type ParserServiceServer interface {
+	// UploadDocument streams a document: the first message carries the
+	// metadata, every following one a chunk of the file.
+	UploadDocument(grpc.ClientStreamingServer[UploadDocumentRequest, SubmitResponse]) error
+	// ParseText queues raw text or Markdown for parsing.
+	ParseText(context.Context, *ParseTextRequest) (*SubmitResponse, error)
+	// GetStatus returns the current state of a job.
+	GetStatus(context.Context, *GetJobRequest) (*JobStatus, error)
+	// GetResult returns the result of a completed job.
+	GetResult(context.Context, *GetJobRequest) (*ParseResultResponse, error)
+	// WatchJob streams every progress change of a job and ends once it has
+	// completed or failed.
+	WatchJob(*GetJobRequest, grpc.ServerStreamingServer[JobStatus]) error
+	mustEmbedUnimplementedParserServiceServer()
+}
+
+// UnimplementedParserServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedParserServiceServer struct{}
+
+func (UnimplementedParserServiceServer) UploadDocument(grpc.ClientStreamingServer[UploadDocumentRequest, SubmitResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadDocument not implemented")
+}
+func (UnimplementedParserServiceServer) ParseText(context.Context, *ParseTextRequest) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ParseText not implemented")
+}
+func (UnimplementedParserServiceServer) GetStatus(context.Context, *GetJobRequest) (*JobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedParserServiceServer) GetResult(context.Context, *GetJobRequest) (*ParseResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedParserServiceServer) WatchJob(*GetJobRequest, grpc.ServerStreamingServer[JobStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedParserServiceServer) mustEmbedUnimplementedParserServiceServer() {}
+func (UnimplementedParserServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeParserServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ParserServiceServer will
+// result in compilation errors.
+type UnsafeParserServiceServer interface {
+	mustEmbedUnimplementedParserServiceServer()
+}
+
+func RegisterParserServiceServer(s grpc.ServiceRegistrar, srv ParserServiceServer) {
+	// If the following call pancis, it indicates UnimplementedParserServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ParserService_ServiceDesc, srv)
+}
+
+func _ParserService_UploadDocument_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ParserServiceServer).UploadDocument(&grpc.GenericServerStream[UploadDocumentRequest, SubmitResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ParserService_UploadDocumentServer = grpc.ClientStreamingServer[UploadDocumentRequest, SubmitResponse]
+
+func _ParserService_ParseText_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseTextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParserServiceServer).ParseText(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParserService_ParseText_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParserServiceServer).ParseText(ctx, req.(*ParseTextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParserService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParserServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParserService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParserServiceServer).GetStatus(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParserService_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParserServiceServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParserService_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParserServiceServer).GetResult(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParserService_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ParserServiceServer).WatchJob(m, &grpc.GenericServerStream[GetJobRequest, JobStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ParserService_WatchJobServer = grpc.ServerStreamingServer[JobStatus]
+
+// ParserService_ServiceDesc is the grpc.ServiceDesc for ParserService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ParserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "zhcp.v1.ParserService",
+	HandlerType: (*ParserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ParseText",
+			Handler:    _ParserService_ParseText_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _ParserService_GetStatus_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _ParserService_GetResult_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadDocument",
+			Handler:       _ParserService_UploadDocument_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchJob",
+			Handler:       _ParserService_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/zhcp/v1/parser.proto",
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -16,19 +17,26 @@ import (
 	"zhcp-parser-go/internal/ai/llm_providers/deepseek"
 	"zhcp-parser-go/internal/ai/llm_providers/ollama"
 	"zhcp-parser-go/internal/ai/llm_providers/openai"
+	"zhcp-parser-go/internal/ai/llm_providers/openai_compatible"
 	"zhcp-parser-go/internal/common"
 	"zhcp-parser-go/internal/config"
 	"zhcp-parser-go/internal/parser"
 	"zhcp-parser-go/internal/server"
+	"zhcp-parser-go/internal/storage"
+	"zhcp-parser-go/internal/storage/postgres"
+	"zhcp-parser-go/internal/storage/s3"
 	"zhcp-parser-go/internal/storage/sqlite"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	configPath string
-	dbPath     string
-	port       string
+	configPath  string
+	dbPath      string
+	databaseURL string
+	port        string
+	grpcPort    string
+	swaggerUI   bool
 )
 
 var rootCmd = &cobra.Command{
@@ -42,9 +50,12 @@ Provides RESTful endpoints for document parsing and project management.`,
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&configPath, "config", "c", "configs/llm_config.yaml", "Configuration file path")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "configs/llm_config.yaml", "Configuration file path")
 	rootCmd.Flags().StringVarP(&dbPath, "db", "d", "zhcp.db", "Path to SQLite database")
+	rootCmd.Flags().StringVar(&databaseURL, "database-url", "", "Postgres connection URL, used instead of SQLite when set")
 	rootCmd.Flags().StringVarP(&port, "port", "p", "8080", "Server port")
+	rootCmd.Flags().StringVar(&grpcPort, "grpc-port", "", "gRPC server port (disabled when empty)")
+	rootCmd.Flags().BoolVar(&swaggerUI, "swagger-ui", false, "Serve Swagger UI for the OpenAPI document at /docs")
 }
 
 func main() {
@@ -73,6 +84,10 @@ func registerProviders() {
 	ai.RegisterProvider("deepseek", func(config common.ProviderConfig) (ai.LLMProvider, error) {
 		return deepseek.NewDeepSeekProvider(config.APIKey, config.Model)
 	})
+
+	ai.RegisterProvider("openai_compatible", func(config common.ProviderConfig) (ai.LLMProvider, error) {
+		return openai_compatible.NewOpenAICompatibleProvider(config.APIKey, config.Model, config.BaseURL, config.DetailBool("json_mode", true), config.DetailBool("structured_output", false))
+	})
 }
 
 func startServer() {
@@ -94,17 +109,53 @@ func startServer() {
 	defer zhcpParser.Close()
 	log.Println("✅ Parser initialized")
 
-	// Initialize database
-	store := sqlite.New(dbPath)
+	// Initialize database: Postgres when a URL is given, so the parser can
+	// share the platform's database, otherwise the local SQLite file
+	var store storage.Storage
+	if url := stringEnv("PARSER_DATABASE_URL", databaseURL); url != "" {
+		store = postgres.New(url, stringEnv("PARSER_DATABASE_SCHEMA", postgres.DefaultSchema))
+	} else {
+		store = sqlite.New(dbPath)
+	}
 	if err := store.Init(context.Background()); err != nil {
 		log.Fatalf("❌ Error initializing database: %v", err)
 	}
 	log.Println("✅ Database initialized")
 
+	grpcPort = stringEnv("PARSER_GRPC_PORT", grpcPort)
+	swaggerUI = boolEnv("PARSER_SWAGGER_UI", swaggerUI)
+
+	// Documents and results go to S3 when a bucket is configured, so several
+	// parser nodes can share them
+	var objects storage.ObjectStore
+	if bucket := os.Getenv("PARSER_S3_BUCKET"); bucket != "" {
+		s3Store, err := s3.New(s3.Config{
+			Endpoint:        os.Getenv("PARSER_S3_ENDPOINT"),
+			Region:          stringEnv("PARSER_S3_REGION", stringEnv("AWS_REGION", "us-east-1")),
+			Bucket:          bucket,
+			Prefix:          os.Getenv("PARSER_S3_PREFIX"),
+			AccessKeyID:     stringEnv("PARSER_S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID")),
+			SecretAccessKey: stringEnv("PARSER_S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			PathStyle:       boolEnv("PARSER_S3_PATH_STYLE", false),
+		})
+		if err != nil {
+			log.Fatalf("❌ Error configuring S3 storage: %v", err)
+		}
+		objects = s3Store
+		log.Printf("✅ Documents and results stored in S3 bucket %s", bucket)
+	}
+
+	// Identical documents are answered from the database-backed result cache
+	var resultCacheTTL time.Duration
+	if cfg.ResultCache.Enabled {
+		resultCacheTTL = time.Duration(cfg.ResultCache.TTLHours) * time.Hour
+	}
+
 	// Create and start HTTP server
 	srv := server.NewServer(zhcpParser, store, port, server.ServerOptions{
 		AllowedOrigins:    splitCSVEnv("PARSER_CORS_ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:3001,http://localhost:3002"),
 		Workers:           intEnv("PARSER_WORKERS", 4),
+		MaxWorkers:        intEnv("PARSER_MAX_WORKERS", 0),
 		QueueSize:         intEnv("PARSER_QUEUE_SIZE", 64),
 		JobTTL:            durationEnvSeconds("PARSER_JOB_TTL_SEC", 1800),
 		ReadTimeout:       durationEnvSeconds("PARSER_READ_TIMEOUT_SEC", 20),
@@ -112,22 +163,72 @@ func startServer() {
 		WriteTimeout:      durationEnvSeconds("PARSER_WRITE_TIMEOUT_SEC", 30),
 		IdleTimeout:       durationEnvSeconds("PARSER_IDLE_TIMEOUT_SEC", 60),
 		ShutdownTimeout:   durationEnvSeconds("PARSER_SHUTDOWN_TIMEOUT_SEC", 10),
+		UploadDir:         stringEnv("PARSER_UPLOAD_DIR", filepath.Join(filepath.Dir(dbPath), "uploads")),
+		GRPCPort:          grpcPort,
+		ReadyTimeout:      durationEnvSeconds("PARSER_READY_TIMEOUT_SEC", 5),
+		ReadyCacheTTL:     durationEnvSeconds("PARSER_READY_CACHE_SEC", 15),
+		ResultCacheTTL:    resultCacheTTL,
+		MaxUploadBytes:    int64(intEnv("PARSER_MAX_UPLOAD_MB", 32)) << 20,
+		UploadTimeout:     durationEnvSeconds("PARSER_UPLOAD_TIMEOUT_SEC", 300),
+		SwaggerUI:         swaggerUI,
+		UploadsPerMinute:  intEnv("PARSER_UPLOADS_PER_MINUTE", 0),
+		MaxConcurrentJobs: intEnv("PARSER_MAX_CONCURRENT_JOBS", 0),
+		Objects:           objects,
+		Auth:              cfg.Auth,
+
+		WebhookSecret:      os.Getenv("PARSER_WEBHOOK_SECRET"),
+		WebhookMaxAttempts: intEnv("PARSER_WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookTimeout:     durationEnvSeconds("PARSER_WEBHOOK_TIMEOUT_SEC", 10),
+
+		WebhookAllowedHosts: splitCSVEnv("PARSER_WEBHOOK_ALLOWED_HOSTS", ""),
 	})
 	log.Printf("✅ Server configured on port %s\n", port)
+	if cfg.Auth.Enabled {
+		log.Printf("🔒 API key auth enabled for %d client(s)", len(cfg.Auth.APIKeys))
+	} else {
+		log.Println("⚠️  API key auth disabled, the API is open to anyone who can reach it")
+	}
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	log.Println("📡 API Endpoints:")
 	log.Println("  POST   /api/parse/upload")
+	log.Println("  POST   /api/parse/text")
+	log.Println("  POST   /api/parse/documents")
 	log.Println("  GET    /api/parse/status/{jobId}")
-	log.Println("  GET    /api/parse/result/{jobId}")
+	log.Println("  GET    /api/parse/stream/{jobId}")
+	log.Println("  GET    /api/parse/result/{jobId}?format=json|msproject|csv")
+	log.Println("  GET    /api/parse/jobs")
+	log.Println("  DELETE /api/parse/jobs/{jobId}")
+	log.Println("  POST   /api/parse/jobs/{jobId}/retry")
+	log.Println("  GET    /api/parse/dead-letters")
+	log.Println("  GET    /api/parse/dead-letters/{jobId}")
+	log.Println("  POST   /api/parse/dead-letters/{jobId}/requeue")
+	log.Println("  DELETE /api/parse/dead-letters/{jobId}")
+	log.Println("  POST   /api/validate")
+	log.Println("  GET    /api/usage")
 	log.Println("  GET    /api/projects")
 	log.Println("  GET    /api/projects/{id}")
 	log.Println("  POST   /api/projects")
 	log.Println("  PUT    /api/projects/{id}")
 	log.Println("  DELETE /api/projects/{id}")
 	log.Println("  GET    /api/projects/{projectId}/tasks")
+	log.Println("  GET    /api/projects/{id}/diff/{jobId}")
 	log.Println("  GET    /api/tasks/{id}")
 	log.Println("  PUT    /api/tasks/{id}")
 	log.Println("  PUT    /api/tasks/{id}/status")
+	log.Println("  GET    /api/prompts")
+	log.Println("  POST   /api/prompts/reload")
+	log.Println("  GET    /api/prompts/{name}")
+	log.Println("  DELETE /api/prompts/{name}")
+	log.Println("  POST   /api/prompts/{name}/versions")
+	log.Println("  POST   /api/prompts/{name}/versions/{version}/activate")
+	log.Println("  GET    /metrics")
+	log.Println("  GET    /openapi.json")
+	if swaggerUI {
+		log.Println("  GET    /docs")
+	}
+	if grpcPort != "" {
+		log.Printf("📡 gRPC zhcp.v1.ParserService on port %s", grpcPort)
+	}
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -154,6 +255,13 @@ func splitCSVEnv(key, fallback string) []string {
 	return out
 }
 
+func stringEnv(key, fallback string) string {
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		return raw
+	}
+	return fallback
+}
+
 func intEnv(key string, fallback int) int {
 	raw := strings.TrimSpace(os.Getenv(key))
 	if raw == "" {
@@ -166,6 +274,14 @@ func intEnv(key string, fallback int) int {
 	return parsed
 }
 
+func boolEnv(key string, fallback bool) bool {
+	parsed, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
 func durationEnvSeconds(key string, fallback int) time.Duration {
 	return time.Duration(intEnv(key, fallback)) * time.Second
 }
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"zhcp-parser-go/internal/config"
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/server"
+
+	"github.com/spf13/cobra"
+)
+
+// summaryFileName is the report written next to the results of a batch
+const summaryFileName = "summary.json"
+
+var (
+	batchOutputDir    string
+	batchWorkers      int
+	batchRecursive    bool
+	batchSkipExisting bool
+)
+
+var parseCmd = &cobra.Command{
+	Use:   "parse <input-dir>",
+	Short: "Parse a directory of documents offline",
+	Long: `Parses every supported document (PDF, DOCX, XLSX, PPTX, TXT, MD) in a
+directory without starting the HTTP server, for bulk backfills and prompt
+evaluation. Each result is written as JSON to the output directory under the
+document's relative path with .json appended, and summary.json reports the
+outcome, tokens and cost of every document. The result cache is not used.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBatch(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	parseCmd.Flags().StringVarP(&batchOutputDir, "output", "o", "results", "Directory for the JSON results and summary.json")
+	parseCmd.Flags().IntVarP(&batchWorkers, "workers", "w", 1, "Documents parsed at the same time")
+	parseCmd.Flags().BoolVarP(&batchRecursive, "recursive", "r", false, "Include documents in subdirectories")
+	parseCmd.Flags().BoolVar(&batchSkipExisting, "skip-existing", false, "Skip documents that already have a successful result in the output directory")
+	parseCmd.SilenceUsage = true
+	parseCmd.SilenceErrors = true // main prints the error
+	rootCmd.AddCommand(parseCmd)
+}
+
+// BatchSummary is written to summary.json when a batch finishes
+type BatchSummary struct {
+	InputDir     string          `json:"input_dir"`
+	OutputDir    string          `json:"output_dir"`
+	StartedAt    time.Time       `json:"started_at"`
+	FinishedAt   time.Time       `json:"finished_at"`
+	Duration     float64         `json:"duration_seconds"`
+	Documents    int             `json:"documents"`
+	Succeeded    int             `json:"succeeded"`
+	Failed       int             `json:"failed"`
+	Skipped      int             `json:"skipped"`
+	InputTokens  int             `json:"input_tokens"`
+	OutputTokens int             `json:"output_tokens"`
+	CostUSD      float64         `json:"cost_usd"`
+	Results      []BatchDocument `json:"results"`
+}
+
+// BatchDocument is the outcome of one document of a batch
+type BatchDocument struct {
+	File           string  `json:"file"`             // relative to the input directory
+	Output         string  `json:"output,omitempty"` // relative to the output directory
+	Status         string  `json:"status"`           // succeeded, failed or skipped
+	Confidence     float64 `json:"confidence,omitempty"`
+	Phases         int     `json:"phases,omitempty"`
+	Tasks          int     `json:"tasks,omitempty"`
+	ProcessingTime float64 `json:"processing_time,omitempty"`
+	Provider       string  `json:"provider,omitempty"`
+	Model          string  `json:"model,omitempty"`
+	InputTokens    int     `json:"input_tokens,omitempty"`
+	OutputTokens   int     `json:"output_tokens,omitempty"`
+	CostUSD        float64 `json:"cost_usd,omitempty"`
+	ErrorCategory  string  `json:"error_category,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// runBatch parses the documents in inputDir with batchWorkers workers. It
+// fails when any document failed, so scripts can tell from the exit code.
+func runBatch(ctx context.Context, inputDir string) error {
+	files, err := findDocuments(inputDir, batchRecursive)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no supported documents in %s", inputDir)
+	}
+	if err := os.MkdirAll(batchOutputDir, 0755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+
+	cfg, err := config.NewConfigManager(configPath).LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load configuration: %w", err)
+	}
+	zhcpParser, err := parser.NewZhcpParser(cfg)
+	if err != nil {
+		return fmt.Errorf("initialize parser: %w", err)
+	}
+	defer zhcpParser.Close()
+
+	// Ctrl+C stops the batch; documents not started yet are reported as skipped
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	summary := &BatchSummary{
+		InputDir:  inputDir,
+		OutputDir: batchOutputDir,
+		StartedAt: time.Now().UTC(),
+		Documents: len(files),
+		Results:   make([]BatchDocument, len(files)),
+	}
+
+	workers := batchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				document := parseBatchDocument(ctx, zhcpParser, inputDir, files[index])
+				summary.Results[index] = document
+
+				mu.Lock()
+				done++
+				fmt.Printf("[%d/%d] %s: %s\n", done, len(files), document.File, describeBatchDocument(document))
+				mu.Unlock()
+			}
+		}()
+	}
+	for index := range files {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	summary.FinishedAt = time.Now().UTC()
+	summary.Duration = summary.FinishedAt.Sub(summary.StartedAt).Seconds()
+	for _, document := range summary.Results {
+		switch document.Status {
+		case "succeeded":
+			summary.Succeeded++
+		case "failed":
+			summary.Failed++
+		default:
+			summary.Skipped++
+		}
+		summary.InputTokens += document.InputTokens
+		summary.OutputTokens += document.OutputTokens
+		summary.CostUSD += document.CostUSD
+	}
+
+	if err := writeJSONFile(filepath.Join(batchOutputDir, summaryFileName), summary); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+	fmt.Printf("\n%d documents: %d succeeded, %d failed, %d skipped; %d input and %d output tokens, $%.4f in %.1fs\n",
+		summary.Documents, summary.Succeeded, summary.Failed, summary.Skipped,
+		summary.InputTokens, summary.OutputTokens, summary.CostUSD, summary.Duration)
+	fmt.Printf("Summary written to %s\n", filepath.Join(batchOutputDir, summaryFileName))
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d documents failed", summary.Failed, summary.Documents)
+	}
+	return nil
+}
+
+// parseBatchDocument parses one document and writes its result
+func parseBatchDocument(ctx context.Context, zhcpParser *parser.ZhcpParser, inputDir, file string) BatchDocument {
+	document := BatchDocument{File: file, Output: file + ".json"}
+	outputPath := filepath.Join(batchOutputDir, document.Output)
+
+	if ctx.Err() != nil {
+		document.Status = "skipped"
+		document.Output = ""
+		return document
+	}
+	if batchSkipExisting && hasSuccessfulResult(outputPath) {
+		document.Status = "skipped"
+		return document
+	}
+
+	result, err := zhcpParser.ParseDocumentWithOptions(ctx, filepath.Join(inputDir, file), parser.ParseOptions{
+		Validate: true,
+		Enrich:   true,
+		Force:    true,
+	})
+	if err != nil {
+		document.Status = "failed"
+		if ctx.Err() != nil {
+			document.Status = "skipped" // interrupted
+		}
+		document.Output = ""
+		document.Error = err.Error()
+		return document
+	}
+
+	document.Status = "succeeded"
+	if !result.Success {
+		document.Status = "failed"
+	}
+	document.Confidence = result.ExtractionMetadata.Confidence
+	document.ProcessingTime = result.ExtractionMetadata.ProcessingTime
+	if usage := result.ExtractionMetadata.Usage; usage != nil {
+		document.Provider = usage.Provider
+		document.Model = usage.Model
+		document.InputTokens = usage.InputTokens
+		document.OutputTokens = usage.OutputTokens
+		document.CostUSD = usage.CostUSD
+	}
+	if result.ProjectStructure != nil {
+		document.Phases = len(result.ProjectStructure.Project.Phases)
+		for _, phase := range result.ProjectStructure.Project.Phases {
+			document.Tasks += len(phase.Tasks)
+		}
+	}
+	if result.Error != nil {
+		document.ErrorCategory = result.Error.Category
+		document.Error = result.Error.Message
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err == nil {
+		err = writeJSONFile(outputPath, result)
+	}
+	if err != nil {
+		document.Status = "failed"
+		document.Output = ""
+		document.Error = fmt.Sprintf("write result: %v", err)
+	}
+	return document
+}
+
+// findDocuments lists the supported documents in dir by their path relative
+// to it, sorted
+func findDocuments(dir string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !server.IsSupportedDocument(entry.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read input directory: %w", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// hasSuccessfulResult reports whether path holds the result of a successful
+// parse, so failed documents are retried with --skip-existing
+func hasSuccessfulResult(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var result struct {
+		Success bool `json:"success"`
+	}
+	return json.Unmarshal(data, &result) == nil && result.Success
+}
+
+func describeBatchDocument(document BatchDocument) string {
+	switch document.Status {
+	case "succeeded":
+		return fmt.Sprintf("%d phases, %d tasks, confidence %.2f", document.Phases, document.Tasks, document.Confidence)
+	case "failed":
+		if document.ErrorCategory != "" {
+			return fmt.Sprintf("failed (%s): %s", document.ErrorCategory, document.Error)
+		}
+		return "failed: " + document.Error
+	default:
+		return "skipped"
+	}
+}
+
+func writeJSONFile(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -9,6 +10,7 @@ import (
 	"zhcp-parser-go/internal/ai/llm_providers/deepseek"
 	"zhcp-parser-go/internal/ai/llm_providers/ollama"
 	"zhcp-parser-go/internal/ai/llm_providers/openai"
+	"zhcp-parser-go/internal/ai/llm_providers/openai_compatible"
 	"zhcp-parser-go/internal/common"
 	"zhcp-parser-go/internal/config"
 	"zhcp-parser-go/internal/parser"
@@ -65,7 +67,7 @@ func main() {
 		fmt.Println("No text extracted from PDF")
 	}
 
-	result, err := zhcpParser.ParseDocument(sampleDocPath, true, true)
+	result, err := zhcpParser.ParseDocument(context.Background(), sampleDocPath, true, true)
 	if err != nil {
 		fmt.Printf("Error parsing document: %v\n", err)
 		return
@@ -132,6 +134,10 @@ func registerProviders() {
 	ai.RegisterProvider("deepseek", func(config common.ProviderConfig) (ai.LLMProvider, error) {
 		return deepseek.NewDeepSeekProvider(config.APIKey, config.Model)
 	})
+
+	ai.RegisterProvider("openai_compatible", func(config common.ProviderConfig) (ai.LLMProvider, error) {
+		return openai_compatible.NewOpenAICompatibleProvider(config.APIKey, config.Model, config.BaseURL, config.DetailBool("json_mode", true), config.DetailBool("structured_output", false))
+	})
 }
 
 func truncateString(s string, maxLen int) string {
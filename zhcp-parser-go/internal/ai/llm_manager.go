@@ -3,8 +3,11 @@ package ai
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"zhcp-parser-go/internal/common"
+	"zhcp-parser-go/internal/metrics"
 )
 
 // LLMManager manages LLM providers with fallback mechanisms
@@ -12,14 +15,21 @@ type LLMManager struct {
 	config           *common.Config
 	providers        map[ProviderType]LLMProvider
 	providerPriority []ProviderType
+	retryPolicies    map[ProviderType]retryPolicy
+	breakers         map[ProviderType]*circuitBreaker
+	quotas           map[ProviderType]*providerQuota
+	router           *router
 	logger           interface{} // In a real implementation, we'd use a proper logger interface
 }
 
 // NewLLMManager creates a new LLM manager
 func NewLLMManager(config *common.Config) (*LLMManager, error) {
 	manager := &LLMManager{
-		config:    config,
-		providers: make(map[ProviderType]LLMProvider),
+		config:        config,
+		providers:     make(map[ProviderType]LLMProvider),
+		retryPolicies: make(map[ProviderType]retryPolicy),
+		breakers:      make(map[ProviderType]*circuitBreaker),
+		quotas:        make(map[ProviderType]*providerQuota),
 	}
 
 	// Initialize providers
@@ -38,8 +48,11 @@ func NewLLMManager(config *common.Config) (*LLMManager, error) {
 			manager.providerPriority = append(manager.providerPriority, OllamaProvider)
 		case "deepseek":
 			manager.providerPriority = append(manager.providerPriority, DeepSeekProvider)
+		case "openai_compatible":
+			manager.providerPriority = append(manager.providerPriority, OpenAICompatibleProvider)
 		}
 	}
+	manager.router = newRouter(config, manager.providerPriority)
 
 	return manager, nil
 }
@@ -62,6 +75,14 @@ func (lm *LLMManager) InitializeProviders() error {
 
 			providerType := getProviderType(providerName)
 			lm.providers[providerType] = provider
+
+			retry := lm.config.RetrySettings
+			if providerConfig.Retry != nil {
+				retry = *providerConfig.Retry
+			}
+			lm.retryPolicies[providerType] = newRetryPolicy(retry)
+			lm.breakers[providerType] = newCircuitBreaker(lm.config.CircuitBreaker)
+			lm.quotas[providerType] = newProviderQuota(providerConfig.Quota)
 		}
 	}
 
@@ -84,36 +105,168 @@ func getProviderType(providerName string) ProviderType {
 		return OllamaProvider
 	case "deepseek":
 		return DeepSeekProvider
+	case "openai_compatible":
+		return OpenAICompatibleProvider
 	default:
 		return OpenAIProvider // Default fallback
 	}
 }
 
-// GenerateWithFallback generates response with fallback to alternative providers
+// GenerateWithFallback generates response with fallback to alternative
+// providers along the routing chain for the document, or with opts.Provider
+// alone when it is set. Each provider is
+// retried per its retry policy; providers whose circuit breaker is open or
+// whose quota is used up are skipped. When all fail, the error is a
+// *GenerationError describing every provider's failure.
 func (lm *LLMManager) GenerateWithFallback(ctx context.Context, opts GenerationOptions, prompt string) (*LLMResponse, error) {
-	var lastError error
+	var failures []ProviderFailure
+	tried := make(map[ProviderType]bool)
 
-	for _, providerType := range lm.providerPriority {
-		provider, exists := lm.providers[providerType]
-		if !exists {
-			continue
+	chain := lm.router.chain(opts)
+	if opts.Provider != "" {
+		chain = [][]ProviderType{{getProviderType(opts.Provider)}}
+	}
+	for _, step := range chain {
+		for _, providerType := range lm.router.order(step) {
+			provider, exists := lm.providers[providerType]
+			if !exists || tried[providerType] {
+				continue
+			}
+			tried[providerType] = true
+
+			response, failure := lm.generateWithRetry(ctx, providerType, provider, lm.providerOptions(providerType, opts), prompt)
+			if failure == nil {
+				return response, nil
+			}
+			failures = append(failures, *failure)
+
+			if ctx.Err() != nil {
+				return nil, &GenerationError{Failures: failures}
+			}
+		}
+	}
+
+	return nil, &GenerationError{Failures: failures}
+}
+
+// providerOptions drops the response schema for providers configured with
+// structured_output: false, e.g. models that predate structured outputs
+func (lm *LLMManager) providerOptions(providerType ProviderType, opts GenerationOptions) GenerationOptions {
+	if opts.ResponseSchema != nil && !lm.config.Providers[string(providerType)].DetailBool("structured_output", true) {
+		opts.ResponseSchema = nil
+	}
+	return opts
+}
+
+// generateWithRetry calls one provider until it succeeds, fails with an
+// error its retry policy does not cover, or runs out of retries
+func (lm *LLMManager) generateWithRetry(ctx context.Context, providerType ProviderType, provider LLMProvider, opts GenerationOptions, prompt string) (*LLMResponse, *ProviderFailure) {
+	breaker := lm.breakers[providerType]
+	if err := breaker.allow(); err != nil {
+		return nil, &ProviderFailure{Provider: providerType, Kind: FailureCircuitOpen, Error: err.Error()}
+	}
+
+	policy := lm.retryPolicies[providerType]
+	quota := lm.quotas[providerType]
+	var err error
+	attempts := 0
+	for {
+		if quotaErr := quota.take(); quotaErr != nil {
+			if attempts == 0 {
+				breaker.release()
+				return nil, &ProviderFailure{Provider: providerType, Kind: FailureQuota, Error: quotaErr.Error()}
+			}
+			err = fmt.Errorf("%w (retry skipped: %v)", err, quotaErr)
+			break
+		}
+		attempts++
+
+		started := time.Now()
+		var response *LLMResponse
+		response, err = provider.Generate(ctx, opts, prompt)
+		if err == nil && response != nil {
+			response.Provider = providerType
+			response.Cost = lm.cost(providerType, provider, response)
+		}
+		observeProviderCall(providerType, time.Since(started), response, err)
+		if err == nil {
+			breaker.success()
+			if response != nil {
+				quota.addTokens(response.TokensUsed.Total)
+			}
+			return response, nil
+		}
+
+		if attempts > policy.maxRetries || !policy.shouldRetry(err) {
+			break
+		}
+		if sleepErr := sleepContext(ctx, policy.delay(attempts, err)); sleepErr != nil {
+			err = fmt.Errorf("%w (retry cancelled: %v)", err, sleepErr)
+			break
 		}
+	}
 
-		// In a real implementation, you'd handle context cancellation
-		response, err := provider.Generate(opts, prompt)
-		if err != nil {
-			lastError = err
-			continue
+	kind := ClassifyError(err)
+	// Rejected prompts and cancelled calls say nothing about the provider's health
+	if kind == FailureBadRequest || kind == FailureCanceled {
+		breaker.release()
+	} else {
+		breaker.failure()
+	}
+	return nil, &ProviderFailure{Provider: providerType, Kind: kind, Attempts: attempts, Error: err.Error()}
+}
+
+// CheckProviders probes every initialized provider concurrently, in priority
+// order. Each probe is bounded by ctx.
+func (lm *LLMManager) CheckProviders(ctx context.Context) []ProviderHealth {
+	order := make([]ProviderType, 0, len(lm.providers))
+	seen := make(map[ProviderType]bool)
+	for _, providerType := range lm.providerPriority {
+		if _, exists := lm.providers[providerType]; exists && !seen[providerType] {
+			order = append(order, providerType)
+			seen[providerType] = true
 		}
+	}
+	for providerType := range lm.providers {
+		if !seen[providerType] {
+			order = append(order, providerType)
+		}
+	}
+
+	results := make([]ProviderHealth, len(order))
+	var wg sync.WaitGroup
+	for i, providerType := range order {
+		wg.Add(1)
+		go func(i int, providerType ProviderType) {
+			defer wg.Done()
+			results[i] = checkProvider(ctx, providerType, lm.providers[providerType])
+			results[i].Circuit = lm.breakers[providerType].state()
+		}(i, providerType)
+	}
+	wg.Wait()
 
-		return response, nil
+	return results
+}
+
+func checkProvider(ctx context.Context, providerType ProviderType, provider LLMProvider) ProviderHealth {
+	health := ProviderHealth{Provider: providerType, Status: "unknown"}
+
+	checker, ok := provider.(HealthChecker)
+	if !ok {
+		return health
 	}
 
-	if lastError != nil {
-		return nil, fmt.Errorf("all providers failed. Last error: %w", lastError)
+	started := time.Now()
+	err := checker.Ping(ctx)
+	health.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		health.Status = "error"
+		health.Error = err.Error()
+		return health
 	}
 
-	return nil, fmt.Errorf("no providers configured or available")
+	health.Status = "ok"
+	return health
 }
 
 // GetProvider returns a specific provider
@@ -136,3 +289,37 @@ func (lm *LLMManager) UpdateConfig(config *common.Config) error {
 	lm.config = config
 	return lm.InitializeProviders()
 }
+
+// cost prices a response with the model pricing from the provider config,
+// falling back to the provider's built-in estimate for unlisted models
+func (lm *LLMManager) cost(providerType ProviderType, provider LLMProvider, response *LLMResponse) float64 {
+	input, output := response.TokensUsed.Input, response.TokensUsed.Output
+	providerConfig := lm.config.Providers[string(providerType)]
+
+	// Routers may answer with a more specific model name than configured
+	pricing, ok := providerConfig.Pricing[response.Model]
+	if !ok {
+		pricing, ok = providerConfig.Pricing[providerConfig.Model]
+	}
+	if ok {
+		return (float64(input)*pricing.InputPerMillion + float64(output)*pricing.OutputPerMillion) / 1_000_000
+	}
+	return provider.GetCostEstimate(input, output)
+}
+
+// observeProviderCall records latency, outcome and token usage of a provider call
+func observeProviderCall(providerType ProviderType, elapsed time.Duration, response *LLMResponse, err error) {
+	provider := string(providerType)
+	metrics.LLMRequestDuration.WithLabelValues(provider).Observe(elapsed.Seconds())
+	if err != nil {
+		metrics.LLMRequests.WithLabelValues(provider, "error").Inc()
+		return
+	}
+
+	metrics.LLMRequests.WithLabelValues(provider, "success").Inc()
+	if response != nil {
+		metrics.LLMTokens.WithLabelValues(provider, "input").Add(float64(response.TokensUsed.Input))
+		metrics.LLMTokens.WithLabelValues(provider, "output").Add(float64(response.TokensUsed.Output))
+		metrics.LLMCost.WithLabelValues(provider, response.Model).Add(response.Cost)
+	}
+}
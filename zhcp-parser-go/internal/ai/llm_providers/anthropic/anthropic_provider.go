@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
@@ -37,11 +36,28 @@ func NewAnthropicProvider(apiKey, model string) (*AnthropicProvider, error) {
 
 // MessageRequest represents the request structure for Anthropic API
 type MessageRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens"`
-	Temperature float32   `json:"temperature,omitempty"`
-	System      string    `json:"system,omitempty"`
+	Model       string      `json:"model"`
+	Messages    []Message   `json:"messages"`
+	MaxTokens   int         `json:"max_tokens"`
+	Temperature float32     `json:"temperature,omitempty"`
+	System      string      `json:"system,omitempty"`
+	Tools       []Tool      `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+}
+
+// Tool describes a tool the model may call. Forcing the model to call a tool
+// whose input schema is the response schema is how the Messages API returns
+// structured output.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// ToolChoice forces the model to call the named tool
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 // Message represents a message in the conversation
@@ -60,8 +76,10 @@ type MessageResponse struct {
 
 // Content represents the content in the response
 type Content struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Name  string          `json:"name,omitempty"`  // with type tool_use
+	Input json.RawMessage `json:"input,omitempty"` // with type tool_use
 }
 
 // Usage represents token usage
@@ -71,8 +89,8 @@ type Usage struct {
 }
 
 // Generate generates a response from the Anthropic API
-func (p *AnthropicProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (p *AnthropicProvider) Generate(ctx context.Context, opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
 	// Use the model from options if provided, otherwise use the default
@@ -103,6 +121,14 @@ func (p *AnthropicProvider) Generate(opts ai.GenerationOptions, prompt string) (
 		Temperature: temperature,
 		System:      "You are an expert in extracting structured project information from documents. Return only valid JSON without additional text.",
 	}
+	if opts.ResponseSchema != nil {
+		request.Tools = []Tool{{
+			Name:        opts.ResponseSchema.Name,
+			Description: "Record the information extracted from the document.",
+			InputSchema: opts.ResponseSchema.Schema,
+		}}
+		request.ToolChoice = &ToolChoice{Type: "tool", Name: opts.ResponseSchema.Name}
+	}
 
 	requestBody, err := json.Marshal(request)
 	if err != nil {
@@ -125,8 +151,7 @@ func (p *AnthropicProvider) Generate(opts ai.GenerationOptions, prompt string) (
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Anthropic API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, ai.NewStatusError("Anthropic", resp)
 	}
 
 	var apiResponse MessageResponse
@@ -138,7 +163,7 @@ func (p *AnthropicProvider) Generate(opts ai.GenerationOptions, prompt string) (
 		return nil, fmt.Errorf("no content returned from Anthropic API")
 	}
 
-	content := apiResponse.Content[0].Text
+	content, structured := responseContent(apiResponse.Content, opts.ResponseSchema)
 
 	// Calculate tokens used
 	tokensUsed := ai.TokenUsage{
@@ -156,11 +181,30 @@ func (p *AnthropicProvider) Generate(opts ai.GenerationOptions, prompt string) (
 		Confidence: confidence,
 		Model:      model,
 		Timestamp:  time.Now(),
+		Structured: structured,
 	}
 
 	return response, nil
 }
 
+// responseContent returns the input of the call to the schema tool when
+// there is one, and the text of the response otherwise
+func responseContent(blocks []Content, schema *ai.ResponseSchema) (string, bool) {
+	if schema != nil {
+		for _, block := range blocks {
+			if block.Type == "tool_use" && block.Name == schema.Name && len(block.Input) > 0 {
+				return string(block.Input), true
+			}
+		}
+	}
+	for _, block := range blocks {
+		if block.Type == "text" {
+			return block.Text, false
+		}
+	}
+	return blocks[0].Text, false
+}
+
 // GetCostEstimate calculates cost based on Anthropic pricing
 func (p *AnthropicProvider) GetCostEstimate(inputTokens, outputTokens int) float64 {
 	// Example pricing (Claude 3 Sonnet): $3/1M input tokens, $15/1M output tokens
@@ -169,6 +213,28 @@ func (p *AnthropicProvider) GetCostEstimate(inputTokens, outputTokens int) float
 	return inputCost + outputCost
 }
 
+// Ping checks that the API is reachable and the key is accepted by listing
+// the available models
+func (p *AnthropicProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Anthropic API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ai.NewStatusError("Anthropic", resp)
+	}
+	return nil
+}
+
 // GetProviderType returns the provider type
 func (p *AnthropicProvider) GetProviderType() ai.ProviderType {
 	return ai.AnthropicProvider
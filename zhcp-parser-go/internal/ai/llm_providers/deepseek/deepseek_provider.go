@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
@@ -77,9 +76,9 @@ type Usage struct {
 }
 
 // Generate generates a response from the DeepSeek API
-func (p *DeepSeekProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
+func (p *DeepSeekProvider) Generate(ctx context.Context, opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
 	// Increased timeout to 5 minutes to handle large documents and slow responses
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
 	defer cancel()
 
 	// Use the model from options if provided, otherwise use the default
@@ -136,8 +135,7 @@ func (p *DeepSeekProvider) Generate(opts ai.GenerationOptions, prompt string) (*
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("DeepSeek API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, ai.NewStatusError("DeepSeek", resp)
 	}
 
 	var apiResponse ChatCompletionResponse
@@ -178,6 +176,27 @@ func (p *DeepSeekProvider) GetCostEstimate(inputTokens, outputTokens int) float6
 	return 0.0 // Assuming free or minimal cost for demonstration
 }
 
+// Ping checks that the API is reachable and the key is accepted by listing
+// the available models
+func (p *DeepSeekProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("DeepSeek API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ai.NewStatusError("DeepSeek", resp)
+	}
+	return nil
+}
+
 // GetProviderType returns the provider type
 func (p *DeepSeekProvider) GetProviderType() ai.ProviderType {
 	return ai.DeepSeekProvider
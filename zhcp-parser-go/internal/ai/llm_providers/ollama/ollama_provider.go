@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -36,10 +35,11 @@ func NewOllamaProvider(model, baseURL string) (*OllamaProvider, error) {
 
 // GenerateRequest represents the request structure for Ollama API
 type GenerateRequest struct {
-	Model   string  `json:"model"`
-	Prompt  string  `json:"prompt"`
-	Stream  bool    `json:"stream"`
-	Options Options `json:"options,omitempty"`
+	Model   string      `json:"model"`
+	Prompt  string      `json:"prompt"`
+	Stream  bool        `json:"stream"`
+	Format  interface{} `json:"format,omitempty"` // a JSON schema, Ollama 0.5 and later
+	Options Options     `json:"options,omitempty"`
 }
 
 // Options contains model options
@@ -63,8 +63,8 @@ type GenerateResponse struct {
 }
 
 // Generate generates a response from the local Ollama instance
-func (p *OllamaProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+func (p *OllamaProvider) Generate(ctx context.Context, opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel()
 
 	// Use the model from options if provided, otherwise use the default
@@ -92,6 +92,9 @@ func (p *OllamaProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 			NumPredict:  maxTokens,
 		},
 	}
+	if opts.ResponseSchema != nil {
+		request.Format = opts.ResponseSchema.Schema
+	}
 
 	requestBody, err := json.Marshal(request)
 	if err != nil {
@@ -112,8 +115,7 @@ func (p *OllamaProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Ollama API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, ai.NewStatusError("Ollama", resp)
 	}
 
 	var apiResponse GenerateResponse
@@ -123,16 +125,18 @@ func (p *OllamaProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 
 	content := apiResponse.Response
 
-	// Calculate tokens used (Ollama doesn't provide exact token counts, so we estimate)
-	inputTokens := len(strings.Fields(prompt))
-	outputTokens := len(strings.Fields(content))
-	totalTokens := inputTokens + outputTokens
-
+	// Ollama reports token counts in eval counters; older versions omit
+	// them, so fall back to estimating from the word count
 	tokensUsed := ai.TokenUsage{
-		Input:  inputTokens,
-		Output: outputTokens,
-		Total:  totalTokens,
+		Input:  apiResponse.PromptEvalCount,
+		Output: apiResponse.EvalCount,
 	}
+	if tokensUsed.Input == 0 && tokensUsed.Output == 0 {
+		tokensUsed.Input = len(strings.Fields(prompt))
+		tokensUsed.Output = len(strings.Fields(content))
+		tokensUsed.Estimated = true
+	}
+	tokensUsed.Total = tokensUsed.Input + tokensUsed.Output
 
 	// Calculate confidence based on response quality
 	confidence := p.calculateConfidence(content)
@@ -143,6 +147,7 @@ func (p *OllamaProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 		Confidence: confidence,
 		Model:      model,
 		Timestamp:  time.Now(),
+		Structured: opts.ResponseSchema != nil,
 	}
 
 	return response, nil
@@ -154,6 +159,25 @@ func (p *OllamaProvider) GetCostEstimate(inputTokens, outputTokens int) float64
 	return 0.0
 }
 
+// Ping checks that the Ollama server is reachable by listing local models
+func (p *OllamaProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Ollama API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ai.NewStatusError("Ollama", resp)
+	}
+	return nil
+}
+
 // GetProviderType returns the provider type
 func (p *OllamaProvider) GetProviderType() ai.ProviderType {
 	return ai.OllamaProvider
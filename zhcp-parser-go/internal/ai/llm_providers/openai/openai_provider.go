@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
@@ -52,7 +51,17 @@ type Message struct {
 
 // ResponseFormat specifies the format of the response
 type ResponseFormat struct {
-	Type string `json:"type"`
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"` // with type json_schema
+}
+
+// JSONSchema is the schema structured outputs must follow. Strict mode is
+// left off: it requires every property to be listed as required, which the
+// project schema does not do.
+type JSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
 }
 
 // ChatCompletionResponse represents the response from OpenAI API
@@ -76,9 +85,9 @@ type Usage struct {
 }
 
 // Generate generates a response from the OpenAI API
-func (p *OpenAIProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
+func (p *OpenAIProvider) Generate(ctx context.Context, opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
 	// Increased timeout to 5 minutes for large documents
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
 	defer cancel()
 
 	// Use the model from options if provided, otherwise use the default
@@ -113,6 +122,12 @@ func (p *OpenAIProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 		MaxTokens:      maxTokens,
 		ResponseFormat: &ResponseFormat{Type: "json_object"},
 	}
+	if opts.ResponseSchema != nil {
+		request.ResponseFormat = &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &JSONSchema{Name: opts.ResponseSchema.Name, Schema: opts.ResponseSchema.Schema},
+		}
+	}
 
 	requestBody, err := json.Marshal(request)
 	if err != nil {
@@ -134,8 +149,7 @@ func (p *OpenAIProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, ai.NewStatusError("OpenAI", resp)
 	}
 
 	var apiResponse ChatCompletionResponse
@@ -165,6 +179,7 @@ func (p *OpenAIProvider) Generate(opts ai.GenerationOptions, prompt string) (*ai
 		Confidence: confidence,
 		Model:      model,
 		Timestamp:  time.Now(),
+		Structured: opts.ResponseSchema != nil,
 	}
 
 	return response, nil
@@ -178,6 +193,27 @@ func (p *OpenAIProvider) GetCostEstimate(inputTokens, outputTokens int) float64
 	return inputCost + outputCost
 }
 
+// Ping checks that the API is reachable and the key is accepted by listing
+// the available models
+func (p *OpenAIProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ai.NewStatusError("OpenAI", resp)
+	}
+	return nil
+}
+
 // GetProviderType returns the provider type
 func (p *OpenAIProvider) GetProviderType() ai.ProviderType {
 	return ai.OpenAIProvider
@@ -0,0 +1,266 @@
+package openai_compatible
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"zhcp-parser-go/internal/ai"
+)
+
+// OpenAICompatibleProvider implements the LLMProvider interface for any
+// server speaking the OpenAI chat completions API, such as vLLM, LM Studio
+// or OpenRouter
+type OpenAICompatibleProvider struct {
+	apiKey     string // optional, many self-hosted servers need none
+	model      string
+	baseURL    string
+	jsonMode   bool // request response_format json_object
+	jsonSchema bool // request response_format json_schema when given a schema
+	client     *http.Client
+}
+
+// NewOpenAICompatibleProvider creates a provider for the API at baseURL,
+// e.g. "http://localhost:8000/v1". Disable jsonMode for servers that reject
+// response_format; enable jsonSchema for servers with structured outputs,
+// such as vLLM, LM Studio and OpenRouter.
+func NewOpenAICompatibleProvider(apiKey, model, baseURL string, jsonMode, jsonSchema bool) (*OpenAICompatibleProvider, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("OpenAI-compatible provider requires base_url")
+	}
+	if model == "" {
+		return nil, fmt.Errorf("OpenAI-compatible provider requires a model")
+	}
+
+	return &OpenAICompatibleProvider{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		jsonMode:   jsonMode,
+		jsonSchema: jsonSchema,
+		client:     &http.Client{Timeout: 300 * time.Second}, // self-hosted models can be slow
+	}, nil
+}
+
+// ChatCompletionRequest represents the request structure for the chat completions API
+type ChatCompletionRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Temperature    float32         `json:"temperature,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// Message represents a message in the conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ResponseFormat specifies the format of the response
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"` // with type json_schema
+}
+
+// JSONSchema is the schema structured outputs must follow
+type JSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
+}
+
+// ChatCompletionResponse represents the response from the chat completions API
+type ChatCompletionResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage"`
+}
+
+// Choice represents a choice in the response
+type Choice struct {
+	Index   int     `json:"index"`
+	Message Message `json:"message"`
+}
+
+// Usage represents token usage
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Generate generates a response from the configured server
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, opts ai.GenerationOptions, prompt string) (*ai.LLMResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
+	defer cancel()
+
+	// Use the model from options if provided, otherwise use the default
+	model := opts.Model
+	if model == "" {
+		model = p.model
+	}
+
+	temperature := float32(opts.Temperature)
+	if temperature == 0 {
+		temperature = 0.1
+	}
+
+	maxTokens := opts.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
+
+	request := ChatCompletionRequest{
+		Model: model,
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: "You are an expert in extracting structured project information from documents. Return only valid JSON without additional text.",
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+	}
+	structured := p.jsonMode && p.jsonSchema && opts.ResponseSchema != nil
+	switch {
+	case structured:
+		request.ResponseFormat = &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &JSONSchema{Name: opts.ResponseSchema.Name, Schema: opts.ResponseSchema.Schema},
+		}
+	case p.jsonMode:
+		request.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI-compatible API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ai.NewStatusError("OpenAI-compatible", resp)
+	}
+
+	var apiResponse ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode OpenAI-compatible response: %w", err)
+	}
+
+	if len(apiResponse.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from OpenAI-compatible API")
+	}
+
+	content := apiResponse.Choices[0].Message.Content
+
+	// Some servers omit usage; estimate from word counts then
+	var tokensUsed ai.TokenUsage
+	if apiResponse.Usage != nil {
+		tokensUsed = ai.TokenUsage{
+			Input:  apiResponse.Usage.PromptTokens,
+			Output: apiResponse.Usage.CompletionTokens,
+			Total:  apiResponse.Usage.TotalTokens,
+		}
+	} else {
+		tokensUsed = ai.TokenUsage{
+			Input:     len(strings.Fields(prompt)),
+			Output:    len(strings.Fields(content)),
+			Estimated: true,
+		}
+		tokensUsed.Total = tokensUsed.Input + tokensUsed.Output
+	}
+
+	// Routers like OpenRouter report the model that actually answered
+	if apiResponse.Model != "" {
+		model = apiResponse.Model
+	}
+
+	response := &ai.LLMResponse{
+		Content:    content,
+		TokensUsed: tokensUsed,
+		Confidence: calculateConfidence(content, tokensUsed),
+		Model:      model,
+		Timestamp:  time.Now(),
+		Structured: structured,
+	}
+
+	return response, nil
+}
+
+// GetCostEstimate returns 0: prices vary per deployment, configure them
+// with the provider's pricing instead
+func (p *OpenAICompatibleProvider) GetCostEstimate(inputTokens, outputTokens int) float64 {
+	return 0.0
+}
+
+// Ping checks that the server is reachable by listing its models
+func (p *OpenAICompatibleProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI-compatible API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ai.NewStatusError("OpenAI-compatible", resp)
+	}
+	return nil
+}
+
+// GetProviderType returns the provider type
+func (p *OpenAICompatibleProvider) GetProviderType() ai.ProviderType {
+	return ai.OpenAICompatibleProvider
+}
+
+func (p *OpenAICompatibleProvider) authorize(req *http.Request) {
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+}
+
+// calculateConfidence scores a response by whether it is JSON and how much
+// the model produced
+func calculateConfidence(content string, usage ai.TokenUsage) float64 {
+	content = strings.TrimSpace(content)
+	if len(content) < 10 {
+		return 0.1
+	}
+	if !json.Valid([]byte(content)) {
+		return 0.3
+	}
+
+	lengthFactor := float64(usage.Total) / 1000
+	if lengthFactor > 1 {
+		lengthFactor = 1
+	}
+	return (1 + lengthFactor) / 2
+}
@@ -1,20 +1,30 @@
 package prompt_engineering
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
+// extractionPromptName is the template used to extract the project
+// structure; language variants add a suffix, e.g. project_extraction_kk
+const extractionPromptName = "project_extraction"
+
 // PromptManager manages prompt templates and creation
 type PromptManager struct {
 	promptsDir   string
 	prompts      map[string]PromptTemplate
 	employeePool EmployeePool
-	logger       interface{} // In a real implementation, we'd use a proper logger interface
+	versions     map[string]int // stored version of each template, absent when read from promptsDir
+	logger       interface{}    // In a real implementation, we'd use a proper logger interface
+	mu           sync.RWMutex
 }
 
 // NewPromptManager creates a new prompt manager
@@ -22,6 +32,7 @@ func NewPromptManager(promptsDir string) *PromptManager {
 	pm := &PromptManager{
 		promptsDir: promptsDir,
 		prompts:    make(map[string]PromptTemplate),
+		versions:   make(map[string]int),
 	}
 
 	// Load all prompt templates
@@ -74,6 +85,50 @@ func (pm *PromptManager) loadPrompts() {
 	}
 }
 
+// Reload reads the templates and the employee pool from promptsDir again,
+// dropping stored versions set with SetStoredPrompt
+func (pm *PromptManager) Reload() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.prompts = make(map[string]PromptTemplate)
+	pm.versions = make(map[string]int)
+	pm.loadPrompts()
+	pm.loadEmployeePool()
+}
+
+// SetStoredPrompt replaces a template with a version kept outside
+// promptsDir, e.g. in the database; it takes effect for the next prompt
+func (pm *PromptManager) SetStoredPrompt(name string, template PromptTemplate, version int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.prompts[name] = template
+	pm.versions[name] = version
+}
+
+// StoredVersion returns the stored version of a template, or 0 when the
+// template was read from promptsDir
+func (pm *PromptManager) StoredVersion(name string) int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.versions[name]
+}
+
+// ValidateTemplate checks that a template has text and uses every
+// parameter it declares
+func ValidateTemplate(template PromptTemplate) error {
+	if strings.TrimSpace(template.Template) == "" {
+		return fmt.Errorf("template text is empty")
+	}
+	for _, param := range template.Parameters {
+		if !strings.Contains(template.Template, "{"+param+"}") {
+			return fmt.Errorf("parameter '%s' is not used in the template", param)
+		}
+	}
+	return nil
+}
+
 // createDefaultPrompts creates default prompt templates
 func (pm *PromptManager) createDefaultPrompts() {
 	// Create prompts directory if it doesn't exist
@@ -104,6 +159,7 @@ Extract this information and return ONLY a valid JSON object with the following
 Important guidelines:
 - Use Russian terminology where appropriate in the output
 - If dates are not explicitly mentioned, set to null
+- Put the total budget in the budget of the project and the costs of phases and tasks in their cost, as {"amount": number, "currency": "KZT"} with the amount as a plain number (1,2 млн → 1200000) and the currency as an ISO 4217 code; set them to null when the document gives no amount
 - If responsible persons are not explicitly mentioned, set to empty array
 - Estimate confidence scores based on clarity of information in the document
 - Use UUID-like strings for IDs (e.g., "phase_1", "task_1_1")
@@ -129,7 +185,9 @@ Important guidelines:
 
 // GetPrompt gets a formatted prompt with provided arguments
 func (pm *PromptManager) GetPrompt(promptName string, args map[string]interface{}) (string, error) {
+	pm.mu.RLock()
 	promptTemplate, exists := pm.prompts[promptName]
+	pm.mu.RUnlock()
 	if !exists {
 		return "", fmt.Errorf("prompt '%s' not found", promptName)
 	}
@@ -162,8 +220,32 @@ func (pm *PromptManager) GetPrompt(promptName string, args map[string]interface{
 
 // CreateExtractionPrompt creates a specialized prompt for project structure extraction
 func (pm *PromptManager) CreateExtractionPrompt(documentContent string, jsonSchema map[string]interface{}) (string, error) {
+	return pm.CreateLanguageExtractionPrompt("", documentContent, jsonSchema)
+}
+
+// ExtractionPromptName returns the extraction template for a document
+// language: project_extraction_<language> when there is one, otherwise
+// project_extraction
+func (pm *PromptManager) ExtractionPromptName(language string) string {
+	if language != "" {
+		name := extractionPromptName + "_" + language
+		pm.mu.RLock()
+		_, exists := pm.prompts[name]
+		pm.mu.RUnlock()
+		if exists {
+			return name
+		}
+	}
+	return extractionPromptName
+}
+
+// CreateLanguageExtractionPrompt is CreateExtractionPrompt with the
+// template of the document language
+func (pm *PromptManager) CreateLanguageExtractionPrompt(language, documentContent string, jsonSchema map[string]interface{}) (string, error) {
 	// Format employee pool for prompt
+	pm.mu.RLock()
 	employeePoolStr := pm.formatEmployeePool()
+	pm.mu.RUnlock()
 
 	args := map[string]interface{}{
 		"document_content": documentContent,
@@ -171,21 +253,29 @@ func (pm *PromptManager) CreateExtractionPrompt(documentContent string, jsonSche
 		"employee_pool":    employeePoolStr,
 	}
 
-	return pm.GetPrompt("project_extraction", args)
+	return pm.GetPrompt(pm.ExtractionPromptName(language), args)
 }
 
 // AddPrompt adds a new prompt template
 func (pm *PromptManager) AddPrompt(name string, template PromptTemplate) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	pm.prompts[name] = template
 }
 
 // RemovePrompt removes a prompt template
 func (pm *PromptManager) RemovePrompt(name string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	delete(pm.prompts, name)
+	delete(pm.versions, name)
 }
 
 // ListPrompts returns a list of available prompt names
 func (pm *PromptManager) ListPrompts() []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
 	names := make([]string, 0, len(pm.prompts))
 	for name := range pm.prompts {
 		names = append(names, name)
@@ -195,6 +285,8 @@ func (pm *PromptManager) ListPrompts() []string {
 
 // GetPromptTemplate returns a specific prompt template
 func (pm *PromptManager) GetPromptTemplate(name string) (PromptTemplate, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
 	template, exists := pm.prompts[name]
 	return template, exists
 }
@@ -216,7 +308,9 @@ func (pm *PromptManager) SavePrompt(name string, template PromptTemplate) error
 	}
 
 	// Add to in-memory prompts
+	pm.mu.Lock()
 	pm.prompts[name] = template
+	pm.mu.Unlock()
 
 	return nil
 }
@@ -224,7 +318,9 @@ func (pm *PromptManager) SavePrompt(name string, template PromptTemplate) error
 // UpdatePrompt updates an existing prompt template
 func (pm *PromptManager) UpdatePrompt(name string, template PromptTemplate) error {
 	// Check if prompt exists
+	pm.mu.RLock()
 	_, exists := pm.prompts[name]
+	pm.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("prompt '%s' does not exist", name)
 	}
@@ -296,7 +392,35 @@ func (pm *PromptManager) formatEmployeePool() string {
 	return builder.String()
 }
 
+// Version identifies the extraction prompts of all languages together with
+// the employee pool they embed, so it changes whenever any is edited
+func (pm *PromptManager) Version() string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var names []string
+	for name := range pm.prompts {
+		if name == extractionPromptName || strings.HasPrefix(name, extractionPromptName+"_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(pm.prompts[name].Template))
+		hash.Write([]byte{0})
+	}
+	hash.Write([]byte{0})
+	hash.Write([]byte(pm.formatEmployeePool()))
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
 // GetEmployeePool returns the current employee pool
 func (pm *PromptManager) GetEmployeePool() EmployeePool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
 	return pm.employeePool
 }
@@ -0,0 +1,340 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zhcp-parser-go/internal/common"
+)
+
+// maxRetryDelay caps backoff and Retry-After waits between attempts
+const maxRetryDelay = time.Minute
+
+// FailureKind classifies why a provider call failed
+type FailureKind string
+
+const (
+	FailureRateLimited FailureKind = "rate_limited"
+	FailureServer      FailureKind = "server_error"
+	FailureTimeout     FailureKind = "timeout"
+	FailureNetwork     FailureKind = "network"
+	FailureAuth        FailureKind = "auth"
+	FailureBadRequest  FailureKind = "bad_request"
+	FailureCircuitOpen FailureKind = "circuit_open"
+	FailureQuota       FailureKind = "quota_exceeded"
+	FailureCanceled    FailureKind = "canceled" // the caller gave up, e.g. the parse job was cancelled
+	FailureUnknown     FailureKind = "unknown"
+)
+
+// StatusError is a non-success HTTP answer from a provider API
+type StatusError struct {
+	Service    string // provider name used in the message, e.g. "OpenAI"
+	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header, 0 when absent
+	Body       string
+}
+
+// NewStatusError reads a failed provider response. The caller still closes
+// the body.
+func NewStatusError(service string, resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &StatusError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Body:       string(body),
+	}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s API request failed with status %d: %s", e.Service, e.StatusCode, e.Body)
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date values
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// ClassifyError maps a provider error to a FailureKind
+func ClassifyError(err error) FailureKind {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusTooManyRequests:
+			return FailureRateLimited
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return FailureAuth
+		case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+			return FailureTimeout
+		case code >= 500:
+			return FailureServer
+		case code >= 400:
+			return FailureBadRequest
+		}
+		return FailureUnknown
+	}
+
+	var circuitErr *circuitOpenError
+	if errors.As(err, &circuitErr) {
+		return FailureCircuitOpen
+	}
+	var quotaErr *quotaExceededError
+	if errors.As(err, &quotaErr) {
+		return FailureQuota
+	}
+
+	// Checked before net.Error, which the HTTP client wraps cancellations in
+	if errors.Is(err, context.Canceled) {
+		return FailureCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return FailureTimeout
+		}
+		return FailureNetwork
+	}
+	return FailureUnknown
+}
+
+// Retryable reports whether a later attempt may succeed
+func (k FailureKind) Retryable() bool {
+	switch k {
+	case FailureRateLimited, FailureServer, FailureTimeout, FailureNetwork, FailureCircuitOpen, FailureQuota:
+		return true
+	}
+	return false
+}
+
+// ProviderFailure is how one provider failed during GenerateWithFallback
+type ProviderFailure struct {
+	Provider ProviderType `json:"provider"`
+	Kind     FailureKind  `json:"kind"`
+	Attempts int          `json:"attempts"`
+	Error    string       `json:"error"`
+}
+
+// GenerationError is returned by GenerateWithFallback when every provider
+// failed. Failures are in the order the providers were tried.
+type GenerationError struct {
+	Failures []ProviderFailure
+}
+
+func (e *GenerationError) Error() string {
+	if len(e.Failures) == 0 {
+		return "no providers configured or available"
+	}
+	return fmt.Sprintf("all providers failed. Last error: %s", e.Failures[len(e.Failures)-1].Error)
+}
+
+// Kind is the kind shared by all failures, or that of the last provider
+// tried when they differ
+func (e *GenerationError) Kind() FailureKind {
+	if len(e.Failures) == 0 {
+		return FailureUnknown
+	}
+	kind := e.Failures[0].Kind
+	for _, failure := range e.Failures[1:] {
+		if failure.Kind != kind {
+			return e.Failures[len(e.Failures)-1].Kind
+		}
+	}
+	return kind
+}
+
+// Retryable reports whether any provider may succeed on a later attempt
+func (e *GenerationError) Retryable() bool {
+	for _, failure := range e.Failures {
+		if failure.Kind.Retryable() {
+			return true
+		}
+	}
+	return false
+}
+
+// retryPolicy decides whether and when a failed provider call is repeated
+type retryPolicy struct {
+	maxRetries    int
+	backoffFactor float64 // seconds before the first retry, doubled each time
+	statusCodes   map[int]bool
+}
+
+func newRetryPolicy(settings common.RetrySettings) retryPolicy {
+	policy := retryPolicy{
+		maxRetries:    settings.MaxRetries,
+		backoffFactor: settings.BackoffFactor,
+		statusCodes:   make(map[int]bool),
+	}
+	if policy.backoffFactor <= 0 {
+		policy.backoffFactor = 1
+	}
+	for _, code := range settings.StatusCodes {
+		policy.statusCodes[code] = true
+	}
+	return policy
+}
+
+// shouldRetry retries network failures and the configured status codes
+func (p retryPolicy) shouldRetry(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return p.statusCodes[statusErr.StatusCode]
+	}
+	switch ClassifyError(err) {
+	case FailureTimeout, FailureNetwork:
+		return true
+	}
+	return false
+}
+
+// delay is the wait before retry number attempt (starting at 1), stretched
+// to the provider's Retry-After when that is longer
+func (p retryPolicy) delay(attempt int, err error) time.Duration {
+	wait := time.Duration(p.backoffFactor * float64(time.Second) * float64(int(1)<<(attempt-1)))
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+		wait = statusErr.RetryAfter
+	}
+	if wait > maxRetryDelay {
+		wait = maxRetryDelay
+	}
+	return wait
+}
+
+// circuitOpenError is reported for providers skipped by their breaker
+type circuitOpenError struct {
+	until time.Time
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open until %s after repeated failures", e.until.Format(time.RFC3339))
+}
+
+// Circuit breaker states reported in ProviderHealth
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// circuitBreaker takes a provider out of rotation after threshold
+// consecutive failed calls. After the cooldown a single trial call is let
+// through; its outcome closes the circuit or opens it again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(settings common.CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: settings.FailureThreshold,
+		cooldown:  time.Duration(settings.CooldownSeconds) * time.Second,
+	}
+}
+
+// allow reports whether a call may go to the provider
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return &circuitOpenError{until: b.openUntil}
+	}
+	b.probing = true
+	return nil
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// release gives up a call allowed but never made, so a half-open breaker
+// lets the next one through
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+func (b *circuitBreaker) state() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.openUntil.IsZero():
+		return CircuitClosed
+	case time.Now().Before(b.openUntil):
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// sleepContext waits for d unless ctx ends first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
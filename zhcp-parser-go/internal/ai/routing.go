@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"zhcp-parser-go/internal/common"
+)
+
+// router picks the fallback chain for a generation. A chain is a list of
+// steps; the providers of a step share traffic by weight.
+type router struct {
+	defaultChain [][]ProviderType
+	chains       map[string][][]ProviderType
+	rules        []common.RoutingRule
+	weights      map[ProviderType]int
+}
+
+func newRouter(config *common.Config, priority []ProviderType) *router {
+	r := &router{
+		chains:  make(map[string][][]ProviderType),
+		rules:   config.Routing.Rules,
+		weights: make(map[ProviderType]int),
+	}
+
+	for name, steps := range config.Routing.Chains {
+		chain := make([][]ProviderType, 0, len(steps))
+		for _, step := range steps {
+			providers := make([]ProviderType, 0, len(step.Providers))
+			for _, providerName := range step.Providers {
+				providers = append(providers, getProviderType(providerName))
+			}
+			chain = append(chain, providers)
+		}
+		r.chains[name] = chain
+	}
+
+	if chain, ok := r.chains[config.Routing.DefaultChain]; ok {
+		r.defaultChain = chain
+	} else {
+		// provider_priority is a chain of single-provider steps
+		for _, providerType := range priority {
+			r.defaultChain = append(r.defaultChain, []ProviderType{providerType})
+		}
+	}
+
+	for providerName, providerConfig := range config.Providers {
+		r.weights[getProviderType(providerName)] = providerConfig.Weight
+	}
+	return r
+}
+
+// chain returns the chain of the first rule matching the document, or the
+// default chain
+func (r *router) chain(opts GenerationOptions) [][]ProviderType {
+	for _, rule := range r.rules {
+		if ruleMatches(rule, opts) {
+			if chain, ok := r.chains[rule.Chain]; ok {
+				return chain
+			}
+		}
+	}
+	return r.defaultChain
+}
+
+func ruleMatches(rule common.RoutingRule, opts GenerationOptions) bool {
+	if len(rule.DocumentTypes) > 0 {
+		matched := false
+		for _, documentType := range rule.DocumentTypes {
+			if documentType == opts.DocumentType {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if rule.MinChars > 0 && opts.DocumentChars < rule.MinChars {
+		return false
+	}
+	if rule.MaxChars > 0 && opts.DocumentChars > rule.MaxChars {
+		return false
+	}
+	return true
+}
+
+// order shuffles the providers of a step so that each comes first with a
+// probability proportional to its weight
+func (r *router) order(step []ProviderType) []ProviderType {
+	if len(step) < 2 {
+		return step
+	}
+
+	remaining := append([]ProviderType(nil), step...)
+	ordered := make([]ProviderType, 0, len(step))
+	for len(remaining) > 0 {
+		total := 0
+		for _, providerType := range remaining {
+			total += r.weight(providerType)
+		}
+
+		pick := rand.IntN(total)
+		for i, providerType := range remaining {
+			pick -= r.weight(providerType)
+			if pick < 0 {
+				ordered = append(ordered, providerType)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+func (r *router) weight(providerType ProviderType) int {
+	if weight := r.weights[providerType]; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// quotaExceededError is reported for providers skipped by their quota
+type quotaExceededError struct {
+	limit string
+	until time.Time
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exhausted until %s", e.limit, e.until.Format(time.RFC3339))
+}
+
+// providerQuota counts requests per minute and tokens per UTC day in fixed
+// windows
+type providerQuota struct {
+	mu       sync.Mutex
+	limits   common.ProviderQuota
+	minute   time.Time
+	requests int
+	day      time.Time
+	tokens   int
+}
+
+// newProviderQuota returns nil when the provider is unlimited
+func newProviderQuota(limits *common.ProviderQuota) *providerQuota {
+	if limits == nil || (limits.RequestsPerMinute <= 0 && limits.TokensPerDay <= 0) {
+		return nil
+	}
+	return &providerQuota{limits: *limits}
+}
+
+// take counts a request, or reports that the quota is exhausted
+func (q *providerQuota) take() error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now().UTC()
+	q.rollWindows(now)
+
+	if q.limits.TokensPerDay > 0 && q.tokens >= q.limits.TokensPerDay {
+		return &quotaExceededError{limit: "daily token", until: q.day.AddDate(0, 0, 1)}
+	}
+	if q.limits.RequestsPerMinute > 0 && q.requests >= q.limits.RequestsPerMinute {
+		return &quotaExceededError{limit: "per-minute request", until: q.minute.Add(time.Minute)}
+	}
+	q.requests++
+	return nil
+}
+
+// addTokens counts the tokens of a finished request
+func (q *providerQuota) addTokens(tokens int) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollWindows(time.Now().UTC())
+	q.tokens += tokens
+}
+
+func (q *providerQuota) rollWindows(now time.Time) {
+	if minute := now.Truncate(time.Minute); !minute.Equal(q.minute) {
+		q.minute = minute
+		q.requests = 0
+	}
+	if day := now.Truncate(24 * time.Hour); !day.Equal(q.day) {
+		q.day = day
+		q.tokens = 0
+	}
+}
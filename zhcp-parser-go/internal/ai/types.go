@@ -1,6 +1,9 @@
 package ai
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // ProviderType represents the type of LLM provider
 type ProviderType string
@@ -10,6 +13,10 @@ const (
 	AnthropicProvider ProviderType = "anthropic"
 	OllamaProvider    ProviderType = "ollama"
 	DeepSeekProvider  ProviderType = "deepseek"
+
+	// OpenAICompatibleProvider is any server with the OpenAI chat
+	// completions API at a configured base URL
+	OpenAICompatibleProvider ProviderType = "openai_compatible"
 )
 
 // GenerationOptions contains options for LLM generation
@@ -17,28 +24,65 @@ type GenerationOptions struct {
 	Temperature float64 `json:"temperature"`
 	MaxTokens   int     `json:"max_tokens"`
 	Model       string  `json:"model"`
+
+	// Provider, when set, is the only provider called, instead of the
+	// routing chain
+	Provider string `json:"provider,omitempty"`
+
+	// Routing hints describing the document behind the prompt
+	DocumentType  string `json:"document_type,omitempty"`
+	DocumentChars int    `json:"document_chars,omitempty"`
+
+	// ResponseSchema asks providers with native structured output to return
+	// JSON matching the schema; others ignore it and answer in free text
+	ResponseSchema *ResponseSchema `json:"response_schema,omitempty"`
+}
+
+// ResponseSchema is a JSON schema the LLM response must follow
+type ResponseSchema struct {
+	Name   string                 `json:"name"` // identifier sent to the API, e.g. "project_structure"
+	Schema map[string]interface{} `json:"schema"`
 }
 
 // LLMResponse represents the response from an LLM
 type LLMResponse struct {
-	Content    string      `json:"content"`
-	TokensUsed TokenUsage  `json:"tokens_used"`
-	Confidence float64     `json:"confidence"`
-	Model      string      `json:"model"`
-	Timestamp  time.Time   `json:"timestamp"`
-	ParsedData interface{} `json:"parsed_data,omitempty"` // Will be set after JSON parsing
+	Content    string       `json:"content"`
+	TokensUsed TokenUsage   `json:"tokens_used"`
+	Confidence float64      `json:"confidence"`
+	Model      string       `json:"model"`
+	Provider   ProviderType `json:"provider"`
+	Cost       float64      `json:"cost"` // USD, set by LLMManager from the configured pricing
+	Timestamp  time.Time    `json:"timestamp"`
+	ParsedData interface{}  `json:"parsed_data,omitempty"` // Will be set after JSON parsing
+	Structured bool         `json:"structured,omitempty"`  // the provider enforced ResponseSchema
 }
 
 // TokenUsage represents token usage information
 type TokenUsage struct {
-	Input  int `json:"input"`
-	Output int `json:"output"`
-	Total  int `json:"total"`
+	Input     int  `json:"input"`
+	Output    int  `json:"output"`
+	Total     int  `json:"total"`
+	Estimated bool `json:"estimated,omitempty"` // the provider reported no counts
 }
 
 // LLMProvider is the interface for LLM providers
 type LLMProvider interface {
-	Generate(opts GenerationOptions, prompt string) (*LLMResponse, error)
+	Generate(ctx context.Context, opts GenerationOptions, prompt string) (*LLMResponse, error)
 	GetCostEstimate(inputTokens, outputTokens int) float64
 	GetProviderType() ProviderType
 }
+
+// HealthChecker is implemented by providers that can be probed without
+// generating anything, e.g. by listing models
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+// ProviderHealth is the outcome of probing a provider
+type ProviderHealth struct {
+	Provider  ProviderType `json:"provider"`
+	Status    string       `json:"status"` // ok, error or unknown (no probe available)
+	LatencyMs int64        `json:"latency_ms"`
+	Circuit   string       `json:"circuit,omitempty"` // circuit breaker state: closed, open or half_open
+	Error     string       `json:"error,omitempty"`
+}
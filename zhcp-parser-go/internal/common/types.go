@@ -2,13 +2,32 @@ package common
 
 // ProviderConfig holds configuration for an LLM provider
 type ProviderConfig struct {
-	Enabled     bool                   `yaml:"enabled" json:"enabled"`
-	APIKey      string                 `yaml:"api_key" json:"api_key"`
-	Model       string                 `yaml:"model" json:"model"`
-	Temperature float64                `yaml:"temperature" json:"temperature"`
-	MaxTokens   int                    `yaml:"max_tokens" json:"max_tokens"`
-	BaseURL     string                 `yaml:"base_url,omitempty" json:"base_url,omitempty"`
-	Details     map[string]interface{} `yaml:",inline" json:",omitempty"`
+	Enabled     bool                    `yaml:"enabled" json:"enabled"`
+	APIKey      string                  `yaml:"api_key" json:"api_key"`
+	Model       string                  `yaml:"model" json:"model"`
+	Temperature float64                 `yaml:"temperature" json:"temperature"`
+	MaxTokens   int                     `yaml:"max_tokens" json:"max_tokens"`
+	BaseURL     string                  `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	Pricing     map[string]ModelPricing `yaml:"pricing,omitempty" json:"pricing,omitempty"` // by model name
+	Retry       *RetrySettings          `yaml:"retry,omitempty" json:"retry,omitempty"`     // overrides retry_settings
+	Weight      int                     `yaml:"weight,omitempty" json:"weight,omitempty"`   // share within a routing step, default 1
+	Quota       *ProviderQuota          `yaml:"quota,omitempty" json:"quota,omitempty"`
+	Details     map[string]interface{}  `yaml:",inline" json:",omitempty"`
+}
+
+// DetailBool reads a boolean provider option that has no field of its own,
+// e.g. json_mode of the openai_compatible provider
+func (c ProviderConfig) DetailBool(key string, fallback bool) bool {
+	if value, ok := c.Details[key].(bool); ok {
+		return value
+	}
+	return fallback
+}
+
+// ModelPricing is the price of a model in USD per million tokens
+type ModelPricing struct {
+	InputPerMillion  float64 `yaml:"input_per_million" json:"input_per_million"`
+	OutputPerMillion float64 `yaml:"output_per_million" json:"output_per_million"`
 }
 
 // RetrySettings holds retry configuration
@@ -18,6 +37,44 @@ type RetrySettings struct {
 	StatusCodes   []int   `yaml:"status_codes" json:"status_codes"`
 }
 
+// ProviderQuota caps the use of a provider; a provider over quota is skipped
+// like a failing one. Zero values are unlimited.
+type ProviderQuota struct {
+	RequestsPerMinute int `yaml:"requests_per_minute" json:"requests_per_minute"`
+	TokensPerDay      int `yaml:"tokens_per_day" json:"tokens_per_day"` // UTC day
+}
+
+// RoutingConfig picks the fallback chain for a document. Without chains,
+// provider_priority is the only chain.
+type RoutingConfig struct {
+	DefaultChain string                   `yaml:"default_chain" json:"default_chain"`
+	Chains       map[string][]RoutingStep `yaml:"chains" json:"chains"`
+	Rules        []RoutingRule            `yaml:"rules" json:"rules"` // first match wins
+}
+
+// RoutingStep is one link of a fallback chain. Its providers share the
+// traffic by weight; the next step is only tried when all of them fail.
+type RoutingStep struct {
+	Providers []string `yaml:"providers" json:"providers"`
+}
+
+// RoutingRule sends matching documents to a chain. Unset criteria match
+// every document.
+type RoutingRule struct {
+	Name          string   `yaml:"name" json:"name"`
+	Chain         string   `yaml:"chain" json:"chain"`
+	DocumentTypes []string `yaml:"document_types,omitempty" json:"document_types,omitempty"` // pdf, docx, xlsx, pptx, text, markdown
+	MinChars      int      `yaml:"min_chars,omitempty" json:"min_chars,omitempty"`           // extracted text length
+	MaxChars      int      `yaml:"max_chars,omitempty" json:"max_chars,omitempty"`
+}
+
+// CircuitBreakerConfig takes a provider out of rotation after repeated
+// failures. A zero failure threshold disables the breaker.
+type CircuitBreakerConfig struct {
+	FailureThreshold int `yaml:"failure_threshold" json:"failure_threshold"`
+	CooldownSeconds  int `yaml:"cooldown_seconds" json:"cooldown_seconds"`
+}
+
 // RateLimiting holds rate limiting configuration
 type RateLimiting struct {
 	RequestsPerMinute int `yaml:"requests_per_minute" json:"requests_per_minute"`
@@ -29,8 +86,59 @@ type Config struct {
 	Providers        map[string]ProviderConfig `yaml:"providers" json:"providers"`
 	ProviderPriority []string                  `yaml:"provider_priority" json:"provider_priority"`
 	RetrySettings    RetrySettings             `yaml:"retry_settings" json:"retry_settings"`
+	CircuitBreaker   CircuitBreakerConfig      `yaml:"circuit_breaker" json:"circuit_breaker"`
+	Routing          RoutingConfig             `yaml:"routing,omitempty" json:"routing,omitempty"`
 	RateLimiting     RateLimiting              `yaml:"rate_limiting" json:"rate_limiting"`
 	ErrorHandling    ErrorHandlingConfig       `yaml:"error_handling" json:"error_handling"`
+	OCR              OCRConfig                 `yaml:"ocr" json:"ocr"`
+	Auth             AuthConfig                `yaml:"auth" json:"auth"`
+	ResultCache      ResultCacheConfig         `yaml:"result_cache" json:"result_cache"`
+	Chunking         ChunkingConfig            `yaml:"chunking" json:"chunking"`
+	Repair           RepairConfig              `yaml:"repair" json:"repair"`
+	Generation       GenerationConfig          `yaml:"generation" json:"generation"`
+	Redaction        RedactionConfig           `yaml:"redaction" json:"redaction"`
+}
+
+// ResultCacheConfig holds caching of parse results for identical documents
+type ResultCacheConfig struct {
+	Enabled    bool `yaml:"enabled" json:"enabled"`
+	TTLHours   int  `yaml:"ttl_hours" json:"ttl_hours"`
+	MaxEntries int  `yaml:"max_entries,omitempty" json:"max_entries,omitempty"` // in-memory cache only
+}
+
+// ChunkingConfig holds map-reduce parsing of documents too long for one LLM
+// call: the text is split into overlapping chunks that are parsed separately
+// and merged into one project structure
+type ChunkingConfig struct {
+	Enabled      bool `yaml:"enabled" json:"enabled"`
+	MaxChars     int  `yaml:"max_chars" json:"max_chars"`                         // longer text is chunked, default 40000
+	OverlapChars int  `yaml:"overlap_chars" json:"overlap_chars"`                 // repeated at the start of the next chunk, default 1500
+	MaxChunks    int  `yaml:"max_chunks,omitempty" json:"max_chunks,omitempty"`   // longer documents are rejected, default 20
+	Concurrency  int  `yaml:"concurrency,omitempty" json:"concurrency,omitempty"` // chunks parsed at once, default 2
+}
+
+// RepairConfig holds re-prompting the LLM with the errors of a response the
+// DataTransformer could not use
+type RepairConfig struct {
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"` // repair calls per LLM response, 0 disables
+}
+
+// GenerationConfig holds the LLM settings of extraction calls and the bounds
+// on the overrides a parse request may ask for
+type GenerationConfig struct {
+	Temperature    float64             `yaml:"temperature" json:"temperature"`           // default 0.1
+	MaxTokens      int                 `yaml:"max_tokens" json:"max_tokens"`             // default 4096
+	MaxTemperature float64             `yaml:"max_temperature" json:"max_temperature"`   // highest a request may set, default 1
+	MaxTokensLimit int                 `yaml:"max_tokens_limit" json:"max_tokens_limit"` // most a request may set, default 16384
+	Models         map[string][]string `yaml:"models,omitempty" json:"models,omitempty"` // by provider, models a request may pick besides the configured one
+}
+
+// RedactionConfig holds masking of personal data (phone numbers, emails,
+// IINs and passport numbers) in document text before it is sent to LLM
+// providers outside the local network
+type RedactionConfig struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled"`
+	LocalProviders []string `yaml:"local_providers,omitempty" json:"local_providers,omitempty"` // get the text unmasked, default [ollama]
 }
 
 // ErrorHandlingConfig holds error handling configuration
@@ -41,3 +149,44 @@ type ErrorHandlingConfig struct {
 	ErrorTolerance  float64 `yaml:"error_tolerance" json:"error_tolerance"`
 	RecoveryEnabled bool    `yaml:"recovery_enabled" json:"recovery_enabled"`
 }
+
+// OCRConfig holds configuration for recognizing scanned (image-only) PDFs
+type OCRConfig struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled"`
+	Engine         string   `yaml:"engine" json:"engine"`       // "tesseract" or "service"
+	Languages      []string `yaml:"languages" json:"languages"` // language hints: ru, kk, en
+	TesseractPath  string   `yaml:"tesseract_path,omitempty" json:"tesseract_path,omitempty"`
+	PdftoppmPath   string   `yaml:"pdftoppm_path,omitempty" json:"pdftoppm_path,omitempty"`
+	DPI            int      `yaml:"dpi,omitempty" json:"dpi,omitempty"`
+	ServiceURL     string   `yaml:"service_url,omitempty" json:"service_url,omitempty"`
+	APIKey         string   `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	TimeoutSeconds int      `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+	MinTextChars   int      `yaml:"min_text_chars,omitempty" json:"min_text_chars,omitempty"`
+}
+
+// API key scopes of zhcp-server
+const (
+	ScopeParse    = "parse"    // parse, status, result and text extraction endpoints
+	ScopeProjects = "projects" // project and task CRUD
+	ScopePrompts  = "prompts"  // prompt template management
+	ScopeAdmin    = "admin"    // every client's jobs, dead letters and usage
+)
+
+// AuthConfig holds API key authentication for zhcp-server
+type AuthConfig struct {
+	Enabled bool           `yaml:"enabled" json:"enabled"`
+	APIKeys []APIKeyConfig `yaml:"api_keys" json:"api_keys"`
+}
+
+// APIKeyConfig describes a client allowed to call zhcp-server
+type APIKeyConfig struct {
+	Name              string   `yaml:"name" json:"name"`
+	Key               string   `yaml:"key" json:"key"`
+	Scopes            []string `yaml:"scopes" json:"scopes"`                                               // parse, projects, prompts
+	RequestsPerMinute int      `yaml:"requests_per_minute,omitempty" json:"requests_per_minute,omitempty"` // 0 = unlimited
+
+	// Limits on parse job submissions, replacing the server's per-client
+	// defaults when set
+	UploadsPerMinute  int `yaml:"uploads_per_minute,omitempty" json:"uploads_per_minute,omitempty"`
+	MaxConcurrentJobs int `yaml:"max_concurrent_jobs,omitempty" json:"max_concurrent_jobs,omitempty"`
+}
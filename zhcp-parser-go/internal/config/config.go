@@ -128,13 +128,31 @@ func (cm *ConfigManager) validateConfig(config *common.Config) error {
 	for providerName, providerConfig := range config.Providers {
 		if providerConfig.Enabled {
 			// Validate required fields based on provider
-			if providerConfig.APIKey == "" && providerName != "ollama" {
+			// Local and self-hosted servers usually need no key
+			if providerConfig.APIKey == "" && providerName != "ollama" && providerName != "openai_compatible" {
 				return fmt.Errorf("provider %s is enabled but API key is not set", providerName)
 			}
+			if providerName == "openai_compatible" && providerConfig.BaseURL == "" {
+				return fmt.Errorf("provider %s is enabled but base_url is not set", providerName)
+			}
 			if providerConfig.Model == "" {
 				return fmt.Errorf("provider %s is enabled but model is not set", providerName)
 			}
 		}
+		if providerConfig.Weight < 0 {
+			return fmt.Errorf("provider %s weight must not be negative", providerName)
+		}
+		if quota := providerConfig.Quota; quota != nil && (quota.RequestsPerMinute < 0 || quota.TokensPerDay < 0) {
+			return fmt.Errorf("provider %s quota must not be negative", providerName)
+		}
+		if retry := providerConfig.Retry; retry != nil && (retry.MaxRetries < 0 || retry.BackoffFactor < 0) {
+			return fmt.Errorf("provider %s retry settings must not be negative", providerName)
+		}
+		for model, pricing := range providerConfig.Pricing {
+			if pricing.InputPerMillion < 0 || pricing.OutputPerMillion < 0 {
+				return fmt.Errorf("provider %s has a negative price for model %s", providerName, model)
+			}
+		}
 	}
 
 	// Validate provider priority list references existing providers
@@ -144,6 +162,44 @@ func (cm *ConfigManager) validateConfig(config *common.Config) error {
 		}
 	}
 
+	// Validate retry and circuit breaker settings
+	if config.RetrySettings.MaxRetries < 0 || config.RetrySettings.BackoffFactor < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	if config.CircuitBreaker.FailureThreshold > 0 && config.CircuitBreaker.CooldownSeconds <= 0 {
+		return fmt.Errorf("circuit breaker cooldown_seconds must be positive")
+	}
+
+	// Validate routing chains and rules
+	for name, chain := range config.Routing.Chains {
+		if len(chain) == 0 {
+			return fmt.Errorf("routing chain %s has no steps", name)
+		}
+		for _, step := range chain {
+			if len(step.Providers) == 0 {
+				return fmt.Errorf("routing chain %s has a step without providers", name)
+			}
+			for _, providerName := range step.Providers {
+				if _, exists := config.Providers[providerName]; !exists {
+					return fmt.Errorf("routing chain %s references non-existent provider: %s", name, providerName)
+				}
+			}
+		}
+	}
+	if name := config.Routing.DefaultChain; name != "" {
+		if _, exists := config.Routing.Chains[name]; !exists {
+			return fmt.Errorf("routing default_chain references non-existent chain: %s", name)
+		}
+	}
+	for _, rule := range config.Routing.Rules {
+		if _, exists := config.Routing.Chains[rule.Chain]; !exists {
+			return fmt.Errorf("routing rule %q references non-existent chain: %s", rule.Name, rule.Chain)
+		}
+		if rule.MaxChars > 0 && rule.MinChars > rule.MaxChars {
+			return fmt.Errorf("routing rule %q has min_chars above max_chars", rule.Name)
+		}
+	}
+
 	// Validate rate limiting values
 	if config.RateLimiting.RequestsPerMinute <= 0 {
 		return fmt.Errorf("requests per minute must be positive")
@@ -152,6 +208,85 @@ func (cm *ConfigManager) validateConfig(config *common.Config) error {
 		return fmt.Errorf("tokens per minute must be positive")
 	}
 
+	// Validate OCR settings
+	if config.OCR.Enabled {
+		switch config.OCR.Engine {
+		case "", "tesseract":
+		case "service":
+			if config.OCR.ServiceURL == "" {
+				return fmt.Errorf("OCR service is enabled but service_url is not set")
+			}
+		default:
+			return fmt.Errorf("unknown OCR engine: %s", config.OCR.Engine)
+		}
+	}
+
+	// Validate result cache settings
+	if config.ResultCache.Enabled && config.ResultCache.TTLHours <= 0 {
+		return fmt.Errorf("result cache is enabled but ttl_hours is not positive")
+	}
+
+	// Validate chunking settings
+	if config.Chunking.Enabled {
+		if config.Chunking.MaxChars < 0 || config.Chunking.OverlapChars < 0 ||
+			config.Chunking.MaxChunks < 0 || config.Chunking.Concurrency < 0 {
+			return fmt.Errorf("chunking settings must not be negative")
+		}
+		if config.Chunking.MaxChars > 0 && config.Chunking.OverlapChars*2 >= config.Chunking.MaxChars {
+			return fmt.Errorf("chunking overlap_chars must be less than half of max_chars")
+		}
+	}
+
+	if config.Repair.MaxAttempts < 0 {
+		return fmt.Errorf("repair max_attempts must not be negative")
+	}
+
+	// Validate generation settings
+	generation := config.Generation
+	if generation.Temperature < 0 || generation.MaxTokens < 0 || generation.MaxTemperature < 0 || generation.MaxTokensLimit < 0 {
+		return fmt.Errorf("generation settings must not be negative")
+	}
+	if generation.MaxTemperature > 0 && generation.Temperature > generation.MaxTemperature {
+		return fmt.Errorf("generation temperature must not exceed max_temperature")
+	}
+	if generation.MaxTokensLimit > 0 && generation.MaxTokens > generation.MaxTokensLimit {
+		return fmt.Errorf("generation max_tokens must not exceed max_tokens_limit")
+	}
+
+	// Validate API keys
+	if config.Auth.Enabled {
+		if len(config.Auth.APIKeys) == 0 {
+			return fmt.Errorf("auth is enabled but no API keys are configured")
+		}
+		seen := make(map[string]bool)
+		for _, apiKey := range config.Auth.APIKeys {
+			if apiKey.Name == "" {
+				return fmt.Errorf("API key name is required")
+			}
+			if apiKey.Key == "" {
+				return fmt.Errorf("API key %s has no key set", apiKey.Name)
+			}
+			if seen[apiKey.Key] {
+				return fmt.Errorf("API key %s duplicates another key", apiKey.Name)
+			}
+			seen[apiKey.Key] = true
+			if len(apiKey.Scopes) == 0 {
+				return fmt.Errorf("API key %s has no scopes", apiKey.Name)
+			}
+			for _, scope := range apiKey.Scopes {
+				if scope != common.ScopeParse && scope != common.ScopeProjects && scope != common.ScopePrompts && scope != common.ScopeAdmin {
+					return fmt.Errorf("API key %s has unknown scope: %s", apiKey.Name, scope)
+				}
+			}
+			if apiKey.RequestsPerMinute < 0 {
+				return fmt.Errorf("API key %s requests per minute must not be negative", apiKey.Name)
+			}
+			if apiKey.UploadsPerMinute < 0 || apiKey.MaxConcurrentJobs < 0 {
+				return fmt.Errorf("API key %s job limits must not be negative", apiKey.Name)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -219,7 +354,11 @@ func (cm *ConfigManager) getDefaultConfig() *common.Config {
 		RetrySettings: common.RetrySettings{
 			MaxRetries:    3,
 			BackoffFactor: 1.0,
-			StatusCodes:   []int{429, 502, 503, 504},
+			StatusCodes:   []int{429, 500, 502, 503, 504},
+		},
+		CircuitBreaker: common.CircuitBreakerConfig{
+			FailureThreshold: 5,
+			CooldownSeconds:  60,
 		},
 		RateLimiting: common.RateLimiting{
 			RequestsPerMinute: 60,
@@ -232,6 +371,37 @@ func (cm *ConfigManager) getDefaultConfig() *common.Config {
 			ErrorTolerance:  0.1,
 			RecoveryEnabled: true,
 		},
+		OCR: common.OCRConfig{
+			Enabled:   false,
+			Engine:    "tesseract",
+			Languages: []string{"ru", "kk", "en"},
+			DPI:       300,
+		},
+		ResultCache: common.ResultCacheConfig{
+			Enabled:    true,
+			TTLHours:   168,
+			MaxEntries: 500,
+		},
+		Chunking: common.ChunkingConfig{
+			Enabled:      true,
+			MaxChars:     40000,
+			OverlapChars: 1500,
+			MaxChunks:    20,
+			Concurrency:  2,
+		},
+		Repair: common.RepairConfig{
+			MaxAttempts: 2,
+		},
+		Generation: common.GenerationConfig{
+			Temperature:    0.1,
+			MaxTokens:      4096,
+			MaxTemperature: 1,
+			MaxTokensLimit: 16384,
+		},
+		Redaction: common.RedactionConfig{
+			Enabled:        true,
+			LocalProviders: []string{"ollama"},
+		},
 	}
 }
 
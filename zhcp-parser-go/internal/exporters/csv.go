@@ -0,0 +1,59 @@
+package exporters
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"zhcp-parser-go/internal/transformers"
+)
+
+// utf8BOM marks the CSV as UTF-8 for spreadsheet applications
+const utf8BOM = "\uFEFF"
+
+// tasksCSVHeader names the columns of the tasks CSV
+var tasksCSVHeader = []string{
+	"phase_id", "phase", "task_id", "task", "description", "start_date", "end_date",
+	"status", "responsible", "roles", "dependencies",
+}
+
+// TasksCSV flattens a project structure into one row per task. Lists such
+// as responsible persons and dependencies are joined with "; ". The file
+// starts with a UTF-8 byte order mark, so Excel opens Cyrillic text
+// correctly.
+func TasksCSV(data *transformers.ProjectStructure) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(utf8BOM)
+
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(tasksCSVHeader); err != nil {
+		return nil, fmt.Errorf("write tasks CSV: %w", err)
+	}
+
+	for _, phase := range data.Project.Phases {
+		for _, task := range phase.Tasks {
+			var names, roles []string
+			for _, person := range task.ResponsiblePersons {
+				names = append(names, person.Name)
+				if person.Role != "" {
+					roles = append(roles, person.Role)
+				}
+			}
+
+			row := []string{
+				phase.ID, phase.Name, task.ID, task.Name, task.Description, task.StartDate, task.EndDate,
+				task.Status, strings.Join(names, "; "), strings.Join(roles, "; "), strings.Join(task.Dependencies, "; "),
+			}
+			if err := writer.Write(row); err != nil {
+				return nil, fmt.Errorf("write tasks CSV: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("write tasks CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
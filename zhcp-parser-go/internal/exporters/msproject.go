@@ -0,0 +1,245 @@
+package exporters
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"zhcp-parser-go/internal/transformers"
+)
+
+// msProjectNamespace is the namespace of the MS Project XML (MSPDI) format
+const msProjectNamespace = "http://schemas.microsoft.com/project"
+
+// Working hours of a day in MS Project's default calendar
+const (
+	workdayStart = "T08:00:00"
+	workdayEnd   = "T17:00:00"
+	hoursPerDay  = 8
+)
+
+// Project XML structures (only the elements we fill in)
+
+type msProject struct {
+	XMLName           xml.Name       `xml:"Project"`
+	Xmlns             string         `xml:"xmlns,attr"`
+	Name              string         `xml:"Name"`
+	Title             string         `xml:"Title"`
+	CreationDate      string         `xml:"CreationDate"`
+	ScheduleFromStart int            `xml:"ScheduleFromStart"`
+	StartDate         string         `xml:"StartDate,omitempty"`
+	FinishDate        string         `xml:"FinishDate,omitempty"`
+	Tasks             []msTask       `xml:"Tasks>Task"`
+	Resources         []msResource   `xml:"Resources>Resource"`
+	Assignments       []msAssignment `xml:"Assignments>Assignment"`
+}
+
+type msTask struct {
+	UID             int             `xml:"UID"`
+	ID              int             `xml:"ID"`
+	Name            string          `xml:"Name"`
+	OutlineNumber   string          `xml:"OutlineNumber"`
+	OutlineLevel    int             `xml:"OutlineLevel"`
+	Summary         int             `xml:"Summary"`
+	Start           string          `xml:"Start,omitempty"`
+	Finish          string          `xml:"Finish,omitempty"`
+	Duration        string          `xml:"Duration,omitempty"`
+	PercentComplete int             `xml:"PercentComplete"`
+	Notes           string          `xml:"Notes,omitempty"`
+	PredecessorLink []msPredecessor `xml:"PredecessorLink"`
+}
+
+type msPredecessor struct {
+	PredecessorUID int `xml:"PredecessorUID"`
+	Type           int `xml:"Type"` // 1 is finish-to-start
+}
+
+type msResource struct {
+	UID          int    `xml:"UID"`
+	ID           int    `xml:"ID"`
+	Name         string `xml:"Name"`
+	Group        string `xml:"Group,omitempty"`
+	EmailAddress string `xml:"EmailAddress,omitempty"`
+}
+
+type msAssignment struct {
+	UID         int `xml:"UID"`
+	TaskUID     int `xml:"TaskUID"`
+	ResourceUID int `xml:"ResourceUID"`
+	Units       int `xml:"Units"`
+}
+
+// MSProjectXML converts a project structure to MS Project XML, which MS
+// Project, ProjectLibre and GanttProject import. Phases become summary
+// tasks, responsible persons resources assigned to their tasks, and
+// dependencies finish-to-start links. Completed tasks are 100% complete,
+// the others 0%. Tasks without dates are left for the importing tool to
+// schedule.
+func MSProjectXML(data *transformers.ProjectStructure, created time.Time) ([]byte, error) {
+	project := data.Project
+	out := msProject{
+		Xmlns:             msProjectNamespace,
+		Name:              project.Title,
+		Title:             project.Title,
+		CreationDate:      created.UTC().Format("2006-01-02T15:04:05"),
+		ScheduleFromStart: 1,
+	}
+
+	taskUIDs := make(map[string]int)
+	uid := 0
+	for _, phase := range project.Phases {
+		uid++
+		taskUIDs[phase.ID] = uid
+		for _, task := range phase.Tasks {
+			uid++
+			taskUIDs[task.ID] = uid
+		}
+	}
+
+	resourceUIDs := make(map[string]int)
+	var start, finish string
+	for i, phase := range project.Phases {
+		summary := msTask{
+			UID:           taskUIDs[phase.ID],
+			ID:            taskUIDs[phase.ID],
+			Name:          phase.Name,
+			OutlineNumber: fmt.Sprintf("%d", i+1),
+			OutlineLevel:  1,
+			Summary:       1,
+			Notes:         phase.Description,
+		}
+		setDates(&summary, phase.StartDate, phase.EndDate)
+		summaryIndex := len(out.Tasks)
+		out.Tasks = append(out.Tasks, summary)
+
+		completed := 0
+		for j, task := range phase.Tasks {
+			item := msTask{
+				UID:           taskUIDs[task.ID],
+				ID:            taskUIDs[task.ID],
+				Name:          task.Name,
+				OutlineNumber: fmt.Sprintf("%d.%d", i+1, j+1),
+				OutlineLevel:  2,
+				Notes:         task.Description,
+			}
+			setDates(&item, task.StartDate, task.EndDate)
+			if task.Status == "completed" {
+				item.PercentComplete = 100
+				completed++
+			}
+			for _, dep := range task.Dependencies {
+				if predecessor, ok := taskUIDs[dep]; ok && dep != task.ID {
+					item.PredecessorLink = append(item.PredecessorLink, msPredecessor{PredecessorUID: predecessor, Type: 1})
+				}
+			}
+			out.Tasks = append(out.Tasks, item)
+
+			for _, person := range task.ResponsiblePersons {
+				out.addAssignment(resourceUIDs, item.UID, person)
+			}
+			start = earliest(start, task.StartDate)
+			finish = latest(finish, task.EndDate)
+		}
+		if len(phase.Tasks) > 0 {
+			out.Tasks[summaryIndex].PercentComplete = 100 * completed / len(phase.Tasks)
+		}
+		start = earliest(start, phase.StartDate)
+		finish = latest(finish, phase.EndDate)
+	}
+	finish = latest(finish, project.Deadline)
+	if start != "" {
+		out.StartDate = start + workdayStart
+	}
+	if finish != "" {
+		out.FinishDate = finish + workdayEnd
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return nil, fmt.Errorf("encode MS Project XML: %w", err)
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// addAssignment assigns person to a task, adding them as a resource on first
+// use. Persons are told apart by name.
+func (p *msProject) addAssignment(resourceUIDs map[string]int, taskUID int, person transformers.ResponsiblePerson) {
+	name := strings.TrimSpace(person.Name)
+	if name == "" {
+		return
+	}
+
+	key := strings.ToLower(name)
+	resourceUID, exists := resourceUIDs[key]
+	if !exists {
+		resourceUID = len(p.Resources) + 1
+		resourceUIDs[key] = resourceUID
+		resource := msResource{UID: resourceUID, ID: resourceUID, Name: name, Group: person.Role}
+		if strings.Contains(person.Contact, "@") {
+			resource.EmailAddress = strings.TrimSpace(person.Contact)
+		}
+		p.Resources = append(p.Resources, resource)
+	}
+
+	p.Assignments = append(p.Assignments, msAssignment{
+		UID:         len(p.Assignments) + 1,
+		TaskUID:     taskUID,
+		ResourceUID: resourceUID,
+		Units:       1,
+	})
+}
+
+// setDates fills in start, finish and the duration in working hours when
+// both dates are known
+func setDates(task *msTask, startDate, endDate string) {
+	if startDate != "" {
+		task.Start = startDate + workdayStart
+	}
+	if endDate != "" {
+		task.Finish = endDate + workdayEnd
+	}
+	if days := workdays(startDate, endDate); days > 0 {
+		task.Duration = fmt.Sprintf("PT%dH0M0S", days*hoursPerDay)
+	}
+}
+
+// workdays counts the weekdays from start to end, both included
+func workdays(startDate, endDate string) int {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return 0
+	}
+
+	days := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			days++
+		}
+	}
+	return days
+}
+
+// earliest and latest compare YYYY-MM-DD dates, ignoring empty ones
+func earliest(a, b string) string {
+	if a == "" || (b != "" && b < a) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b string) string {
+	if a == "" || (b != "" && b > a) {
+		return b
+	}
+	return a
+}
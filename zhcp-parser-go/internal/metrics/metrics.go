@@ -0,0 +1,99 @@
+// Package metrics holds the Prometheus metrics of the parser service
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "zhcp"
+
+// Registry collects every parser metric together with the Go runtime and
+// process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// LLMRequestDuration is the latency of single provider calls
+	LLMRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of LLM provider calls.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"provider"})
+
+	// LLMRequests counts provider calls by outcome (success or error)
+	LLMRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_requests_total",
+		Help:      "LLM provider calls by outcome.",
+	}, []string{"provider", "outcome"})
+
+	// LLMTokens counts tokens reported by providers, by direction (input or output)
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens used by LLM provider calls.",
+	}, []string{"provider", "direction"})
+
+	// LLMCost is the USD cost of provider calls, priced per model
+	LLMCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_cost_usd_total",
+		Help:      "Cost of LLM provider calls in USD.",
+	}, []string{"provider", "model"})
+
+	// ParseDuration is the time from a worker picking up a job to its outcome
+	ParseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "parse_duration_seconds",
+		Help:      "Duration of parse jobs by document format and final status.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600},
+	}, []string{"format", "status"})
+
+	// ParseQueueWait is the time jobs spend in the queue before a worker
+	// picks them up
+	ParseQueueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "parse_queue_wait_seconds",
+		Help:      "Time parse jobs wait in the queue for a worker.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	})
+
+	// ParseJobsRejected counts submissions turned away, by reason
+	// (queue_full or client_limit)
+	ParseJobsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parse_jobs_rejected_total",
+		Help:      "Parse job submissions rejected by reason.",
+	}, []string{"reason"})
+
+	// ParseJobsFinished counts finished parse jobs by final status
+	ParseJobsFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parse_jobs_finished_total",
+		Help:      "Parse jobs that completed or failed.",
+	}, []string{"status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		LLMRequestDuration,
+		LLMRequests,
+		LLMTokens,
+		LLMCost,
+		ParseDuration,
+		ParseQueueWait,
+		ParseJobsRejected,
+		ParseJobsFinished,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
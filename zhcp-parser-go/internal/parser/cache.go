@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// defaultCacheEntries bounds the in-memory result cache
+const defaultCacheEntries = 500
+
+// ResultCache stores successful parse results by cache key. Keys cover the
+// extracted text, the prompt version and the parse options, so a changed
+// prompt or employee pool never returns a stale result.
+type ResultCache interface {
+	Get(key string) (*ParseResult, bool)
+	Put(key string, result *ParseResult)
+}
+
+// SetResultCache replaces the result cache; nil disables caching
+func (p *ZhcpParser) SetResultCache(cache ResultCache) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resultCache = cache
+}
+
+func (p *ZhcpParser) getResultCache() ResultCache {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.resultCache
+}
+
+// PromptVersion identifies everything besides the document that shapes the
+// LLM answer: the extraction prompt, the employee pool and the JSON schema
+func (p *ZhcpParser) PromptVersion() string {
+	schema, _ := json.Marshal(p.getProjectJSONSchema())
+
+	hash := sha256.New()
+	hash.Write([]byte(p.promptManager.Version()))
+	hash.Write([]byte{0})
+	hash.Write(schema)
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// resultCacheKey hashes the extracted text with the prompt version and the
+// options that change the result
+func resultCacheKey(promptVersion string, doc extractedDocument, opts ParseOptions) string {
+	hash := sha256.New()
+	hash.Write([]byte(promptVersion))
+	hash.Write([]byte{0})
+	hash.Write([]byte(doc.docType))
+	if opts.Validate {
+		hash.Write([]byte("|validate"))
+	}
+	if opts.Enrich {
+		hash.Write([]byte("|enrich"))
+	}
+	if !opts.Generation.IsZero() {
+		// Results of other models or settings are not interchangeable
+		if generation, err := json.Marshal(opts.Generation); err == nil {
+			hash.Write([]byte("|"))
+			hash.Write(generation)
+		}
+	}
+	hash.Write([]byte{0})
+	hash.Write([]byte(doc.text))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cachedResult returns a copy of a cached result marked as such, with the
+// OCR details and notes of the current extraction
+func cachedResult(cached *ParseResult, doc extractedDocument, startTime time.Time) *ParseResult {
+	result := *cached
+	result.ExtractionMetadata.Cached = true
+	result.ExtractionMetadata.Usage = nil
+	result.ExtractionMetadata.ProcessingTime = time.Since(startTime).Seconds()
+	result.ExtractionMetadata.OCR = doc.ocr
+	result.ProcessingNotes = append(append([]string{}, doc.notes...), "Result reused from an identical document parsed earlier")
+	return &result
+}
+
+// MemoryResultCache keeps results in memory for a TTL, evicting the oldest
+// entry once it is full
+type MemoryResultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	result   *ParseResult
+	storedAt time.Time
+}
+
+// NewMemoryResultCache creates an in-memory result cache
+func NewMemoryResultCache(ttl time.Duration, maxEntries int) *MemoryResultCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheEntries
+	}
+	return &MemoryResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]memoryCacheEntry),
+	}
+}
+
+// Get returns the result stored under key unless it has expired
+func (c *MemoryResultCache) Get(key string) (*ParseResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(entry.storedAt) > c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// Put stores result under key
+func (c *MemoryResultCache) Put(key string, result *ParseResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if oldestKey == "" || entry.storedAt.Before(oldest) {
+				oldestKey, oldest = k, entry.storedAt
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+	c.entries[key] = memoryCacheEntry{result: result, storedAt: time.Now()}
+}
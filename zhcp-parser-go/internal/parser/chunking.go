@@ -0,0 +1,290 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"zhcp-parser-go/internal/ai"
+	"zhcp-parser-go/internal/common"
+	"zhcp-parser-go/internal/transformers"
+)
+
+// Chunking settings used when the config leaves them unset
+const (
+	defaultChunkChars       = 40000
+	defaultChunkOverlap     = 1500
+	defaultMaxChunks        = 20
+	defaultChunkConcurrency = 2
+)
+
+// ChunkMetadata describes one chunk of a document parsed in parts
+type ChunkMetadata struct {
+	Index      int     `json:"index"`
+	Start      int     `json:"start"` // character offsets in the extracted text
+	End        int     `json:"end"`
+	Status     string  `json:"status"`
+	Confidence float64 `json:"confidence"`
+	Phases     int     `json:"phases"`
+	Tasks      int     `json:"tasks"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// textChunk is a part of the extracted text
+type textChunk struct {
+	text       string
+	start, end int
+}
+
+// chunkingSettings returns the chunking config with defaults filled in, or
+// false when chunking is disabled
+func (p *ZhcpParser) chunkingSettings() (common.ChunkingConfig, bool) {
+	if p.config == nil || !p.config.Chunking.Enabled {
+		return common.ChunkingConfig{}, false
+	}
+
+	settings := p.config.Chunking
+	if settings.MaxChars <= 0 {
+		settings.MaxChars = defaultChunkChars
+	}
+	if settings.OverlapChars <= 0 || settings.OverlapChars*2 >= settings.MaxChars {
+		settings.OverlapChars = min(defaultChunkOverlap, settings.MaxChars/4)
+	}
+	if settings.MaxChunks <= 0 {
+		settings.MaxChunks = defaultMaxChunks
+	}
+	if settings.Concurrency <= 0 {
+		settings.Concurrency = defaultChunkConcurrency
+	}
+	return settings, true
+}
+
+// splitDocument splits text into chunks for the LLM; text that fits in one
+// call, or any text when chunking is disabled, is a single chunk
+func (p *ZhcpParser) splitDocument(text string) []textChunk {
+	settings, ok := p.chunkingSettings()
+	if !ok {
+		return []textChunk{{text: text, start: 0, end: utf8.RuneCountInString(text)}}
+	}
+	return splitText(text, settings.MaxChars, settings.OverlapChars)
+}
+
+// splitText cuts text into chunks of at most maxChars characters, preferably
+// at paragraph or line breaks. Each chunk after the first starts overlap
+// characters before the end of the previous one, so phases and tasks cut at
+// a boundary are seen whole by one of the chunks.
+func splitText(text string, maxChars, overlap int) []textChunk {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return []textChunk{{text: text, start: 0, end: len(runes)}}
+	}
+
+	var chunks []textChunk
+	start := 0
+	for {
+		end := start + maxChars
+		if end >= len(runes) {
+			chunks = append(chunks, textChunk{text: string(runes[start:]), start: start, end: len(runes)})
+			return chunks
+		}
+		end = breakPoint(runes, start+maxChars/2, end)
+		chunks = append(chunks, textChunk{text: string(runes[start:end]), start: start, end: end})
+
+		next := lineStart(runes, end-overlap, end)
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+}
+
+// breakPoint returns the best place to end a chunk in (from, to]: after a
+// blank line, a line, a sentence or a word, in that order of preference
+func breakPoint(runes []rune, from, to int) int {
+	var line, sentence, word int
+	for i := to; i > from; i-- {
+		switch {
+		case runes[i-1] == '\n' && i >= 2 && runes[i-2] == '\n':
+			return i
+		case runes[i-1] == '\n':
+			if line == 0 {
+				line = i
+			}
+		case runes[i-1] == ' ' && i >= 2 && (runes[i-2] == '.' || runes[i-2] == ';'):
+			if sentence == 0 {
+				sentence = i
+			}
+		case runes[i-1] == ' ':
+			if word == 0 {
+				word = i
+			}
+		}
+	}
+	for _, i := range []int{line, sentence, word} {
+		if i > 0 {
+			return i
+		}
+	}
+	return to
+}
+
+// lineStart moves from forward to the start of the next line before limit,
+// so an overlap does not begin mid-line
+func lineStart(runes []rune, from, limit int) int {
+	if from <= 0 {
+		return 0
+	}
+	for i := from; i < limit; i++ {
+		if runes[i-1] == '\n' {
+			return i
+		}
+	}
+	return from
+}
+
+// chunkPrompts builds the extraction prompt of every chunk. Chunks of a
+// longer document are introduced as such, so the LLM does not invent a
+// whole project from one part.
+func (p *ZhcpParser) chunkPrompts(chunks []textChunk, language string) ([]string, error) {
+	jsonSchema := p.getProjectJSONSchema()
+	prompts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		content := chunk.text
+		if len(chunks) > 1 {
+			content = fmt.Sprintf(
+				"[Part %d of %d of a longer document. Extract only the phases and tasks this part mentions; "+
+					"the parts are merged afterwards.]\n\n%s", i+1, len(chunks), chunk.text)
+		}
+
+		prompt, err := p.promptManager.CreateLanguageExtractionPrompt(language, content, jsonSchema)
+		if err != nil {
+			return nil, err
+		}
+		prompts[i] = prompt
+	}
+	return prompts, nil
+}
+
+// projectSchemaName names the project schema in structured output requests
+const projectSchemaName = "project_structure"
+
+// generate asks the LLM for the project structure of one prompt, with the
+// generation overrides of the parse. Providers with native structured output
+// are held to the project schema; the others answer in free text, which the
+// DataTransformer parses as before.
+func (p *ZhcpParser) generate(ctx context.Context, prompt, docType string, chars int, gen Generation) (*ai.LLMResponse, error) {
+	return p.llmManager.GenerateWithFallback(ctx, p.generationOptions(gen, docType, chars), prompt)
+}
+
+// parseChunks sends every chunk to the LLM, a few at a time, transforms the
+// answers and merges them. Chunks whose call fails are left out of the
+// merge; the error of the first one is returned only when every call failed.
+func (p *ZhcpParser) parseChunks(ctx context.Context, chunks []textChunk, prompts []string, docType, language string, gen Generation, progress ProgressFunc) (*transformers.TransformationResult, *LLMUsage, []ChunkMetadata, error) {
+	settings, _ := p.chunkingSettings()
+
+	responses := make([]*ai.LLMResponse, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	slots := make(chan struct{}, settings.Concurrency)
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if errs[i] = ctx.Err(); errs[i] == nil {
+				responses[i], errs[i] = p.generate(ctx, prompts[i], docType, chunks[i].end-chunks[i].start, gen)
+			}
+
+			mu.Lock()
+			done++
+			progress.report(StageLLM, 30+55*done/len(chunks))
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	progress.report(StageTransformation, 85)
+
+	parts := make([]*transformers.TransformationResult, len(chunks))
+	weights := make([]float64, len(chunks))
+	metadata := make([]ChunkMetadata, len(chunks))
+	var usage *LLMUsage
+	var firstErr error
+	for i, chunk := range chunks {
+		weights[i] = float64(chunk.end - chunk.start)
+		metadata[i] = ChunkMetadata{Index: i, Start: chunk.start, End: chunk.end}
+
+		if errs[i] != nil {
+			metadata[i].Status = string(transformers.TransformationStatusFailed)
+			metadata[i].Error = errs[i].Error()
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+
+		part, partUsage := p.transform(ctx, prompts[i], responses[i], docType, chunk.end-chunk.start, gen, transformers.TransformOptions{
+			Language: language,
+			Part:     true,
+		})
+		usage = mergeUsage(usage, partUsage)
+		metadata[i].Status = string(part.Status)
+		metadata[i].Confidence = part.ConfidenceScore
+		if part.TransformedData != nil {
+			parts[i] = part
+			metadata[i].Phases = len(part.TransformedData.Project.Phases)
+			for _, phase := range part.TransformedData.Project.Phases {
+				metadata[i].Tasks += len(phase.Tasks)
+			}
+		} else if len(part.ValidationErrors) > 0 {
+			metadata[i].Error = part.ValidationErrors[0]
+		}
+	}
+
+	if usage == nil {
+		return nil, nil, metadata, firstErr
+	}
+	return p.dataTransformer.Merge(parts, weights), usage, metadata, nil
+}
+
+// usageOf reports the usage of one LLM call
+func usageOf(response *ai.LLMResponse) *LLMUsage {
+	return &LLMUsage{
+		Provider:     string(response.Provider),
+		Model:        response.Model,
+		InputTokens:  response.TokensUsed.Input,
+		OutputTokens: response.TokensUsed.Output,
+		TotalTokens:  response.TokensUsed.Total,
+		CostUSD:      response.Cost,
+		Estimated:    response.TokensUsed.Estimated,
+		Structured:   response.Structured,
+	}
+}
+
+// addUsage adds the usage of a call to a running total, which keeps the
+// provider and model of the first call
+func addUsage(total *LLMUsage, response *ai.LLMResponse) *LLMUsage {
+	return mergeUsage(total, usageOf(response))
+}
+
+// mergeUsage adds usage to a running total
+func mergeUsage(total, usage *LLMUsage) *LLMUsage {
+	if total == nil {
+		return usage
+	}
+	total.InputTokens += usage.InputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.TotalTokens += usage.TotalTokens
+	total.CostUSD += usage.CostUSD
+	total.Estimated = total.Estimated || usage.Estimated
+	total.Structured = total.Structured && usage.Structured
+	return total
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	letters := "abcdefghijklmnopqrst"
+	cyrillic := strings.Repeat("ж", 20)
+
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		overlap  int
+		want     []textChunk
+	}{
+		{
+			name:     "shorter than a chunk",
+			text:     "abc",
+			maxChars: 10,
+			want:     []textChunk{{"abc", 0, 3}},
+		},
+		{
+			name:     "exactly one chunk",
+			text:     letters[:10],
+			maxChars: 10,
+			overlap:  4,
+			want:     []textChunk{{"abcdefghij", 0, 10}},
+		},
+		{
+			name:     "exact multiple of the chunk size",
+			text:     letters,
+			maxChars: 10,
+			want:     []textChunk{{"abcdefghij", 0, 10}, {"klmnopqrst", 10, 20}},
+		},
+		{
+			name:     "overlap",
+			text:     letters,
+			maxChars: 10,
+			overlap:  4,
+			want: []textChunk{
+				{"abcdefghij", 0, 10},
+				{"ghijklmnop", 6, 16},
+				{"mnopqrst", 12, 20},
+			},
+		},
+		{
+			name:     "cut at line breaks",
+			text:     "aaaa\nbbbb\ncccc",
+			maxChars: 8,
+			want: []textChunk{
+				{"aaaa\n", 0, 5},
+				{"bbbb\n", 5, 10},
+				{"cccc", 10, 14},
+			},
+		},
+		{
+			name:     "multibyte runes",
+			text:     cyrillic,
+			maxChars: 10,
+			overlap:  2,
+			want: []textChunk{
+				{strings.Repeat("ж", 10), 0, 10},
+				{strings.Repeat("ж", 10), 8, 18},
+				{strings.Repeat("ж", 4), 16, 20},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.maxChars, tt.overlap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitText() = %q, want %q", got, tt.want)
+			}
+
+			// Offsets count runes, not bytes, and the chunks cover the text
+			runes := []rune(tt.text)
+			for _, chunk := range got {
+				if chunk.text != string(runes[chunk.start:chunk.end]) {
+					t.Errorf("chunk %q does not match its offsets [%d, %d)", chunk.text, chunk.start, chunk.end)
+				}
+			}
+			if last := got[len(got)-1]; last.end != len(runes) {
+				t.Errorf("last chunk ends at %d, want %d", last.end, len(runes))
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"zhcp-parser-go/internal/errors"
+	"zhcp-parser-go/internal/transformers"
+)
+
+// Document is one of several documents parsed together into one project
+type Document struct {
+	Path string
+	Name string // labels the document in conflicts and notes; the file name of Path when empty
+}
+
+// DocumentMetadata describes one document of a multi-document parse
+type DocumentMetadata struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Confidence float64 `json:"confidence"`
+	Phases     int     `json:"phases"`
+	Tasks      int     `json:"tasks"`
+	Cached     bool    `json:"cached,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// ParseDocuments parses documents about one project, such as a contract, an
+// annex and a schedule, and merges them into one project structure. Each
+// document goes through the pipeline of ParseDocument on its own, then the
+// transformer merges the results; documents, in order, are the precedence
+// ConflictsLaterWins applies to. Documents that fail are left out and
+// reported in the metadata; when all of them fail, the result of the first
+// is returned. As with ParseDocument, the error is only set when ctx is
+// cancelled.
+func (p *ZhcpParser) ParseDocuments(ctx context.Context, documents []Document, strategy transformers.ConflictStrategy, opts ParseOptions) (*ParseResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	if len(documents) == 0 {
+		return p.createErrorResult(errors.NewParsingError("No documents to parse", "", nil), "", startTime), nil
+	}
+
+	// Retries need the text of one document, which a merge does not have
+	progress := opts.Progress
+	opts.Artifacts = nil
+
+	parts := make([]*transformers.TransformationResult, len(documents))
+	names := make([]string, len(documents))
+	metadata := make([]DocumentMetadata, len(documents))
+	var (
+		usage     *LLMUsage
+		language  string
+		notes     []string
+		first     *ParseResult
+		succeeded int
+	)
+	for i, document := range documents {
+		names[i] = document.Name
+		if names[i] == "" {
+			names[i] = filepath.Base(document.Path)
+		}
+		opts.Progress = func(stage string, percent int) {
+			progress.report(stage, (100*i+percent)/len(documents))
+		}
+
+		result, err := p.ParseDocumentWithOptions(ctx, document.Path, opts)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = result
+		}
+		metadata[i] = documentMetadata(names[i], result)
+		for _, note := range result.ProcessingNotes {
+			notes = append(notes, fmt.Sprintf("%s: %s", names[i], note))
+		}
+		if result.ExtractionMetadata.Usage != nil {
+			// mergeUsage adds to the first usage, which must not be the result's
+			documentUsage := *result.ExtractionMetadata.Usage
+			usage = mergeUsage(usage, &documentUsage)
+		}
+		if !result.Success || result.ProjectStructure == nil {
+			continue
+		}
+		if succeeded == 0 {
+			language = result.ExtractionMetadata.Language
+		}
+		succeeded++
+		parts[i] = &transformers.TransformationResult{
+			TransformedData:     result.ProjectStructure,
+			Status:              transformers.TransformationStatus(result.ExtractionMetadata.Status),
+			ConfidenceScore:     result.ExtractionMetadata.Confidence,
+			ConfidenceBreakdown: result.ExtractionMetadata.ConfidenceBreakdown,
+		}
+	}
+
+	if succeeded == 0 {
+		failed := *first
+		failed.ExtractionMetadata.Documents = metadata
+		failed.ProcessingNotes = notes
+		return &failed, nil
+	}
+
+	progress.report(StageTransformation, 95)
+	merged := p.dataTransformer.MergeDocuments(parts, names, strategy)
+
+	result := &ParseResult{
+		Success: merged.Status == transformers.TransformationStatusSuccess ||
+			merged.Status == transformers.TransformationStatusPartial,
+		ProjectStructure: merged.TransformedData,
+		ExtractionMetadata: ExtractionMetadata{
+			Confidence:          merged.ConfidenceScore,
+			ConfidenceBreakdown: merged.ConfidenceBreakdown,
+			Status:              string(merged.Status),
+			ProcessingTime:      time.Since(startTime).Seconds(),
+			PromptVersion:       p.PromptVersion(),
+			Language:            language,
+			Usage:               usage,
+			Documents:           metadata,
+		},
+		Conflicts:       merged.Conflicts,
+		ProcessingNotes: append(merged.ProcessingNotes, notes...),
+	}
+	if len(merged.ValidationErrors) > 0 {
+		result.ValidationError = merged.ValidationErrors
+	}
+	return result, nil
+}
+
+// documentMetadata summarizes the result of one document
+func documentMetadata(name string, result *ParseResult) DocumentMetadata {
+	metadata := DocumentMetadata{
+		Name:       name,
+		Status:     result.ExtractionMetadata.Status,
+		Confidence: result.ExtractionMetadata.Confidence,
+		Cached:     result.ExtractionMetadata.Cached,
+	}
+	if result.ProjectStructure != nil {
+		metadata.Phases = len(result.ProjectStructure.Project.Phases)
+		for _, phase := range result.ProjectStructure.Project.Phases {
+			metadata.Tasks += len(phase.Tasks)
+		}
+	}
+	switch {
+	case result.Error != nil:
+		metadata.Status = string(transformers.TransformationStatusFailed)
+		metadata.Error = result.Error.Message
+	case len(result.ValidationError) > 0:
+		metadata.Error = result.ValidationError[0]
+	}
+	return metadata
+}
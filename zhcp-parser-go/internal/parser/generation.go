@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+
+	"zhcp-parser-go/internal/ai"
+	"zhcp-parser-go/internal/common"
+)
+
+// Generation settings used when the config leaves them unset
+const (
+	defaultTemperature    = 0.1
+	defaultMaxTokens      = 4096
+	defaultMaxTemperature = 1.0
+	defaultMaxTokensLimit = 16384
+)
+
+// Generation overrides the LLM settings of one parse; zero fields keep the
+// configured ones. CheckGeneration holds them to the configured bounds.
+type Generation struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Provider    string   `json:"provider,omitempty"` // called alone instead of the fallback chain
+	Model       string   `json:"model,omitempty"`    // needs Provider
+}
+
+// IsZero reports whether g overrides nothing
+func (g Generation) IsZero() bool {
+	return g.Temperature == nil && g.MaxTokens == 0 && g.Provider == "" && g.Model == ""
+}
+
+// generationSettings returns the generation config with defaults filled in
+func (p *ZhcpParser) generationSettings() common.GenerationConfig {
+	var settings common.GenerationConfig
+	if p.config != nil {
+		settings = p.config.Generation
+	}
+	if settings.Temperature <= 0 {
+		settings.Temperature = defaultTemperature
+	}
+	if settings.MaxTokens <= 0 {
+		settings.MaxTokens = defaultMaxTokens
+	}
+	if settings.MaxTemperature <= 0 {
+		settings.MaxTemperature = defaultMaxTemperature
+	}
+	if settings.MaxTokensLimit <= 0 {
+		settings.MaxTokensLimit = max(defaultMaxTokensLimit, settings.MaxTokens)
+	}
+	return settings
+}
+
+// CheckGeneration returns an error describing why the overrides of a parse
+// request are outside the configured bounds, or nil
+func (p *ZhcpParser) CheckGeneration(g Generation) error {
+	settings := p.generationSettings()
+	if g.Temperature != nil && (*g.Temperature < 0 || *g.Temperature > settings.MaxTemperature) {
+		return fmt.Errorf("temperature must be between 0 and %g", settings.MaxTemperature)
+	}
+	if g.MaxTokens < 0 || g.MaxTokens > settings.MaxTokensLimit {
+		return fmt.Errorf("max_tokens must be between 1 and %d", settings.MaxTokensLimit)
+	}
+	if g.Model != "" && g.Provider == "" {
+		return fmt.Errorf("model can only be set together with provider")
+	}
+	if g.Provider == "" {
+		return nil
+	}
+
+	if !slices.Contains(p.llmManager.GetAvailableProviders(), ai.ProviderType(g.Provider)) {
+		return fmt.Errorf("provider %s is not enabled", g.Provider)
+	}
+	if g.Model == "" || (p.config != nil && g.Model == p.config.Providers[g.Provider].Model) {
+		return nil
+	}
+	if !slices.Contains(settings.Models[g.Provider], g.Model) {
+		return fmt.Errorf("model %s is not allowed for provider %s", g.Model, g.Provider)
+	}
+	return nil
+}
+
+// generationOptions are the options of an extraction call for a document
+// of docType with chars characters
+func (p *ZhcpParser) generationOptions(g Generation, docType string, chars int) ai.GenerationOptions {
+	settings := p.generationSettings()
+	opts := ai.GenerationOptions{
+		Temperature:   settings.Temperature,
+		MaxTokens:     settings.MaxTokens,
+		Provider:      g.Provider,
+		Model:         g.Model,
+		DocumentType:  docType,
+		DocumentChars: chars,
+		ResponseSchema: &ai.ResponseSchema{
+			Name:   projectSchemaName,
+			Schema: p.getProjectJSONSchema(),
+		},
+	}
+	if g.Temperature != nil {
+		opts.Temperature = *g.Temperature
+	}
+	if g.MaxTokens > 0 {
+		opts.MaxTokens = g.MaxTokens
+	}
+	return opts
+}
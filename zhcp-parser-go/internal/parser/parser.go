@@ -2,6 +2,7 @@ package parser
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -15,10 +16,16 @@ import (
 	"zhcp-parser-go/internal/parsers"
 	"zhcp-parser-go/internal/parsers/docx"
 	"zhcp-parser-go/internal/parsers/pdf"
+	"zhcp-parser-go/internal/parsers/pptx"
+	"zhcp-parser-go/internal/parsers/xlsx"
 	"zhcp-parser-go/internal/transformers"
 	"zhcp-parser-go/internal/validators"
 )
 
+// lowOCRConfidence is the OCR confidence below which the result gets a
+// processing note asking to double-check it
+const lowOCRConfidence = 0.6
+
 // ZhcpParser is the main parser that orchestrates all components of the parsing system
 type ZhcpParser struct {
 	config             *common.Config
@@ -26,6 +33,10 @@ type ZhcpParser struct {
 	pdfValidator       *pdf.PDFValidator
 	docxExtractor      *docx.DOCXExtractor
 	docxValidator      *docx.DOCXValidator
+	xlsxExtractor      *xlsx.XLSXExtractor
+	xlsxValidator      *xlsx.XLSXValidator
+	pptxExtractor      *pptx.PPTXExtractor
+	pptxValidator      *pptx.PPTXValidator
 	textPreprocessor   *parsers.TextPreprocessor
 	llmManager         *ai.LLMManager
 	promptManager      *prompt_engineering.PromptManager
@@ -33,6 +44,7 @@ type ZhcpParser struct {
 	dataEnricher       *transformers.DataEnricher
 	validationPipeline *validators.ValidationPipeline
 	errorHandler       *errors.ErrorHandler
+	resultCache        ResultCache
 	logger             interface{}  // In a real implementation, we'd use a proper logger interface
 	mu                 sync.RWMutex // For thread safety
 }
@@ -58,8 +70,15 @@ func (p *ZhcpParser) initializeComponents() error {
 	// Initialize document parsers
 	p.pdfExtractor = pdf.NewPDFExtractor(p.logger)
 	p.pdfValidator = pdf.NewPDFValidator()
+	if err := p.configureOCR(); err != nil {
+		return fmt.Errorf("failed to initialize OCR: %w", err)
+	}
 	p.docxExtractor = docx.NewDOCXExtractor(p.logger)
 	p.docxValidator = docx.NewDOCXValidator()
+	p.xlsxExtractor = xlsx.NewXLSXExtractor(p.logger)
+	p.xlsxValidator = xlsx.NewXLSXValidator()
+	p.pptxExtractor = pptx.NewPPTXExtractor(p.logger)
+	p.pptxValidator = pptx.NewPPTXValidator()
 	p.textPreprocessor = parsers.NewTextPreprocessor()
 
 	// Initialize LLM components
@@ -81,12 +100,44 @@ func (p *ZhcpParser) initializeComponents() error {
 	// Initialize error handler
 	p.errorHandler = errors.NewErrorHandler("logs/errors.log", 1000)
 
+	// Reuse results for identical documents; the server swaps in a
+	// persistent cache
+	if p.config.ResultCache.Enabled {
+		ttl := time.Duration(p.config.ResultCache.TTLHours) * time.Hour
+		p.resultCache = NewMemoryResultCache(ttl, p.config.ResultCache.MaxEntries)
+	}
+
 	return nil
 }
 
-// ParseDocument parses a document and extracts project structure
-func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (*ParseResult, error) {
+// CheckProviders probes the configured LLM providers
+func (p *ZhcpParser) CheckProviders(ctx context.Context) []ai.ProviderHealth {
+	if p.llmManager == nil {
+		return nil
+	}
+	return p.llmManager.CheckProviders(ctx)
+}
+
+// Prompts returns the prompt manager, to replace templates at runtime
+func (p *ZhcpParser) Prompts() *prompt_engineering.PromptManager {
+	return p.promptManager
+}
+
+// ParseDocument parses a document and extracts project structure. Failures
+// are reported in the result; the error is only set, to ctx.Err(), when ctx
+// is cancelled, which also aborts LLM calls in flight.
+func (p *ZhcpParser) ParseDocument(ctx context.Context, documentPath string, validate, enrich bool) (*ParseResult, error) {
+	return p.ParseDocumentWithOptions(ctx, documentPath, ParseOptions{Validate: validate, Enrich: enrich})
+}
+
+// ParseDocumentWithOptions is ParseDocument with progress reporting and
+// control over the result cache
+func (p *ZhcpParser) ParseDocumentWithOptions(ctx context.Context, documentPath string, opts ParseOptions) (*ParseResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	startTime := time.Now()
+	opts.Progress.report(StageValidation, 5)
 
 	// Determine document type and validate
 	docType, err := p.getDocumentType(documentPath)
@@ -95,37 +146,64 @@ func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (
 	}
 
 	// Validate document based on type
-	if docType == "pdf" {
+	var validationErrors []string
+	switch docType {
+	case "pdf":
 		validation, err := p.pdfValidator.ValidatePDF(documentPath)
 		if err != nil {
 			return p.createErrorResult(err, documentPath, startTime), nil
 		}
 		if !validation.IsValid {
-			err := errors.NewParsingError(
-				fmt.Sprintf("PDF validation failed: %s", strings.Join(validation.Errors, ", ")),
-				documentPath,
-				nil)
+			validationErrors = validation.Errors
+		}
+	case "xlsx":
+		validation, err := p.xlsxValidator.ValidateXLSX(documentPath)
+		if err != nil {
 			return p.createErrorResult(err, documentPath, startTime), nil
 		}
-	} else { // docx
-		validation, err := p.docxValidator.ValidateDOCX(documentPath)
+		if !validation.IsValid {
+			validationErrors = validation.Errors
+		}
+	case "pptx":
+		validation, err := p.pptxValidator.ValidatePPTX(documentPath)
 		if err != nil {
 			return p.createErrorResult(err, documentPath, startTime), nil
 		}
 		if !validation.IsValid {
-			err := errors.NewParsingError(
-				fmt.Sprintf("DOCX validation failed: %s", strings.Join(validation.Errors, ", ")),
-				documentPath,
-				nil)
+			validationErrors = validation.Errors
+		}
+	case "text", "markdown":
+		// Checked by parsers.ReadPlainText during extraction
+	default: // docx
+		validation, err := p.docxValidator.ValidateDOCX(documentPath)
+		if err != nil {
 			return p.createErrorResult(err, documentPath, startTime), nil
 		}
+		if !validation.IsValid {
+			validationErrors = validation.Errors
+		}
+	}
+	if validationErrors != nil {
+		err := errors.NewParsingError(
+			fmt.Sprintf("%s validation failed: %s", strings.ToUpper(docType), strings.Join(validationErrors, ", ")),
+			documentPath,
+			nil)
+		return p.createErrorResult(err, documentPath, startTime), nil
 	}
 
 	// Extract content based on document type
+	opts.Progress.report(StageExtraction, 15)
 	var extractionResult interface{}
-	if docType == "pdf" {
+	switch docType {
+	case "pdf":
 		extractionResult, err = p.parsePDF(documentPath)
-	} else {
+	case "xlsx":
+		extractionResult, err = p.parseXLSX(documentPath)
+	case "pptx":
+		extractionResult, err = p.parsePPTX(documentPath)
+	case "text", "markdown":
+		extractionResult, err = parsers.ReadPlainText(documentPath)
+	default:
 		extractionResult, err = p.parseDOCX(documentPath)
 	}
 	if err != nil {
@@ -134,16 +212,112 @@ func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (
 
 	// For simplicity in this implementation, we'll use a type assertion
 	// In a real implementation, you'd have a common interface
-	var extractedText string
-	if pdfResult, ok := extractionResult.(*pdf.PDFExtractionResult); ok {
+	var (
+		extractedText string
+		ocrMetadata   *OCRMetadata
+		extractNotes  []string
+	)
+	if plainText, ok := extractionResult.(string); ok {
+		extractedText = plainText
+	} else if pdfResult, ok := extractionResult.(*pdf.PDFExtractionResult); ok {
 		extractedText = pdfResult.Text
+		if pdfResult.OCR != nil {
+			ocrMetadata = &OCRMetadata{
+				Engine:     pdfResult.OCR.Engine,
+				Languages:  pdfResult.OCR.Languages,
+				Confidence: pdfResult.OCR.Confidence,
+				Pages:      pdfResult.OCR.Pages,
+			}
+			if pdfResult.OCR.Confidence < lowOCRConfidence {
+				extractNotes = append(extractNotes, fmt.Sprintf(
+					"Text was recognized with low OCR confidence (%.0f%%), check dates and names", pdfResult.OCR.Confidence*100))
+			}
+		} else if pdfResult.ImageOnly {
+			extractNotes = append(extractNotes, "Document looks scanned but OCR is disabled, little text could be extracted")
+		}
 	} else if docxResult, ok := extractionResult.(*docx.DOCXExtractionResult); ok {
 		extractedText = docxResult.Content.Text
+	} else if xlsxResult, ok := extractionResult.(*xlsx.XLSXExtractionResult); ok {
+		extractedText = xlsxResult.Text
+	} else if pptxResult, ok := extractionResult.(*pptx.PPTXExtractionResult); ok {
+		extractedText = pptxResult.Text
 	} else {
 		err := errors.NewParsingError("Unknown extraction result type", documentPath, nil)
 		return p.createErrorResult(err, documentPath, startTime), nil
 	}
 
+	return p.runPipeline(ctx, extractedDocument{
+		text:    extractedText,
+		docType: docType,
+		path:    documentPath,
+		ocr:     ocrMetadata,
+		notes:   extractNotes,
+	}, opts, startTime)
+}
+
+// ParseText runs the LLM pipeline on text that needs no file extraction, such
+// as content pasted from an email. format is "text" or "markdown". Like
+// ParseDocument, it returns an error only when ctx is cancelled.
+func (p *ZhcpParser) ParseText(ctx context.Context, text, format string, validate, enrich bool) (*ParseResult, error) {
+	return p.ParseTextWithOptions(ctx, text, format, ParseOptions{Validate: validate, Enrich: enrich})
+}
+
+// ParseTextWithOptions is ParseText with progress reporting and control over
+// the result cache
+func (p *ZhcpParser) ParseTextWithOptions(ctx context.Context, text, format string, opts ParseOptions) (*ParseResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	opts.Progress.report(StageValidation, 5)
+
+	if format != "markdown" {
+		format = "text"
+	}
+
+	text, err := parsers.NormalizePlainText(text)
+	if err != nil {
+		return p.createErrorResult(errors.NewParsingError(err.Error(), "", nil), "", startTime), nil
+	}
+
+	return p.runPipeline(ctx, extractedDocument{text: text, docType: format}, opts, startTime)
+}
+
+// extractedDocument is the text of a document ready for the LLM pipeline
+type extractedDocument struct {
+	text    string
+	docType string
+	path    string
+	ocr     *OCRMetadata
+	notes   []string
+
+	response *ai.LLMResponse // answer of an earlier attempt, transformed instead of calling the LLM
+}
+
+// runPipeline sends extracted text through the LLM, then transforms,
+// enriches and validates the answer. Text parsed before with the same prompt
+// version is answered from the result cache unless opts.Force is set.
+func (p *ZhcpParser) runPipeline(ctx context.Context, doc extractedDocument, opts ParseOptions, startTime time.Time) (*ParseResult, error) {
+	extractedText, docType, documentPath := doc.text, doc.docType, doc.path
+	validate, enrich, progress := opts.Validate, opts.Enrich, opts.Progress
+	if opts.Artifacts != nil {
+		*opts.Artifacts = artifactsOf(doc)
+	}
+
+	language := p.textPreprocessor.DetectLanguage(extractedText).Language
+	promptVersion := p.PromptVersion()
+	promptTemplate := p.promptManager.StoredVersion(p.promptManager.ExtractionPromptName(language))
+	cache := p.getResultCache()
+	var cacheKey string
+	if cache != nil {
+		cacheKey = resultCacheKey(promptVersion, doc, opts)
+		if !opts.Force {
+			if cached, ok := cache.Get(cacheKey); ok {
+				return cachedResult(cached, doc, startTime), nil
+			}
+		}
+	}
+
 	// Validate extracted content
 	contentValidation := p.validationPipeline.DocumentValidator.ValidateDocumentContent(
 		extractedText, docType)
@@ -152,24 +326,69 @@ func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (
 		// In a real implementation, you'd log these appropriately
 	}
 
-	// Create extraction prompt
-	jsonSchema := p.getProjectJSONSchema()
-	prompt, err := p.promptManager.CreateExtractionPrompt(extractedText, jsonSchema)
-	if err != nil {
+	// Mask personal data before the text leaves for an external provider
+	llmText := extractedText
+	var masked *redaction
+	if p.redacts(opts.Generation) {
+		llmText, masked = redactText(extractedText)
+	}
+
+	// Split text too long for one LLM call into chunks
+	chunks := p.splitDocument(llmText)
+	if settings, _ := p.chunkingSettings(); len(chunks) > 1 && len(chunks) > settings.MaxChunks {
+		err := errors.NewParsingError(fmt.Sprintf(
+			"Document is too long: %d chunks needed, at most %d allowed", len(chunks), settings.MaxChunks),
+			documentPath, nil)
 		return p.createErrorResult(err, documentPath, startTime), nil
 	}
 
-	// Generate response from LLM
-	llmResponse, err := p.llmManager.GenerateWithFallback(context.Background(), ai.GenerationOptions{
-		Temperature: 0.1,
-		MaxTokens:   4096,
-	}, prompt)
+	// Create extraction prompts
+	prompts, err := p.chunkPrompts(chunks, language)
 	if err != nil {
 		return p.createErrorResult(err, documentPath, startTime), nil
 	}
 
-	// Transform LLM response to structured data
-	transformationResult := p.dataTransformer.Transform(llmResponse.Content)
+	// Generate response from LLM and transform it to structured data
+	progress.report(StageLLM, 30)
+	var (
+		transformationResult *transformers.TransformationResult
+		usage                *LLMUsage
+		chunkMetadata        []ChunkMetadata
+	)
+	if len(chunks) == 1 {
+		llmResponse, err := p.answer(ctx, doc, prompts[0], chunks[0].end, opts.Generation)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return p.createLLMErrorResult(err, documentPath, startTime), nil
+		}
+		if opts.Artifacts != nil && doc.response == nil {
+			opts.Artifacts.LLMResponse = llmResponse
+		}
+
+		progress.report(StageTransformation, 85)
+		transformationResult, usage = p.transform(ctx, prompts[0], llmResponse, docType, chunks[0].end, opts.Generation, transformers.TransformOptions{
+			Language: language,
+		})
+	} else {
+		transformationResult, usage, chunkMetadata, err = p.parseChunks(ctx, chunks, prompts, docType, language, opts.Generation, progress)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return p.createLLMErrorResult(err, documentPath, startTime), nil
+		}
+	}
+
+	// A result missing the calls cut short by cancellation is not kept
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if transformationResult.TransformedData != nil {
+		masked.restoreProject(&transformationResult.TransformedData.Project)
+	}
 
 	if transformationResult.Status == transformers.TransformationStatusSuccess ||
 		transformationResult.Status == transformers.TransformationStatusPartial {
@@ -190,7 +409,15 @@ func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (
 			// Adjust confidence based on validation
 			if validationResults != nil {
 				adjustment, ok := validationResults.ValidationStages["confidence_adjustment"].(float64)
-				if ok {
+				if ok && transformationResult.ConfidenceBreakdown != nil {
+					breakdown := transformationResult.ConfidenceBreakdown
+					breakdown.ValidationAdjustment = adjustment
+					if adjustment < 0 {
+						breakdown.Reasons = append(breakdown.Reasons, fmt.Sprintf(
+							"Validation found %d issues and %d warnings", len(validationResults.Issues), len(validationResults.Warnings)))
+					}
+					transformationResult.ConfidenceScore = breakdown.Score()
+				} else if ok {
 					transformationResult.ConfidenceScore += adjustment
 					if transformationResult.ConfidenceScore < 0.0 {
 						transformationResult.ConfidenceScore = 0.0
@@ -211,9 +438,17 @@ func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (
 			transformationResult.Status == transformers.TransformationStatusPartial,
 		ProjectStructure: transformationResult.TransformedData,
 		ExtractionMetadata: ExtractionMetadata{
-			Confidence:     transformationResult.ConfidenceScore,
-			Status:         string(transformationResult.Status),
-			ProcessingTime: processingTime,
+			Confidence:            transformationResult.ConfidenceScore,
+			ConfidenceBreakdown:   transformationResult.ConfidenceBreakdown,
+			Status:                string(transformationResult.Status),
+			ProcessingTime:        processingTime,
+			OCR:                   doc.ocr,
+			PromptVersion:         promptVersion,
+			PromptTemplateVersion: promptTemplate,
+			Language:              language,
+			Usage:                 usage,
+			Chunks:                chunkMetadata,
+			Redacted:              masked.len(),
 		},
 	}
 
@@ -221,13 +456,86 @@ func (p *ZhcpParser) ParseDocument(documentPath string, validate, enrich bool) (
 		result.ValidationError = transformationResult.ValidationErrors
 	}
 
-	if len(transformationResult.ProcessingNotes) > 0 {
-		result.ProcessingNotes = transformationResult.ProcessingNotes
+	if len(transformationResult.ProcessingNotes) > 0 || len(doc.notes) > 0 {
+		result.ProcessingNotes = append(doc.notes, transformationResult.ProcessingNotes...)
+	}
+
+	if cache != nil && result.Success {
+		cache.Put(cacheKey, result)
 	}
 
 	return result, nil
 }
 
+// ValidateProject runs the validation pipeline and the business rules on a
+// project structure without a document or an LLM call, e.g. on a parse
+// result edited by hand
+func (p *ZhcpParser) ValidateProject(structure *transformers.ProjectStructure) *validators.ValidationResult {
+	return p.validationPipeline.ValidateProjectStructure(structure)
+}
+
+// ExtractText returns the plain text of a supported document using the same
+// extractors as ParseDocument, without running the LLM pipeline.
+func (p *ZhcpParser) ExtractText(documentPath string) (string, string, error) {
+	docType, err := p.getDocumentType(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch docType {
+	case "pdf":
+		result, err := p.pdfExtractor.ExtractText(documentPath)
+		if err != nil {
+			return "", docType, err
+		}
+		return result.Text, docType, nil
+	case "xlsx":
+		result, err := p.xlsxExtractor.ExtractContent(documentPath)
+		if err != nil {
+			return "", docType, err
+		}
+		return result.Text, docType, nil
+	case "pptx":
+		result, err := p.pptxExtractor.ExtractContent(documentPath)
+		if err != nil {
+			return "", docType, err
+		}
+		return result.Text, docType, nil
+	case "text", "markdown":
+		text, err := parsers.ReadPlainText(documentPath)
+		return text, docType, err
+	}
+
+	result, err := p.docxExtractor.ExtractWithFormatting(documentPath)
+	if err != nil {
+		return "", docType, err
+	}
+	return result.Content.Text, docType, nil
+}
+
+// configureOCR enables OCR of scanned PDFs when it is turned on in the config
+func (p *ZhcpParser) configureOCR() error {
+	if p.config == nil || !p.config.OCR.Enabled {
+		return nil
+	}
+
+	cfg := p.config.OCR
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+
+	var engine pdf.OCREngine
+	switch cfg.Engine {
+	case "", "tesseract":
+		engine = pdf.NewTesseractOCR(cfg.TesseractPath, cfg.PdftoppmPath, cfg.DPI, timeout)
+	case "service":
+		engine = pdf.NewServiceOCR(cfg.ServiceURL, cfg.APIKey, timeout)
+	default:
+		return fmt.Errorf("unknown OCR engine: %s", cfg.Engine)
+	}
+
+	p.pdfExtractor.EnableOCR(engine, cfg.Languages, cfg.MinTextChars)
+	return nil
+}
+
 // getDocumentType determines the document type based on file extension
 func (p *ZhcpParser) getDocumentType(documentPath string) (string, error) {
 	ext := strings.ToLower(filepath.Ext(documentPath))
@@ -236,6 +544,14 @@ func (p *ZhcpParser) getDocumentType(documentPath string) (string, error) {
 		return "pdf", nil
 	case ".docx":
 		return "docx", nil
+	case ".xlsx":
+		return "xlsx", nil
+	case ".pptx":
+		return "pptx", nil
+	case ".txt":
+		return "text", nil
+	case ".md", ".markdown":
+		return "markdown", nil
 	default:
 		return "", fmt.Errorf("unsupported document type: %s", ext)
 	}
@@ -251,8 +567,26 @@ func (p *ZhcpParser) parseDOCX(docxPath string) (interface{}, error) {
 	return p.docxExtractor.ExtractWithFormatting(docxPath)
 }
 
+// parseXLSX parses an XLSX spreadsheet
+func (p *ZhcpParser) parseXLSX(xlsxPath string) (interface{}, error) {
+	return p.xlsxExtractor.ExtractContent(xlsxPath)
+}
+
+// parsePPTX parses a PPTX presentation
+func (p *ZhcpParser) parsePPTX(pptxPath string) (interface{}, error) {
+	return p.pptxExtractor.ExtractContent(pptxPath)
+}
+
 // getProjectJSONSchema returns the expected JSON schema for project structure
 func (p *ZhcpParser) getProjectJSONSchema() map[string]interface{} {
+	money := map[string]interface{}{
+		"type": []string{"object", "null"},
+		"properties": map[string]interface{}{
+			"amount":   map[string]interface{}{"type": "number"},
+			"currency": map[string]interface{}{"type": "string"},
+		},
+	}
+
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
@@ -262,6 +596,7 @@ func (p *ZhcpParser) getProjectJSONSchema() map[string]interface{} {
 					"title":       map[string]interface{}{"type": "string"},
 					"description": map[string]interface{}{"type": "string"},
 					"deadline":    map[string]interface{}{"type": []string{"string", "null"}},
+					"budget":      money,
 					"phases": map[string]interface{}{
 						"type": "array",
 						"items": map[string]interface{}{
@@ -272,6 +607,7 @@ func (p *ZhcpParser) getProjectJSONSchema() map[string]interface{} {
 								"description": map[string]interface{}{"type": "string"},
 								"start_date":  map[string]interface{}{"type": []string{"string", "null"}},
 								"end_date":    map[string]interface{}{"type": []string{"string", "null"}},
+								"cost":        money,
 								"tasks": map[string]interface{}{
 									"type": "array",
 									"items": map[string]interface{}{
@@ -298,6 +634,7 @@ func (p *ZhcpParser) getProjectJSONSchema() map[string]interface{} {
 												"items": map[string]interface{}{"type": "string"},
 											},
 											"status": map[string]interface{}{"type": "string"},
+											"cost":   money,
 										},
 									},
 								},
@@ -359,6 +696,46 @@ func (p *ZhcpParser) createErrorResult(err error, documentPath string, startTime
 }
 
 // determineSeverity determines the severity level for an error category
+// llmSuggestedActions replace the generic LLM advice for failures that are
+// not configuration problems
+var llmSuggestedActions = map[ai.FailureKind]string{
+	ai.FailureRateLimited: "Provider rate limits were reached, retry later or raise the limits",
+	ai.FailureServer:      "Providers are unavailable, retry later",
+	ai.FailureTimeout:     "Providers did not answer in time, retry later",
+	ai.FailureNetwork:     "Check network connectivity to the providers",
+	ai.FailureCircuitOpen: "Providers failed repeatedly and are paused, retry after the cooldown",
+	ai.FailureQuota:       "Provider quotas are used up, retry later or raise the quotas",
+}
+
+// createLLMErrorResult creates the result of a failed LLM generation. The
+// error is categorized by how the providers failed, and its details list
+// each provider's failure.
+func (p *ZhcpParser) createLLMErrorResult(err error, documentPath string, startTime time.Time) *ParseResult {
+	var genErr *ai.GenerationError
+	if !stderrors.As(err, &genErr) {
+		return p.createErrorResult(errors.NewLLMError(err.Error(), "", nil), documentPath, startTime)
+	}
+
+	kind := genErr.Kind()
+	var provider string
+	if n := len(genErr.Failures); n > 0 {
+		provider = string(genErr.Failures[n-1].Provider)
+	}
+	llmErr := errors.NewLLMError(genErr.Error(), provider, map[string]interface{}{
+		"reason":    string(kind),
+		"retryable": genErr.Retryable(),
+		"providers": genErr.Failures,
+	})
+
+	result := p.createErrorResult(llmErr, documentPath, startTime)
+	result.Error.Reason = string(kind)
+	result.Error.Retryable = genErr.Retryable()
+	if action, ok := llmSuggestedActions[kind]; ok {
+		result.Error.SuggestedAction = &action
+	}
+	return result
+}
+
 func (p *ZhcpParser) determineSeverity(category errors.ErrorCategory) errors.ErrorSeverity {
 	severityMapping := map[errors.ErrorCategory]errors.ErrorSeverity{
 		errors.ErrorCategoryParsing:        errors.ErrorSeverityError,
@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"zhcp-parser-go/internal/ai"
+	"zhcp-parser-go/internal/transformers"
+)
+
+// defaultLocalProviders get document text unmasked when the config does not
+// list any
+var defaultLocalProviders = []string{"ollama"}
+
+// Kinds of personal data, used in the placeholders that replace them
+const (
+	piiEmail    = "EMAIL"
+	piiIIN      = "IIN"
+	piiPassport = "PASSPORT"
+	piiPhone    = "PHONE"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// IINs and BINs are 12 digits: after the label any of them, elsewhere
+	// only those whose control digit validIIN accepts
+	iinLabelPattern = regexp.MustCompile(`(?i)(?:ИИН|ЖСН|БИН|IIN|BIN)[^\d\n]{0,5}(\d{12})\b`)
+	iinPattern      = regexp.MustCompile(`\b\d{12}\b`)
+	// Kazakh passports are N and 8 digits; other passport and ID card
+	// numbers are only recognized after a word naming the document
+	kzPassportPattern = regexp.MustCompile(`\bN\d{8}\b`)
+	passportPattern   = regexp.MustCompile(`(?i)(?:паспорт\p{L}*|passport|удостоверени\p{L}* личности|жеке куәлі\p{L}*)[^\d\n]{0,25}(\p{Lu}?\d[\d №]{5,12}\d)`)
+	// Phones as written in Kazakhstan and Russia: +7 701 123 45 67,
+	// 8 (701) 123-45-67, 87011234567, and other country codes
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}|\b8)[\s\-]?\(?\d{3}\)?[\s\-]?\d{3}[\s\-]?\d{2}[\s\-]?\d{2}\b`)
+	// Placeholders as the LLM echoes them, with or without the brackets
+	placeholderPattern = regexp.MustCompile(`\[?((?:EMAIL|IIN|PASSPORT|PHONE)_\d+)\]?`)
+)
+
+// redaction is the personal data masked in a document before it is sent to
+// an LLM, kept in memory to put the values back into the parsed project
+type redaction struct {
+	values       map[string]string // original value by placeholder name
+	placeholders map[string]string // placeholder name by original value
+	counts       map[string]int    // placeholders made of each kind
+}
+
+// redactText replaces phones, emails, IINs and passport numbers in text with
+// placeholders such as [PHONE_1]. A value repeated in the text gets the same
+// placeholder each time, and the same text always gets the same placeholders.
+func redactText(text string) (string, *redaction) {
+	r := &redaction{
+		values:       make(map[string]string),
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+	}
+	text = r.mask(text, emailPattern, piiEmail, nil)
+	text = r.mask(text, iinLabelPattern, piiIIN, nil)
+	text = r.mask(text, iinPattern, piiIIN, validIIN)
+	text = r.mask(text, kzPassportPattern, piiPassport, nil)
+	text = r.mask(text, passportPattern, piiPassport, nil)
+	text = r.mask(text, phonePattern, piiPhone, nil)
+	return text, r
+}
+
+// mask replaces the matches of pattern that valid accepts with placeholders
+// of kind. When pattern has a group, only the group is replaced.
+func (r *redaction) mask(text string, pattern *regexp.Regexp, kind string, valid func(string) bool) string {
+	group := min(pattern.NumSubexp(), 1)
+
+	var masked strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2*group], match[2*group+1]
+		if start < 0 {
+			continue
+		}
+		value := text[start:end]
+		if valid != nil && !valid(value) {
+			continue
+		}
+		masked.WriteString(text[last:start])
+		masked.WriteString("[" + r.placeholder(kind, value) + "]")
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	masked.WriteString(text[last:])
+	return masked.String()
+}
+
+// placeholder returns the placeholder name of a value, making one the first
+// time the value is seen
+func (r *redaction) placeholder(kind, value string) string {
+	if name, ok := r.placeholders[value]; ok {
+		return name
+	}
+	r.counts[kind]++
+	name := fmt.Sprintf("%s_%d", kind, r.counts[kind])
+	r.placeholders[value] = name
+	r.values[name] = value
+	return name
+}
+
+// len returns the number of values masked; a nil redaction masked none
+func (r *redaction) len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.values)
+}
+
+// restore puts the original values back in place of the placeholders in text
+func (r *redaction) restore(text string) string {
+	if !strings.Contains(text, "_") {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := r.values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// restoreProject puts the original values back into the text fields of a
+// parsed project, the contacts of responsible persons above all
+func (r *redaction) restoreProject(project *transformers.Project) {
+	if r.len() == 0 {
+		return
+	}
+	project.Title = r.restore(project.Title)
+	project.Description = r.restore(project.Description)
+	for i := range project.Phases {
+		phase := &project.Phases[i]
+		phase.Name = r.restore(phase.Name)
+		phase.Description = r.restore(phase.Description)
+		for j := range phase.Tasks {
+			task := &phase.Tasks[j]
+			task.Name = r.restore(task.Name)
+			task.Description = r.restore(task.Description)
+			for k := range task.ResponsiblePersons {
+				person := &task.ResponsiblePersons[k]
+				person.Name = r.restore(person.Name)
+				person.Role = r.restore(person.Role)
+				person.Contact = r.restore(person.Contact)
+			}
+		}
+	}
+}
+
+// validIIN checks the control digit of a Kazakh IIN or BIN
+func validIIN(value string) bool {
+	checksum := func(weight func(i int) int) int {
+		sum := 0
+		for i := 0; i < 11; i++ {
+			sum += int(value[i]-'0') * weight(i)
+		}
+		return sum % 11
+	}
+
+	control := checksum(func(i int) int { return i + 1 })
+	if control == 10 {
+		// Second pass with the weights 3..11, 1, 2
+		control = checksum(func(i int) int { return (i+2)%11 + 1 })
+	}
+	return control < 10 && control == int(value[11]-'0')
+}
+
+// redacts reports whether the text of a parse is masked before the LLM call:
+// redaction is on and the parse may reach a provider that is not local,
+// either the one the request picked or any of the fallback chain
+func (p *ZhcpParser) redacts(gen Generation) bool {
+	if p.config == nil || !p.config.Redaction.Enabled {
+		return false
+	}
+	local := p.config.Redaction.LocalProviders
+	if len(local) == 0 {
+		local = defaultLocalProviders
+	}
+
+	providers := p.llmManager.GetAvailableProviders()
+	if gen.Provider != "" {
+		providers = []ai.ProviderType{ai.ProviderType(gen.Provider)}
+	}
+	for _, provider := range providers {
+		if !slices.Contains(local, string(provider)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package parser
+
+import "testing"
+
+func TestValidIIN(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"control digit matches", "900101300126", true},
+		{"control digit differs", "900101300125", false},
+		{"control digit from the second pass", "850715400004", true},
+		{"both passes give 10", "900101300800", false},
+		{"arbitrary number", "123456789012", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validIIN(tt.value); got != tt.want {
+				t.Errorf("validIIN(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactTextRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		masked string
+		count  int
+	}{
+		{
+			name:   "phone and email",
+			text:   "Ответственный: Иванов, тел. +7 701 123 45 67, ivanov@example.kz",
+			masked: "Ответственный: Иванов, тел. [PHONE_1], [EMAIL_1]",
+			count:  2,
+		},
+		{
+			name:   "labelled IIN and a repeated valid one",
+			text:   "ИИН 900101300125 и снова 900101300126, 900101300126",
+			masked: "ИИН [IIN_1] и снова [IIN_2], [IIN_2]",
+			count:  2,
+		},
+		{
+			name:   "passport and ID card",
+			text:   "Паспорт: N12345678; удостоверение личности № 045123789",
+			masked: "Паспорт: [PASSPORT_1]; удостоверение личности № [PASSPORT_2]",
+			count:  2,
+		},
+		{
+			name:   "phones written two ways",
+			text:   "Звонить 8 (701) 123-45-67 или 87011234567",
+			masked: "Звонить [PHONE_1] или [PHONE_2]",
+			count:  2,
+		},
+		{
+			name:   "unlabelled number failing the IIN check",
+			text:   "Договор 123456789012 от 12.03.2024",
+			masked: "Договор 123456789012 от 12.03.2024",
+			count:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, r := redactText(tt.text)
+			if masked != tt.masked {
+				t.Errorf("redactText() = %q, want %q", masked, tt.masked)
+			}
+			if r.len() != tt.count {
+				t.Errorf("redactText() masked %d values, want %d", r.len(), tt.count)
+			}
+			if restored := r.restore(masked); restored != tt.text {
+				t.Errorf("restore() = %q, want %q", restored, tt.text)
+			}
+		})
+	}
+}
+
+func TestRestoreWithoutBrackets(t *testing.T) {
+	_, r := redactText("тел. +7 701 123 45 67, ivanov@example.kz")
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"bracketed", "[PHONE_1]", "+7 701 123 45 67"},
+		{"without brackets", "Звонить PHONE_1, писать на EMAIL_1", "Звонить +7 701 123 45 67, писать на ivanov@example.kz"},
+		{"one bracket dropped", "PHONE_1]", "+7 701 123 45 67"},
+		{"unknown placeholder", "PHONE_9", "PHONE_9"},
+		{"no placeholder", "Иванов", "Иванов"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.restore(tt.text); got != tt.want {
+				t.Errorf("restore(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
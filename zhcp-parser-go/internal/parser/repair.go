@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"zhcp-parser-go/internal/ai"
+	"zhcp-parser-go/internal/transformers"
+)
+
+const (
+	// maxRepairErrors bounds how many validation errors a repair prompt lists
+	maxRepairErrors = 20
+
+	// repairPenalty is taken off the extraction quality per repair call
+	repairPenalty = 0.2
+)
+
+// repairAttempts returns how many times a response the DataTransformer
+// rejects is sent back to the LLM for correction
+func (p *ZhcpParser) repairAttempts() int {
+	if p.config == nil {
+		return 0
+	}
+	return p.config.Repair.MaxAttempts
+}
+
+// transform turns an LLM response into a project structure. When the
+// response is not valid JSON or fails validation, the LLM is shown its
+// answer and the errors and asked for a corrected one, up to the configured
+// number of attempts. usage includes the repair calls.
+func (p *ZhcpParser) transform(ctx context.Context, prompt string, response *ai.LLMResponse, docType string, chars int, gen Generation, opts transformers.TransformOptions) (*transformers.TransformationResult, *LLMUsage) {
+	result := p.dataTransformer.TransformWithOptions(response.Content, opts)
+	usage := usageOf(response)
+
+	maxAttempts := p.repairAttempts()
+	if !needsRepair(result) || maxAttempts == 0 {
+		return result, usage
+	}
+
+	var notes []string
+	content := response.Content
+	attempt := 1
+	for ; attempt <= maxAttempts; attempt++ {
+		repaired, err := p.generate(ctx, repairPrompt(prompt, content, result.ValidationErrors), docType, chars, gen)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("Repair attempt %d failed: %v", attempt, err))
+			break
+		}
+		usage = addUsage(usage, repaired)
+
+		content = repaired.Content
+		result = p.dataTransformer.TransformWithOptions(content, opts)
+		if !needsRepair(result) {
+			notes = append(notes, fmt.Sprintf("LLM response repaired after %d attempt(s)", attempt))
+			if result.ConfidenceBreakdown != nil {
+				result.ConfidenceBreakdown.LowerExtractionQuality(repairPenalty*float64(attempt),
+					fmt.Sprintf("LLM response was rejected and needed %d repair call(s)", attempt))
+				result.ConfidenceScore = result.ConfidenceBreakdown.Score()
+			}
+			break
+		}
+		notes = append(notes, fmt.Sprintf("Repair attempt %d still invalid: %s", attempt, strings.Join(result.ValidationErrors, "; ")))
+	}
+	if attempt > maxAttempts {
+		notes = append(notes, fmt.Sprintf("LLM response could not be repaired in %d attempt(s)", maxAttempts))
+	}
+
+	result.ProcessingNotes = append(notes, result.ProcessingNotes...)
+	return result, usage
+}
+
+// needsRepair reports whether the DataTransformer produced nothing usable
+func needsRepair(result *transformers.TransformationResult) bool {
+	return result.Status == transformers.TransformationStatusFailed ||
+		result.Status == transformers.TransformationStatusValidationError
+}
+
+// repairPrompt repeats the original request with the rejected answer and the
+// reasons it was rejected
+func repairPrompt(prompt, response string, validationErrors []string) string {
+	if len(validationErrors) > maxRepairErrors {
+		validationErrors = append(validationErrors[:maxRepairErrors:maxRepairErrors],
+			fmt.Sprintf("... and %d more", len(validationErrors)-maxRepairErrors))
+	}
+
+	var builder strings.Builder
+	builder.WriteString(prompt)
+	builder.WriteString("\n\nYour previous answer to this request could not be used:\n\n")
+	builder.WriteString(response)
+	builder.WriteString("\n\nErrors:\n")
+	for _, validationError := range validationErrors {
+		builder.WriteString("- ")
+		builder.WriteString(validationError)
+		builder.WriteString("\n")
+	}
+	builder.WriteString("\nReturn the corrected answer: a single valid JSON object following the schema above, without additional text.")
+	return builder.String()
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"zhcp-parser-go/internal/ai"
+	"zhcp-parser-go/internal/errors"
+)
+
+// Artifacts are the intermediate outputs of a parse: the extracted text and,
+// once the LLM answered, its raw response. They are enough to run the
+// pipeline again from the LLM call or from the transformation.
+type Artifacts struct {
+	Text    string       `json:"text"`
+	DocType string       `json:"doc_type"`
+	Path    string       `json:"path,omitempty"` // document the text was extracted from
+	OCR     *OCRMetadata `json:"ocr,omitempty"`
+	Notes   []string     `json:"notes,omitempty"` // extraction notes
+
+	// LLMResponse is only kept for documents parsed in a single chunk
+	LLMResponse *ai.LLMResponse `json:"llm_response,omitempty"`
+}
+
+func artifactsOf(doc extractedDocument) Artifacts {
+	return Artifacts{
+		Text:        doc.text,
+		DocType:     doc.docType,
+		Path:        doc.path,
+		OCR:         doc.ocr,
+		Notes:       slices.Clone(doc.notes),
+		LLMResponse: doc.response,
+	}
+}
+
+// Retry runs the pipeline again on the artifacts of a failed parse, without
+// the document. From StageLLM the LLM is asked again; from
+// StageTransformation the kept LLM response is transformed again, e.g. after
+// the transformer was fixed. The result cache is not consulted.
+func (p *ZhcpParser) Retry(ctx context.Context, artifacts *Artifacts, stage string, opts ParseOptions) (*ParseResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	opts.Force = true
+
+	doc := extractedDocument{
+		text:    artifacts.Text,
+		docType: artifacts.DocType,
+		path:    artifacts.Path,
+		ocr:     artifacts.OCR,
+		notes:   slices.Clone(artifacts.Notes),
+	}
+	switch stage {
+	case StageLLM:
+	case StageTransformation:
+		if artifacts.LLMResponse == nil {
+			err := errors.NewParsingError("No LLM response was kept to retry the transformation from", doc.path, nil)
+			return p.createErrorResult(err, doc.path, startTime), nil
+		}
+		doc.response = artifacts.LLMResponse
+		doc.notes = append(doc.notes, "Retried from the transformation with the LLM response of the previous attempt")
+	default:
+		return nil, fmt.Errorf("cannot retry a parse from stage %q", stage)
+	}
+	return p.runPipeline(ctx, doc, opts, startTime)
+}
+
+// answer returns the LLM response for the prompt of a single-chunk document.
+// A response kept from an earlier attempt is reused without its usage,
+// which was accounted for by that attempt.
+func (p *ZhcpParser) answer(ctx context.Context, doc extractedDocument, prompt string, chars int, gen Generation) (*ai.LLMResponse, error) {
+	if doc.response == nil {
+		return p.generate(ctx, prompt, doc.docType, chars, gen)
+	}
+
+	reused := *doc.response
+	reused.TokensUsed = ai.TokenUsage{}
+	reused.Cost = 0
+	return &reused, nil
+}
@@ -7,6 +7,42 @@ import (
 	"zhcp-parser-go/internal/validators"
 )
 
+// Parse stages reported to a ProgressFunc, in pipeline order
+const (
+	StageValidation     = "validation"
+	StageExtraction     = "extraction"
+	StageLLM            = "llm"
+	StageTransformation = "transformation"
+)
+
+// ProgressFunc receives the stage a parse has reached and its overall
+// progress in percent
+type ProgressFunc func(stage string, progress int)
+
+// report calls the ProgressFunc if there is one
+func (f ProgressFunc) report(stage string, progress int) {
+	if f != nil {
+		f(stage, progress)
+	}
+}
+
+// ParseOptions control a single parse
+type ParseOptions struct {
+	Validate bool
+	Enrich   bool
+	// Force skips the result cache lookup; the new result still replaces
+	// the cached one
+	Force bool
+	// Progress, when set, is called as each pipeline stage starts
+	Progress ProgressFunc
+	// Generation overrides the configured LLM settings for this parse
+	Generation Generation
+	// Artifacts, when set, is filled in with the extracted text once
+	// extraction finished and the raw LLM response once the LLM answered,
+	// so a failed parse can be retried with Retry
+	Artifacts *Artifacts
+}
+
 // ParseResult represents the result of document parsing
 type ParseResult struct {
 	Success            bool                           `json:"success"`
@@ -14,21 +50,54 @@ type ParseResult struct {
 	ExtractionMetadata ExtractionMetadata             `json:"extraction_metadata"`
 	ValidationError    []string                       `json:"validation_errors,omitempty"`
 	ProcessingNotes    []string                       `json:"processing_notes,omitempty"`
+	Conflicts          []transformers.Conflict        `json:"conflicts,omitempty"` // fields documents parsed together disagree on
 	Error              *ErrorInfo                     `json:"error,omitempty"`
 }
 
 // ExtractionMetadata contains metadata about the extraction process
 type ExtractionMetadata struct {
-	Confidence        float64                      `json:"confidence"`
-	Status            string                       `json:"status"`
-	ProcessingTime    float64                      `json:"processing_time"`
-	ValidationResults *validators.ValidationResult `json:"validation_results,omitempty"`
+	Confidence            float64                           `json:"confidence"`
+	ConfidenceBreakdown   *transformers.ConfidenceBreakdown `json:"confidence_breakdown,omitempty"` // why the confidence is what it is
+	Status                string                            `json:"status"`
+	ProcessingTime        float64                           `json:"processing_time"`
+	ValidationResults     *validators.ValidationResult      `json:"validation_results,omitempty"`
+	OCR                   *OCRMetadata                      `json:"ocr,omitempty"`
+	Cached                bool                              `json:"cached,omitempty"`
+	PromptVersion         string                            `json:"prompt_version,omitempty"`
+	PromptTemplateVersion int                               `json:"prompt_template_version,omitempty"` // stored extraction prompt version, 0 when read from prompts/
+	Language              string                            `json:"language,omitempty"`                // detected document language: ru, kk or en
+	Usage                 *LLMUsage                         `json:"usage,omitempty"`                   // nil when no LLM call was made
+	Chunks                []ChunkMetadata                   `json:"chunks,omitempty"`                  // set when the document was parsed in parts
+	Documents             []DocumentMetadata                `json:"documents,omitempty"`               // set when several documents were parsed together
+	Redacted              int                               `json:"redacted,omitempty"`                // personal data values masked before the LLM call
+}
+
+// LLMUsage is the token usage and cost of the LLM call behind a result
+type LLMUsage struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalTokens  int     `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	Estimated    bool    `json:"estimated,omitempty"`  // token counts were estimated
+	Structured   bool    `json:"structured,omitempty"` // the provider enforced the project schema
+}
+
+// OCRMetadata describes the OCR pass run on a scanned document
+type OCRMetadata struct {
+	Engine     string   `json:"engine"`
+	Languages  []string `json:"languages"`
+	Confidence float64  `json:"confidence"`
+	Pages      int      `json:"pages"`
 }
 
 // ErrorInfo represents error information
 type ErrorInfo struct {
 	ErrorID         string                 `json:"error_id"`
 	Category        string                 `json:"category"`
+	Reason          string                 `json:"reason,omitempty"` // why the LLM call failed, e.g. rate_limited
+	Retryable       bool                   `json:"retryable"`
 	Severity        string                 `json:"severity"`
 	Message         string                 `json:"message"`
 	Details         map[string]interface{} `json:"details"`
@@ -0,0 +1,99 @@
+package parsers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages the parser has prompts and dictionaries for
+const (
+	LanguageRussian = "ru"
+	LanguageKazakh  = "kk"
+	LanguageEnglish = "en"
+)
+
+// languageSampleRunes bounds how much text language detection reads
+const languageSampleRunes = 20000
+
+// kazakhLetters are Cyrillic letters of Kazakh that Russian does not use
+const kazakhLetters = "әғқңөұүһі"
+
+// kazakhWords are frequent Kazakh words, for text that avoids the letters
+// above, e.g. when they were replaced by Russian look-alikes
+var kazakhWords = map[string]bool{
+	"және": true, "бойынша": true, "үшін": true, "жоба": true, "жобаның": true,
+	"мен": true, "бен": true, "пен": true, "бұл": true, "жылы": true,
+	"тиіс": true, "жауапты": true, "кезең": true, "міндет": true,
+}
+
+// LanguageDetection is the detected language of a text
+type LanguageDetection struct {
+	Language   string  `json:"language"`   // ru, kk or en
+	Confidence float64 `json:"confidence"` // share of letters supporting the language
+}
+
+// DetectLanguage tells Russian, Kazakh and English apart by their alphabets:
+// mostly Latin text is English, Cyrillic text is Kazakh when it uses
+// Kazakh-only letters or words often enough, Russian otherwise. Text without
+// letters is reported as Russian with zero confidence.
+func (tp *TextPreprocessor) DetectLanguage(text string) LanguageDetection {
+	var latin, cyrillic, kazakh, seen int
+	var word strings.Builder
+	words, kazakhWordCount := 0, 0
+
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		words++
+		if kazakhWords[word.String()] {
+			kazakhWordCount++
+		}
+		word.Reset()
+	}
+
+	for _, r := range text {
+		if seen >= languageSampleRunes {
+			break
+		}
+		seen++
+
+		if !unicode.IsLetter(r) {
+			endWord()
+			continue
+		}
+		r = unicode.ToLower(r)
+		word.WriteRune(r)
+
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune(kazakhLetters, r) {
+				kazakh++
+			}
+		}
+	}
+	endWord()
+
+	letters := latin + cyrillic
+	if letters == 0 {
+		return LanguageDetection{Language: LanguageRussian}
+	}
+	if latin > cyrillic {
+		return LanguageDetection{Language: LanguageEnglish, Confidence: float64(latin) / float64(letters)}
+	}
+
+	// Kazakh-only letters make up several percent of Kazakh text, while in
+	// Russian they only appear in quoted Kazakh names
+	cyrillicShare := float64(cyrillic) / float64(letters)
+	kazakhLetterShare := float64(kazakh) / float64(cyrillic)
+	kazakhWordShare := float64(kazakhWordCount) / float64(max(words, 1))
+	if kazakhLetterShare >= 0.02 || kazakhWordShare >= 0.05 {
+		confidence := min(1.0, max(kazakhLetterShare/0.05, kazakhWordShare/0.1)) * cyrillicShare
+		return LanguageDetection{Language: LanguageKazakh, Confidence: confidence}
+	}
+
+	return LanguageDetection{Language: LanguageRussian, Confidence: cyrillicShare * (1 - kazakhLetterShare/0.02)}
+}
@@ -0,0 +1,490 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxStreamSize bounds the decompressed size of one content stream
+const maxStreamSize = 16 << 20
+
+// textRun is a string shown by a content stream, at the position of its
+// first glyph in user space
+type textRun struct {
+	page int
+	x, y float64
+	text string
+}
+
+// matrix is a PDF transformation matrix [a b c d e f]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(tx, ty float64) matrix {
+	return matrix{1, 0, 0, 1, tx, ty}
+}
+
+// extractTextRuns reads the positioned text of every content stream, taking
+// the streams that show text as pages in file order. Streams compressed with
+// anything but FlateDecode are skipped, as are glyphs of fonts encoded other
+// than as ASCII, UTF-8 or UTF-16.
+func extractTextRuns(content []byte) []textRun {
+	var runs []textRun
+	page := 0
+	for _, stream := range contentStreams(content) {
+		streamRuns := interpretContent(stream)
+		if len(streamRuns) == 0 {
+			continue
+		}
+		page++
+		for i := range streamRuns {
+			streamRuns[i].page = page
+		}
+		runs = append(runs, streamRuns...)
+	}
+	return runs
+}
+
+// contentStreams returns the decoded data of the streams of a PDF that may
+// hold page content
+func contentStreams(content []byte) [][]byte {
+	var streams [][]byte
+	offset := 0
+	for {
+		start := bytes.Index(content[offset:], []byte("stream"))
+		if start < 0 {
+			break
+		}
+		start += offset
+		offset = start + len("stream")
+		if start >= 3 && string(content[start-3:start]) == "end" {
+			continue
+		}
+
+		// The stream dictionary sits between the object header and the keyword
+		dictStart := bytes.LastIndex(content[:start], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := content[dictStart:start]
+
+		dataStart := offset
+		if dataStart < len(content) && content[dataStart] == '\r' {
+			dataStart++
+		}
+		if dataStart < len(content) && content[dataStart] == '\n' {
+			dataStart++
+		}
+		end := bytes.Index(content[dataStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		data := content[dataStart : dataStart+end]
+		offset = dataStart + end + len("endstream")
+
+		if bytes.Contains(dict, []byte("/Subtype/Image")) || bytes.Contains(dict, []byte("/Subtype /Image")) {
+			continue
+		}
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			// Streams often end with a line break the length does not cover,
+			// so a decoding error after some data is not fatal
+			decoded, _ := io.ReadAll(io.LimitReader(reader, maxStreamSize))
+			reader.Close()
+			if len(decoded) > 0 {
+				streams = append(streams, decoded)
+			}
+		case bytes.Contains(dict, []byte("/Filter")):
+			// Other filters are not supported
+		default:
+			streams = append(streams, data)
+		}
+	}
+	return streams
+}
+
+// contentToken is an operand or operator of a content stream
+type contentToken struct {
+	kind   byte // 'n' number, 's' string, 'a' array, 'o' operator, 'x' other
+	number float64
+	text   []byte
+	array  []contentToken
+}
+
+// contentLexer splits a content stream into tokens
+type contentLexer struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next token, or false at the end of the stream
+func (l *contentLexer) next() (contentToken, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return contentToken{}, false
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return contentToken{kind: 's', text: l.literalString()}, true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.skipDictionary()
+		return contentToken{kind: 'x'}, true
+	case c == '<':
+		return contentToken{kind: 's', text: l.hexString()}, true
+	case c == '[':
+		l.pos++
+		var array []contentToken
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				break
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				break
+			}
+			token, ok := l.next()
+			if !ok {
+				break
+			}
+			array = append(array, token)
+		}
+		return contentToken{kind: 'a', array: array}, true
+	case c == '/':
+		l.pos++
+		l.word()
+		return contentToken{kind: 'x'}, true
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		word := l.word()
+		number, err := strconv.ParseFloat(string(word), 64)
+		if err != nil {
+			return contentToken{kind: 'x'}, true
+		}
+		return contentToken{kind: 'n', number: number}, true
+	case isDelimiter(c):
+		l.pos++
+		return contentToken{kind: 'x'}, true
+	default:
+		return contentToken{kind: 'o', text: l.word()}, true
+	}
+}
+
+func (l *contentLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// word reads up to the next whitespace or delimiter
+func (l *contentLexer) word() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++ // never stall on an unexpected byte
+	}
+	return l.data[start:l.pos]
+}
+
+// literalString reads a (string) with nested parentheses and escapes
+func (l *contentLexer) literalString() []byte {
+	l.pos++
+	var text []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return text
+			}
+			escaped := l.data[l.pos]
+			l.pos++
+			switch escaped {
+			case 'n':
+				text = append(text, '\n')
+			case 'r':
+				text = append(text, '\r')
+			case 't':
+				text = append(text, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if escaped >= '0' && escaped <= '7' {
+					value := int(escaped - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					text = append(text, byte(value))
+				} else {
+					text = append(text, escaped)
+				}
+			}
+		case '(':
+			depth++
+			text = append(text, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return text
+			}
+			text = append(text, c)
+		default:
+			text = append(text, c)
+		}
+	}
+	return text
+}
+
+// hexString reads a <hex string>
+func (l *contentLexer) hexString() []byte {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; unicode.Is(unicode.ASCII_Hex_Digit, rune(c)) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	text := make([]byte, len(digits)/2)
+	for i := range text {
+		value, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		text[i] = byte(value)
+	}
+	return text
+}
+
+func (l *contentLexer) skipDictionary() {
+	depth := 0
+	for l.pos+1 < len(l.data) {
+		switch {
+		case l.data[l.pos] == '<' && l.data[l.pos+1] == '<':
+			depth++
+			l.pos += 2
+		case l.data[l.pos] == '>' && l.data[l.pos+1] == '>':
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.data)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return c == '(' || c == ')' || c == '<' || c == '>' || c == '[' || c == ']' || c == '{' || c == '}' || c == '/' || c == '%'
+}
+
+// tjSpacing is the TJ adjustment, in thousandths of a text space unit, from
+// which a gap between two strings is read as a space
+const tjSpacing = -200
+
+// interpretContent runs the text operators of a content stream and returns
+// the strings it shows. Glyph widths are unknown, so strings shown without
+// moving the text position are joined to the previous run.
+func interpretContent(data []byte) []textRun {
+	lexer := &contentLexer{data: data}
+	var (
+		runs     []textRun
+		operands []contentToken
+		ctm      = identity
+		ctmStack []matrix
+		tm, tlm  = identity, identity
+		leading  float64
+		inText   bool
+		moved    = true
+	)
+
+	show := func(text string) {
+		if text == "" {
+			return
+		}
+		position := tm.multiply(ctm)
+		if !moved && len(runs) > 0 {
+			runs[len(runs)-1].text += text
+			return
+		}
+		runs = append(runs, textRun{x: position[4], y: position[5], text: text})
+		moved = false
+	}
+	nextLine := func() {
+		tlm = translate(0, -leading).multiply(tlm)
+		tm = tlm
+		moved = true
+	}
+
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+		if token.kind != 'o' {
+			operands = append(operands, token)
+			continue
+		}
+
+		numbers := numberOperands(operands)
+		switch string(token.text) {
+		case "q":
+			ctmStack = append(ctmStack, ctm)
+		case "Q":
+			if len(ctmStack) > 0 {
+				ctm = ctmStack[len(ctmStack)-1]
+				ctmStack = ctmStack[:len(ctmStack)-1]
+			}
+		case "cm":
+			if len(numbers) == 6 {
+				ctm = matrix(numbers).multiply(ctm)
+			}
+		case "BT":
+			inText = true
+			tm, tlm = identity, identity
+			moved = true
+		case "ET":
+			inText = false
+		case "Tm":
+			if len(numbers) == 6 {
+				tm = matrix(numbers)
+				tlm = tm
+				moved = true
+			}
+		case "Td", "TD":
+			if len(numbers) == 2 {
+				if string(token.text) == "TD" {
+					leading = -numbers[1]
+				}
+				tlm = translate(numbers[0], numbers[1]).multiply(tlm)
+				tm = tlm
+				moved = true
+			}
+		case "TL":
+			if len(numbers) == 1 {
+				leading = numbers[0]
+			}
+		case "T*":
+			nextLine()
+		case "Tj", "'", "\"":
+			if !inText || len(operands) == 0 {
+				break
+			}
+			if string(token.text) != "Tj" {
+				nextLine()
+			}
+			if last := operands[len(operands)-1]; last.kind == 's' {
+				show(decodePDFString(last.text))
+			}
+		case "TJ":
+			if !inText || len(operands) == 0 || operands[len(operands)-1].kind != 'a' {
+				break
+			}
+			var text []rune
+			for _, element := range operands[len(operands)-1].array {
+				switch element.kind {
+				case 's':
+					text = append(text, []rune(decodePDFString(element.text))...)
+				case 'n':
+					if element.number <= tjSpacing && len(text) > 0 && text[len(text)-1] != ' ' {
+						text = append(text, ' ')
+					}
+				}
+			}
+			show(string(text))
+		}
+		operands = operands[:0]
+	}
+	return runs
+}
+
+func numberOperands(operands []contentToken) []float64 {
+	numbers := make([]float64, 0, len(operands))
+	for _, operand := range operands {
+		if operand.kind == 'n' {
+			numbers = append(numbers, operand.number)
+		}
+	}
+	return numbers
+}
+
+// decodePDFString converts a shown string to text: UTF-16BE when it starts
+// with a byte order mark, UTF-8 when valid, ASCII otherwise. Other bytes,
+// e.g. glyph IDs of embedded fonts, are dropped.
+func decodePDFString(raw []byte) string {
+	var runes []rune
+	switch {
+	case len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF:
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		runes = utf16.Decode(units)
+	case utf8.Valid(raw):
+		runes = []rune(string(raw))
+	default:
+		for _, b := range raw {
+			if b < utf8.RuneSelf {
+				runes = append(runes, rune(b))
+			}
+		}
+	}
+
+	text := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			text = append(text, ' ')
+		case unicode.IsPrint(r):
+			text = append(text, r)
+		}
+	}
+	return string(text)
+}
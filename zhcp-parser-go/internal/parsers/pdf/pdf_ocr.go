@@ -0,0 +1,310 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultOCRLanguages are the language hints used when none are configured
+var DefaultOCRLanguages = []string{"ru", "kk", "en"}
+
+// tesseractLanguages maps language hints to Tesseract traineddata names
+var tesseractLanguages = map[string]string{
+	"ru": "rus",
+	"kk": "kaz",
+	"en": "eng",
+}
+
+var (
+	pdfImagePattern = regexp.MustCompile(`/Subtype\s*/Image`)
+	pdfFontPattern  = regexp.MustCompile(`/Type\s*/Font`)
+)
+
+// OCREngine recognizes text in scanned PDF documents
+type OCREngine interface {
+	Name() string
+	Recognize(ctx context.Context, pdfPath string, languages []string) (*OCRResult, error)
+}
+
+// OCRResult represents the text recognized by an OCR engine
+type OCRResult struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"` // 0.0 - 1.0
+	Pages      int     `json:"pages"`
+}
+
+// isImageOnlyPDF reports whether a PDF looks like a scan: it embeds images
+// and either has no fonts at all or yields almost no extractable letters
+func isImageOnlyPDF(content []byte, text string, minTextChars int) bool {
+	if !pdfImagePattern.Match(content) {
+		return false
+	}
+	if !pdfFontPattern.Match(content) {
+		return true
+	}
+
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters < minTextChars
+}
+
+// TesseractOCR runs the tesseract CLI on pages rendered by pdftoppm
+type TesseractOCR struct {
+	tesseractPath string
+	pdftoppmPath  string
+	dpi           int
+	timeout       time.Duration
+}
+
+// NewTesseractOCR creates a Tesseract OCR engine. Empty paths are looked up
+// in PATH.
+func NewTesseractOCR(tesseractPath, pdftoppmPath string, dpi int, timeout time.Duration) *TesseractOCR {
+	if tesseractPath == "" {
+		tesseractPath = "tesseract"
+	}
+	if pdftoppmPath == "" {
+		pdftoppmPath = "pdftoppm"
+	}
+	if dpi <= 0 {
+		dpi = 300
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return &TesseractOCR{
+		tesseractPath: tesseractPath,
+		pdftoppmPath:  pdftoppmPath,
+		dpi:           dpi,
+		timeout:       timeout,
+	}
+}
+
+// Name returns the engine name
+func (t *TesseractOCR) Name() string {
+	return "tesseract"
+}
+
+// Recognize renders every page to PNG and recognizes it with Tesseract. The
+// confidence is the mean word confidence over all pages.
+func (t *TesseractOCR) Recognize(ctx context.Context, pdfPath string, languages []string) (*OCRResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "zhcp-ocr-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCR work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	render := exec.CommandContext(ctx, t.pdftoppmPath, "-r", strconv.Itoa(t.dpi), "-png", pdfPath, filepath.Join(workDir, "page"))
+	if output, err := render.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	pages, err := filepath.Glob(filepath.Join(workDir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("pdftoppm rendered no pages")
+	}
+	sort.Strings(pages)
+
+	langArg := tesseractLanguageArg(languages)
+	result := &OCRResult{Pages: len(pages)}
+	var (
+		text          strings.Builder
+		confidenceSum float64
+		words         int
+	)
+	for _, page := range pages {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, t.tesseractPath, page, "stdout", "-l", langArg, "tsv")
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+
+		pageText, pageSum, pageWords := parseTesseractTSV(stdout.String())
+		if pageText != "" {
+			text.WriteString(pageText)
+			text.WriteString("\n\n")
+		}
+		confidenceSum += pageSum
+		words += pageWords
+	}
+
+	result.Text = strings.TrimSpace(text.String())
+	if words > 0 {
+		result.Confidence = confidenceSum / float64(words) / 100
+	}
+	return result, nil
+}
+
+// tesseractLanguageArg converts language hints to Tesseract's "rus+kaz+eng"
+// form; unknown hints are passed through as-is
+func tesseractLanguageArg(languages []string) string {
+	if len(languages) == 0 {
+		languages = DefaultOCRLanguages
+	}
+
+	codes := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" {
+			continue
+		}
+		if code, ok := tesseractLanguages[lang]; ok {
+			lang = code
+		}
+		codes = append(codes, lang)
+	}
+	return strings.Join(codes, "+")
+}
+
+// parseTesseractTSV rebuilds the text of a page from Tesseract's TSV output
+// and returns the sum of word confidences with the number of words
+func parseTesseractTSV(tsv string) (string, float64, int) {
+	var (
+		text          strings.Builder
+		confidenceSum float64
+		words         int
+		lastLine      string
+	)
+
+	for i, line := range strings.Split(tsv, "\n") {
+		if i == 0 {
+			continue // header
+		}
+		// level page block par line word left top width height conf text
+		fields := strings.SplitN(line, "\t", 12)
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+		word := strings.TrimSpace(fields[11])
+		confidence, err := strconv.ParseFloat(fields[10], 64)
+		if word == "" || err != nil || confidence < 0 {
+			continue
+		}
+
+		lineKey := strings.Join(fields[1:5], ".")
+		switch {
+		case text.Len() == 0:
+		case lineKey != lastLine:
+			text.WriteString("\n")
+		default:
+			text.WriteString(" ")
+		}
+		lastLine = lineKey
+		text.WriteString(word)
+
+		confidenceSum += confidence
+		words++
+	}
+
+	return text.String(), confidenceSum, words
+}
+
+// ServiceOCR sends scanned PDFs to an external OCR service. The service
+// receives a multipart form with "file" and "languages" and answers with
+// {"text": "...", "confidence": 0.93, "pages": 3}.
+type ServiceOCR struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewServiceOCR creates an OCR engine backed by an HTTP service
+func NewServiceOCR(url, apiKey string, timeout time.Duration) *ServiceOCR {
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return &ServiceOCR{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the engine name
+func (s *ServiceOCR) Name() string {
+	return "service"
+}
+
+// Recognize uploads the PDF to the OCR service
+func (s *ServiceOCR) Recognize(ctx context.Context, pdfPath string, languages []string) (*OCRResult, error) {
+	if len(languages) == 0 {
+		languages = DefaultOCRLanguages
+	}
+
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF file: %w", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(pdfPath))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteField("languages", strings.Join(languages, ",")); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCR service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("OCR service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result OCRResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode OCR service response: %w", err)
+	}
+	// Some services report confidence in percent
+	if result.Confidence > 1 {
+		result.Confidence /= 100
+	}
+	return &result, nil
+}
@@ -2,6 +2,7 @@ package pdf
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -9,22 +10,45 @@ import (
 	"strings"
 )
 
+// defaultMinTextChars is how many letters a PDF must yield before it is no
+// longer treated as a scan
+const defaultMinTextChars = 50
+
 // PDFExtractor handles PDF text extraction with fallback mechanisms
 type PDFExtractor struct {
-	logger interface{} // In a real implementation, we'd use a proper logger interface
+	logger       interface{} // In a real implementation, we'd use a proper logger interface
+	ocrEngine    OCREngine
+	ocrLanguages []string
+	minTextChars int
 }
 
 // NewPDFExtractor creates a new PDF extractor
 func NewPDFExtractor(logger interface{}) *PDFExtractor {
 	return &PDFExtractor{
-		logger: logger,
+		logger:       logger,
+		minTextChars: defaultMinTextChars,
+	}
+}
+
+// EnableOCR makes the extractor recognize image-only PDFs with the given
+// engine and language hints (ru, kk, en) before the text goes to the LLM
+func (e *PDFExtractor) EnableOCR(engine OCREngine, languages []string, minTextChars int) {
+	if len(languages) == 0 {
+		languages = DefaultOCRLanguages
+	}
+	if minTextChars <= 0 {
+		minTextChars = defaultMinTextChars
 	}
+	e.ocrEngine = engine
+	e.ocrLanguages = languages
+	e.minTextChars = minTextChars
 }
 
 // ExtractText extracts text from PDF with fallback mechanisms
 func (e *PDFExtractor) ExtractText(pdfPath string) (*PDFExtractionResult, error) {
 	result := &PDFExtractionResult{
 		Metadata:  make(map[string]interface{}),
+		Tables:    []TableInfo{},
 		Structure: []StructureInfo{},
 	}
 
@@ -61,10 +85,41 @@ func (e *PDFExtractor) ExtractText(pdfPath string) (*PDFExtractionResult, error)
 	// This is a simplified approach - in a real implementation you'd use a proper PDF library
 	// For now, we'll extract text using regex patterns to find text within PDF format
 	text := extractTextFromPDFBytes(content)
-
+	result.PageCount = 1
+
+	// Text positioned by the content streams keeps lines and table rows
+	// apart, so it replaces the plain string scan when there is any
+	if runs := extractTextRuns(content); len(runs) > 0 {
+		layoutText, tables := reconstructLayout(runs)
+		if layoutText != "" {
+			text = layoutText
+			result.Tables = tables
+			result.PageCount = runs[len(runs)-1].page
+		}
+	}
 	result.Text = text
-	result.PageCount = 1 // Simplified - in real implementation, count actual pages
-	result.HasTables = e.hasTables(result.Text)
+
+	// Scanned documents carry no text layer, so recognize them instead
+	result.ImageOnly = isImageOnlyPDF(content, text, e.minTextChars)
+	if result.ImageOnly && e.ocrEngine != nil {
+		ocrResult, err := e.ocrEngine.Recognize(context.Background(), pdfPath, e.ocrLanguages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recognize scanned PDF: %w", err)
+		}
+		result.Text = ocrResult.Text
+		result.Tables = []TableInfo{}
+		if ocrResult.Pages > 0 {
+			result.PageCount = ocrResult.Pages
+		}
+		result.OCR = &OCRInfo{
+			Engine:     e.ocrEngine.Name(),
+			Languages:  e.ocrLanguages,
+			Confidence: ocrResult.Confidence,
+			Pages:      ocrResult.Pages,
+		}
+	}
+
+	result.HasTables = len(result.Tables) > 0 || e.hasTables(result.Text)
 
 	// Add basic structure information
 	structureInfo := StructureInfo{
@@ -73,6 +128,13 @@ func (e *PDFExtractor) ExtractText(pdfPath string) (*PDFExtractionResult, error)
 		Content: "PDF content extracted",
 	}
 	result.Structure = append(result.Structure, structureInfo)
+	for _, table := range result.Tables {
+		result.Structure = append(result.Structure, StructureInfo{
+			Page:    table.Page,
+			Type:    "table",
+			Content: fmt.Sprintf("Table %d: %d rows, %d columns", table.Index, table.Rows, table.Columns),
+		})
+	}
 
 	return result, nil
 }
@@ -106,9 +168,20 @@ func (e *PDFExtractor) ExtractTables(pdfPath string) ([][]string, error) {
 		return nil, err
 	}
 
-	// This is a simplified implementation - in a real implementation, you'd use
-	// more sophisticated table detection algorithms
+	// Tables rebuilt from the layout, a pipe-separated line per row
 	tables := [][]string{}
+	if len(result.Tables) > 0 {
+		for _, table := range result.Tables {
+			rows := []string{strings.Join(table.HeaderRow, " | ")}
+			for _, row := range table.DataRows {
+				rows = append(rows, strings.Join(row, " | "))
+			}
+			tables = append(tables, rows)
+		}
+		return tables, nil
+	}
+
+	// Otherwise lines with separators, e.g. in OCR text
 
 	text := result.Text
 	lines := strings.Split(text, "\n")
@@ -0,0 +1,269 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Tolerances of the layout reconstruction, in points
+const (
+	lineTolerance   = 3.0 // runs whose baselines differ less are on one line
+	columnTolerance = 8.0 // cells whose left edges differ less are in one column
+)
+
+// minTableRows is how many rows, header included, make a table
+const minTableRows = 2
+
+// layoutLine is a line of a page with its runs as cells, left to right
+type layoutLine struct {
+	page  int
+	y     float64
+	cells []textRun
+}
+
+// layoutTable is a run of consecutive lines whose cells share column edges
+type layoutTable struct {
+	page        int
+	first, last int       // indexes of the lines
+	anchors     []float64 // left edges of the columns, in order found
+	rows        [][]textRun
+	lastY       float64
+	rowGap      float64 // smallest distance between rows so far
+}
+
+// reconstructLayout orders the runs of a PDF into lines and rebuilds the
+// tables among them from the column edges of their cells. The text has a
+// line per layout line, with each table as a block of pipe-separated rows,
+// so the LLM sees schedules and budgets as rows instead of a stream of words.
+func reconstructLayout(runs []textRun) (string, []TableInfo) {
+	lines := layoutLines(runs)
+	spans := findTables(lines)
+
+	tables := make([]TableInfo, 0, len(spans))
+	starts := make(map[int]int, len(spans))
+	inTable := make(map[int]bool)
+	for i, span := range spans {
+		tables = append(tables, span.tableInfo(i+1))
+		starts[span.first] = i
+		for line := span.first; line <= span.last; line++ {
+			inTable[line] = true
+		}
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 && line.page != lines[i-1].page {
+			b.WriteString("\n")
+		}
+		if index, ok := starts[i]; ok {
+			writeTableBlock(&b, tables[index])
+			continue
+		}
+		if inTable[i] {
+			continue
+		}
+		texts := make([]string, 0, len(line.cells))
+		for _, cell := range line.cells {
+			texts = append(texts, cell.text)
+		}
+		b.WriteString(strings.Join(texts, " "))
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String()), tables
+}
+
+// layoutLines groups runs with the same baseline into lines, top to bottom
+func layoutLines(runs []textRun) []layoutLine {
+	sorted := make([]textRun, 0, len(runs))
+	for _, run := range runs {
+		run.text = strings.TrimSpace(run.text)
+		if run.text != "" {
+			sorted = append(sorted, run)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].page != sorted[j].page {
+			return sorted[i].page < sorted[j].page
+		}
+		return sorted[i].y > sorted[j].y
+	})
+
+	var lines []layoutLine
+	for _, run := range sorted {
+		if n := len(lines); n > 0 && lines[n-1].page == run.page && lines[n-1].y-run.y < lineTolerance {
+			lines[n-1].cells = append(lines[n-1].cells, run)
+			continue
+		}
+		lines = append(lines, layoutLine{page: run.page, y: run.y, cells: []textRun{run}})
+	}
+	for _, line := range lines {
+		sort.SliceStable(line.cells, func(i, j int) bool {
+			return line.cells[i].x < line.cells[j].x
+		})
+	}
+	return lines
+}
+
+// findTables finds the tables among lines. A table starts at a line of two
+// or more cells and continues while lines have at least two cells, most of
+// them in its columns. A line with a single cell in a column continues the
+// cell above it, as wrapped text does; in the first column only when it is
+// closer to the row above than the rows are to each other.
+func findTables(lines []layoutLine) []layoutTable {
+	var (
+		tables  []layoutTable
+		current *layoutTable
+	)
+	finish := func() {
+		if current != nil && len(current.rows) >= minTableRows && len(current.anchors) >= 2 {
+			tables = append(tables, *current)
+		}
+		current = nil
+	}
+
+	for i, line := range lines {
+		if current != nil && line.page != current.page {
+			finish()
+		}
+		if current != nil {
+			aligned := current.aligned(line.cells)
+			if len(line.cells) >= 2 && aligned >= 2 && 2*aligned >= len(line.cells) {
+				current.addRow(i, line)
+				continue
+			}
+			if len(line.cells) == 1 && current.continues(line) {
+				current.extendCell(i, line)
+				continue
+			}
+			finish()
+		}
+		if len(line.cells) >= 2 {
+			current = &layoutTable{page: line.page, first: i, rowGap: math.Inf(1)}
+			current.addRow(i, line)
+		}
+	}
+	finish()
+	return tables
+}
+
+// column returns the column whose left edge is at x, or -1
+func (t *layoutTable) column(x float64) int {
+	for i, anchor := range t.anchors {
+		if math.Abs(anchor-x) < columnTolerance {
+			return i
+		}
+	}
+	return -1
+}
+
+// aligned counts the cells that start at a column edge of the table
+func (t *layoutTable) aligned(cells []textRun) int {
+	count := 0
+	for _, cell := range cells {
+		if t.column(cell.x) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (t *layoutTable) addRow(index int, line layoutLine) {
+	for _, cell := range line.cells {
+		if t.column(cell.x) < 0 {
+			t.anchors = append(t.anchors, cell.x)
+		}
+	}
+	if len(t.rows) > 0 {
+		t.rowGap = math.Min(t.rowGap, t.lastY-line.y)
+	}
+	t.rows = append(t.rows, line.cells)
+	t.last = index
+	t.lastY = line.y
+}
+
+// continues reports whether a single-cell line is wrapped text of the last row
+func (t *layoutTable) continues(line layoutLine) bool {
+	column := t.column(line.cells[0].x)
+	if column < 0 {
+		return false
+	}
+	leftmost := true
+	for _, anchor := range t.anchors {
+		if anchor < t.anchors[column]-columnTolerance {
+			leftmost = false
+		}
+	}
+	return !leftmost || t.lastY-line.y < t.rowGap
+}
+
+// extendCell appends wrapped text to the cell of its column in the last row
+func (t *layoutTable) extendCell(index int, line layoutLine) {
+	cell := line.cells[0]
+	row := t.rows[len(t.rows)-1]
+	extended := false
+	for i := range row {
+		if math.Abs(row[i].x-cell.x) < columnTolerance {
+			row[i].text += " " + cell.text
+			extended = true
+			break
+		}
+	}
+	if !extended {
+		row = append(row, cell)
+	}
+	t.rows[len(t.rows)-1] = row
+	t.last = index
+}
+
+// tableInfo lays the cells out in the columns of the table, left to right;
+// the first row is taken as the header
+func (t *layoutTable) tableInfo(index int) TableInfo {
+	anchors := append([]float64(nil), t.anchors...)
+	sort.Float64s(anchors)
+
+	grid := make([][]string, 0, len(t.rows))
+	for _, cells := range t.rows {
+		row := make([]string, len(anchors))
+		for _, cell := range cells {
+			column := nearestColumn(anchors, cell.x)
+			if row[column] != "" {
+				row[column] += " "
+			}
+			row[column] += cell.text
+		}
+		grid = append(grid, row)
+	}
+
+	return TableInfo{
+		Index:     index,
+		Page:      t.page,
+		Rows:      len(grid),
+		Columns:   len(anchors),
+		HeaderRow: grid[0],
+		DataRows:  grid[1:],
+	}
+}
+
+func nearestColumn(anchors []float64, x float64) int {
+	nearest := 0
+	for i, anchor := range anchors {
+		if math.Abs(anchor-x) < math.Abs(anchors[nearest]-x) {
+			nearest = i
+		}
+	}
+	return nearest
+}
+
+// writeTableBlock renders a table between markers, a line per row
+func writeTableBlock(b *strings.Builder, table TableInfo) {
+	fmt.Fprintf(b, "[Table %d, page %d]\n", table.Index, table.Page)
+	b.WriteString(strings.Join(table.HeaderRow, " | "))
+	b.WriteString("\n")
+	for _, row := range table.DataRows {
+		b.WriteString(strings.Join(row, " | "))
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "[End of table %d]\n", table.Index)
+}
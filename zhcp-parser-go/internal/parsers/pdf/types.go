@@ -14,7 +14,29 @@ type PDFExtractionResult struct {
 	Metadata  map[string]interface{} `json:"metadata"`
 	PageCount int                    `json:"page_count"`
 	HasTables bool                   `json:"has_tables"`
+	Tables    []TableInfo            `json:"tables"`
 	Structure []StructureInfo        `json:"structure"`
+	ImageOnly bool                   `json:"image_only"`
+	OCR       *OCRInfo               `json:"ocr,omitempty"`
+}
+
+// TableInfo represents a table rebuilt from the positions of the text on a
+// page
+type TableInfo struct {
+	Index     int        `json:"index"`
+	Page      int        `json:"page"`
+	Rows      int        `json:"rows"`
+	Columns   int        `json:"columns"`
+	HeaderRow []string   `json:"header_row"`
+	DataRows  [][]string `json:"data_rows"`
+}
+
+// OCRInfo describes the OCR pass run on an image-only PDF
+type OCRInfo struct {
+	Engine     string   `json:"engine"`
+	Languages  []string `json:"languages"`
+	Confidence float64  `json:"confidence"`
+	Pages      int      `json:"pages"`
 }
 
 // ValidationResult represents the result of PDF validation
@@ -0,0 +1,51 @@
+package parsers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPlainTextSize is the largest plain text or Markdown input accepted (5MB)
+const MaxPlainTextSize = 5 * 1024 * 1024
+
+// ReadPlainText reads a .txt or .md file. Such input needs no extraction,
+// only a size and encoding check.
+func ReadPlainText(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read text file: %w", err)
+	}
+	if info.Size() > MaxPlainTextSize {
+		return "", fmt.Errorf("text file size exceeds 5MB limit")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read text file: %w", err)
+	}
+
+	return NormalizePlainText(string(content))
+}
+
+// NormalizePlainText checks that text is non-empty UTF-8, drops a byte order
+// mark and normalizes line endings
+func NormalizePlainText(text string) (string, error) {
+	if len(text) > MaxPlainTextSize {
+		return "", fmt.Errorf("text exceeds 5MB limit")
+	}
+	if !utf8.ValidString(text) {
+		return "", fmt.Errorf("text is not valid UTF-8")
+	}
+
+	text = strings.TrimPrefix(text, "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("text is empty")
+	}
+
+	return text, nil
+}
@@ -0,0 +1,373 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// requiredFiles are the archive entries every PPTX presentation must contain
+var requiredFiles = []string{"[Content_Types].xml", "ppt/presentation.xml"}
+
+// PPTXExtractor handles PPTX extraction of slide text, notes and tables
+type PPTXExtractor struct {
+	logger interface{} // In a real implementation, we'd use a proper logger interface
+}
+
+// NewPPTXExtractor creates a new PPTX extractor
+func NewPPTXExtractor(logger interface{}) *PPTXExtractor {
+	return &PPTXExtractor{
+		logger: logger,
+	}
+}
+
+type xmlRelationships struct {
+	Relationships []xmlRelationship `xml:"Relationship"`
+}
+
+type xmlRelationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type xmlPresentation struct {
+	Slides []struct {
+		RelID string `xml:"id,attr"`
+	} `xml:"sldIdLst>sldId"`
+}
+
+// slideContent is the text found on a single slide or notes page
+type slideContent struct {
+	title      []string
+	paragraphs []string
+	tables     [][][]string
+}
+
+// ExtractContent extracts the slides in presentation order with their
+// titles, body text, speaker notes and tables, and a plain-text rendering of
+// the whole deck for the LLM stage
+func (e *PPTXExtractor) ExtractContent(pptxPath string) (*PPTXExtractionResult, error) {
+	result := &PPTXExtractionResult{
+		Slides:   []SlideInfo{},
+		Tables:   []TableInfo{},
+		Metadata: make(map[string]interface{}),
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(pptxPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("PPTX file does not exist: %s", pptxPath)
+	}
+
+	zipFile, err := zip.OpenReader(pptxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PPTX file: %w", err)
+	}
+	defer zipFile.Close()
+	zipReader := &zipFile.Reader
+
+	for _, requiredFile := range requiredFiles {
+		if findZipFile(zipReader, requiredFile) == nil {
+			return nil, fmt.Errorf("not a valid PPTX structure: missing %s", requiredFile)
+		}
+	}
+
+	var presentation xmlPresentation
+	if err := readZipXML(zipReader, "ppt/presentation.xml", &presentation); err != nil {
+		return nil, fmt.Errorf("failed to read presentation: %w", err)
+	}
+
+	rels, err := readRelationships(zipReader, "ppt/presentation.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presentation relationships: %w", err)
+	}
+
+	var text strings.Builder
+	for _, slideRef := range presentation.Slides {
+		rel, ok := rels[slideRef.RelID]
+		if !ok {
+			continue
+		}
+
+		slide, err := readPart(zipReader, rel.Target, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slide %s: %w", rel.Target, err)
+		}
+
+		info := SlideInfo{
+			Index:      len(result.Slides) + 1,
+			Title:      strings.Join(slide.title, " "),
+			Paragraphs: slide.paragraphs,
+			Tables:     []int{},
+		}
+		if info.Paragraphs == nil {
+			info.Paragraphs = []string{}
+		}
+
+		notes, err := readNotes(zipReader, rel.Target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notes of slide %d: %w", info.Index, err)
+		}
+		info.Notes = notes
+
+		for _, rows := range slide.tables {
+			table := newTableInfo(len(result.Tables), info.Index, rows)
+			info.Tables = append(info.Tables, table.Index)
+			result.Tables = append(result.Tables, table)
+		}
+		result.Slides = append(result.Slides, info)
+
+		writeSlideText(&text, info, slide.tables)
+	}
+
+	result.Text = strings.TrimSpace(text.String())
+	result.Metadata["slide_count"] = len(result.Slides)
+	result.Metadata["table_count"] = len(result.Tables)
+
+	return result, nil
+}
+
+// readNotes returns the speaker notes of a slide, if it has any
+func readNotes(zipReader *zip.Reader, slidePath string) (string, error) {
+	rels, err := readRelationships(zipReader, slidePath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels {
+		if !strings.HasSuffix(rel.Type, "/notesSlide") {
+			continue
+		}
+		notes, err := readPart(zipReader, rel.Target, true)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(notes.paragraphs, "\n"), nil
+	}
+	return "", nil
+}
+
+// readPart walks the shapes of a slide or notes page. Title placeholders go
+// to title, tables are collected cell by cell, everything else becomes a
+// paragraph. On notes pages only the notes body is kept, so the copied
+// slide image and the slide number do not leak into the notes.
+func readPart(zipReader *zip.Reader, partPath string, notesPage bool) (slideContent, error) {
+	var content slideContent
+
+	data, err := readZipFile(zipReader, partPath)
+	if err != nil {
+		return content, err
+	}
+
+	var (
+		decoder   = xml.NewDecoder(bytes.NewReader(data))
+		paragraph strings.Builder
+		inText    bool
+
+		inShape    bool
+		isTitle    bool
+		skipShape  bool
+		tableDepth int
+
+		table [][]string
+		row   []string
+		cell  []string
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return content, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				inShape, isTitle, skipShape = true, false, notesPage
+			case "ph":
+				phType := attrValue(t, "type")
+				switch {
+				case phType == "title" || phType == "ctrTitle":
+					isTitle = true
+				case notesPage:
+					skipShape = phType != "body"
+				case phType == "sldNum" || phType == "dt" || phType == "ftr":
+					skipShape = true
+				}
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					table = [][]string{}
+				}
+			case "tr":
+				row = []string{}
+			case "tc":
+				cell = []string{}
+			case "p":
+				paragraph.Reset()
+			case "t":
+				inText = true
+			case "br":
+				paragraph.WriteString(" ")
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				value := strings.Join(strings.Fields(paragraph.String()), " ")
+				switch {
+				case value == "":
+				case tableDepth > 0:
+					cell = append(cell, value)
+				case !inShape || skipShape:
+				case isTitle:
+					content.title = append(content.title, value)
+				default:
+					content.paragraphs = append(content.paragraphs, value)
+				}
+			case "tc":
+				row = append(row, strings.Join(cell, " "))
+			case "tr":
+				table = append(table, row)
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 && len(table) > 0 && !notesPage {
+					content.tables = append(content.tables, table)
+				}
+			case "sp":
+				inShape, isTitle, skipShape = false, false, false
+			}
+		}
+	}
+
+	return content, nil
+}
+
+// newTableInfo treats the first row of a slide table as its header
+func newTableInfo(index, slide int, rows [][]string) TableInfo {
+	table := TableInfo{
+		Index:     index,
+		Slide:     slide,
+		Rows:      len(rows),
+		HeaderRow: []string{},
+		DataRows:  [][]string{},
+	}
+	for _, row := range rows {
+		if len(row) > table.Columns {
+			table.Columns = len(row)
+		}
+	}
+	if len(rows) > 0 {
+		table.HeaderRow = rows[0]
+		table.DataRows = append(table.DataRows, rows[1:]...)
+	}
+	return table
+}
+
+// writeSlideText renders a slide as text: title, body, tables, then notes
+func writeSlideText(b *strings.Builder, slide SlideInfo, tables [][][]string) {
+	fmt.Fprintf(b, "Slide %d", slide.Index)
+	if slide.Title != "" {
+		fmt.Fprintf(b, ": %s", slide.Title)
+	}
+	b.WriteString("\n")
+
+	for _, paragraph := range slide.Paragraphs {
+		b.WriteString(paragraph)
+		b.WriteString("\n")
+	}
+	for _, rows := range tables {
+		for _, row := range rows {
+			b.WriteString(strings.Join(row, " | "))
+			b.WriteString("\n")
+		}
+	}
+	if slide.Notes != "" {
+		fmt.Fprintf(b, "Notes: %s\n", strings.ReplaceAll(slide.Notes, "\n", " "))
+	}
+	b.WriteString("\n")
+}
+
+// attrValue returns the value of an attribute by local name
+func attrValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// readRelationships returns the relationships of an archive part with
+// targets resolved to archive paths, keyed by relationship id
+func readRelationships(zipReader *zip.Reader, partPath string) (map[string]xmlRelationship, error) {
+	relsPath := path.Join(path.Dir(partPath), "_rels", path.Base(partPath)+".rels")
+	result := make(map[string]xmlRelationship)
+	if findZipFile(zipReader, relsPath) == nil {
+		return result, nil
+	}
+
+	var rels xmlRelationships
+	if err := readZipXML(zipReader, relsPath, &rels); err != nil {
+		return nil, err
+	}
+
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			rel.Target = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			rel.Target = path.Join(path.Dir(partPath), rel.Target)
+		}
+		result[rel.ID] = rel
+	}
+	return result, nil
+}
+
+// findZipFile looks up an archive entry by name
+func findZipFile(zipReader *zip.Reader, name string) *zip.File {
+	for _, file := range zipReader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// readZipFile returns the content of an archive entry
+func readZipFile(zipReader *zip.Reader, name string) ([]byte, error) {
+	file := findZipFile(zipReader, name)
+	if file == nil {
+		return nil, fmt.Errorf("%s not found in PPTX archive", name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// readZipXML decodes an XML archive entry into v
+func readZipXML(zipReader *zip.Reader, name string, v interface{}) error {
+	content, err := readZipFile(zipReader, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(content, v)
+}
@@ -0,0 +1,76 @@
+package pptx
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PPTXValidator validates PPTX files before processing
+type PPTXValidator struct{}
+
+// NewPPTXValidator creates a new PPTX validator
+func NewPPTXValidator() *PPTXValidator {
+	return &PPTXValidator{}
+}
+
+// ValidatePPTX validates PPTX file before processing
+func (v *PPTXValidator) ValidatePPTX(pptxPath string) (*ValidationResult, error) {
+	validationResult := &ValidationResult{
+		IsValid:   false,
+		FileSize:  0,
+		Errors:    []string{},
+		IsZipFile: false,
+	}
+
+	// Check file size
+	fileInfo, err := os.Stat(pptxPath)
+	if os.IsNotExist(err) {
+		validationResult.Errors = append(validationResult.Errors, "File does not exist")
+		return validationResult, nil
+	}
+	if err != nil {
+		validationResult.Errors = append(validationResult.Errors, fmt.Sprintf("Error getting file info: %v", err))
+		return validationResult, nil
+	}
+
+	fileSize := fileInfo.Size()
+	validationResult.FileSize = fileSize
+
+	// Check file size limit (100MB, decks often embed images)
+	if fileSize > 100*1024*1024 { // 100MB
+		validationResult.Errors = append(validationResult.Errors, "File size exceeds 100MB limit")
+	}
+
+	// Check file extension
+	if !strings.EqualFold(filepath.Ext(pptxPath), ".pptx") {
+		validationResult.Errors = append(validationResult.Errors, "File is not a PPTX")
+	}
+
+	// Check if it's a valid zip file (PPTX is a zip archive)
+	file, err := os.Open(pptxPath)
+	if err != nil {
+		validationResult.Errors = append(validationResult.Errors, fmt.Sprintf("Error opening file: %v", err))
+		return validationResult, nil
+	}
+	defer file.Close()
+
+	zipReader, err := zip.NewReader(file, fileSize)
+	if err != nil {
+		validationResult.Errors = append(validationResult.Errors, "File is not a valid zip archive")
+		return validationResult, nil
+	}
+	validationResult.IsZipFile = true
+
+	// Check if it contains required PPTX files
+	for _, requiredFile := range requiredFiles {
+		if findZipFile(zipReader, requiredFile) == nil {
+			validationResult.Errors = append(validationResult.Errors, fmt.Sprintf("Missing required file: %s", requiredFile))
+		}
+	}
+
+	validationResult.IsValid = len(validationResult.Errors) == 0
+	return validationResult, nil
+}
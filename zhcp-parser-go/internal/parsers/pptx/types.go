@@ -0,0 +1,36 @@
+package pptx
+
+// SlideInfo represents a slide of a PPTX presentation
+type SlideInfo struct {
+	Index      int      `json:"index"`
+	Title      string   `json:"title"`
+	Paragraphs []string `json:"paragraphs"`
+	Notes      string   `json:"notes"`
+	Tables     []int    `json:"tables"` // indexes into PPTXExtractionResult.Tables
+}
+
+// TableInfo represents a table placed on a slide
+type TableInfo struct {
+	Index     int        `json:"index"`
+	Slide     int        `json:"slide"`
+	Rows      int        `json:"rows"`
+	Columns   int        `json:"columns"`
+	HeaderRow []string   `json:"header_row"`
+	DataRows  [][]string `json:"data_rows"`
+}
+
+// PPTXExtractionResult represents the result of PPTX extraction
+type PPTXExtractionResult struct {
+	Text     string                 `json:"text"`
+	Slides   []SlideInfo            `json:"slides"`
+	Tables   []TableInfo            `json:"tables"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// ValidationResult represents the result of PPTX validation
+type ValidationResult struct {
+	IsValid   bool     `json:"is_valid"`
+	FileSize  int64    `json:"file_size"`
+	Errors    []string `json:"errors"`
+	IsZipFile bool     `json:"is_zip_file"`
+}
@@ -0,0 +1,38 @@
+package xlsx
+
+// SheetInfo represents a worksheet of an XLSX workbook
+type SheetInfo struct {
+	Index        int        `json:"index"`
+	Name         string     `json:"name"`
+	Hidden       bool       `json:"hidden"`
+	Rows         [][]string `json:"rows"`
+	RowCount     int        `json:"row_count"`
+	ColumnCount  int        `json:"column_count"`
+	MergedRanges []string   `json:"merged_ranges"`
+}
+
+// TableInfo represents a table (ListObject) defined on a worksheet
+type TableInfo struct {
+	Index     int        `json:"index"`
+	Sheet     string     `json:"sheet"`
+	Name      string     `json:"name"`
+	Ref       string     `json:"ref"`
+	HeaderRow []string   `json:"header_row"`
+	DataRows  [][]string `json:"data_rows"`
+}
+
+// XLSXExtractionResult represents the result of XLSX extraction
+type XLSXExtractionResult struct {
+	Text     string                 `json:"text"`
+	Sheets   []SheetInfo            `json:"sheets"`
+	Tables   []TableInfo            `json:"tables"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// ValidationResult represents the result of XLSX validation
+type ValidationResult struct {
+	IsValid   bool     `json:"is_valid"`
+	FileSize  int64    `json:"file_size"`
+	Errors    []string `json:"errors"`
+	IsZipFile bool     `json:"is_zip_file"`
+}
@@ -0,0 +1,575 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sheets larger than this are truncated; a stray value far from the data
+// would otherwise blow up the grid
+const (
+	maxSheetRows    = 10000
+	maxSheetColumns = 256
+)
+
+// requiredFiles are the archive entries every XLSX workbook must contain
+var requiredFiles = []string{"[Content_Types].xml", "xl/workbook.xml"}
+
+// XLSXExtractor handles XLSX extraction of sheets, merged cells and tables
+type XLSXExtractor struct {
+	logger interface{} // In a real implementation, we'd use a proper logger interface
+}
+
+// NewXLSXExtractor creates a new XLSX extractor
+func NewXLSXExtractor(logger interface{}) *XLSXExtractor {
+	return &XLSXExtractor{
+		logger: logger,
+	}
+}
+
+// Workbook XML structures (only the parts we need)
+
+type xmlRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xmlWorkbook struct {
+	WorkbookPr struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		State string `xml:"state,attr"`
+		RelID string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xmlRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt xmlRichText) text() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var b strings.Builder
+	for _, run := range rt.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xmlSharedStrings struct {
+	Items []xmlRichText `xml:"si"`
+}
+
+type xmlStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xmlWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string      `xml:"r,attr"`
+			Type   string      `xml:"t,attr"`
+			Style  int         `xml:"s,attr"`
+			Value  string      `xml:"v"`
+			Inline xmlRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	MergeCells []struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"mergeCells>mergeCell"`
+}
+
+type xmlTable struct {
+	Name        string `xml:"name,attr"`
+	DisplayName string `xml:"displayName,attr"`
+	Ref         string `xml:"ref,attr"`
+	HeaderCount *int   `xml:"headerRowCount,attr"`
+}
+
+// workbookReader resolves values shared across the sheets of a workbook
+type workbookReader struct {
+	zip           *zip.Reader
+	sharedStrings []string
+	dateStyles    map[int]bool
+	date1904      bool
+}
+
+// ExtractContent extracts every sheet of the workbook with merged cells
+// filled in, the tables defined on them, and a plain-text rendering of the
+// visible sheets for the LLM stage
+func (e *XLSXExtractor) ExtractContent(xlsxPath string) (*XLSXExtractionResult, error) {
+	result := &XLSXExtractionResult{
+		Sheets:   []SheetInfo{},
+		Tables:   []TableInfo{},
+		Metadata: make(map[string]interface{}),
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(xlsxPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("XLSX file does not exist: %s", xlsxPath)
+	}
+
+	zipFile, err := zip.OpenReader(xlsxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX file: %w", err)
+	}
+	defer zipFile.Close()
+
+	for _, requiredFile := range requiredFiles {
+		if findZipFile(&zipFile.Reader, requiredFile) == nil {
+			return nil, fmt.Errorf("not a valid XLSX structure: missing %s", requiredFile)
+		}
+	}
+
+	var workbook xmlWorkbook
+	if err := readZipXML(&zipFile.Reader, "xl/workbook.xml", &workbook); err != nil {
+		return nil, fmt.Errorf("failed to read workbook: %w", err)
+	}
+
+	reader := &workbookReader{
+		zip:        &zipFile.Reader,
+		dateStyles: make(map[int]bool),
+		date1904:   workbook.WorkbookPr.Date1904,
+	}
+	if err := reader.loadSharedStrings(); err != nil {
+		return nil, fmt.Errorf("failed to read shared strings: %w", err)
+	}
+	if err := reader.loadStyles(); err != nil {
+		return nil, fmt.Errorf("failed to read styles: %w", err)
+	}
+
+	rels, err := readRelationships(&zipFile.Reader, "xl/workbook.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workbook relationships: %w", err)
+	}
+
+	var text strings.Builder
+	for i, sheet := range workbook.Sheets {
+		target, ok := rels[sheet.RelID]
+		if !ok {
+			continue
+		}
+
+		info, tables, err := reader.readSheet(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %q: %w", sheet.Name, err)
+		}
+		info.Index = i
+		info.Name = sheet.Name
+		info.Hidden = sheet.State == "hidden" || sheet.State == "veryHidden"
+		result.Sheets = append(result.Sheets, info)
+
+		for _, table := range tables {
+			table.Index = len(result.Tables)
+			table.Sheet = sheet.Name
+			result.Tables = append(result.Tables, table)
+		}
+
+		if !info.Hidden {
+			writeSheetText(&text, info)
+		}
+	}
+
+	result.Text = strings.TrimSpace(text.String())
+	result.Metadata["sheet_count"] = len(result.Sheets)
+	result.Metadata["table_count"] = len(result.Tables)
+
+	return result, nil
+}
+
+// loadSharedStrings reads the shared string table, which is optional
+func (wr *workbookReader) loadSharedStrings() error {
+	if findZipFile(wr.zip, "xl/sharedStrings.xml") == nil {
+		return nil
+	}
+
+	var sst xmlSharedStrings
+	if err := readZipXML(wr.zip, "xl/sharedStrings.xml", &sst); err != nil {
+		return err
+	}
+
+	wr.sharedStrings = make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		wr.sharedStrings[i] = item.text()
+	}
+	return nil
+}
+
+// loadStyles records which cell styles format numbers as dates, so that
+// deadlines stored as serial numbers come out as dates
+func (wr *workbookReader) loadStyles() error {
+	if findZipFile(wr.zip, "xl/styles.xml") == nil {
+		return nil
+	}
+
+	var styles xmlStyles
+	if err := readZipXML(wr.zip, "xl/styles.xml", &styles); err != nil {
+		return err
+	}
+
+	customDates := make(map[int]bool)
+	for _, numFmt := range styles.NumFmts {
+		customDates[numFmt.ID] = isDateFormatCode(numFmt.Code)
+	}
+
+	for i, xf := range styles.CellXfs {
+		if isBuiltinDateFormat(xf.NumFmtID) || customDates[xf.NumFmtID] {
+			wr.dateStyles[i] = true
+		}
+	}
+	return nil
+}
+
+// readSheet reads a worksheet into a rectangular grid, copies the value of
+// each merged range into all of its cells and reads the sheet's tables
+func (wr *workbookReader) readSheet(sheetPath string) (SheetInfo, []TableInfo, error) {
+	info := SheetInfo{Rows: [][]string{}, MergedRanges: []string{}}
+
+	var worksheet xmlWorksheet
+	if err := readZipXML(wr.zip, sheetPath, &worksheet); err != nil {
+		return info, nil, err
+	}
+
+	cells := make(map[[2]int]string)
+	maxRow, maxCol := -1, -1
+	for rowIndex, row := range worksheet.Rows {
+		col := 0
+		for _, cell := range row.Cells {
+			r, c := rowIndex, col
+			if cell.Ref != "" {
+				if parsedRow, parsedCol, ok := parseCellRef(cell.Ref); ok {
+					r, c = parsedRow, parsedCol
+				}
+			}
+			col = c + 1
+			if r >= maxSheetRows || c >= maxSheetColumns {
+				continue
+			}
+
+			value := strings.TrimSpace(wr.cellValue(cell.Type, cell.Style, cell.Value, cell.Inline))
+			if value == "" {
+				continue
+			}
+			cells[[2]int{r, c}] = value
+			if r > maxRow {
+				maxRow = r
+			}
+			if c > maxCol {
+				maxCol = c
+			}
+		}
+	}
+
+	for _, merge := range worksheet.MergeCells {
+		top, left, bottom, right, ok := parseRangeRef(merge.Ref)
+		if !ok {
+			continue
+		}
+		info.MergedRanges = append(info.MergedRanges, merge.Ref)
+		bottom = min(bottom, maxSheetRows-1)
+		right = min(right, maxSheetColumns-1)
+
+		value, ok := cells[[2]int{top, left}]
+		if !ok {
+			continue
+		}
+		for r := top; r <= bottom; r++ {
+			for c := left; c <= right; c++ {
+				cells[[2]int{r, c}] = value
+			}
+		}
+		if bottom > maxRow {
+			maxRow = bottom
+		}
+		if right > maxCol {
+			maxCol = right
+		}
+	}
+
+	info.RowCount = maxRow + 1
+	info.ColumnCount = maxCol + 1
+	info.Rows = make([][]string, info.RowCount)
+	for r := range info.Rows {
+		info.Rows[r] = make([]string, info.ColumnCount)
+		for c := range info.Rows[r] {
+			info.Rows[r][c] = cells[[2]int{r, c}]
+		}
+	}
+
+	tables, err := wr.readTables(sheetPath, info.Rows)
+	if err != nil {
+		return info, nil, err
+	}
+
+	return info, tables, nil
+}
+
+// readTables reads the table definitions linked from a worksheet and cuts
+// their cells out of the sheet grid
+func (wr *workbookReader) readTables(sheetPath string, rows [][]string) ([]TableInfo, error) {
+	rels, err := readRelationships(wr.zip, sheetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []TableInfo{}
+	for _, target := range rels {
+		if !strings.Contains(target, "/tables/") {
+			continue
+		}
+
+		var table xmlTable
+		if err := readZipXML(wr.zip, target, &table); err != nil {
+			return nil, err
+		}
+		top, left, bottom, right, ok := parseRangeRef(table.Ref)
+		if !ok {
+			continue
+		}
+
+		info := TableInfo{
+			Name:      table.DisplayName,
+			Ref:       table.Ref,
+			HeaderRow: []string{},
+			DataRows:  [][]string{},
+		}
+		if info.Name == "" {
+			info.Name = table.Name
+		}
+
+		hasHeader := table.HeaderCount == nil || *table.HeaderCount > 0
+		for r := top; r <= bottom && r < len(rows); r++ {
+			row := make([]string, 0, right-left+1)
+			for c := left; c <= right; c++ {
+				value := ""
+				if c < len(rows[r]) {
+					value = rows[r][c]
+				}
+				row = append(row, value)
+			}
+			if hasHeader && r == top {
+				info.HeaderRow = row
+				continue
+			}
+			info.DataRows = append(info.DataRows, row)
+		}
+		tables = append(tables, info)
+	}
+
+	return tables, nil
+}
+
+// cellValue converts a raw cell value to text according to its type and style
+func (wr *workbookReader) cellValue(cellType string, style int, raw string, inline xmlRichText) string {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || index < 0 || index >= len(wr.sharedStrings) {
+			return ""
+		}
+		return wr.sharedStrings[index]
+	case "inlineStr":
+		return inline.text()
+	case "b":
+		if strings.TrimSpace(raw) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return raw
+	}
+
+	// Numbers (no type or "n"): dates are stored as serial numbers
+	if wr.dateStyles[style] {
+		if serial, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+			return serialToDate(serial, wr.date1904)
+		}
+	}
+	return raw
+}
+
+// writeSheetText renders the non-empty rows of a sheet as pipe-separated lines
+func writeSheetText(b *strings.Builder, sheet SheetInfo) {
+	fmt.Fprintf(b, "Sheet: %s\n", sheet.Name)
+	for _, row := range sheet.Rows {
+		values := make([]string, 0, len(row))
+		empty := true
+		for _, value := range row {
+			if value != "" {
+				empty = false
+			}
+			values = append(values, value)
+		}
+		if empty {
+			continue
+		}
+
+		// Trailing empty cells only add separators
+		for len(values) > 0 && values[len(values)-1] == "" {
+			values = values[:len(values)-1]
+		}
+		b.WriteString(strings.Join(values, " | "))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+}
+
+// readRelationships returns the relationship targets of an archive part,
+// resolved to archive paths and keyed by relationship id
+func readRelationships(zipReader *zip.Reader, partPath string) (map[string]string, error) {
+	relsPath := path.Join(path.Dir(partPath), "_rels", path.Base(partPath)+".rels")
+	targets := make(map[string]string)
+	if findZipFile(zipReader, relsPath) == nil {
+		return targets, nil
+	}
+
+	var rels xmlRelationships
+	if err := readZipXML(zipReader, relsPath, &rels); err != nil {
+		return nil, err
+	}
+
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(path.Dir(partPath), target)
+		}
+		targets[rel.ID] = target
+	}
+	return targets, nil
+}
+
+// findZipFile looks up an archive entry by name
+func findZipFile(zipReader *zip.Reader, name string) *zip.File {
+	for _, file := range zipReader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// readZipXML decodes an XML archive entry into v
+func readZipXML(zipReader *zip.Reader, name string, v interface{}) error {
+	file := findZipFile(zipReader, name)
+	if file == nil {
+		return fmt.Errorf("%s not found in XLSX archive", name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal(content, v)
+}
+
+// parseCellRef converts a reference like "B3" to zero-based row and column
+func parseCellRef(ref string) (int, int, bool) {
+	ref = strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(ref)), "$", "")
+
+	col, i := 0, 0
+	for i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z' {
+		col = col*26 + int(ref[i]-'A'+1)
+		i++
+	}
+	if i == 0 || i == len(ref) {
+		return 0, 0, false
+	}
+
+	row, err := strconv.Atoi(ref[i:])
+	if err != nil || row < 1 {
+		return 0, 0, false
+	}
+	return row - 1, col - 1, true
+}
+
+// parseRangeRef converts a range like "A1:C4" to zero-based bounds
+func parseRangeRef(ref string) (top, left, bottom, right int, ok bool) {
+	start, end, found := strings.Cut(ref, ":")
+	if !found {
+		end = start
+	}
+
+	top, left, ok = parseCellRef(start)
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	bottom, right, ok = parseCellRef(end)
+	if !ok || bottom < top || right < left {
+		return 0, 0, 0, 0, false
+	}
+	return top, left, bottom, right, true
+}
+
+// isBuiltinDateFormat reports whether a built-in number format is a date
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 45 && id <= 47)
+}
+
+// isDateFormatCode reports whether a custom number format displays a date,
+// ignoring quoted literals and bracketed sections such as colors
+func isDateFormatCode(code string) bool {
+	inQuotes, inBrackets := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case inBrackets:
+		case r == 'd' || r == 'm' || r == 'y':
+			return true
+		}
+	}
+	return false
+}
+
+// serialToDate converts an Excel serial date to YYYY-MM-DD
+func serialToDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	days := math.Floor(serial)
+	date := epoch.AddDate(0, 0, int(days))
+	if seconds := math.Round((serial - days) * 24 * 60 * 60); seconds > 0 {
+		return date.Add(time.Duration(seconds) * time.Second).Format("2006-01-02 15:04")
+	}
+	return date.Format("2006-01-02")
+}
@@ -0,0 +1,76 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// XLSXValidator validates XLSX files before processing
+type XLSXValidator struct{}
+
+// NewXLSXValidator creates a new XLSX validator
+func NewXLSXValidator() *XLSXValidator {
+	return &XLSXValidator{}
+}
+
+// ValidateXLSX validates XLSX file before processing
+func (v *XLSXValidator) ValidateXLSX(xlsxPath string) (*ValidationResult, error) {
+	validationResult := &ValidationResult{
+		IsValid:   false,
+		FileSize:  0,
+		Errors:    []string{},
+		IsZipFile: false,
+	}
+
+	// Check file size
+	fileInfo, err := os.Stat(xlsxPath)
+	if os.IsNotExist(err) {
+		validationResult.Errors = append(validationResult.Errors, "File does not exist")
+		return validationResult, nil
+	}
+	if err != nil {
+		validationResult.Errors = append(validationResult.Errors, fmt.Sprintf("Error getting file info: %v", err))
+		return validationResult, nil
+	}
+
+	fileSize := fileInfo.Size()
+	validationResult.FileSize = fileSize
+
+	// Check file size limit (50MB)
+	if fileSize > 50*1024*1024 { // 50MB
+		validationResult.Errors = append(validationResult.Errors, "File size exceeds 50MB limit")
+	}
+
+	// Check file extension
+	if !strings.EqualFold(filepath.Ext(xlsxPath), ".xlsx") {
+		validationResult.Errors = append(validationResult.Errors, "File is not an XLSX")
+	}
+
+	// Check if it's a valid zip file (XLSX is a zip archive)
+	file, err := os.Open(xlsxPath)
+	if err != nil {
+		validationResult.Errors = append(validationResult.Errors, fmt.Sprintf("Error opening file: %v", err))
+		return validationResult, nil
+	}
+	defer file.Close()
+
+	zipReader, err := zip.NewReader(file, fileSize)
+	if err != nil {
+		validationResult.Errors = append(validationResult.Errors, "File is not a valid zip archive")
+		return validationResult, nil
+	}
+	validationResult.IsZipFile = true
+
+	// Check if it contains required XLSX files
+	for _, requiredFile := range requiredFiles {
+		if findZipFile(zipReader, requiredFile) == nil {
+			validationResult.Errors = append(validationResult.Errors, fmt.Sprintf("Missing required file: %s", requiredFile))
+		}
+	}
+
+	validationResult.IsValid = len(validationResult.Errors) == 0
+	return validationResult, nil
+}
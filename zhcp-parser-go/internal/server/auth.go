@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zhcp-parser-go/internal/common"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyHeader carries the key; "Authorization: Bearer <key>" works as well
+const apiKeyHeader = "X-API-Key"
+
+// authenticator checks API keys against the configured clients. Keys are
+// looked up by their SHA-256 digest, so lookups don't leak key prefixes
+// through timing.
+type authenticator struct {
+	keys map[[sha256.Size]byte]*apiClient
+}
+
+// apiClient is a configured API key with its scopes and rate limits
+type apiClient struct {
+	name    string
+	scopes  map[string]bool
+	limiter *rateLimiter // nil when unlimited
+
+	// Job limits replacing the server defaults, when set
+	uploadsPerMinute  int
+	maxConcurrentJobs int
+}
+
+// authError is a rejected request, mapped to an HTTP status or gRPC code
+type authError struct {
+	httpStatus int
+	grpcCode   codes.Code
+	message    string
+	retryAfter time.Duration
+}
+
+// newAuthenticator returns nil when authentication is disabled
+func newAuthenticator(cfg common.AuthConfig) *authenticator {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	auth := &authenticator{keys: make(map[[sha256.Size]byte]*apiClient)}
+	for _, key := range cfg.APIKeys {
+		client := &apiClient{
+			name:              key.Name,
+			scopes:            make(map[string]bool),
+			uploadsPerMinute:  key.UploadsPerMinute,
+			maxConcurrentJobs: key.MaxConcurrentJobs,
+		}
+		for _, scope := range key.Scopes {
+			client.scopes[scope] = true
+		}
+		if key.RequestsPerMinute > 0 {
+			client.limiter = newRateLimiter(key.RequestsPerMinute)
+		}
+		auth.keys[sha256.Sum256([]byte(key.Key))] = client
+	}
+	return auth
+}
+
+// authorize checks that key exists, has scope and is within its rate limit
+func (a *authenticator) authorize(key, scope string) *authError {
+	if key == "" {
+		return &authError{http.StatusUnauthorized, codes.Unauthenticated, "API key required", 0}
+	}
+
+	client, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return &authError{http.StatusUnauthorized, codes.Unauthenticated, "Invalid API key", 0}
+	}
+	if !client.scopes[scope] {
+		log.Printf("API key %s denied: missing scope %s", client.name, scope)
+		return &authError{http.StatusForbidden, codes.PermissionDenied, "API key is not allowed to access this resource", 0}
+	}
+	if client.limiter != nil {
+		if wait := client.limiter.take(); wait > 0 {
+			return &authError{http.StatusTooManyRequests, codes.ResourceExhausted, "Rate limit exceeded", wait}
+		}
+	}
+	return nil
+}
+
+// client returns the client holding key, nil for unknown keys
+func (a *authenticator) client(key string) *apiClient {
+	if key == "" {
+		return nil
+	}
+	return a.keys[sha256.Sum256([]byte(key))]
+}
+
+// write answers an HTTP request with the error, telling rate limited
+// clients when to retry
+func (e *authError) write(w http.ResponseWriter) {
+	if e.retryAfter > 0 {
+		seconds := int(math.Ceil(e.retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	writeError(w, e.httpStatus, e.message)
+}
+
+func (e *authError) grpcError() error {
+	return status.Error(e.grpcCode, e.message)
+}
+
+// requireScope rejects HTTP requests without an API key granting scope
+func (s *Server) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if s.auth == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if authErr := s.auth.authorize(requestAPIKey(r), scope); authErr != nil {
+				authErr.write(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestAPIKey reads the key from X-API-Key or a bearer token
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	return bearerToken(r.Header.Get("Authorization"))
+}
+
+// grpcAuthInterceptors require the parse scope on every gRPC call
+func (s *Server) grpcAuthInterceptors() []grpc.ServerOption {
+	if s.auth == nil {
+		return nil
+	}
+
+	check := func(ctx context.Context) error {
+		authErr := s.auth.authorize(grpcAPIKey(ctx), common.ScopeParse)
+		if authErr == nil {
+			return nil
+		}
+		return authErr.grpcError()
+	}
+
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := check(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+// grpcAPIKey reads the key from "x-api-key" or "authorization" metadata
+func grpcAPIKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(strings.ToLower(apiKeyHeader)); len(values) > 0 {
+		return values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		return bearerToken(values[0])
+	}
+	return ""
+}
+
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
+
+// rateLimiter is a token bucket holding up to a minute's worth of requests
+type rateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // tokens per second
+	last     time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// take consumes a token, or returns how long to wait until one is available
+func (l *rateLimiter) take() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// idleSince returns how long ago the last token was taken
+func (l *rateLimiter) idleSince(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return now.Sub(l.last)
+}
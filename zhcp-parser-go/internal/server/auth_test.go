@@ -0,0 +1,74 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"bearer token", "Bearer abc123", "abc123"},
+		{"scheme in any case", "BEARER abc123", "abc123"},
+		{"surrounding spaces", "Bearer   abc123  ", "abc123"},
+		{"empty header", "", ""},
+		{"scheme without token", "Bearer ", ""},
+		{"other scheme", "Basic YWxhZGRpbjpvcGVu", ""},
+		{"scheme not followed by a space", "Bearerabc123", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bearerToken(tt.header); got != tt.want {
+				t.Errorf("bearerToken(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterTake(t *testing.T) {
+	// 60 a minute refills one token a second
+	tests := []struct {
+		name   string
+		tokens float64
+		idle   time.Duration
+		want   time.Duration
+	}{
+		{"full bucket", 60, 0, 0},
+		{"last token", 1, 0, 0},
+		{"empty bucket", 0, 0, time.Second},
+		{"half a token left", 0.5, 0, 500 * time.Millisecond},
+		{"refilled while idle", 0, 2 * time.Second, 0},
+		{"partly refilled while idle", 0, 250 * time.Millisecond, 750 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(60)
+			l.tokens = tt.tokens
+			l.last = time.Now().Add(-tt.idle)
+
+			// The clock moves on between setting last and take
+			got := l.take()
+			if got > tt.want || got < tt.want-50*time.Millisecond {
+				t.Errorf("take() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterBurstIsCapped(t *testing.T) {
+	l := newRateLimiter(5)
+	l.tokens = 0
+	l.last = time.Now().Add(-time.Hour)
+
+	for i := 0; i < 5; i++ {
+		if wait := l.take(); wait != 0 {
+			t.Fatalf("take() #%d = %v, want 0", i+1, wait)
+		}
+	}
+	if wait := l.take(); wait <= 0 {
+		t.Errorf("take() after a minute's worth = %v, want a wait", wait)
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/storage"
+)
+
+// storedResultCache keeps parse results in the database, so identical
+// documents are answered from the cache across restarts
+type storedResultCache struct {
+	store storage.Storage
+	ttl   time.Duration
+}
+
+func (c *storedResultCache) Get(key string) (*parser.ParseResult, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	raw, err := c.store.GetCachedResult(ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("failed to read cached parse result: %v", err)
+		}
+		return nil, false
+	}
+
+	var result parser.ParseResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		log.Printf("failed to decode cached parse result: %v", err)
+		return nil, false
+	}
+	return &result, true
+}
+
+func (c *storedResultCache) Put(key string, result *parser.ParseResult) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("failed to encode parse result for the cache: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := c.store.SaveCachedResult(ctx, key, raw, time.Now().Add(c.ttl)); err != nil {
+		log.Printf("failed to cache parse result: %v", err)
+	}
+}
+
+func (s *Server) deleteExpiredCachedResults(now time.Time) {
+	if s.store == nil || s.opts.ResultCacheTTL <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if _, err := s.store.DeleteExpiredCachedResults(ctx, now); err != nil {
+		log.Printf("failed to delete expired cached parse results: %v", err)
+	}
+}
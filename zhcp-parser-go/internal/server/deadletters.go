@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DeadLetterListResponse is one page of the dead letters, last failed first
+type DeadLetterListResponse struct {
+	DeadLetters []*storage.DeadLetter `json:"dead_letters"`
+	Total       int                   `json:"total"`
+	Limit       int                   `json:"limit"`
+	Offset      int                   `json:"offset"`
+}
+
+// recordDeadLetter keeps a failed job in the dead letters, so its error and
+// the artifacts of the attempt outlive the job TTL and it can be requeued.
+// job is a copy of the finished job.
+func (s *Server) recordDeadLetter(job ParseJob) {
+	if s.store == nil {
+		return
+	}
+
+	// The job keeps its result inline: a result in object storage expires
+	// with the job
+	stored := storedJob(&job)
+	raw, err := json.Marshal(stored)
+	if err != nil {
+		log.Printf("failed to encode dead letter of parse job %s: %v", job.ID, err)
+		return
+	}
+
+	letter := &storage.DeadLetter{
+		JobID:         job.ID,
+		FileName:      job.source.FileName,
+		Error:         failureMessage(job),
+		ErrorCategory: stored.ErrorCategory,
+		Stage:         job.Stage,
+		Provider:      stored.Provider,
+		Job:           raw,
+	}
+	if job.FinishedAt != nil {
+		letter.FailedAt = *job.FinishedAt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := s.store.SaveDeadLetter(ctx, letter); err != nil {
+		log.Printf("failed to record dead letter of parse job %s: %v", job.ID, err)
+	}
+}
+
+// removeDeadLetter drops the dead letter of a job whose retry succeeded
+func (s *Server) removeDeadLetter(jobID string) {
+	if s.store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := s.store.DeleteDeadLetter(ctx, jobID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("failed to delete dead letter of parse job %s: %v", jobID, err)
+	}
+}
+
+// failureMessage describes why a failed job failed: its error, or the error
+// or first validation error of its unsuccessful result
+func failureMessage(job ParseJob) string {
+	switch result := job.Result; {
+	case job.Error != "":
+		return job.Error
+	case result == nil:
+		return "parse failed"
+	case result.Error != nil:
+		return result.Error.Message
+	case len(result.ValidationError) > 0:
+		return result.ValidationError[0]
+	default:
+		return "parse result was not successful"
+	}
+}
+
+// handleListDeadLetters lists the jobs that failed for good, last failed
+// first, without their inputs and artifacts
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	limit, offset, err := pageParams(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	letters, total, err := s.store.ListDeadLetters(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if letters == nil {
+		letters = []*storage.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, DeadLetterListResponse{DeadLetters: letters, Total: total, Limit: limit, Offset: offset})
+}
+
+// handleGetDeadLetter returns the dead letter of a job with the job as it
+// last failed, including its input and kept artifacts
+func (s *Server) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, ok := s.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, letter)
+}
+
+// handleRequeueDeadLetter queues the job of a dead letter again, like a
+// retry of the job but also after the job itself expired. from works as for
+// retries. The dead letter stays until the requeued run succeeds; another
+// failure counts one more attempt.
+func (s *Server) handleRequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	if from != "" && from != parser.StageLLM && from != parser.StageTransformation {
+		writeError(w, http.StatusBadRequest, "from must be llm or transformation")
+		return
+	}
+
+	letter, ok := s.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	// The job is rebuilt from the dead letter once it expired
+	job, exists := s.getJob(r.Context(), letter.JobID)
+	if !exists {
+		var stored storage.ParseJob
+		if err := json.Unmarshal(letter.Job, &stored); err != nil {
+			log.Printf("failed to decode dead letter of parse job %s: %v", letter.JobID, err)
+			writeError(w, http.StatusInternalServerError, "Dead letter is corrupted")
+			return
+		}
+		job = *jobFromStored(&stored)
+	}
+
+	if !s.requeueJob(w, r, job, from) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := s.store.MarkDeadLetterRequeued(ctx, letter.JobID, time.Now().UTC()); err != nil {
+		log.Printf("failed to mark dead letter of parse job %s requeued: %v", letter.JobID, err)
+	}
+}
+
+// handleDeleteDeadLetter discards the dead letter of a job that will not be
+// requeued
+func (s *Server) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	err := s.store.DeleteDeadLetter(r.Context(), chi.URLParam(r, "jobId"))
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to delete dead letter")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Dead letter deleted"})
+}
+
+// loadDeadLetter reads the dead letter of the request's job, answering the
+// request when there is none
+func (s *Server) loadDeadLetter(w http.ResponseWriter, r *http.Request) (*storage.DeadLetter, bool) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return nil, false
+	}
+
+	letter, err := s.store.GetDeadLetter(r.Context(), chi.URLParam(r, "jobId"))
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Dead letter not found")
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return letter, true
+}
@@ -0,0 +1,334 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"zhcp-parser-go/internal/storage"
+	"zhcp-parser-go/internal/transformers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// taskPhaseKey is the task metadata key holding the name of its phase
+const taskPhaseKey = "phase"
+
+// ProjectDiff lists what a parse result changes in a stored project, e.g.
+// after a revised document was uploaded again
+type ProjectDiff struct {
+	ProjectID      string        `json:"project_id"`
+	JobID          string        `json:"job_id"`
+	Summary        DiffSummary   `json:"summary"`
+	ProjectChanges []FieldChange `json:"project_changes"`
+	PhasesAdded    []string      `json:"phases_added"`
+	PhasesRemoved  []string      `json:"phases_removed"`
+	PhasesChanged  []string      `json:"phases_changed"` // in both, with tasks added, removed or changed
+	TasksAdded     []TaskDiff    `json:"tasks_added"`
+	TasksRemoved   []TaskDiff    `json:"tasks_removed"`
+	TasksChanged   []TaskDiff    `json:"tasks_changed"`
+	Notes          []string      `json:"notes,omitempty"`
+}
+
+// DiffSummary counts the entries of a ProjectDiff
+type DiffSummary struct {
+	PhasesAdded    int `json:"phases_added"`
+	PhasesRemoved  int `json:"phases_removed"`
+	PhasesChanged  int `json:"phases_changed"`
+	TasksAdded     int `json:"tasks_added"`
+	TasksRemoved   int `json:"tasks_removed"`
+	TasksChanged   int `json:"tasks_changed"`
+	TasksUnchanged int `json:"tasks_unchanged"`
+}
+
+// TaskDiff is a task only in the parse result, only in the project, or in
+// both with different fields
+type TaskDiff struct {
+	ID       string        `json:"id,omitempty"`        // stored task, empty for added tasks
+	ParsedID string        `json:"parsed_id,omitempty"` // task in the parse result, empty for removed tasks
+	Name     string        `json:"name"`
+	Phase    string        `json:"phase,omitempty"`
+	Changes  []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is a field whose stored value differs from the parsed one
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// handleDiffProject compares the result of a completed parse job with a
+// stored project and its tasks
+func (s *Server) handleDiffProject(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	projectID := chi.URLParam(r, "id")
+	jobID := chi.URLParam(r, "jobId")
+
+	job, exists := s.getJob(r.Context(), jobID)
+	if !exists {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if job.Status != "completed" {
+		writeError(w, http.StatusBadRequest, "Job not completed, current status: "+job.Status)
+		return
+	}
+	if job.Result == nil || job.Result.ProjectStructure == nil {
+		writeError(w, http.StatusUnprocessableEntity, "Parse result has no project structure to compare")
+		return
+	}
+
+	project, err := s.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			writeError(w, http.StatusNotFound, "Project not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, "Failed to get project")
+		}
+		return
+	}
+	tasks, err := s.store.ListTasks(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list tasks")
+		return
+	}
+
+	diff := diffProject(project, tasks, job.Result.ProjectStructure)
+	diff.JobID = jobID
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// parsedTask is a task of the parse result with the name of its phase
+type parsedTask struct {
+	task  transformers.Task
+	phase string
+}
+
+// diffProject matches phases and tasks by name, ignoring case and
+// punctuation; tasks with the same name are matched in order. Stored tasks
+// know their phase from the "phase" metadata key; when none has it, phases
+// are not compared.
+func diffProject(project *storage.Project, tasks []*storage.Task, parsed *transformers.ProjectStructure) *ProjectDiff {
+	diff := &ProjectDiff{
+		ProjectID:      project.ID,
+		ProjectChanges: []FieldChange{},
+		PhasesAdded:    []string{},
+		PhasesRemoved:  []string{},
+		PhasesChanged:  []string{},
+		TasksAdded:     []TaskDiff{},
+		TasksRemoved:   []TaskDiff{},
+		TasksChanged:   []TaskDiff{},
+	}
+
+	diff.ProjectChanges = appendChange(diff.ProjectChanges, "title", project.Title, parsed.Project.Title)
+	diff.ProjectChanges = appendChange(diff.ProjectChanges, "description", project.Description, parsed.Project.Description)
+	diff.ProjectChanges = appendChange(diff.ProjectChanges, "deadline", formatDate(project.EndDate), parsed.Project.Deadline)
+
+	// Dependencies are compared by the names of the tasks they point at
+	storedNames := make(map[string]string, len(tasks))
+	comparePhases := false
+	for _, task := range tasks {
+		storedNames[task.ID] = task.Title
+		if storedPhase(task) != "" {
+			comparePhases = true
+		}
+	}
+	parsedNames := make(map[string]string)
+	var parsedTasks []parsedTask
+	for _, phase := range parsed.Project.Phases {
+		for _, task := range phase.Tasks {
+			parsedNames[task.ID] = task.Name
+			parsedTasks = append(parsedTasks, parsedTask{task: task, phase: phase.Name})
+		}
+	}
+	if !comparePhases {
+		diff.Notes = append(diff.Notes, "Stored tasks have no phase, so phases were not compared")
+	}
+
+	// Stored tasks not matched yet, by name key in their original order
+	unmatched := make(map[string][]*storage.Task)
+	for _, task := range tasks {
+		key := transformers.MergeKey(task.Title)
+		unmatched[key] = append(unmatched[key], task)
+	}
+
+	matched := make(map[*storage.Task]bool)
+	changedPhases := make(map[string]bool)
+	for _, parsedTask := range parsedTasks {
+		task := parsedTask.task
+		key := transformers.MergeKey(task.Name)
+		candidates := unmatched[key]
+		if len(candidates) == 0 {
+			diff.TasksAdded = append(diff.TasksAdded, TaskDiff{ParsedID: task.ID, Name: task.Name, Phase: parsedTask.phase})
+			changedPhases[transformers.MergeKey(parsedTask.phase)] = true
+			continue
+		}
+		stored := candidates[0]
+		unmatched[key] = candidates[1:]
+		matched[stored] = true
+
+		changes := diffTask(stored, parsedTask, storedNames, parsedNames, comparePhases)
+		if len(changes) == 0 {
+			diff.Summary.TasksUnchanged++
+			continue
+		}
+		diff.TasksChanged = append(diff.TasksChanged, TaskDiff{
+			ID:       stored.ID,
+			ParsedID: task.ID,
+			Name:     task.Name,
+			Phase:    parsedTask.phase,
+			Changes:  changes,
+		})
+		changedPhases[transformers.MergeKey(parsedTask.phase)] = true
+		changedPhases[transformers.MergeKey(storedPhase(stored))] = true
+	}
+	for _, task := range tasks {
+		if !matched[task] {
+			diff.TasksRemoved = append(diff.TasksRemoved, TaskDiff{ID: task.ID, Name: task.Title, Phase: storedPhase(task)})
+			changedPhases[transformers.MergeKey(storedPhase(task))] = true
+		}
+	}
+
+	if comparePhases {
+		diffPhases(diff, tasks, parsed, changedPhases)
+	}
+
+	diff.Summary.PhasesAdded = len(diff.PhasesAdded)
+	diff.Summary.PhasesRemoved = len(diff.PhasesRemoved)
+	diff.Summary.PhasesChanged = len(diff.PhasesChanged)
+	diff.Summary.TasksAdded = len(diff.TasksAdded)
+	diff.Summary.TasksRemoved = len(diff.TasksRemoved)
+	diff.Summary.TasksChanged = len(diff.TasksChanged)
+	return diff
+}
+
+// diffPhases sorts the phases of both sides into added, removed and changed
+func diffPhases(diff *ProjectDiff, tasks []*storage.Task, parsed *transformers.ProjectStructure, changedPhases map[string]bool) {
+	storedPhases := make(map[string]string)
+	for _, task := range tasks {
+		if phase := storedPhase(task); phase != "" {
+			storedPhases[transformers.MergeKey(phase)] = phase
+		}
+	}
+
+	parsedPhases := make(map[string]bool)
+	for _, phase := range parsed.Project.Phases {
+		key := transformers.MergeKey(phase.Name)
+		parsedPhases[key] = true
+		switch {
+		case storedPhases[key] == "":
+			diff.PhasesAdded = append(diff.PhasesAdded, phase.Name)
+		case changedPhases[key]:
+			diff.PhasesChanged = append(diff.PhasesChanged, phase.Name)
+		}
+	}
+
+	for key, phase := range storedPhases {
+		if !parsedPhases[key] {
+			diff.PhasesRemoved = append(diff.PhasesRemoved, phase)
+		}
+	}
+	sort.Strings(diff.PhasesRemoved)
+}
+
+// diffTask lists the fields of a stored task that the parsed task changes
+func diffTask(stored *storage.Task, parsed parsedTask, storedNames, parsedNames map[string]string, comparePhases bool) []FieldChange {
+	task := parsed.task
+	var changes []FieldChange
+
+	if comparePhases && transformers.MergeKey(storedPhase(stored)) != transformers.MergeKey(parsed.phase) {
+		changes = append(changes, FieldChange{Field: "phase", Old: storedPhase(stored), New: parsed.phase})
+	}
+	changes = appendChange(changes, "description", stored.Description, task.Description)
+	changes = appendChange(changes, "start_date", formatDate(stored.StartDate), task.StartDate)
+	changes = appendChange(changes, "end_date", formatDate(stored.DueDate), task.EndDate)
+	if task.Status != "" && storedStatus(stored.Status) != task.Status {
+		changes = append(changes, FieldChange{Field: "status", Old: stored.Status, New: task.Status})
+	}
+
+	// The stored assignee only changes when no parsed person is them
+	var persons []string
+	assigned := false
+	for _, person := range task.ResponsiblePersons {
+		persons = append(persons, person.Name)
+		if stored.AssignedTo != "" && strings.EqualFold(strings.TrimSpace(person.Name), strings.TrimSpace(stored.AssignedTo)) {
+			assigned = true
+		}
+	}
+	if len(persons) > 0 && !assigned {
+		changes = append(changes, FieldChange{Field: "assigned_to", Old: stored.AssignedTo, New: strings.Join(persons, "; ")})
+	}
+
+	oldDeps := dependencyNames(stored.Dependencies, storedNames)
+	newDeps := dependencyNames(task.Dependencies, parsedNames)
+	if !sameNames(oldDeps, newDeps) {
+		changes = append(changes, FieldChange{Field: "dependencies", Old: strings.Join(oldDeps, "; "), New: strings.Join(newDeps, "; ")})
+	}
+	return changes
+}
+
+// appendChange records a field when its values differ; empty parsed values
+// are not a change, as the document may just not mention the field
+func appendChange(changes []FieldChange, field, oldValue, newValue string) []FieldChange {
+	if strings.TrimSpace(newValue) == "" || strings.TrimSpace(oldValue) == strings.TrimSpace(newValue) {
+		return changes
+	}
+	return append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+}
+
+// storedStatus maps the statuses of stored tasks to those of parse results
+func storedStatus(status string) string {
+	if status == "pending" {
+		return "planned"
+	}
+	return status
+}
+
+func storedPhase(task *storage.Task) string {
+	phase, _ := task.Metadata[taskPhaseKey].(string)
+	return strings.TrimSpace(phase)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// dependencyNames resolves dependency IDs to task names, sorted
+func dependencyNames(ids []string, names map[string]string) []string {
+	resolved := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			resolved = append(resolved, name)
+		}
+	}
+	sort.Strings(resolved)
+	return resolved
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := make(map[string]int, len(a))
+	for _, name := range a {
+		keys[transformers.MergeKey(name)]++
+	}
+	for _, name := range b {
+		keys[transformers.MergeKey(name)]--
+	}
+	for _, count := range keys {
+		if count != 0 {
+			return false
+		}
+	}
+	return true
+}
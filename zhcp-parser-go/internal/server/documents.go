@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/transformers"
+)
+
+// maxJobDocuments bounds the documents parsed together in one job
+const maxJobDocuments = 10
+
+// jobDocument is one of the documents of a job that parses several into one
+// project. Like a single upload, it is kept on disk or in object storage
+// until the job finishes.
+type jobDocument struct {
+	FileName    string `json:"file_name"`
+	FilePath    string `json:"file_path,omitempty"`
+	DocumentKey string `json:"document_key,omitempty"`
+}
+
+// handleUploadDocuments queues documents about one project, such as a
+// contract, its annex and a schedule, to be parsed and merged into one
+// project structure. The "file" parts are taken in order: with
+// conflicts=later_wins, the default, a later document overrides the values
+// earlier ones gave a field; with conflicts=manual the first value is kept.
+// Either way the fields the documents disagree on are listed in the result.
+func (s *Server) handleUploadDocuments(w http.ResponseWriter, r *http.Request) {
+	upload, uploadErr := s.receiveUploadForm(w, r, maxJobDocuments, s.createUpload)
+	if uploadErr != nil {
+		uploadErr.write(w)
+		return
+	}
+
+	strategy, ok := transformers.ParseConflictStrategy(strings.TrimSpace(upload.fields["conflicts"]))
+	if !ok {
+		upload.remove()
+		writeError(w, http.StatusBadRequest, "conflicts must be later_wins or manual")
+		return
+	}
+
+	callbackURL, err := s.parseCallbackURL(upload.fields["callback_url"])
+	if err != nil {
+		upload.remove()
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	options, err := s.uploadJobOptions(upload.fields)
+	if err != nil {
+		upload.remove()
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item := queuedParseJob{
+		ID:          uuid.New().String(),
+		Conflicts:   string(strategy),
+		CallbackURL: callbackURL,
+		Force:       formBool(upload.fields["force"]),
+		Options:     options,
+		client:      requestJobClient(r),
+	}
+	names := make([]string, 0, len(upload.files))
+	for _, file := range upload.files {
+		item.Documents = append(item.Documents, jobDocument{FileName: file.fileName, FilePath: file.path})
+		names = append(names, file.fileName)
+	}
+	item.FileName = strings.Join(names, ", ")
+
+	if err := s.stageDocuments(r.Context(), &item); err != nil {
+		upload.remove()
+		log.Printf("failed to store uploads %s: %v", item.FileName, err)
+		writeError(w, http.StatusInternalServerError, "Failed to save file")
+		return
+	}
+
+	s.enqueue(w, item)
+}
+
+// stageDocuments moves the documents of a job to object storage, as
+// stageUpload does for a single upload. Documents already moved are deleted
+// when one fails.
+func (s *Server) stageDocuments(ctx context.Context, item *queuedParseJob) error {
+	if s.opts.Objects == nil {
+		return nil
+	}
+
+	for i := range item.Documents {
+		document := &item.Documents[i]
+		key := documentKey(fmt.Sprintf("%s-%d", item.ID, i+1), document.FileName)
+		if err := s.putDocument(ctx, document.FilePath, key); err != nil {
+			s.removeUpload(*item)
+			return err
+		}
+		_ = os.Remove(document.FilePath)
+		document.FilePath = ""
+		document.DocumentKey = key
+	}
+	return nil
+}
+
+// parseDocuments parses the documents of a job into one project, fetching
+// those kept in object storage first
+func (s *Server) parseDocuments(ctx context.Context, item queuedParseJob, opts parser.ParseOptions) (*parser.ParseResult, error) {
+	documents := make([]parser.Document, 0, len(item.Documents))
+	for _, document := range item.Documents {
+		path := document.FilePath
+		if document.DocumentKey != "" {
+			local, err := s.fetchDocument(ctx, document.DocumentKey)
+			if err != nil {
+				return nil, err
+			}
+			defer os.Remove(local)
+			path = local
+		}
+		documents = append(documents, parser.Document{Path: path, Name: document.FileName})
+	}
+
+	return s.parser.ParseDocuments(ctx, documents, transformers.ConflictStrategy(item.Conflicts), opts)
+}
+
+// uploadMissing reports whether a document of a job restored from storage
+// was removed from the upload dir
+func uploadMissing(item queuedParseJob) bool {
+	paths := []string{item.FilePath}
+	for _, document := range item.Documents {
+		paths = append(paths, document.FilePath)
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"zhcp-parser-go/internal/exporters"
+)
+
+// writeExport answers /parse/result with the project structure of a
+// completed job converted for external planning tools: format "msproject"
+// for MS Project XML, "csv" for a flat tasks table
+func writeExport(w http.ResponseWriter, job ParseJob, format string) {
+	var (
+		body        []byte
+		contentType string
+		extension   string
+		err         error
+	)
+	switch format {
+	case "msproject":
+		contentType, extension = "application/xml; charset=utf-8", "xml"
+	case "csv":
+		contentType, extension = "text/csv; charset=utf-8", "csv"
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, msproject or csv")
+		return
+	}
+
+	if job.Result == nil || job.Result.ProjectStructure == nil {
+		writeError(w, http.StatusUnprocessableEntity, "Parse result has no project structure to export")
+		return
+	}
+
+	if format == "msproject" {
+		body, err = exporters.MSProjectXML(job.Result.ProjectStructure, job.UpdatedAt)
+	} else {
+		body, err = exporters.TasksCSV(job.Result.ProjectStructure)
+	}
+	if err != nil {
+		log.Printf("failed to export result of parse job %s as %s: %v", job.ID, format, err)
+		writeError(w, http.StatusInternalServerError, "Failed to export result")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.ID+"."+extension))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	zhcpv1 "zhcp-parser-go/api/zhcp/v1"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcParserService implements zhcpv1.ParserServiceServer on top of the same
+// job queue as the HTTP handlers
+type grpcParserService struct {
+	zhcpv1.UnimplementedParserServiceServer
+	server *Server
+}
+
+// startGRPC serves the gRPC API when a port is configured. Serve errors are
+// reported on errCh.
+func (s *Server) startGRPC(errCh chan<- error) (*grpc.Server, error) {
+	if s.opts.GRPCPort == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", ":"+s.opts.GRPCPort)
+	if err != nil {
+		return nil, fmt.Errorf("listen grpc: %w", err)
+	}
+
+	grpcServer := grpc.NewServer(s.grpcAuthInterceptors()...)
+	zhcpv1.RegisterParserServiceServer(grpcServer, &grpcParserService{server: s})
+
+	go func() {
+		log.Printf("gRPC server listening on %s", listener.Addr())
+		if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			errCh <- err
+		}
+	}()
+	return grpcServer, nil
+}
+
+// stopGRPC lets in-flight calls finish, but no longer than ctx allows
+func (s *Server) stopGRPC(ctx context.Context, grpcServer *grpc.Server) {
+	if grpcServer == nil {
+		return
+	}
+
+	// WatchJob streams would otherwise hold GracefulStop until their job ends
+	s.stopStreams()
+
+	done := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+}
+
+func (g *grpcParserService) UploadDocument(stream zhcpv1.ParserService_UploadDocumentServer) error {
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "upload metadata is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	metadata := first.GetMetadata()
+	if metadata == nil {
+		return status.Error(codes.InvalidArgument, "first message must carry the upload metadata")
+	}
+
+	client, err := g.server.grpcJobClient(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(filepath.Ext(metadata.GetFilename()))
+	if !supportedExtensions[ext] {
+		return status.Error(codes.InvalidArgument, "Only PDF, DOCX, XLSX, PPTX, TXT and MD files are supported")
+	}
+
+	callbackURL, err := g.server.parseCallbackURL(metadata.GetCallbackUrl())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Keep the upload until the job finishes, so it can be requeued after a restart
+	// or parsed by another node
+	item := queuedParseJob{
+		ID:          uuid.New().String(),
+		FileName:    filepath.Base(metadata.GetFilename()),
+		CallbackURL: callbackURL,
+		Force:       metadata.GetForce(),
+		client:      client,
+	}
+	item.FilePath = filepath.Join(g.server.opts.UploadDir, item.ID+ext)
+	if err := receiveUpload(stream, item.FilePath, g.server.opts.MaxUploadBytes); err != nil {
+		_ = os.Remove(item.FilePath)
+		return err
+	}
+	if err := g.server.stageUpload(stream.Context(), &item); err != nil {
+		_ = os.Remove(item.FilePath)
+		log.Printf("failed to store upload %s: %v", item.FileName, err)
+		return status.Error(codes.Internal, "Failed to save file")
+	}
+
+	jobID, err := g.server.submit(item)
+	if errors.Is(err, errTooManyJobs) {
+		return tooManyJobs().grpcError()
+	}
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, "Parser queue is full, try again later")
+	}
+
+	return stream.SendAndClose(&zhcpv1.SubmitResponse{JobId: jobID, Status: "queued"})
+}
+
+// receiveUpload writes the chunks following the metadata message to path,
+// up to limit bytes
+func receiveUpload(stream zhcpv1.ParserService_UploadDocumentServer, path string, limit int64) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return status.Error(codes.Internal, "Failed to create temp file")
+	}
+	defer out.Close()
+
+	var size int64
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if msg.GetMetadata() != nil {
+			return status.Error(codes.InvalidArgument, "upload metadata must only be sent once")
+		}
+
+		chunk := msg.GetChunk()
+		size += int64(len(chunk))
+		if size > limit {
+			return status.Errorf(codes.ResourceExhausted, "File exceeds the upload limit of %s", formatUploadLimit(limit))
+		}
+		if _, err := out.Write(chunk); err != nil {
+			return status.Error(codes.Internal, "Failed to save file")
+		}
+	}
+
+	if size == 0 {
+		return status.Error(codes.InvalidArgument, "No file provided")
+	}
+	if err := out.Close(); err != nil {
+		return status.Error(codes.Internal, "Failed to save file")
+	}
+	return nil
+}
+
+func (g *grpcParserService) ParseText(ctx context.Context, req *zhcpv1.ParseTextRequest) (*zhcpv1.SubmitResponse, error) {
+	if strings.TrimSpace(req.GetText()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "Text is required")
+	}
+	if len(req.GetText()) > maxParseTextBytes {
+		return nil, status.Error(codes.InvalidArgument, "text exceeds 5MB limit")
+	}
+
+	format, ok := normalizeTextFormat(req.GetFormat())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "Format must be text or markdown")
+	}
+
+	callbackURL, err := g.server.parseCallbackURL(req.GetCallbackUrl())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := g.server.grpcJobClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobID, err := g.server.submit(queuedParseJob{Text: req.GetText(), Format: format, CallbackURL: callbackURL, Force: req.GetForce(), client: client})
+	if errors.Is(err, errTooManyJobs) {
+		return nil, tooManyJobs().grpcError()
+	}
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, "Parser queue is full, try again later")
+	}
+
+	return &zhcpv1.SubmitResponse{JobId: jobID, Status: "queued"}, nil
+}
+
+func (g *grpcParserService) GetStatus(ctx context.Context, req *zhcpv1.GetJobRequest) (*zhcpv1.JobStatus, error) {
+	job, exists := g.server.getJob(ctx, req.GetJobId())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "Job not found")
+	}
+	return jobStatusProto(job), nil
+}
+
+func (g *grpcParserService) GetResult(ctx context.Context, req *zhcpv1.GetJobRequest) (*zhcpv1.ParseResultResponse, error) {
+	job, exists := g.server.getJob(ctx, req.GetJobId())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "Job not found")
+	}
+	if job.Status != "completed" {
+		return nil, status.Errorf(codes.FailedPrecondition, "Job not completed, current status: %s", job.Status)
+	}
+
+	result, err := json.Marshal(job.Result)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode result: %v", err)
+	}
+	return &zhcpv1.ParseResultResponse{JobId: job.ID, ResultJson: result}, nil
+}
+
+// WatchJob is the gRPC counterpart of /api/parse/stream/{jobId}
+func (g *grpcParserService) WatchJob(req *zhcpv1.GetJobRequest, stream zhcpv1.ParserService_WatchJobServer) error {
+	ctx := stream.Context()
+	jobID := req.GetJobId()
+
+	// Subscribe before the first read so no update is lost in between
+	updates, unsubscribe := g.server.subscribe(jobID)
+	defer unsubscribe()
+
+	job, exists := g.server.getJob(ctx, jobID)
+	if !exists {
+		return status.Error(codes.NotFound, "Job not found")
+	}
+
+	var last StatusResponse
+	for {
+		if current := statusResponse(job); current != last {
+			if err := stream.Send(jobStatusProto(job)); err != nil {
+				return err
+			}
+			if jobFinished(job.Status) {
+				return nil
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.server.streamsDone:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-updates:
+		}
+
+		job, exists = g.server.getJob(ctx, jobID)
+		if !exists {
+			return status.Error(codes.NotFound, "Job expired")
+		}
+	}
+}
+
+func jobStatusProto(job ParseJob) *zhcpv1.JobStatus {
+	return &zhcpv1.JobStatus{
+		JobId:    job.ID,
+		Status:   job.Status,
+		Stage:    job.Stage,
+		Progress: int32(job.Progress),
+		Error:    job.Error,
+	}
+}
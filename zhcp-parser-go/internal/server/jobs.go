@@ -0,0 +1,618 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	zhcperrors "zhcp-parser-go/internal/errors"
+	"zhcp-parser-go/internal/metrics"
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// storeTimeout bounds every job persistence call
+const storeTimeout = 5 * time.Second
+
+// errUploadMissing fails restored jobs whose uploaded file was removed
+const errUploadMissing = "uploaded file is no longer available"
+
+// errArtifactsMissing fails retries whose kept artifacts were lost
+const errArtifactsMissing = "intermediate results of the previous attempt are no longer available"
+
+// storedJob converts a job to its persisted form. Callers must hold jobsMu.
+func storedJob(job *ParseJob) *storage.ParseJob {
+	stored := &storage.ParseJob{
+		ID:        job.ID,
+		Status:    job.Status,
+		Progress:  job.Progress,
+		FilePath:  job.source.FilePath,
+		Text:      job.source.Text,
+		Format:    job.source.Format,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+
+		CallbackURL: job.source.CallbackURL,
+		Force:       job.source.Force,
+		RetryFrom:   job.source.RetryFrom,
+		Conflicts:   job.source.Conflicts,
+
+		FileName:    job.source.FileName,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+		DocumentKey: job.source.DocumentKey,
+	}
+	stored.Provider, stored.ErrorCategory = jobOutcome(job)
+	if job.Result != nil {
+		if raw, err := json.Marshal(job.Result); err == nil {
+			stored.Result = raw
+		} else {
+			log.Printf("failed to encode result of parse job %s: %v", job.ID, err)
+		}
+	}
+	if len(job.source.Documents) > 0 {
+		if raw, err := json.Marshal(job.source.Documents); err == nil {
+			stored.Documents = raw
+		} else {
+			log.Printf("failed to encode documents of parse job %s: %v", job.ID, err)
+		}
+	}
+	if !job.source.Options.isZero() {
+		if raw, err := json.Marshal(job.source.Options); err == nil {
+			stored.Options = raw
+		} else {
+			log.Printf("failed to encode options of parse job %s: %v", job.ID, err)
+		}
+	}
+	if job.artifacts != nil {
+		if raw, err := json.Marshal(job.artifacts); err == nil {
+			stored.Artifacts = raw
+		} else {
+			log.Printf("failed to encode artifacts of parse job %s: %v", job.ID, err)
+		}
+	}
+	return stored
+}
+
+// jobFromStored rebuilds a job loaded from storage
+func jobFromStored(stored *storage.ParseJob) *ParseJob {
+	job := &ParseJob{
+		ID:        stored.ID,
+		Status:    stored.Status,
+		Progress:  stored.Progress,
+		Error:     stored.Error,
+		CreatedAt: stored.CreatedAt.UTC(),
+		UpdatedAt: stored.UpdatedAt.UTC(),
+
+		StartedAt:  utcTime(stored.StartedAt),
+		FinishedAt: utcTime(stored.FinishedAt),
+
+		source: queuedParseJob{
+			ID:       stored.ID,
+			FilePath: stored.FilePath,
+			FileName: stored.FileName,
+			Text:     stored.Text,
+			Format:   stored.Format,
+
+			DocumentKey: stored.DocumentKey,
+
+			CallbackURL: stored.CallbackURL,
+			Force:       stored.Force,
+			RetryFrom:   stored.RetryFrom,
+			Conflicts:   stored.Conflicts,
+		},
+	}
+	if len(stored.Documents) > 0 {
+		if err := json.Unmarshal(stored.Documents, &job.source.Documents); err != nil {
+			log.Printf("failed to decode documents of parse job %s: %v", stored.ID, err)
+		}
+	}
+	if len(stored.Options) > 0 {
+		if err := json.Unmarshal(stored.Options, &job.source.Options); err != nil {
+			log.Printf("failed to decode options of parse job %s: %v", stored.ID, err)
+		}
+	}
+	if len(stored.Result) > 0 {
+		var result parser.ParseResult
+		if err := json.Unmarshal(stored.Result, &result); err == nil {
+			job.Result = &result
+		} else {
+			log.Printf("failed to decode result of parse job %s: %v", stored.ID, err)
+		}
+	}
+	if len(stored.Artifacts) > 0 {
+		var artifacts parser.Artifacts
+		if err := json.Unmarshal(stored.Artifacts, &artifacts); err == nil {
+			job.artifacts = &artifacts
+		} else {
+			log.Printf("failed to decode artifacts of parse job %s: %v", stored.ID, err)
+		}
+	}
+	return job
+}
+
+// saveJob persists a job snapshot; failures are logged, the in-memory job
+// stays authoritative while the server runs
+func (s *Server) saveJob(stored *storage.ParseJob) {
+	if s.store == nil || stored == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := s.store.SaveParseJob(ctx, stored); err != nil {
+		log.Printf("failed to persist parse job %s: %v", stored.ID, err)
+	}
+}
+
+func (s *Server) deleteStoredJob(jobID string) {
+	if s.store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := s.store.DeleteParseJob(ctx, jobID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("failed to delete parse job %s: %v", jobID, err)
+	}
+}
+
+func (s *Server) deleteExpiredStoredJobs(before time.Time) {
+	if s.store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if _, err := s.store.DeleteFinishedParseJobs(ctx, before); err != nil {
+		log.Printf("failed to delete expired parse jobs: %v", err)
+	}
+}
+
+// getJob returns a copy of a job, loading it from storage when it is not in
+// memory (e.g. it finished before the last restart)
+func (s *Server) getJob(ctx context.Context, jobID string) (ParseJob, bool) {
+	s.jobsMu.RLock()
+	job, exists := s.jobs[jobID]
+	if exists {
+		snapshot := *job
+		s.jobsMu.RUnlock()
+		return snapshot, true
+	}
+	s.jobsMu.RUnlock()
+
+	if s.store == nil {
+		return ParseJob{}, false
+	}
+
+	stored, err := s.store.GetParseJob(ctx, jobID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("failed to load parse job %s: %v", jobID, err)
+		}
+		return ParseJob{}, false
+	}
+	loaded := jobFromStored(stored)
+	if stored.ResultKey != "" {
+		s.loadResult(ctx, loaded, stored.ResultKey)
+	}
+	return *loaded, true
+}
+
+// restoreJobs requeues the jobs that were queued or processing when the
+// server stopped. Jobs whose upload has disappeared are marked failed.
+func (s *Server) restoreJobs(ctx context.Context) {
+	if s.store == nil {
+		return
+	}
+
+	storedJobs, err := s.store.ListIncompleteParseJobs(ctx)
+	if err != nil {
+		log.Printf("failed to load unfinished parse jobs: %v", err)
+		return
+	}
+
+	pending := make([]queuedParseJob, 0, len(storedJobs))
+	for _, stored := range storedJobs {
+		job := jobFromStored(stored)
+		job.Status = "queued"
+		job.Progress = 0
+		job.UpdatedAt = time.Now().UTC()
+		job.StartedAt = nil
+		if uploadMissing(job.source) {
+			job.Status = "failed"
+			job.Error = errUploadMissing
+			finishedAt := job.UpdatedAt
+			job.FinishedAt = &finishedAt
+		}
+
+		s.jobsMu.Lock()
+		s.jobs[job.ID] = job
+		snapshot := storedJob(job)
+		finished := *job
+		s.jobsMu.Unlock()
+		s.saveJob(snapshot)
+
+		if job.Status == "queued" {
+			item := job.source
+			item.queuedAt = time.Now()
+			pending = append(pending, item)
+		} else {
+			s.sendWebhook(finished)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	log.Printf("requeued %d unfinished parse jobs", len(pending))
+
+	// The queue may be smaller than the backlog, so feed it as workers free up
+	go func() {
+		for _, item := range pending {
+			select {
+			case s.queue <- item:
+				s.scaleWorkers()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// jobFinished reports whether a job with status will not change any more
+func jobFinished(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
+
+// jobFailed reports whether a finished job failed, either with an error or
+// with an unsuccessful parse result
+func jobFailed(job *ParseJob) bool {
+	return job.Status == "failed" || (job.Status == "completed" && job.Result != nil && !job.Result.Success)
+}
+
+// handleCancelJob cancels a queued or processing job. Queued jobs are
+// dropped before a worker picks them up; processing jobs have their LLM
+// calls aborted. The job stays visible with status "cancelled" until it
+// expires.
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	s.jobsMu.Lock()
+	job, exists := s.jobs[jobID]
+	if !exists || jobFinished(job.Status) {
+		s.jobsMu.Unlock()
+		if finished, ok := s.getJob(r.Context(), jobID); ok {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Job already finished, current status: %s", finished.Status))
+			return
+		}
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	if cancel, running := s.cancels[jobID]; running {
+		cancel()
+	} else {
+		s.removeUpload(job.source)
+	}
+	finishedAt := time.Now().UTC()
+	job.Status = "cancelled"
+	job.Error = "cancelled by request"
+	job.UpdatedAt = finishedAt
+	job.FinishedAt = &finishedAt
+	stored := storedJob(job)
+	cancelled := *job
+	s.jobsMu.Unlock()
+
+	s.saveJob(stored)
+	s.notify(jobID)
+	s.sendWebhook(cancelled)
+
+	writeJSON(w, http.StatusOK, statusResponse(cancelled))
+}
+
+// handleRetryJob requeues a failed job under the same ID, with the text and
+// LLM response kept from its last attempt instead of the document. from=llm
+// asks the LLM again; from=transformation, the default when a response was
+// kept, transforms that response again without another LLM call.
+func (s *Server) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+	from := r.URL.Query().Get("from")
+	if from != "" && from != parser.StageLLM && from != parser.StageTransformation {
+		writeError(w, http.StatusBadRequest, "from must be llm or transformation")
+		return
+	}
+
+	// Jobs that finished before the last restart are only in storage
+	loaded, exists := s.getJob(r.Context(), jobID)
+	if !exists {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	s.requeueJob(w, r, loaded, from)
+}
+
+// requeueJob queues a failed job again from the stage retryStage picks for
+// from, using the in-memory job or else loaded, and answers the request. It
+// reports whether the job was queued.
+func (s *Server) requeueJob(w http.ResponseWriter, r *http.Request, loaded ParseJob, from string) bool {
+	jobID := loaded.ID
+
+	s.jobsMu.Lock()
+	job, exists := s.jobs[jobID]
+	if !exists {
+		job = &loaded
+	}
+	stage, conflict := retryStage(job, from)
+	if conflict != "" {
+		s.jobsMu.Unlock()
+		writeError(w, http.StatusConflict, conflict)
+		return false
+	}
+	client := requestJobClient(r)
+	if client != nil && client.maxConcurrentJobs > 0 && s.activeJobsLocked(client.id) >= client.maxConcurrentJobs {
+		s.jobsMu.Unlock()
+		metrics.ParseJobsRejected.WithLabelValues("client_limit").Inc()
+		tooManyJobs().write(w)
+		return false
+	}
+
+	previous := *job
+	job.Status = "queued"
+	job.Progress = 0
+	job.Stage = ""
+	job.Result = nil
+	job.Error = ""
+	job.UpdatedAt = time.Now().UTC()
+	job.StartedAt = nil
+	job.FinishedAt = nil
+	// The document was removed when the job finished
+	job.source.FilePath = ""
+	job.source.DocumentKey = ""
+	job.source.RetryFrom = stage
+	job.source.client = client
+	s.jobs[jobID] = job
+	stored := storedJob(job)
+	item := job.source
+	s.jobsMu.Unlock()
+	s.saveJob(stored)
+
+	// The retry's result replaces the previous one, which is saved with the
+	// job again if the retry cannot be queued
+	if previous.Result != nil {
+		s.deleteObject(resultKey(jobID))
+	}
+
+	item.queuedAt = time.Now()
+	select {
+	case s.queue <- item:
+		s.scaleWorkers()
+	default:
+		s.jobsMu.Lock()
+		*job = previous
+		stored = storedJob(job)
+		s.jobsMu.Unlock()
+		s.saveJob(stored)
+		metrics.ParseJobsRejected.WithLabelValues("queue_full").Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(int(busyRetryAfter.Seconds())))
+		writeError(w, http.StatusServiceUnavailable, "Parser queue is full, try again later")
+		return false
+	}
+
+	s.notify(jobID)
+	writeJSON(w, http.StatusAccepted, UploadResponse{
+		JobID:  jobID,
+		Status: "queued",
+	})
+	return true
+}
+
+// retryStage returns the stage a retry of job starts from, or why the job
+// cannot be retried. Callers must hold jobsMu.
+func retryStage(job *ParseJob, from string) (string, string) {
+	if !jobFinished(job.Status) {
+		return "", "Job not finished, current status: " + job.Status
+	}
+	if !jobFailed(job) {
+		return "", "Only failed jobs can be retried, current status: " + job.Status
+	}
+	if job.artifacts == nil {
+		return "", "No intermediate results were kept for this job, upload the document again"
+	}
+
+	switch {
+	case from == "" && job.artifacts.LLMResponse != nil:
+		return parser.StageTransformation, ""
+	case from == "":
+		return parser.StageLLM, ""
+	case from == parser.StageTransformation && job.artifacts.LLMResponse == nil:
+		return "", "No LLM response was kept for this job, retry from llm"
+	default:
+		return from, ""
+	}
+}
+
+// Page sizes of the job listing
+const (
+	defaultJobPageSize = 50
+	maxJobPageSize     = 200
+)
+
+// JobSummary describes a parse job in the job listing
+type JobSummary struct {
+	ID              string     `json:"id"`
+	Status          string     `json:"status"`
+	FileName        string     `json:"file_name,omitempty"`        // empty for raw text jobs
+	Provider        string     `json:"provider,omitempty"`         // LLM provider that answered
+	ErrorCategory   string     `json:"error_category,omitempty"`   // e.g. llm_error, parsing_error
+	Error           string     `json:"error,omitempty"`            // message of a failed job
+	DurationSeconds float64    `json:"duration_seconds,omitempty"` // processing time so far for running jobs
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobListResponse is one page of the job listing
+type JobListResponse struct {
+	Jobs   []JobSummary `json:"jobs"`
+	Total  int          `json:"total"` // jobs matching the filter
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// handleListJobs lists parse jobs, newest first, for operators inspecting
+// the backlog and failures. status filters by job status, since (RFC 3339
+// time or date) by creation time; limit and offset page through the result.
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := storage.ParseJobFilter{Status: query.Get("status"), Limit: defaultJobPageSize}
+
+	if filter.Status != "" && !slices.Contains(jobStatuses, filter.Status) {
+		writeError(w, http.StatusBadRequest, "status must be queued, processing, completed, failed or cancelled")
+		return
+	}
+	since, err := parseUsageTime(query.Get("since"), time.Time{}, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid since, expected RFC 3339 time or YYYY-MM-DD")
+		return
+	}
+	filter.Since = since
+	filter.Limit, filter.Offset, err = pageParams(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		jobs  []*storage.ParseJob
+		total int
+	)
+	if s.store != nil {
+		jobs, total, err = s.store.ListParseJobs(r.Context(), filter)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else {
+		jobs, total = s.listMemoryJobs(filter)
+	}
+
+	response := JobListResponse{Jobs: make([]JobSummary, 0, len(jobs)), Total: total, Limit: filter.Limit, Offset: filter.Offset}
+	now := time.Now().UTC()
+	for _, job := range jobs {
+		response.Jobs = append(response.Jobs, jobSummary(job, now))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// pageParams reads the limit and offset of a listing page
+func pageParams(query url.Values) (limit, offset int, err error) {
+	limit = defaultJobPageSize
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxJobPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxJobPageSize)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must not be negative")
+		}
+	}
+	return limit, offset, nil
+}
+
+// listMemoryJobs applies filter to the jobs held in memory, for servers
+// running without storage
+func (s *Server) listMemoryJobs(filter storage.ParseJobFilter) ([]*storage.ParseJob, int) {
+	s.jobsMu.RLock()
+	var matching []*storage.ParseJob
+	for _, job := range s.jobs {
+		if filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		if job.CreatedAt.Before(filter.Since) {
+			continue
+		}
+		matching = append(matching, storedJob(job))
+	}
+	s.jobsMu.RUnlock()
+
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].CreatedAt.After(matching[j].CreatedAt)
+	})
+
+	total := len(matching)
+	start := min(filter.Offset, total)
+	end := min(start+filter.Limit, total)
+	return matching[start:end], total
+}
+
+func jobSummary(job *storage.ParseJob, now time.Time) JobSummary {
+	summary := JobSummary{
+		ID:            job.ID,
+		Status:        job.Status,
+		FileName:      job.FileName,
+		Provider:      job.Provider,
+		ErrorCategory: job.ErrorCategory,
+		CreatedAt:     job.CreatedAt.UTC(),
+		StartedAt:     utcTime(job.StartedAt),
+		FinishedAt:    utcTime(job.FinishedAt),
+	}
+	if job.Status == "failed" || job.ErrorCategory != "" {
+		summary.Error = job.Error
+	}
+	if summary.StartedAt != nil {
+		end := now
+		if summary.FinishedAt != nil {
+			end = *summary.FinishedAt
+		}
+		summary.DurationSeconds = end.Sub(*summary.StartedAt).Seconds()
+	}
+	return summary
+}
+
+// jobOutcome returns the LLM provider that answered a job and the category
+// of its failure, if it failed. Jobs whose parse failed still complete, with
+// the error in their result.
+func jobOutcome(job *ParseJob) (provider, errorCategory string) {
+	if result := job.Result; result != nil {
+		if result.ExtractionMetadata.Usage != nil {
+			provider = result.ExtractionMetadata.Usage.Provider
+		}
+		if !result.Success && result.Error != nil {
+			errorCategory = result.Error.Category
+		}
+	}
+	if job.Status == "failed" && errorCategory == "" {
+		errorCategory = string(zhcperrors.ErrorCategoryGeneral)
+		if job.Error == errUploadMissing {
+			errorCategory = string(zhcperrors.ErrorCategoryFile)
+		}
+	}
+	return provider, errorCategory
+}
+
+// utcTime converts an optional time to UTC
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
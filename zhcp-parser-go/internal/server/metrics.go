@@ -0,0 +1,109 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"zhcp-parser-go/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// jobStatuses are reported even when no job is in them, so alerts on
+// absent series are not needed
+var jobStatuses = []string{"queued", "processing", "completed", "failed", "cancelled"}
+
+// jobsCollector reports the queue depth, the worker pool and the jobs held
+// in memory by status at scrape time
+type jobsCollector struct {
+	server *Server
+
+	queueDepth       *prometheus.Desc
+	queueCapacity    *prometheus.Desc
+	queueUtilization *prometheus.Desc
+	workers          *prometheus.Desc
+	busyWorkers      *prometheus.Desc
+	maxWorkers       *prometheus.Desc
+	jobs             *prometheus.Desc
+}
+
+func newJobsCollector(s *Server) *jobsCollector {
+	return &jobsCollector{
+		server:           s,
+		queueDepth:       prometheus.NewDesc("zhcp_parse_queue_depth", "Parse jobs waiting for a worker.", nil, nil),
+		queueCapacity:    prometheus.NewDesc("zhcp_parse_queue_capacity", "Size of the parse job queue.", nil, nil),
+		queueUtilization: prometheus.NewDesc("zhcp_parse_queue_utilization", "Fraction of the parse job queue in use, from 0 to 1.", nil, nil),
+		workers:          prometheus.NewDesc("zhcp_parse_workers", "Parse workers running.", nil, nil),
+		busyWorkers:      prometheus.NewDesc("zhcp_parse_workers_busy", "Parse workers processing a job.", nil, nil),
+		maxWorkers:       prometheus.NewDesc("zhcp_parse_workers_max", "Upper bound of the parse worker pool.", nil, nil),
+		jobs:             prometheus.NewDesc("zhcp_parse_jobs", "Parse jobs known to the server by status.", []string{"status"}, nil),
+	}
+}
+
+func (c *jobsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueDepth
+	ch <- c.queueCapacity
+	ch <- c.queueUtilization
+	ch <- c.workers
+	ch <- c.busyWorkers
+	ch <- c.maxWorkers
+	ch <- c.jobs
+}
+
+func (c *jobsCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(len(c.server.queue)))
+	ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(cap(c.server.queue)))
+	utilization := 0.0
+	if capacity := cap(c.server.queue); capacity > 0 {
+		utilization = float64(len(c.server.queue)) / float64(capacity)
+	}
+	ch <- prometheus.MustNewConstMetric(c.queueUtilization, prometheus.GaugeValue, utilization)
+
+	workers, busy := c.server.workerStats()
+	ch <- prometheus.MustNewConstMetric(c.workers, prometheus.GaugeValue, float64(workers))
+	ch <- prometheus.MustNewConstMetric(c.busyWorkers, prometheus.GaugeValue, float64(busy))
+	ch <- prometheus.MustNewConstMetric(c.maxWorkers, prometheus.GaugeValue, float64(c.server.opts.MaxWorkers))
+
+	counts := make(map[string]int, len(jobStatuses))
+	c.server.jobsMu.RLock()
+	for _, job := range c.server.jobs {
+		if job != nil {
+			counts[job.Status]++
+		}
+	}
+	c.server.jobsMu.RUnlock()
+
+	for _, status := range jobStatuses {
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(counts[status]), status)
+	}
+}
+
+// observeJob records the outcome and duration of a finished job
+func observeJob(item queuedParseJob, status string, elapsed time.Duration) {
+	metrics.ParseJobsFinished.WithLabelValues(status).Inc()
+	metrics.ParseDuration.WithLabelValues(jobFormat(item), status).Observe(elapsed.Seconds())
+}
+
+// jobFormat is the document format label of a job: the upload's extension,
+// the raw text format or "documents" for several documents parsed together
+func jobFormat(item queuedParseJob) string {
+	if len(item.Documents) > 0 {
+		return "documents"
+	}
+	path := item.FilePath
+	if path == "" {
+		path = item.DocumentKey
+	}
+	if path == "" {
+		return item.Format
+	}
+	switch ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."); ext {
+	case "txt":
+		return "text"
+	case "md", "markdown":
+		return "markdown"
+	default:
+		return ext
+	}
+}
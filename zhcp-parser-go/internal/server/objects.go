@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/storage"
+)
+
+// objectTimeout bounds every object storage call; documents can be large,
+// so it is longer than storeTimeout
+const objectTimeout = 2 * time.Minute
+
+// Object keys of the document and the result of a job
+func documentKey(jobID, fileName string) string {
+	return "documents/" + jobID + strings.ToLower(filepath.Ext(fileName))
+}
+
+func resultKey(jobID string) string {
+	return "results/" + jobID + ".json"
+}
+
+// stageUpload moves an upload from the local disk to object storage, so a
+// worker on any node can parse it. Without object storage it stays on disk.
+func (s *Server) stageUpload(ctx context.Context, item *queuedParseJob) error {
+	if s.opts.Objects == nil {
+		return nil
+	}
+
+	key := documentKey(item.ID, item.FileName)
+	if err := s.putDocument(ctx, item.FilePath, key); err != nil {
+		return err
+	}
+
+	_ = os.Remove(item.FilePath)
+	item.FilePath = ""
+	item.DocumentKey = key
+	return nil
+}
+
+// putDocument uploads the document at path to object storage under key
+func (s *Server) putDocument(ctx context.Context, path, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, objectTimeout)
+	defer cancel()
+
+	return s.opts.Objects.PutObject(ctx, key, file)
+}
+
+// parseStoredDocument downloads a document kept in object storage into the
+// upload dir and parses it there
+func (s *Server) parseStoredDocument(ctx context.Context, item queuedParseJob, opts parser.ParseOptions) (*parser.ParseResult, error) {
+	local, err := s.fetchDocument(ctx, item.DocumentKey)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(local)
+
+	return s.parser.ParseDocumentWithOptions(ctx, local, opts)
+}
+
+// fetchDocument downloads a document kept in object storage into a file in
+// the upload dir, which the caller removes
+func (s *Server) fetchDocument(ctx context.Context, key string) (string, error) {
+	if s.opts.Objects == nil {
+		return "", fmt.Errorf("document %s is in object storage, which is not configured", key)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, objectTimeout)
+	defer cancel()
+
+	body, err := s.opts.Objects.GetObject(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", errors.New(errUploadMissing)
+	}
+	if err != nil {
+		return "", fmt.Errorf("fetch document: %w", err)
+	}
+	defer body.Close()
+
+	// The parsers pick the format by extension
+	local, err := os.CreateTemp(s.opts.UploadDir, "fetched-*"+strings.ToLower(filepath.Ext(key)))
+	if err != nil {
+		return "", fmt.Errorf("fetch document: %w", err)
+	}
+
+	_, err = io.Copy(local, body)
+	if closeErr := local.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(local.Name())
+		return "", fmt.Errorf("fetch document: %w", err)
+	}
+	return local.Name(), nil
+}
+
+// storeResult moves the result of a finished job snapshot to object storage
+// before the snapshot is saved. When the upload fails the result stays in
+// the snapshot, so it is kept in the database instead.
+func (s *Server) storeResult(stored *storage.ParseJob) {
+	if s.opts.Objects == nil || len(stored.Result) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectTimeout)
+	defer cancel()
+
+	key := resultKey(stored.ID)
+	if err := s.opts.Objects.PutObject(ctx, key, bytes.NewReader(stored.Result)); err != nil {
+		log.Printf("failed to store result of parse job %s: %v", stored.ID, err)
+		return
+	}
+	stored.Result = nil
+	stored.ResultKey = key
+}
+
+// loadResult reads the result of a job loaded from storage whose result is
+// kept in object storage
+func (s *Server) loadResult(ctx context.Context, job *ParseJob, key string) {
+	if s.opts.Objects == nil {
+		log.Printf("result of parse job %s is in object storage, which is not configured", job.ID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, objectTimeout)
+	defer cancel()
+
+	body, err := s.opts.Objects.GetObject(ctx, key)
+	if err != nil {
+		log.Printf("failed to load result of parse job %s: %v", job.ID, err)
+		return
+	}
+	defer body.Close()
+
+	var result parser.ParseResult
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		log.Printf("failed to decode result of parse job %s: %v", job.ID, err)
+		return
+	}
+	job.Result = &result
+}
+
+// removeUpload deletes the uploaded documents of a finished job
+func (s *Server) removeUpload(item queuedParseJob) {
+	if item.FilePath != "" {
+		_ = os.Remove(item.FilePath)
+	}
+	if item.DocumentKey != "" {
+		s.deleteObject(item.DocumentKey)
+	}
+	for _, document := range item.Documents {
+		if document.FilePath != "" {
+			_ = os.Remove(document.FilePath)
+		}
+		if document.DocumentKey != "" {
+			s.deleteObject(document.DocumentKey)
+		}
+	}
+}
+
+// removeResults deletes the results of expired jobs from object storage
+func (s *Server) removeResults(jobIDs []string) {
+	if s.opts.Objects == nil {
+		return
+	}
+	for _, jobID := range jobIDs {
+		s.deleteObject(resultKey(jobID))
+	}
+}
+
+func (s *Server) deleteObject(key string) {
+	if s.opts.Objects == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), objectTimeout)
+	defer cancel()
+
+	if err := s.opts.Objects.DeleteObject(ctx, key); err != nil {
+		log.Printf("failed to delete object %s: %v", key, err)
+	}
+}
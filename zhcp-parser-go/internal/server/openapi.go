@@ -0,0 +1,524 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zhcp-parser-go/internal/common"
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/storage"
+	"zhcp-parser-go/internal/transformers"
+)
+
+// openAPIVersion is the version of the API described by the spec
+const openAPIVersion = "1.0.0"
+
+// apiOperation describes one endpoint in the OpenAPI spec. Schemas are
+// generated from the Go types the handlers decode and encode, so the spec
+// follows their json tags.
+type apiOperation struct {
+	method  string
+	path    string // chi pattern, e.g. /api/parse/status/{jobId}
+	tag     string
+	summary string
+	scope   string // API key scope, empty for open endpoints
+
+	query []apiParam
+	form  []apiParam  // multipart form fields; a "file" field is the document
+	body  interface{} // JSON request body
+
+	status      int         // success status, 200 when zero
+	response    interface{} // JSON response body
+	list        bool        // response is {"items": [response], "total"}
+	contentType string      // non-JSON response, e.g. text/event-stream
+}
+
+// apiParam is a query parameter or form field
+type apiParam struct {
+	name        string
+	kind        string // string, integer, number, boolean or binary
+	description string
+	many        bool // may be repeated, e.g. several files
+}
+
+// messageResponse is the body of endpoints that only confirm an action
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// errorResponse is the body of every error answer
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// generationFields are the form fields that override the parse options
+var generationFields = []apiParam{
+	{name: "temperature", kind: "number", description: "LLM temperature, up to generation.max_temperature"},
+	{name: "max_tokens", kind: "integer", description: "LLM max tokens, up to generation.max_tokens_limit"},
+	{name: "provider", kind: "string", description: "call this enabled provider alone instead of the fallback chain"},
+	{name: "model", kind: "string", description: "model of provider: its configured one or one of generation.models"},
+	{name: "validate", kind: "boolean", description: "validate the result, default true"},
+	{name: "enrich", kind: "boolean", description: "enrich the result, default true"},
+}
+
+// uploadFields are the form fields of every parse upload
+var uploadFields = []apiParam{
+	{name: "callback_url", kind: "string", description: "notified with the result once the job finishes"},
+	{name: "force", kind: "boolean", description: "parse again even if the result is cached"},
+}
+
+// pageQuery are the query parameters of paged listings
+var pageQuery = []apiParam{
+	{name: "limit", kind: "integer", description: "page size, 1 to 200, default 50"},
+	{name: "offset", kind: "integer", description: "entries to skip"},
+}
+
+// retryFrom is the query parameter of retries and requeues
+var retryFrom = apiParam{name: "from", kind: "string", description: "llm or transformation; transformation when an LLM response was kept"}
+
+// apiOperations are the endpoints of the HTTP API, in the order of the routes
+var apiOperations = []apiOperation{
+	{method: "POST", path: "/api/parse/upload", tag: "parse", scope: common.ScopeParse,
+		summary: "Queue a document for parsing",
+		form: append(append([]apiParam{{name: "file", kind: "binary", description: "PDF, DOCX, XLSX, PPTX, TXT or MD document"}},
+			uploadFields...), generationFields...),
+		status: http.StatusAccepted, response: UploadResponse{}},
+	{method: "POST", path: "/api/parse/documents", tag: "parse", scope: common.ScopeParse,
+		summary: "Queue several documents to be parsed into one project",
+		form: append(append([]apiParam{
+			{name: "file", kind: "binary", description: "documents in order of precedence, at most 10", many: true},
+			{name: "conflicts", kind: "string", description: "later_wins (default) or manual"},
+		}, uploadFields...), generationFields...),
+		status: http.StatusAccepted, response: UploadResponse{}},
+	{method: "POST", path: "/api/parse/text", tag: "parse", scope: common.ScopeParse,
+		summary: "Queue raw text for parsing",
+		body:    ParseTextRequest{}, status: http.StatusAccepted, response: UploadResponse{}},
+	{method: "GET", path: "/api/parse/status/{jobId}", tag: "parse", scope: common.ScopeParse,
+		summary: "Get the status of a parse job", response: StatusResponse{}},
+	{method: "GET", path: "/api/parse/stream/{jobId}", tag: "parse", scope: common.ScopeParse,
+		summary: "Stream the progress of a parse job as server-sent events", contentType: "text/event-stream"},
+	{method: "GET", path: "/api/parse/result/{jobId}", tag: "parse", scope: common.ScopeParse,
+		summary:  "Get the result of a finished parse job",
+		query:    []apiParam{{name: "format", kind: "string", description: "json (default), msproject or csv"}},
+		response: parser.ParseResult{}},
+	{method: "GET", path: "/api/parse/jobs", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "List parse jobs, newest first",
+		query: append([]apiParam{
+			{name: "status", kind: "string", description: "queued, processing, completed, failed or cancelled"},
+			{name: "since", kind: "string", description: "RFC 3339 time or YYYY-MM-DD"},
+		}, pageQuery...),
+		response: JobListResponse{}},
+	{method: "DELETE", path: "/api/parse/jobs/{jobId}", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "Cancel a queued or running parse job", response: StatusResponse{}},
+	{method: "POST", path: "/api/parse/jobs/{jobId}/retry", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "Retry a failed parse job from its kept artifacts",
+		query:   []apiParam{retryFrom}, status: http.StatusAccepted, response: UploadResponse{}},
+	{method: "GET", path: "/api/parse/dead-letters", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "List parse jobs that failed for good", query: pageQuery, response: DeadLetterListResponse{}},
+	{method: "GET", path: "/api/parse/dead-letters/{jobId}", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "Get a dead letter with its job", response: storage.DeadLetter{}},
+	{method: "POST", path: "/api/parse/dead-letters/{jobId}/requeue", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "Queue the job of a dead letter again",
+		query:   []apiParam{retryFrom}, status: http.StatusAccepted, response: UploadResponse{}},
+	{method: "DELETE", path: "/api/parse/dead-letters/{jobId}", tag: "jobs", scope: common.ScopeAdmin,
+		summary: "Discard a dead letter", response: messageResponse{}},
+	{method: "POST", path: "/api/validate", tag: "parse", scope: common.ScopeParse,
+		summary: "Validate a project structure without an LLM call",
+		body:    transformers.ProjectStructure{}, response: ValidateResponse{}},
+	{method: "POST", path: "/api/extract/text", tag: "parse", scope: common.ScopeParse,
+		summary:  "Extract the plain text of a document without parsing it",
+		form:     []apiParam{{name: "file", kind: "binary", description: "PDF, DOCX, XLSX, PPTX, TXT or MD document"}},
+		response: ExtractTextResponse{}},
+	{method: "GET", path: "/api/usage", tag: "usage", scope: common.ScopeAdmin,
+		summary: "Report LLM token usage and cost",
+		query: []apiParam{
+			{name: "from", kind: "string", description: "RFC 3339 time or YYYY-MM-DD, default 30 days before to"},
+			{name: "to", kind: "string", description: "RFC 3339 time or YYYY-MM-DD, default now"},
+			{name: "group_by", kind: "string", description: "model (default) or day"},
+		},
+		response: UsageResponse{}},
+
+	{method: "GET", path: "/api/projects", tag: "projects", scope: common.ScopeProjects,
+		summary: "List projects", response: storage.Project{}, list: true},
+	{method: "GET", path: "/api/projects/{id}", tag: "projects", scope: common.ScopeProjects,
+		summary: "Get a project", response: storage.Project{}},
+	{method: "POST", path: "/api/projects", tag: "projects", scope: common.ScopeProjects,
+		summary: "Create a project", body: storage.Project{}, status: http.StatusCreated, response: storage.Project{}},
+	{method: "PUT", path: "/api/projects/{id}", tag: "projects", scope: common.ScopeProjects,
+		summary: "Update a project", body: storage.Project{}, response: storage.Project{}},
+	{method: "DELETE", path: "/api/projects/{id}", tag: "projects", scope: common.ScopeProjects,
+		summary: "Delete a project", response: messageResponse{}},
+	{method: "GET", path: "/api/projects/{projectId}/tasks", tag: "projects", scope: common.ScopeProjects,
+		summary: "List the tasks of a project", response: storage.Task{}, list: true},
+	{method: "GET", path: "/api/projects/{id}/diff/{jobId}", tag: "projects", scope: common.ScopeProjects,
+		summary: "Compare a project with a parse result", response: ProjectDiff{}},
+	{method: "GET", path: "/api/tasks/{id}", tag: "projects", scope: common.ScopeProjects,
+		summary: "Get a task", response: storage.Task{}},
+	{method: "PUT", path: "/api/tasks/{id}", tag: "projects", scope: common.ScopeProjects,
+		summary: "Update a task", body: storage.Task{}, response: storage.Task{}},
+	{method: "PUT", path: "/api/tasks/{id}/status", tag: "projects", scope: common.ScopeProjects,
+		summary: "Update the status of a task",
+		body: struct {
+			Status string `json:"status"`
+		}{},
+		response: struct {
+			Status string `json:"status"`
+		}{}},
+
+	{method: "GET", path: "/api/prompts", tag: "prompts", scope: common.ScopePrompts,
+		summary: "List the prompt templates in use", response: []PromptInfo{}},
+	{method: "POST", path: "/api/prompts/reload", tag: "prompts", scope: common.ScopePrompts,
+		summary: "Reload the prompt templates", response: []PromptInfo{}},
+	{method: "GET", path: "/api/prompts/{name}", tag: "prompts", scope: common.ScopePrompts,
+		summary: "Get a prompt template with its stored versions", response: PromptResponse{}},
+	{method: "DELETE", path: "/api/prompts/{name}", tag: "prompts", scope: common.ScopePrompts,
+		summary: "Delete the stored versions of a prompt template", response: messageResponse{}},
+	{method: "POST", path: "/api/prompts/{name}/versions", tag: "prompts", scope: common.ScopePrompts,
+		summary: "Store a new version of a prompt template",
+		body:    CreatePromptVersionRequest{}, status: http.StatusCreated, response: storage.PromptTemplate{}},
+	{method: "POST", path: "/api/prompts/{name}/versions/{version}/activate", tag: "prompts", scope: common.ScopePrompts,
+		summary: "Activate a stored version of a prompt template", response: storage.PromptTemplate{}},
+
+	{method: "GET", path: "/health", tag: "health", summary: "Liveness check", response: map[string]string{}},
+	{method: "GET", path: "/ready", tag: "health", summary: "Readiness of storage and LLM providers", response: ReadinessResponse{}},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+
+	pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// OpenAPISpec returns the OpenAPI 3 document of the HTTP API, with the
+// schemas of requests and responses, ParseResult among them
+func OpenAPISpec() map[string]interface{} {
+	schemas := &schemaBuilder{components: make(map[string]interface{}), names: make(map[string]reflect.Type)}
+	schemas.components["Error"] = schemas.inline(reflect.TypeOf(errorResponse{}))
+
+	paths := make(map[string]interface{})
+	for _, op := range apiOperations {
+		item, ok := paths[op.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = op.spec(schemas)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "ЖЦП Parser API",
+			"version":     openAPIVersion,
+			"description": "Parses construction project documents into project structures. Endpoints with a security requirement need an API key when auth is enabled.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": apiKeyHeader},
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// spec returns the OpenAPI operation object of op
+func (op apiOperation) spec(schemas *schemaBuilder) map[string]interface{} {
+	operation := map[string]interface{}{
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+		"operationId": operationID(op),
+	}
+
+	var parameters []interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, param := range op.query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": param.name, "in": "query", "description": param.description, "schema": param.schema(),
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	switch {
+	case op.body != nil:
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(op.body))},
+			},
+		}
+	case len(op.form) > 0:
+		properties := make(map[string]interface{})
+		for _, field := range op.form {
+			properties[field.name] = field.schema()
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object", "properties": properties, "required": []string{"file"}},
+				},
+			},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case op.contentType != "":
+		response["content"] = map[string]interface{}{
+			op.contentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	case op.response != nil:
+		schema := schemas.schema(reflect.TypeOf(op.response))
+		if op.list {
+			schema = map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"items": map[string]interface{}{"type": "array", "items": schema},
+					"total": map[string]interface{}{"type": "integer"},
+				},
+				"required": []string{"items", "total"},
+			}
+		}
+		content := map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+		if op.path == "/api/parse/result/{jobId}" {
+			content["application/xml"] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+			content["text/csv"] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+		response["content"] = content
+	}
+
+	operation["responses"] = map[string]interface{}{
+		strconv.Itoa(status): response,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+			},
+		},
+	}
+	if op.scope != "" {
+		operation["security"] = []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearer": []string{}},
+		}
+		operation["description"] = "Needs an API key with the " + op.scope + " scope when auth is enabled."
+	}
+	return operation
+}
+
+// schema returns the schema of a query parameter or form field
+func (p apiParam) schema() map[string]interface{} {
+	schema := map[string]interface{}{"type": p.kind}
+	if p.kind == "binary" {
+		schema = map[string]interface{}{"type": "string", "format": "binary"}
+	}
+	if p.description != "" {
+		schema["description"] = p.description
+	}
+	if p.many {
+		return map[string]interface{}{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+// operationID names an operation after its method and path, e.g.
+// getParseStatusByJobId, for the methods of generated clients
+func operationID(op apiOperation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.method))
+	for _, segment := range strings.Split(strings.TrimPrefix(op.path, "/api"), "/") {
+		if segment == "" {
+			continue
+		}
+		if match := pathParamPattern.FindStringSubmatch(segment); match != nil {
+			b.WriteString("By")
+			segment = match[1]
+		}
+		for _, word := range strings.Split(segment, "-") {
+			if word != "" {
+				b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+			}
+		}
+	}
+	return b.String()
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaBuilder turns Go types into JSON schemas. Named structs become
+// components referenced by $ref, anonymous ones are inlined.
+type schemaBuilder struct {
+	components map[string]interface{}
+	names      map[string]reflect.Type
+}
+
+// schema returns the schema of values of t as encoding/json writes them
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawType:
+		return map[string]interface{}{} // any JSON value
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.inline(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + b.component(t)}
+	default:
+		return map[string]interface{}{} // interfaces hold any JSON value
+	}
+}
+
+// component registers the schema of the named struct t and returns its
+// component name, prefixed with the package when two packages share a name
+func (b *schemaBuilder) component(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if known, ok := b.names[name]; ok && known != t {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	if _, ok := b.names[name]; ok {
+		return name
+	}
+
+	// Registered before its fields so recursive types end in a $ref
+	b.names[name] = t
+	b.components[name] = nil
+	b.components[name] = b.inline(t)
+	return name
+}
+
+// inline returns the object schema of the struct t, with the fields
+// encoding/json writes. Fields without omitempty are required.
+func (b *schemaBuilder) inline(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// handleOpenAPI serves the OpenAPI document, built once
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		var err error
+		openAPIJSON, err = json.MarshalIndent(OpenAPISpec(), "", "  ")
+		if err != nil {
+			panic(err) // the spec is built from static types only
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIJSON)
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at /openapi.json
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>ЖЦП Parser API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// handleSwaggerUI serves Swagger UI for the OpenAPI document, when enabled
+// with ServerOptions.SwaggerUI
+func (s *Server) handleSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(swaggerUIPage))
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"zhcp-parser-go/internal/parser"
+)
+
+// jobOptions are the parse options a client chose for a job. Unset fields
+// keep the defaults: validation and enrichment on, generation as configured.
+type jobOptions struct {
+	Validate   *bool             `json:"validate,omitempty"`
+	Enrich     *bool             `json:"enrich,omitempty"`
+	Generation parser.Generation `json:"generation"`
+}
+
+// isZero reports whether the options keep all defaults
+func (o jobOptions) isZero() bool {
+	return o.Validate == nil && o.Enrich == nil && o.Generation.IsZero()
+}
+
+// apply sets the options on the parse options of the job
+func (o jobOptions) apply(opts *parser.ParseOptions) {
+	opts.Validate = o.Validate == nil || *o.Validate
+	opts.Enrich = o.Enrich == nil || *o.Enrich
+	opts.Generation = o.Generation
+}
+
+// formJobOptions reads the options from the fields of an upload form:
+// temperature, max_tokens, provider, model, validate and enrich
+func formJobOptions(fields map[string]string) (jobOptions, error) {
+	var options jobOptions
+	if value := strings.TrimSpace(fields["temperature"]); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return options, fmt.Errorf("temperature must be a number")
+		}
+		options.Generation.Temperature = &temperature
+	}
+	if value := strings.TrimSpace(fields["max_tokens"]); value != "" {
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil || maxTokens <= 0 {
+			return options, fmt.Errorf("max_tokens must be a positive integer")
+		}
+		options.Generation.MaxTokens = maxTokens
+	}
+	options.Generation.Provider = strings.TrimSpace(fields["provider"])
+	options.Generation.Model = strings.TrimSpace(fields["model"])
+
+	flags := []struct {
+		name  string
+		value **bool
+	}{{"validate", &options.Validate}, {"enrich", &options.Enrich}}
+	for _, flag := range flags {
+		value := strings.TrimSpace(fields[flag.name])
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fmt.Errorf("%s must be true or false", flag.name)
+		}
+		*flag.value = &b
+	}
+	return options, nil
+}
+
+// uploadJobOptions reads the options of an upload form and holds them to
+// the bounds of the parser config
+func (s *Server) uploadJobOptions(fields map[string]string) (jobOptions, error) {
+	options, err := formJobOptions(fields)
+	if err != nil {
+		return options, err
+	}
+	return options, s.parser.CheckGeneration(options.Generation)
+}
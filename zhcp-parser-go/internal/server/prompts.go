@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"zhcp-parser-go/internal/ai/prompt_engineering"
+	"zhcp-parser-go/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// promptNamePattern keeps template names usable as prompts/ file names
+var promptNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// PromptInfo describes a template the parser currently uses
+type PromptInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Template    string   `json:"template,omitempty"`
+	Parameters  []string `json:"parameters,omitempty"`
+	Version     int      `json:"version"` // active stored version, 0 when read from prompts/
+	Source      string   `json:"source"`  // "file" or "database"
+}
+
+// PromptResponse is a template in use with all of its stored versions
+type PromptResponse struct {
+	PromptInfo
+	Versions []*storage.PromptTemplate `json:"versions"`
+}
+
+// CreatePromptVersionRequest is the body of POST /prompts/{name}/versions
+type CreatePromptVersionRequest struct {
+	Description string   `json:"description"`
+	Template    string   `json:"template"`
+	Parameters  []string `json:"parameters"`
+	Activate    *bool    `json:"activate,omitempty"` // defaults to true
+}
+
+// syncStoredPrompts makes the parser use the active stored version of every
+// template. Templates whose stored versions were all deleted fall back to
+// prompts/.
+func (s *Server) syncStoredPrompts(ctx context.Context) {
+	if s.store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	templates, err := s.store.ListActivePromptTemplates(ctx)
+	if err != nil {
+		log.Printf("failed to load stored prompt templates: %v", err)
+		return
+	}
+
+	prompts := s.parser.Prompts()
+	active := make(map[string]bool, len(templates))
+	for _, template := range templates {
+		active[template.Name] = true
+	}
+	for _, name := range prompts.ListPrompts() {
+		if prompts.StoredVersion(name) > 0 && !active[name] {
+			prompts.Reload()
+			break
+		}
+	}
+
+	for _, template := range templates {
+		if prompts.StoredVersion(template.Name) != template.Version {
+			prompts.SetStoredPrompt(template.Name, promptTemplate(template), template.Version)
+		}
+	}
+}
+
+// promptTemplate converts a stored template for the prompt manager
+func promptTemplate(template *storage.PromptTemplate) prompt_engineering.PromptTemplate {
+	return prompt_engineering.PromptTemplate{
+		Name:        template.Name,
+		Description: template.Description,
+		Template:    template.Template,
+		Parameters:  template.Parameters,
+	}
+}
+
+// promptInfo describes the template the parser uses under name
+func (s *Server) promptInfo(name string, withTemplate bool) (PromptInfo, bool) {
+	prompts := s.parser.Prompts()
+	template, ok := prompts.GetPromptTemplate(name)
+	if !ok {
+		return PromptInfo{}, false
+	}
+
+	info := PromptInfo{
+		Name:        name,
+		Description: template.Description,
+		Parameters:  template.Parameters,
+		Version:     prompts.StoredVersion(name),
+		Source:      "file",
+	}
+	if info.Version > 0 {
+		info.Source = "database"
+	}
+	if withTemplate {
+		info.Template = template.Template
+	}
+	return info, true
+}
+
+func (s *Server) handleListPrompts(w http.ResponseWriter, r *http.Request) {
+	names := s.parser.Prompts().ListPrompts()
+	sort.Strings(names)
+
+	prompts := make([]PromptInfo, 0, len(names))
+	for _, name := range names {
+		if info, ok := s.promptInfo(name, false); ok {
+			prompts = append(prompts, info)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, prompts)
+}
+
+func (s *Server) handleGetPrompt(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	info, inUse := s.promptInfo(name, true)
+
+	response := PromptResponse{PromptInfo: info, Versions: []*storage.PromptTemplate{}}
+	if s.store != nil {
+		versions, err := s.store.ListPromptTemplateVersions(r.Context(), name)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to list prompt versions")
+			return
+		}
+		if versions != nil {
+			response.Versions = versions
+		}
+	}
+	if !inUse && len(response.Versions) == 0 {
+		writeError(w, http.StatusNotFound, "Prompt not found")
+		return
+	}
+	if !inUse {
+		response.Name = name
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleCreatePromptVersion stores a new version of a template and, unless
+// activate is false, switches the parser to it for the next parse
+func (s *Server) handleCreatePromptVersion(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if !promptNamePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, "Prompt name must be 1-64 lowercase letters, digits or underscores")
+		return
+	}
+
+	var req CreatePromptVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	template := &storage.PromptTemplate{
+		Name:        name,
+		Description: req.Description,
+		Template:    req.Template,
+		Parameters:  req.Parameters,
+		Active:      req.Activate == nil || *req.Activate,
+	}
+	if err := prompt_engineering.ValidateTemplate(promptTemplate(template)); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid template: "+err.Error())
+		return
+	}
+
+	if err := s.store.SavePromptTemplate(r.Context(), template); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to save prompt template")
+		return
+	}
+	if template.Active {
+		s.parser.Prompts().SetStoredPrompt(name, promptTemplate(template), template.Version)
+	}
+
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// handleActivatePromptVersion switches a template to an earlier or later
+// stored version
+func (s *Server) handleActivatePromptVersion(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || version <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid version")
+		return
+	}
+
+	if err := s.store.ActivatePromptTemplate(r.Context(), name, version); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Prompt version not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, "Failed to activate prompt version")
+		}
+		return
+	}
+
+	template, err := s.store.GetPromptTemplate(r.Context(), name, version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load prompt version")
+		return
+	}
+	s.parser.Prompts().SetStoredPrompt(name, promptTemplate(template), template.Version)
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// handleDeletePrompt removes the stored versions of a template; the parser
+// goes back to the prompts/ file of the same name, if there is one
+func (s *Server) handleDeletePrompt(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	deleted, err := s.store.DeletePromptTemplate(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to delete prompt template")
+		return
+	}
+	if deleted == 0 {
+		writeError(w, http.StatusNotFound, "Prompt has no stored versions")
+		return
+	}
+	s.syncStoredPrompts(r.Context())
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Prompt versions deleted"})
+}
+
+// handleReloadPrompts re-reads prompts/ and the employee pool, then applies
+// the active stored versions on top
+func (s *Server) handleReloadPrompts(w http.ResponseWriter, r *http.Request) {
+	s.parser.Prompts().Reload()
+	s.syncStoredPrompts(r.Context())
+	s.handleListPrompts(w, r)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+)
+
+// busyRetryAfter is suggested to clients at their concurrent job limit; jobs
+// take from seconds to minutes, so there is no exact time to give
+const busyRetryAfter = 30 * time.Second
+
+// jobClient is whoever submits parse jobs: an API key by name when auth is
+// enabled, a client IP otherwise. Uploads and raw text share its limits.
+type jobClient struct {
+	id                string
+	uploadsPerMinute  int // 0 = unlimited
+	maxConcurrentJobs int // queued and processing jobs, 0 = unlimited
+}
+
+// jobLimiter holds the upload rate of every client seen in the last minute
+type jobLimiter struct {
+	mu      sync.Mutex
+	uploads map[string]*rateLimiter
+}
+
+func newJobLimiter() *jobLimiter {
+	return &jobLimiter{uploads: make(map[string]*rateLimiter)}
+}
+
+// take counts a submission of client, or returns how long to wait until it
+// is allowed
+func (l *jobLimiter) take(client *jobClient) time.Duration {
+	if client.uploadsPerMinute <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	limiter, exists := l.uploads[client.id]
+	if !exists || limiter.capacity != float64(client.uploadsPerMinute) {
+		limiter = newRateLimiter(client.uploadsPerMinute)
+		l.uploads[client.id] = limiter
+	}
+	l.mu.Unlock()
+
+	return limiter.take()
+}
+
+// prune forgets clients whose bucket has refilled, so the map of client IPs
+// does not grow without bound
+func (l *jobLimiter) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for id, limiter := range l.uploads {
+		if limiter.idleSince(now) > time.Minute {
+			delete(l.uploads, id)
+		}
+	}
+}
+
+// clientFor returns the limits of the client holding key, or of addr when
+// auth is disabled
+func (s *Server) clientFor(key, addr string) *jobClient {
+	client := &jobClient{
+		id:                "ip:" + addr,
+		uploadsPerMinute:  s.opts.UploadsPerMinute,
+		maxConcurrentJobs: s.opts.MaxConcurrentJobs,
+	}
+	if s.auth == nil {
+		return client
+	}
+
+	// requireScope has already rejected unknown keys
+	apiClient := s.auth.client(key)
+	if apiClient == nil {
+		return client
+	}
+	client.id = "key:" + apiClient.name
+	if apiClient.uploadsPerMinute > 0 {
+		client.uploadsPerMinute = apiClient.uploadsPerMinute
+	}
+	if apiClient.maxConcurrentJobs > 0 {
+		client.maxConcurrentJobs = apiClient.maxConcurrentJobs
+	}
+	return client
+}
+
+// admitJob checks that client may submit another job. The concurrent job
+// limit is checked again when the job is registered, as other submissions
+// of the client may be under way.
+func (s *Server) admitJob(client *jobClient) *authError {
+	if client.maxConcurrentJobs > 0 {
+		s.jobsMu.RLock()
+		active := s.activeJobsLocked(client.id)
+		s.jobsMu.RUnlock()
+		if active >= client.maxConcurrentJobs {
+			return tooManyJobs()
+		}
+	}
+	if wait := s.jobLimits.take(client); wait > 0 {
+		return &authError{http.StatusTooManyRequests, codes.ResourceExhausted, "Upload rate limit exceeded", wait}
+	}
+	return nil
+}
+
+// activeJobsLocked counts the queued and processing jobs of a client; the
+// caller holds jobsMu. Jobs restored after a restart have no client.
+func (s *Server) activeJobsLocked(clientID string) int {
+	active := 0
+	for _, job := range s.jobs {
+		if job.source.client != nil && job.source.client.id == clientID && !jobFinished(job.Status) {
+			active++
+		}
+	}
+	return active
+}
+
+func tooManyJobs() *authError {
+	return &authError{http.StatusTooManyRequests, codes.ResourceExhausted, "Too many parse jobs in progress, try again later", busyRetryAfter}
+}
+
+type jobClientKey struct{}
+
+// limitJobs applies the per-client job limits to the endpoints that submit
+// parse jobs, before the request body is read
+func (s *Server) limitJobs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := s.clientFor(requestAPIKey(r), remoteIP(r.RemoteAddr))
+		if limitErr := s.admitJob(client); limitErr != nil {
+			limitErr.write(w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jobClientKey{}, client)))
+	})
+}
+
+// requestJobClient returns the client limitJobs found for r
+func requestJobClient(r *http.Request) *jobClient {
+	client, _ := r.Context().Value(jobClientKey{}).(*jobClient)
+	return client
+}
+
+// grpcJobClient applies the per-client job limits to a gRPC submission
+func (s *Server) grpcJobClient(ctx context.Context) (*jobClient, error) {
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = remoteIP(p.Addr.String())
+	}
+
+	client := s.clientFor(grpcAPIKey(ctx), addr)
+	if limitErr := s.admitJob(client); limitErr != nil {
+		return nil, limitErr.grpcError()
+	}
+	return client, nil
+}
+
+// remoteIP strips the port from an address; RealIP has already replaced
+// the address of proxied requests
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
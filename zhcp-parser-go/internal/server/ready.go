@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"zhcp-parser-go/internal/ai"
+)
+
+// ReadinessCheck is the outcome of probing a single dependency
+type ReadinessCheck struct {
+	Status    string `json:"status"` // ok, error or disabled
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReadinessResponse is returned by /ready. The server is ready when storage
+// and object storage, if configured, answer and at least one LLM provider
+// does; failed providers beyond that only make it "degraded".
+type ReadinessResponse struct {
+	Status     string              `json:"status"` // ready, degraded or not_ready
+	Workers    int                 `json:"workers"`
+	QueueSize  int                 `json:"queue_size"`
+	QueueDepth int                 `json:"queue_depth"`
+	Storage    ReadinessCheck      `json:"storage"`
+	Objects    ReadinessCheck      `json:"object_storage"`
+	Providers  []ai.ProviderHealth `json:"providers"`
+	CheckedAt  time.Time           `json:"checked_at"`
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.parser == nil {
+		writeError(w, http.StatusServiceUnavailable, "parser not initialized")
+		return
+	}
+
+	readiness := s.checkReadiness(r.Context())
+	readiness.QueueDepth = len(s.queue)
+
+	code := http.StatusOK
+	if readiness.Status == "not_ready" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, readiness)
+}
+
+// checkReadiness probes storage, object storage and the providers, or returns the previous
+// result while it is fresh so frequent probes don't hit provider APIs.
+// Concurrent callers wait for a single probe.
+func (s *Server) checkReadiness(ctx context.Context) ReadinessResponse {
+	s.readinessMu.Lock()
+	defer s.readinessMu.Unlock()
+
+	if s.readiness != nil && time.Since(s.readiness.CheckedAt) < s.opts.ReadyCacheTTL {
+		return *s.readiness
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.ReadyTimeout)
+	defer cancel()
+
+	readiness := ReadinessResponse{
+		Workers:   s.opts.Workers,
+		QueueSize: cap(s.queue),
+		Storage:   s.checkStorage(ctx),
+		Objects:   s.checkObjects(ctx),
+		Providers: s.parser.CheckProviders(ctx),
+		CheckedAt: time.Now().UTC(),
+	}
+	if readiness.Providers == nil {
+		readiness.Providers = []ai.ProviderHealth{}
+	}
+
+	failed := 0
+	for _, provider := range readiness.Providers {
+		if provider.Status == "error" {
+			failed++
+		}
+	}
+	switch {
+	case readiness.Storage.Status == "error" || readiness.Objects.Status == "error":
+		readiness.Status = "not_ready"
+	case len(readiness.Providers) == 0 || failed == len(readiness.Providers):
+		readiness.Status = "not_ready"
+	case failed > 0:
+		readiness.Status = "degraded"
+	default:
+		readiness.Status = "ready"
+	}
+
+	// A cancelled request says nothing about the dependencies
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		s.readiness = &readiness
+	}
+	return readiness
+}
+
+func (s *Server) checkStorage(ctx context.Context) ReadinessCheck {
+	if s.store == nil {
+		return ReadinessCheck{Status: "disabled"}
+	}
+
+	started := time.Now()
+	err := s.store.Ping(ctx)
+	check := ReadinessCheck{Status: "ok", LatencyMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		check.Status = "error"
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (s *Server) checkObjects(ctx context.Context) ReadinessCheck {
+	if s.opts.Objects == nil {
+		return ReadinessCheck{Status: "disabled"}
+	}
+
+	started := time.Now()
+	err := s.opts.Objects.Ping(ctx)
+	check := ReadinessCheck{Status: "ok", LatencyMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		check.Status = "error"
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -5,15 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"zhcp-parser-go/internal/common"
+	"zhcp-parser-go/internal/metrics"
 	"zhcp-parser-go/internal/parser"
 	"zhcp-parser-go/internal/storage"
 
@@ -25,7 +27,8 @@ import (
 
 type ServerOptions struct {
 	AllowedOrigins    []string
-	Workers           int
+	Workers           int // minimum worker pool
+	MaxWorkers        int // pool grows up to this while jobs wait; Workers when lower
 	QueueSize         int
 	JobTTL            time.Duration
 	ReadTimeout       time.Duration
@@ -33,6 +36,35 @@ type ServerOptions struct {
 	WriteTimeout      time.Duration
 	IdleTimeout       time.Duration
 	ShutdownTimeout   time.Duration
+	UploadDir         string
+	GRPCPort          string        // gRPC API is disabled when empty
+	ReadyTimeout      time.Duration // bound on each /ready dependency probe
+	ReadyCacheTTL     time.Duration // how long /ready reuses probe results
+	ResultCacheTTL    time.Duration // parse results are cached in storage when set
+	MaxUploadBytes    int64         // largest document accepted by uploads, HTTP and gRPC
+	UploadTimeout     time.Duration // deadline of HTTP uploads, in place of the read, write and 60s route timeouts
+	SwaggerUI         bool          // serve Swagger UI for /openapi.json at /docs
+
+	// Per-client limits on parse job submissions, by API key or by client IP
+	// when auth is disabled; 0 = unlimited. API keys may set their own.
+	UploadsPerMinute  int
+	MaxConcurrentJobs int
+
+	// Documents and results are kept in object storage (e.g. S3) when set,
+	// on the local disk and in the database otherwise
+	Objects storage.ObjectStore
+
+	// API keys; every /api route and gRPC call is open when auth is disabled
+	Auth common.AuthConfig
+
+	// Webhook delivery for jobs submitted with a callback_url
+	WebhookSecret      string // HMAC-SHA256 key for X-Zhcp-Signature; callback_url is refused when empty
+	WebhookMaxAttempts int
+	WebhookTimeout     time.Duration
+
+	// Internal hosts callbacks may reach anyway, such as the TM backend on
+	// the private network: host names, IP addresses or CIDR ranges
+	WebhookAllowedHosts []string
 }
 
 type Server struct {
@@ -42,27 +74,116 @@ type Server struct {
 	jobs   map[string]*ParseJob
 	jobsMu sync.RWMutex
 
-	opts ServerOptions
+	// Cancels the parse of each processing job, guarded by jobsMu
+	cancels map[string]context.CancelFunc
+
+	opts      ServerOptions
+	auth      *authenticator
+	jobLimits *jobLimiter
 
 	queue     chan queuedParseJob
 	stopCh    chan struct{}
 	workersWG sync.WaitGroup
+
+	// Size of the worker pool and workers parsing a job, guarded by poolMu
+	poolMu      sync.Mutex
+	workerCount int
+	busyWorkers int
+
 	cleanupWG sync.WaitGroup
+	webhookWG sync.WaitGroup
+
+	webhookClient *http.Client
+	callbackHosts callbackAllowlist
+
+	// Progress stream subscribers per job
+	subscribers   map[string]map[chan struct{}]struct{}
+	subscribersMu sync.Mutex
+	streamsDone   chan struct{}
+	streamsOnce   sync.Once
+
+	// Last /ready probe, reused for ReadyCacheTTL
+	readiness   *ReadinessResponse
+	readinessMu sync.Mutex
+}
+
+// supportedExtensions are the upload types the parser can handle
+var supportedExtensions = map[string]bool{
+	".pdf":      true,
+	".docx":     true,
+	".xlsx":     true,
+	".pptx":     true,
+	".txt":      true,
+	".md":       true,
+	".markdown": true,
+}
+
+// IsSupportedDocument reports whether the parser handles a file by its name
+func IsSupportedDocument(name string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(name))]
 }
 
+// maxParseTextBytes limits the body of /parse/text
+const maxParseTextBytes = 5 << 20
+
+// queuedParseJob is an uploaded file, documents uploaded together to
+// /parse/documents or raw text from /parse/text
 type queuedParseJob struct {
 	ID       string
 	FilePath string
+	FileName string // name of the uploaded document as sent by the client
+	Text     string
+	Format   string
+
+	DocumentKey string // uploaded document in object storage, instead of FilePath
+
+	CallbackURL string // notified with the result once the job finishes
+	Force       bool   // parse again even if the result is cached
+
+	RetryFrom string // pipeline stage of a retry, which parses the job's artifacts instead
+
+	// Documents are parsed together into one project, in order, instead of
+	// FilePath or DocumentKey; Conflicts is the transformers.ConflictStrategy
+	// that merges them
+	Documents []jobDocument
+	Conflicts string
+
+	Options jobOptions // validate, enrich and generation overrides of the client
+
+	client   *jobClient // submitter, for the concurrent job limit; not persisted
+	queuedAt time.Time  // when the job entered the queue, for the wait time metric
 }
 
 type ParseJob struct {
 	ID        string              `json:"id"`
-	Status    string              `json:"status"` // queued, processing, completed, failed
+	Status    string              `json:"status"` // queued, processing, completed, failed, cancelled
 	Progress  int                 `json:"progress"`
+	Stage     string              `json:"stage,omitempty"`
 	Result    *parser.ParseResult `json:"result,omitempty"`
 	Error     string              `json:"error,omitempty"`
 	CreatedAt time.Time           `json:"created_at"`
 	UpdatedAt time.Time           `json:"updated_at"`
+
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	source    queuedParseJob    // input of the job, persisted for requeue
+	artifacts *parser.Artifacts // extracted text and LLM response of a failed attempt, for retries
+}
+
+type ParseTextRequest struct {
+	Text        string `json:"text"`
+	Format      string `json:"format"` // "text" (default) or "markdown"
+	CallbackURL string `json:"callback_url,omitempty"`
+	Force       bool   `json:"force,omitempty"` // skip the result cache
+
+	// Overrides of the parse options, within the bounds of the config
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Validate    *bool    `json:"validate,omitempty"`
+	Enrich      *bool    `json:"enrich,omitempty"`
 }
 
 type UploadResponse struct {
@@ -70,23 +191,43 @@ type UploadResponse struct {
 	Status string `json:"status"`
 }
 
+type ExtractTextResponse struct {
+	Text   string `json:"text"`
+	Format string `json:"format"`
+}
+
 type StatusResponse struct {
 	JobID    string `json:"jobId"`
 	Status   string `json:"status"`
+	Stage    string `json:"stage,omitempty"`
 	Progress int    `json:"progress"`
 	Error    string `json:"error,omitempty"`
 }
 
 func NewServer(parser *parser.ZhcpParser, store storage.Storage, port string, opts ServerOptions) *Server {
 	resolved := resolveOptions(opts)
+	callbackHosts := newCallbackAllowlist(resolved.WebhookAllowedHosts)
+	if store != nil && resolved.ResultCacheTTL > 0 {
+		parser.SetResultCache(&storedResultCache{store: store, ttl: resolved.ResultCacheTTL})
+	}
 	return &Server{
 		parser: parser,
 		store:  store,
 		port:   port,
 		jobs:   make(map[string]*ParseJob),
 		opts:   resolved,
-		queue:  make(chan queuedParseJob, resolved.QueueSize),
-		stopCh: make(chan struct{}),
+
+		cancels: make(map[string]context.CancelFunc),
+
+		auth:      newAuthenticator(resolved.Auth),
+		jobLimits: newJobLimiter(),
+		queue:     make(chan queuedParseJob, resolved.QueueSize),
+		stopCh:    make(chan struct{}),
+
+		webhookClient: newWebhookClient(resolved.WebhookTimeout, callbackHosts),
+		callbackHosts: callbackHosts,
+		subscribers:   make(map[string]map[chan struct{}]struct{}),
+		streamsDone:   make(chan struct{}),
 	}
 }
 
@@ -95,8 +236,20 @@ func (s *Server) Start(ctx context.Context) error {
 		ctx = context.Background()
 	}
 
+	if err := os.MkdirAll(s.opts.UploadDir, 0o755); err != nil {
+		return fmt.Errorf("create upload dir: %w", err)
+	}
+
+	collector := newJobsCollector(s)
+	if err := metrics.Registry.Register(collector); err != nil {
+		return fmt.Errorf("register metrics: %w", err)
+	}
+	defer metrics.Registry.Unregister(collector)
+
+	s.syncStoredPrompts(ctx)
 	s.startWorkers()
 	s.startCleanupLoop()
+	s.restoreJobs(ctx)
 
 	r := chi.NewRouter()
 
@@ -105,13 +258,12 @@ func (s *Server) Start(ctx context.Context) error {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS configuration for frontend
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   s.opts.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", apiKeyHeader},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -119,40 +271,100 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Routes
 	r.Route("/api", func(r chi.Router) {
-		// Parse endpoints
-		r.Post("/parse/upload", s.handleUpload)
-		r.Get("/parse/status/{jobId}", s.handleStatus)
-		r.Get("/parse/result/{jobId}", s.handleResult)
-
-		// Project endpoints
-		r.Get("/projects", s.handleListProjects)
-		r.Get("/projects/{id}", s.handleGetProject)
-		r.Post("/projects", s.handleCreateProject)
-		r.Put("/projects/{id}", s.handleUpdateProject)
-		r.Delete("/projects/{id}", s.handleDeleteProject)
-
-		// Task endpoints
-		r.Get("/projects/{projectId}/tasks", s.handleListTasks)
-		r.Get("/tasks/{id}", s.handleGetTask)
-		r.Put("/tasks/{id}", s.handleUpdateTask)
-		r.Put("/tasks/{id}/status", s.handleUpdateTaskStatus)
+		// Progress stream stays open for the whole job, so no request timeout
+		r.With(s.requireScope(common.ScopeParse)).Get("/parse/stream/{jobId}", s.handleStream)
+
+		// Document uploads, which may take longer than the other routes
+		r.Group(func(r chi.Router) {
+			r.Use(s.uploadDeadline)
+			r.Use(s.requireScope(common.ScopeParse))
+
+			r.With(s.limitJobs).Post("/parse/upload", s.handleUpload)
+			r.With(s.limitJobs).Post("/parse/documents", s.handleUploadDocuments)
+
+			// Plain text extraction for search indexing
+			r.Post("/extract/text", s.handleExtractText)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+			r.Use(s.requireScope(common.ScopeParse))
+
+			// Parse endpoints
+			r.With(s.limitJobs).Post("/parse/text", s.handleParseText)
+			r.Get("/parse/status/{jobId}", s.handleStatus)
+			r.Get("/parse/result/{jobId}", s.handleResult)
+
+			// Checks of a project structure edited by the client, no LLM
+			r.Post("/validate", s.handleValidate)
+		})
+
+		// Operator endpoints, which reach the jobs of every client
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+			r.Use(s.requireScope(common.ScopeAdmin))
+
+			r.Get("/parse/jobs", s.handleListJobs)
+			r.Delete("/parse/jobs/{jobId}", s.handleCancelJob)
+			r.With(s.limitJobs).Post("/parse/jobs/{jobId}/retry", s.handleRetryJob)
+
+			// Jobs that failed for good, kept past the job TTL
+			r.Get("/parse/dead-letters", s.handleListDeadLetters)
+			r.Get("/parse/dead-letters/{jobId}", s.handleGetDeadLetter)
+			r.With(s.limitJobs).Post("/parse/dead-letters/{jobId}/requeue", s.handleRequeueDeadLetter)
+			r.Delete("/parse/dead-letters/{jobId}", s.handleDeleteDeadLetter)
+
+			// LLM token usage and cost of parse jobs
+			r.Get("/usage", s.handleUsage)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+			r.Use(s.requireScope(common.ScopeProjects))
+
+			// Project endpoints
+			r.Get("/projects", s.handleListProjects)
+			r.Get("/projects/{id}", s.handleGetProject)
+			r.Post("/projects", s.handleCreateProject)
+			r.Put("/projects/{id}", s.handleUpdateProject)
+			r.Delete("/projects/{id}", s.handleDeleteProject)
+
+			// Task endpoints
+			r.Get("/projects/{projectId}/tasks", s.handleListTasks)
+			r.Get("/projects/{id}/diff/{jobId}", s.handleDiffProject)
+			r.Get("/tasks/{id}", s.handleGetTask)
+			r.Put("/tasks/{id}", s.handleUpdateTask)
+			r.Put("/tasks/{id}/status", s.handleUpdateTaskStatus)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+			r.Use(s.requireScope(common.ScopePrompts))
+
+			// Prompt template management
+			r.Get("/prompts", s.handleListPrompts)
+			r.Post("/prompts/reload", s.handleReloadPrompts)
+			r.Get("/prompts/{name}", s.handleGetPrompt)
+			r.Delete("/prompts/{name}", s.handleDeletePrompt)
+			r.Post("/prompts/{name}/versions", s.handleCreatePromptVersion)
+			r.Post("/prompts/{name}/versions/{version}/activate", s.handleActivatePromptVersion)
+		})
 	})
 
+	// Prometheus scrape endpoint, open like the health checks
+	r.Handle("/metrics", metrics.Handler())
+
 	// Health/readiness checks
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
-	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if s.parser == nil {
-			writeError(w, http.StatusServiceUnavailable, "parser not initialized")
-			return
-		}
-		writeJSON(w, http.StatusOK, map[string]any{
-			"status":     "ready",
-			"workers":    s.opts.Workers,
-			"queue_size": cap(s.queue),
-		})
-	})
+	r.Get("/ready", s.handleReady)
+
+	// OpenAPI document for generated clients, open like the health checks
+	r.Get("/openapi.json", s.handleOpenAPI)
+	if s.opts.SwaggerUI {
+		r.Get("/docs", s.handleSwaggerUI)
+	}
 
 	addr := ":" + s.port
 	httpServer := &http.Server{
@@ -164,7 +376,10 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:       s.opts.IdleTimeout,
 	}
 
-	errCh := make(chan error, 1)
+	// Long-lived progress streams end as soon as shutdown starts
+	httpServer.RegisterOnShutdown(s.stopStreams)
+
+	errCh := make(chan error, 2)
 	go func() {
 		log.Printf("server listening on %s", addr)
 		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -172,21 +387,38 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}()
 
+	grpcServer, err := s.startGRPC(errCh)
+	if err != nil {
+		_ = httpServer.Close()
+		close(s.stopCh)
+		s.workersWG.Wait()
+		s.cleanupWG.Wait()
+		return err
+	}
+
 	select {
 	case <-ctx.Done():
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
 		defer cancel()
+		s.stopGRPC(shutdownCtx, grpcServer)
 		if err := httpServer.Shutdown(shutdownCtx); err != nil {
 			return err
 		}
 		close(s.stopCh)
 		s.workersWG.Wait()
 		s.cleanupWG.Wait()
+		s.webhookWG.Wait()
 		return nil
 	case err := <-errCh:
+		s.stopStreams()
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		_ = httpServer.Close()
 		close(s.stopCh)
 		s.workersWG.Wait()
 		s.cleanupWG.Wait()
+		s.webhookWG.Wait()
 		return err
 	}
 }
@@ -196,98 +428,235 @@ func (s *Server) Start(ctx context.Context) error {
 // ============================================================================
 
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form
-	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32 MB max
-		writeError(w, http.StatusBadRequest, "Failed to parse form")
+	upload, uploadErr := s.receiveUploadForm(w, r, 1, s.createUpload)
+	if uploadErr != nil {
+		uploadErr.write(w)
 		return
 	}
+	file := upload.files[0]
 
-	file, header, err := r.FormFile("file")
+	callbackURL, err := s.parseCallbackURL(upload.fields["callback_url"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "No file provided")
+		upload.remove()
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer file.Close()
 
-	// Validate file type
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext != ".pdf" && ext != ".docx" {
-		writeError(w, http.StatusBadRequest, "Only PDF and DOCX files are supported")
+	options, err := s.uploadJobOptions(upload.fields)
+	if err != nil {
+		upload.remove()
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Create temp file
-	tempDir := os.TempDir()
-	tempFile := filepath.Join(tempDir, fmt.Sprintf("%s%s", uuid.New().String(), ext))
+	item := queuedParseJob{
+		ID:          uuid.New().String(),
+		FilePath:    file.path,
+		FileName:    file.fileName,
+		CallbackURL: callbackURL,
+		Force:       formBool(upload.fields["force"]),
+		Options:     options,
+		client:      requestJobClient(r),
+	}
+	if err := s.stageUpload(r.Context(), &item); err != nil {
+		upload.remove()
+		log.Printf("failed to store upload %s: %v", file.fileName, err)
+		writeError(w, http.StatusInternalServerError, "Failed to save file")
+		return
+	}
 
-	out, err := os.Create(tempFile)
+	s.enqueue(w, item)
+}
+
+// createUpload creates the file of an uploaded document. Uploads are kept
+// until the job finishes, so it can be requeued after a restart or parsed by
+// another node.
+func (s *Server) createUpload(ext string) (*os.File, error) {
+	return os.Create(filepath.Join(s.opts.UploadDir, fmt.Sprintf("%s%s", uuid.New().String(), ext)))
+}
+
+// handleParseText queues raw text (e.g. pasted from an email) for parsing.
+// There is nothing to extract, so the job goes straight to the LLM pipeline.
+func (s *Server) handleParseText(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxParseTextBytes)
+
+	var req ParseTextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, "Text is required")
+		return
+	}
+
+	format, ok := normalizeTextFormat(req.Format)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Format must be text or markdown")
+		return
+	}
+
+	callbackURL, err := s.parseCallbackURL(req.CallbackURL)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to create temp file")
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer out.Close()
 
-	if _, err := io.Copy(out, file); err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to save file")
+	options := jobOptions{
+		Validate: req.Validate,
+		Enrich:   req.Enrich,
+		Generation: parser.Generation{
+			Temperature: req.Temperature,
+			MaxTokens:   req.MaxTokens,
+			Provider:    strings.TrimSpace(req.Provider),
+			Model:       strings.TrimSpace(req.Model),
+		},
+	}
+	if err := s.parser.CheckGeneration(options.Generation); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.enqueue(w, queuedParseJob{Text: req.Text, Format: format, CallbackURL: callbackURL, Force: req.Force, Options: options, client: requestJobClient(r)})
+}
+
+// formBool reads a boolean form field such as force=true; anything
+// unparseable counts as false
+func formBool(value string) bool {
+	b, _ := strconv.ParseBool(strings.TrimSpace(value))
+	return b
+}
+
+// normalizeTextFormat maps the accepted spellings of a raw text format to
+// "text" or "markdown"
+func normalizeTextFormat(format string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text", "txt":
+		return "text", true
+	case "markdown", "md":
+		return "markdown", true
+	default:
+		return "", false
+	}
+}
+
+// Errors of submit: no worker can take the job, or its client already has
+// as many jobs in progress as allowed
+var (
+	errQueueFull   = errors.New("parser queue is full")
+	errTooManyJobs = errors.New("too many parse jobs in progress")
+)
+
+// enqueue creates a job for item and hands it to the workers, or answers 503
+// when the queue is full and 429 when the client is at its job limit
+func (s *Server) enqueue(w http.ResponseWriter, item queuedParseJob) {
+	jobID, err := s.submit(item)
+	if errors.Is(err, errTooManyJobs) {
+		tooManyJobs().write(w)
+		return
+	}
+	if err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(busyRetryAfter.Seconds())))
+		writeError(w, http.StatusServiceUnavailable, "Parser queue is full, try again later")
 		return
 	}
 
-	// Create job
-	jobID := uuid.New().String()
+	writeJSON(w, http.StatusAccepted, UploadResponse{
+		JobID:  jobID,
+		Status: "queued",
+	})
+}
+
+// submit registers a job for item and queues it, under item.ID when the
+// upload was already stored by ID. A job that does not fit in the queue or
+// over its client's job limit is dropped together with its upload.
+func (s *Server) submit(item queuedParseJob) (string, error) {
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	jobID := item.ID
 	job := &ParseJob{
 		ID:        jobID,
 		Status:    "queued",
 		Progress:  0,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		source:    item,
 	}
 
 	s.jobsMu.Lock()
+	if client := item.client; client != nil && client.maxConcurrentJobs > 0 && s.activeJobsLocked(client.id) >= client.maxConcurrentJobs {
+		s.jobsMu.Unlock()
+		s.removeUpload(item)
+		metrics.ParseJobsRejected.WithLabelValues("client_limit").Inc()
+		return "", errTooManyJobs
+	}
 	s.jobs[jobID] = job
+	stored := storedJob(job)
 	s.jobsMu.Unlock()
 
+	// Persist before queueing so a worker's update can't be overwritten
+	s.saveJob(stored)
+
+	item.queuedAt = time.Now()
 	select {
-	case s.queue <- queuedParseJob{ID: jobID, FilePath: tempFile}:
-		writeJSON(w, http.StatusAccepted, UploadResponse{
-			JobID:  jobID,
-			Status: "queued",
-		})
+	case s.queue <- item:
+		s.scaleWorkers()
+		return jobID, nil
 	default:
 		s.jobsMu.Lock()
 		delete(s.jobs, jobID)
 		s.jobsMu.Unlock()
-		_ = os.Remove(tempFile)
-		writeError(w, http.StatusServiceUnavailable, "Parser queue is full, try again later")
+		s.deleteStoredJob(jobID)
+		s.removeUpload(item)
+		metrics.ParseJobsRejected.WithLabelValues("queue_full").Inc()
+		workers, busy := s.workerStats()
+		log.Printf("rejected parse job: queue full (%d queued, %d of %d workers busy)", len(s.queue), busy, workers)
+		return "", errQueueFull
+	}
+}
+
+// handleExtractText synchronously returns the text of an uploaded document.
+// Unlike /parse/upload it skips the LLM pipeline, so callers can use it to
+// index documents.
+func (s *Server) handleExtractText(w http.ResponseWriter, r *http.Request) {
+	upload, uploadErr := s.receiveUploadForm(w, r, 1, func(ext string) (*os.File, error) {
+		return os.CreateTemp("", "zhcp-extract-*"+ext)
+	})
+	if uploadErr != nil {
+		uploadErr.write(w)
+		return
 	}
+	defer upload.remove()
+
+	text, format, err := s.parser.ExtractText(upload.files[0].path)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Failed to extract text: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ExtractTextResponse{
+		Text:   text,
+		Format: format,
+	})
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	jobID := chi.URLParam(r, "jobId")
 
-	s.jobsMu.RLock()
-	job, exists := s.jobs[jobID]
-	s.jobsMu.RUnlock()
-
+	job, exists := s.getJob(r.Context(), jobID)
 	if !exists {
 		writeError(w, http.StatusNotFound, "Job not found")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, StatusResponse{
-		JobID:    job.ID,
-		Status:   job.Status,
-		Progress: job.Progress,
-		Error:    job.Error,
-	})
+	writeJSON(w, http.StatusOK, statusResponse(job))
 }
 
 func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
 	jobID := chi.URLParam(r, "jobId")
 
-	s.jobsMu.RLock()
-	job, exists := s.jobs[jobID]
-	s.jobsMu.RUnlock()
-
+	job, exists := s.getJob(r.Context(), jobID)
 	if !exists {
 		writeError(w, http.StatusNotFound, "Job not found")
 		return
@@ -298,61 +667,118 @@ func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, job.Result)
-}
-
-func (s *Server) startWorkers() {
-	for i := 0; i < s.opts.Workers; i++ {
-		s.workersWG.Add(1)
-		go func(workerID int) {
-			defer s.workersWG.Done()
-			for {
-				select {
-				case <-s.stopCh:
-					return
-				case item := <-s.queue:
-					s.processFile(item.ID, item.FilePath)
-				}
-			}
-		}(i)
+	format := r.URL.Query().Get("format")
+	if format == "" || format == "json" {
+		writeJSON(w, http.StatusOK, job.Result)
+		return
 	}
+	writeExport(w, job, format)
 }
 
-func (s *Server) processFile(jobID, filePath string) {
-	defer os.Remove(filePath)
+func (s *Server) processFile(item queuedParseJob) {
+	jobID := item.ID
 
 	s.jobsMu.Lock()
 	job, exists := s.jobs[jobID]
-	if !exists {
+	if !exists || job.Status == "cancelled" {
 		s.jobsMu.Unlock()
+		s.removeUpload(item)
 		return
 	}
+	started := time.Now()
+	startedAt := started.UTC()
+	if !item.queuedAt.IsZero() {
+		metrics.ParseQueueWait.Observe(started.Sub(item.queuedAt).Seconds())
+	}
 	job.Status = "processing"
-	job.Progress = 10
-	job.UpdatedAt = time.Now().UTC()
+	job.Progress = 0
+	job.UpdatedAt = startedAt
+	job.StartedAt = &startedAt
+	stored := storedJob(job)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.cancels[jobID] = cancel
+	retryArtifacts := job.artifacts
 	s.jobsMu.Unlock()
-
-	result, err := s.parser.ParseDocument(filePath, true, true)
+	s.saveJob(stored)
+	s.notify(jobID)
+
+	opts := parser.ParseOptions{
+		Force: item.Force,
+		Progress: func(stage string, percent int) {
+			s.updateProgress(jobID, stage, percent)
+		},
+		Artifacts: &parser.Artifacts{},
+	}
+	item.Options.apply(&opts)
+
+	var (
+		result *parser.ParseResult
+		err    error
+	)
+	switch {
+	case item.RetryFrom != "" && retryArtifacts == nil:
+		err = errors.New(errArtifactsMissing)
+	case item.RetryFrom != "":
+		result, err = s.parser.Retry(ctx, retryArtifacts, item.RetryFrom, opts)
+	case len(item.Documents) > 0:
+		result, err = s.parseDocuments(ctx, item, opts)
+	case item.DocumentKey != "":
+		result, err = s.parseStoredDocument(ctx, item, opts)
+	case item.FilePath != "":
+		result, err = s.parser.ParseDocumentWithOptions(ctx, item.FilePath, opts)
+	default:
+		result, err = s.parser.ParseTextWithOptions(ctx, item.Text, item.Format, opts)
+	}
 
 	s.jobsMu.Lock()
-	defer s.jobsMu.Unlock()
-
+	delete(s.cancels, jobID)
 	job, exists = s.jobs[jobID]
-	if !exists {
+	// A cancelled job was already finished by handleCancelJob
+	if !exists || job.Status == "cancelled" {
+		s.jobsMu.Unlock()
+		s.removeUpload(item)
+		if exists {
+			observeJob(item, "cancelled", time.Since(started))
+		}
 		return
 	}
 	if err != nil {
 		job.Status = "failed"
 		job.Error = err.Error()
 		job.Progress = 0
-		job.UpdatedAt = time.Now().UTC()
-		return
+	} else {
+		job.Status = "completed"
+		job.Stage = stageDone
+		job.Progress = 100
+		job.Result = result
 	}
+	// Failures after extraction keep what the parse got to, so the job can
+	// be retried without the document
+	job.artifacts = nil
+	if opts.Artifacts.Text != "" && (err != nil || !result.Success) {
+		job.artifacts = opts.Artifacts
+	}
+	finishedAt := time.Now().UTC()
+	job.UpdatedAt = finishedAt
+	job.FinishedAt = &finishedAt
+	stored = storedJob(job)
+	finished := *job
+	s.jobsMu.Unlock()
 
-	job.Status = "completed"
-	job.Progress = 100
-	job.Result = result
-	job.UpdatedAt = time.Now().UTC()
+	// The upload is only dropped once the outcome is stored
+	s.storeResult(stored)
+	s.saveJob(stored)
+	if jobFailed(&finished) {
+		s.recordDeadLetter(finished)
+	} else if item.RetryFrom != "" {
+		s.removeDeadLetter(jobID)
+	}
+	s.recordUsage(jobID, finished.Result)
+	s.notify(jobID)
+	s.removeUpload(item)
+	observeJob(item, finished.Status, time.Since(started))
+	s.sendWebhook(finished)
 }
 
 func (s *Server) startCleanupLoop() {
@@ -368,19 +794,28 @@ func (s *Server) startCleanupLoop() {
 				return
 			case <-ticker.C:
 				now := time.Now().UTC()
+				s.deleteExpiredStoredJobs(now.Add(-s.opts.JobTTL))
+				s.deleteExpiredCachedResults(now)
+				s.syncStoredPrompts(context.Background())
+				s.jobLimits.prune(now)
+				var expired []string
 				s.jobsMu.Lock()
 				for id, job := range s.jobs {
 					if job == nil {
 						delete(s.jobs, id)
 						continue
 					}
-					if job.Status == "completed" || job.Status == "failed" {
+					if jobFinished(job.Status) {
 						if now.Sub(job.UpdatedAt) > s.opts.JobTTL {
 							delete(s.jobs, id)
+							if job.Result != nil {
+								expired = append(expired, id)
+							}
 						}
 					}
 				}
 				s.jobsMu.Unlock()
+				s.removeResults(expired)
 			}
 		}
 	}()
@@ -393,6 +828,9 @@ func resolveOptions(opts ServerOptions) ServerOptions {
 	if opts.Workers <= 0 {
 		opts.Workers = 4
 	}
+	if opts.MaxWorkers < opts.Workers {
+		opts.MaxWorkers = opts.Workers
+	}
 	if opts.QueueSize <= 0 {
 		opts.QueueSize = 64
 	}
@@ -414,6 +852,27 @@ func resolveOptions(opts ServerOptions) ServerOptions {
 	if opts.ShutdownTimeout <= 0 {
 		opts.ShutdownTimeout = 10 * time.Second
 	}
+	if opts.UploadDir == "" {
+		opts.UploadDir = filepath.Join(os.TempDir(), "zhcp-uploads")
+	}
+	if opts.MaxUploadBytes <= 0 {
+		opts.MaxUploadBytes = defaultMaxUploadBytes
+	}
+	if opts.UploadTimeout <= 0 {
+		opts.UploadTimeout = 5 * time.Minute
+	}
+	if opts.ReadyTimeout <= 0 {
+		opts.ReadyTimeout = 5 * time.Second
+	}
+	if opts.ReadyCacheTTL <= 0 {
+		opts.ReadyCacheTTL = 15 * time.Second
+	}
+	if opts.WebhookMaxAttempts <= 0 {
+		opts.WebhookMaxAttempts = 5
+	}
+	if opts.WebhookTimeout <= 0 {
+		opts.WebhookTimeout = 10 * time.Second
+	}
 	return opts
 }
 
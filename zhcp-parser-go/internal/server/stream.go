@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// stageDone is reported once a job has finished successfully
+const stageDone = "done"
+
+// streamHeartbeat keeps idle progress streams alive through proxies
+const streamHeartbeat = 15 * time.Second
+
+func statusResponse(job ParseJob) StatusResponse {
+	return StatusResponse{
+		JobID:    job.ID,
+		Status:   job.Status,
+		Stage:    job.Stage,
+		Progress: job.Progress,
+		Error:    job.Error,
+	}
+}
+
+// updateProgress records the stage a running job has reached and wakes up
+// its stream subscribers
+func (s *Server) updateProgress(jobID, stage string, progress int) {
+	s.jobsMu.Lock()
+	job, exists := s.jobs[jobID]
+	if !exists {
+		s.jobsMu.Unlock()
+		return
+	}
+	job.Stage = stage
+	job.Progress = progress
+	job.UpdatedAt = time.Now().UTC()
+	stored := storedJob(job)
+	s.jobsMu.Unlock()
+
+	s.saveJob(stored)
+	s.notify(jobID)
+}
+
+// subscribe registers for change notifications of a job. The channel holds
+// at most one pending signal, so a slow reader only sees the latest state.
+func (s *Server) subscribe(jobID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	s.subscribersMu.Lock()
+	if s.subscribers[jobID] == nil {
+		s.subscribers[jobID] = make(map[chan struct{}]struct{})
+	}
+	s.subscribers[jobID][ch] = struct{}{}
+	s.subscribersMu.Unlock()
+
+	return ch, func() {
+		s.subscribersMu.Lock()
+		delete(s.subscribers[jobID], ch)
+		if len(s.subscribers[jobID]) == 0 {
+			delete(s.subscribers, jobID)
+		}
+		s.subscribersMu.Unlock()
+	}
+}
+
+// stopStreams ends all open progress streams, so that shutdown does not wait
+// for jobs that may never finish
+func (s *Server) stopStreams() {
+	s.streamsOnce.Do(func() { close(s.streamsDone) })
+}
+
+func (s *Server) notify(jobID string) {
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+
+	for ch := range s.subscribers[jobID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// handleStream sends the progress of a job as server-sent events until the
+// job finishes: "progress" for every change, then "done", "failed" or
+// "cancelled"
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// Subscribe before the first read so no update is lost in between
+	updates, unsubscribe := s.subscribe(jobID)
+	defer unsubscribe()
+
+	job, exists := s.getJob(r.Context(), jobID)
+	if !exists {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	// The server write timeout would cut off long-running jobs
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("failed to clear write deadline for stream of job %s: %v", jobID, err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	var last StatusResponse
+	for {
+		status := statusResponse(job)
+		if status != last {
+			event := "progress"
+			switch job.Status {
+			case "completed":
+				event = "done"
+			case "failed":
+				event = "failed"
+			case "cancelled":
+				event = "cancelled"
+			}
+			if err := writeEvent(w, event, status); err != nil {
+				return
+			}
+			flusher.Flush()
+			if event != "progress" {
+				return
+			}
+			last = status
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.streamsDone:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-updates:
+		}
+
+		job, exists = s.getJob(r.Context(), jobID)
+		if !exists {
+			// Expired by the cleanup loop while streaming
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
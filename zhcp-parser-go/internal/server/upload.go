@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultMaxUploadBytes bounds an uploaded document when no limit is configured
+const defaultMaxUploadBytes = 32 << 20
+
+// Bounds on the form around the document: each text field, and all of the
+// fields, boundaries and part headers together
+const (
+	maxFormFieldBytes    = 64 << 10
+	maxFormOverheadBytes = 1 << 20
+)
+
+// uploadDeadline gives an upload request UploadTimeout from its start to
+// finish. The server's read and write timeouts, sized for JSON bodies, would
+// otherwise cut off a large document on a slow connection.
+func (s *Server) uploadDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(s.opts.UploadTimeout)
+		rc := http.NewResponseController(w)
+		// ErrNotSupported only outside a real connection, e.g. in tests
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// receivedUpload is the documents of a form streamed to disk, with the other
+// fields of the form
+type receivedUpload struct {
+	files  []receivedFile // removed by the caller once the documents are not needed
+	fields map[string]string
+}
+
+// receivedFile is an uploaded document on disk
+type receivedFile struct {
+	path     string
+	fileName string // name of the document as sent by the client
+}
+
+// remove deletes the documents from disk
+func (u *receivedUpload) remove() {
+	for _, file := range u.files {
+		_ = os.Remove(file.path)
+	}
+}
+
+// uploadError is a rejected upload, mapped to an HTTP status
+type uploadError struct {
+	status  int
+	message string
+	limit   int64 // upload limit in bytes, set for 413
+}
+
+// write answers the request with the error. Uploads over the limit report
+// the limit, so clients can tell users how large a document may be.
+func (e *uploadError) write(w http.ResponseWriter) {
+	if e.status == http.StatusRequestEntityTooLarge {
+		writeJSON(w, e.status, map[string]interface{}{"error": e.message, "limit_bytes": e.limit})
+		return
+	}
+	writeError(w, e.status, e.message)
+}
+
+// receiveUploadForm streams the "file" parts of a multipart form, at most
+// maxFiles, into files made by create, without buffering them in memory, and
+// collects the other fields. Documents over the configured limit are rejected
+// with 413 as soon as the limit is crossed.
+func (s *Server) receiveUploadForm(w http.ResponseWriter, r *http.Request, maxFiles int, create func(ext string) (*os.File, error)) (*receivedUpload, *uploadError) {
+	limit := s.opts.MaxUploadBytes
+	tooLarge := uploadTooLarge(limit)
+	bodyLimit := int64(maxFiles)*limit + maxFormOverheadBytes
+	if r.ContentLength > bodyLimit {
+		return nil, tooLarge
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, bodyLimit)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, &uploadError{status: http.StatusBadRequest, message: "Failed to parse form"}
+	}
+
+	upload := &receivedUpload{fields: make(map[string]string)}
+	fail := func(uploadErr *uploadError) (*receivedUpload, *uploadError) {
+		upload.remove()
+		return nil, uploadErr
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if isBodyTooLarge(err) {
+				return fail(tooLarge)
+			}
+			return fail(&uploadError{status: http.StatusBadRequest, message: "Failed to parse form"})
+		}
+
+		if part.FormName() != "file" || part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormFieldBytes+1))
+			if err != nil || len(value) > maxFormFieldBytes {
+				if isBodyTooLarge(err) {
+					return fail(tooLarge)
+				}
+				return fail(&uploadError{status: http.StatusBadRequest, message: fmt.Sprintf("Form field %s is too large", part.FormName())})
+			}
+			upload.fields[part.FormName()] = string(value)
+			continue
+		}
+		if len(upload.files) == maxFiles {
+			if maxFiles == 1 {
+				return fail(&uploadError{status: http.StatusBadRequest, message: "Only one file can be uploaded"})
+			}
+			return fail(&uploadError{status: http.StatusBadRequest, message: fmt.Sprintf("At most %d files can be uploaded", maxFiles)})
+		}
+
+		file := receivedFile{fileName: filepath.Base(part.FileName())}
+		uploadErr := file.save(part, limit, create)
+		if file.path != "" {
+			upload.files = append(upload.files, file)
+		}
+		if uploadErr != nil {
+			return fail(uploadErr)
+		}
+	}
+
+	if len(upload.files) == 0 {
+		return nil, &uploadError{status: http.StatusBadRequest, message: "No file provided"}
+	}
+	return upload, nil
+}
+
+// save copies the document to disk, reading at most one byte past limit
+func (u *receivedFile) save(part *multipart.Part, limit int64, create func(ext string) (*os.File, error)) *uploadError {
+	ext := strings.ToLower(filepath.Ext(u.fileName))
+	if !supportedExtensions[ext] {
+		return &uploadError{status: http.StatusBadRequest, message: "Only PDF, DOCX, XLSX, PPTX, TXT and MD files are supported"}
+	}
+
+	out, err := create(ext)
+	if err != nil {
+		return &uploadError{status: http.StatusInternalServerError, message: "Failed to create temp file"}
+	}
+	u.path = out.Name()
+
+	written, err := io.Copy(out, io.LimitReader(part, limit+1))
+	closeErr := out.Close()
+	switch {
+	case written > limit || isBodyTooLarge(err):
+		return uploadTooLarge(limit)
+	case err != nil:
+		return &uploadError{status: http.StatusBadRequest, message: "Failed to read file"}
+	case closeErr != nil:
+		return &uploadError{status: http.StatusInternalServerError, message: "Failed to save file"}
+	case written == 0:
+		return &uploadError{status: http.StatusBadRequest, message: "No file provided"}
+	}
+	return nil
+}
+
+func uploadTooLarge(limit int64) *uploadError {
+	return &uploadError{
+		status:  http.StatusRequestEntityTooLarge,
+		message: fmt.Sprintf("File exceeds the upload limit of %s", formatUploadLimit(limit)),
+		limit:   limit,
+	}
+}
+
+// isBodyTooLarge reports whether err comes from the request body limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// formatUploadLimit renders a limit in whole megabytes where possible
+func formatUploadLimit(limit int64) string {
+	if limit >= 1<<20 && limit%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", limit>>20)
+	}
+	return fmt.Sprintf("%d bytes", limit)
+}
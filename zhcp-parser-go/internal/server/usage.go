@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"zhcp-parser-go/internal/parser"
+	"zhcp-parser-go/internal/storage"
+)
+
+// defaultUsagePeriod is reported when /usage is called without a range
+const defaultUsagePeriod = 30 * 24 * time.Hour
+
+// UsageResponse is the LLM usage of finished parse jobs in [From, To)
+type UsageResponse struct {
+	From    time.Time               `json:"from"`
+	To      time.Time               `json:"to"`
+	GroupBy string                  `json:"group_by"`
+	Total   storage.UsageSummary    `json:"total"`
+	Groups  []*storage.UsageSummary `json:"groups"`
+}
+
+// recordUsage keeps the LLM usage of a finished job for usage reports.
+// Cached results made no LLM call and are not recorded.
+func (s *Server) recordUsage(jobID string, result *parser.ParseResult) {
+	if s.store == nil || result == nil || result.ExtractionMetadata.Usage == nil {
+		return
+	}
+	usage := result.ExtractionMetadata.Usage
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	err := s.store.SaveLLMUsage(ctx, &storage.LLMUsage{
+		JobID:        jobID,
+		Provider:     usage.Provider,
+		Model:        usage.Model,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostUSD:      usage.CostUSD,
+	})
+	if err != nil {
+		log.Printf("failed to record LLM usage of parse job %s: %v", jobID, err)
+	}
+}
+
+// handleUsage reports token usage and cost by model or by day. from and to
+// are RFC 3339 times or dates; a date in to includes that whole day.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now().UTC()
+
+	to, err := parseUsageTime(query.Get("to"), now, true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid to, expected RFC 3339 time or YYYY-MM-DD")
+		return
+	}
+	from, err := parseUsageTime(query.Get("from"), to.Add(-defaultUsagePeriod), false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid from, expected RFC 3339 time or YYYY-MM-DD")
+		return
+	}
+	if !from.Before(to) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	groupBy := query.Get("group_by")
+	switch groupBy {
+	case "":
+		groupBy = "model"
+	case "model", "day":
+	default:
+		writeError(w, http.StatusBadRequest, "group_by must be model or day")
+		return
+	}
+
+	groups, err := s.store.SummarizeLLMUsage(r.Context(), storage.UsageFilter{From: from, To: to, GroupBy: groupBy})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := UsageResponse{From: from, To: to, GroupBy: groupBy, Groups: groups}
+	if response.Groups == nil {
+		response.Groups = []*storage.UsageSummary{}
+	}
+	for _, group := range groups {
+		response.Total.Jobs += group.Jobs
+		response.Total.InputTokens += group.InputTokens
+		response.Total.OutputTokens += group.OutputTokens
+		response.Total.CostUSD += group.CostUSD
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseUsageTime parses an RFC 3339 time or a date. For an end of range, a
+// date means the end of that day.
+func parseUsageTime(value string, fallback time.Time, end bool) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
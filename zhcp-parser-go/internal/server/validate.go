@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"zhcp-parser-go/internal/transformers"
+)
+
+// ValidateResponse is the outcome of validating a project structure. Issues
+// make it invalid, warnings and suggestions do not.
+type ValidateResponse struct {
+	Valid                bool     `json:"valid"`
+	Issues               []string `json:"issues"`
+	Warnings             []string `json:"warnings"`
+	Suggestions          []string `json:"suggestions"`
+	QualityScore         float64  `json:"quality_score"`
+	ConfidenceAdjustment float64  `json:"confidence_adjustment"` // what a parse would subtract from its confidence
+}
+
+// handleValidate validates a project structure sent by the client, e.g. a
+// parse result after manual edits in the frontend. It runs the same checks
+// as a parse but no LLM call, and answers 200 whether or not it is valid.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxParseTextBytes)
+
+	var structure transformers.ProjectStructure
+	if err := json.NewDecoder(r.Body).Decode(&structure); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	validation := s.parser.ValidateProject(&structure)
+	adjustment, _ := validation.ValidationStages["confidence_adjustment"].(float64)
+	writeJSON(w, http.StatusOK, ValidateResponse{
+		Valid:                validation.IsValid,
+		Issues:               validation.Issues,
+		Warnings:             validation.Warnings,
+		Suggestions:          validation.Suggestions,
+		QualityScore:         validation.QualityScore,
+		ConfidenceAdjustment: adjustment,
+	})
+}
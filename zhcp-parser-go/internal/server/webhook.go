@@ -0,0 +1,288 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"zhcp-parser-go/internal/parser"
+)
+
+// webhookBaseDelay is the wait before the first retry; it doubles per attempt
+const webhookBaseDelay = 2 * time.Second
+
+// WebhookPayload is POSTed to the callback_url of a job once it completes,
+// fails or is cancelled. The body is signed with the webhook secret:
+// X-Zhcp-Timestamp is the Unix time of the attempt and X-Zhcp-Signature is
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>", so a
+// captured delivery cannot be replayed later with a fresh timestamp.
+type WebhookPayload struct {
+	Event      string              `json:"event"` // parse.completed, parse.failed or parse.cancelled
+	JobID      string              `json:"jobId"`
+	Status     string              `json:"status"`
+	Result     *parser.ParseResult `json:"result,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	FinishedAt time.Time           `json:"finishedAt"`
+}
+
+// parseCallbackURL validates an optional callback_url; only absolute http(s)
+// URLs of public or allowlisted hosts are accepted, and only when deliveries
+// can be signed
+func (s *Server) parseCallbackURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if s.opts.WebhookSecret == "" {
+		return "", errors.New("callback_url is not available: PARSER_WEBHOOK_SECRET is not configured")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("callback_url must be an absolute http or https URL")
+	}
+	// names are checked again on every delivery, when they are resolved
+	host := strings.ToLower(parsed.Hostname())
+	addr, err := netip.ParseAddr(host)
+	if s.callbackHosts.allowsHost(host) {
+		return parsed.String(), nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || (err == nil && internalAddress(addr)) {
+		return "", fmt.Errorf("callback_url must point to a public address or a host in PARSER_WEBHOOK_ALLOWED_HOSTS")
+	}
+	return parsed.String(), nil
+}
+
+// sendWebhook delivers the outcome of a finished job in the background
+func (s *Server) sendWebhook(job ParseJob) {
+	if job.source.CallbackURL == "" {
+		return
+	}
+	if s.opts.WebhookSecret == "" {
+		// accepted before the secret was removed; never sent unsigned
+		log.Printf("webhook for parse job %s dropped: PARSER_WEBHOOK_SECRET is not configured", job.ID)
+		return
+	}
+
+	event := "parse.completed"
+	switch job.Status {
+	case "failed":
+		event = "parse.failed"
+	case "cancelled":
+		event = "parse.cancelled"
+	}
+	body, err := json.Marshal(WebhookPayload{
+		Event:      event,
+		JobID:      job.ID,
+		Status:     job.Status,
+		Result:     job.Result,
+		Error:      job.Error,
+		FinishedAt: job.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("failed to encode webhook for parse job %s: %v", job.ID, err)
+		return
+	}
+
+	s.webhookWG.Add(1)
+	go func() {
+		defer s.webhookWG.Done()
+		s.deliverWebhook(job.ID, job.source.CallbackURL, event, body)
+	}()
+}
+
+// deliverWebhook POSTs body to callbackURL, retrying with exponential backoff
+// on network errors, 429 and 5xx responses. Other responses are final.
+func (s *Server) deliverWebhook(jobID, callbackURL, event string, body []byte) {
+	delay := webhookBaseDelay
+	for attempt := 1; attempt <= s.opts.WebhookMaxAttempts; attempt++ {
+		retry, err := s.postWebhook(callbackURL, event, jobID, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == s.opts.WebhookMaxAttempts {
+			log.Printf("webhook for parse job %s gave up after %d attempt(s): %v", jobID, attempt, err)
+			return
+		}
+		log.Printf("webhook for parse job %s failed (attempt %d/%d), retrying in %s: %v",
+			jobID, attempt, s.opts.WebhookMaxAttempts, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-s.stopCh:
+			log.Printf("webhook for parse job %s abandoned on shutdown", jobID)
+			return
+		}
+		delay *= 2
+	}
+}
+
+// postWebhook makes a single delivery attempt and reports whether a failure
+// is worth retrying
+func (s *Server) postWebhook(callbackURL, event, jobID string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "zhcp-parser-webhook")
+	req.Header.Set("X-Zhcp-Event", event)
+	req.Header.Set("X-Zhcp-Job-Id", jobID)
+	req.Header.Set("X-Zhcp-Timestamp", timestamp)
+	req.Header.Set("X-Zhcp-Signature", signWebhook(s.opts.WebhookSecret, timestamp, body))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("callback returned %d", resp.StatusCode)
+}
+
+// signWebhook returns the X-Zhcp-Signature value for body sent at timestamp
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// errInternalAddress is returned for a callback on the private network the
+// parser runs in
+var errInternalAddress = errors.New("callback_url resolves to an internal address")
+
+// sharedAddressSpace is the carrier-grade NAT range, which cloud providers
+// use for internal services too
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// internalAddress reports whether addr belongs to the host or the network
+// around it rather than the internet
+func internalAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// callbackAllowlist is PARSER_WEBHOOK_ALLOWED_HOSTS: the internal hosts a
+// callback may reach, named or given as addresses and CIDR ranges
+type callbackAllowlist struct {
+	names    map[string]bool
+	prefixes []netip.Prefix
+}
+
+func newCallbackAllowlist(entries []string) callbackAllowlist {
+	list := callbackAllowlist{names: make(map[string]bool)}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			list.prefixes = append(list.prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			list.prefixes = append(list.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else if entry != "" {
+			list.names[entry] = true
+		}
+	}
+	return list
+}
+
+// allowsHost reports whether the host of a callback_url is allowlisted,
+// by name or by address
+func (l callbackAllowlist) allowsHost(host string) bool {
+	host = strings.ToLower(host)
+	if l.names[host] {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && l.allowsAddr(addr)
+}
+
+func (l callbackAllowlist) allowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range l.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardDial refuses connections to internal addresses outside the
+// allowlisted ranges. It runs on the address actually dialled, after DNS
+// resolution, so a callback host that resolves to an internal address by the
+// time the job finishes is refused too.
+func (l callbackAllowlist) guardDial(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", address, err)
+	}
+	if internalAddress(addrPort.Addr()) && !l.allowsAddr(addrPort.Addr()) {
+		return errInternalAddress
+	}
+	return nil
+}
+
+// newWebhookClient returns the client callbacks are sent with: it reaches
+// only public and allowlisted addresses, goes through no proxy and follows
+// no redirects. Hosts allowlisted by name are dialled wherever they resolve.
+func newWebhookClient(timeout time.Duration, allowed callbackAllowlist) *http.Client {
+	guarded := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   allowed.guardDial,
+	}
+	trusted := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && allowed.names[strings.ToLower(host)] {
+			return trusted.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dial,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCallbackAllowlistAllowsHost(t *testing.T) {
+	l := newCallbackAllowlist([]string{"Backend", "10.0.3.7", "172.20.0.0/16", " "})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"backend", true},
+		{"BACKEND", true},
+		{"backend.internal", false},
+		{"10.0.3.7", true},
+		{"10.0.3.8", false},
+		{"172.20.9.1", true},
+		{"172.21.0.1", false},
+		{"::ffff:10.0.3.7", true},
+		{"localhost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := l.allowsHost(tt.host); got != tt.want {
+				t.Errorf("allowsHost(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackAllowlistGuardDial(t *testing.T) {
+	l := newCallbackAllowlist([]string{"backend", "10.0.0.0/8"})
+
+	tests := []struct {
+		address string
+		want    error
+	}{
+		{"93.184.216.34:443", nil},
+		{"10.0.3.7:8080", nil},
+		{"[::ffff:10.0.3.7]:8080", nil},
+		{"172.16.3.4:443", errInternalAddress},
+		{"192.168.1.1:443", errInternalAddress},
+		{"127.0.0.1:80", errInternalAddress},
+		{"169.254.169.254:80", errInternalAddress},
+		{"100.64.0.1:443", errInternalAddress},
+		{"[fd00::1]:443", errInternalAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := l.guardDial("tcp", tt.address, nil); !errors.Is(err, tt.want) {
+				t.Errorf("guardDial(%q) = %v, want %v", tt.address, err, tt.want)
+			}
+		})
+	}
+}
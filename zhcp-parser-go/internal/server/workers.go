@@ -0,0 +1,95 @@
+package server
+
+import (
+	"log"
+	"time"
+)
+
+// workerIdleTimeout is how long a worker above the minimum waits for a job
+// before it stops
+const workerIdleTimeout = 30 * time.Second
+
+// startWorkers starts the minimum pool; scaleWorkers adds workers up to
+// MaxWorkers while jobs wait in the queue
+func (s *Server) startWorkers() {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+
+	for i := 0; i < s.opts.Workers; i++ {
+		s.startWorkerLocked(false)
+	}
+}
+
+// scaleWorkers starts a worker when more jobs are queued than workers are
+// idle, unless the pool is at MaxWorkers
+func (s *Server) scaleWorkers() {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+
+	select {
+	case <-s.stopCh:
+		return
+	default:
+	}
+
+	idle := s.workerCount - s.busyWorkers
+	if len(s.queue) > idle && s.workerCount < s.opts.MaxWorkers {
+		s.startWorkerLocked(true)
+		log.Printf("parse backlog of %d jobs, scaled up to %d workers", len(s.queue), s.workerCount)
+	}
+}
+
+// startWorkerLocked starts a worker; the caller holds poolMu. Extra workers
+// stop after workerIdleTimeout without a job, down to the minimum pool.
+func (s *Server) startWorkerLocked(extra bool) {
+	s.workerCount++
+	s.workersWG.Add(1)
+	go func() {
+		defer s.workersWG.Done()
+
+		var idle <-chan time.Time
+		for {
+			if extra {
+				idle = time.After(workerIdleTimeout)
+			}
+			select {
+			case <-s.stopCh:
+				return
+			case item := <-s.queue:
+				s.setBusy(1)
+				s.processFile(item)
+				s.setBusy(-1)
+			case <-idle:
+				if s.stopIdleWorker() {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stopIdleWorker reports whether an idle extra worker may stop, and if so
+// removes it from the pool
+func (s *Server) stopIdleWorker() bool {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+
+	if s.workerCount <= s.opts.Workers {
+		return false
+	}
+	s.workerCount--
+	return true
+}
+
+func (s *Server) setBusy(delta int) {
+	s.poolMu.Lock()
+	s.busyWorkers += delta
+	s.poolMu.Unlock()
+}
+
+// workerStats returns the size of the pool and how many workers are parsing
+func (s *Server) workerStats() (workers, busy int) {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+	return s.workerCount, s.busyWorkers
+}
@@ -0,0 +1,1179 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"zhcp-parser-go/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// DefaultSchema keeps the parser's tables apart from the platform's, whose
+// projects table has the same name
+const DefaultSchema = "zhcp"
+
+var schemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+type PostgresStorage struct {
+	db          *sql.DB
+	databaseURL string
+	schema      string
+}
+
+// New returns a storage for the database at databaseURL (a postgres:// URL
+// or a key=value connection string). Tables live in schema, which Init
+// creates if needed.
+func New(databaseURL, schema string) *PostgresStorage {
+	if schema == "" {
+		schema = DefaultSchema
+	}
+	return &PostgresStorage{databaseURL: databaseURL, schema: schema}
+}
+
+func (s *PostgresStorage) Init(ctx context.Context) error {
+	if !schemaPattern.MatchString(s.schema) {
+		return fmt.Errorf("invalid schema name %q", s.schema)
+	}
+
+	config, err := pgx.ParseConfig(s.databaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid database URL: %w", err)
+	}
+	// Every connection resolves the unqualified table names in our schema
+	config.RuntimeParams["search_path"] = s.schema
+	s.db = stdlib.OpenDB(*config)
+
+	if _, err := s.db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+s.schema); err != nil {
+		return err
+	}
+
+	// Create tables
+	schema := `
+	CREATE TABLE IF NOT EXISTS projects (
+		id TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		description TEXT,
+		location TEXT,
+		start_date TIMESTAMPTZ,
+		end_date TIMESTAMPTZ,
+		budget DOUBLE PRECISION,
+		status TEXT NOT NULL DEFAULT 'planned',
+		metadata JSONB,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS tasks (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		description TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		priority TEXT,
+		assigned_to TEXT,
+		start_date TIMESTAMPTZ,
+		due_date TIMESTAMPTZ,
+		completed_at TIMESTAMPTZ,
+		dependencies JSONB,
+		metadata JSONB,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tasks_project_id ON tasks(project_id);
+	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+	CREATE INDEX IF NOT EXISTS idx_projects_status ON projects(status);
+
+	CREATE TABLE IF NOT EXISTS parse_jobs (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL,
+		progress INTEGER NOT NULL DEFAULT 0,
+		file_path TEXT,
+		input_text TEXT,
+		format TEXT,
+		callback_url TEXT,
+		force BOOLEAN NOT NULL DEFAULT FALSE,
+		result TEXT,
+		error TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		file_name TEXT,
+		provider TEXT,
+		error_category TEXT,
+		started_at TIMESTAMPTZ,
+		finished_at TIMESTAMPTZ,
+		document_key TEXT,
+		result_key TEXT,
+		retry_from TEXT,
+		artifacts TEXT,
+		documents TEXT,
+		conflicts TEXT,
+		options TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_parse_jobs_status ON parse_jobs(status, created_at);
+	CREATE INDEX IF NOT EXISTS idx_parse_jobs_created_at ON parse_jobs(created_at);
+
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		job_id TEXT PRIMARY KEY,
+		file_name TEXT,
+		error TEXT NOT NULL,
+		error_category TEXT,
+		stage TEXT,
+		provider TEXT,
+		attempts INTEGER NOT NULL DEFAULT 1,
+		job TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		failed_at TIMESTAMPTZ NOT NULL,
+		requeued_at TIMESTAMPTZ
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_dead_letters_failed_at ON dead_letters(failed_at);
+
+	CREATE TABLE IF NOT EXISTS parse_result_cache (
+		cache_key TEXT PRIMARY KEY,
+		result TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_parse_result_cache_expires_at ON parse_result_cache(expires_at);
+
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		job_id TEXT PRIMARY KEY,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		input_tokens INTEGER NOT NULL DEFAULT 0,
+		output_tokens INTEGER NOT NULL DEFAULT 0,
+		cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage(created_at);
+
+	CREATE TABLE IF NOT EXISTS prompt_templates (
+		name TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		description TEXT,
+		template TEXT NOT NULL,
+		parameters JSONB,
+		active BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (name, version)
+	);
+	`
+
+	_, err = s.db.ExecContext(ctx, schema)
+	return err
+}
+
+// Ping checks that the database answers queries
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+func (s *PostgresStorage) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// ============================================================================
+// Project Operations
+// ============================================================================
+
+func (s *PostgresStorage) SaveProject(ctx context.Context, project *storage.Project) error {
+	if project.ID == "" {
+		project.ID = uuid.New().String()
+	}
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = time.Now()
+	}
+	project.UpdatedAt = time.Now()
+
+	metadataJSON, _ := json.Marshal(project.Metadata)
+
+	query := `
+		INSERT INTO projects (id, title, description, location, start_date, end_date, budget, status, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		project.ID, project.Title, project.Description, project.Location,
+		project.StartDate, project.EndDate, project.Budget, project.Status,
+		string(metadataJSON), project.CreatedAt, project.UpdatedAt,
+	)
+	return err
+}
+
+func (s *PostgresStorage) GetProject(ctx context.Context, id string) (*storage.Project, error) {
+	query := `
+		SELECT id, title, description, location, start_date, end_date, budget, status, metadata, created_at, updated_at
+		FROM projects WHERE id = $1
+	`
+
+	project, err := scanProject(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *PostgresStorage) ListProjects(ctx context.Context) ([]*storage.Project, error) {
+	query := `
+		SELECT id, title, description, location, start_date, end_date, budget, status, metadata, created_at, updated_at
+		FROM projects ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*storage.Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (s *PostgresStorage) UpdateProject(ctx context.Context, project *storage.Project) error {
+	project.UpdatedAt = time.Now()
+	metadataJSON, _ := json.Marshal(project.Metadata)
+
+	query := `
+		UPDATE projects
+		SET title = $1, description = $2, location = $3, start_date = $4, end_date = $5,
+		    budget = $6, status = $7, metadata = $8, updated_at = $9
+		WHERE id = $10
+	`
+
+	result, err := s.db.ExecContext(ctx, query,
+		project.Title, project.Description, project.Location,
+		project.StartDate, project.EndDate, project.Budget, project.Status,
+		string(metadataJSON), project.UpdatedAt, project.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result)
+}
+
+func (s *PostgresStorage) DeleteProject(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM projects WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result)
+}
+
+// ============================================================================
+// Task Operations
+// ============================================================================
+
+func (s *PostgresStorage) SaveTask(ctx context.Context, task *storage.Task) error {
+	if task.ID == "" {
+		task.ID = uuid.New().String()
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	task.UpdatedAt = time.Now()
+
+	metadataJSON, _ := json.Marshal(task.Metadata)
+	dependenciesJSON, _ := json.Marshal(task.Dependencies)
+
+	query := `
+		INSERT INTO tasks (id, project_id, title, description, status, priority, assigned_to,
+		                   start_date, due_date, completed_at, dependencies, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		task.ID, task.ProjectID, task.Title, task.Description, task.Status, task.Priority,
+		task.AssignedTo, task.StartDate, task.DueDate, task.CompletedAt,
+		string(dependenciesJSON), string(metadataJSON), task.CreatedAt, task.UpdatedAt,
+	)
+	return err
+}
+
+func (s *PostgresStorage) GetTask(ctx context.Context, id string) (*storage.Task, error) {
+	query := `
+		SELECT id, project_id, title, description, status, priority, assigned_to,
+		       start_date, due_date, completed_at, dependencies, metadata, created_at, updated_at
+		FROM tasks WHERE id = $1
+	`
+
+	task, err := scanTask(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *PostgresStorage) ListTasks(ctx context.Context, projectID string) ([]*storage.Task, error) {
+	query := `
+		SELECT id, project_id, title, description, status, priority, assigned_to,
+		       start_date, due_date, completed_at, dependencies, metadata, created_at, updated_at
+		FROM tasks WHERE project_id = $1 ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*storage.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func (s *PostgresStorage) UpdateTask(ctx context.Context, task *storage.Task) error {
+	task.UpdatedAt = time.Now()
+	metadataJSON, _ := json.Marshal(task.Metadata)
+	dependenciesJSON, _ := json.Marshal(task.Dependencies)
+
+	query := `
+		UPDATE tasks
+		SET title = $1, description = $2, status = $3, priority = $4, assigned_to = $5,
+		    start_date = $6, due_date = $7, completed_at = $8, dependencies = $9, metadata = $10, updated_at = $11
+		WHERE id = $12
+	`
+
+	result, err := s.db.ExecContext(ctx, query,
+		task.Title, task.Description, task.Status, task.Priority, task.AssignedTo,
+		task.StartDate, task.DueDate, task.CompletedAt,
+		string(dependenciesJSON), string(metadataJSON), task.UpdatedAt, task.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result)
+}
+
+func (s *PostgresStorage) UpdateTaskStatus(ctx context.Context, id, status string) error {
+	var completedAt *time.Time
+	if status == "completed" {
+		now := time.Now()
+		completedAt = &now
+	}
+
+	query := `UPDATE tasks SET status = $1, completed_at = $2, updated_at = $3 WHERE id = $4`
+	result, err := s.db.ExecContext(ctx, query, status, completedAt, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result)
+}
+
+func (s *PostgresStorage) DeleteTask(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM tasks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result)
+}
+
+// ============================================================================
+// Parse Job Operations
+// ============================================================================
+
+// SaveParseJob inserts the job or overwrites its stored state
+func (s *PostgresStorage) SaveParseJob(ctx context.Context, job *storage.ParseJob) error {
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	if job.UpdatedAt.IsZero() {
+		job.UpdatedAt = time.Now()
+	}
+
+	var result, artifacts, documents, options sql.NullString
+	if len(job.Result) > 0 {
+		result = sql.NullString{String: string(job.Result), Valid: true}
+	}
+	if len(job.Artifacts) > 0 {
+		artifacts = sql.NullString{String: string(job.Artifacts), Valid: true}
+	}
+	if len(job.Documents) > 0 {
+		documents = sql.NullString{String: string(job.Documents), Valid: true}
+	}
+	if len(job.Options) > 0 {
+		options = sql.NullString{String: string(job.Options), Valid: true}
+	}
+
+	query := `
+		INSERT INTO parse_jobs (id, status, progress, file_path, input_text, format, callback_url, force, result, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, artifacts,
+			documents, conflicts, options)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			progress = excluded.progress,
+			file_path = excluded.file_path,
+			input_text = excluded.input_text,
+			format = excluded.format,
+			callback_url = excluded.callback_url,
+			force = excluded.force,
+			result = excluded.result,
+			error = excluded.error,
+			updated_at = excluded.updated_at,
+			file_name = excluded.file_name,
+			provider = excluded.provider,
+			error_category = excluded.error_category,
+			started_at = excluded.started_at,
+			finished_at = excluded.finished_at,
+			document_key = excluded.document_key,
+			result_key = excluded.result_key,
+			retry_from = excluded.retry_from,
+			artifacts = excluded.artifacts,
+			documents = excluded.documents,
+			conflicts = excluded.conflicts,
+			options = excluded.options
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		job.ID, job.Status, job.Progress, job.FilePath, job.Text, job.Format, job.CallbackURL,
+		job.Force, result, job.Error, job.CreatedAt, job.UpdatedAt,
+		job.FileName, job.Provider, job.ErrorCategory, job.StartedAt, job.FinishedAt, job.DocumentKey, job.ResultKey,
+		job.RetryFrom, artifacts, documents, job.Conflicts, options,
+	)
+	return err
+}
+
+func (s *PostgresStorage) GetParseJob(ctx context.Context, id string) (*storage.ParseJob, error) {
+	query := `
+		SELECT id, status, progress, file_path, input_text, format, callback_url, force, result, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, artifacts,
+			documents, conflicts, options
+		FROM parse_jobs WHERE id = $1
+	`
+
+	job, err := scanParseJob(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// ListIncompleteParseJobs returns queued and processing jobs, oldest first
+func (s *PostgresStorage) ListIncompleteParseJobs(ctx context.Context) ([]*storage.ParseJob, error) {
+	query := `
+		SELECT id, status, progress, file_path, input_text, format, callback_url, force, result, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, artifacts,
+			documents, conflicts, options
+		FROM parse_jobs WHERE status IN ('queued', 'processing') ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*storage.ParseJob
+	for rows.Next() {
+		job, err := scanParseJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (s *PostgresStorage) DeleteParseJob(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM parse_jobs WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return requireRow(result)
+}
+
+// ListParseJobs returns a page of the jobs matching filter, newest first,
+// and the number of matching jobs. The input text, result and artifacts are left out.
+func (s *PostgresStorage) ListParseJobs(ctx context.Context, filter storage.ParseJobFilter) ([]*storage.ParseJob, int, error) {
+	where := "WHERE TRUE"
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		where += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM parse_jobs "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	var limit interface{} // LIMIT NULL does not limit
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	query := fmt.Sprintf(`
+		SELECT id, status, progress, file_path, NULL, format, callback_url, force, NULL, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, NULL,
+			documents, conflicts, options
+		FROM parse_jobs %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var jobs []*storage.ParseJob
+	for rows.Next() {
+		job, err := scanParseJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+// DeleteFinishedParseJobs removes completed, failed and cancelled jobs last
+// updated before the given time
+func (s *PostgresStorage) DeleteFinishedParseJobs(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM parse_jobs WHERE status IN ('completed', 'failed', 'cancelled') AND updated_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ============================================================================
+// Dead Letter Operations
+// ============================================================================
+
+// SaveDeadLetter records a failed run of a job. A job already in the dead
+// letters has its failure replaced and its attempts counted up; either way
+// a pending requeue is cleared.
+func (s *PostgresStorage) SaveDeadLetter(ctx context.Context, letter *storage.DeadLetter) error {
+	if letter.FailedAt.IsZero() {
+		letter.FailedAt = time.Now()
+	}
+	if letter.CreatedAt.IsZero() {
+		letter.CreatedAt = letter.FailedAt
+	}
+
+	query := `
+		INSERT INTO dead_letters (job_id, file_name, error, error_category, stage, provider, attempts, job, created_at, failed_at, requeued_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 1, $7, $8, $9, NULL)
+		ON CONFLICT(job_id) DO UPDATE SET
+			file_name = excluded.file_name,
+			error = excluded.error,
+			error_category = excluded.error_category,
+			stage = excluded.stage,
+			provider = excluded.provider,
+			attempts = dead_letters.attempts + 1,
+			job = excluded.job,
+			failed_at = excluded.failed_at,
+			requeued_at = NULL
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		letter.JobID, letter.FileName, letter.Error, letter.ErrorCategory, letter.Stage, letter.Provider,
+		string(letter.Job), letter.CreatedAt.UTC(), letter.FailedAt.UTC(),
+	)
+	return err
+}
+
+// GetDeadLetter returns the dead letter of a job with the serialized job
+func (s *PostgresStorage) GetDeadLetter(ctx context.Context, jobID string) (*storage.DeadLetter, error) {
+	query := `
+		SELECT job_id, file_name, error, error_category, stage, provider, attempts, job, created_at, failed_at, requeued_at
+		FROM dead_letters WHERE job_id = $1
+	`
+
+	letter, err := scanDeadLetter(s.db.QueryRowContext(ctx, query, jobID))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// ListDeadLetters returns one page of dead letters, last failed first,
+// without the serialized jobs, and the number of dead letters
+func (s *PostgresStorage) ListDeadLetters(ctx context.Context, limit, offset int) ([]*storage.DeadLetter, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dead_letters").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	var pageLimit interface{}
+	if limit > 0 {
+		pageLimit = limit // NULL is no limit
+	}
+	query := `
+		SELECT job_id, file_name, error, error_category, stage, provider, attempts, NULL, created_at, failed_at, requeued_at
+		FROM dead_letters ORDER BY failed_at DESC LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pageLimit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var letters []*storage.DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, total, rows.Err()
+}
+
+// MarkDeadLetterRequeued records that the job of a dead letter was queued
+// again
+func (s *PostgresStorage) MarkDeadLetterRequeued(ctx context.Context, jobID string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE dead_letters SET requeued_at = $1 WHERE job_id = $2", at.UTC(), jobID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteDeadLetter removes the dead letter of a job
+func (s *PostgresStorage) DeleteDeadLetter(ctx context.Context, jobID string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM dead_letters WHERE job_id = $1", jobID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Parse Result Cache Operations
+// ============================================================================
+
+// GetCachedResult returns the result cached under key, or ErrNotFound when
+// there is none or it has expired
+func (s *PostgresStorage) GetCachedResult(ctx context.Context, key string) (json.RawMessage, error) {
+	var result string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT result FROM parse_result_cache WHERE cache_key = $1 AND expires_at > $2", key, time.Now(),
+	).Scan(&result)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(result), nil
+}
+
+// SaveCachedResult stores result under key, replacing any previous entry
+func (s *PostgresStorage) SaveCachedResult(ctx context.Context, key string, result json.RawMessage, expiresAt time.Time) error {
+	query := `
+		INSERT INTO parse_result_cache (cache_key, result, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cache_key) DO UPDATE SET
+			result = excluded.result,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query, key, string(result), time.Now(), expiresAt)
+	return err
+}
+
+// DeleteExpiredCachedResults removes cache entries that expired before now
+func (s *PostgresStorage) DeleteExpiredCachedResults(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM parse_result_cache WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ============================================================================
+// LLM Usage Operations
+// ============================================================================
+
+// SaveLLMUsage records the usage of a job, replacing an earlier record of
+// the same job
+func (s *PostgresStorage) SaveLLMUsage(ctx context.Context, usage *storage.LLMUsage) error {
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO llm_usage (job_id, provider, model, input_tokens, output_tokens, cost_usd, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (job_id) DO UPDATE SET
+			provider = excluded.provider,
+			model = excluded.model,
+			input_tokens = excluded.input_tokens,
+			output_tokens = excluded.output_tokens,
+			cost_usd = excluded.cost_usd,
+			created_at = excluded.created_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		usage.JobID, usage.Provider, usage.Model, usage.InputTokens, usage.OutputTokens,
+		usage.CostUSD, usage.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// SummarizeLLMUsage totals usage in the filter's time range by model or by
+// UTC day, most expensive model or earliest day first
+func (s *PostgresStorage) SummarizeLLMUsage(ctx context.Context, filter storage.UsageFilter) ([]*storage.UsageSummary, error) {
+	var query string
+	if filter.GroupBy == "day" {
+		query = `
+			SELECT to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, '', '', COUNT(*),
+				SUM(input_tokens), SUM(output_tokens), SUM(cost_usd)
+			FROM llm_usage WHERE created_at >= $1 AND created_at < $2
+			GROUP BY day ORDER BY day ASC
+		`
+	} else {
+		query = `
+			SELECT '', provider, model, COUNT(*),
+				SUM(input_tokens), SUM(output_tokens), SUM(cost_usd) AS cost
+			FROM llm_usage WHERE created_at >= $1 AND created_at < $2
+			GROUP BY provider, model ORDER BY cost DESC, provider, model
+		`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, filter.From.UTC(), filter.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*storage.UsageSummary
+	for rows.Next() {
+		var summary storage.UsageSummary
+		if err := rows.Scan(
+			&summary.Day, &summary.Provider, &summary.Model, &summary.Jobs,
+			&summary.InputTokens, &summary.OutputTokens, &summary.CostUSD,
+		); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ============================================================================
+// Prompt Template Operations
+// ============================================================================
+
+// SavePromptTemplate stores template as the next version of its name and
+// sets template.Version. An active template deactivates the other versions.
+func (s *PostgresStorage) SavePromptTemplate(ctx context.Context, template *storage.PromptTemplate) error {
+	parameters, err := json.Marshal(template.Parameters)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parameters: %w", err)
+	}
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = time.Now()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Concurrent saves of the same name wait here, so they get distinct versions
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "prompt_templates:"+template.Name); err != nil {
+		return err
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) + 1 FROM prompt_templates WHERE name = $1", template.Name,
+	).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	if template.Active {
+		if _, err := tx.ExecContext(ctx, "UPDATE prompt_templates SET active = FALSE WHERE name = $1", template.Name); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO prompt_templates (name, version, description, template, parameters, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = tx.ExecContext(ctx, query,
+		template.Name, version, template.Description, template.Template, string(parameters),
+		template.Active, template.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	template.Version = version
+	return nil
+}
+
+// GetPromptTemplate returns one version of a template
+func (s *PostgresStorage) GetPromptTemplate(ctx context.Context, name string, version int) (*storage.PromptTemplate, error) {
+	query := `
+		SELECT name, version, description, template, parameters, active, created_at
+		FROM prompt_templates WHERE name = $1 AND version = $2
+	`
+
+	template, err := scanPromptTemplate(s.db.QueryRowContext(ctx, query, name, version))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	return template, err
+}
+
+// ListActivePromptTemplates returns the active version of every template
+func (s *PostgresStorage) ListActivePromptTemplates(ctx context.Context) ([]*storage.PromptTemplate, error) {
+	query := `
+		SELECT name, version, description, template, parameters, active, created_at
+		FROM prompt_templates WHERE active ORDER BY name
+	`
+
+	return s.queryPromptTemplates(ctx, query)
+}
+
+// ListPromptTemplateVersions returns every version of a template, newest first
+func (s *PostgresStorage) ListPromptTemplateVersions(ctx context.Context, name string) ([]*storage.PromptTemplate, error) {
+	query := `
+		SELECT name, version, description, template, parameters, active, created_at
+		FROM prompt_templates WHERE name = $1 ORDER BY version DESC
+	`
+
+	return s.queryPromptTemplates(ctx, query, name)
+}
+
+// ActivatePromptTemplate makes version the active version of a template
+func (s *PostgresStorage) ActivatePromptTemplate(ctx context.Context, name string, version int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM prompt_templates WHERE name = $1 AND version = $2)", name, version,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE prompt_templates SET active = (version = $1) WHERE name = $2", version, name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeletePromptTemplate removes every version of a template and returns how
+// many there were
+func (s *PostgresStorage) DeletePromptTemplate(ctx context.Context, name string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM prompt_templates WHERE name = $1", name)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *PostgresStorage) queryPromptTemplates(ctx context.Context, query string, args ...interface{}) ([]*storage.PromptTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*storage.PromptTemplate
+	for rows.Next() {
+		template, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// requireRow turns an update or delete that matched nothing into ErrNotFound
+func requireRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProject(row rowScanner) (*storage.Project, error) {
+	var project storage.Project
+	var description, location, metadataJSON sql.NullString
+	var startDate, endDate sql.NullTime
+	var budget sql.NullFloat64
+
+	err := row.Scan(
+		&project.ID, &project.Title, &description, &location,
+		&startDate, &endDate, &budget, &project.Status,
+		&metadataJSON, &project.CreatedAt, &project.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	project.Description = description.String
+	project.Location = location.String
+	project.Budget = budget.Float64
+	if startDate.Valid {
+		project.StartDate = &startDate.Time
+	}
+	if endDate.Valid {
+		project.EndDate = &endDate.Time
+	}
+	if metadataJSON.Valid {
+		json.Unmarshal([]byte(metadataJSON.String), &project.Metadata)
+	}
+
+	return &project, nil
+}
+
+func scanTask(row rowScanner) (*storage.Task, error) {
+	var task storage.Task
+	var description, priority, assignedTo, metadataJSON, dependenciesJSON sql.NullString
+	var startDate, dueDate, completedAt sql.NullTime
+
+	err := row.Scan(
+		&task.ID, &task.ProjectID, &task.Title, &description, &task.Status,
+		&priority, &assignedTo, &startDate, &dueDate, &completedAt,
+		&dependenciesJSON, &metadataJSON, &task.CreatedAt, &task.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	task.Description = description.String
+	task.Priority = priority.String
+	task.AssignedTo = assignedTo.String
+	if startDate.Valid {
+		task.StartDate = &startDate.Time
+	}
+	if dueDate.Valid {
+		task.DueDate = &dueDate.Time
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+	if metadataJSON.Valid {
+		json.Unmarshal([]byte(metadataJSON.String), &task.Metadata)
+	}
+	if dependenciesJSON.Valid {
+		json.Unmarshal([]byte(dependenciesJSON.String), &task.Dependencies)
+	}
+
+	return &task, nil
+}
+
+func scanPromptTemplate(row rowScanner) (*storage.PromptTemplate, error) {
+	var template storage.PromptTemplate
+	var description, parameters sql.NullString
+
+	err := row.Scan(
+		&template.Name, &template.Version, &description, &template.Template,
+		&parameters, &template.Active, &template.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Description = description.String
+	if parameters.Valid && parameters.String != "" {
+		if err := json.Unmarshal([]byte(parameters.String), &template.Parameters); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
+		}
+	}
+
+	return &template, nil
+}
+
+func scanParseJob(row rowScanner) (*storage.ParseJob, error) {
+	var job storage.ParseJob
+	var filePath, text, format, callbackURL, result, errorMessage sql.NullString
+	var fileName, provider, errorCategory, documentKey, resultKey, retryFrom, artifacts sql.NullString
+	var documents, conflicts, options sql.NullString
+	var startedAt, finishedAt sql.NullTime
+
+	err := row.Scan(
+		&job.ID, &job.Status, &job.Progress, &filePath, &text, &format,
+		&callbackURL, &job.Force, &result, &errorMessage, &job.CreatedAt, &job.UpdatedAt,
+		&fileName, &provider, &errorCategory, &startedAt, &finishedAt, &documentKey, &resultKey,
+		&retryFrom, &artifacts, &documents, &conflicts, &options,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.FilePath = filePath.String
+	job.Text = text.String
+	job.Format = format.String
+	job.CallbackURL = callbackURL.String
+	job.Error = errorMessage.String
+	job.FileName = fileName.String
+	job.Provider = provider.String
+	job.ErrorCategory = errorCategory.String
+	job.DocumentKey = documentKey.String
+	job.ResultKey = resultKey.String
+	job.RetryFrom = retryFrom.String
+	job.Conflicts = conflicts.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if result.Valid && result.String != "" {
+		job.Result = json.RawMessage(result.String)
+	}
+	if artifacts.Valid && artifacts.String != "" {
+		job.Artifacts = json.RawMessage(artifacts.String)
+	}
+	if documents.Valid && documents.String != "" {
+		job.Documents = json.RawMessage(documents.String)
+	}
+	if options.Valid && options.String != "" {
+		job.Options = json.RawMessage(options.String)
+	}
+
+	return &job, nil
+}
+
+func scanDeadLetter(row rowScanner) (*storage.DeadLetter, error) {
+	var letter storage.DeadLetter
+	var fileName, errorCategory, stage, provider, job sql.NullString
+	var requeuedAt sql.NullTime
+
+	err := row.Scan(
+		&letter.JobID, &fileName, &letter.Error, &errorCategory, &stage, &provider, &letter.Attempts,
+		&job, &letter.CreatedAt, &letter.FailedAt, &requeuedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	letter.FileName = fileName.String
+	letter.ErrorCategory = errorCategory.String
+	letter.Stage = stage.String
+	letter.Provider = provider.String
+	if job.Valid && job.String != "" {
+		letter.Job = json.RawMessage(job.String)
+	}
+	if requeuedAt.Valid {
+		letter.RequeuedAt = &requeuedAt.Time
+	}
+
+	return &letter, nil
+}
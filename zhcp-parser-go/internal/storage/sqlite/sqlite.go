@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"zhcp-parser-go/internal/storage"
@@ -13,15 +14,19 @@ import (
 )
 
 type SQLiteStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	dbPath string
 }
 
 func New(dbPath string) *SQLiteStorage {
-	return &SQLiteStorage{}
+	if dbPath == "" {
+		dbPath = "zhcp.db"
+	}
+	return &SQLiteStorage{dbPath: dbPath}
 }
 
 func (s *SQLiteStorage) Init(ctx context.Context) error {
-	db, err := sql.Open("sqlite3", "zhcp.db")
+	db, err := sql.Open("sqlite3", s.dbPath)
 	if err != nil {
 		return err
 	}
@@ -64,12 +69,150 @@ func (s *SQLiteStorage) Init(ctx context.Context) error {
 	CREATE INDEX IF NOT EXISTS idx_tasks_project_id ON tasks(project_id);
 	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
 	CREATE INDEX IF NOT EXISTS idx_projects_status ON projects(status);
+
+	CREATE TABLE IF NOT EXISTS parse_jobs (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL,
+		progress INTEGER NOT NULL DEFAULT 0,
+		file_path TEXT,
+		input_text TEXT,
+		format TEXT,
+		callback_url TEXT,
+		force INTEGER NOT NULL DEFAULT 0,
+		result TEXT,
+		error TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		file_name TEXT,
+		provider TEXT,
+		error_category TEXT,
+		started_at DATETIME,
+		finished_at DATETIME,
+		document_key TEXT,
+		result_key TEXT,
+		retry_from TEXT,
+		artifacts TEXT,
+		documents TEXT,
+		conflicts TEXT,
+		options TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_parse_jobs_status ON parse_jobs(status, created_at);
+	CREATE INDEX IF NOT EXISTS idx_parse_jobs_created_at ON parse_jobs(created_at);
+
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		job_id TEXT PRIMARY KEY,
+		file_name TEXT,
+		error TEXT NOT NULL,
+		error_category TEXT,
+		stage TEXT,
+		provider TEXT,
+		attempts INTEGER NOT NULL DEFAULT 1,
+		job TEXT,
+		created_at DATETIME NOT NULL,
+		failed_at DATETIME NOT NULL,
+		requeued_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_dead_letters_failed_at ON dead_letters(failed_at);
+
+	CREATE TABLE IF NOT EXISTS parse_result_cache (
+		cache_key TEXT PRIMARY KEY,
+		result TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_parse_result_cache_expires_at ON parse_result_cache(expires_at);
+
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		job_id TEXT PRIMARY KEY,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		input_tokens INTEGER NOT NULL DEFAULT 0,
+		output_tokens INTEGER NOT NULL DEFAULT 0,
+		cost_usd REAL NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage(created_at);
+
+	CREATE TABLE IF NOT EXISTS prompt_templates (
+		name TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		description TEXT,
+		template TEXT NOT NULL,
+		parameters TEXT,
+		active INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (name, version)
+	);
 	`
 
-	_, err = s.db.ExecContext(ctx, schema)
+	if _, err = s.db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	// Columns added after a table was first created
+	if err := s.addColumnIfMissing(ctx, "parse_jobs", "callback_url", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing(ctx, "parse_jobs", "force", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	for _, column := range []string{"file_name", "provider", "error_category", "document_key", "result_key", "retry_from", "artifacts", "documents", "conflicts", "options"} {
+		if err := s.addColumnIfMissing(ctx, "parse_jobs", column, "TEXT"); err != nil {
+			return err
+		}
+	}
+	for _, column := range []string{"started_at", "finished_at"} {
+		if err := s.addColumnIfMissing(ctx, "parse_jobs", column, "DATETIME"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing upgrades databases created by an older version
+func (s *SQLiteStorage) addColumnIfMissing(ctx context.Context, table, column, definition string) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name, kind   string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
+// Ping checks that the database answers queries
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
 func (s *SQLiteStorage) Close() error {
 	if s.db != nil {
 		return s.db.Close()
@@ -427,3 +570,689 @@ func (s *SQLiteStorage) DeleteTask(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// ============================================================================
+// Parse Job Operations
+// ============================================================================
+
+// SaveParseJob inserts the job or overwrites its stored state
+func (s *SQLiteStorage) SaveParseJob(ctx context.Context, job *storage.ParseJob) error {
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	if job.UpdatedAt.IsZero() {
+		job.UpdatedAt = time.Now()
+	}
+
+	var result, artifacts, documents, options sql.NullString
+	if len(job.Result) > 0 {
+		result = sql.NullString{String: string(job.Result), Valid: true}
+	}
+	if len(job.Artifacts) > 0 {
+		artifacts = sql.NullString{String: string(job.Artifacts), Valid: true}
+	}
+	if len(job.Documents) > 0 {
+		documents = sql.NullString{String: string(job.Documents), Valid: true}
+	}
+	if len(job.Options) > 0 {
+		options = sql.NullString{String: string(job.Options), Valid: true}
+	}
+
+	query := `
+		INSERT INTO parse_jobs (id, status, progress, file_path, input_text, format, callback_url, force, result, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, artifacts,
+			documents, conflicts, options)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			progress = excluded.progress,
+			file_path = excluded.file_path,
+			input_text = excluded.input_text,
+			format = excluded.format,
+			callback_url = excluded.callback_url,
+			force = excluded.force,
+			result = excluded.result,
+			error = excluded.error,
+			updated_at = excluded.updated_at,
+			file_name = excluded.file_name,
+			provider = excluded.provider,
+			error_category = excluded.error_category,
+			started_at = excluded.started_at,
+			finished_at = excluded.finished_at,
+			document_key = excluded.document_key,
+			result_key = excluded.result_key,
+			retry_from = excluded.retry_from,
+			artifacts = excluded.artifacts,
+			documents = excluded.documents,
+			conflicts = excluded.conflicts,
+			options = excluded.options
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		job.ID, job.Status, job.Progress, job.FilePath, job.Text, job.Format, job.CallbackURL,
+		job.Force, result, job.Error, job.CreatedAt, job.UpdatedAt,
+		job.FileName, job.Provider, job.ErrorCategory, job.StartedAt, job.FinishedAt, job.DocumentKey, job.ResultKey,
+		job.RetryFrom, artifacts, documents, job.Conflicts, options,
+	)
+	return err
+}
+
+func (s *SQLiteStorage) GetParseJob(ctx context.Context, id string) (*storage.ParseJob, error) {
+	query := `
+		SELECT id, status, progress, file_path, input_text, format, callback_url, force, result, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, artifacts,
+			documents, conflicts, options
+		FROM parse_jobs WHERE id = ?
+	`
+
+	job, err := scanParseJob(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// ListIncompleteParseJobs returns queued and processing jobs, oldest first
+func (s *SQLiteStorage) ListIncompleteParseJobs(ctx context.Context) ([]*storage.ParseJob, error) {
+	query := `
+		SELECT id, status, progress, file_path, input_text, format, callback_url, force, result, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, artifacts,
+			documents, conflicts, options
+		FROM parse_jobs WHERE status IN ('queued', 'processing') ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*storage.ParseJob
+	for rows.Next() {
+		job, err := scanParseJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (s *SQLiteStorage) DeleteParseJob(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM parse_jobs WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListParseJobs returns a page of the jobs matching filter, newest first,
+// and the number of matching jobs. The input text, result and artifacts are left out.
+func (s *SQLiteStorage) ListParseJobs(ctx context.Context, filter storage.ParseJobFilter) ([]*storage.ParseJob, int, error) {
+	where := "WHERE 1 = 1"
+	var args []interface{}
+	if filter.Status != "" {
+		where += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, filter.Since)
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM parse_jobs "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+	query := `
+		SELECT id, status, progress, file_path, NULL, format, callback_url, force, NULL, error, created_at, updated_at,
+			file_name, provider, error_category, started_at, finished_at, document_key, result_key, retry_from, NULL,
+			documents, conflicts, options
+		FROM parse_jobs ` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var jobs []*storage.ParseJob
+	for rows.Next() {
+		job, err := scanParseJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+// DeleteFinishedParseJobs removes completed, failed and cancelled jobs last
+// updated before the given time
+func (s *SQLiteStorage) DeleteFinishedParseJobs(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM parse_jobs WHERE status IN ('completed', 'failed', 'cancelled') AND updated_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ============================================================================
+// Dead Letter Operations
+// ============================================================================
+
+// SaveDeadLetter records a failed run of a job. A job already in the dead
+// letters has its failure replaced and its attempts counted up; either way
+// a pending requeue is cleared.
+func (s *SQLiteStorage) SaveDeadLetter(ctx context.Context, letter *storage.DeadLetter) error {
+	if letter.FailedAt.IsZero() {
+		letter.FailedAt = time.Now()
+	}
+	if letter.CreatedAt.IsZero() {
+		letter.CreatedAt = letter.FailedAt
+	}
+
+	query := `
+		INSERT INTO dead_letters (job_id, file_name, error, error_category, stage, provider, attempts, job, created_at, failed_at, requeued_at)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, ?, NULL)
+		ON CONFLICT(job_id) DO UPDATE SET
+			file_name = excluded.file_name,
+			error = excluded.error,
+			error_category = excluded.error_category,
+			stage = excluded.stage,
+			provider = excluded.provider,
+			attempts = dead_letters.attempts + 1,
+			job = excluded.job,
+			failed_at = excluded.failed_at,
+			requeued_at = NULL
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		letter.JobID, letter.FileName, letter.Error, letter.ErrorCategory, letter.Stage, letter.Provider,
+		string(letter.Job), letter.CreatedAt.UTC(), letter.FailedAt.UTC(),
+	)
+	return err
+}
+
+// GetDeadLetter returns the dead letter of a job with the serialized job
+func (s *SQLiteStorage) GetDeadLetter(ctx context.Context, jobID string) (*storage.DeadLetter, error) {
+	query := `
+		SELECT job_id, file_name, error, error_category, stage, provider, attempts, job, created_at, failed_at, requeued_at
+		FROM dead_letters WHERE job_id = ?
+	`
+
+	letter, err := scanDeadLetter(s.db.QueryRowContext(ctx, query, jobID))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// ListDeadLetters returns one page of dead letters, last failed first,
+// without the serialized jobs, and the number of dead letters
+func (s *SQLiteStorage) ListDeadLetters(ctx context.Context, limit, offset int) ([]*storage.DeadLetter, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dead_letters").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+	query := `
+		SELECT job_id, file_name, error, error_category, stage, provider, attempts, NULL, created_at, failed_at, requeued_at
+		FROM dead_letters ORDER BY failed_at DESC LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var letters []*storage.DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, total, rows.Err()
+}
+
+// MarkDeadLetterRequeued records that the job of a dead letter was queued
+// again
+func (s *SQLiteStorage) MarkDeadLetterRequeued(ctx context.Context, jobID string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE dead_letters SET requeued_at = ? WHERE job_id = ?", at.UTC(), jobID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteDeadLetter removes the dead letter of a job
+func (s *SQLiteStorage) DeleteDeadLetter(ctx context.Context, jobID string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM dead_letters WHERE job_id = ?", jobID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Parse Result Cache Operations
+// ============================================================================
+
+// GetCachedResult returns the result cached under key, or ErrNotFound when
+// there is none or it has expired
+func (s *SQLiteStorage) GetCachedResult(ctx context.Context, key string) (json.RawMessage, error) {
+	var result string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT result FROM parse_result_cache WHERE cache_key = ? AND expires_at > ?", key, time.Now(),
+	).Scan(&result)
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(result), nil
+}
+
+// SaveCachedResult stores result under key, replacing any previous entry
+func (s *SQLiteStorage) SaveCachedResult(ctx context.Context, key string, result json.RawMessage, expiresAt time.Time) error {
+	query := `
+		INSERT INTO parse_result_cache (cache_key, result, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET
+			result = excluded.result,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query, key, string(result), time.Now(), expiresAt)
+	return err
+}
+
+// DeleteExpiredCachedResults removes cache entries that expired before now
+func (s *SQLiteStorage) DeleteExpiredCachedResults(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM parse_result_cache WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ============================================================================
+// LLM Usage Operations
+// ============================================================================
+
+// SaveLLMUsage records the usage of a job, replacing an earlier record of
+// the same job
+func (s *SQLiteStorage) SaveLLMUsage(ctx context.Context, usage *storage.LLMUsage) error {
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO llm_usage (job_id, provider, model, input_tokens, output_tokens, cost_usd, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET
+			provider = excluded.provider,
+			model = excluded.model,
+			input_tokens = excluded.input_tokens,
+			output_tokens = excluded.output_tokens,
+			cost_usd = excluded.cost_usd,
+			created_at = excluded.created_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		usage.JobID, usage.Provider, usage.Model, usage.InputTokens, usage.OutputTokens,
+		usage.CostUSD, usage.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// SummarizeLLMUsage totals usage in the filter's time range by model or by
+// day, most expensive model or earliest day first
+func (s *SQLiteStorage) SummarizeLLMUsage(ctx context.Context, filter storage.UsageFilter) ([]*storage.UsageSummary, error) {
+	var query string
+	if filter.GroupBy == "day" {
+		query = `
+			SELECT substr(created_at, 1, 10) AS day, '', '', COUNT(*),
+				SUM(input_tokens), SUM(output_tokens), SUM(cost_usd)
+			FROM llm_usage WHERE created_at >= ? AND created_at < ?
+			GROUP BY day ORDER BY day ASC
+		`
+	} else {
+		query = `
+			SELECT '', provider, model, COUNT(*),
+				SUM(input_tokens), SUM(output_tokens), SUM(cost_usd) AS cost
+			FROM llm_usage WHERE created_at >= ? AND created_at < ?
+			GROUP BY provider, model ORDER BY cost DESC, provider, model
+		`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, filter.From.UTC(), filter.To.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*storage.UsageSummary
+	for rows.Next() {
+		var summary storage.UsageSummary
+		if err := rows.Scan(
+			&summary.Day, &summary.Provider, &summary.Model, &summary.Jobs,
+			&summary.InputTokens, &summary.OutputTokens, &summary.CostUSD,
+		); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ============================================================================
+// Prompt Template Operations
+// ============================================================================
+
+// SavePromptTemplate stores template as the next version of its name and
+// sets template.Version. An active template deactivates the other versions.
+func (s *SQLiteStorage) SavePromptTemplate(ctx context.Context, template *storage.PromptTemplate) error {
+	parameters, err := json.Marshal(template.Parameters)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parameters: %w", err)
+	}
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = time.Now()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) + 1 FROM prompt_templates WHERE name = ?", template.Name,
+	).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	if template.Active {
+		if _, err := tx.ExecContext(ctx, "UPDATE prompt_templates SET active = 0 WHERE name = ?", template.Name); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO prompt_templates (name, version, description, template, parameters, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = tx.ExecContext(ctx, query,
+		template.Name, version, template.Description, template.Template, string(parameters),
+		template.Active, template.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	template.Version = version
+	return nil
+}
+
+// GetPromptTemplate returns one version of a template
+func (s *SQLiteStorage) GetPromptTemplate(ctx context.Context, name string, version int) (*storage.PromptTemplate, error) {
+	query := `
+		SELECT name, version, description, template, parameters, active, created_at
+		FROM prompt_templates WHERE name = ? AND version = ?
+	`
+
+	template, err := scanPromptTemplate(s.db.QueryRowContext(ctx, query, name, version))
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	return template, err
+}
+
+// ListActivePromptTemplates returns the active version of every template
+func (s *SQLiteStorage) ListActivePromptTemplates(ctx context.Context) ([]*storage.PromptTemplate, error) {
+	query := `
+		SELECT name, version, description, template, parameters, active, created_at
+		FROM prompt_templates WHERE active = 1 ORDER BY name
+	`
+
+	return s.queryPromptTemplates(ctx, query)
+}
+
+// ListPromptTemplateVersions returns every version of a template, newest first
+func (s *SQLiteStorage) ListPromptTemplateVersions(ctx context.Context, name string) ([]*storage.PromptTemplate, error) {
+	query := `
+		SELECT name, version, description, template, parameters, active, created_at
+		FROM prompt_templates WHERE name = ? ORDER BY version DESC
+	`
+
+	return s.queryPromptTemplates(ctx, query, name)
+}
+
+// ActivatePromptTemplate makes version the active version of a template
+func (s *SQLiteStorage) ActivatePromptTemplate(ctx context.Context, name string, version int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM prompt_templates WHERE name = ? AND version = ?)", name, version,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE prompt_templates SET active = (version = ?) WHERE name = ?", version, name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeletePromptTemplate removes every version of a template and returns how
+// many there were
+func (s *SQLiteStorage) DeletePromptTemplate(ctx context.Context, name string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM prompt_templates WHERE name = ?", name)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *SQLiteStorage) queryPromptTemplates(ctx context.Context, query string, args ...interface{}) ([]*storage.PromptTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*storage.PromptTemplate
+	for rows.Next() {
+		template, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+func scanPromptTemplate(row rowScanner) (*storage.PromptTemplate, error) {
+	var template storage.PromptTemplate
+	var description, parameters sql.NullString
+
+	err := row.Scan(
+		&template.Name, &template.Version, &description, &template.Template,
+		&parameters, &template.Active, &template.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Description = description.String
+	if parameters.Valid && parameters.String != "" {
+		if err := json.Unmarshal([]byte(parameters.String), &template.Parameters); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
+		}
+	}
+
+	return &template, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanParseJob(row rowScanner) (*storage.ParseJob, error) {
+	var job storage.ParseJob
+	var filePath, text, format, callbackURL, result, errorMessage sql.NullString
+	var fileName, provider, errorCategory, documentKey, resultKey, retryFrom, artifacts sql.NullString
+	var documents, conflicts, options sql.NullString
+	var startedAt, finishedAt sql.NullTime
+
+	err := row.Scan(
+		&job.ID, &job.Status, &job.Progress, &filePath, &text, &format,
+		&callbackURL, &job.Force, &result, &errorMessage, &job.CreatedAt, &job.UpdatedAt,
+		&fileName, &provider, &errorCategory, &startedAt, &finishedAt, &documentKey, &resultKey,
+		&retryFrom, &artifacts, &documents, &conflicts, &options,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.FilePath = filePath.String
+	job.Text = text.String
+	job.Format = format.String
+	job.CallbackURL = callbackURL.String
+	job.Error = errorMessage.String
+	job.FileName = fileName.String
+	job.Provider = provider.String
+	job.ErrorCategory = errorCategory.String
+	job.DocumentKey = documentKey.String
+	job.ResultKey = resultKey.String
+	job.RetryFrom = retryFrom.String
+	job.Conflicts = conflicts.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if result.Valid && result.String != "" {
+		job.Result = json.RawMessage(result.String)
+	}
+	if artifacts.Valid && artifacts.String != "" {
+		job.Artifacts = json.RawMessage(artifacts.String)
+	}
+	if documents.Valid && documents.String != "" {
+		job.Documents = json.RawMessage(documents.String)
+	}
+	if options.Valid && options.String != "" {
+		job.Options = json.RawMessage(options.String)
+	}
+
+	return &job, nil
+}
+
+func scanDeadLetter(row rowScanner) (*storage.DeadLetter, error) {
+	var letter storage.DeadLetter
+	var fileName, errorCategory, stage, provider, job sql.NullString
+	var requeuedAt sql.NullTime
+
+	err := row.Scan(
+		&letter.JobID, &fileName, &letter.Error, &errorCategory, &stage, &provider, &letter.Attempts,
+		&job, &letter.CreatedAt, &letter.FailedAt, &requeuedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	letter.FileName = fileName.String
+	letter.ErrorCategory = errorCategory.String
+	letter.Stage = stage.String
+	letter.Provider = provider.String
+	if job.Valid && job.String != "" {
+		letter.Job = json.RawMessage(job.String)
+	}
+	if requeuedAt.Valid {
+		letter.RequeuedAt = &requeuedAt.Time
+	}
+
+	return &letter, nil
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"time"
 )
 
@@ -14,6 +16,7 @@ var (
 type Storage interface {
 	Init(ctx context.Context) error
 	Close() error
+	Ping(ctx context.Context) error
 
 	// Project operations
 	SaveProject(ctx context.Context, project *Project) error
@@ -29,6 +32,48 @@ type Storage interface {
 	UpdateTask(ctx context.Context, task *Task) error
 	UpdateTaskStatus(ctx context.Context, id, status string) error
 	DeleteTask(ctx context.Context, id string) error
+
+	// Parse job operations
+	SaveParseJob(ctx context.Context, job *ParseJob) error
+	GetParseJob(ctx context.Context, id string) (*ParseJob, error)
+	ListIncompleteParseJobs(ctx context.Context) ([]*ParseJob, error)
+	ListParseJobs(ctx context.Context, filter ParseJobFilter) ([]*ParseJob, int, error)
+	DeleteParseJob(ctx context.Context, id string) error
+	DeleteFinishedParseJobs(ctx context.Context, before time.Time) (int64, error)
+
+	// Dead letter operations
+	SaveDeadLetter(ctx context.Context, letter *DeadLetter) error
+	GetDeadLetter(ctx context.Context, jobID string) (*DeadLetter, error)
+	ListDeadLetters(ctx context.Context, limit, offset int) ([]*DeadLetter, int, error)
+	MarkDeadLetterRequeued(ctx context.Context, jobID string, at time.Time) error
+	DeleteDeadLetter(ctx context.Context, jobID string) error
+
+	// Parse result cache operations
+	GetCachedResult(ctx context.Context, key string) (json.RawMessage, error)
+	SaveCachedResult(ctx context.Context, key string, result json.RawMessage, expiresAt time.Time) error
+	DeleteExpiredCachedResults(ctx context.Context, now time.Time) (int64, error)
+
+	// LLM usage operations
+	SaveLLMUsage(ctx context.Context, usage *LLMUsage) error
+	SummarizeLLMUsage(ctx context.Context, filter UsageFilter) ([]*UsageSummary, error)
+
+	// Prompt template operations
+	SavePromptTemplate(ctx context.Context, template *PromptTemplate) error
+	GetPromptTemplate(ctx context.Context, name string, version int) (*PromptTemplate, error)
+	ListActivePromptTemplates(ctx context.Context) ([]*PromptTemplate, error)
+	ListPromptTemplateVersions(ctx context.Context, name string) ([]*PromptTemplate, error)
+	ActivatePromptTemplate(ctx context.Context, name string, version int) error
+	DeletePromptTemplate(ctx context.Context, name string) (int64, error)
+}
+
+// ObjectStore keeps uploaded documents and parse results outside the local
+// disk and database, e.g. in S3, so parser nodes behind a load balancer can
+// share them
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, body io.ReadSeeker) error
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error) // ErrNotFound for missing keys
+	DeleteObject(ctx context.Context, key string) error
+	Ping(ctx context.Context) error
 }
 
 // Project represents a construction project
@@ -63,3 +108,114 @@ type Task struct {
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 }
+
+// LLMUsage is the token usage and cost of a parse job. It outlives the job
+// so that usage can be reported over long periods.
+type LLMUsage struct {
+	JobID        string    `json:"job_id"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UsageFilter selects LLM usage in [From, To) and how to group it
+type UsageFilter struct {
+	From    time.Time
+	To      time.Time
+	GroupBy string // "model" (default) or "day"
+}
+
+// UsageSummary aggregates LLM usage for one group
+type UsageSummary struct {
+	Day          string  `json:"day,omitempty"`      // YYYY-MM-DD, when grouped by day
+	Provider     string  `json:"provider,omitempty"` // when grouped by model
+	Model        string  `json:"model,omitempty"`
+	Jobs         int     `json:"jobs"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// PromptTemplate is one version of a prompt template managed through the
+// API. Versions of a name are numbered from 1; the active one replaces the
+// template of the same name in prompts/.
+type PromptTemplate struct {
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description,omitempty"`
+	Template    string    `json:"template"`
+	Parameters  []string  `json:"parameters,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ParseJob is the persisted state of a document parse job, so that queued
+// work and results survive a restart of the server
+type ParseJob struct {
+	ID          string          `json:"id"`
+	Status      string          `json:"status"` // queued, processing, completed, failed, cancelled
+	Progress    int             `json:"progress"`
+	FilePath    string          `json:"file_path,omitempty"`    // uploaded document, empty for raw text jobs
+	Text        string          `json:"text,omitempty"`         // raw text input
+	Format      string          `json:"format,omitempty"`       // text or markdown, for raw text jobs
+	CallbackURL string          `json:"callback_url,omitempty"` // webhook notified when the job finishes
+	Force       bool            `json:"force,omitempty"`        // bypass the parse result cache
+	Result      json.RawMessage `json:"result,omitempty"`       // serialized parser.ParseResult
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+
+	// Summary of the job for listings, so they need not decode Result
+	FileName      string     `json:"file_name,omitempty"`      // name of the uploaded document
+	Provider      string     `json:"provider,omitempty"`       // LLM provider that answered
+	ErrorCategory string     `json:"error_category,omitempty"` // of a failed job or parse result
+	StartedAt     *time.Time `json:"started_at,omitempty"`     // when a worker picked the job up
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+
+	// Object storage keys, set instead of FilePath and Result when the
+	// document and the result are kept in an ObjectStore
+	DocumentKey string `json:"document_key,omitempty"`
+	ResultKey   string `json:"result_key,omitempty"`
+
+	// Retries of a failed parse start from the stage in RetryFrom, with the
+	// extracted text and LLM response kept in Artifacts instead of the document
+	RetryFrom string          `json:"retry_from,omitempty"` // llm or transformation
+	Artifacts json.RawMessage `json:"artifacts,omitempty"`  // serialized parser.Artifacts
+
+	// Jobs parsing several documents into one project keep them in Documents
+	// instead of FilePath or DocumentKey
+	Documents json.RawMessage `json:"documents,omitempty"` // file names, paths and object keys, in order
+	Conflicts string          `json:"conflicts,omitempty"` // later_wins or manual
+
+	// Options are the parse options the job was submitted with
+	Options json.RawMessage `json:"options,omitempty"` // validate, enrich and generation overrides
+}
+
+// DeadLetter is a parse job that failed for good. It keeps the job, with its
+// input and the artifacts of the last attempt, after the job itself expires,
+// until the job is requeued and succeeds or the dead letter is deleted.
+type DeadLetter struct {
+	JobID         string          `json:"job_id"`
+	FileName      string          `json:"file_name,omitempty"`
+	Error         string          `json:"error"`
+	ErrorCategory string          `json:"error_category,omitempty"`
+	Stage         string          `json:"stage,omitempty"`       // pipeline stage the job failed in
+	Provider      string          `json:"provider,omitempty"`    // LLM provider that answered, if any
+	Attempts      int             `json:"attempts"`              // failed runs of the job, counted by SaveDeadLetter
+	Job           json.RawMessage `json:"job,omitempty"`         // serialized ParseJob, omitted in listings
+	CreatedAt     time.Time       `json:"created_at"`            // first failure
+	FailedAt      time.Time       `json:"failed_at"`             // last failure
+	RequeuedAt    *time.Time      `json:"requeued_at,omitempty"` // set while a requeued run is pending
+}
+
+// ParseJobFilter selects parse jobs for a listing, newest first. Zero fields
+// do not filter.
+type ParseJobFilter struct {
+	Status string
+	Since  time.Time // created at or after
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,206 @@
+package transformers
+
+import (
+	"fmt"
+	"time"
+)
+
+// Weights of the confidence components; they add up to 1
+const (
+	extractionQualityWeight  = 0.3
+	schemaCompletenessWeight = 0.3
+	dateConsistencyWeight    = 0.2
+	dependencyValidityWeight = 0.2
+)
+
+// ConfidenceBreakdown explains a confidence score. Each component is in
+// [0, 1]; the score is their weighted sum plus the validation adjustment,
+// clamped to [0, 1]. Reasons name what lowered a component.
+type ConfidenceBreakdown struct {
+	ExtractionQuality    float64  `json:"extraction_quality"`    // the LLM response came through intact, weight 0.3
+	SchemaCompleteness   float64  `json:"schema_completeness"`   // share of optional fields filled in, weight 0.3
+	DateConsistency      float64  `json:"date_consistency"`      // dates in order and within the deadline, weight 0.2
+	DependencyValidity   float64  `json:"dependency_validity"`   // dependencies pointing at existing tasks, weight 0.2
+	ValidationAdjustment float64  `json:"validation_adjustment"` // from the validation pipeline, at most 0
+	Reasons              []string `json:"reasons"`
+}
+
+// Score combines the components into a confidence score
+func (b *ConfidenceBreakdown) Score() float64 {
+	score := b.ExtractionQuality*extractionQualityWeight +
+		b.SchemaCompleteness*schemaCompletenessWeight +
+		b.DateConsistency*dateConsistencyWeight +
+		b.DependencyValidity*dependencyValidityWeight +
+		b.ValidationAdjustment
+	return clampScore(score)
+}
+
+// LowerExtractionQuality takes penalty off the extraction quality, e.g. for
+// repair calls the response needed
+func (b *ConfidenceBreakdown) LowerExtractionQuality(penalty float64, reason string) {
+	b.ExtractionQuality = clampScore(b.ExtractionQuality - penalty)
+	b.Reasons = append(b.Reasons, reason)
+}
+
+// scoreConfidence rates a transformed project structure. repaired tells
+// whether the response had to be repaired before it parsed as JSON.
+func (dt *DataTransformer) scoreConfidence(data *ProjectStructure, repaired bool) *ConfidenceBreakdown {
+	breakdown := &ConfidenceBreakdown{ExtractionQuality: 1.0, Reasons: []string{}}
+	if repaired {
+		breakdown.LowerExtractionQuality(0.1, "LLM response was not clean JSON and had to be repaired")
+	}
+
+	breakdown.SchemaCompleteness = schemaCompleteness(data, breakdown)
+	breakdown.DateConsistency = dateConsistency(data, breakdown)
+	breakdown.DependencyValidity = dependencyValidity(data, breakdown)
+	return breakdown
+}
+
+// schemaCompleteness weighs the project fields at 0.4 and the task fields
+// at 0.6, as tasks carry most of the value of a parse
+func schemaCompleteness(data *ProjectStructure, breakdown *ConfidenceBreakdown) float64 {
+	project := data.Project
+	projectFields := 0
+	if project.Title != "" {
+		projectFields++
+	} else {
+		breakdown.Reasons = append(breakdown.Reasons, "Project title is missing")
+	}
+	if project.Description != "" {
+		projectFields++
+	} else {
+		breakdown.Reasons = append(breakdown.Reasons, "Project description is missing")
+	}
+	if project.Deadline != "" {
+		projectFields++
+	} else {
+		breakdown.Reasons = append(breakdown.Reasons, "Project deadline is missing")
+	}
+	if len(project.Phases) > 0 {
+		projectFields++
+	} else {
+		breakdown.Reasons = append(breakdown.Reasons, "No phases were extracted")
+	}
+
+	var tasks, undated, unassigned, undescribed int
+	for _, phase := range project.Phases {
+		for _, task := range phase.Tasks {
+			tasks++
+			if task.StartDate == "" && task.EndDate == "" {
+				undated++
+			}
+			if len(task.ResponsiblePersons) == 0 {
+				unassigned++
+			}
+			if task.Description == "" {
+				undescribed++
+			}
+		}
+	}
+	if tasks == 0 {
+		if len(project.Phases) > 0 {
+			breakdown.Reasons = append(breakdown.Reasons, "No tasks were extracted")
+		}
+		return 0.4 * float64(projectFields) / 4
+	}
+
+	for _, missing := range []struct {
+		count int
+		what  string
+	}{
+		{undated, "have no dates"},
+		{unassigned, "have no responsible persons"},
+		{undescribed, "have no description"},
+	} {
+		if missing.count > 0 {
+			breakdown.Reasons = append(breakdown.Reasons, fmt.Sprintf("%d of %d tasks %s", missing.count, tasks, missing.what))
+		}
+	}
+
+	// Each task has a name, so the remaining fields decide its completeness
+	taskFields := float64(3*tasks-undated-unassigned-undescribed) / float64(3*tasks)
+	return 0.4*float64(projectFields)/4 + 0.6*taskFields
+}
+
+// dateConsistency is the share of dated phases and tasks whose dates are in
+// order and not after the project deadline; without dates it is 1
+func dateConsistency(data *ProjectStructure, breakdown *ConfidenceBreakdown) float64 {
+	deadline, hasDeadline := parseDate(data.Project.Deadline)
+
+	var dated, reversed, late int
+	check := func(start, end string) {
+		startDate, hasStart := parseDate(start)
+		endDate, hasEnd := parseDate(end)
+		if !hasStart && !hasEnd {
+			return
+		}
+		dated++
+		switch {
+		case hasStart && hasEnd && startDate.After(endDate):
+			reversed++
+		case hasDeadline && hasEnd && endDate.After(deadline):
+			late++
+		}
+	}
+
+	for _, phase := range data.Project.Phases {
+		check(phase.StartDate, phase.EndDate)
+		for _, task := range phase.Tasks {
+			check(task.StartDate, task.EndDate)
+		}
+	}
+	if dated == 0 {
+		return 1.0
+	}
+
+	if reversed > 0 {
+		breakdown.Reasons = append(breakdown.Reasons, fmt.Sprintf("%d phases or tasks end before they start", reversed))
+	}
+	if late > 0 {
+		breakdown.Reasons = append(breakdown.Reasons, fmt.Sprintf("%d phases or tasks end after the project deadline", late))
+	}
+	return float64(dated-reversed-late) / float64(dated)
+}
+
+// dependencyValidity is the share of dependencies pointing at another
+// existing task; without dependencies it is 1
+func dependencyValidity(data *ProjectStructure, breakdown *ConfidenceBreakdown) float64 {
+	taskIDs := make(map[string]bool)
+	for _, phase := range data.Project.Phases {
+		for _, task := range phase.Tasks {
+			taskIDs[task.ID] = true
+		}
+	}
+
+	var total, invalid int
+	for _, phase := range data.Project.Phases {
+		for _, task := range phase.Tasks {
+			for _, dep := range task.Dependencies {
+				total++
+				if dep == task.ID || !taskIDs[dep] {
+					invalid++
+				}
+			}
+		}
+	}
+	if total == 0 {
+		return 1.0
+	}
+
+	if invalid > 0 {
+		breakdown.Reasons = append(breakdown.Reasons, fmt.Sprintf("%d of %d dependencies point at unknown tasks or the task itself", invalid, total))
+	}
+	return float64(total-invalid) / float64(total)
+}
+
+func parseDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	date, err := time.Parse("2006-01-02", value)
+	return date, err == nil
+}
+
+func clampScore(score float64) float64 {
+	return min(1.0, max(0.0, score))
+}
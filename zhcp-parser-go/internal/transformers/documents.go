@@ -0,0 +1,140 @@
+package transformers
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ConflictStrategy decides which value a merged project keeps when the
+// documents parsed together disagree on a field
+type ConflictStrategy string
+
+const (
+	// ConflictsLaterWins keeps the value of the later document, so an annex
+	// or an updated schedule overrides the contract it follows
+	ConflictsLaterWins ConflictStrategy = "later_wins"
+	// ConflictsManual keeps the value of the first document and leaves the
+	// conflict to be decided by the user
+	ConflictsManual ConflictStrategy = "manual"
+)
+
+// ParseConflictStrategy reads a strategy as given by clients; empty means
+// ConflictsLaterWins
+func ParseConflictStrategy(value string) (ConflictStrategy, bool) {
+	switch ConflictStrategy(value) {
+	case "", ConflictsLaterWins:
+		return ConflictsLaterWins, true
+	case ConflictsManual:
+		return ConflictsManual, true
+	default:
+		return "", false
+	}
+}
+
+// Conflict is a field of the project, a phase or a task to which documents
+// parsed together give different values
+type Conflict struct {
+	Phase    string          `json:"phase,omitempty"` // name of the phase, empty for project fields
+	Task     string          `json:"task,omitempty"`  // name of the task, empty for phase and project fields
+	Field    string          `json:"field"`           // JSON name of the field, e.g. end_date
+	Values   []ConflictValue `json:"values"`          // in document order
+	Resolved bool            `json:"resolved"`        // the later value was taken; false leaves the first one for review
+}
+
+// ConflictValue is the value one document gives a conflicting field
+type ConflictValue struct {
+	Document string      `json:"document"`
+	Value    interface{} `json:"value"`
+}
+
+// MergeDocuments combines the transformations of several documents about
+// one project, such as a contract, its annexes and a schedule, into a single
+// result. Phases and tasks are matched by name as in Merge. Fields the
+// documents give different values are listed in Conflicts and resolved by
+// strategy; documents that failed are left out. names label the documents
+// in conflicts and notes, in the order of parts.
+func (dt *DataTransformer) MergeDocuments(parts []*TransformationResult, names []string, strategy ConflictStrategy) *TransformationResult {
+	merger := newStructureMerger()
+	merger.unit = "document"
+	merger.conflicts = &documentConflicts{
+		names:    names,
+		strategy: strategy,
+		sources:  make(map[string]int),
+		index:    make(map[string]int),
+	}
+
+	return dt.mergeParts(parts, nil, merger)
+}
+
+// documentConflicts tracks which document set each field of a merge, and
+// the fields documents disagree on
+type documentConflicts struct {
+	names    []string
+	strategy ConflictStrategy
+	document int // index of the document being added
+
+	sources map[string]int // document whose value each field holds
+	index   map[string]int // position of each field in list
+	list    []Conflict
+}
+
+// replaces reports whether the value the current document gives a field
+// replaces the merged one, recording a conflict when both are set and differ
+func (c *documentConflicts) replaces(phase, task, field string, current, value interface{}, currentEmpty, valueEmpty bool) bool {
+	key := phase + "\x00" + task + "\x00" + field
+	switch {
+	case valueEmpty:
+		return false
+	case currentEmpty:
+		c.sources[key] = c.document
+		return true
+	case reflect.DeepEqual(current, value):
+		return false
+	}
+
+	position, exists := c.index[key]
+	if !exists {
+		position = len(c.list)
+		c.index[key] = position
+		c.list = append(c.list, Conflict{
+			Phase:  phase,
+			Task:   task,
+			Field:  field,
+			Values: []ConflictValue{{Document: c.name(c.sources[key]), Value: current}},
+		})
+	}
+	conflict := &c.list[position]
+	conflict.Values = append(conflict.Values, ConflictValue{Document: c.name(c.document), Value: value})
+
+	if c.strategy == ConflictsManual {
+		return false
+	}
+	conflict.Resolved = true
+	c.sources[key] = c.document
+	return true
+}
+
+func (c *documentConflicts) name(document int) string {
+	if document < len(c.names) && c.names[document] != "" {
+		return c.names[document]
+	}
+	return fmt.Sprintf("document %d", document+1)
+}
+
+func (c *documentConflicts) notes(documents, mergedPhases, mergedTasks int) []string {
+	notes := []string{fmt.Sprintf("Project was merged from %d documents", documents)}
+	if mergedPhases > 0 || mergedTasks > 0 {
+		notes = append(notes, fmt.Sprintf(
+			"Combined %d phases and %d tasks found in more than one document", mergedPhases, mergedTasks))
+	}
+	switch {
+	case len(c.list) == 0:
+	case c.strategy == ConflictsManual:
+		notes = append(notes, fmt.Sprintf(
+			"%d fields differ between the documents and need a decision, the value of the first document is kept until then", len(c.list)))
+	default:
+		notes = append(notes, fmt.Sprintf(
+			"%d fields differ between the documents, the values of the later documents were kept", len(c.list)))
+	}
+	return notes
+}
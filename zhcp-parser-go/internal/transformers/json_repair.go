@@ -0,0 +1,56 @@
+package transformers
+
+import "strings"
+
+// repairJSON fixes the most common ways LLMs break JSON: markdown code
+// fences, text before or after the object and trailing commas. It returns
+// false when there was nothing to repair.
+func repairJSON(response string) (string, bool) {
+	repaired := strings.TrimSpace(response)
+
+	// Keep only the outermost object, which also drops code fences
+	start := strings.Index(repaired, "{")
+	end := strings.LastIndex(repaired, "}")
+	if start < 0 || end < start {
+		return "", false
+	}
+	repaired = repaired[start : end+1]
+	repaired = removeTrailingCommas(repaired)
+
+	return repaired, repaired != response
+}
+
+// removeTrailingCommas drops commas directly before a closing brace or
+// bracket, leaving string contents alone
+func removeTrailingCommas(text string) string {
+	var builder strings.Builder
+	builder.Grow(len(text))
+
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := i + 1
+			for next < len(text) && strings.IndexByte(" \t\r\n", text[next]) >= 0 {
+				next++
+			}
+			if next < len(text) && (text[next] == '}' || text[next] == ']') {
+				continue
+			}
+		}
+		builder.WriteByte(c)
+	}
+	return builder.String()
+}
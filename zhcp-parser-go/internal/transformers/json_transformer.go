@@ -28,6 +28,12 @@ func NewDataTransformer() *DataTransformer {
 
 // Transform transforms LLM response to standardized project structure
 func (dt *DataTransformer) Transform(llmResponse string) *TransformationResult {
+	return dt.TransformWithOptions(llmResponse, TransformOptions{})
+}
+
+// TransformWithOptions is Transform for a document of a known language or
+// for one chunk of a document
+func (dt *DataTransformer) TransformWithOptions(llmResponse string, opts TransformOptions) *TransformationResult {
 	result := &TransformationResult{
 		ValidationErrors: []string{},
 		ProcessingNotes:  []string{},
@@ -35,10 +41,17 @@ func (dt *DataTransformer) Transform(llmResponse string) *TransformationResult {
 
 	// Parse response
 	var responseMap map[string]interface{}
+	jsonRepaired := false
 	if err := json.Unmarshal([]byte(llmResponse), &responseMap); err != nil {
-		result.Status = TransformationStatusFailed
-		result.ValidationErrors = append(result.ValidationErrors, fmt.Sprintf("Invalid JSON: %v", err))
-		return result
+		repaired, ok := repairJSON(llmResponse)
+		if !ok || json.Unmarshal([]byte(repaired), &responseMap) != nil {
+			result.Status = TransformationStatusFailed
+			result.ValidationErrors = append(result.ValidationErrors, fmt.Sprintf("Invalid JSON: %v", err))
+			return result
+		}
+		result.ProcessingNotes = append(result.ProcessingNotes,
+			"Repaired malformed JSON in the LLM response (code fences, surrounding text or trailing commas)")
+		jsonRepaired = true
 	}
 
 	// Extract project structure
@@ -59,7 +72,13 @@ func (dt *DataTransformer) Transform(llmResponse string) *TransformationResult {
 	}
 
 	// Normalize and validate data
-	normalizedData := dt.normalizeData(projectData)
+	normalizedData := dt.normalizeData(projectData, opts.Language)
+	if opts.Part {
+		if dropped := dropDanglingDependencies(normalizedData); dropped > 0 {
+			result.ProcessingNotes = append(result.ProcessingNotes,
+				fmt.Sprintf("Dropped %d dependencies on tasks outside this part of the document", dropped))
+		}
+	}
 
 	// Validate against schema
 	validationResult := dt.validateData(normalizedData)
@@ -67,31 +86,34 @@ func (dt *DataTransformer) Transform(llmResponse string) *TransformationResult {
 	if validationResult.IsValid {
 		result.TransformedData = normalizedData
 		result.Status = TransformationStatusSuccess
-		result.ConfidenceScore = dt.calculateConfidenceScore(normalizedData, validationResult)
+		result.ConfidenceBreakdown = dt.scoreConfidence(normalizedData, jsonRepaired)
+		result.ConfidenceScore = result.ConfidenceBreakdown.Score()
 	} else {
 		// Attempt partial transformation
 		partialData := dt.createPartialTransformation(normalizedData, validationResult)
 		if partialData != nil {
 			result.TransformedData = partialData
 			result.Status = TransformationStatusPartial
-			result.ConfidenceScore = dt.calculateConfidenceScore(partialData, validationResult)
+			result.ConfidenceBreakdown = dt.scoreConfidence(partialData, jsonRepaired)
+			result.ConfidenceScore = result.ConfidenceBreakdown.Score()
 		} else {
 			result.Status = TransformationStatusValidationError
 			result.ValidationErrors = validationResult.Issues
 		}
 	}
 
-	result.ProcessingNotes = validationResult.Suggestions
+	result.ProcessingNotes = append(result.ProcessingNotes, validationResult.Suggestions...)
 	return result
 }
 
 // normalizeData normalizes raw data to standard format
-func (dt *DataTransformer) normalizeData(rawData map[string]interface{}) *ProjectStructure {
+func (dt *DataTransformer) normalizeData(rawData map[string]interface{}, language string) *ProjectStructure {
 	projectStructure := &ProjectStructure{
 		Project: Project{
 			Title:       dt.normalizeText(rawData["title"]),
 			Description: dt.normalizeText(rawData["description"]),
 			Deadline:    dt.normalizeDate(rawData["deadline"]),
+			Budget:      dt.normalizeMoney(rawData["budget"]),
 			Phases:      []Phase{},
 			Metadata:    make(map[string]interface{}),
 		},
@@ -103,9 +125,10 @@ func (dt *DataTransformer) normalizeData(rawData map[string]interface{}) *Projec
 	// Normalize phases
 	if rawPhases, exists := rawData["phases"]; exists {
 		if phasesSlice, ok := rawPhases.([]interface{}); ok {
-			projectStructure.Project.Phases = dt.normalizePhases(phasesSlice)
+			projectStructure.Project.Phases = dt.normalizePhases(phasesSlice, language)
 		}
 	}
+	fillCurrencies(&projectStructure.Project)
 
 	// Add metadata
 	if metadata, exists := rawData["metadata"]; exists {
@@ -118,7 +141,7 @@ func (dt *DataTransformer) normalizeData(rawData map[string]interface{}) *Projec
 }
 
 // normalizePhases normalizes phases data
-func (dt *DataTransformer) normalizePhases(rawPhases []interface{}) []Phase {
+func (dt *DataTransformer) normalizePhases(rawPhases []interface{}, language string) []Phase {
 	phases := make([]Phase, 0, len(rawPhases))
 
 	for i, rawPhase := range rawPhases {
@@ -129,6 +152,7 @@ func (dt *DataTransformer) normalizePhases(rawPhases []interface{}) []Phase {
 				Description: dt.normalizeText(rawPhaseMap["description"]),
 				StartDate:   dt.normalizeDate(rawPhaseMap["start_date"]),
 				EndDate:     dt.normalizeDate(rawPhaseMap["end_date"]),
+				Cost:        dt.normalizeMoney(rawPhaseMap["cost"]),
 				Tasks:       []Task{},
 			}
 
@@ -140,7 +164,7 @@ func (dt *DataTransformer) normalizePhases(rawPhases []interface{}) []Phase {
 			// Normalize tasks
 			if rawTasks, exists := rawPhaseMap["tasks"]; exists {
 				if tasksSlice, ok := rawTasks.([]interface{}); ok {
-					phase.Tasks = dt.normalizeTasks(tasksSlice, phase.ID)
+					phase.Tasks = dt.normalizeTasks(tasksSlice, phase.ID, language)
 				}
 			}
 
@@ -152,7 +176,7 @@ func (dt *DataTransformer) normalizePhases(rawPhases []interface{}) []Phase {
 }
 
 // normalizeTasks normalizes tasks data
-func (dt *DataTransformer) normalizeTasks(rawTasks []interface{}, phaseID string) []Task {
+func (dt *DataTransformer) normalizeTasks(rawTasks []interface{}, phaseID, language string) []Task {
 	tasks := make([]Task, 0, len(rawTasks))
 
 	for i, rawTask := range rawTasks {
@@ -168,7 +192,8 @@ func (dt *DataTransformer) normalizeTasks(rawTasks []interface{}, phaseID string
 				Description: dt.normalizeText(rawTaskMap["description"]),
 				StartDate:   dt.normalizeDate(rawTaskMap["start_date"]),
 				EndDate:     dt.normalizeDate(rawTaskMap["end_date"]),
-				Status:      dt.normalizeStatus(rawTaskMap["status"]),
+				Status:      dt.normalizeStatus(rawTaskMap["status"], language),
+				Cost:        dt.normalizeMoney(rawTaskMap["cost"]),
 			}
 
 			// Normalize responsible persons
@@ -286,8 +311,9 @@ func (dt *DataTransformer) normalizeText(text interface{}) string {
 	return strings.TrimSpace(textStr)
 }
 
-// normalizeStatus normalizes task status
-func (dt *DataTransformer) normalizeStatus(status interface{}) string {
+// normalizeStatus normalizes task status using the status dictionary of
+// the document language first
+func (dt *DataTransformer) normalizeStatus(status interface{}, language string) string {
 	if status == nil {
 		return "planned"
 	}
@@ -295,18 +321,8 @@ func (dt *DataTransformer) normalizeStatus(status interface{}) string {
 	statusStr := strings.ToLower(dt.normalizeText(status))
 
 	// Map various status representations to standard values
-	statusMapping := map[string][]string{
-		"planned":     {"planned", "planning", "not started", "в плане", "запланировано"},
-		"in_progress": {"in_progress", "in progress", "progress", "в работе", "выполняется"},
-		"completed":   {"completed", "done", "finished", "завершено", "выполнено", "complete"},
-	}
-
-	for standardStatus, variations := range statusMapping {
-		for _, variation := range variations {
-			if strings.Contains(statusStr, variation) {
-				return standardStatus
-			}
-		}
+	if standardStatus, ok := matchStatus(statusStr, language); ok {
+		return standardStatus
 	}
 
 	return "planned" // Default to planned
@@ -334,6 +350,12 @@ func (dt *DataTransformer) validateData(data *ProjectStructure) *ValidationResul
 	return validationResult
 }
 
+// ValidateBusinessRules checks the date ranges and dependency references of
+// a project structure, as Transform does with its result
+func (dt *DataTransformer) ValidateBusinessRules(data *ProjectStructure) *ValidationResult {
+	return dt.validateData(data)
+}
+
 // businessValidation performs business logic validation
 func (dt *DataTransformer) businessValidation(data *ProjectStructure) *ValidationResult {
 	result := &ValidationResult{
@@ -398,43 +420,3 @@ func (dt *DataTransformer) createPartialTransformation(data *ProjectStructure, v
 	// to fix validation errors and create a partially valid structure
 	return nil
 }
-
-// calculateConfidenceScore calculates confidence score based on data quality
-func (dt *DataTransformer) calculateConfidenceScore(data *ProjectStructure, validation *ValidationResult) float64 {
-	score := 1.0
-
-	// Reduce score based on validation issues
-	score -= float64(len(validation.Issues)) * 0.2
-	score -= float64(len(validation.Warnings)) * 0.05
-
-	// Consider data completeness
-	if data.Project.Title == "" {
-		score -= 0.1
-	}
-	if data.Project.Description == "" {
-		score -= 0.05
-	}
-
-	if len(data.Project.Phases) == 0 {
-		score -= 0.3
-	}
-
-	// Consider task completeness
-	totalTasks := 0
-	for _, phase := range data.Project.Phases {
-		totalTasks += len(phase.Tasks)
-	}
-	if totalTasks == 0 {
-		score -= 0.2
-	}
-
-	// Ensure score stays within bounds
-	if score < 0.0 {
-		score = 0.0
-	}
-	if score > 1.0 {
-		score = 1.0
-	}
-
-	return score
-}
@@ -0,0 +1,415 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// statusRank orders task statuses by progress, so merged tasks keep the most
+// advanced status any chunk reported
+var statusRank = map[string]int{
+	"planned":     0,
+	"in_progress": 1,
+	"completed":   2,
+}
+
+// Merge combines the transformations of the chunks of one document into a
+// single result. Phases with the same name are merged, as are tasks with the
+// same name within a phase, and all IDs are renumbered with dependencies
+// rewritten to match. The confidence is scored on the merged structure, with
+// the extraction quality of the chunks averaged by weights, their shares of
+// the text; failed chunks count with zero extraction quality.
+func (dt *DataTransformer) Merge(parts []*TransformationResult, weights []float64) *TransformationResult {
+	return dt.mergeParts(parts, weights, newStructureMerger())
+}
+
+// mergeParts merges the parts of a chunked document or the documents of a
+// multi-document parse with merger
+func (dt *DataTransformer) mergeParts(parts []*TransformationResult, weights []float64, merger *structureMerger) *TransformationResult {
+	result := &TransformationResult{
+		ValidationErrors: []string{},
+		ProcessingNotes:  []string{},
+	}
+
+	var extractionQuality, totalWeight float64
+	failed := 0
+	for i, part := range parts {
+		weight := 1.0
+		if i < len(weights) {
+			weight = weights[i]
+		}
+		totalWeight += weight
+
+		if part == nil || part.TransformedData == nil {
+			failed++
+			continue
+		}
+		quality := 1.0
+		if part.ConfidenceBreakdown != nil {
+			quality = part.ConfidenceBreakdown.ExtractionQuality
+		}
+		extractionQuality += quality * weight
+		merger.add(i, part.TransformedData)
+	}
+
+	if failed == len(parts) {
+		result.Status = TransformationStatusFailed
+		result.ValidationErrors = append(result.ValidationErrors, fmt.Sprintf("No %s could be transformed", merger.partName()))
+		return result
+	}
+
+	merged := merger.result()
+	validationResult := dt.validateData(merged)
+	if !validationResult.IsValid {
+		result.Status = TransformationStatusValidationError
+		result.ValidationErrors = validationResult.Issues
+		return result
+	}
+
+	result.TransformedData = merged
+	result.Status = TransformationStatusSuccess
+	if failed > 0 {
+		result.Status = TransformationStatusPartial
+		result.ProcessingNotes = append(result.ProcessingNotes,
+			fmt.Sprintf("%d of %d %ss could not be parsed, their phases and tasks are missing", failed, len(parts), merger.unit))
+	}
+	result.ConfidenceBreakdown = dt.scoreConfidence(merged, false)
+	if totalWeight > 0 {
+		result.ConfidenceBreakdown.ExtractionQuality = extractionQuality / totalWeight
+	}
+	if failed > 0 {
+		result.ConfidenceBreakdown.Reasons = append(result.ConfidenceBreakdown.Reasons,
+			fmt.Sprintf("%d of %d %ss could not be parsed", failed, len(parts), merger.unit))
+	}
+	result.ConfidenceScore = result.ConfidenceBreakdown.Score()
+
+	if merger.conflicts != nil {
+		result.Conflicts = merger.conflicts.list
+	}
+	result.ProcessingNotes = append(result.ProcessingNotes, merger.notes(len(parts))...)
+	result.ProcessingNotes = append(result.ProcessingNotes, validationResult.Suggestions...)
+	return result
+}
+
+// structureMerger accumulates the project structures of chunks, or of
+// documents, in order
+type structureMerger struct {
+	unit      string             // chunk or document
+	conflicts *documentConflicts // set when merging documents
+
+	project  Project
+	metadata Metadata
+	started  bool
+
+	phases     []*mergedPhase
+	phaseIndex map[string]*mergedPhase
+
+	// chunk-local task IDs of every chunk or document, for rewriting dependencies
+	taskIDs []map[string]*mergedTask
+
+	mergedPhases int
+	mergedTasks  int
+	droppedDeps  int
+}
+
+type mergedPhase struct {
+	phase     Phase
+	tasks     []*mergedTask
+	taskIndex map[string]*mergedTask
+}
+
+type mergedTask struct {
+	task Task
+	deps []taskRef
+}
+
+// taskRef is a dependency as written in its chunk
+type taskRef struct {
+	chunk int
+	id    string
+}
+
+func newStructureMerger() *structureMerger {
+	return &structureMerger{unit: "chunk", phaseIndex: make(map[string]*mergedPhase)}
+}
+
+// partName names a part in messages about the whole merge
+func (m *structureMerger) partName() string {
+	if m.conflicts != nil {
+		return "document"
+	}
+	return "chunk of the document"
+}
+
+func (m *structureMerger) add(chunk int, data *ProjectStructure) {
+	for len(m.taskIDs) <= chunk {
+		m.taskIDs = append(m.taskIDs, make(map[string]*mergedTask))
+	}
+	if m.conflicts != nil {
+		m.conflicts.document = chunk
+	}
+
+	m.addProject(data)
+
+	for i, phase := range data.Project.Phases {
+		key := MergeKey(phase.Name)
+		if key == "" {
+			key = fmt.Sprintf("#%d/%d", chunk, i)
+		}
+
+		target, exists := m.phaseIndex[key]
+		if !exists {
+			target = &mergedPhase{
+				phase:     Phase{Name: phase.Name},
+				taskIndex: make(map[string]*mergedTask),
+			}
+			m.phaseIndex[key] = target
+			m.phases = append(m.phases, target)
+		} else {
+			m.mergedPhases++
+		}
+		name := target.phase.Name
+		m.mergeText(&target.phase.Description, phase.Description, name, "", "description", longer)
+		m.mergeText(&target.phase.StartDate, phase.StartDate, name, "", "start_date", earlierDate)
+		m.mergeText(&target.phase.EndDate, phase.EndDate, name, "", "end_date", laterDate)
+		m.mergeMoney(&target.phase.Cost, phase.Cost, name, "", "cost")
+
+		for j, task := range phase.Tasks {
+			m.addTask(chunk, target, task, j)
+		}
+	}
+}
+
+func (m *structureMerger) addProject(data *ProjectStructure) {
+	project := data.Project
+	if !m.started {
+		m.started = true
+		m.metadata = data.Metadata
+		m.project = Project{Metadata: make(map[string]interface{})}
+	}
+	m.mergeText(&m.project.Title, project.Title, "", "", "title", firstText)
+	m.mergeText(&m.project.Description, project.Description, "", "", "description", longer)
+	m.mergeText(&m.project.Deadline, project.Deadline, "", "", "deadline", laterDate)
+	m.mergeMoney(&m.project.Budget, project.Budget, "", "", "budget")
+
+	for key, value := range project.Metadata {
+		if _, exists := m.project.Metadata[key]; !exists {
+			m.project.Metadata[key] = value
+		}
+	}
+}
+
+func (m *structureMerger) addTask(chunk int, phase *mergedPhase, task Task, position int) {
+	key := MergeKey(task.Name)
+	if key == "" {
+		key = fmt.Sprintf("#%d/%d", chunk, position)
+	}
+
+	target, exists := phase.taskIndex[key]
+	if !exists {
+		target = &mergedTask{task: Task{Name: task.Name}}
+		phase.taskIndex[key] = target
+		phase.tasks = append(phase.tasks, target)
+	} else {
+		m.mergedTasks++
+	}
+	phaseName, name := phase.phase.Name, target.task.Name
+	m.mergeText(&target.task.Description, task.Description, phaseName, name, "description", longer)
+	m.mergeText(&target.task.StartDate, task.StartDate, phaseName, name, "start_date", earlierDate)
+	m.mergeText(&target.task.EndDate, task.EndDate, phaseName, name, "end_date", laterDate)
+	m.mergeText(&target.task.Status, task.Status, phaseName, name, "status", advancedStatus)
+	m.mergeMoney(&target.task.Cost, task.Cost, phaseName, name, "cost")
+
+	for _, person := range task.ResponsiblePersons {
+		if !hasPerson(target.task.ResponsiblePersons, person.Name) {
+			target.task.ResponsiblePersons = append(target.task.ResponsiblePersons, person)
+		}
+	}
+	for _, dep := range task.Dependencies {
+		target.deps = append(target.deps, taskRef{chunk: chunk, id: dep})
+	}
+	if task.ID != "" {
+		m.taskIDs[chunk][task.ID] = target
+	}
+}
+
+// result renumbers phases and tasks as phase_N and task_N_M and resolves
+// dependencies to the new IDs. Dependencies on tasks no chunk returned are
+// dropped.
+func (m *structureMerger) result() *ProjectStructure {
+	project := m.project
+	project.Phases = make([]Phase, 0, len(m.phases))
+
+	for i, phase := range m.phases {
+		phase.phase.ID = fmt.Sprintf("phase_%d", i+1)
+		for j, task := range phase.tasks {
+			task.task.ID = fmt.Sprintf("task_%d_%d", i+1, j+1)
+		}
+	}
+
+	for _, phase := range m.phases {
+		merged := phase.phase
+		merged.Tasks = make([]Task, 0, len(phase.tasks))
+		for _, task := range phase.tasks {
+			merged.Tasks = append(merged.Tasks, m.resolveDependencies(task))
+		}
+		project.Phases = append(project.Phases, merged)
+	}
+
+	return &ProjectStructure{Project: project, Metadata: m.metadata}
+}
+
+func (m *structureMerger) resolveDependencies(task *mergedTask) Task {
+	resolved := task.task
+	seen := make(map[string]bool)
+	for _, dep := range task.deps {
+		target, ok := m.taskIDs[dep.chunk][dep.id]
+		if !ok {
+			m.droppedDeps++
+			continue
+		}
+		id := target.task.ID
+		if id == resolved.ID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		resolved.Dependencies = append(resolved.Dependencies, id)
+	}
+	return resolved
+}
+
+// mergeText merges a field of a phase, task or, without a phase, the
+// project. Chunks of a document combine their values with rule; documents
+// disagreeing on a value are resolved by m.conflicts.
+func (m *structureMerger) mergeText(current *string, value, phase, task, field string, rule func(a, b string) string) {
+	if m.conflicts == nil {
+		*current = rule(*current, value)
+		return
+	}
+	if m.conflicts.replaces(phase, task, field, *current, value, *current == "", value == "") {
+		*current = value
+	}
+}
+
+// mergeMoney merges a budget or cost like mergeText; chunks keep the first
+// amount found
+func (m *structureMerger) mergeMoney(current **Money, value *Money, phase, task, field string) {
+	if m.conflicts == nil {
+		if *current == nil {
+			*current = value
+		}
+		return
+	}
+	if m.conflicts.replaces(phase, task, field, *current, value, *current == nil, value == nil) {
+		*current = value
+	}
+}
+
+func (m *structureMerger) notes(parts int) []string {
+	if m.conflicts != nil {
+		return append(m.conflicts.notes(parts, m.mergedPhases, m.mergedTasks), m.dependencyNotes()...)
+	}
+	notes := []string{fmt.Sprintf("Document was parsed in %d chunks and merged", parts)}
+	if m.mergedPhases > 0 || m.mergedTasks > 0 {
+		notes = append(notes, fmt.Sprintf(
+			"Combined %d duplicate phases and %d duplicate tasks found in overlapping chunks", m.mergedPhases, m.mergedTasks))
+	}
+	return append(notes, m.dependencyNotes()...)
+}
+
+func (m *structureMerger) dependencyNotes() []string {
+	var notes []string
+	if m.droppedDeps > 0 {
+		notes = append(notes, fmt.Sprintf("Dropped %d dependencies on tasks that were not extracted", m.droppedDeps))
+	}
+	return notes
+}
+
+// dropDanglingDependencies removes dependencies on task IDs missing from
+// data and returns how many were removed. A chunk may depend on tasks
+// defined in another chunk, which are not known by ID until the merge.
+func dropDanglingDependencies(data *ProjectStructure) int {
+	taskIDs := make(map[string]bool)
+	for _, phase := range data.Project.Phases {
+		for _, task := range phase.Tasks {
+			taskIDs[task.ID] = true
+		}
+	}
+
+	dropped := 0
+	for i := range data.Project.Phases {
+		tasks := data.Project.Phases[i].Tasks
+		for j := range tasks {
+			kept := tasks[j].Dependencies[:0]
+			for _, dep := range tasks[j].Dependencies {
+				if taskIDs[dep] {
+					kept = append(kept, dep)
+				} else {
+					dropped++
+				}
+			}
+			tasks[j].Dependencies = kept
+		}
+	}
+	return dropped
+}
+
+// MergeKey compares names by their letters and digits only, ignoring case,
+// so the same phase or task is recognized across chunks and documents
+func MergeKey(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+func hasPerson(persons []ResponsiblePerson, name string) bool {
+	for _, person := range persons {
+		if strings.EqualFold(person.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstText(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a
+}
+
+// advancedStatus keeps the status further along by statusRank
+func advancedStatus(a, b string) string {
+	if a == "" || statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
+
+func longer(a, b string) string {
+	if len([]rune(b)) > len([]rune(a)) {
+		return b
+	}
+	return a
+}
+
+// earlierDate and laterDate compare normalized YYYY-MM-DD dates, preferring
+// a set date to an empty one
+func earlierDate(a, b string) string {
+	if a == "" || (b != "" && b < a) {
+		return b
+	}
+	return a
+}
+
+func laterDate(a, b string) string {
+	if a == "" || (b != "" && b > a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,255 @@
+package transformers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// part wraps a project as the successful transformation of a chunk or
+// document
+func part(project Project) *TransformationResult {
+	return &TransformationResult{
+		TransformedData: &ProjectStructure{Project: project},
+		Status:          TransformationStatusSuccess,
+	}
+}
+
+func TestMergeOverlappingChunks(t *testing.T) {
+	first := part(Project{
+		Title:       "Реконструкция школы",
+		Description: "Капитальный ремонт",
+		Phases: []Phase{{
+			ID:   "phase_1",
+			Name: "Подготовка",
+			Tasks: []Task{
+				{ID: "task_1", Name: "Обследование объекта", Status: "completed"},
+				{ID: "task_2", Name: "Проектирование", Status: "planned", Dependencies: []string{"task_1"}},
+			},
+		}},
+	})
+	// The second chunk starts inside the first phase and repeats its last task
+	second := part(Project{
+		Title:       "Реконструкция школы",
+		Description: "Капитальный ремонт",
+		Phases: []Phase{
+			{
+				ID:   "phase_1",
+				Name: "подготовка.",
+				Tasks: []Task{
+					{ID: "task_1", Name: "Проектирование", Status: "planned"},
+					{ID: "task_2", Name: "Закупка материалов", Status: "planned", Dependencies: []string{"task_1", "task_9"}},
+				},
+			},
+			{
+				ID:    "phase_2",
+				Name:  "Строительство",
+				Tasks: []Task{{ID: "task_3", Name: "Демонтаж", Status: "planned", Dependencies: []string{"task_2"}}},
+			},
+		},
+	})
+
+	result := NewDataTransformer().Merge([]*TransformationResult{first, second}, []float64{1, 1})
+	if result.Status != TransformationStatusSuccess {
+		t.Fatalf("Merge() status = %s, errors %v", result.Status, result.ValidationErrors)
+	}
+
+	type task struct {
+		id   string
+		name string
+		deps []string
+	}
+	var got [][]task
+	for _, phase := range result.TransformedData.Project.Phases {
+		var tasks []task
+		for _, tk := range phase.Tasks {
+			tasks = append(tasks, task{tk.ID, tk.Name, tk.Dependencies})
+		}
+		got = append(got, tasks)
+	}
+	want := [][]task{
+		{
+			{"task_1_1", "Обследование объекта", nil},
+			{"task_1_2", "Проектирование", []string{"task_1_1"}},
+			// task_1 of the second chunk is the repeated Проектирование
+			{"task_1_3", "Закупка материалов", []string{"task_1_2"}},
+		},
+		{
+			{"task_2_1", "Демонтаж", []string{"task_1_3"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged tasks = %v, want %v", got, want)
+	}
+
+	notes := []string{
+		"Document was parsed in 2 chunks and merged",
+		"Combined 1 duplicate phases and 1 duplicate tasks found in overlapping chunks",
+		"Dropped 1 dependencies on tasks that were not extracted",
+	}
+	for _, note := range notes {
+		if !contains(result.ProcessingNotes, note) {
+			t.Errorf("ProcessingNotes = %q, missing %q", result.ProcessingNotes, note)
+		}
+	}
+}
+
+func TestMergeConflictingFieldsOfChunks(t *testing.T) {
+	first := part(Project{
+		Title:       "Реконструкция школы",
+		Description: "Ремонт",
+		Deadline:    "2025-06-30",
+		Budget:      &Money{Amount: 1000000, Currency: "KZT"},
+		Phases: []Phase{{
+			ID:        "phase_1",
+			Name:      "Ремонт",
+			StartDate: "2025-02-01",
+			EndDate:   "2025-04-30",
+			Tasks: []Task{{
+				ID:                 "task_1",
+				Name:               "Кровля",
+				Description:        "Замена кровли",
+				StartDate:          "2025-02-01",
+				EndDate:            "2025-03-15",
+				Status:             "in_progress",
+				ResponsiblePersons: []ResponsiblePerson{{Name: "Иванов И."}},
+			}},
+		}},
+	})
+	second := part(Project{
+		Title:       "Школа №5",
+		Description: "Капитальный ремонт здания школы",
+		Deadline:    "2025-09-01",
+		Budget:      &Money{Amount: 1200000, Currency: "KZT"},
+		Phases: []Phase{{
+			ID:        "phase_1",
+			Name:      "Ремонт",
+			StartDate: "2025-01-15",
+			EndDate:   "2025-05-31",
+			Tasks: []Task{{
+				ID:                 "task_1",
+				Name:               "Кровля",
+				Description:        "Замена",
+				StartDate:          "2025-02-10",
+				EndDate:            "2025-04-01",
+				Status:             "planned",
+				ResponsiblePersons: []ResponsiblePerson{{Name: "иванов и."}, {Name: "Петров П."}},
+			}},
+		}},
+	})
+
+	result := NewDataTransformer().Merge([]*TransformationResult{first, second}, nil)
+	if result.Status != TransformationStatusSuccess {
+		t.Fatalf("Merge() status = %s, errors %v", result.Status, result.ValidationErrors)
+	}
+	project := result.TransformedData.Project
+	phase := project.Phases[0]
+	task := phase.Tasks[0]
+
+	tests := []struct {
+		field string
+		got   interface{}
+		want  interface{}
+	}{
+		{"project title, the first", project.Title, "Реконструкция школы"},
+		{"project description, the longer", project.Description, "Капитальный ремонт здания школы"},
+		{"project deadline, the later", project.Deadline, "2025-09-01"},
+		{"project budget, the first", project.Budget.Amount, 1000000.0},
+		{"phase start, the earlier", phase.StartDate, "2025-01-15"},
+		{"phase end, the later", phase.EndDate, "2025-05-31"},
+		{"task description, the longer", task.Description, "Замена кровли"},
+		{"task start, the earlier", task.StartDate, "2025-02-01"},
+		{"task end, the later", task.EndDate, "2025-04-01"},
+		{"task status, the most advanced", task.Status, "in_progress"},
+		{"responsible persons, without case duplicates", len(task.ResponsiblePersons), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+			}
+		})
+	}
+	if len(result.Conflicts) != 0 {
+		t.Errorf("chunks reported conflicts %v, want none", result.Conflicts)
+	}
+}
+
+func TestMergeDocumentsConflictingFields(t *testing.T) {
+	contract := part(Project{
+		Title:       "Реконструкция школы",
+		Description: "Договор подряда",
+		Deadline:    "2025-06-30",
+		Phases: []Phase{{
+			ID:    "phase_1",
+			Name:  "Ремонт",
+			Tasks: []Task{{ID: "task_1", Name: "Кровля", EndDate: "2025-03-15", Status: "planned"}},
+		}},
+	})
+	annex := part(Project{
+		Title:       "Реконструкция школы",
+		Description: "Договор подряда",
+		Deadline:    "2025-09-01",
+		Phases: []Phase{{
+			ID:    "phase_1",
+			Name:  "Ремонт",
+			Tasks: []Task{{ID: "task_1", Name: "Кровля", EndDate: "2025-04-01", Status: "planned"}},
+		}},
+	})
+	wantConflicts := []Conflict{
+		{
+			Field:  "deadline",
+			Values: []ConflictValue{{"contract.pdf", "2025-06-30"}, {"annex.pdf", "2025-09-01"}},
+		},
+		{
+			Phase:  "Ремонт",
+			Task:   "Кровля",
+			Field:  "end_date",
+			Values: []ConflictValue{{"contract.pdf", "2025-03-15"}, {"annex.pdf", "2025-04-01"}},
+		},
+	}
+
+	tests := []struct {
+		strategy     ConflictStrategy
+		wantDeadline string
+		wantEndDate  string
+		wantResolved bool
+	}{
+		{ConflictsLaterWins, "2025-09-01", "2025-04-01", true},
+		{ConflictsManual, "2025-06-30", "2025-03-15", false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			result := NewDataTransformer().MergeDocuments(
+				[]*TransformationResult{contract, annex}, []string{"contract.pdf", "annex.pdf"}, tt.strategy)
+			if result.Status != TransformationStatusSuccess {
+				t.Fatalf("MergeDocuments() status = %s, errors %v", result.Status, result.ValidationErrors)
+			}
+
+			project := result.TransformedData.Project
+			if project.Deadline != tt.wantDeadline {
+				t.Errorf("deadline = %s, want %s", project.Deadline, tt.wantDeadline)
+			}
+			if end := project.Phases[0].Tasks[0].EndDate; end != tt.wantEndDate {
+				t.Errorf("task end_date = %s, want %s", end, tt.wantEndDate)
+			}
+
+			want := make([]Conflict, len(wantConflicts))
+			for i, conflict := range wantConflicts {
+				conflict.Resolved = tt.wantResolved
+				want[i] = conflict
+			}
+			if !reflect.DeepEqual(result.Conflicts, want) {
+				t.Errorf("Conflicts = %+v, want %+v", result.Conflicts, want)
+			}
+		})
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
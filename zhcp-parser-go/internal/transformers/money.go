@@ -0,0 +1,198 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// currencyAliases maps how documents write currencies to ISO 4217 codes
+var currencyAliases = map[string]string{
+	"₸": "KZT", "тг": "KZT", "тенге": "KZT", "теңге": "KZT", "tenge": "KZT", "kzt": "KZT",
+	"₽": "RUB", "руб": "RUB", "рубль": "RUB", "рублей": "RUB", "рубля": "RUB", "rub": "RUB",
+	"$": "USD", "долл": "USD", "доллар": "USD", "долларов": "USD", "usd": "USD",
+	"€": "EUR", "евро": "EUR", "eur": "EUR",
+}
+
+// amountScales are the words for thousands, millions and billions written
+// after an amount, e.g. "1,2 млн"
+var amountScales = map[string]float64{
+	"тыс": 1e3, "тысяч": 1e3, "мың": 1e3, "thousand": 1e3,
+	"млн": 1e6, "миллион": 1e6, "миллионов": 1e6, "million": 1e6, "mln": 1e6,
+	"млрд": 1e9, "миллиард": 1e9, "миллиардов": 1e9, "billion": 1e9, "bln": 1e9,
+}
+
+var (
+	amountPattern = regexp.MustCompile(`\d[\d\s\x{00A0}\x{202F}.,']*`)
+	wordPattern   = regexp.MustCompile(`[\p{L}$€₸₽]+`)
+	isoCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// normalizeMoney reads a budget or cost the LLM wrote as {"amount",
+// "currency"}, as a number, or as text such as "1,2 млн тг". Missing and
+// non-positive amounts give nil.
+func (dt *DataTransformer) normalizeMoney(value interface{}) *Money {
+	var amount float64
+	var currency string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		amount, currency = parseMoneyValue(v["amount"])
+		if code := normalizeCurrency(dt.normalizeText(v["currency"])); code != "" {
+			currency = code
+		}
+	default:
+		amount, currency = parseMoneyValue(v)
+	}
+
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return nil
+	}
+	return &Money{Amount: math.Round(amount*100) / 100, Currency: currency}
+}
+
+// parseMoneyValue reads an amount and the currency written with it, if any
+func parseMoneyValue(value interface{}) (float64, string) {
+	switch v := value.(type) {
+	case float64:
+		return v, ""
+	case string:
+		return parseMoneyText(v)
+	case nil:
+		return 0, ""
+	default:
+		return parseMoneyText(fmt.Sprintf("%v", v))
+	}
+}
+
+// parseMoneyText reads the first amount in text with the scale and
+// currency words around it
+func parseMoneyText(text string) (float64, string) {
+	location := amountPattern.FindStringIndex(text)
+	if location == nil {
+		return 0, ""
+	}
+	amount, ok := parseAmount(text[location[0]:location[1]])
+	if !ok {
+		return 0, ""
+	}
+
+	currency := ""
+	scaled := false
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		lower := strings.ToLower(strings.TrimSuffix(word, "."))
+		if scale, ok := amountScales[lower]; ok && !scaled {
+			amount *= scale
+			scaled = true
+			continue
+		}
+		if code := normalizeCurrency(word); code != "" && currency == "" {
+			currency = code
+		}
+	}
+	return amount, currency
+}
+
+// parseAmount reads a number with any of the usual digit group and decimal
+// separators: "1 200 000", "1,200,000.50", "1.200.000,50", "1,5"
+func parseAmount(text string) (float64, bool) {
+	var digits strings.Builder
+	for _, r := range text {
+		if unicode.IsDigit(r) || r == '.' || r == ',' {
+			digits.WriteRune(r)
+		}
+	}
+	number := strings.Trim(digits.String(), ".,")
+
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// The separator that comes last is the decimal one
+		if lastComma > lastDot {
+			number = strings.ReplaceAll(number, ".", "")
+			number = strings.Replace(number, ",", ".", 1)
+		} else {
+			number = strings.ReplaceAll(number, ",", "")
+		}
+	case lastComma >= 0:
+		// A single comma is decimal unless three digits follow it
+		if strings.Count(number, ",") > 1 || len(number)-lastComma-1 == 3 {
+			number = strings.ReplaceAll(number, ",", "")
+		} else {
+			number = strings.Replace(number, ",", ".", 1)
+		}
+	case strings.Count(number, ".") > 1:
+		number = strings.ReplaceAll(number, ".", "")
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	return amount, err == nil
+}
+
+// normalizeCurrency returns the ISO 4217 code of a currency as written, or
+// "" when it is not recognized
+func normalizeCurrency(currency string) string {
+	currency = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(currency), "."))
+	if currency == "" {
+		return ""
+	}
+	if code, ok := currencyAliases[strings.ToLower(currency)]; ok {
+		return code
+	}
+	if upper := strings.ToUpper(currency); isoCodeRegexp.MatchString(upper) && len(currency) == 3 {
+		return upper
+	}
+	return ""
+}
+
+// fillCurrencies gives costs without a currency the one of the project
+// budget, or the only currency the other amounts of the document use
+func fillCurrencies(project *Project) {
+	currencies := make(map[string]bool)
+	visitMoney(project, func(money *Money) {
+		if money.Currency != "" {
+			currencies[money.Currency] = true
+		}
+	})
+
+	currency := ""
+	if project.Budget != nil && project.Budget.Currency != "" {
+		currency = project.Budget.Currency
+	} else if len(currencies) == 1 {
+		for only := range currencies {
+			currency = only
+		}
+	}
+	if currency == "" {
+		return
+	}
+
+	visitMoney(project, func(money *Money) {
+		if money.Currency == "" {
+			money.Currency = currency
+		}
+	})
+}
+
+// visitMoney calls visit for the budget and every cost of a project
+func visitMoney(project *Project, visit func(*Money)) {
+	if project.Budget != nil {
+		visit(project.Budget)
+	}
+	for i := range project.Phases {
+		phase := &project.Phases[i]
+		if phase.Cost != nil {
+			visit(phase.Cost)
+		}
+		for j := range phase.Tasks {
+			if cost := phase.Tasks[j].Cost; cost != nil {
+				visit(cost)
+			}
+		}
+	}
+}
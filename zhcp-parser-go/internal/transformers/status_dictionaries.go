@@ -0,0 +1,62 @@
+package transformers
+
+import "strings"
+
+// statusVariations maps the wording of a task status to a standard status.
+// Entries are checked in order, so negated wordings such as "не выполнено"
+// come before the wordings they contain.
+type statusVariations []struct {
+	status     string
+	variations []string
+}
+
+// statusDictionaries holds the status wordings of each document language
+var statusDictionaries = map[string]statusVariations{
+	"ru": {
+		{"planned", []string{"не начат", "не выполнен", "в плане", "запланирован", "планируется", "ожидает"}},
+		{"in_progress", []string{"в работе", "выполняется", "в процессе", "ведется", "ведётся", "начат"}},
+		{"completed", []string{"завершен", "завершён", "выполнен", "готово", "закрыт"}},
+	},
+	"kk": {
+		{"planned", []string{"басталмаған", "орындалмаған", "жоспарланған", "жоспарда", "күтуде"}},
+		{"in_progress", []string{"орындалуда", "жүргізілуде", "іске асырылуда", "жұмыста", "процесте"}},
+		{"completed", []string{"аяқталды", "аяқталған", "орындалды", "орындалған", "дайын"}},
+	},
+	"en": {
+		{"planned", []string{"not started", "planned", "planning", "scheduled", "to do", "todo"}},
+		{"in_progress", []string{"in_progress", "in progress", "progress", "ongoing", "started"}},
+		{"completed", []string{"completed", "complete", "done", "finished", "closed"}},
+	},
+}
+
+// statusLanguages is the order dictionaries are tried in after the one of
+// the document language; LLMs often answer in English whatever the document
+var statusLanguages = []string{"en", "ru", "kk"}
+
+// matchStatus maps a lowercase status to a standard status using the
+// dictionary of language first, then the others
+func matchStatus(status, language string) (string, bool) {
+	if standard, ok := statusDictionaries[language].match(status); ok {
+		return standard, true
+	}
+	for _, other := range statusLanguages {
+		if other == language {
+			continue
+		}
+		if standard, ok := statusDictionaries[other].match(status); ok {
+			return standard, true
+		}
+	}
+	return "", false
+}
+
+func (d statusVariations) match(status string) (string, bool) {
+	for _, entry := range d {
+		for _, variation := range entry.variations {
+			if strings.Contains(status, variation) {
+				return entry.status, true
+			}
+		}
+	}
+	return "", false
+}
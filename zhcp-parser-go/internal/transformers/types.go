@@ -12,12 +12,20 @@ const (
 
 // TransformationResult represents the result of data transformation
 type TransformationResult struct {
-	TransformedData  *ProjectStructure    `json:"transformed_data,omitempty"`
-	Status           TransformationStatus `json:"status"`
-	ConfidenceScore  float64              `json:"confidence_score"`
-	ValidationErrors []string             `json:"validation_errors"`
-	ProcessingNotes  []string             `json:"processing_notes"`
-	TokensUsed       TokenUsage           `json:"tokens_used,omitempty"`
+	TransformedData     *ProjectStructure    `json:"transformed_data,omitempty"`
+	Status              TransformationStatus `json:"status"`
+	ConfidenceScore     float64              `json:"confidence_score"`
+	ConfidenceBreakdown *ConfidenceBreakdown `json:"confidence_breakdown,omitempty"` // explains ConfidenceScore
+	ValidationErrors    []string             `json:"validation_errors"`
+	ProcessingNotes     []string             `json:"processing_notes"`
+	TokensUsed          TokenUsage           `json:"tokens_used,omitempty"`
+	Conflicts           []Conflict           `json:"conflicts,omitempty"` // set by MergeDocuments
+}
+
+// TransformOptions describe the document an LLM response is about
+type TransformOptions struct {
+	Language string // ru, kk or en; picks the status dictionary tried first
+	Part     bool   // the response covers one chunk of a longer document
 }
 
 // TokenUsage represents token usage information
@@ -38,6 +46,7 @@ type Project struct {
 	Title       string                 `json:"title" validate:"required"`
 	Description string                 `json:"description" validate:"required"`
 	Deadline    string                 `json:"deadline,omitempty"`
+	Budget      *Money                 `json:"budget,omitempty"` // total budget
 	Phases      []Phase                `json:"phases" validate:"required,min=1"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -49,6 +58,7 @@ type Phase struct {
 	Description string `json:"description"`
 	StartDate   string `json:"start_date,omitempty" validate:"omitempty,date_format"`
 	EndDate     string `json:"end_date,omitempty" validate:"omitempty,date_format,date_after_start"`
+	Cost        *Money `json:"cost,omitempty"`
 	Tasks       []Task `json:"tasks"`
 }
 
@@ -62,6 +72,13 @@ type Task struct {
 	ResponsiblePersons []ResponsiblePerson `json:"responsible_persons"`
 	Dependencies       []string            `json:"dependencies"`
 	Status             string              `json:"status" validate:"oneof=planned in_progress completed"`
+	Cost               *Money              `json:"cost,omitempty"`
+}
+
+// Money is an amount of a budget or cost
+type Money struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"` // ISO 4217 code, e.g. KZT; empty when the document does not say
 }
 
 // ResponsiblePerson represents a person responsible for a task
@@ -11,8 +11,9 @@ type ValidationPipeline struct {
 	DocumentValidator    *DocumentValidator
 	StructureValidator   *StructureValidator
 	ConsistencyValidator *ConsistencyValidator
-	ErrorHandler         interface{} // In a real implementation, we'd use a proper error handler
-	logger               interface{} // In a real implementation, we'd use a proper logger
+	BusinessValidator    *transformers.DataTransformer // date ranges and dependency references
+	ErrorHandler         interface{}                   // In a real implementation, we'd use a proper error handler
+	logger               interface{}                   // In a real implementation, we'd use a proper logger
 }
 
 // NewValidationPipeline creates a new validation pipeline
@@ -21,6 +22,7 @@ func NewValidationPipeline() *ValidationPipeline {
 		DocumentValidator:    NewDocumentValidator(),
 		StructureValidator:   NewStructureValidator(),
 		ConsistencyValidator: NewConsistencyValidator(),
+		BusinessValidator:    transformers.NewDataTransformer(),
 	}
 }
 
@@ -123,15 +125,59 @@ func (vp *ValidationPipeline) calculateConfidenceAdjustment(issues, warnings []s
 	return adjustment
 }
 
-// ValidateProjectStructure validates just the project structure
+// ValidateProjectStructure validates just the project structure, e.g. one
+// edited by hand after parsing. There is no document, so the content stage is
+// skipped and the business rules of the transformer run instead.
 func (vp *ValidationPipeline) ValidateProjectStructure(projectStructure *transformers.ProjectStructure) *ValidationResult {
-	data := map[string]interface{}{
-		"project_structure": projectStructure,
-		"extracted_content": "",
-		"document_type":     "unknown",
+	results := &ValidationResult{
+		IsValid:          true,
+		ValidationStages: make(map[string]interface{}),
+		Issues:           []string{},
+		Warnings:         []string{},
+		Suggestions:      []string{},
+		QualityScore:     1.0,
+	}
+	if projectStructure == nil {
+		projectStructure = &transformers.ProjectStructure{}
+	}
+
+	// Stage 1: Structure validation
+	structureResults := vp.StructureValidator.ValidateStructure(projectStructure)
+	results.ValidationStages["structure"] = structureResults
+
+	if !structureResults.IsValid {
+		results.IsValid = false
+		results.Issues = append(results.Issues, structureResults.Issues...)
+	}
+	results.Warnings = append(results.Warnings, structureResults.Warnings...)
+	results.Suggestions = append(results.Suggestions, structureResults.Suggestions...)
+	results.QualityScore = structureResults.QualityScore
+
+	// Stage 2: Consistency validation
+	consistencyResults := vp.ConsistencyValidator.ValidateConsistency(projectStructure)
+	results.ValidationStages["consistency"] = consistencyResults
+
+	if !consistencyResults.IsValid {
+		results.IsValid = false
+		results.Issues = append(results.Issues, consistencyResults.Issues...)
 	}
+	results.Warnings = append(results.Warnings, consistencyResults.Warnings...)
+	results.Suggestions = append(results.Suggestions, consistencyResults.Suggestions...)
 
-	return vp.ValidateComplete(data, "")
+	// Stage 3: Business rules
+	businessResults := vp.BusinessValidator.ValidateBusinessRules(projectStructure)
+	results.ValidationStages["business"] = businessResults
+
+	if !businessResults.IsValid {
+		results.IsValid = false
+		results.Issues = append(results.Issues, businessResults.Issues...)
+	}
+	results.Warnings = append(results.Warnings, businessResults.Warnings...)
+
+	results.ValidationStages["confidence_adjustment"] = vp.calculateConfidenceAdjustment(
+		results.Issues, results.Warnings)
+
+	return results
 }
 
 // GetValidationSummary provides a summary of validation results